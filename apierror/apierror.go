@@ -0,0 +1,145 @@
+// apierror/apierror.go
+
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/requestid"
+	"net/http"
+)
+
+// Code is a stable, machine readable error identifier.
+type Code string
+
+// Error codes returned by the API.
+const (
+	CodeBadRequest           Code = "BAD_REQUEST"
+	CodeInvalidJSON          Code = "INVALID_JSON"
+	CodeValidation           Code = "VALIDATION_FAILED"
+	CodeInvalidLang          Code = "INVALID_LANG"
+	CodeInvalidToken         Code = "INVALID_TOKEN"
+	CodeUnauthorized         Code = "UNAUTHORIZED"
+	CodeForbidden            Code = "FORBIDDEN"
+	CodeNotFound             Code = "NOT_FOUND"
+	CodeConflict             Code = "CONFLICT"
+	CodeIdempotencyKeyReused Code = "IDEMPOTENCY_KEY_REUSED"
+	CodePreconditionFailed   Code = "PRECONDITION_FAILED"
+	CodePreconditionRequired Code = "PRECONDITION_REQUIRED"
+	CodeMethodNotAllowed     Code = "METHOD_NOT_ALLOWED"
+	CodeUnsupportedMediaType Code = "UNSUPPORTED_MEDIA_TYPE"
+	CodeBodyTooLarge         Code = "BODY_TOO_LARGE"
+	CodeRateLimited          Code = "RATE_LIMITED"
+	CodeUpstreamError        Code = "UPSTREAM_ERROR"
+	CodeUpstreamUnavailable  Code = "UPSTREAM_UNAVAILABLE"
+	CodeDBError              Code = "DB_ERROR"
+	CodeUnavailable          Code = "SERVICE_UNAVAILABLE"
+	CodeMaintenance          Code = "MAINTENANCE"
+	CodeTimeout              Code = "TIMEOUT"
+	CodeInternal             Code = "INTERNAL"
+)
+
+// Error is the JSON error envelope written by Write. Err keeps the
+// underlying cause for logs; it is never sent to clients.
+type Error struct {
+	Status    int         `json:"-"`
+	Code      Code        `json:"code"`
+	Message   string      `json:"message"`
+	Details   interface{} `json:"details,omitempty"`
+	RequestID string      `json:"requestId,omitempty"`
+	Err       error       `json:"-"`
+}
+
+// New ...
+func New(status int, code Code, message string) *Error {
+	return &Error{
+		Status:  status,
+		Code:    code,
+		Message: message,
+	}
+}
+
+// Wrap ...
+func Wrap(err error, status int, code Code, message string) *Error {
+	e := New(status, code, message)
+	e.Err = err
+	return e
+}
+
+// Error returns the underlying cause when there is one, so wrapping does not
+// hide it from logs.
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap ...
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WithDetails returns a copy of e carrying details.
+func (e *Error) WithDetails(details interface{}) *Error {
+	c := *e
+	c.Details = details
+	return &c
+}
+
+// BadRequest ...
+func BadRequest(code Code, message string) *Error {
+	return New(http.StatusBadRequest, code, message)
+}
+
+// Unauthorized ...
+func Unauthorized(message string) *Error {
+	return New(http.StatusUnauthorized, CodeUnauthorized, message)
+}
+
+// Forbidden ...
+func Forbidden(message string) *Error {
+	return New(http.StatusForbidden, CodeForbidden, message)
+}
+
+// NotFound ...
+func NotFound(message string) *Error {
+	return New(http.StatusNotFound, CodeNotFound, message)
+}
+
+// Conflict ...
+func Conflict(message string) *Error {
+	return New(http.StatusConflict, CodeConflict, message)
+}
+
+// Internal ...
+func Internal(err error) *Error {
+	return Wrap(err, http.StatusInternalServerError, CodeInternal, "internal error")
+}
+
+// From returns err as an *Error. Errors that carry no API error become 500s.
+func From(err error) *Error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return Wrap(err, http.StatusRequestEntityTooLarge, CodeBodyTooLarge,
+			fmt.Sprintf("request body is limited to %d bytes", tooLarge.Limit))
+	}
+	return Internal(err)
+}
+
+// Write sends err as a JSON error envelope tagged with the request's ID.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	e := *From(err)
+	e.RequestID = requestid.FromContext(r.Context())
+
+	resp, _ := json.Marshal(e)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(e.Status)
+	w.Write(resp)
+}
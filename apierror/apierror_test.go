@@ -0,0 +1,79 @@
+// apierror/apierror_test.go
+
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/requestid"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	testCases := []struct {
+		name           string
+		err            error
+		expectedStatus int
+		expectedBody   map[string]interface{}
+	}{
+		{
+			"APIError",
+			NotFound("recipient not found"),
+			http.StatusNotFound,
+			map[string]interface{}{"code": "NOT_FOUND", "message": "recipient not found", "requestId": "abc123"},
+		},
+		{
+			"WithDetails",
+			BadRequest(CodeValidation, "invalid recipient").WithDetails("name is required"),
+			http.StatusBadRequest,
+			map[string]interface{}{"code": "VALIDATION_FAILED", "message": "invalid recipient", "details": "name is required", "requestId": "abc123"},
+		},
+		{
+			"Wrapped",
+			fmt.Errorf("sending: %w", Wrap(errors.New("dial tcp: refused"), http.StatusBadGateway, CodeUpstreamError, "quote provider failed")),
+			http.StatusBadGateway,
+			map[string]interface{}{"code": "UPSTREAM_ERROR", "message": "quote provider failed", "requestId": "abc123"},
+		},
+		{
+			"BodyTooLarge",
+			fmt.Errorf("reading body: %w", &http.MaxBytesError{Limit: 1024}),
+			http.StatusRequestEntityTooLarge,
+			map[string]interface{}{"code": "BODY_TOO_LARGE", "message": "request body is limited to 1024 bytes", "requestId": "abc123"},
+		},
+		{
+			"PlainError",
+			errors.New("sample error"),
+			http.StatusInternalServerError,
+			map[string]interface{}{"code": "INTERNAL", "message": "internal error", "requestId": "abc123"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/", nil)
+			req = req.WithContext(requestid.NewContext(req.Context(), "abc123"))
+			rr := httptest.NewRecorder()
+
+			Write(rr, req, tC.err)
+
+			var body map[string]interface{}
+			_ = json.Unmarshal(rr.Body.Bytes(), &body)
+
+			assert.Equal(t, tC.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			assert.Equal(t, "application/json", rr.Header().Get("Content-Type"), "Content-Type is different than expected")
+			assert.Equal(t, tC.expectedBody, body, "Error envelope is different than expected")
+		})
+	}
+}
+
+func TestError(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := Wrap(cause, http.StatusServiceUnavailable, CodeDBError, "database unavailable")
+
+	assert.Equal(t, "connection refused", err.Error(), "Error should report the cause")
+	assert.True(t, errors.Is(err, cause), "Error should unwrap to the cause")
+	assert.Equal(t, "database unavailable", New(http.StatusServiceUnavailable, CodeDBError, "database unavailable").Error(), "Error without cause should report the message")
+}
@@ -0,0 +1,249 @@
+// bind/bind.go
+
+package bind
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidJSON is returned when the request body cannot be decoded.
+var ErrInvalidJSON = errors.New("request body is not valid JSON")
+
+// FieldError reports why one field of a request is invalid. Field is the
+// query parameter or JSON name of the field.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Errors lists every invalid field of a request.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fe.Field+": "+fe.Message)
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Rule checks value against the rule parameter, the text after "=" in the
+// validate tag. It returns the message to report, or "" when value is valid.
+type Rule func(value reflect.Value, param string) string
+
+// Binder decodes requests into tagged structs and validates them.
+//
+// Fields tagged `query:"name"` are read from the URL query, falling back to
+// their `default:"..."` tag. Request structs with json-tagged fields get the
+// JSON body decoded into them first. Fields are then checked against their
+// comma separated `validate:"..."` rules. Embedded structs are flattened.
+type Binder struct {
+	rules map[string]Rule
+}
+
+// New returns a Binder knowing the built-in rules: required, email, min, max
+// and oneof.
+func New() *Binder {
+	b := &Binder{rules: make(map[string]Rule)}
+	for name, rule := range builtinRules {
+		b.rules[name] = rule
+	}
+	return b
+}
+
+// Register adds or replaces a validation rule.
+func (b *Binder) Register(name string, rule Rule) {
+	b.rules[name] = rule
+}
+
+// Bind fills dst, a pointer to a struct, from r and validates it. It returns
+// ErrInvalidJSON for a malformed body, Errors for invalid fields and the
+// *http.MaxBytesError of a body cut short by http.MaxBytesReader.
+func (b *Binder) Bind(r *http.Request, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bind: dst must be a pointer to a struct, got %T", dst)
+	}
+
+	fields := collectFields(v.Elem())
+
+	if hasBody(fields) && r.Body != nil {
+		if err := json.NewDecoder(r.Body).Decode(dst); err != nil && err != io.EOF {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return err
+			}
+			return ErrInvalidJSON
+		}
+	}
+
+	var errs Errors
+	query := r.URL.Query()
+	for _, f := range fields {
+		if f.query == "" {
+			continue
+		}
+
+		raw, ok := query[f.query]
+		if !ok || len(raw) == 0 || raw[0] == "" {
+			raw = nil
+			if def, ok := f.tag.Lookup("default"); ok {
+				raw = []string{def}
+			}
+		}
+		if err := setValue(f.value, raw); err != nil {
+			errs = append(errs, FieldError{Field: f.name, Message: err.Error()})
+			f.invalid = true
+		}
+	}
+
+	for _, f := range fields {
+		if f.invalid {
+			continue
+		}
+		if msg := b.validate(f); msg != "" {
+			errs = append(errs, FieldError{Field: f.name, Message: msg})
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+type field struct {
+	name    string
+	query   string
+	tag     reflect.StructTag
+	value   reflect.Value
+	invalid bool
+}
+
+func collectFields(v reflect.Value) []*field {
+	var fields []*field
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct {
+			fields = append(fields, collectFields(v.Field(i))...)
+			continue
+		}
+		if !sf.IsExported() {
+			continue
+		}
+
+		f := &field{tag: sf.Tag, value: v.Field(i)}
+		if q, ok := sf.Tag.Lookup("query"); ok {
+			f.query, f.name = q, q
+		} else if j, ok := sf.Tag.Lookup("json"); ok {
+			name, _, _ := strings.Cut(j, ",")
+			if name == "-" {
+				continue
+			}
+			f.name = name
+		} else {
+			continue
+		}
+		if f.name == "" {
+			f.name = sf.Name
+		}
+		fields = append(fields, f)
+	}
+
+	return fields
+}
+
+func hasBody(fields []*field) bool {
+	for _, f := range fields {
+		if f.query == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// setValue parses raw into v. No value resets v, so query parameters always
+// win over a body that happens to carry the same field.
+func setValue(v reflect.Value, raw []string) error {
+	if len(raw) == 0 {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw[0])
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw[0], 10, v.Type().Bits())
+		if err != nil {
+			return errors.New("must be an integer")
+		}
+		v.SetInt(n)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw[0])
+		if err != nil {
+			return errors.New("must be true or false")
+		}
+		v.SetBool(b)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		var values []string
+		for _, r := range raw {
+			values = append(values, strings.Split(r, ",")...)
+		}
+		v.Set(reflect.ValueOf(values))
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+func (b *Binder) validate(f *field) string {
+	tag := f.tag.Get("validate")
+	if tag == "" {
+		return ""
+	}
+
+	for _, spec := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(spec, "=")
+		if name != "required" && isEmpty(f.value) {
+			// Only required rejects missing strings and lists.
+			continue
+		}
+
+		rule, ok := b.rules[name]
+		if !ok {
+			return fmt.Sprintf("unknown validation rule %q", name)
+		}
+		if msg := rule(f.value, param); msg != "" {
+			return msg
+		}
+	}
+
+	return ""
+}
+
+func isZero(v reflect.Value) bool {
+	return v.IsZero() || isEmpty(v)
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String, reflect.Slice:
+		return v.Len() == 0
+	default:
+		return false
+	}
+}
@@ -0,0 +1,162 @@
+// bind/bind_test.go
+
+package bind
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type page struct {
+	Limit  int    `query:"limit" default:"20" validate:"min=1,max=100"`
+	Offset int    `query:"offset" validate:"min=0"`
+	Order  string `query:"order" validate:"oneof=asc desc"`
+}
+
+type search struct {
+	Term   string   `query:"q" validate:"required,max=5"`
+	Tags   []string `query:"tag"`
+	Strict bool     `query:"strict"`
+	page
+}
+
+type signup struct {
+	Name   string `json:"name" validate:"required"`
+	Email  string `json:"email" validate:"required,email"`
+	Source string `query:"source" validate:"even"`
+}
+
+func TestBinder_Query(t *testing.T) {
+	testCases := []struct {
+		name           string
+		query          string
+		expected       search
+		expectedErrors Errors
+	}{
+		{
+			"Defaults",
+			"q=go",
+			search{Term: "go", page: page{Limit: 20}},
+			nil,
+		},
+		{
+			"AllFields",
+			"q=go&tag=a,b&tag=c&strict=true&limit=5&offset=10&order=desc",
+			search{Term: "go", Tags: []string{"a", "b", "c"}, Strict: true, page: page{Limit: 5, Offset: 10, Order: "desc"}},
+			nil,
+		},
+		{
+			"Invalid",
+			"q=golang&strict=maybe&limit=0&offset=x&order=up",
+			search{},
+			Errors{
+				{Field: "strict", Message: "must be true or false"},
+				{Field: "offset", Message: "must be an integer"},
+				{Field: "q", Message: "must be at most 5 characters"},
+				{Field: "limit", Message: "must be at least 1"},
+				{Field: "order", Message: "must be one of asc, desc"},
+			},
+		},
+		{
+			"Missing",
+			"",
+			search{},
+			Errors{{Field: "q", Message: "is required"}},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/search?"+tc.query, nil)
+
+			var got search
+			err := New().Bind(req, &got)
+
+			if tc.expectedErrors != nil {
+				assert.Equal(t, tc.expectedErrors, err, "Field errors are different than expected")
+				return
+			}
+			assert.NoError(t, err, "Should have no error when binding")
+			assert.Equal(t, tc.expected, got, "Bound request is different than expected")
+		})
+	}
+}
+
+func TestBinder_Body(t *testing.T) {
+	b := New()
+	b.Register("even", func(v reflect.Value, _ string) string {
+		if len(v.String())%2 != 0 {
+			return "must have an even length"
+		}
+		return ""
+	})
+
+	testCases := []struct {
+		name          string
+		target        string
+		body          string
+		expected      signup
+		expectedError error
+	}{
+		{
+			"Valid",
+			"/signup?source=ad",
+			`{"name":"Bob","email":"bob@testmail.com","source":"ignored"}`,
+			signup{Name: "Bob", Email: "bob@testmail.com", Source: "ad"},
+			nil,
+		},
+		{
+			"InvalidFields",
+			"/signup?source=abc",
+			`{"name":" ","email":"nope"}`,
+			signup{},
+			Errors{
+				{Field: "name", Message: "is required"},
+				{Field: "email", Message: "must be a valid email address"},
+				{Field: "source", Message: "must have an even length"},
+			},
+		},
+		{
+			"InvalidJSON",
+			"/signup",
+			`{"name":`,
+			signup{},
+			ErrInvalidJSON,
+		},
+		{
+			"BodyTooLarge",
+			"/signup",
+			`{"name":"` + strings.Repeat("Bob", 100) + `","email":"bob@testmail.com"}`,
+			signup{},
+			&http.MaxBytesError{Limit: 128},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tc.target, strings.NewReader(tc.body))
+			req.Body = http.MaxBytesReader(httptest.NewRecorder(), req.Body, 128)
+
+			var got signup
+			err := b.Bind(req, &got)
+
+			if tc.expectedError != nil {
+				assert.Equal(t, tc.expectedError, err, "Error is different than expected")
+				return
+			}
+			assert.NoError(t, err, "Should have no error when binding")
+			assert.Equal(t, tc.expected, got, "Bound request is different than expected")
+		})
+	}
+}
+
+func TestBinder_NotAStruct(t *testing.T) {
+	var n int
+	err := New().Bind(httptest.NewRequest(http.MethodGet, "/", nil), &n)
+
+	assert.Error(t, err, "Should reject targets that are not struct pointers")
+	_, isFieldErrors := err.(Errors)
+	assert.False(t, isFieldErrors, "Misuse should not look like a client error")
+}
@@ -0,0 +1,86 @@
+// bind/rules.go
+
+package bind
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var builtinRules = map[string]Rule{
+	"required": required,
+	"email":    email,
+	"min":      min,
+	"max":      max,
+	"oneof":    oneOf,
+}
+
+func required(v reflect.Value, _ string) string {
+	if isZero(v) || (v.Kind() == reflect.String && strings.TrimSpace(v.String()) == "") {
+		return "is required"
+	}
+	return ""
+}
+
+func email(v reflect.Value, _ string) string {
+	addr, err := mail.ParseAddress(v.String())
+	if err != nil || addr.Address != v.String() {
+		return "must be a valid email address"
+	}
+	return ""
+}
+
+// size is the length of strings and slices and the value of integers.
+func size(v reflect.Value) int64 {
+	switch v.Kind() {
+	case reflect.String:
+		return int64(utf8.RuneCountInString(v.String()))
+	case reflect.Slice:
+		return int64(v.Len())
+	default:
+		return v.Int()
+	}
+}
+
+func min(v reflect.Value, param string) string {
+	n, _ := strconv.ParseInt(param, 10, 64)
+	if size(v) >= n {
+		return ""
+	}
+	if v.Kind() == reflect.String {
+		return fmt.Sprintf("must be at least %d characters", n)
+	}
+	if v.Kind() == reflect.Slice {
+		return fmt.Sprintf("must have at least %d items", n)
+	}
+	return fmt.Sprintf("must be at least %d", n)
+}
+
+func max(v reflect.Value, param string) string {
+	n, _ := strconv.ParseInt(param, 10, 64)
+	if size(v) <= n {
+		return ""
+	}
+	if v.Kind() == reflect.String {
+		return fmt.Sprintf("must be at most %d characters", n)
+	}
+	if v.Kind() == reflect.Slice {
+		return fmt.Sprintf("must have at most %d items", n)
+	}
+	return fmt.Sprintf("must be at most %d", n)
+}
+
+// oneOf takes space separated choices, as in oneof=json xml text.
+func oneOf(v reflect.Value, param string) string {
+	choices := strings.Fields(param)
+	for _, c := range choices {
+		if fmt.Sprint(v.Interface()) == c {
+			return ""
+		}
+	}
+	return "must be one of " + strings.Join(choices, ", ")
+}
@@ -0,0 +1,145 @@
+// clock/mock.go
+
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Mock is a Clock that only moves when told to. Timers and tickers fire as
+// Add or Set moves the time past them.
+type Mock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	waiters []*mockWaiter
+}
+
+// mockWaiter is a timer, or a ticker when period is set.
+type mockWaiter struct {
+	mock   *Mock
+	c      chan time.Time
+	at     time.Time
+	period time.Duration
+}
+
+// NewMock returns a clock stopped at now.
+func NewMock(now time.Time) *Mock {
+	m := &Mock{now: now}
+	m.cond = sync.NewCond(&m.mu)
+	return m
+}
+
+// Now ...
+func (m *Mock) Now() time.Time {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.now
+}
+
+// NewTimer ...
+func (m *Mock) NewTimer(d time.Duration) Timer {
+	return m.wait(d, 0)
+}
+
+// NewTicker ...
+func (m *Mock) NewTicker(d time.Duration) Ticker {
+	if d <= 0 {
+		panic("clock: non-positive interval for NewTicker")
+	}
+	return mockTicker{m.wait(d, d)}
+}
+
+func (m *Mock) wait(d, period time.Duration) *mockWaiter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w := &mockWaiter{mock: m, c: make(chan time.Time, 1), at: m.now.Add(d), period: period}
+	if period == 0 && d <= 0 {
+		w.c <- m.now
+		return w
+	}
+	m.waiters = append(m.waiters, w)
+	m.cond.Broadcast()
+	return w
+}
+
+// Add moves the time forward by d.
+func (m *Mock) Add(d time.Duration) {
+	m.Set(m.Now().Add(d))
+}
+
+// Set moves the time to t, firing the timers and tickers due by then in the
+// order they are due. Like a time.Ticker, a ticker whose tick was not
+// received yet drops the ticks it misses.
+func (m *Mock) Set(t time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.now = t
+	sort.SliceStable(m.waiters, func(i, j int) bool {
+		return m.waiters[i].at.Before(m.waiters[j].at)
+	})
+
+	pending := m.waiters[:0]
+	for _, w := range m.waiters {
+		if w.at.After(t) {
+			pending = append(pending, w)
+			continue
+		}
+
+		select {
+		case w.c <- w.at:
+		default:
+		}
+		if w.period > 0 {
+			for !w.at.After(t) {
+				w.at = w.at.Add(w.period)
+			}
+			pending = append(pending, w)
+		}
+	}
+	m.waiters = pending
+	m.cond.Broadcast()
+}
+
+// BlockUntil waits until n timers and tickers are pending, so a test knows
+// the code under test is waiting before it moves the time.
+func (m *Mock) BlockUntil(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for len(m.waiters) < n {
+		m.cond.Wait()
+	}
+}
+
+func (w *mockWaiter) C() <-chan time.Time {
+	return w.c
+}
+
+// Stop reports whether w was still pending.
+func (w *mockWaiter) Stop() bool {
+	m := w.mock
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, other := range m.waiters {
+		if other == w {
+			m.waiters = append(m.waiters[:i], m.waiters[i+1:]...)
+			m.cond.Broadcast()
+			return true
+		}
+	}
+	return false
+}
+
+type mockTicker struct {
+	*mockWaiter
+}
+
+func (t mockTicker) Stop() {
+	t.mockWaiter.Stop()
+}
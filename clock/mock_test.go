@@ -0,0 +1,76 @@
+// clock/mock_test.go
+
+package clock
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2019, 9, 1, 10, 0, 0, 0, time.UTC)
+
+// fired returns what c holds, or the zero time when it holds nothing.
+func fired(c <-chan time.Time) time.Time {
+	select {
+	case t := <-c:
+		return t
+	default:
+		return time.Time{}
+	}
+}
+
+func TestMock_Timer(t *testing.T) {
+	m := NewMock(epoch)
+	timer := m.NewTimer(time.Minute)
+
+	m.Add(59 * time.Second)
+	assert.True(t, fired(timer.C()).IsZero(), "Timer should not fire early")
+
+	m.Add(time.Second)
+	assert.Equal(t, epoch.Add(time.Minute), fired(timer.C()), "Timer should fire when due")
+	assert.Equal(t, epoch.Add(time.Minute), m.Now(), "Time is different than expected")
+
+	m.Add(time.Hour)
+	assert.True(t, fired(timer.C()).IsZero(), "Timer should fire once")
+	assert.False(t, timer.Stop(), "Fired timer should not be pending")
+}
+
+func TestMock_TimerStop(t *testing.T) {
+	m := NewMock(epoch)
+	timer := m.NewTimer(time.Minute)
+
+	assert.True(t, timer.Stop(), "Timer should be pending")
+	m.Add(time.Hour)
+	assert.True(t, fired(timer.C()).IsZero(), "Stopped timer should not fire")
+}
+
+func TestMock_Ticker(t *testing.T) {
+	m := NewMock(epoch)
+	ticker := m.NewTicker(time.Minute)
+
+	m.Add(time.Minute)
+	assert.Equal(t, epoch.Add(time.Minute), fired(ticker.C()), "Ticker should tick every interval")
+
+	m.Add(3 * time.Minute)
+	assert.Equal(t, epoch.Add(2*time.Minute), fired(ticker.C()), "Ticker should tick once for the missed ticks")
+	m.Add(time.Minute)
+	assert.Equal(t, epoch.Add(5*time.Minute), fired(ticker.C()), "Missed ticks should be dropped")
+
+	ticker.Stop()
+	m.Add(time.Hour)
+	assert.True(t, fired(ticker.C()).IsZero(), "Stopped ticker should not tick")
+}
+
+func TestMock_BlockUntil(t *testing.T) {
+	m := NewMock(epoch)
+	done := make(chan time.Time)
+	go func() {
+		done <- <-m.NewTimer(time.Second).C()
+	}()
+
+	m.BlockUntil(1)
+	m.Add(time.Second)
+
+	assert.Equal(t, epoch.Add(time.Second), <-done, "Waiting goroutine should wake up")
+}
@@ -0,0 +1,187 @@
+// cmd/quoteserver/main.go
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/internal/config"
+	"github.com/jeniok12/golang-tdd-example/internal/server"
+	"github.com/jeniok12/golang-tdd-example/tracing"
+	"github.com/rs/zerolog"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// usage lists the commands. Without one, quoteserver serves.
+const usage = `usage: quoteserver [command] [flags]
+
+commands:
+  serve  serve the API (default)
+  seed   fetch quotes from the provider into the local quotes`
+
+func main() {
+	logger := zerolog.New(os.Stderr).With().Timestamp().Logger()
+
+	cmd, args := command(os.Args[1:])
+	switch cmd {
+	case "serve":
+		serve(args, logger)
+	case "seed":
+		seed(args, logger)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		logger.Fatal().Str("command", cmd).Msg("unknown command")
+	}
+}
+
+// command splits the command off args. Flags alone mean serve, so
+// quoteserver keeps starting the way it did before it had commands.
+func command(args []string) (string, []string) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return "serve", args
+	}
+	return args[0], args[1:]
+}
+
+// loadConfig loads the config from args parsed with fs and sets the log
+// level. The level is global, so a config reload can change it.
+func loadConfig(fs *flag.FlagSet, args []string, logger zerolog.Logger) *config.Config {
+	cfg, err := config.LoadFlags(fs, args)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("loading config")
+	}
+
+	level, err := zerolog.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("parsing log level")
+	}
+	zerolog.SetGlobalLevel(level)
+
+	return cfg
+}
+
+func serve(args []string, logger zerolog.Logger) {
+	cfg := loadConfig(flag.NewFlagSet("quoteserver serve", flag.ContinueOnError), args, logger)
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Endpoint:    cfg.OTLPEndpoint,
+		ServiceName: "quoteserver",
+		SampleRatio: cfg.TraceSampleRatio,
+	})
+	if err != nil {
+		logger.Fatal().Err(err).Msg("configuring tracing")
+	}
+
+	// Reloads reread the same file, environment and flags.
+	reload := func() (*config.Config, error) {
+		fs := flag.NewFlagSet("quoteserver serve", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		return config.LoadFlags(fs, args)
+	}
+	svr, err := server.NewFromConfig(cfg, logger, server.WithConfigReload(reload))
+	if err != nil {
+		logger.Fatal().Err(err).Msg("creating server")
+	}
+
+	degraded := checkDependencies(svr, cfg, logger)
+
+	if cfg.Migrate {
+		if err := svr.Migrate(context.Background()); err != nil {
+			if !degraded {
+				logger.Fatal().Err(err).Msg("migrating database")
+			}
+			logger.Error().Err(err).Msg("migrating database, the schema may be out of date")
+		}
+	}
+
+	listeners, err := httpServers(cfg, svr.Handler())
+	if err != nil {
+		logger.Fatal().Err(err).Msg("configuring listeners")
+	}
+	for _, httpServer := range listeners {
+		httpServer.RegisterOnShutdown(svr.EndStreams)
+	}
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM)
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go reloadOnHangup(svr, hangup, logger)
+
+	svr.Start()
+
+	if err := runServers(listeners, stop, cfg.ShutdownTimeout.Duration, logger); err != nil {
+		logger.Error().Err(err).Msg("serving")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout.Duration)
+	defer cancel()
+	if err := svr.Shutdown(ctx); err != nil {
+		logger.Error().Err(err).Msg("closing server")
+	}
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error().Err(err).Msg("flushing traces")
+	}
+}
+
+// runServers serves until a signal arrives on stop or any server fails, then
+// gives in-flight requests up to timeout to finish on every server.
+func runServers(httpServers []*http.Server, stop <-chan os.Signal, timeout time.Duration, logger zerolog.Logger) error {
+	errs := make(chan error, len(httpServers))
+	for _, httpServer := range httpServers {
+		httpServer := httpServer
+		logger.Info().Str("addr", httpServer.Addr).Bool("tls", httpServer.TLSConfig != nil).Msg("listening")
+		go func() {
+			errs <- listenAndServe(httpServer)
+		}()
+	}
+
+	var serveErr error
+	select {
+	case serveErr = <-errs:
+	case sig := <-stop:
+		logger.Info().Str("signal", sig.String()).Msg("shutting down")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	for _, httpServer := range httpServers {
+		if err := httpServer.Shutdown(ctx); err != nil && serveErr == nil {
+			serveErr = err
+		}
+	}
+	return serveErr
+}
+
+// reloadOnHangup reloads the config of svr on every signal on hangup.
+func reloadOnHangup(svr *server.Server, hangup <-chan os.Signal, logger zerolog.Logger) {
+	for range hangup {
+		if _, err := svr.Reload(); err != nil {
+			logger.Error().Err(err).Msg("reloading config, keeping the current one")
+		}
+	}
+}
+
+// checkDependencies waits for the database and other dependencies to answer.
+// If they do not, the server exits, or in degrade mode serves anyway and
+// reports them as down on /readyz, which checkDependencies returns true for.
+func checkDependencies(svr *server.Server, cfg *config.Config, logger zerolog.Logger) bool {
+	err := svr.CheckDependencies(context.Background(), cfg.StartupAttempts, cfg.StartupRetryDelay.Duration)
+	if err == nil {
+		return false
+	}
+	if cfg.StartupMode != "degrade" {
+		logger.Fatal().Err(err).Msg("dependencies unavailable")
+	}
+
+	logger.Warn().Err(err).Msg("dependencies unavailable, serving degraded")
+	return true
+}
@@ -0,0 +1,67 @@
+// cmd/quoteserver/main_test.go
+
+package main
+
+import (
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestCommand(t *testing.T) {
+	testCases := []struct {
+		name            string
+		args            []string
+		expectedCommand string
+		expectedArgs    []string
+	}{
+		{"NoArgs", nil, "serve", nil},
+		{"FlagsOnly", []string{"-port", "9000"}, "serve", []string{"-port", "9000"}},
+		{"Serve", []string{"serve", "-port", "9000"}, "serve", []string{"-port", "9000"}},
+		{"Seed", []string{"seed", "--count", "10"}, "seed", []string{"--count", "10"}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			cmd, args := command(tC.args)
+
+			assert.Equal(t, tC.expectedCommand, cmd)
+			assert.Equal(t, tC.expectedArgs, args)
+		})
+	}
+}
+
+func TestRunServers(t *testing.T) {
+	testCases := []struct {
+		name               string
+		addrs              []string
+		expectedToGetError bool
+	}{
+		{"ShutdownOnSignal", []string{"127.0.0.1:0", "127.0.0.1:0"}, false},
+		{"ListenFail", []string{"127.0.0.1:0", "127.0.0.1:-1"}, true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			stop := make(chan os.Signal, 1)
+			var httpServers []*http.Server
+			for _, addr := range tC.addrs {
+				httpServers = append(httpServers, &http.Server{Addr: addr, Handler: http.NotFoundHandler()})
+			}
+
+			if !tC.expectedToGetError {
+				stop <- syscall.SIGTERM
+			}
+
+			err := runServers(httpServers, stop, time.Second, zerolog.Nop())
+
+			if tC.expectedToGetError {
+				assert.Error(t, err, "Got no error when expected")
+			} else {
+				assert.NoError(t, err, "Got error when not expected")
+			}
+		})
+	}
+}
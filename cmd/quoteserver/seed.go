@@ -0,0 +1,49 @@
+// cmd/quoteserver/seed.go
+
+package main
+
+import (
+	"context"
+	"flag"
+	"github.com/jeniok12/golang-tdd-example/internal/server"
+	"github.com/rs/zerolog"
+	"os/signal"
+	"syscall"
+)
+
+// seed fetches quotes from the configured providers into the local quotes,
+// so the local provider and search have something to serve.
+func seed(args []string, logger zerolog.Logger) {
+	fs := flag.NewFlagSet("quoteserver seed", flag.ContinueOnError)
+	count := fs.Int("count", 100, "number of quotes to fetch")
+	lang := fs.String("lang", "en", "language of the quotes")
+	cfg := loadConfig(fs, args, logger)
+
+	if *count < 1 {
+		logger.Fatal().Int("count", *count).Msg("count must be positive")
+	}
+
+	svr, err := server.NewFromConfig(cfg, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("creating server")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	if cfg.Migrate {
+		if err := svr.Migrate(ctx); err != nil {
+			logger.Fatal().Err(err).Msg("migrating database")
+		}
+	}
+
+	res, err := svr.Seed(ctx, *lang, *count)
+	if err := svr.Close(); err != nil {
+		logger.Error().Err(err).Msg("closing server")
+	}
+	if err != nil {
+		logger.Fatal().Err(err).Int("added", res.Added).Int("skipped", res.Skipped).Msg("seeding local quotes")
+	}
+
+	logger.Info().Int("added", res.Added).Int("skipped", res.Skipped).Str("lang", *lang).Msg("seeded local quotes")
+}
@@ -0,0 +1,80 @@
+// cmd/quoteserver/tls.go
+
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/internal/config"
+	"github.com/jeniok12/golang-tdd-example/internal/server"
+	"golang.org/x/crypto/acme/autocert"
+	"net/http"
+)
+
+// httpServers builds the listeners for cfg. Without TLS that is a single
+// plain HTTP server. With TLS the API is served over HTTPS on its own port,
+// and the plain HTTP port redirects to it, or keeps serving the API when
+// redirects are off. HTTP/2 is negotiated on the HTTPS listener.
+func httpServers(cfg *config.Config, handler http.Handler) ([]*http.Server, error) {
+	if !cfg.TLSEnabled() {
+		return withTimeouts(cfg, &http.Server{Addr: cfg.Addr(), Handler: handler}), nil
+	}
+
+	insecure := handler
+	if cfg.TLS.Redirect {
+		insecure = server.RedirectHTTPS(cfg.TLS.Port)
+	}
+
+	var tlsConfig *tls.Config
+	switch {
+	case len(cfg.TLS.Domains) > 0 && (cfg.TLS.CertFile != "" || cfg.TLS.KeyFile != ""):
+		return nil, errors.New("tls: use either a certificate or autocert domains, not both")
+	case len(cfg.TLS.Domains) > 0:
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.TLS.Domains...),
+			Cache:      autocert.DirCache(cfg.TLS.CacheDir),
+			Email:      cfg.TLS.Email,
+		}
+		tlsConfig = m.TLSConfig()
+		// Let's Encrypt HTTP-01 challenges arrive on the plain HTTP port.
+		insecure = m.HTTPHandler(insecure)
+	case cfg.TLS.CertFile == "" || cfg.TLS.KeyFile == "":
+		return nil, errors.New("tls: certificate and key files must be set together")
+	default:
+		cert, err := tls.LoadX509KeyPair(cfg.TLS.CertFile, cfg.TLS.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: loading certificate: %v", err)
+		}
+		tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+	tlsConfig.MinVersion = tls.VersionTLS12
+
+	return withTimeouts(cfg,
+		&http.Server{Addr: cfg.Addr(), Handler: insecure},
+		&http.Server{Addr: cfg.TLSAddr(), Handler: handler, TLSConfig: tlsConfig},
+	), nil
+}
+
+// withTimeouts keeps slow clients from holding connections, and the
+// goroutines serving them, open indefinitely: headers, whole requests and
+// responses must each arrive in time, and idle keep-alives are closed.
+func withTimeouts(cfg *config.Config, servers ...*http.Server) []*http.Server {
+	for _, s := range servers {
+		s.ReadHeaderTimeout = cfg.ReadHeaderTimeout.Duration
+		s.ReadTimeout = cfg.ReadTimeout.Duration
+		s.WriteTimeout = cfg.WriteTimeout.Duration
+		s.IdleTimeout = cfg.IdleTimeout.Duration
+	}
+	return servers
+}
+
+// listenAndServe serves HTTPS when httpServer has a TLS config and plain
+// HTTP otherwise.
+func listenAndServe(httpServer *http.Server) error {
+	if httpServer.TLSConfig != nil {
+		return httpServer.ListenAndServeTLS("", "")
+	}
+	return httpServer.ListenAndServe()
+}
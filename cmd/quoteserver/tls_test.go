@@ -0,0 +1,131 @@
+// cmd/quoteserver/tls_test.go
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/jeniok12/golang-tdd-example/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCertificate writes a self-signed certificate and its key to dir.
+func writeCertificate(t *testing.T, dir string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err, "Should generate a key")
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err, "Should create a certificate")
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err, "Should marshal the key")
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	require.NoError(t, ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600), "Should write the certificate")
+	require.NoError(t, ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600), "Should write the key")
+	return certFile, keyFile
+}
+
+func TestHTTPServers(t *testing.T) {
+	certFile, keyFile := writeCertificate(t, t.TempDir())
+
+	testCases := []struct {
+		name               string
+		tls                config.TLS
+		expectedServers    int
+		expectedRedirect   bool
+		expectedToGetError bool
+	}{
+		{"PlainHTTP", config.TLS{}, 1, false, false},
+		{"CertificateFiles", config.TLS{CertFile: certFile, KeyFile: keyFile, Port: 8443, Redirect: true}, 2, true, false},
+		{"NoRedirect", config.TLS{CertFile: certFile, KeyFile: keyFile, Port: 8443}, 2, false, false},
+		{"Autocert", config.TLS{Domains: []string{"quotes.example.com"}, CacheDir: t.TempDir(), Port: 8443, Redirect: true}, 2, true, false},
+		{"MissingKey", config.TLS{CertFile: certFile}, 0, false, true},
+		{"CertificateAndAutocert", config.TLS{CertFile: certFile, KeyFile: keyFile, Domains: []string{"quotes.example.com"}}, 0, false, true},
+		{"UnreadableCertificate", config.TLS{CertFile: "/does/not/exist.pem", KeyFile: keyFile}, 0, false, true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.TLS = tC.tls
+			api := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTeapot)
+			})
+
+			httpServers, err := httpServers(cfg, api)
+
+			if tC.expectedToGetError {
+				assert.Error(t, err, "Got no error when expected")
+				return
+			}
+			require.NoError(t, err, "Got error when not expected")
+			require.Len(t, httpServers, tC.expectedServers, "Wrong number of listeners")
+			for _, s := range httpServers {
+				assert.Equal(t, cfg.ReadHeaderTimeout.Duration, s.ReadHeaderTimeout, "Slow headers should time out")
+				assert.Equal(t, cfg.WriteTimeout.Duration, s.WriteTimeout, "Slow reads of the response should time out")
+			}
+			assert.Equal(t, cfg.Addr(), httpServers[0].Addr, "Plain HTTP should listen on the configured port")
+			assert.Nil(t, httpServers[0].TLSConfig, "Plain HTTP listener should not use TLS")
+			if tC.expectedServers == 1 {
+				return
+			}
+
+			assert.Equal(t, ":8443", httpServers[1].Addr, "HTTPS should listen on the TLS port")
+			assert.NotNil(t, httpServers[1].TLSConfig, "HTTPS listener should use TLS")
+
+			rr := httptest.NewRecorder()
+			httpServers[0].Handler.ServeHTTP(rr, httptest.NewRequest("GET", "http://quotes.example.com/quote", nil))
+			if tC.expectedRedirect {
+				assert.Equal(t, "https://quotes.example.com:8443/quote", rr.Header().Get("Location"), "Plain HTTP should redirect to HTTPS")
+			} else {
+				assert.Equal(t, http.StatusTeapot, rr.Code, "Plain HTTP should keep serving the API")
+			}
+		})
+	}
+}
+
+func TestHTTPServers_HTTP2(t *testing.T) {
+	certFile, keyFile := writeCertificate(t, t.TempDir())
+
+	cfg := config.Default()
+	cfg.TLS = config.TLS{CertFile: certFile, KeyFile: keyFile}
+	httpServers, err := httpServers(cfg, http.NotFoundHandler())
+	require.NoError(t, err, "Got error when not expected")
+
+	httpsServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	}))
+	httpsServer.TLS = httpServers[1].TLSConfig
+	httpsServer.EnableHTTP2 = true
+	httpsServer.StartTLS()
+	defer httpsServer.Close()
+
+	client := &http.Client{Transport: &http.Transport{
+		// The test certificate is self-signed.
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		ForceAttemptHTTP2: true,
+	}}
+	response, err := client.Get(httpsServer.URL)
+	require.NoError(t, err, "Got error when not expected")
+	defer response.Body.Close()
+
+	assert.Equal(t, 2, response.ProtoMajor, "HTTPS should negotiate HTTP/2")
+}
@@ -0,0 +1,45 @@
+// errtrack/errtrack.go
+
+// Package errtrack reports errors to an error tracker such as Sentry, which
+// groups them and alerts on new ones, where log lines are only searched once
+// someone already knows something is wrong.
+package errtrack
+
+import (
+	"context"
+	"net/http"
+)
+
+// Event is an error worth a look from a human.
+type Event struct {
+	Err error
+	// Stack is the goroutine stack of a recovered panic, as returned by
+	// runtime/debug.Stack.
+	Stack []byte
+	// Request is the request being served when Err happened, if any.
+	Request *http.Request
+	// Tags index the event, such as the request ID or the failing job.
+	Tags map[string]string
+}
+
+// Reporter sends events to an error tracker. Report must not hold up the
+// caller, which is usually serving a request.
+type Reporter interface {
+	Report(ctx context.Context, e Event)
+	// Flush waits for the reported events to be sent, or for ctx to be done.
+	Flush(ctx context.Context) error
+}
+
+// ReporterFunc adapts a function to Reporter. It reports synchronously, so
+// there is nothing to flush.
+type ReporterFunc func(ctx context.Context, e Event)
+
+// Report calls f.
+func (f ReporterFunc) Report(ctx context.Context, e Event) {
+	f(ctx, e)
+}
+
+// Flush ...
+func (f ReporterFunc) Flush(context.Context) error {
+	return nil
+}
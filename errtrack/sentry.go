@@ -0,0 +1,339 @@
+// errtrack/sentry.go
+
+package errtrack
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sentryClient names this reporter to Sentry.
+const sentryClient = "quoteserver-errtrack/1.0"
+
+// sentryTimeout bounds the sending of one event.
+const sentryTimeout = 10 * time.Second
+
+// maxPendingEvents is how many events are sent at once. Events reported
+// while that many are in flight, as when everything fails at once, are
+// dropped rather than piling up goroutines.
+const maxPendingEvents = 32
+
+// Sentry sends events to the Sentry envelope endpoint of a project, see
+// https://develop.sentry.dev/sdk/envelopes/.
+type Sentry struct {
+	// Environment and Release tag every event, so Sentry can tell staging
+	// from production and the deploy that introduced an error.
+	Environment string
+	Release     string
+	Client      *http.Client
+	// OnError is called with the events that could not be sent.
+	OnError func(err error)
+
+	dsn      string
+	endpoint string
+	key      string
+	now      func() time.Time
+
+	pending chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewSentry reports to the project of dsn, a URL such as
+// https://<key>@o0.ingest.sentry.io/<project>.
+func NewSentry(dsn string) (*Sentry, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("errtrack: parsing sentry dsn: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, errors.New("errtrack: sentry dsn must be an absolute http or https URL")
+	}
+	if u.User == nil || u.User.Username() == "" {
+		return nil, errors.New("errtrack: sentry dsn has no public key")
+	}
+	dir, project := path.Split(strings.TrimSuffix(u.Path, "/"))
+	if project == "" {
+		return nil, errors.New("errtrack: sentry dsn has no project")
+	}
+
+	endpoint := url.URL{Scheme: u.Scheme, Host: u.Host, Path: dir + "api/" + project + "/envelope/"}
+	return &Sentry{
+		dsn:      dsn,
+		endpoint: endpoint.String(),
+		key:      u.User.Username(),
+		now:      time.Now,
+		pending:  make(chan struct{}, maxPendingEvents),
+	}, nil
+}
+
+// Report sends e in the background.
+func (s *Sentry) Report(ctx context.Context, e Event) {
+	if e.Err == nil {
+		return
+	}
+
+	select {
+	case s.pending <- struct{}{}:
+	default:
+		s.failed(errors.New("errtrack: too many pending events, dropping one"))
+		return
+	}
+
+	// The event outlives the request it is about.
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sentryTimeout)
+	s.wg.Add(1)
+	go func() {
+		defer func() {
+			cancel()
+			<-s.pending
+			s.wg.Done()
+		}()
+		if err := s.Send(ctx, e); err != nil {
+			s.failed(err)
+		}
+	}()
+}
+
+// Flush ...
+func (s *Sentry) Flush(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Sentry) failed(err error) {
+	if s.OnError != nil {
+		s.OnError(err)
+	}
+}
+
+type sentryEvent struct {
+	EventID     string            `json:"event_id"`
+	Timestamp   string            `json:"timestamp"`
+	Level       string            `json:"level"`
+	Platform    string            `json:"platform"`
+	Environment string            `json:"environment,omitempty"`
+	Release     string            `json:"release,omitempty"`
+	Tags        map[string]string `json:"tags,omitempty"`
+	Exception   sentryExceptions  `json:"exception"`
+	Request     *sentryRequest    `json:"request,omitempty"`
+}
+
+type sentryExceptions struct {
+	Values []sentryException `json:"values"`
+}
+
+type sentryException struct {
+	Type       string            `json:"type"`
+	Value      string            `json:"value"`
+	Stacktrace *sentryStacktrace `json:"stacktrace,omitempty"`
+}
+
+type sentryStacktrace struct {
+	Frames []sentryFrame `json:"frames"`
+}
+
+type sentryFrame struct {
+	Function string `json:"function"`
+	Filename string `json:"filename"`
+	Lineno   int    `json:"lineno"`
+}
+
+// sentryRequest leaves out the query string and most headers, which may
+// hold tokens and API keys.
+type sentryRequest struct {
+	Method  string            `json:"method"`
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Send sends e and waits for Sentry to accept it.
+func (s *Sentry) Send(ctx context.Context, e Event) error {
+	id, err := eventID()
+	if err != nil {
+		return err
+	}
+
+	event := sentryEvent{
+		EventID:     id,
+		Timestamp:   s.now().UTC().Format(time.RFC3339Nano),
+		Level:       "error",
+		Platform:    "go",
+		Environment: s.Environment,
+		Release:     s.Release,
+		Tags:        e.Tags,
+		Exception: sentryExceptions{Values: []sentryException{{
+			Type:  errorType(e.Err),
+			Value: e.Err.Error(),
+		}}},
+	}
+	if frames := parseStack(e.Stack); len(frames) > 0 {
+		event.Exception.Values[0].Stacktrace = &sentryStacktrace{Frames: frames}
+	}
+	if r := e.Request; r != nil {
+		event.Request = &sentryRequest{
+			Method:  r.Method,
+			URL:     requestURL(r),
+			Headers: map[string]string{"User-Agent": r.UserAgent()},
+		}
+	}
+
+	body, err := envelope(s.dsn, event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-sentry-envelope")
+	req.Header.Set("X-Sentry-Auth", fmt.Sprintf("Sentry sentry_version=7, sentry_client=%s, sentry_key=%s", sentryClient, s.key))
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("errtrack: sending event to sentry: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode/100 != 2 {
+		reason := resp.Header.Get("X-Sentry-Error")
+		if reason == "" {
+			reason = http.StatusText(resp.StatusCode)
+		}
+		return fmt.Errorf("errtrack: sentry rejected event: %s (status %d)", reason, resp.StatusCode)
+	}
+	return nil
+}
+
+// envelope wraps event in an envelope of one item.
+func envelope(dsn string, event sentryEvent) ([]byte, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	header, _ := json.Marshal(map[string]string{"event_id": event.EventID, "sent_at": event.Timestamp, "dsn": dsn})
+	item, _ := json.Marshal(map[string]interface{}{"type": "event", "length": len(payload)})
+
+	var buf bytes.Buffer
+	for _, line := range [][]byte{header, item, payload} {
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes(), nil
+}
+
+// eventID is a random UUID without dashes, as Sentry expects.
+func eventID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return hex.EncodeToString(b[:]), nil
+}
+
+// errorType names the type of the root cause of err, which Sentry groups
+// events by, such as *pq.Error rather than the wrapping *apierror.Error.
+func errorType(err error) string {
+	for {
+		next := errors.Unwrap(err)
+		if next == nil {
+			return fmt.Sprintf("%T", err)
+		}
+		err = next
+	}
+}
+
+func requestURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.Path
+}
+
+// parseStack turns a stack printed by runtime/debug.Stack into Sentry
+// frames, outermost call first.
+func parseStack(stack []byte) []sentryFrame {
+	var frames []sentryFrame
+	var function string
+	sc := bufio.NewScanner(bytes.NewReader(stack))
+	for sc.Scan() {
+		line := sc.Text()
+		if !strings.HasPrefix(line, "\t") {
+			function = stackFunction(line)
+			continue
+		}
+		if function == "" {
+			continue
+		}
+
+		location := strings.TrimSpace(line)
+		if i := strings.LastIndex(location, " +0x"); i >= 0 {
+			location = location[:i]
+		}
+		i := strings.LastIndex(location, ":")
+		if i < 0 {
+			continue
+		}
+		lineno, err := strconv.Atoi(location[i+1:])
+		if err != nil {
+			continue
+		}
+		frames = append(frames, sentryFrame{Function: function, Filename: location[:i], Lineno: lineno})
+		function = ""
+	}
+
+	for i, j := 0, len(frames)-1; i < j; i, j = i+1, j-1 {
+		frames[i], frames[j] = frames[j], frames[i]
+	}
+	return frames
+}
+
+// stackFunction returns the function called on a line of a stack, without
+// its arguments, or "" for the goroutine header.
+func stackFunction(line string) string {
+	if strings.HasPrefix(line, "goroutine ") {
+		return ""
+	}
+	if rest, ok := strings.CutPrefix(line, "created by "); ok {
+		if i := strings.Index(rest, " in goroutine "); i >= 0 {
+			rest = rest[:i]
+		}
+		return rest
+	}
+	if i := strings.LastIndex(line, "("); i > 0 {
+		return line[:i]
+	}
+	return line
+}
@@ -0,0 +1,153 @@
+// errtrack/sentry_test.go
+
+package errtrack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime/debug"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewSentry(t *testing.T) {
+	testCases := []struct {
+		desc             string
+		dsn              string
+		expectedEndpoint string
+		expectedErr      string
+	}{
+		{"Hosted", "https://abc@o1.ingest.sentry.io/42", "https://o1.ingest.sentry.io/api/42/envelope/", ""},
+		{"SelfHosted", "http://abc@sentry.internal:9000/sentry/42/", "http://sentry.internal:9000/sentry/api/42/envelope/", ""},
+		{"NoKey", "https://o1.ingest.sentry.io/42", "", "errtrack: sentry dsn has no public key"},
+		{"NoProject", "https://abc@o1.ingest.sentry.io/", "", "errtrack: sentry dsn has no project"},
+		{"Relative", "abc@sentry/42", "", "errtrack: sentry dsn must be an absolute http or https URL"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			s, err := NewSentry(tC.dsn)
+
+			if tC.expectedErr != "" {
+				assert.EqualError(t, err, tC.expectedErr)
+				return
+			}
+			require.NoError(t, err, "Got error when not expected")
+			assert.Equal(t, tC.expectedEndpoint, s.endpoint, "Envelope endpoint is different than expected")
+			assert.Equal(t, "abc", s.key, "Public key is different than expected")
+		})
+	}
+}
+
+// sentryServer answers envelopes with status and decodes the event they carry.
+func sentryServer(t *testing.T, status int, events chan<- sentryEvent) (*httptest.Server, string) {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/42/envelope/", r.URL.Path, "Should post to the project envelope endpoint")
+		assert.Contains(t, r.Header.Get("X-Sentry-Auth"), "sentry_key=abc", "Should authenticate with the public key")
+
+		body, _ := io.ReadAll(r.Body)
+		lines := bytes.Split(bytes.TrimSuffix(body, []byte("\n")), []byte("\n"))
+		if assert.Len(t, lines, 3, "The envelope should hold a header and one item") {
+			var event sentryEvent
+			assert.NoError(t, json.Unmarshal(lines[2], &event), "The item should be an event")
+			events <- event
+		}
+
+		if status != http.StatusOK {
+			w.Header().Set("X-Sentry-Error", "rate limited")
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, strings.Replace(srv.URL, "://", "://abc@", 1) + "/42"
+}
+
+func TestSentry_Send(t *testing.T) {
+	events := make(chan sentryEvent, 1)
+	srv, dsn := sentryServer(t, http.StatusOK, events)
+	s, err := NewSentry(dsn)
+	require.NoError(t, err, "Got error when not expected")
+	s.Client = srv.Client()
+	s.Environment = "staging"
+	s.Release = "1.2.3"
+	s.now = func() time.Time { return time.Date(2019, 9, 1, 10, 0, 0, 0, time.UTC) }
+
+	req := httptest.NewRequest(http.MethodGet, "http://quotes.example.com/quote?token=secret", nil)
+	req.Header.Set("User-Agent", "curl/8.0")
+	cause := &json.SyntaxError{Offset: 3}
+	err = s.Send(context.Background(), Event{
+		Err:     fmt.Errorf("decoding: %w", cause),
+		Stack:   debug.Stack(),
+		Request: req,
+		Tags:    map[string]string{"requestId": "req-1"},
+	})
+
+	require.NoError(t, err, "Got error when not expected")
+	event := <-events
+	assert.Len(t, event.EventID, 32, "Event ID should be a UUID without dashes")
+	assert.Equal(t, "2019-09-01T10:00:00Z", event.Timestamp, "Timestamp is different than expected")
+	assert.Equal(t, "staging", event.Environment, "Environment is different than expected")
+	assert.Equal(t, "1.2.3", event.Release, "Release is different than expected")
+	assert.Equal(t, map[string]string{"requestId": "req-1"}, event.Tags, "Tags are different than expected")
+	require.Len(t, event.Exception.Values, 1, "The error should be one exception")
+	exception := event.Exception.Values[0]
+	assert.Equal(t, "*json.SyntaxError", exception.Type, "Events should be grouped by the root cause")
+	assert.Equal(t, "decoding: "+cause.Error(), exception.Value, "Exception value is different than expected")
+	require.NotNil(t, exception.Stacktrace, "The stack should be sent")
+	frames := exception.Stacktrace.Frames
+	assert.Equal(t, "github.com/jeniok12/golang-tdd-example/errtrack.TestSentry_Send", frames[len(frames)-2].Function, "The innermost frames should come last")
+	assert.Equal(t, &sentryRequest{Method: http.MethodGet, URL: "http://quotes.example.com/quote", Headers: map[string]string{"User-Agent": "curl/8.0"}}, event.Request, "The query string should be left out")
+}
+
+func TestSentry_SendRejected(t *testing.T) {
+	srv, dsn := sentryServer(t, http.StatusTooManyRequests, make(chan sentryEvent, 1))
+	s, _ := NewSentry(dsn)
+	s.Client = srv.Client()
+
+	err := s.Send(context.Background(), Event{Err: errors.New("sample error")})
+
+	assert.EqualError(t, err, "errtrack: sentry rejected event: rate limited (status 429)")
+}
+
+func TestSentry_ReportFlush(t *testing.T) {
+	events := make(chan sentryEvent, 2)
+	srv, dsn := sentryServer(t, http.StatusOK, events)
+	s, _ := NewSentry(dsn)
+	s.Client = srv.Client()
+	s.OnError = func(err error) { t.Errorf("Got error when not expected: %v", err) }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.Report(ctx, Event{Err: errors.New("sample error")})
+	s.Report(ctx, Event{})
+	cancel()
+
+	require.NoError(t, s.Flush(context.Background()), "Flush should wait for the event")
+	assert.Len(t, events, 1, "Only events with an error should be sent, even after the request is done")
+}
+
+func TestParseStack(t *testing.T) {
+	stack := "goroutine 7 [running]:\n" +
+		"main.handler({0x1, 0x2})\n" +
+		"\t/src/main.go:12 +0x1d\n" +
+		"net/http.HandlerFunc.ServeHTTP(0xc0, {0x9, 0xc1})\n" +
+		"\t/go/src/net/http/server.go:2220 +0x29\n" +
+		"created by net/http.(*Server).Serve in goroutine 1\n" +
+		"\t/go/src/net/http/server.go:3360 +0x485\n"
+
+	frames := parseStack([]byte(stack))
+
+	assert.Equal(t, []sentryFrame{
+		{Function: "net/http.(*Server).Serve", Filename: "/go/src/net/http/server.go", Lineno: 3360},
+		{Function: "net/http.HandlerFunc.ServeHTTP", Filename: "/go/src/net/http/server.go", Lineno: 2220},
+		{Function: "main.handler", Filename: "/src/main.go", Lineno: 12},
+	}, frames, "Frames are different than expected")
+}
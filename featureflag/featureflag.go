@@ -0,0 +1,195 @@
+// featureflag/featureflag.go
+
+// Package featureflag turns features on and off without a redeploy. Flags
+// are registered with a default, configured at startup and overridden at
+// runtime; an override wins over the configured value, which wins over the
+// default.
+package featureflag
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrUnknown is returned for flags that were never registered.
+var ErrUnknown = errors.New("featureflag: unknown flag")
+
+// Flag is a registered feature.
+type Flag struct {
+	name        string
+	description string
+	def         bool
+	registry    *Registry
+}
+
+// Name ...
+func (f *Flag) Name() string {
+	return f.name
+}
+
+// Enabled reports whether the feature is on.
+func (f *Flag) Enabled() bool {
+	return f.registry.Enabled(f.name)
+}
+
+// Require returns middleware passing requests on to the next handler only
+// while f is on, and to off otherwise.
+func (f *Flag) Require(off http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !f.Enabled() {
+				off.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// State is the current value of a flag and where it comes from.
+type State struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+	Default     bool   `json:"default"`
+	// Overridden is set when Enabled comes from a runtime override.
+	Overridden bool `json:"overridden"`
+}
+
+// Registry holds the flags and their values. It is safe for concurrent use.
+type Registry struct {
+	mu         sync.RWMutex
+	flags      map[string]*Flag
+	configured map[string]bool
+	overrides  map[string]bool
+}
+
+// NewRegistry returns a registry without flags.
+func NewRegistry() *Registry {
+	return &Registry{
+		flags:      make(map[string]*Flag),
+		configured: make(map[string]bool),
+		overrides:  make(map[string]bool),
+	}
+}
+
+// Register adds a flag, on or off by default. Registering a name twice
+// panics, as it is a programming error.
+func (r *Registry) Register(name, description string, def bool) *Flag {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.flags[name]; ok {
+		panic("featureflag: flag " + name + " registered twice")
+	}
+	f := &Flag{name: name, description: description, def: def, registry: r}
+	r.flags[name] = f
+	return f
+}
+
+// Configure sets flags from specs such as "translation=off" or "cache=true";
+// a bare name turns the flag on. The specs replace those of earlier calls,
+// so flags left out go back to their default. Nothing is set if any spec is
+// invalid.
+func (r *Registry) Configure(specs []string) error {
+	values := make(map[string]bool, len(specs))
+	for _, spec := range specs {
+		name, value, hasValue := strings.Cut(strings.TrimSpace(spec), "=")
+		on := true
+		if hasValue {
+			var err error
+			if on, err = parseValue(value); err != nil {
+				return fmt.Errorf("featureflag: %s: %v", name, err)
+			}
+		}
+		values[name] = on
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for name := range values {
+		if _, ok := r.flags[name]; !ok {
+			return fmt.Errorf("%w %q", ErrUnknown, name)
+		}
+	}
+	r.configured = values
+	return nil
+}
+
+func parseValue(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "on":
+		return true, nil
+	case "off":
+		return false, nil
+	}
+	return strconv.ParseBool(s)
+}
+
+// Enabled reports whether the named flag is on. Unknown flags are off.
+func (r *Registry) Enabled(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.state(name).Enabled
+}
+
+// Override turns the named flag on or off until the override is cleared.
+func (r *Registry) Override(name string, on bool) (State, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.flags[name]; !ok {
+		return State{}, fmt.Errorf("%w %q", ErrUnknown, name)
+	}
+	r.overrides[name] = on
+	return r.state(name), nil
+}
+
+// ClearOverride brings the named flag back to its configured value.
+func (r *Registry) ClearOverride(name string) (State, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.flags[name]; !ok {
+		return State{}, fmt.Errorf("%w %q", ErrUnknown, name)
+	}
+	delete(r.overrides, name)
+	return r.state(name), nil
+}
+
+// States returns every flag, sorted by name.
+func (r *Registry) States() []State {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	states := make([]State, 0, len(r.flags))
+	for name := range r.flags {
+		states = append(states, r.state(name))
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	return states
+}
+
+// state must be called with mu held.
+func (r *Registry) state(name string) State {
+	f, ok := r.flags[name]
+	if !ok {
+		return State{Name: name}
+	}
+
+	s := State{Name: name, Description: f.description, Default: f.def, Enabled: f.def}
+	if on, ok := r.configured[name]; ok {
+		s.Enabled = on
+	}
+	if on, ok := r.overrides[name]; ok {
+		s.Enabled, s.Overridden = on, true
+	}
+	return s
+}
@@ -0,0 +1,112 @@
+// featureflag/featureflag_test.go
+
+package featureflag
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRegistry_Configure(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		specs              []string
+		expectedCache      bool
+		expectedBeta       bool
+		expectedToGetError bool
+	}{
+		{"Defaults", nil, true, false, false},
+		{"OnOff", []string{"cache=off", "beta=on"}, false, true, false},
+		{"Bool", []string{"cache=false", "beta=1"}, false, true, false},
+		{"BareName", []string{"beta"}, true, true, false},
+		{"InvalidValue", []string{"beta=maybe"}, true, false, true},
+		{"UnknownFlag", []string{"beta", "gamma"}, true, false, true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			r := NewRegistry()
+			cache := r.Register("cache", "cache quotes", true)
+			beta := r.Register("beta", "beta endpoints", false)
+
+			err := r.Configure(tC.specs)
+			if tC.expectedToGetError {
+				assert.Error(t, err, "Got no error when expected")
+			} else {
+				assert.NoError(t, err, "Got error when not expected")
+			}
+			assert.Equal(t, tC.expectedCache, cache.Enabled())
+			assert.Equal(t, tC.expectedBeta, beta.Enabled(), "Invalid specs should set nothing")
+		})
+	}
+}
+
+func TestRegistry_Configure_Replaces(t *testing.T) {
+	r := NewRegistry()
+	cache := r.Register("cache", "cache quotes", true)
+	beta := r.Register("beta", "beta endpoints", false)
+
+	assert.NoError(t, r.Configure([]string{"cache=off", "beta"}), "Got error when not expected")
+	assert.NoError(t, r.Configure([]string{"beta"}), "Got error when not expected")
+	assert.True(t, cache.Enabled(), "Flags left out should go back to their default")
+	assert.True(t, beta.Enabled())
+
+	assert.Error(t, r.Configure([]string{"gamma"}), "Got no error when expected")
+	assert.True(t, beta.Enabled(), "Invalid specs should keep the configured values")
+}
+
+func TestRegistry_Override(t *testing.T) {
+	r := NewRegistry()
+	cache := r.Register("cache", "cache quotes", true)
+	r.Configure([]string{"cache=off"})
+
+	s, err := r.Override("cache", true)
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, State{Name: "cache", Description: "cache quotes", Enabled: true, Default: true, Overridden: true}, s)
+	assert.True(t, cache.Enabled(), "Overrides should win over the configured value")
+
+	s, err = r.ClearOverride("cache")
+	assert.NoError(t, err, "Got error when not expected")
+	assert.False(t, s.Enabled, "Clearing the override should restore the configured value")
+	assert.False(t, s.Overridden)
+
+	_, err = r.Override("gamma", true)
+	assert.ErrorIs(t, err, ErrUnknown)
+	assert.False(t, r.Enabled("gamma"), "Unknown flags should be off")
+}
+
+func TestRegistry_States(t *testing.T) {
+	r := NewRegistry()
+	r.Register("cache", "cache quotes", true)
+	r.Register("beta", "beta endpoints", false)
+
+	var names []string
+	for _, s := range r.States() {
+		names = append(names, s.Name)
+	}
+	assert.Equal(t, []string{"beta", "cache"}, names, "States should be sorted by name")
+}
+
+func TestRegistry_RegisterTwice(t *testing.T) {
+	r := NewRegistry()
+	r.Register("cache", "", true)
+
+	assert.Panics(t, func() { r.Register("cache", "", true) })
+}
+
+func TestFlag_Require(t *testing.T) {
+	r := NewRegistry()
+	beta := r.Register("beta", "beta endpoints", false)
+	off := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNotFound) })
+	h := beta.Require(off)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code, "Requests should be turned away while the flag is off")
+
+	r.Override("beta", true)
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusOK, rr.Code, "Requests should pass while the flag is on")
+}
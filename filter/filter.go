@@ -0,0 +1,115 @@
+// filter/filter.go
+
+// Package filter screens text, such as generated quotes, before it reaches
+// users: against a blocklist of words and phrases and optionally an external
+// moderation API.
+package filter
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// Decision is the outcome of screening a text.
+type Decision struct {
+	Allowed bool
+	// Reason says why a text was rejected.
+	Reason string
+}
+
+// Allow ...
+var Allow = Decision{Allowed: true}
+
+// Reject ...
+func Reject(format string, args ...interface{}) Decision {
+	return Decision{Reason: fmt.Sprintf(format, args...)}
+}
+
+// Screener decides whether a text may be shown.
+type Screener interface {
+	Screen(ctx context.Context, text string) (Decision, error)
+}
+
+// Chain rejects a text as soon as one of its screeners does.
+type Chain []Screener
+
+// Screen ...
+func (c Chain) Screen(ctx context.Context, text string) (Decision, error) {
+	for _, s := range c {
+		d, err := s.Screen(ctx, text)
+		if err != nil || !d.Allowed {
+			return d, err
+		}
+	}
+	return Allow, nil
+}
+
+// Blocklist rejects texts containing any of its entries as whole words,
+// ignoring case and punctuation. Entries may be phrases of several words.
+type Blocklist struct {
+	entries []string
+}
+
+// NewBlocklist ...
+func NewBlocklist(entries ...string) *Blocklist {
+	b := &Blocklist{}
+	for _, e := range entries {
+		if e = normalize(e); strings.TrimSpace(e) != "" {
+			b.entries = append(b.entries, e)
+		}
+	}
+	return b
+}
+
+// LoadBlocklist reads a blocklist from a file with an entry per line. Empty
+// lines and lines starting with # are skipped.
+func LoadBlocklist(path string) (*Blocklist, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("filter: reading %s: %w", path, err)
+	}
+	return NewBlocklist(entries...), nil
+}
+
+// Len ...
+func (b *Blocklist) Len() int {
+	return len(b.entries)
+}
+
+// Screen ...
+func (b *Blocklist) Screen(ctx context.Context, text string) (Decision, error) {
+	text = normalize(text)
+	for _, e := range b.entries {
+		if strings.Contains(text, e) {
+			return Reject("blocked %q", strings.TrimSpace(e)), nil
+		}
+	}
+	return Allow, nil
+}
+
+// normalize lowercases s and turns every run of non letters and digits into
+// a single space, with one around the whole, so entries match whole words.
+func normalize(s string) string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return " " + strings.Join(fields, " ") + " "
+}
@@ -0,0 +1,61 @@
+// filter/filter_test.go
+
+package filter
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBlocklist_Screen(t *testing.T) {
+	blocklist := NewBlocklist("damn", "Bad Word", "  ")
+
+	testCases := []struct {
+		desc     string
+		text     string
+		expected Decision
+	}{
+		{"Clean", "Bla Bla Bla", Allow},
+		{"Word", "Well, DAMN!", Reject(`blocked "damn"`)},
+		{"Phrase", "such a bad-word here", Reject(`blocked "bad word"`)},
+		{"PartOfWord", "Amsterdamned", Allow},
+		{"PhraseSplit", "bad words", Allow},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			d, err := blocklist.Screen(context.Background(), tC.text)
+			assert.NoError(t, err, "Got error when not expected")
+			assert.Equal(t, tC.expected, d)
+		})
+	}
+	assert.Equal(t, 2, blocklist.Len(), "Blank entries should be skipped")
+}
+
+func TestLoadBlocklist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	os.WriteFile(path, []byte("# swear words\ndamn\n\n  heck \n"), 0o600)
+
+	blocklist, err := LoadBlocklist(path)
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, 2, blocklist.Len(), "Comments and empty lines should be skipped")
+
+	d, _ := blocklist.Screen(context.Background(), "what the heck")
+	assert.False(t, d.Allowed, "Loaded entries should be blocked")
+
+	_, err = LoadBlocklist(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err, "Got no error when expected")
+}
+
+func TestChain_Screen(t *testing.T) {
+	chain := Chain{NewBlocklist("damn"), NewBlocklist("heck")}
+
+	d, err := chain.Screen(context.Background(), "Bla Bla Bla")
+	assert.NoError(t, err, "Got error when not expected")
+	assert.True(t, d.Allowed)
+
+	d, _ = chain.Screen(context.Background(), "heck")
+	assert.Equal(t, Reject(`blocked "heck"`), d, "Any screener should be able to reject")
+}
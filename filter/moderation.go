@@ -0,0 +1,91 @@
+// filter/moderation.go
+
+package filter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Moderation screens texts with a moderation API speaking the OpenAI
+// moderations format, see
+// https://platform.openai.com/docs/api-reference/moderations.
+type Moderation struct {
+	URL    string
+	Token  string
+	Client *http.Client
+}
+
+type moderationRequest struct {
+	Input string `json:"input"`
+}
+
+type moderationResponse struct {
+	Results []struct {
+		Flagged    bool            `json:"flagged"`
+		Categories map[string]bool `json:"categories"`
+	} `json:"results"`
+}
+
+// Screen ...
+func (m *Moderation) Screen(ctx context.Context, text string) (Decision, error) {
+	body, err := json.Marshal(moderationRequest{Input: text})
+	if err != nil {
+		return Decision{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", m.URL, bytes.NewReader(body))
+	if err != nil {
+		return Decision{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if m.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+m.Token)
+	}
+
+	client := m.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Decision{}, fmt.Errorf("filter: moderation: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Decision{}, fmt.Errorf("filter: moderation answered %d", resp.StatusCode)
+	}
+
+	var res moderationResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+		return Decision{}, fmt.Errorf("filter: moderation: %w", err)
+	}
+	if len(res.Results) == 0 {
+		return Decision{}, errors.New("filter: moderation returned no result")
+	}
+
+	for _, r := range res.Results {
+		if !r.Flagged {
+			continue
+		}
+		var categories []string
+		for c, flagged := range r.Categories {
+			if flagged {
+				categories = append(categories, c)
+			}
+		}
+		sort.Strings(categories)
+		if len(categories) == 0 {
+			return Reject("flagged by moderation"), nil
+		}
+		return Reject("flagged by moderation: %s", strings.Join(categories, ", ")), nil
+	}
+	return Allow, nil
+}
@@ -0,0 +1,58 @@
+// filter/moderation_test.go
+
+package filter
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestModeration_Screen(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		status             int
+		body               string
+		expected           Decision
+		expectedToGetError bool
+	}{
+		{"Allowed", http.StatusOK, `{"results":[{"flagged":false,"categories":{"hate":false}}]}`, Allow, false},
+		{
+			"Flagged",
+			http.StatusOK,
+			`{"results":[{"flagged":true,"categories":{"violence":true,"hate":true,"sexual":false}}]}`,
+			Reject("flagged by moderation: hate, violence"),
+			false,
+		},
+		{"FlaggedWithoutCategories", http.StatusOK, `{"results":[{"flagged":true}]}`, Reject("flagged by moderation"), false},
+		{"NoResults", http.StatusOK, `{"results":[]}`, Decision{}, true},
+		{"InvalidJSON", http.StatusOK, `{`, Decision{}, true},
+		{"ServerError", http.StatusInternalServerError, ``, Decision{}, true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var got moderationRequest
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				assert.Equal(t, "Bearer secret", req.Header.Get("Authorization"))
+				json.NewDecoder(req.Body).Decode(&got)
+				rw.WriteHeader(tC.status)
+				rw.Write([]byte(tC.body))
+			}))
+			defer server.Close()
+
+			moderation := Moderation{URL: server.URL, Token: "secret", Client: server.Client()}
+			d, err := moderation.Screen(context.Background(), "Bla Bla Bla")
+
+			assert.Equal(t, "Bla Bla Bla", got.Input, "Text should be sent as input")
+			if tC.expectedToGetError {
+				assert.Error(t, err, "Got no error when expected")
+				return
+			}
+			assert.NoError(t, err, "Got error when not expected")
+			assert.Equal(t, tC.expected, d)
+		})
+	}
+}
@@ -0,0 +1,115 @@
+// internal/audit/audit.go
+
+// Package audit records who changed what through the API, and when.
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"github.com/jeniok12/golang-tdd-example/internal/dbtx"
+	"time"
+)
+
+// Actions recorded in the log.
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Entities recorded in the log.
+const (
+	EntityRecipient = "recipient"
+	EntityGroup     = "group"
+	EntityQuote     = "quote"
+	// EntityOAuthClient entries name the client by EntityKey.
+	EntityOAuthClient = "oauth_client"
+	// EntityAPIKey entries name the API key by the ID in EntityKey.
+	EntityAPIKey = "api_key"
+)
+
+// Entities lists every entity, for validating filters.
+var Entities = []string{EntityRecipient, EntityGroup, EntityQuote, EntityOAuthClient, EntityAPIKey}
+
+// Entry is one mutation.
+type Entry struct {
+	ID int `json:"id"`
+	// Actor identifies the caller by a fingerprint of their API key, or by
+	// their IP address without one.
+	Actor    string `json:"actor"`
+	Action   string `json:"action"`
+	Entity   string `json:"entity"`
+	EntityID int    `json:"entityId"`
+	// EntityKey identifies entities whose IDs are not numbers.
+	EntityKey string    `json:"entityKey,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+// Filter narrows the entries returned by List. An empty Entity matches every
+// entity and a zero Since matches every time.
+type Filter struct {
+	Entity string
+	Since  time.Time
+	Limit  int
+}
+
+// Store ...
+type Store interface {
+	Record(ctx context.Context, e Entry) error
+	List(ctx context.Context, f Filter) ([]Entry, error)
+}
+
+// Persistence keeps the log in the audit_log table. Its queries run on both
+// Postgres and SQLite; times are stored in UTC so they compare the same way
+// in either.
+type Persistence struct {
+	DB *sql.DB
+
+	// tx, when set, is the transaction the entries are recorded in.
+	tx *sql.Tx
+}
+
+// WithTx returns the Persistence recording entries in tx, so they are only
+// kept if the change they describe is.
+func (p *Persistence) WithTx(tx *sql.Tx) *Persistence {
+	return &Persistence{DB: p.DB, tx: tx}
+}
+
+func (p *Persistence) exec() dbtx.Executor {
+	if p.tx != nil {
+		return p.tx
+	}
+	return p.DB
+}
+
+// Record ...
+func (p *Persistence) Record(ctx context.Context, e Entry) error {
+	query := "INSERT INTO audit_log (actor, action, entity, entity_id, entity_key, at) VALUES ($1, $2, $3, $4, $5, $6)"
+	_, err := p.exec().ExecContext(ctx, query, e.Actor, e.Action, e.Entity, e.EntityID, e.EntityKey, e.At.UTC())
+	return err
+}
+
+// List returns the newest entries first.
+func (p *Persistence) List(ctx context.Context, f Filter) ([]Entry, error) {
+	query := `SELECT id, actor, action, entity, entity_id, entity_key, at FROM audit_log
+		WHERE ($1 = '' OR entity = $1) AND at >= $2
+		ORDER BY at DESC, id DESC
+		LIMIT $3`
+
+	rows, err := p.exec().QueryContext(ctx, query, f.Entity, f.Since.UTC(), f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []Entry{}
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.ID, &e.Actor, &e.Action, &e.Entity, &e.EntityID, &e.EntityKey, &e.At); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
@@ -0,0 +1,70 @@
+// internal/audit/audit_test.go
+
+package audit
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/internal/dbtest"
+	"github.com/jeniok12/golang-tdd-example/internal/pgtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Main(m))
+}
+
+func TestPersistence(t *testing.T) {
+	for _, d := range dbtest.Dialects {
+		t.Run(d.Name, func(t *testing.T) {
+			testPersistence(t, &Persistence{DB: d.Open(t)})
+		})
+	}
+}
+
+func testPersistence(t *testing.T, p *Persistence) {
+	ctx := context.Background()
+	start := time.Date(2019, 6, 23, 10, 0, 0, 0, time.UTC)
+	for i, e := range []Entry{
+		{Actor: "ip:10.0.0.1", Action: ActionCreate, Entity: EntityRecipient, EntityID: 1},
+		{Actor: "ip:10.0.0.1", Action: ActionCreate, Entity: EntityGroup, EntityID: 1},
+		{Actor: "ip:10.0.0.2", Action: ActionDelete, Entity: EntityRecipient, EntityID: 1},
+	} {
+		e.At = start.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, p.Record(ctx, e), "Got error when not expected")
+	}
+
+	testCases := []struct {
+		desc          string
+		filter        Filter
+		expectedIDs   []int
+		expectedFirst Entry
+	}{
+		{"All", Filter{Limit: 10}, []int{3, 2, 1}, Entry{ID: 3, Actor: "ip:10.0.0.2", Action: ActionDelete, Entity: EntityRecipient, EntityID: 1, At: start.Add(2 * time.Minute)}},
+		{"Entity", Filter{Entity: EntityRecipient, Limit: 10}, []int{3, 1}, Entry{}},
+		{"Since", Filter{Since: start.Add(time.Minute), Limit: 10}, []int{3, 2}, Entry{}},
+		{"SinceInAnotherZone", Filter{Since: start.Add(time.Minute).In(time.FixedZone("CEST", 2*60*60)), Limit: 10}, []int{3, 2}, Entry{}},
+		{"Limit", Filter{Limit: 1}, []int{3}, Entry{}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			entries, err := p.List(ctx, tC.filter)
+			require.NoError(t, err, "Got error when not expected")
+
+			ids := []int{}
+			for _, e := range entries {
+				ids = append(ids, e.ID)
+			}
+			assert.Equal(t, tC.expectedIDs, ids)
+
+			if tC.expectedFirst.ID != 0 {
+				assert.Equal(t, tC.expectedFirst.At.Unix(), entries[0].At.Unix(), "Wrong time")
+				entries[0].At = tC.expectedFirst.At
+				assert.Equal(t, tC.expectedFirst, entries[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// internal/auth/auth.go
+
+// Package auth authenticates API clients with JWTs, whose claims grant them
+// a role, and tells whether a role may call a route.
+package auth
+
+import (
+	"context"
+	"time"
+)
+
+// Role is what a client may do through the API.
+type Role string
+
+// Roles, each allowed all the previous one is.
+const (
+	// RoleReader may only read.
+	RoleReader Role = "reader"
+	// RoleEditor may also change recipients and send quotes.
+	RoleEditor Role = "editor"
+	// RoleAdmin may also call the admin routes.
+	RoleAdmin Role = "admin"
+)
+
+var ranks = map[Role]int{RoleReader: 1, RoleEditor: 2, RoleAdmin: 3}
+
+// Allows reports whether r may do what takes the required role. An unknown
+// or empty role allows nothing.
+func (r Role) Allows(required Role) bool {
+	return ranks[r] > 0 && ranks[r] >= ranks[required]
+}
+
+// Valid reports whether r is one of the roles above.
+func (r Role) Valid() bool {
+	return ranks[r] > 0
+}
+
+// Principal is an authenticated client.
+type Principal struct {
+	Subject string
+	// Role is the highest role granted by the token, empty for none.
+	Role Role
+	// TokenID and ExpiresAt identify the token, to revoke it.
+	TokenID   string
+	ExpiresAt time.Time
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying p.
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the principal of ctx, or nil for anonymous requests.
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(contextKey{}).(*Principal)
+	return p
+}
@@ -0,0 +1,18 @@
+// internal/auth/auth_test.go
+
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRole_Allows(t *testing.T) {
+	assert.True(t, RoleAdmin.Allows(RoleEditor), "Admins should do what editors do")
+	assert.True(t, RoleEditor.Allows(RoleReader), "Editors should do what readers do")
+	assert.True(t, RoleReader.Allows(RoleReader), "Readers should read")
+	assert.False(t, RoleReader.Allows(RoleEditor), "Readers should not edit")
+	assert.False(t, RoleEditor.Allows(RoleAdmin), "Editors should not administer")
+	assert.False(t, Role("").Allows(RoleReader), "No role should allow nothing")
+	assert.False(t, Role("owner").Allows(RoleReader), "Unknown roles should allow nothing")
+}
@@ -0,0 +1,121 @@
+// internal/auth/issuer.go
+
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/clock"
+	"strings"
+	"time"
+)
+
+// Issuer signs the tokens the server hands out itself, with ES256. It is
+// the KeySet of its own tokens.
+type Issuer struct {
+	// Name is the iss claim of the tokens.
+	Name  string
+	TTL   time.Duration
+	Clock clock.Clock
+
+	key   *ecdsa.PrivateKey
+	keyID string
+}
+
+// NewIssuer ...
+func NewIssuer(name string, key *ecdsa.PrivateKey, ttl time.Duration) (*Issuer, error) {
+	if key.Curve != elliptic.P256() {
+		return nil, errors.New("auth: token signing key must be on curve P-256")
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("auth: token TTL must be positive, got %s", ttl)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(der)
+	return &Issuer{Name: name, TTL: ttl, Clock: clock.Real, key: key, keyID: hex.EncodeToString(sum[:8])}, nil
+}
+
+// ParseKey reads a P-256 private key from PEM, in PKCS #8 or SEC 1 form.
+func ParseKey(pemKey []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(pemKey)
+	if block == nil {
+		return nil, errors.New("auth: token signing key is not PEM")
+	}
+	if key, err := x509.ParseECPrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("auth: parsing token signing key: %w", err)
+	}
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("auth: token signing key is not an EC key")
+	}
+	return ecKey, nil
+}
+
+// Issue returns a token for subject granting roles, and when it expires.
+func (i *Issuer) Issue(subject string, roles []string) (string, time.Time, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", time.Time{}, err
+	}
+	now := i.Clock.Now()
+	expires := now.Add(i.TTL)
+
+	header, err := json.Marshal(map[string]string{"alg": "ES256", "typ": "JWT", "kid": i.keyID})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	claims, err := json.Marshal(map[string]interface{}{
+		"iss":   i.Name,
+		"sub":   subject,
+		"jti":   hex.EncodeToString(id),
+		"iat":   now.Unix(),
+		"exp":   expires.Unix(),
+		"roles": roles,
+		"scope": strings.Join(roles, " "),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signed))
+	r, s, err := ecdsa.Sign(rand.Reader, i.key, digest[:])
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	sig := append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig), time.Unix(expires.Unix(), 0), nil
+}
+
+// Key returns the public key of the issuer, its only one.
+func (i *Issuer) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	if kid != i.keyID {
+		return nil, ErrUnknownKey
+	}
+	return &i.key.PublicKey, nil
+}
+
+// Verifier checks the tokens of the issuer, rejecting those in revocations
+// when it is not nil.
+func (i *Issuer) Verifier(revocations Revocations) *Verifier {
+	return &Verifier{Issuer: i.Name, Keys: i, Clock: i.Clock, Revocations: revocations}
+}
@@ -0,0 +1,86 @@
+// internal/auth/issuer_test.go
+
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/jeniok12/golang-tdd-example/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+type revokedIDs map[string]bool
+
+func (r revokedIDs) Revoked(ctx context.Context, tokenID string) (bool, error) {
+	return r[tokenID], nil
+}
+
+func TestIssuer(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err, "Got error when not expected")
+	issuer, err := NewIssuer("https://quotes.test", key, 15*time.Minute)
+	require.NoError(t, err, "Got error when not expected")
+	now := clock.NewMock(time.Now())
+	issuer.Clock = now
+	revoked := revokedIDs{}
+	v := issuer.Verifier(revoked)
+
+	token, expires, err := issuer.Issue("client-1", []string{"reader", "editor"})
+	require.NoError(t, err, "Got error when not expected")
+	assert.WithinDuration(t, now.Now().Add(15*time.Minute), expires, time.Second, "Expiry is different than expected")
+
+	p, err := v.Verify(context.Background(), token)
+	require.NoError(t, err, "Issued tokens should verify")
+	assert.Equal(t, "client-1", p.Subject, "Subject is different than expected")
+	assert.Equal(t, RoleEditor, p.Role, "Role is different than expected")
+	assert.NotEmpty(t, p.TokenID, "Issued tokens should have an ID")
+	assert.Equal(t, expires, p.ExpiresAt, "Expiry is different than expected")
+
+	other, err := NewIssuer("https://other.test", key, time.Minute)
+	require.NoError(t, err, "Got error when not expected")
+	_, err = other.Verifier(nil).Verify(context.Background(), token)
+	assert.Equal(t, ErrInvalidToken, err, "Tokens of other issuers should be rejected")
+
+	revoked[p.TokenID] = true
+	_, err = v.Verify(context.Background(), token)
+	assert.Equal(t, ErrRevokedToken, err, "Revoked tokens should be rejected")
+
+	delete(revoked, p.TokenID)
+	now.Add(time.Hour)
+	_, err = v.Verify(context.Background(), token)
+	assert.Equal(t, ErrExpiredToken, err, "Tokens should expire")
+}
+
+func TestNewIssuer_Invalid(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	require.NoError(t, err, "Got error when not expected")
+	_, err = NewIssuer("https://quotes.test", key, time.Minute)
+	assert.Error(t, err, "Keys on other curves should be rejected")
+}
+
+func TestParseKey(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err, "Got error when not expected")
+
+	sec1, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err, "Got error when not expected")
+	parsed, err := ParseKey(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1}))
+	require.NoError(t, err, "Got error when not expected")
+	assert.True(t, key.Equal(parsed), "SEC 1 key is different than expected")
+
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err, "Got error when not expected")
+	parsed, err = ParseKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}))
+	require.NoError(t, err, "Got error when not expected")
+	assert.True(t, key.Equal(parsed), "PKCS #8 key is different than expected")
+
+	_, err = ParseKey([]byte("not a key"))
+	assert.Error(t, err, "Got no error when expected")
+}
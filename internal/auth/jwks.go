@@ -0,0 +1,190 @@
+// internal/auth/jwks.go
+
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/clock"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// JWKS is the KeySet published by an issuer as a JSON Web Key Set. Keys are
+// fetched when first needed, then again every TTL, or sooner for a token
+// naming a key not seen yet, which is how issuers roll their keys.
+type JWKS struct {
+	URL    string
+	Client *http.Client
+	// TTL is how long fetched keys are used for, one hour when zero.
+	TTL time.Duration
+	// MinRefresh keeps tokens naming unknown keys from refetching the set
+	// more often than that, one minute when zero.
+	MinRefresh time.Duration
+	Clock      clock.Clock
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+}
+
+// NewJWKS ...
+func NewJWKS(url string, client *http.Client) *JWKS {
+	return &JWKS{URL: url, Client: client, TTL: time.Hour, MinRefresh: time.Minute, Clock: clock.Real}
+}
+
+// Key returns the key with ID kid. When the set cannot be refetched, the
+// keys fetched before are used until the issuer is back.
+func (j *JWKS) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	now := j.now()
+	key, ok := j.keys[kid]
+	age := now.Sub(j.fetched)
+	switch {
+	case ok && age < j.ttl():
+		return key, nil
+	case !ok && age < j.minRefresh():
+		return nil, ErrUnknownKey
+	}
+
+	keys, err := j.fetch(ctx)
+	if err != nil {
+		if ok {
+			return key, nil
+		}
+		return nil, err
+	}
+	j.keys, j.fetched = keys, now
+	key, ok = j.keys[kid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
+
+func (j *JWKS) now() time.Time {
+	if j.Clock == nil {
+		return time.Now()
+	}
+	return j.Clock.Now()
+}
+
+func (j *JWKS) ttl() time.Duration {
+	if j.TTL <= 0 {
+		return time.Hour
+	}
+	return j.TTL
+}
+
+func (j *JWKS) minRefresh() time.Duration {
+	if j.MinRefresh <= 0 {
+		return time.Minute
+	}
+	return j.MinRefresh
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	// RSA keys.
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC keys.
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+func (j *JWKS) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", j.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	client := j.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("auth: fetching JWKS: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("auth: fetching JWKS: status %d", resp.StatusCode)
+	}
+
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("auth: decoding JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		// Keys of unsupported types are skipped, tokens signed with them
+		// are then rejected as signed by an unknown key.
+		if key, err := k.publicKey(); err == nil {
+			keys[k.Kid] = key
+		}
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("auth: RSA key %q has an invalid exponent", k.Kid)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Crv != "P-256" {
+			return nil, fmt.Errorf("auth: EC key %q is on unsupported curve %q", k.Kid, k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !elliptic.P256().IsOnCurve(x, y) {
+			return nil, fmt.Errorf("auth: EC key %q is not on its curve", k.Kid)
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("auth: key %q has unsupported type %q", k.Kid, k.Kty)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, fmt.Errorf("auth: invalid JWK number %q", s)
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,79 @@
+// internal/auth/jwks_test.go
+
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/jeniok12/golang-tdd-example/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func jwkOf(kid string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func TestJWKS_Key(t *testing.T) {
+	first, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err, "Got error when not expected")
+	second, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err, "Got error when not expected")
+
+	var fetches atomic.Int32
+	var down atomic.Bool
+	keys := []map[string]string{jwkOf("first", &first.PublicKey), {"kty": "oct", "kid": "secret"}}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		if down.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": keys})
+	}))
+	defer srv.Close()
+
+	now := clock.NewMock(time.Now())
+	jwks := NewJWKS(srv.URL, srv.Client())
+	jwks.Clock = now
+	ctx := context.Background()
+
+	key, err := jwks.Key(ctx, "first")
+	require.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, &first.PublicKey, key, "Key is different than expected")
+	_, err = jwks.Key(ctx, "first")
+	require.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, int32(1), fetches.Load(), "Keys should be cached")
+
+	_, err = jwks.Key(ctx, "secret")
+	assert.Equal(t, ErrUnknownKey, err, "Unsupported keys should be skipped")
+	assert.Equal(t, int32(1), fetches.Load(), "Unknown keys should not refetch right away")
+
+	keys = append(keys, jwkOf("second", &second.PublicKey))
+	now.Add(2 * time.Minute)
+	key, err = jwks.Key(ctx, "second")
+	require.NoError(t, err, "Rolled keys should be fetched")
+	assert.Equal(t, &second.PublicKey, key, "Key is different than expected")
+	assert.Equal(t, int32(2), fetches.Load(), "Unknown keys should refetch the set")
+
+	down.Store(true)
+	now.Add(2 * time.Hour)
+	_, err = jwks.Key(ctx, "first")
+	assert.NoError(t, err, "Known keys should be used while the issuer is down")
+	assert.Equal(t, int32(3), fetches.Load(), "Expired keys should be refetched")
+}
@@ -0,0 +1,226 @@
+// internal/auth/jwt.go
+
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/clock"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// ErrInvalidToken is returned for malformed tokens, tokens with a bad
+// signature and tokens meant for another issuer or audience.
+var ErrInvalidToken = errors.New("invalid token")
+
+// ErrExpiredToken is returned for genuine tokens past their expiry.
+var ErrExpiredToken = errors.New("token expired")
+
+// ErrRevokedToken is returned for genuine tokens revoked before their expiry.
+var ErrRevokedToken = errors.New("token revoked")
+
+// ErrUnknownKey is returned by a KeySet without the key a token names.
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// KeySet finds the public key a token was signed with.
+type KeySet interface {
+	Key(ctx context.Context, kid string) (crypto.PublicKey, error)
+}
+
+// Revocations tells whether the token with an ID was revoked.
+type Revocations interface {
+	Revoked(ctx context.Context, tokenID string) (bool, error)
+}
+
+// Verifier checks JWTs signed with RS256 or ES256 by the keys of an issuer.
+type Verifier struct {
+	Issuer string
+	// Audience, when set, must be one of the token audiences.
+	Audience string
+	// RolesClaim names the claim listing the roles of the subject, "roles"
+	// when empty. It may hold a single role or a list of them.
+	RolesClaim string
+	Keys       KeySet
+	// Leeway absorbs clock skew with the issuer.
+	Leeway time.Duration
+	Clock  clock.Clock
+	// Revocations, when set, rejects revoked tokens. Tokens without an ID
+	// cannot be revoked.
+	Revocations Revocations
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+type jwtClaims struct {
+	ID        string          `json:"jti"`
+	Issuer    string          `json:"iss"`
+	Subject   string          `json:"sub"`
+	Audience  json.RawMessage `json:"aud"`
+	ExpiresAt *float64        `json:"exp"`
+	NotBefore *float64        `json:"nbf"`
+}
+
+// Verify checks token and returns the principal it authenticates. Tokens of
+// other issuers are rejected before looking up their key, so several
+// verifiers can be tried in turn.
+func (v *Verifier) Verify(ctx context.Context, token string) (*Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrInvalidToken
+	}
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if claims.Issuer != v.Issuer || (v.Audience != "" && !hasAudience(claims.Audience, v.Audience)) {
+		return nil, ErrInvalidToken
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	key, err := v.Keys.Key(ctx, header.Kid)
+	if errors.Is(err, ErrUnknownKey) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !verifySignature(header.Alg, key, parts[0]+"."+parts[1], sig) {
+		return nil, ErrInvalidToken
+	}
+
+	// Tokens that never expire are not accepted.
+	if claims.ExpiresAt == nil {
+		return nil, ErrInvalidToken
+	}
+	now := v.now()
+	if now.After(unixTime(*claims.ExpiresAt).Add(v.Leeway)) {
+		return nil, ErrExpiredToken
+	}
+	if claims.NotBefore != nil && now.Add(v.Leeway).Before(unixTime(*claims.NotBefore)) {
+		return nil, ErrInvalidToken
+	}
+
+	if v.Revocations != nil && claims.ID != "" {
+		revoked, err := v.Revocations.Revoked(ctx, claims.ID)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, ErrRevokedToken
+		}
+	}
+
+	var raw map[string]json.RawMessage
+	if err := decodeSegment(parts[1], &raw); err != nil {
+		return nil, ErrInvalidToken
+	}
+	return &Principal{
+		Subject:   claims.Subject,
+		Role:      highestRole(raw[v.rolesClaim()]),
+		TokenID:   claims.ID,
+		ExpiresAt: unixTime(*claims.ExpiresAt),
+	}, nil
+}
+
+func (v *Verifier) now() time.Time {
+	if v.Clock == nil {
+		return time.Now()
+	}
+	return v.Clock.Now()
+}
+
+func (v *Verifier) rolesClaim() string {
+	if v.RolesClaim == "" {
+		return "roles"
+	}
+	return v.RolesClaim
+}
+
+func decodeSegment(seg string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return err
+	}
+	return json.NewDecoder(bytes.NewReader(b)).Decode(v)
+}
+
+func verifySignature(alg string, key crypto.PublicKey, signed string, sig []byte) bool {
+	digest := sha256.Sum256([]byte(signed))
+	switch alg {
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		return ok && rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) == nil
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		// JWS signatures are r and s side by side, 32 bytes each for P-256.
+		if !ok || pub.Curve.Params().BitSize != 256 || len(sig) != 64 {
+			return false
+		}
+		r, s := new(big.Int).SetBytes(sig[:32]), new(big.Int).SetBytes(sig[32:])
+		return ecdsa.Verify(pub, digest[:], r, s)
+	default:
+		// Including "none", and HS256 with a public key as the secret.
+		return false
+	}
+}
+
+// hasAudience reports whether aud, a string or a list of them, holds want.
+func hasAudience(aud json.RawMessage, want string) bool {
+	var one string
+	if json.Unmarshal(aud, &one) == nil {
+		return one == want
+	}
+	var many []string
+	if json.Unmarshal(aud, &many) != nil {
+		return false
+	}
+	for _, a := range many {
+		if a == want {
+			return true
+		}
+	}
+	return false
+}
+
+// highestRole returns the highest known role in claim, a string or a list of
+// them, ignoring the others.
+func highestRole(claim json.RawMessage) Role {
+	var roles []string
+	var one string
+	if json.Unmarshal(claim, &one) == nil {
+		roles = []string{one}
+	} else if json.Unmarshal(claim, &roles) != nil {
+		return ""
+	}
+
+	var highest Role
+	for _, r := range roles {
+		if ranks[Role(r)] > ranks[highest] {
+			highest = Role(r)
+		}
+	}
+	return highest
+}
+
+func unixTime(secs float64) time.Time {
+	return time.Unix(0, int64(secs*float64(time.Second)))
+}
@@ -0,0 +1,132 @@
+// internal/auth/jwt_test.go
+
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/jeniok12/golang-tdd-example/clock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// staticKeys is a KeySet of fixed keys.
+type staticKeys map[string]crypto.PublicKey
+
+func (k staticKeys) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	if key, ok := k[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+func segment(t *testing.T, v interface{}) string {
+	b, err := json.Marshal(v)
+	require.NoError(t, err, "Got error when not expected")
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// sign returns a JWT of claims signed with key, an RSA or a P-256 key.
+func sign(t *testing.T, key crypto.Signer, kid string, claims map[string]interface{}) string {
+	alg := "RS256"
+	if _, ok := key.(*ecdsa.PrivateKey); ok {
+		alg = "ES256"
+	}
+	signed := segment(t, map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + segment(t, claims)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:])
+		require.NoError(t, err, "Got error when not expected")
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		require.NoError(t, err, "Got error when not expected")
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err, "Got error when not expected")
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err, "Got error when not expected")
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err, "Got error when not expected")
+
+	now := time.Date(2019, 9, 1, 10, 0, 0, 0, time.UTC)
+	v := &Verifier{
+		Issuer:   "https://issuer.test/",
+		Audience: "quotes",
+		Keys:     staticKeys{"rsa": &rsaKey.PublicKey, "ec": &ecKey.PublicKey},
+		Leeway:   time.Minute,
+		Clock:    clock.NewMock(now),
+	}
+	claims := func(overrides map[string]interface{}) map[string]interface{} {
+		c := map[string]interface{}{
+			"iss":   "https://issuer.test/",
+			"aud":   []string{"quotes", "other"},
+			"sub":   "alice",
+			"exp":   now.Add(time.Hour).Unix(),
+			"roles": []string{"reader", "editor"},
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(c, k)
+				continue
+			}
+			c[k] = v
+		}
+		return c
+	}
+
+	testCases := []struct {
+		desc         string
+		token        string
+		expectedRole Role
+		expectedErr  error
+	}{
+		{"RS256", sign(t, rsaKey, "rsa", claims(nil)), RoleEditor, nil},
+		{"ES256", sign(t, ecKey, "ec", claims(nil)), RoleEditor, nil},
+		{"SingleRole", sign(t, rsaKey, "rsa", claims(map[string]interface{}{"roles": "admin"})), RoleAdmin, nil},
+		{"UnknownRoles", sign(t, rsaKey, "rsa", claims(map[string]interface{}{"roles": []string{"owner"}})), "", nil},
+		{"NoRoles", sign(t, rsaKey, "rsa", claims(map[string]interface{}{"roles": nil})), "", nil},
+		{"SingleAudience", sign(t, rsaKey, "rsa", claims(map[string]interface{}{"aud": "quotes"})), RoleEditor, nil},
+		{"WithinLeeway", sign(t, rsaKey, "rsa", claims(map[string]interface{}{"exp": now.Add(-30 * time.Second).Unix()})), RoleEditor, nil},
+		{"Expired", sign(t, rsaKey, "rsa", claims(map[string]interface{}{"exp": now.Add(-time.Hour).Unix()})), "", ErrExpiredToken},
+		{"NoExpiry", sign(t, rsaKey, "rsa", claims(map[string]interface{}{"exp": nil})), "", ErrInvalidToken},
+		{"NotYetValid", sign(t, rsaKey, "rsa", claims(map[string]interface{}{"nbf": now.Add(time.Hour).Unix()})), "", ErrInvalidToken},
+		{"OtherIssuer", sign(t, rsaKey, "rsa", claims(map[string]interface{}{"iss": "https://evil.test/"})), "", ErrInvalidToken},
+		{"OtherAudience", sign(t, rsaKey, "rsa", claims(map[string]interface{}{"aud": "billing"})), "", ErrInvalidToken},
+		{"UnknownKey", sign(t, rsaKey, "gone", claims(nil)), "", ErrInvalidToken},
+		{"WrongKey", sign(t, otherKey, "rsa", claims(nil)), "", ErrInvalidToken},
+		{"KeyOfOtherType", sign(t, rsaKey, "ec", claims(nil)), "", ErrInvalidToken},
+		{"Unsigned", segment(t, map[string]string{"alg": "none"}) + "." + segment(t, claims(nil)) + ".", "", ErrInvalidToken},
+		{"Malformed", "not.a-token", "", ErrInvalidToken},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			p, err := v.Verify(context.Background(), tC.token)
+
+			if tC.expectedErr != nil {
+				assert.Equal(t, tC.expectedErr, err, "Error is different than expected")
+				return
+			}
+			require.NoError(t, err, "Got error when not expected")
+			assert.Equal(t, "alice", p.Subject, "Subject is different than expected")
+			assert.Equal(t, tC.expectedRole, p.Role, "Role is different than expected")
+		})
+	}
+}
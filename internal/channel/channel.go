@@ -0,0 +1,146 @@
+// internal/channel/channel.go
+
+// Package channel delivers quotes to chat apps, next to the emails sent by
+// the mailer. Each recipient picks its channels in its preferences.
+package channel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Sender posts a text message to a recipient on one channel.
+type Sender interface {
+	Send(ctx context.Context, r recipient.Recipient, text string) error
+}
+
+// Error is a failure reported by a chat API.
+type Error struct {
+	Channel string
+	Status  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.Status)
+	}
+	return fmt.Sprintf("channel: %s: %s (status %d)", e.Channel, msg, e.Status)
+}
+
+// Slack posts to the incoming webhook of each recipient, see
+// https://api.slack.com/messaging/webhooks.
+type Slack struct {
+	Client *http.Client
+}
+
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+// Send ...
+func (s *Slack) Send(ctx context.Context, r recipient.Recipient, text string) error {
+	if r.SlackWebhook == "" {
+		return fmt.Errorf("channel: recipient %d has no slack webhook", r.ID)
+	}
+
+	resp, err := postJSON(ctx, s.Client, r.SlackWebhook, slackMessage{Text: text})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	// Slack answers failures with a plain text reason, like invalid_token.
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return &Error{Channel: recipient.ChannelSlack, Status: resp.StatusCode, Message: strings.TrimSpace(string(body))}
+}
+
+// DefaultTelegramURL is the Telegram Bot API.
+const DefaultTelegramURL = "https://api.telegram.org"
+
+// Telegram sends messages through a bot, see
+// https://core.telegram.org/bots/api#sendmessage. Recipients have to start a
+// chat with the bot first.
+type Telegram struct {
+	Token string
+	// URL defaults to DefaultTelegramURL.
+	URL    string
+	Client *http.Client
+}
+
+type telegramMessage struct {
+	ChatID string `json:"chat_id"`
+	Text   string `json:"text"`
+}
+
+type telegramResponse struct {
+	OK          bool   `json:"ok"`
+	Description string `json:"description"`
+}
+
+// Send ...
+func (t *Telegram) Send(ctx context.Context, r recipient.Recipient, text string) error {
+	if r.TelegramChatID == "" {
+		return fmt.Errorf("channel: recipient %d has no telegram chat id", r.ID)
+	}
+
+	endpoint := t.URL
+	if endpoint == "" {
+		endpoint = DefaultTelegramURL
+	}
+	endpoint = strings.TrimSuffix(endpoint, "/") + "/bot" + t.Token + "/sendMessage"
+
+	resp, err := postJSON(ctx, t.Client, endpoint, telegramMessage{ChatID: r.TelegramChatID, Text: text})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var res telegramResponse
+	decodeErr := json.NewDecoder(resp.Body).Decode(&res)
+	if resp.StatusCode/100 == 2 && decodeErr == nil && res.OK {
+		return nil
+	}
+	return &Error{Channel: recipient.ChannelTelegram, Status: resp.StatusCode, Message: res.Description}
+}
+
+// postJSON POSTs v as JSON to url. The caller closes the response body.
+func postJSON(ctx context.Context, c *http.Client, endpoint string, v interface{}) (*http.Response, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if c == nil {
+		c = http.DefaultClient
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		// Drop the URL from the error, it holds the bot token or the
+		// webhook secret.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("channel: posting message: %w", err)
+	}
+	return resp, nil
+}
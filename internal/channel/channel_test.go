@@ -0,0 +1,101 @@
+// internal/channel/channel_test.go
+
+package channel
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSlack_Send(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		status      int
+		body        string
+		expectedErr string
+	}{
+		{"Posted", http.StatusOK, "ok", ""},
+		{"InvalidToken", http.StatusForbidden, "invalid_token", "channel: slack: invalid_token (status 403)"},
+		{"Gone", http.StatusGone, "", "channel: slack: Gone (status 410)"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var got slackMessage
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, http.MethodPost, r.Method, "Should have different request method")
+				assert.Equal(t, "/services/T0/B0/x", r.URL.Path, "Should post to the recipient's webhook")
+				json.NewDecoder(r.Body).Decode(&got)
+				w.WriteHeader(tC.status)
+				w.Write([]byte(tC.body))
+			}))
+			defer srv.Close()
+
+			rcpt := recipient.Recipient{ID: 1, Preferences: recipient.Preferences{SlackWebhook: srv.URL + "/services/T0/B0/x"}}
+			err := (&Slack{Client: srv.Client()}).Send(context.Background(), rcpt, "Bla - Me")
+
+			assert.Equal(t, "Bla - Me", got.Text, "Message text is different than expected")
+			if tC.expectedErr == "" {
+				assert.NoError(t, err, "Got error when not expected")
+				return
+			}
+			assert.EqualError(t, err, tC.expectedErr)
+		})
+	}
+}
+
+func TestSlack_SendWithoutWebhook(t *testing.T) {
+	err := (&Slack{}).Send(context.Background(), recipient.Recipient{ID: 1}, "Bla")
+
+	assert.EqualError(t, err, "channel: recipient 1 has no slack webhook")
+}
+
+func TestTelegram_Send(t *testing.T) {
+	testCases := []struct {
+		desc        string
+		status      int
+		body        string
+		expectedErr string
+	}{
+		{"Sent", http.StatusOK, `{"ok":true,"result":{}}`, ""},
+		{"ChatNotFound", http.StatusBadRequest, `{"ok":false,"description":"Bad Request: chat not found"}`, "channel: telegram: Bad Request: chat not found (status 400)"},
+		{"NotJSON", http.StatusBadGateway, "<html></html>", "channel: telegram: Bad Gateway (status 502)"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var got telegramMessage
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/bottoken/sendMessage", r.URL.Path, "Should call sendMessage of the bot")
+				json.NewDecoder(r.Body).Decode(&got)
+				w.WriteHeader(tC.status)
+				w.Write([]byte(tC.body))
+			}))
+			defer srv.Close()
+
+			rcpt := recipient.Recipient{ID: 1, Preferences: recipient.Preferences{TelegramChatID: "42"}}
+			err := (&Telegram{Token: "token", URL: srv.URL, Client: srv.Client()}).Send(context.Background(), rcpt, "Bla - Me")
+
+			assert.Equal(t, telegramMessage{ChatID: "42", Text: "Bla - Me"}, got, "Message is different than expected")
+			if tC.expectedErr == "" {
+				assert.NoError(t, err, "Got error when not expected")
+				return
+			}
+			assert.EqualError(t, err, tC.expectedErr)
+		})
+	}
+}
+
+func TestTelegram_SendUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	rcpt := recipient.Recipient{ID: 1, Preferences: recipient.Preferences{TelegramChatID: "42"}}
+	err := (&Telegram{Token: "s3cret", URL: srv.URL}).Send(context.Background(), rcpt, "Bla")
+
+	assert.Error(t, err, "Got no error when expected")
+	assert.NotContains(t, err.Error(), "s3cret", "The bot token should not leak into errors")
+}
@@ -0,0 +1,647 @@
+// internal/config/config.go
+
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration is a time.Duration that reads from files as "30s", "5m" etc.
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalJSON ...
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	return d.Set(s)
+}
+
+// UnmarshalYAML ...
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	return d.Set(value.Value)
+}
+
+// MarshalJSON ...
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// Set ...
+func (d *Duration) Set(s string) error {
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+// SMTP ...
+type SMTP struct {
+	Host     string `json:"host" yaml:"host"`
+	Port     int    `json:"port" yaml:"port"`
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password" secret:"true"`
+	From     string `json:"from" yaml:"from"`
+}
+
+// SendGrid configures sending emails through SendGrid.
+type SendGrid struct {
+	APIKey string `json:"apiKey" yaml:"apiKey" secret:"true"`
+}
+
+// SES configures sending emails through Amazon SES.
+type SES struct {
+	Region          string `json:"region" yaml:"region"`
+	AccessKeyID     string `json:"accessKeyId" yaml:"accessKeyId"`
+	SecretAccessKey string `json:"secretAccessKey" yaml:"secretAccessKey" secret:"true"`
+}
+
+// Redis ...
+type Redis struct {
+	Addr     string `json:"addr" yaml:"addr"`
+	Password string `json:"password" yaml:"password" secret:"true"`
+	DB       int    `json:"db" yaml:"db"`
+}
+
+// Translate configures translating quotes with a LibreTranslate server.
+type Translate struct {
+	URL      string   `json:"url" yaml:"url"`
+	APIKey   string   `json:"apiKey" yaml:"apiKey" secret:"true"`
+	CacheTTL Duration `json:"cacheTtl" yaml:"cacheTtl"`
+}
+
+// JWT configures authenticating clients with JWTs from an identity provider.
+type JWT struct {
+	Issuer     string `json:"issuer" yaml:"issuer"`
+	JWKSURL    string `json:"jwksUrl" yaml:"jwksUrl"`
+	Audience   string `json:"audience" yaml:"audience"`
+	RolesClaim string `json:"rolesClaim" yaml:"rolesClaim"`
+}
+
+// OAuth configures handing out tokens to machine clients with the OAuth2
+// client credentials grant.
+type OAuth struct {
+	Enabled bool `json:"enabled" yaml:"enabled"`
+	// Issuer names the server in its tokens, the public URL when empty.
+	Issuer string `json:"issuer" yaml:"issuer"`
+	// SigningKey is a PEM encoded P-256 private key.
+	SigningKey string   `json:"signingKey" yaml:"signingKey" secret:"true"`
+	TokenTTL   Duration `json:"tokenTtl" yaml:"tokenTtl"`
+	// RotationGrace is how long a rotated client secret keeps working.
+	RotationGrace Duration `json:"rotationGrace" yaml:"rotationGrace"`
+}
+
+// Telegram configures sending quotes through a Telegram bot.
+type Telegram struct {
+	Token string `json:"token" yaml:"token" secret:"true"`
+	URL   string `json:"url" yaml:"url"`
+}
+
+// Sentry configures reporting errors to Sentry.
+type Sentry struct {
+	DSN         string `json:"dsn" yaml:"dsn" secret:"true"`
+	Environment string `json:"environment" yaml:"environment"`
+	Release     string `json:"release" yaml:"release"`
+}
+
+// TLS configures the HTTPS listener. Serving HTTPS takes either a
+// certificate and key pair or autocert domains, not both.
+type TLS struct {
+	CertFile   string   `json:"certFile" yaml:"certFile"`
+	KeyFile    string   `json:"keyFile" yaml:"keyFile"`
+	Domains    []string `json:"domains" yaml:"domains"`
+	CacheDir   string   `json:"cacheDir" yaml:"cacheDir"`
+	Email      string   `json:"email" yaml:"email"`
+	Port       int      `json:"port" yaml:"port"`
+	Redirect   bool     `json:"redirect" yaml:"redirect"`
+	HSTSMaxAge Duration `json:"hstsMaxAge" yaml:"hstsMaxAge"`
+}
+
+// Config ...
+type Config struct {
+	Host               string    `json:"host" yaml:"host"`
+	Port               int       `json:"port" yaml:"port"`
+	LogLevel           string    `json:"logLevel" yaml:"logLevel"`
+	OTLPEndpoint       string    `json:"otlpEndpoint" yaml:"otlpEndpoint"`
+	TraceSampleRatio   float64   `json:"traceSampleRatio" yaml:"traceSampleRatio"`
+	AccessLog          string    `json:"accessLog" yaml:"accessLog"`
+	AccessLogSample    float64   `json:"accessLogSample" yaml:"accessLogSample"`
+	Provider           string    `json:"provider" yaml:"provider"`
+	Fallbacks          []string  `json:"fallbacks" yaml:"fallbacks"`
+	ForismaticURL      string    `json:"forismaticUrl" yaml:"forismaticUrl"`
+	ForismaticFormat   string    `json:"forismaticFormat" yaml:"forismaticFormat"`
+	ZenQuotesURL       string    `json:"zenQuotesUrl" yaml:"zenQuotesUrl"`
+	QuotableURL        string    `json:"quotableUrl" yaml:"quotableUrl"`
+	ProviderTimeout    Duration  `json:"providerTimeout" yaml:"providerTimeout"`
+	ProviderRecording  string    `json:"providerRecording" yaml:"providerRecording"`
+	ProviderFixtures   string    `json:"providerFixtures" yaml:"providerFixtures"`
+	ProbeProvider      bool      `json:"probeProvider" yaml:"probeProvider"`
+	Debug              bool      `json:"debug" yaml:"debug"`
+	RetryAttempts      int       `json:"retryAttempts" yaml:"retryAttempts"`
+	RetryBaseDelay     Duration  `json:"retryBaseDelay" yaml:"retryBaseDelay"`
+	RetryMaxDelay      Duration  `json:"retryMaxDelay" yaml:"retryMaxDelay"`
+	BreakerFailures    int       `json:"breakerFailures" yaml:"breakerFailures"`
+	BreakerCooldown    Duration  `json:"breakerCooldown" yaml:"breakerCooldown"`
+	HealthWindow       Duration  `json:"healthWindow" yaml:"healthWindow"`
+	CacheTTL           Duration  `json:"cacheTtl" yaml:"cacheTtl"`
+	CacheSize          int       `json:"cacheSize" yaml:"cacheSize"`
+	Database           string    `json:"database" yaml:"database"`
+	SQLitePath         string    `json:"sqlitePath" yaml:"sqlitePath"`
+	DBHost             string    `json:"dbHost" yaml:"dbHost"`
+	DBName             string    `json:"dbName" yaml:"dbName"`
+	DBMaxOpenConns     int       `json:"dbMaxOpenConns" yaml:"dbMaxOpenConns"`
+	DBMaxIdleConns     int       `json:"dbMaxIdleConns" yaml:"dbMaxIdleConns"`
+	DBConnMaxIdleTime  Duration  `json:"dbConnMaxIdleTime" yaml:"dbConnMaxIdleTime"`
+	DBConnMaxLifetime  Duration  `json:"dbConnMaxLifetime" yaml:"dbConnMaxLifetime"`
+	DBReplicaHosts     []string  `json:"dbReplicaHosts" yaml:"dbReplicaHosts"`
+	DBReplicaCheck     Duration  `json:"dbReplicaCheck" yaml:"dbReplicaCheck"`
+	Migrate            bool      `json:"migrate" yaml:"migrate"`
+	StartupAttempts    int       `json:"startupAttempts" yaml:"startupAttempts"`
+	StartupRetryDelay  Duration  `json:"startupRetryDelay" yaml:"startupRetryDelay"`
+	StartupMode        string    `json:"startupMode" yaml:"startupMode"`
+	Mailer             string    `json:"mailer" yaml:"mailer"`
+	SMTP               SMTP      `json:"smtp" yaml:"smtp"`
+	SendGrid           SendGrid  `json:"sendgrid" yaml:"sendgrid"`
+	SES                SES       `json:"ses" yaml:"ses"`
+	EmailWebhookSecret string    `json:"emailWebhookSecret" yaml:"emailWebhookSecret" secret:"true"`
+	RateLimit          float64   `json:"rateLimit" yaml:"rateLimit"`
+	RateLimitBurst     int       `json:"rateLimitBurst" yaml:"rateLimitBurst"`
+	MaxInFlight        int       `json:"maxInFlight" yaml:"maxInFlight"`
+	TenantKeys         []string  `json:"tenantKeys" yaml:"tenantKeys" secret:"true"`
+	KeyRotationGrace   Duration  `json:"keyRotationGrace" yaml:"keyRotationGrace"`
+	ShedRetryAfter     Duration  `json:"shedRetryAfter" yaml:"shedRetryAfter"`
+	DailyInterval      Duration  `json:"dailyInterval" yaml:"dailyInterval"`
+	DailyLanguages     []string  `json:"dailyLanguages" yaml:"dailyLanguages"`
+	DefaultLang        string    `json:"defaultLang" yaml:"defaultLang"`
+	LangFallbacks      []string  `json:"langFallbacks" yaml:"langFallbacks"`
+	Features           []string  `json:"features" yaml:"features"`
+	ShutdownTimeout    Duration  `json:"shutdownTimeout" yaml:"shutdownTimeout"`
+	PublicURL          string    `json:"publicUrl" yaml:"publicUrl"`
+	TemplatesDir       string    `json:"templatesDir" yaml:"templatesDir"`
+	VerifyKey          string    `json:"verifyKey" yaml:"verifyKey" secret:"true"`
+	AdminToken         string    `json:"adminToken" yaml:"adminToken" secret:"true"`
+	VerifyTTL          Duration  `json:"verifyTtl" yaml:"verifyTtl"`
+	StreamLimit        int       `json:"streamLimit" yaml:"streamLimit"`
+	FeedItems          int       `json:"feedItems" yaml:"feedItems"`
+	AuthorAttempts     int       `json:"authorAttempts" yaml:"authorAttempts"`
+	OutboxInterval     Duration  `json:"outboxInterval" yaml:"outboxInterval"`
+	OutboxMaxAttempts  int       `json:"outboxMaxAttempts" yaml:"outboxMaxAttempts"`
+	WebhookInterval    Duration  `json:"webhookInterval" yaml:"webhookInterval"`
+	WebhookMaxAttempts int       `json:"webhookMaxAttempts" yaml:"webhookMaxAttempts"`
+	Workers            int       `json:"workers" yaml:"workers"`
+	DedupWindow        Duration  `json:"dedupWindow" yaml:"dedupWindow"`
+	DedupAttempts      int       `json:"dedupAttempts" yaml:"dedupAttempts"`
+	Blocklist          []string  `json:"blocklist" yaml:"blocklist"`
+	BlocklistFile      string    `json:"blocklistFile" yaml:"blocklistFile"`
+	ModerationURL      string    `json:"moderationUrl" yaml:"moderationUrl"`
+	ModerationToken    string    `json:"moderationToken" yaml:"moderationToken" secret:"true"`
+	FilterAttempts     int       `json:"filterAttempts" yaml:"filterAttempts"`
+	IdempotencyTTL     Duration  `json:"idempotencyTtl" yaml:"idempotencyTtl"`
+	TrendingWindow     Duration  `json:"trendingWindow" yaml:"trendingWindow"`
+	Store              string    `json:"store" yaml:"store"`
+	Redis              Redis     `json:"redis" yaml:"redis"`
+	TLS                TLS       `json:"tls" yaml:"tls"`
+	Translate          Translate `json:"translate" yaml:"translate"`
+	JWT                JWT       `json:"jwt" yaml:"jwt"`
+	OAuth              OAuth     `json:"oauth" yaml:"oauth"`
+	Telegram           Telegram  `json:"telegram" yaml:"telegram"`
+	Sentry             Sentry    `json:"sentry" yaml:"sentry"`
+	CORSOrigins        []string  `json:"corsOrigins" yaml:"corsOrigins"`
+	CORSMethods        []string  `json:"corsMethods" yaml:"corsMethods"`
+	CORSHeaders        []string  `json:"corsHeaders" yaml:"corsHeaders"`
+	CORSMaxAge         Duration  `json:"corsMaxAge" yaml:"corsMaxAge"`
+	Compression        bool      `json:"compression" yaml:"compression"`
+	CompressionMinSize int       `json:"compressionMinSize" yaml:"compressionMinSize"`
+	RequestTimeout     Duration  `json:"requestTimeout" yaml:"requestTimeout"`
+	ProviderBudget     float64   `json:"providerBudget" yaml:"providerBudget"`
+	MaxBodyBytes       int       `json:"maxBodyBytes" yaml:"maxBodyBytes"`
+	ReadHeaderTimeout  Duration  `json:"readHeaderTimeout" yaml:"readHeaderTimeout"`
+	ReadTimeout        Duration  `json:"readTimeout" yaml:"readTimeout"`
+	WriteTimeout       Duration  `json:"writeTimeout" yaml:"writeTimeout"`
+	IdleTimeout        Duration  `json:"idleTimeout" yaml:"idleTimeout"`
+}
+
+// Default ...
+func Default() *Config {
+	return &Config{
+		Host:              "",
+		Port:              8080,
+		LogLevel:          "info",
+		TraceSampleRatio:  1,
+		AccessLog:         "off",
+		AccessLogSample:   1,
+		Provider:          "forismatic",
+		ForismaticURL:     "http://api.forismatic.com/api/1.0/",
+		ForismaticFormat:  "json",
+		ZenQuotesURL:      "https://zenquotes.io/api/random",
+		QuotableURL:       "https://api.quotable.io/random",
+		ProviderTimeout:   Duration{30 * time.Second},
+		ProviderRecording: "off",
+		ProviderFixtures:  "testdata/fixtures",
+		RetryAttempts:     3,
+		RetryBaseDelay:    Duration{100 * time.Millisecond},
+		RetryMaxDelay:     Duration{2 * time.Second},
+		BreakerFailures:   5,
+		BreakerCooldown:   Duration{30 * time.Second},
+		HealthWindow:      Duration{5 * time.Minute},
+		CacheTTL:          Duration{0},
+		CacheSize:         100,
+		Database:          "postgres",
+		SQLitePath:        "quotes.db",
+		DBHost:            "localhost",
+		DBName:            "quotes",
+		DBMaxOpenConns:    20,
+		DBMaxIdleConns:    5,
+		DBConnMaxIdleTime: Duration{5 * time.Minute},
+		DBConnMaxLifetime: Duration{30 * time.Minute},
+		DBReplicaCheck:    Duration{10 * time.Second},
+		StartupAttempts:   5,
+		StartupRetryDelay: Duration{time.Second},
+		StartupMode:       "fail-fast",
+		Mailer:            "smtp",
+		SMTP: SMTP{
+			Host: "localhost",
+			Port: 25,
+			From: "quotes@localhost",
+		},
+		RateLimit:          0,
+		RateLimitBurst:     20,
+		MaxInFlight:        0,
+		ShedRetryAfter:     Duration{time.Second},
+		KeyRotationGrace:   Duration{24 * time.Hour},
+		DailyInterval:      Duration{0},
+		DailyLanguages:     []string{"en"},
+		ShutdownTimeout:    Duration{15 * time.Second},
+		PublicURL:          "http://localhost:8080",
+		VerifyTTL:          Duration{72 * time.Hour},
+		StreamLimit:        100,
+		FeedItems:          20,
+		AuthorAttempts:     10,
+		OutboxInterval:     Duration{10 * time.Second},
+		OutboxMaxAttempts:  5,
+		WebhookInterval:    Duration{5 * time.Second},
+		WebhookMaxAttempts: 8,
+		Workers:            4,
+		DedupWindow:        Duration{24 * time.Hour},
+		DedupAttempts:      3,
+		FilterAttempts:     3,
+		IdempotencyTTL:     Duration{24 * time.Hour},
+		TrendingWindow:     Duration{7 * 24 * time.Hour},
+		Store:              "memory",
+		Redis: Redis{
+			Addr: "localhost:6379",
+		},
+		Translate: Translate{
+			CacheTTL: Duration{24 * time.Hour},
+		},
+		JWT: JWT{
+			RolesClaim: "roles",
+		},
+		OAuth: OAuth{
+			TokenTTL:      Duration{15 * time.Minute},
+			RotationGrace: Duration{24 * time.Hour},
+		},
+		TLS: TLS{
+			CacheDir:   "certs",
+			Port:       8443,
+			Redirect:   true,
+			HSTSMaxAge: Duration{180 * 24 * time.Hour},
+		},
+		CORSMethods:        []string{"GET", "POST", "PUT", "PATCH", "DELETE"},
+		CORSHeaders:        []string{"Content-Type", "Authorization", "X-API-Key", "X-Request-ID", "X-Tenant-ID", "If-None-Match", "If-Match", "Last-Event-ID"},
+		CORSMaxAge:         Duration{10 * time.Minute},
+		Compression:        true,
+		CompressionMinSize: 1024,
+		RequestTimeout:     Duration{10 * time.Second},
+		ProviderBudget:     0.7,
+		MaxBodyBytes:       1 << 20,
+		ReadHeaderTimeout:  Duration{5 * time.Second},
+		ReadTimeout:        Duration{30 * time.Second},
+		WriteTimeout:       Duration{60 * time.Second},
+		IdleTimeout:        Duration{2 * time.Minute},
+	}
+}
+
+// redacted replaces the values of settings tagged secret.
+const redacted = "[redacted]"
+
+// Redacted returns a copy of c safe to show: the settings tagged secret
+// that are set read "[redacted]".
+func (c *Config) Redacted() *Config {
+	cp := *c
+	redact(reflect.ValueOf(&cp).Elem())
+	return &cp
+}
+
+func redact(v reflect.Value) {
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		switch {
+		case f.Kind() == reflect.Struct:
+			redact(f)
+		case f.Kind() == reflect.String && v.Type().Field(i).Tag.Get("secret") == "true" && f.String() != "":
+			f.SetString(redacted)
+		case f.Kind() == reflect.Slice && v.Type().Field(i).Tag.Get("secret") == "true" && f.Len() > 0:
+			// A new slice, as the copy shares the backing array of the original.
+			f.Set(reflect.ValueOf([]string{redacted}))
+		}
+	}
+}
+
+// Changed returns the flag names of the settings other sets differently
+// from c, in the order of the flags.
+func (c *Config) Changed(other *Config) []string {
+	theirs := other.settings()
+	var changed []string
+	for i, s := range c.settings() {
+		mine, their := reflect.ValueOf(s.value).Elem().Interface(), reflect.ValueOf(theirs[i].value).Elem().Interface()
+		if !reflect.DeepEqual(mine, their) {
+			changed = append(changed, s.flag)
+		}
+	}
+	return changed
+}
+
+// Addr ...
+func (c *Config) Addr() string {
+	return fmt.Sprintf("%s:%d", c.Host, c.Port)
+}
+
+// TLSAddr is the address of the HTTPS listener.
+func (c *Config) TLSAddr() string {
+	return fmt.Sprintf("%s:%d", c.Host, c.TLS.Port)
+}
+
+// TLSEnabled reports whether HTTPS is configured.
+func (c *Config) TLSEnabled() bool {
+	return c.TLS.CertFile != "" || c.TLS.KeyFile != "" || len(c.TLS.Domains) > 0
+}
+
+type setting struct {
+	flag  string
+	env   string
+	usage string
+	value interface{}
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{"host", "QUOTES_HOST", "interface to listen on", &c.Host},
+		{"port", "QUOTES_PORT", "port to listen on", &c.Port},
+		{"log-level", "QUOTES_LOG_LEVEL", "minimum log level (debug, info, warn, error)", &c.LogLevel},
+		{"otlp-endpoint", "QUOTES_OTLP_ENDPOINT", "OTLP/HTTP collector receiving traces, such as http://localhost:4318, empty disables tracing", &c.OTLPEndpoint},
+		{"trace-sample-ratio", "QUOTES_TRACE_SAMPLE_RATIO", "share of new traces recorded, from 0 to 1", &c.TraceSampleRatio},
+		{"access-log", "QUOTES_ACCESS_LOG", "access log written to stdout: off, combined or json", &c.AccessLog},
+		{"access-log-sample", "QUOTES_ACCESS_LOG_SAMPLE", "share of requests in the access log, from 0 to 1; server errors are always logged", &c.AccessLogSample},
+		{"provider", "QUOTES_PROVIDER", "name of the quote provider to use", &c.Provider},
+		{"fallbacks", "QUOTES_FALLBACKS", "comma separated providers to fail over to, in order", &c.Fallbacks},
+		{"forismatic-url", "QUOTES_FORISMATIC_URL", "Forismatic API URL", &c.ForismaticURL},
+		{"forismatic-format", "QUOTES_FORISMATIC_FORMAT", "format quotes are asked of Forismatic in: json, falling back to xml when the JSON is broken, or xml", &c.ForismaticFormat},
+		{"zenquotes-url", "QUOTES_ZENQUOTES_URL", "ZenQuotes API URL", &c.ZenQuotesURL},
+		{"quotable-url", "QUOTES_QUOTABLE_URL", "Quotable API URL", &c.QuotableURL},
+		{"provider-timeout", "QUOTES_PROVIDER_TIMEOUT", "timeout of calls to the quote provider and other HTTP APIs such as the mailer", &c.ProviderTimeout},
+		{"provider-recording", "QUOTES_PROVIDER_RECORDING", "off, record to save the responses of the quote providers to provider-fixtures, or replay to answer from there without calling them", &c.ProviderRecording},
+		{"provider-fixtures", "QUOTES_PROVIDER_FIXTURES", "directory of the recorded quote provider responses", &c.ProviderFixtures},
+		{"probe-provider", "QUOTES_PROBE_PROVIDER", "include the quote provider in readiness checks", &c.ProbeProvider},
+		{"debug", "QUOTES_DEBUG", "serve pprof under /debug/pprof/ and expvar on /debug/vars, behind the admin token", &c.Debug},
+		{"retry-attempts", "QUOTES_RETRY_ATTEMPTS", "maximum attempts per quote provider call, 1 disables retries", &c.RetryAttempts},
+		{"retry-base-delay", "QUOTES_RETRY_BASE_DELAY", "initial backoff between provider retries", &c.RetryBaseDelay},
+		{"retry-max-delay", "QUOTES_RETRY_MAX_DELAY", "maximum backoff between provider retries", &c.RetryMaxDelay},
+		{"breaker-failures", "QUOTES_BREAKER_FAILURES", "consecutive provider failures that open the circuit breaker, 0 disables it", &c.BreakerFailures},
+		{"breaker-cooldown", "QUOTES_BREAKER_COOLDOWN", "how long the circuit breaker stays open before a trial call", &c.BreakerCooldown},
+		{"health-window", "QUOTES_HEALTH_WINDOW", "how far back provider calls count towards provider health", &c.HealthWindow},
+		{"cache-ttl", "QUOTES_CACHE_TTL", "how long generated quotes are cached per language, 0 disables caching", &c.CacheTTL},
+		{"cache-size", "QUOTES_CACHE_SIZE", "maximum number of cached quotes", &c.CacheSize},
+		{"database", "QUOTES_DATABASE", "where recipients and quote history live: postgres, sqlite, or memory to run without a database", &c.Database},
+		{"sqlite-path", "QUOTES_SQLITE_PATH", "SQLite database file, :memory: keeps it in memory", &c.SQLitePath},
+		{"db-host", "QUOTES_DB_HOST", "Postgres host", &c.DBHost},
+		{"db-name", "QUOTES_DB_NAME", "Postgres database name", &c.DBName},
+		{"db-max-open-conns", "QUOTES_DB_MAX_OPEN_CONNS", "maximum open database connections, 0 means no limit", &c.DBMaxOpenConns},
+		{"db-max-idle-conns", "QUOTES_DB_MAX_IDLE_CONNS", "maximum idle database connections kept in the pool", &c.DBMaxIdleConns},
+		{"db-conn-max-idle-time", "QUOTES_DB_CONN_MAX_IDLE_TIME", "how long a database connection may sit idle before it is closed", &c.DBConnMaxIdleTime},
+		{"db-conn-max-lifetime", "QUOTES_DB_CONN_MAX_LIFETIME", "how long a database connection is reused before it is replaced", &c.DBConnMaxLifetime},
+		{"db-replica-hosts", "QUOTES_DB_REPLICA_HOSTS", "comma separated hosts of Postgres read replicas serving the recipient and quote lists and searches", &c.DBReplicaHosts},
+		{"db-replica-check", "QUOTES_DB_REPLICA_CHECK", "how often read replicas are pinged; reads go to the primary while none answers", &c.DBReplicaCheck},
+		{"migrate", "QUOTES_MIGRATE", "apply database migrations on startup", &c.Migrate},
+		{"startup-attempts", "QUOTES_STARTUP_ATTEMPTS", "times the database and other dependencies are pinged on startup before giving up", &c.StartupAttempts},
+		{"startup-retry-delay", "QUOTES_STARTUP_RETRY_DELAY", "initial backoff between startup pings, doubling after each", &c.StartupRetryDelay},
+		{"startup-mode", "QUOTES_STARTUP_MODE", "what to do when dependencies are still down on startup: fail-fast exits, degrade serves anyway and reports them on /readyz", &c.StartupMode},
+		{"mailer", "QUOTES_MAILER", "how quote emails are sent: smtp, sendgrid or ses", &c.Mailer},
+		{"smtp-host", "QUOTES_SMTP_HOST", "SMTP server host", &c.SMTP.Host},
+		{"smtp-port", "QUOTES_SMTP_PORT", "SMTP server port", &c.SMTP.Port},
+		{"smtp-user", "QUOTES_SMTP_USER", "SMTP username", &c.SMTP.Username},
+		{"smtp-password", "QUOTES_SMTP_PASSWORD", "SMTP password", &c.SMTP.Password},
+		{"smtp-from", "QUOTES_SMTP_FROM", "sender address of quote emails, whichever mailer sends them", &c.SMTP.From},
+		{"sendgrid-api-key", "QUOTES_SENDGRID_API_KEY", "SendGrid API key", &c.SendGrid.APIKey},
+		{"ses-region", "QUOTES_SES_REGION", "AWS region of Amazon SES", &c.SES.Region},
+		{"ses-access-key-id", "QUOTES_SES_ACCESS_KEY_ID", "AWS access key ID allowed to send with SES", &c.SES.AccessKeyID},
+		{"ses-secret-access-key", "QUOTES_SES_SECRET_ACCESS_KEY", "AWS secret access key of the SES access key ID", &c.SES.SecretAccessKey},
+		{"email-webhook-secret", "QUOTES_EMAIL_WEBHOOK_SECRET", "secret the sendgrid or ses mailer passes to /webhooks/email, empty disables the webhook", &c.EmailWebhookSecret},
+		{"rate-limit", "QUOTES_RATE_LIMIT", "requests per second allowed per client, 0 disables rate limiting", &c.RateLimit},
+		{"rate-limit-burst", "QUOTES_RATE_LIMIT_BURST", "requests a client may burst above the rate limit", &c.RateLimitBurst},
+		{"max-in-flight", "QUOTES_MAX_IN_FLIGHT", "requests served at once before new ones get a 503, 0 means no limit", &c.MaxInFlight},
+		{"tenant-keys", "QUOTES_TENANT_KEYS", "comma separated api-key:tenant pairs, each optionally followed by :owner-email; requests with one of those keys act for its tenant only", &c.TenantKeys},
+		{"key-rotation-grace", "QUOTES_KEY_ROTATION_GRACE", "how long the previous tenant key keeps working after a rotation", &c.KeyRotationGrace},
+		{"shed-retry-after", "QUOTES_SHED_RETRY_AFTER", "Retry-After given to requests turned away over max-in-flight", &c.ShedRetryAfter},
+		{"daily-interval", "QUOTES_DAILY_INTERVAL", "interval of the scheduled quote delivery, 0 disables it", &c.DailyInterval},
+		{"daily-languages", "QUOTES_DAILY_LANGUAGES", "comma separated languages of the scheduled quote delivery", &c.DailyLanguages},
+		{"default-lang", "QUOTES_DEFAULT_LANG", "language of quotes requested without one, empty leaves it to the provider", &c.DefaultLang},
+		{"lang-fallbacks", "QUOTES_LANG_FALLBACKS", "comma separated language fallbacks such as de:en, used when a language is unsupported or its provider fails", &c.LangFallbacks},
+		{"features", "QUOTES_FEATURES", "comma separated feature flags such as translation=off, see GET /admin/features", &c.Features},
+		{"shutdown-timeout", "QUOTES_SHUTDOWN_TIMEOUT", "time to drain in-flight requests on shutdown", &c.ShutdownTimeout},
+		{"public-url", "QUOTES_PUBLIC_URL", "base URL of the API used in emailed links", &c.PublicURL},
+		{"templates-dir", "QUOTES_TEMPLATES_DIR", "directory of email templates reread on every email for live editing, empty uses the built-in ones", &c.TemplatesDir},
+		{"admin-token", "QUOTES_ADMIN_TOKEN", "bearer token required by the /admin and /debug routes, which are off without it or a JWT issuer", &c.AdminToken},
+		{"verify-key", "QUOTES_VERIFY_KEY", "secret signing email verification tokens, random per process when empty", &c.VerifyKey},
+		{"verify-ttl", "QUOTES_VERIFY_TTL", "how long email verification links stay valid", &c.VerifyTTL},
+		{"stream-limit", "QUOTES_STREAM_LIMIT", "maximum open quote streams, 0 means no limit", &c.StreamLimit},
+		{"feed-items", "QUOTES_FEED_ITEMS", "how many of the latest quotes the RSS and Atom feeds list", &c.FeedItems},
+		{"author-attempts", "QUOTES_AUTHOR_ATTEMPTS", "quotes drawn from the provider looking for a requested author", &c.AuthorAttempts},
+		{"outbox-interval", "QUOTES_OUTBOX_INTERVAL", "how often queued emails are sent, 0 sends them right away without an outbox", &c.OutboxInterval},
+		{"outbox-max-attempts", "QUOTES_OUTBOX_MAX_ATTEMPTS", "send attempts before a queued email is marked failed", &c.OutboxMaxAttempts},
+		{"webhook-interval", "QUOTES_WEBHOOK_INTERVAL", "how often queued webhook notifications are POSTed, 0 disables webhooks", &c.WebhookInterval},
+		{"webhook-max-attempts", "QUOTES_WEBHOOK_MAX_ATTEMPTS", "POST attempts before a webhook notification is marked failed", &c.WebhookMaxAttempts},
+		{"workers", "QUOTES_WORKERS", "background jobs allowed to run at once", &c.Workers},
+		{"dedup-window", "QUOTES_DEDUP_WINDOW", "how long a served quote is avoided, 0 allows repeats", &c.DedupWindow},
+		{"dedup-attempts", "QUOTES_DEDUP_ATTEMPTS", "provider calls made looking for a quote outside the dedup window", &c.DedupAttempts},
+		{"blocklist", "QUOTES_BLOCKLIST", "comma separated words and phrases rejected in quotes", &c.Blocklist},
+		{"blocklist-file", "QUOTES_BLOCKLIST_FILE", "file of words and phrases rejected in quotes, one per line", &c.BlocklistFile},
+		{"moderation-url", "QUOTES_MODERATION_URL", "moderation API, in the OpenAI moderations format, screening quotes; empty disables it", &c.ModerationURL},
+		{"moderation-token", "QUOTES_MODERATION_TOKEN", "bearer token of the moderation API", &c.ModerationToken},
+		{"idempotency-ttl", "QUOTES_IDEMPOTENCY_TTL", "how long responses are kept for replays with the same Idempotency-Key, 0 ignores the header", &c.IdempotencyTTL},
+		{"trending-window", "QUOTES_TRENDING_WINDOW", "how far back ratings count towards /quotes/trending by default", &c.TrendingWindow},
+		{"filter-attempts", "QUOTES_FILTER_ATTEMPTS", "provider calls made looking for a quote the filter accepts", &c.FilterAttempts},
+		{"store", "QUOTES_STORE", "where the quote cache and rate limits live: memory, or redis to share them between instances", &c.Store},
+		{"redis-addr", "QUOTES_REDIS_ADDR", "Redis host:port", &c.Redis.Addr},
+		{"redis-password", "QUOTES_REDIS_PASSWORD", "Redis password", &c.Redis.Password},
+		{"redis-db", "QUOTES_REDIS_DB", "Redis database number", &c.Redis.DB},
+		{"translate-url", "QUOTES_TRANSLATE_URL", "LibreTranslate server translating quotes for ?source=, empty disables translation", &c.Translate.URL},
+		{"translate-api-key", "QUOTES_TRANSLATE_API_KEY", "LibreTranslate API key, if the server requires one", &c.Translate.APIKey},
+		{"translate-cache-ttl", "QUOTES_TRANSLATE_CACHE_TTL", "how long translations are cached, 0 disables caching", &c.Translate.CacheTTL},
+		{"jwt-issuer", "QUOTES_JWT_ISSUER", "issuer of the JWTs clients must send, empty disables JWT authentication", &c.JWT.Issuer},
+		{"jwt-jwks-url", "QUOTES_JWT_JWKS_URL", "URL of the JSON Web Key Set the issuer signs JWTs with", &c.JWT.JWKSURL},
+		{"jwt-audience", "QUOTES_JWT_AUDIENCE", "audience JWTs must be meant for, empty accepts any", &c.JWT.Audience},
+		{"jwt-roles-claim", "QUOTES_JWT_ROLES_CLAIM", "JWT claim holding the roles admin, editor or reader", &c.JWT.RolesClaim},
+		{"oauth", "QUOTES_OAUTH", "hand out tokens to OAuth2 clients on /oauth/token; tokens are then required on every route", &c.OAuth.Enabled},
+		{"oauth-issuer", "QUOTES_OAUTH_ISSUER", "issuer named in the tokens handed out, the public URL when empty", &c.OAuth.Issuer},
+		{"oauth-signing-key", "QUOTES_OAUTH_SIGNING_KEY", "PEM encoded P-256 private key signing the tokens handed out, a random one when empty", &c.OAuth.SigningKey},
+		{"oauth-token-ttl", "QUOTES_OAUTH_TOKEN_TTL", "how long tokens handed out stay valid", &c.OAuth.TokenTTL},
+		{"oauth-rotation-grace", "QUOTES_OAUTH_ROTATION_GRACE", "how long the previous secret of a client keeps working after a rotation", &c.OAuth.RotationGrace},
+		{"telegram-token", "QUOTES_TELEGRAM_TOKEN", "Telegram bot token sending quotes to the telegram channel, empty disables the channel", &c.Telegram.Token},
+		{"telegram-url", "QUOTES_TELEGRAM_URL", "Telegram Bot API URL, the public API when empty", &c.Telegram.URL},
+		{"sentry-dsn", "QUOTES_SENTRY_DSN", "Sentry DSN receiving panics, server errors and failed background jobs, empty disables error tracking", &c.Sentry.DSN},
+		{"sentry-environment", "QUOTES_SENTRY_ENVIRONMENT", "environment tagging the errors sent to Sentry, such as staging", &c.Sentry.Environment},
+		{"sentry-release", "QUOTES_SENTRY_RELEASE", "release tagging the errors sent to Sentry, such as the deployed version", &c.Sentry.Release},
+		{"tls-cert", "QUOTES_TLS_CERT", "PEM certificate file to serve HTTPS with", &c.TLS.CertFile},
+		{"tls-key", "QUOTES_TLS_KEY", "PEM private key file of the TLS certificate", &c.TLS.KeyFile},
+		{"tls-domains", "QUOTES_TLS_DOMAINS", "comma separated domains to get Let's Encrypt certificates for", &c.TLS.Domains},
+		{"tls-cache-dir", "QUOTES_TLS_CACHE_DIR", "directory Let's Encrypt certificates are cached in", &c.TLS.CacheDir},
+		{"tls-email", "QUOTES_TLS_EMAIL", "contact email of the Let's Encrypt account", &c.TLS.Email},
+		{"tls-port", "QUOTES_TLS_PORT", "port to serve HTTPS on when TLS is configured", &c.TLS.Port},
+		{"tls-redirect", "QUOTES_TLS_REDIRECT", "redirect plain HTTP requests to HTTPS when TLS is configured", &c.TLS.Redirect},
+		{"hsts-max-age", "QUOTES_HSTS_MAX_AGE", "max-age of the Strict-Transport-Security header, 0 disables it", &c.TLS.HSTSMaxAge},
+		{"cors-origins", "QUOTES_CORS_ORIGINS", "comma separated origins browsers may call the API from, * for any, empty disables CORS", &c.CORSOrigins},
+		{"cors-methods", "QUOTES_CORS_METHODS", "comma separated methods allowed in cross-origin requests", &c.CORSMethods},
+		{"cors-headers", "QUOTES_CORS_HEADERS", "comma separated request headers allowed in cross-origin requests", &c.CORSHeaders},
+		{"cors-max-age", "QUOTES_CORS_MAX_AGE", "how long browsers may cache a CORS preflight response", &c.CORSMaxAge},
+		{"compression", "QUOTES_COMPRESSION", "gzip or deflate responses for clients that accept it", &c.Compression},
+		{"compression-min-size", "QUOTES_COMPRESSION_MIN_SIZE", "smallest response body in bytes worth compressing", &c.CompressionMinSize},
+		{"request-timeout", "QUOTES_REQUEST_TIMEOUT", "time a request has to complete before it fails with 504, 0 disables it", &c.RequestTimeout},
+		{"provider-budget", "QUOTES_PROVIDER_BUDGET", "share of the request timeout the quote provider may use, the rest is left for the database", &c.ProviderBudget},
+		{"max-body-bytes", "QUOTES_MAX_BODY_BYTES", "largest request body accepted before failing with 413, 0 disables the limit", &c.MaxBodyBytes},
+		{"read-header-timeout", "QUOTES_READ_HEADER_TIMEOUT", "time a client has to send the request headers", &c.ReadHeaderTimeout},
+		{"read-timeout", "QUOTES_READ_TIMEOUT", "time a client has to send the whole request, headers and body", &c.ReadTimeout},
+		{"write-timeout", "QUOTES_WRITE_TIMEOUT", "time to write a response; streams and exports are exempt", &c.WriteTimeout},
+		{"idle-timeout", "QUOTES_IDLE_TIMEOUT", "how long an idle keep-alive connection stays open", &c.IdleTimeout},
+	}
+}
+
+// Load builds a Config from defaults, then the optional config file, then
+// environment variables and finally command-line flags, each overriding the
+// previous one.
+func Load(args []string) (*Config, error) {
+	return LoadFlags(flag.NewFlagSet("quotes", flag.ContinueOnError), args)
+}
+
+// LoadFlags is Load parsing args with fs, so commands can add flags of their
+// own next to the config ones.
+func LoadFlags(fs *flag.FlagSet, args []string) (*Config, error) {
+	c := Default()
+
+	var configPath string
+	var flagValues [][2]string
+
+	fs.StringVar(&configPath, "config", os.Getenv("QUOTES_CONFIG"), "path to a YAML or JSON config file")
+	for _, s := range c.settings() {
+		name := s.flag
+		fs.Func(name, s.usage, func(v string) error {
+			flagValues = append(flagValues, [2]string{name, v})
+			return nil
+		})
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if configPath != "" {
+		if err := c.loadFile(configPath); err != nil {
+			return nil, err
+		}
+	}
+
+	settings := c.settings()
+	for _, s := range settings {
+		if v, ok := os.LookupEnv(s.env); ok {
+			if err := set(s.value, v); err != nil {
+				return nil, fmt.Errorf("config: %s: %v", s.env, err)
+			}
+		}
+	}
+
+	for _, fv := range flagValues {
+		for _, s := range settings {
+			if s.flag != fv[0] {
+				continue
+			}
+			if err := set(s.value, fv[1]); err != nil {
+				return nil, fmt.Errorf("config: -%s: %v", s.flag, err)
+			}
+		}
+	}
+
+	return c, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	switch filepath.Ext(path) {
+	case ".yml", ".yaml":
+		err = yaml.Unmarshal(b, c)
+	case ".json":
+		err = json.Unmarshal(b, c)
+	default:
+		return fmt.Errorf("config: unsupported config file %q", path)
+	}
+	if err != nil {
+		return fmt.Errorf("config: %s: %v", path, err)
+	}
+
+	return nil
+}
+
+func set(value interface{}, s string) error {
+	switch v := value.(type) {
+	case *string:
+		*v = s
+	case *int:
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		*v = i
+	case *float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return err
+		}
+		*v = f
+	case *bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		*v = b
+	case *Duration:
+		return v.Set(s)
+	case *[]string:
+		*v = nil
+		for _, item := range strings.Split(s, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				*v = append(*v, item)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported setting type %T", value)
+	}
+
+	return nil
+}
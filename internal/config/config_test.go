@@ -0,0 +1,172 @@
+// internal/config/config_test.go
+
+package config
+
+import (
+	"flag"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600), "Should write config file")
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	yamlFile := writeConfigFile(t, "config.yaml", "port: 9000\ndbName: from_yaml\nshutdownTimeout: 5s\nsmtp:\n  host: mail.testmail.com\n")
+	jsonFile := writeConfigFile(t, "config.json", `{"port": 9001, "dbHost": "db.json"}`)
+
+	testCases := []struct {
+		name               string
+		args               []string
+		env                map[string]string
+		check              func(t *testing.T, c *Config)
+		expectedToGetError bool
+	}{
+		{
+			"Defaults",
+			nil,
+			nil,
+			func(t *testing.T, c *Config) {
+				assert.Equal(t, Default(), c, "Config should equal defaults")
+				assert.Equal(t, ":8080", c.Addr(), "Wrong address")
+			},
+			false,
+		},
+		{
+			"YAMLFile",
+			[]string{"-config", yamlFile},
+			nil,
+			func(t *testing.T, c *Config) {
+				assert.Equal(t, 9000, c.Port, "Port should come from file")
+				assert.Equal(t, "from_yaml", c.DBName, "DB name should come from file")
+				assert.Equal(t, 5*time.Second, c.ShutdownTimeout.Duration, "Timeout should come from file")
+				assert.Equal(t, "mail.testmail.com", c.SMTP.Host, "SMTP host should come from file")
+				assert.Equal(t, 25, c.SMTP.Port, "SMTP port should keep its default")
+			},
+			false,
+		},
+		{
+			"EnvOverridesFile",
+			nil,
+			map[string]string{"QUOTES_CONFIG": jsonFile, "QUOTES_PORT": "9100"},
+			func(t *testing.T, c *Config) {
+				assert.Equal(t, 9100, c.Port, "Port should come from env")
+				assert.Equal(t, "db.json", c.DBHost, "DB host should come from file")
+			},
+			false,
+		},
+		{
+			"FlagsOverrideEnv",
+			[]string{"-port", "9200", "-provider-timeout", "2s", "-daily-languages", "en, ru"},
+			map[string]string{"QUOTES_PORT": "9100", "QUOTES_DB_NAME": "from_env"},
+			func(t *testing.T, c *Config) {
+				assert.Equal(t, 9200, c.Port, "Port should come from flag")
+				assert.Equal(t, 2*time.Second, c.ProviderTimeout.Duration, "Provider timeout should come from flag")
+				assert.Equal(t, []string{"en", "ru"}, c.DailyLanguages, "Daily languages should come from flag")
+				assert.Equal(t, "from_env", c.DBName, "DB name should come from env")
+			},
+			false,
+		},
+		{
+			"TLS",
+			[]string{"-tls-domains", "quotes.example.com", "-tls-port", "443", "-hsts-max-age", "1h"},
+			nil,
+			func(t *testing.T, c *Config) {
+				assert.True(t, c.TLSEnabled(), "Autocert domains should enable TLS")
+				assert.Equal(t, ":443", c.TLSAddr(), "Wrong TLS address")
+				assert.Equal(t, time.Hour, c.TLS.HSTSMaxAge.Duration, "HSTS max-age should come from flag")
+				assert.True(t, c.TLS.Redirect, "Redirect should keep its default")
+			},
+			false,
+		},
+		{
+			"InvalidEnv",
+			nil,
+			map[string]string{"QUOTES_PORT": "not-a-number"},
+			nil,
+			true,
+		},
+		{
+			"MissingFile",
+			[]string{"-config", "/does/not/exist.yaml"},
+			nil,
+			nil,
+			true,
+		},
+		{
+			"UnknownFlag",
+			[]string{"-bla"},
+			nil,
+			nil,
+			true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			os.Unsetenv("QUOTES_CONFIG")
+			for k, v := range tC.env {
+				t.Setenv(k, v)
+			}
+
+			c, err := Load(tC.args)
+
+			if tC.expectedToGetError {
+				assert.Error(t, err, "Got no error when expected")
+				return
+			}
+			require.NoError(t, err, "Got error when not expected")
+			tC.check(t, c)
+		})
+	}
+}
+
+func TestLoadFlags(t *testing.T) {
+	os.Unsetenv("QUOTES_CONFIG")
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	count := fs.Int("count", 100, "")
+
+	c, err := LoadFlags(fs, []string{"-count", "5", "-port", "9000"})
+
+	require.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, 5, *count, "Command flags should be parsed")
+	assert.Equal(t, 9000, c.Port, "Config flags should be parsed")
+}
+
+func TestRedacted(t *testing.T) {
+	c := Default()
+	c.SMTP.Password = "smtp-secret"
+	c.AdminToken = "admin-secret"
+	c.SendGrid.APIKey = "SG.secret"
+	c.TenantKeys = []string{"key-1:acme", "key-2:globex"}
+
+	r := c.Redacted()
+
+	assert.Equal(t, "[redacted]", r.SMTP.Password, "Nested secrets should be redacted")
+	assert.Equal(t, "[redacted]", r.AdminToken)
+	assert.Equal(t, "[redacted]", r.SendGrid.APIKey)
+	assert.Equal(t, []string{"[redacted]"}, r.TenantKeys, "Secret lists should be redacted whole")
+	assert.Equal(t, "key-1:acme", c.TenantKeys[0], "The secret list itself should not change")
+	assert.Equal(t, "", r.Redis.Password, "Unset secrets should stay empty")
+	assert.Equal(t, c.SMTP.Host, r.SMTP.Host, "Other settings should be kept")
+	assert.Equal(t, "smtp-secret", c.SMTP.Password, "The config itself should not change")
+}
+
+func TestChanged(t *testing.T) {
+	c := Default()
+	other := Default()
+	assert.Empty(t, c.Changed(other), "Equal configs should have no changes")
+
+	other.LogLevel = "debug"
+	other.SMTP.Host = "mail.example.com"
+	other.Features = []string{"translation=off"}
+
+	assert.Equal(t, []string{"log-level", "smtp-host", "features"}, c.Changed(other), "Changes are different than expected")
+}
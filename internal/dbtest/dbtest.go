@@ -0,0 +1,43 @@
+// internal/dbtest/dbtest.go
+
+// Package dbtest lists the databases the stores written against database/sql
+// are tested on, so their tests can run once per dialect.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"github.com/jeniok12/golang-tdd-example/internal/pgtest"
+	"github.com/jeniok12/golang-tdd-example/internal/sqlitestore"
+	"testing"
+)
+
+// Dialect opens migrated databases of one kind.
+type Dialect struct {
+	Name string
+	// Open returns a database migrated to the latest version, closed when t
+	// ends.
+	Open func(t testing.TB) *sql.DB
+}
+
+// Dialects are Postgres, skipped without a server as pgtest says, and SQLite
+// in memory. Packages testing on them call pgtest.Main from TestMain.
+var Dialects = []Dialect{
+	{Name: "Postgres", Open: pgtest.Open},
+	{Name: "SQLite", Open: SQLite},
+}
+
+// SQLite returns a migrated SQLite database in memory.
+func SQLite(t testing.TB) *sql.DB {
+	t.Helper()
+
+	db, err := sqlitestore.Open(":memory:")
+	if err != nil {
+		t.Fatalf("dbtest: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := db.Migrate(context.Background()); err != nil {
+		t.Fatalf("dbtest: migrating: %v", err)
+	}
+	return db.DB
+}
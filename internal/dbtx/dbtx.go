@@ -0,0 +1,42 @@
+// internal/dbtx/dbtx.go
+
+// Package dbtx lets repositories run their queries either on the connection
+// pool or inside a transaction they share with other repositories.
+package dbtx
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Executor runs queries. *sql.DB and *sql.Tx both satisfy it.
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Run calls fn inside a transaction on db. The transaction is committed when
+// fn returns nil and rolled back when it fails or panics.
+func Run(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Within calls fn as part of tx, or in a transaction of its own on db when tx
+// is nil. Repositories use it for methods that must be atomic by themselves.
+func Within(ctx context.Context, db *sql.DB, tx *sql.Tx, fn func(tx *sql.Tx) error) error {
+	if tx != nil {
+		return fn(tx)
+	}
+	return Run(ctx, db, fn)
+}
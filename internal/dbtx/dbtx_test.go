@@ -0,0 +1,77 @@
+// internal/dbtx/dbtx_test.go
+
+package dbtx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+	"testing"
+)
+
+func newDB(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err, "Got error when not expected")
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec("CREATE TABLE items (name TEXT)")
+	require.NoError(t, err, "Got error when not expected")
+	return db
+}
+
+func insert(name string) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		_, err := tx.Exec("INSERT INTO items (name) VALUES ($1)", name)
+		return err
+	}
+}
+
+func count(t *testing.T, db Executor) int {
+	var n int
+	require.NoError(t, db.QueryRowContext(context.Background(), "SELECT count(*) FROM items").Scan(&n), "Got error when not expected")
+	return n
+}
+
+func TestRun(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	errFailed := errors.New("failed")
+
+	err := Run(ctx, db, func(tx *sql.Tx) error {
+		require.NoError(t, insert("a")(tx), "Got error when not expected")
+		return errFailed
+	})
+	assert.ErrorIs(t, err, errFailed, "The error of fn should be returned")
+	assert.Equal(t, 0, count(t, db), "Failed transactions should be rolled back")
+
+	assert.Panics(t, func() {
+		Run(ctx, db, func(tx *sql.Tx) error {
+			insert("a")(tx)
+			panic("boom")
+		})
+	})
+	assert.Equal(t, 0, count(t, db), "Panicking transactions should be rolled back")
+
+	assert.NoError(t, Run(ctx, db, insert("a")), "Got error when not expected")
+	assert.Equal(t, 1, count(t, db), "Transactions should be committed")
+}
+
+func TestWithin(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+
+	err := Run(ctx, db, func(tx *sql.Tx) error {
+		require.NoError(t, Within(ctx, db, tx, insert("a")), "Got error when not expected")
+		assert.Equal(t, 1, count(t, tx), "Within should use the transaction it is given")
+		return errors.New("failed")
+	})
+	assert.Error(t, err, "Got no error when expected")
+	assert.Equal(t, 0, count(t, db), "Within should not commit the transaction it is given")
+
+	assert.NoError(t, Within(ctx, db, nil, insert("a")), "Got error when not expected")
+	assert.Equal(t, 1, count(t, db), "Without a transaction, Within should commit its own")
+}
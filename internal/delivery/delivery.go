@@ -0,0 +1,108 @@
+// internal/delivery/delivery.go
+
+// Package delivery tracks what became of the emails sent: every send attempt
+// is recorded, then updated by the events the mail provider reports back.
+package delivery
+
+import (
+	"context"
+	"database/sql"
+	"github.com/jeniok12/golang-tdd-example/mailer"
+	"time"
+)
+
+// Delivery statuses. Sent and failed are set when sending; the others come
+// from provider events.
+const (
+	StatusSent       = "sent"
+	StatusFailed     = "failed"
+	StatusDelivered  = "delivered"
+	StatusBounced    = "bounced"
+	StatusComplained = "complained"
+)
+
+// Attempt is one email handed to the mailer.
+type Attempt struct {
+	ID int `json:"id"`
+	// MessageID is the ID the mail API gave the email, empty with mailers
+	// that report none.
+	MessageID string    `json:"messageId"`
+	Email     string    `json:"email"`
+	Status    string    `json:"status"`
+	Detail    string    `json:"detail,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Store ...
+type Store interface {
+	Record(ctx context.Context, a Attempt) error
+	// UpdateStatus sets the status of the attempts that sent messageID and
+	// returns how many there were.
+	UpdateStatus(ctx context.Context, messageID, status, detail string, at time.Time) (int, error)
+}
+
+// Persistence keeps the attempts in the deliveries table. Like the audit log,
+// its queries run on both Postgres and SQLite.
+type Persistence struct {
+	DB *sql.DB
+}
+
+// Record ...
+func (p *Persistence) Record(ctx context.Context, a Attempt) error {
+	query := `INSERT INTO deliveries (message_id, email, status, detail, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $5)`
+	_, err := p.DB.ExecContext(ctx, query, a.MessageID, a.Email, a.Status, a.Detail, a.CreatedAt.UTC())
+	return err
+}
+
+// UpdateStatus ...
+func (p *Persistence) UpdateStatus(ctx context.Context, messageID, status, detail string, at time.Time) (int, error) {
+	query := "UPDATE deliveries SET status = $2, detail = $3, updated_at = $4 WHERE message_id = $1 AND message_id <> ''"
+	res, err := p.DB.ExecContext(ctx, query, messageID, status, detail, at.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+// Tracker is a mailer.Sender recording every attempt of Sender in Store.
+// Failing to record an attempt does not fail the send.
+type Tracker struct {
+	Sender mailer.Sender
+	Store  Store
+	// OnError is told about attempts that could not be recorded.
+	OnError func(err error)
+
+	now func() time.Time
+}
+
+// Send ...
+func (t *Tracker) Send(msg mailer.Message) error {
+	_, err := t.SendWithID(msg)
+	return err
+}
+
+// SendWithID sends msg and records the attempt under the ID the mail API
+// gave it.
+func (t *Tracker) SendWithID(msg mailer.Message) (string, error) {
+	id, err := mailer.SendWithID(t.Sender, msg)
+
+	now := time.Now
+	if t.now != nil {
+		now = t.now
+	}
+	a := Attempt{MessageID: id, Email: msg.To, Status: StatusSent, CreatedAt: now()}
+	if err != nil {
+		a.Status, a.Detail = StatusFailed, err.Error()
+	}
+	// mailer.Sender carries no context, and the attempt must be recorded
+	// even when the caller gave up.
+	if recErr := t.Store.Record(context.Background(), a); recErr != nil && t.OnError != nil {
+		t.OnError(recErr)
+	}
+
+	return id, err
+}
@@ -0,0 +1,124 @@
+// internal/delivery/delivery_test.go
+
+package delivery
+
+import (
+	"context"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/internal/dbtest"
+	"github.com/jeniok12/golang-tdd-example/internal/pgtest"
+	"github.com/jeniok12/golang-tdd-example/mailer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Main(m))
+}
+
+func TestPersistence(t *testing.T) {
+	for _, d := range dbtest.Dialects {
+		t.Run(d.Name, func(t *testing.T) {
+			testPersistence(t, &Persistence{DB: d.Open(t)})
+		})
+	}
+}
+
+func testPersistence(t *testing.T, p *Persistence) {
+	ctx := context.Background()
+	sent := time.Date(2019, 7, 21, 10, 0, 0, 0, time.UTC)
+
+	require.NoError(t, p.Record(ctx, Attempt{MessageID: "abc", Email: "user1@testmail.com", Status: StatusSent, CreatedAt: sent}))
+	require.NoError(t, p.Record(ctx, Attempt{Email: "user2@testmail.com", Status: StatusFailed, Detail: "timeout", CreatedAt: sent}))
+
+	n, err := p.UpdateStatus(ctx, "abc", StatusBounced, "550 no such user", sent.Add(time.Minute))
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, 1, n)
+
+	n, err = p.UpdateStatus(ctx, "", StatusDelivered, "", sent.Add(time.Minute))
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, 0, n, "Attempts without a message ID should never match")
+
+	var status, detail string
+	var updatedAt time.Time
+	require.NoError(t, p.DB.QueryRow("SELECT status, detail, updated_at FROM deliveries WHERE message_id = 'abc'").Scan(&status, &detail, &updatedAt))
+	assert.Equal(t, StatusBounced, status)
+	assert.Equal(t, "550 no such user", detail)
+	assert.True(t, sent.Add(time.Minute).Equal(updatedAt), "Update time should be recorded")
+}
+
+// fakeSender reports id for every message, or fails with err.
+type fakeSender struct {
+	id  string
+	err error
+}
+
+func (s *fakeSender) Send(msg mailer.Message) error {
+	_, err := s.SendWithID(msg)
+	return err
+}
+
+func (s *fakeSender) SendWithID(msg mailer.Message) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	return s.id, nil
+}
+
+// failingStore fails to record attempts.
+type failingStore struct{ Store }
+
+func (failingStore) Record(ctx context.Context, a Attempt) error {
+	return errors.New("sample error")
+}
+
+func TestTracker(t *testing.T) {
+	now := time.Date(2019, 7, 21, 10, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		desc            string
+		sender          *fakeSender
+		expectedAttempt Attempt
+	}{
+		{"Sent", &fakeSender{id: "abc"}, Attempt{ID: 1, MessageID: "abc", Email: "user1@testmail.com", Status: StatusSent, CreatedAt: now, UpdatedAt: now}},
+		{"Failed", &fakeSender{err: errors.New("sample error")}, Attempt{ID: 1, Email: "user1@testmail.com", Status: StatusFailed, Detail: "sample error", CreatedAt: now, UpdatedAt: now}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			store := &recordingStore{}
+			tracker := &Tracker{Sender: tC.sender, Store: store, now: func() time.Time { return now }}
+
+			id, err := tracker.SendWithID(mailer.Message{To: "user1@testmail.com"})
+			assert.Equal(t, tC.sender.err, err, "Send errors should be returned as is")
+			assert.Equal(t, tC.sender.id, id)
+			assert.Equal(t, []Attempt{tC.expectedAttempt}, store.attempts)
+		})
+	}
+}
+
+func TestTracker_RecordError(t *testing.T) {
+	var recordErr error
+	tracker := &Tracker{
+		Sender:  &fakeSender{id: "abc"},
+		Store:   failingStore{},
+		OnError: func(err error) { recordErr = err },
+	}
+
+	assert.NoError(t, tracker.Send(mailer.Message{To: "user1@testmail.com"}), "Recording errors should not fail the send")
+	assert.EqualError(t, recordErr, "sample error")
+}
+
+// recordingStore keeps the recorded attempts.
+type recordingStore struct {
+	Store
+	attempts []Attempt
+}
+
+func (s *recordingStore) Record(ctx context.Context, a Attempt) error {
+	a.ID = len(s.attempts) + 1
+	a.UpdatedAt = a.CreatedAt
+	s.attempts = append(s.attempts, a)
+	return nil
+}
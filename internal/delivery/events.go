@@ -0,0 +1,166 @@
+// internal/delivery/events.go
+
+package delivery
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Providers whose event webhooks can be parsed.
+const (
+	ProviderSendGrid = "sendgrid"
+	ProviderSES      = "ses"
+)
+
+// Event is what a mail provider reported about one sent email.
+type Event struct {
+	MessageID string
+	Email     string
+	Status    string
+	Detail    string
+	// Permanent is set on hard bounces: the address will never take mail.
+	Permanent bool
+}
+
+// Parse reads the events in a webhook body of provider. For an SES
+// subscription confirmation it returns no events and the URL to visit to
+// confirm it.
+func Parse(provider string, body []byte) (events []Event, subscribeURL string, err error) {
+	switch provider {
+	case ProviderSendGrid:
+		events, err = parseSendGrid(body)
+		return events, "", err
+	case ProviderSES:
+		return parseSES(body)
+	default:
+		return nil, "", fmt.Errorf("delivery: unknown provider %q", provider)
+	}
+}
+
+// sendGridEvent is an entry of the SendGrid event webhook, see
+// https://docs.sendgrid.com/for-developers/tracking-events/event.
+type sendGridEvent struct {
+	Email string `json:"email"`
+	Event string `json:"event"`
+	// Type tells hard bounces ("bounce") from soft ones ("blocked").
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+	// MessageID is the X-Message-Id of the send followed by a dot and the
+	// ID of the filter that processed it.
+	MessageID string `json:"sg_message_id"`
+}
+
+func parseSendGrid(body []byte) ([]Event, error) {
+	var sgEvents []sendGridEvent
+	if err := json.Unmarshal(body, &sgEvents); err != nil {
+		return nil, fmt.Errorf("delivery: sendgrid events: %v", err)
+	}
+
+	events := make([]Event, 0, len(sgEvents))
+	for _, sg := range sgEvents {
+		e := Event{Email: sg.Email, Detail: sg.Reason}
+		e.MessageID, _, _ = strings.Cut(sg.MessageID, ".")
+		switch sg.Event {
+		case "delivered":
+			e.Status = StatusDelivered
+		case "bounce":
+			e.Status, e.Permanent = StatusBounced, sg.Type != "blocked"
+		case "dropped":
+			e.Status = StatusFailed
+		case "spamreport":
+			e.Status = StatusComplained
+		default:
+			// Opens, clicks and the like say nothing about delivery.
+			continue
+		}
+		events = append(events, e)
+	}
+
+	return events, nil
+}
+
+// snsMessage is the envelope SES notifications arrive in through Amazon SNS.
+type snsMessage struct {
+	Type         string `json:"Type"`
+	Message      string `json:"Message"`
+	SubscribeURL string `json:"SubscribeURL"`
+}
+
+type sesRecipient struct {
+	EmailAddress   string `json:"emailAddress"`
+	DiagnosticCode string `json:"diagnosticCode"`
+}
+
+// sesNotification is an SES notification, see
+// https://docs.aws.amazon.com/ses/latest/dg/notification-contents.html.
+type sesNotification struct {
+	NotificationType string `json:"notificationType"`
+	// EventType replaces NotificationType in event publishing.
+	EventType string `json:"eventType"`
+	Mail      struct {
+		MessageID string `json:"messageId"`
+	} `json:"mail"`
+	Bounce struct {
+		BounceType        string         `json:"bounceType"`
+		BouncedRecipients []sesRecipient `json:"bouncedRecipients"`
+	} `json:"bounce"`
+	Complaint struct {
+		ComplainedRecipients  []sesRecipient `json:"complainedRecipients"`
+		ComplaintFeedbackType string         `json:"complaintFeedbackType"`
+	} `json:"complaint"`
+	Delivery struct {
+		Recipients []string `json:"recipients"`
+	} `json:"delivery"`
+}
+
+func parseSES(body []byte) ([]Event, string, error) {
+	var msg snsMessage
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, "", fmt.Errorf("delivery: sns message: %v", err)
+	}
+	switch msg.Type {
+	case "SubscriptionConfirmation":
+		return nil, msg.SubscribeURL, nil
+	case "Notification":
+	default:
+		return nil, "", nil
+	}
+
+	var n sesNotification
+	if err := json.Unmarshal([]byte(msg.Message), &n); err != nil {
+		return nil, "", fmt.Errorf("delivery: ses notification: %v", err)
+	}
+
+	notificationType := n.NotificationType
+	if notificationType == "" {
+		notificationType = n.EventType
+	}
+
+	var events []Event
+	switch notificationType {
+	case "Delivery":
+		for _, email := range n.Delivery.Recipients {
+			events = append(events, Event{Email: email, Status: StatusDelivered})
+		}
+	case "Bounce":
+		for _, r := range n.Bounce.BouncedRecipients {
+			events = append(events, Event{
+				Email:     r.EmailAddress,
+				Status:    StatusBounced,
+				Detail:    r.DiagnosticCode,
+				Permanent: n.Bounce.BounceType == "Permanent",
+			})
+		}
+	case "Complaint":
+		for _, r := range n.Complaint.ComplainedRecipients {
+			events = append(events, Event{Email: r.EmailAddress, Status: StatusComplained, Detail: n.Complaint.ComplaintFeedbackType})
+		}
+	}
+	for i := range events {
+		events[i].MessageID = n.Mail.MessageID
+	}
+
+	return events, "", nil
+}
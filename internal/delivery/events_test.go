@@ -0,0 +1,77 @@
+// internal/delivery/events_test.go
+
+package delivery
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		desc                 string
+		provider             string
+		body                 string
+		expectedEvents       []Event
+		expectedSubscribeURL string
+		expectedErr          bool
+	}{
+		{
+			"SendGrid",
+			ProviderSendGrid,
+			`[
+				{"email":"user1@testmail.com","event":"delivered","sg_message_id":"abc.filter0001"},
+				{"email":"user2@testmail.com","event":"bounce","type":"bounce","reason":"550 no such user","sg_message_id":"def.filter0001"},
+				{"email":"user3@testmail.com","event":"bounce","type":"blocked","reason":"421 try later","sg_message_id":"ghi.filter0001"},
+				{"email":"user4@testmail.com","event":"spamreport","sg_message_id":"jkl.filter0001"},
+				{"email":"user1@testmail.com","event":"open","sg_message_id":"abc.filter0001"}
+			]`,
+			[]Event{
+				{MessageID: "abc", Email: "user1@testmail.com", Status: StatusDelivered},
+				{MessageID: "def", Email: "user2@testmail.com", Status: StatusBounced, Detail: "550 no such user", Permanent: true},
+				{MessageID: "ghi", Email: "user3@testmail.com", Status: StatusBounced, Detail: "421 try later"},
+				{MessageID: "jkl", Email: "user4@testmail.com", Status: StatusComplained},
+			},
+			"",
+			false,
+		},
+		{
+			"SESBounce",
+			ProviderSES,
+			`{"Type":"Notification","Message":"{\"notificationType\":\"Bounce\",\"mail\":{\"messageId\":\"0100-abc\"},\"bounce\":{\"bounceType\":\"Permanent\",\"bouncedRecipients\":[{\"emailAddress\":\"user1@testmail.com\",\"diagnosticCode\":\"smtp; 550 5.1.1 user unknown\"}]}}"}`,
+			[]Event{{MessageID: "0100-abc", Email: "user1@testmail.com", Status: StatusBounced, Detail: "smtp; 550 5.1.1 user unknown", Permanent: true}},
+			"",
+			false,
+		},
+		{
+			"SESComplaint",
+			ProviderSES,
+			`{"Type":"Notification","Message":"{\"eventType\":\"Complaint\",\"mail\":{\"messageId\":\"0100-abc\"},\"complaint\":{\"complainedRecipients\":[{\"emailAddress\":\"user1@testmail.com\"}],\"complaintFeedbackType\":\"abuse\"}}"}`,
+			[]Event{{MessageID: "0100-abc", Email: "user1@testmail.com", Status: StatusComplained, Detail: "abuse"}},
+			"",
+			false,
+		},
+		{
+			"SESSubscription",
+			ProviderSES,
+			`{"Type":"SubscriptionConfirmation","SubscribeURL":"https://sns.eu-west-1.amazonaws.com/?Action=ConfirmSubscription"}`,
+			nil,
+			"https://sns.eu-west-1.amazonaws.com/?Action=ConfirmSubscription",
+			false,
+		},
+		{"InvalidJSON", ProviderSendGrid, `{`, nil, "", true},
+		{"UnknownProvider", "mailgun", `[]`, nil, "", true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			events, subscribeURL, err := Parse(tC.provider, []byte(tC.body))
+			if tC.expectedErr {
+				assert.Error(t, err, "Got no error when expected")
+				return
+			}
+			assert.NoError(t, err, "Got error when not expected")
+			assert.Equal(t, tC.expectedEvents, events)
+			assert.Equal(t, tC.expectedSubscribeURL, subscribeURL)
+		})
+	}
+}
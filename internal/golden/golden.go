@@ -0,0 +1,66 @@
+// internal/golden/golden.go
+
+// Package golden compares test output with files kept under
+// testdata/golden, so a change to a response shows up as a diff to review
+// rather than slipping through.
+//
+// After an intended change, rewrite the files from the current output with
+//
+//	go test ./internal/server -run Golden -update
+//
+// and commit them with the change. The flag is only defined in packages
+// importing golden, so name them rather than running ./...
+package golden
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Dir is where the golden files are, relative to the package under test.
+const Dir = "testdata/golden"
+
+var update = flag.Bool("update", false, "rewrite the golden files with the current output")
+
+// Assert compares got with the golden file name.
+func Assert(t testing.TB, name string, got []byte) {
+	t.Helper()
+
+	path := filepath.Join(Dir, name)
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("golden: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("golden: %v", err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		t.Fatalf("golden: %s is missing, run the test with -update to create it", path)
+	}
+	if err != nil {
+		t.Fatalf("golden: %v", err)
+	}
+	assert.Equal(t, string(want), string(got), "Output is different than %s, run the test with -update if the change is intended", path)
+}
+
+// JSON compares the JSON document body with the golden file name.json. The
+// document is indented first, so the files read well and diff line by line.
+func JSON(t testing.TB, name string, body []byte) {
+	t.Helper()
+
+	var out bytes.Buffer
+	if err := json.Indent(&out, body, "", "  "); err != nil {
+		t.Fatalf("golden: %s is not JSON: %v\n%s", name, err, body)
+	}
+	out.WriteByte('\n')
+	Assert(t, name+".json", out.Bytes())
+}
@@ -0,0 +1,13 @@
+// internal/golden/golden_test.go
+
+package golden
+
+import (
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	// Compact and indented documents match the same file.
+	JSON(t, "sample", []byte(`{"quote":{"quoteText":"Bla","quoteAuthor":"Bob"},"recipients":[]}`))
+	JSON(t, "sample", []byte("{\n\t\"quote\": {\"quoteText\": \"Bla\", \"quoteAuthor\": \"Bob\"},\n\t\"recipients\": []\n}"))
+}
@@ -0,0 +1,106 @@
+// internal/idempotency/idempotency.go
+
+// Package idempotency remembers the responses of requests made with an
+// Idempotency-Key, so a client retrying one gets the original response
+// instead of repeating its effects.
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// Record is the response saved under a key. A zero Status marks a request
+// still in progress.
+type Record struct {
+	Key string
+	// Fingerprint identifies the request first made with the key, so the
+	// key cannot be reused for another one.
+	Fingerprint string
+	Status      int
+	ContentType string
+	Body        []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// Done reports whether the response is saved.
+func (r *Record) Done() bool {
+	return r.Status != 0
+}
+
+// Store ...
+type Store interface {
+	// Reserve saves r, in progress, unless an unexpired record holds its key,
+	// which is returned instead. It returns nil once r is saved.
+	Reserve(ctx context.Context, r Record) (*Record, error)
+	// Complete saves the response of the request reserving key.
+	Complete(ctx context.Context, key string, status int, contentType string, body []byte) error
+	// Release forgets key, so the request can be made again.
+	Release(ctx context.Context, key string) error
+	// Purge deletes the records expired at now and returns how many there were.
+	Purge(ctx context.Context, now time.Time) (int, error)
+}
+
+// Persistence keeps the records in the idempotency_keys table. Like the audit
+// log, its queries run on both Postgres and SQLite.
+type Persistence struct {
+	DB *sql.DB
+}
+
+// Reserve ...
+func (p *Persistence) Reserve(ctx context.Context, r Record) (*Record, error) {
+	// An expired key is free again.
+	_, err := p.DB.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE key = $1 AND expires_at <= $2", r.Key, r.CreatedAt.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	query := `INSERT INTO idempotency_keys (key, fingerprint, created_at, expires_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (key) DO NOTHING`
+	res, err := p.DB.ExecContext(ctx, query, r.Key, r.Fingerprint, r.CreatedAt.UTC(), r.ExpiresAt.UTC())
+	if err != nil {
+		return nil, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 1 {
+		return nil, err
+	}
+
+	existing := Record{Key: r.Key}
+	var body string
+	query = "SELECT fingerprint, status, content_type, body, created_at, expires_at FROM idempotency_keys WHERE key = $1"
+	err = p.DB.QueryRowContext(ctx, query, r.Key).Scan(
+		&existing.Fingerprint, &existing.Status, &existing.ContentType, &body, &existing.CreatedAt, &existing.ExpiresAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	existing.Body = []byte(body)
+
+	return &existing, nil
+}
+
+// Complete ...
+func (p *Persistence) Complete(ctx context.Context, key string, status int, contentType string, body []byte) error {
+	query := "UPDATE idempotency_keys SET status = $2, content_type = $3, body = $4 WHERE key = $1"
+	_, err := p.DB.ExecContext(ctx, query, key, status, contentType, string(body))
+	return err
+}
+
+// Release ...
+func (p *Persistence) Release(ctx context.Context, key string) error {
+	_, err := p.DB.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE key = $1", key)
+	return err
+}
+
+// Purge ...
+func (p *Persistence) Purge(ctx context.Context, now time.Time) (int, error) {
+	res, err := p.DB.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE expires_at <= $1", now.UTC())
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	return int(n), err
+}
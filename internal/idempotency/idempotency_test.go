@@ -0,0 +1,70 @@
+// internal/idempotency/idempotency_test.go
+
+package idempotency
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/internal/dbtest"
+	"github.com/jeniok12/golang-tdd-example/internal/pgtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Main(m))
+}
+
+func TestPersistence(t *testing.T) {
+	for _, d := range dbtest.Dialects {
+		t.Run(d.Name, func(t *testing.T) {
+			testPersistence(t, &Persistence{DB: d.Open(t)})
+		})
+	}
+}
+
+func testPersistence(t *testing.T, p *Persistence) {
+	ctx := context.Background()
+	now := time.Date(2019, 8, 4, 10, 0, 0, 0, time.UTC)
+	record := Record{Key: "abc", Fingerprint: "first", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+
+	existing, err := p.Reserve(ctx, record)
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Nil(t, existing, "A new key should be reserved")
+
+	existing, err = p.Reserve(ctx, record)
+	assert.NoError(t, err, "Got error when not expected")
+	if assert.NotNil(t, existing, "A reserved key should be returned") {
+		assert.False(t, existing.Done(), "The first request should still be in progress")
+		assert.Equal(t, "first", existing.Fingerprint)
+	}
+
+	require.NoError(t, p.Complete(ctx, "abc", 201, "application/json", []byte(`{"id":1}`)))
+	existing, err = p.Reserve(ctx, Record{Key: "abc", Fingerprint: "second", CreatedAt: now.Add(time.Minute), ExpiresAt: now.Add(time.Hour)})
+	assert.NoError(t, err, "Got error when not expected")
+	if assert.NotNil(t, existing) {
+		assert.Equal(t, "first", existing.Fingerprint, "The first request should be kept")
+		assert.Equal(t, 201, existing.Status)
+		assert.Equal(t, "application/json", existing.ContentType)
+		assert.Equal(t, []byte(`{"id":1}`), existing.Body, "The saved response should be returned")
+		assert.True(t, now.Add(time.Hour).Equal(existing.ExpiresAt), "Expiry should be kept")
+	}
+
+	existing, err = p.Reserve(ctx, Record{Key: "abc", Fingerprint: "third", CreatedAt: now.Add(time.Hour), ExpiresAt: now.Add(2 * time.Hour)})
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Nil(t, existing, "An expired key should be free again")
+
+	require.NoError(t, p.Release(ctx, "abc"))
+	existing, err = p.Reserve(ctx, record)
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Nil(t, existing, "A released key should be free again")
+
+	require.NoError(t, p.Complete(ctx, "abc", 200, "", nil))
+	_, err = p.Reserve(ctx, Record{Key: "later", CreatedAt: now, ExpiresAt: now.Add(3 * time.Hour)})
+	require.NoError(t, err)
+	n, err := p.Purge(ctx, now.Add(2*time.Hour))
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, 1, n, "Only expired keys should be purged")
+}
@@ -0,0 +1,57 @@
+// internal/memstore/audit.go
+
+package memstore
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/internal/audit"
+	"sort"
+	"sync"
+)
+
+// Audit keeps the audit log.
+type Audit struct {
+	mu      sync.Mutex
+	entries []audit.Entry
+}
+
+// NewAudit returns an empty log.
+func NewAudit() *Audit {
+	return &Audit{}
+}
+
+// Record ...
+func (a *Audit) Record(ctx context.Context, e audit.Entry) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	e.ID = len(a.entries) + 1
+	a.entries = append(a.entries, e)
+
+	return nil
+}
+
+// List returns the newest entries first.
+func (a *Audit) List(ctx context.Context, f audit.Filter) ([]audit.Entry, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entries := []audit.Entry{}
+	for _, e := range a.entries {
+		if (f.Entity == "" || e.Entity == f.Entity) && !e.At.Before(f.Since) {
+			entries = append(entries, e)
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if !entries[i].At.Equal(entries[j].At) {
+			return entries[i].At.After(entries[j].At)
+		}
+		return entries[i].ID > entries[j].ID
+	})
+	if f.Limit < len(entries) {
+		entries = entries[:f.Limit]
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,43 @@
+// internal/memstore/audit_test.go
+
+package memstore
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/internal/audit"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestAudit(t *testing.T) {
+	ctx := context.Background()
+	a := NewAudit()
+	start := time.Date(2019, 6, 23, 10, 0, 0, 0, time.UTC)
+	a.Record(ctx, audit.Entry{Action: audit.ActionCreate, Entity: audit.EntityRecipient, At: start})
+	a.Record(ctx, audit.Entry{Action: audit.ActionCreate, Entity: audit.EntityGroup, At: start.Add(time.Minute)})
+	a.Record(ctx, audit.Entry{Action: audit.ActionDelete, Entity: audit.EntityRecipient, At: start.Add(time.Minute)})
+
+	testCases := []struct {
+		desc        string
+		filter      audit.Filter
+		expectedIDs []int
+	}{
+		{"All", audit.Filter{Limit: 10}, []int{3, 2, 1}},
+		{"Entity", audit.Filter{Entity: audit.EntityRecipient, Limit: 10}, []int{3, 1}},
+		{"Since", audit.Filter{Since: start.Add(time.Minute), Limit: 10}, []int{3, 2}},
+		{"Limit", audit.Filter{Limit: 2}, []int{3, 2}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			entries, err := a.List(ctx, tC.filter)
+			assert.NoError(t, err, "Got error when not expected")
+
+			ids := []int{}
+			for _, e := range entries {
+				ids = append(ids, e.ID)
+			}
+			assert.Equal(t, tC.expectedIDs, ids)
+		})
+	}
+}
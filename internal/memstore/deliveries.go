@@ -0,0 +1,57 @@
+// internal/memstore/deliveries.go
+
+package memstore
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/internal/delivery"
+	"sync"
+	"time"
+)
+
+// Deliveries keeps the delivery attempts.
+type Deliveries struct {
+	mu       sync.Mutex
+	attempts []delivery.Attempt
+}
+
+// NewDeliveries returns an empty store.
+func NewDeliveries() *Deliveries {
+	return &Deliveries{}
+}
+
+// Record ...
+func (d *Deliveries) Record(ctx context.Context, a delivery.Attempt) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	a.ID = len(d.attempts) + 1
+	a.UpdatedAt = a.CreatedAt
+	d.attempts = append(d.attempts, a)
+
+	return nil
+}
+
+// UpdateStatus ...
+func (d *Deliveries) UpdateStatus(ctx context.Context, messageID, status, detail string, at time.Time) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	n := 0
+	for i, a := range d.attempts {
+		if messageID != "" && a.MessageID == messageID {
+			d.attempts[i].Status, d.attempts[i].Detail, d.attempts[i].UpdatedAt = status, detail, at
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+// Attempts returns every attempt, oldest first.
+func (d *Deliveries) Attempts() []delivery.Attempt {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]delivery.Attempt(nil), d.attempts...)
+}
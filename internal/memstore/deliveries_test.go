@@ -0,0 +1,30 @@
+// internal/memstore/deliveries_test.go
+
+package memstore
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/internal/delivery"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestDeliveries(t *testing.T) {
+	ctx := context.Background()
+	d := NewDeliveries()
+	sent := time.Date(2019, 7, 21, 10, 0, 0, 0, time.UTC)
+	d.Record(ctx, delivery.Attempt{MessageID: "abc", Email: "user1@testmail.com", Status: delivery.StatusSent, CreatedAt: sent})
+	d.Record(ctx, delivery.Attempt{Email: "user2@testmail.com", Status: delivery.StatusFailed, CreatedAt: sent})
+
+	n, err := d.UpdateStatus(ctx, "abc", delivery.StatusDelivered, "", sent.Add(time.Minute))
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, 1, n)
+	n, _ = d.UpdateStatus(ctx, "", delivery.StatusDelivered, "", sent.Add(time.Minute))
+	assert.Equal(t, 0, n, "Attempts without a message ID should never match")
+
+	assert.Equal(t, []delivery.Attempt{
+		{ID: 1, MessageID: "abc", Email: "user1@testmail.com", Status: delivery.StatusDelivered, CreatedAt: sent, UpdatedAt: sent.Add(time.Minute)},
+		{ID: 2, Email: "user2@testmail.com", Status: delivery.StatusFailed, CreatedAt: sent, UpdatedAt: sent},
+	}, d.Attempts())
+}
@@ -0,0 +1,76 @@
+// internal/memstore/idempotency.go
+
+package memstore
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/internal/idempotency"
+	"sync"
+	"time"
+)
+
+// Idempotency keeps the responses saved under idempotency keys.
+type Idempotency struct {
+	mu      sync.Mutex
+	records map[string]idempotency.Record
+}
+
+// NewIdempotency returns an empty store.
+func NewIdempotency() *Idempotency {
+	return &Idempotency{records: make(map[string]idempotency.Record)}
+}
+
+// Reserve saves r, in progress, unless an unexpired record holds its key,
+// which is returned instead.
+func (s *Idempotency) Reserve(ctx context.Context, r idempotency.Record) (*idempotency.Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if existing, ok := s.records[r.Key]; ok && existing.ExpiresAt.After(r.CreatedAt) {
+		return &existing, nil
+	}
+
+	r.Status, r.ContentType, r.Body = 0, "", nil
+	s.records[r.Key] = r
+
+	return nil, nil
+}
+
+// Complete ...
+func (s *Idempotency) Complete(ctx context.Context, key string, status int, contentType string, body []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.records[key]
+	if !ok {
+		return nil
+	}
+	r.Status, r.ContentType, r.Body = status, contentType, append([]byte(nil), body...)
+	s.records[key] = r
+
+	return nil
+}
+
+// Release ...
+func (s *Idempotency) Release(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, key)
+	return nil
+}
+
+// Purge ...
+func (s *Idempotency) Purge(ctx context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for key, r := range s.records {
+		if !r.ExpiresAt.After(now) {
+			delete(s.records, key)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,46 @@
+// internal/memstore/idempotency_test.go
+
+package memstore
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/internal/idempotency"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestIdempotency(t *testing.T) {
+	ctx := context.Background()
+	s := NewIdempotency()
+	now := time.Date(2019, 8, 4, 10, 0, 0, 0, time.UTC)
+	record := idempotency.Record{Key: "abc", Fingerprint: "first", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+
+	existing, err := s.Reserve(ctx, record)
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Nil(t, existing, "A new key should be reserved")
+
+	existing, _ = s.Reserve(ctx, record)
+	if assert.NotNil(t, existing, "A reserved key should be returned") {
+		assert.False(t, existing.Done(), "The first request should still be in progress")
+	}
+
+	s.Complete(ctx, "abc", 201, "application/json", []byte(`{"id":1}`))
+	existing, _ = s.Reserve(ctx, idempotency.Record{Key: "abc", Fingerprint: "second", CreatedAt: now, ExpiresAt: now.Add(time.Hour)})
+	if assert.NotNil(t, existing) {
+		assert.Equal(t, "first", existing.Fingerprint)
+		assert.Equal(t, 201, existing.Status)
+		assert.Equal(t, []byte(`{"id":1}`), existing.Body)
+	}
+
+	existing, _ = s.Reserve(ctx, idempotency.Record{Key: "abc", CreatedAt: now.Add(time.Hour), ExpiresAt: now.Add(2 * time.Hour)})
+	assert.Nil(t, existing, "An expired key should be free again")
+
+	s.Release(ctx, "abc")
+	existing, _ = s.Reserve(ctx, record)
+	assert.Nil(t, existing, "A released key should be free again")
+
+	n, err := s.Purge(ctx, now.Add(time.Hour))
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, 1, n, "Expired keys should be purged")
+}
@@ -0,0 +1,94 @@
+// internal/memstore/oauth.go
+
+package memstore
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/internal/oauth"
+	"sync"
+	"time"
+)
+
+// OAuth keeps the OAuth2 clients and the revoked tokens.
+type OAuth struct {
+	mu      sync.Mutex
+	clients map[string]oauth.Client
+	revoked map[string]time.Time
+}
+
+// NewOAuth returns an empty store.
+func NewOAuth() *OAuth {
+	return &OAuth{clients: make(map[string]oauth.Client), revoked: make(map[string]time.Time)}
+}
+
+// CreateClient ...
+func (s *OAuth) CreateClient(ctx context.Context, c oauth.Client) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c.Scopes = append([]string(nil), c.Scopes...)
+	s.clients[c.ID] = c
+	return nil
+}
+
+// GetClient ...
+func (s *OAuth) GetClient(ctx context.Context, id string) (*oauth.Client, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c, ok := s.clients[id]
+	if !ok {
+		return nil, oauth.ErrNotFound
+	}
+	c.Scopes = append([]string(nil), c.Scopes...)
+	return &c, nil
+}
+
+// UpdateSecrets ...
+func (s *OAuth) UpdateSecrets(ctx context.Context, c oauth.Client) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.clients[c.ID]
+	if !ok {
+		return oauth.ErrNotFound
+	}
+	stored.SecretHash, stored.PreviousSecretHash, stored.PreviousSecretExpiresAt = c.SecretHash, c.PreviousSecretHash, c.PreviousSecretExpiresAt
+	s.clients[c.ID] = stored
+	return nil
+}
+
+// DeleteClient ...
+func (s *OAuth) DeleteClient(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.clients[id]; !ok {
+		return oauth.ErrNotFound
+	}
+	delete(s.clients, id)
+	return nil
+}
+
+// Revoke ...
+func (s *OAuth) Revoke(ctx context.Context, tokenID string, expiresAt, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, expires := range s.revoked {
+		if !expires.After(now) {
+			delete(s.revoked, id)
+		}
+	}
+	s.revoked[tokenID] = expiresAt
+	return nil
+}
+
+// Revoked ...
+func (s *OAuth) Revoked(ctx context.Context, tokenID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.revoked[tokenID]
+	return ok, nil
+}
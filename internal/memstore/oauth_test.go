@@ -0,0 +1,43 @@
+// internal/memstore/oauth_test.go
+
+package memstore
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/internal/oauth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestOAuth(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	s := NewOAuth()
+	c, secret, err := oauth.NewClient("billing", []string{"reader"})
+	require.NoError(t, err, "Got error when not expected")
+
+	require.NoError(t, s.CreateClient(ctx, c), "Got error when not expected")
+	got, err := s.GetClient(ctx, c.ID)
+	require.NoError(t, err, "Got error when not expected")
+	assert.True(t, got.Authenticate(secret, now), "The saved client should authenticate")
+
+	rotated, err := got.Rotate(0, now)
+	require.NoError(t, err, "Got error when not expected")
+	require.NoError(t, s.UpdateSecrets(ctx, *got), "Got error when not expected")
+	got, _ = s.GetClient(ctx, c.ID)
+	assert.True(t, got.Authenticate(rotated, now), "The rotated secret should be saved")
+	assert.False(t, got.Authenticate(secret, now), "The replaced secret should be dropped")
+
+	require.NoError(t, s.DeleteClient(ctx, c.ID), "Got error when not expected")
+	_, err = s.GetClient(ctx, c.ID)
+	assert.Equal(t, oauth.ErrNotFound, err, "Deleted clients should be gone")
+
+	require.NoError(t, s.Revoke(ctx, "expired", now.Add(-time.Minute), now), "Got error when not expected")
+	require.NoError(t, s.Revoke(ctx, "live", now.Add(time.Hour), now), "Got error when not expected")
+	revoked, _ := s.Revoked(ctx, "live")
+	assert.True(t, revoked, "Revoked tokens should be reported")
+	revoked, _ = s.Revoked(ctx, "expired")
+	assert.False(t, revoked, "Revocations of expired tokens should be dropped")
+}
@@ -0,0 +1,132 @@
+// internal/memstore/outbox.go
+
+package memstore
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/internal/outbox"
+	"github.com/jeniok12/golang-tdd-example/mailer"
+	"sync"
+	"time"
+)
+
+// Outbox keeps queued emails until a worker drains them.
+type Outbox struct {
+	mu      sync.Mutex
+	entries []outbox.Entry
+	now     func() time.Time
+}
+
+// NewOutbox returns an empty outbox.
+func NewOutbox() *Outbox {
+	return &Outbox{now: time.Now}
+}
+
+// Enqueue stores msgs as pending entries that are due right away.
+func (o *Outbox) Enqueue(ctx context.Context, msgs []mailer.Message) ([]outbox.Entry, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := o.now()
+	entries := make([]outbox.Entry, 0, len(msgs))
+	for _, msg := range msgs {
+		e := outbox.Entry{
+			ID:            len(o.entries) + 1,
+			Message:       msg,
+			Status:        outbox.StatusPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		}
+		o.entries = append(o.entries, e)
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
+
+// Claim returns up to limit pending entries that are due and counts an
+// attempt for each. Claimed entries are not due again until lease has passed.
+func (o *Outbox) Claim(ctx context.Context, limit int, lease time.Duration) ([]outbox.Entry, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	now := o.now()
+	var claimed []outbox.Entry
+	for i := range o.entries {
+		e := &o.entries[i]
+		if len(claimed) == limit {
+			break
+		}
+		if e.Status != outbox.StatusPending || e.NextAttemptAt.After(now) {
+			continue
+		}
+
+		e.Attempts++
+		e.NextAttemptAt = now.Add(lease)
+		claimed = append(claimed, *e)
+	}
+
+	return claimed, nil
+}
+
+// entry returns the entry with id, or nil when there is none.
+func (o *Outbox) entry(id int) *outbox.Entry {
+	if id < 1 || id > len(o.entries) {
+		return nil
+	}
+	return &o.entries[id-1]
+}
+
+// MarkSent ...
+func (o *Outbox) MarkSent(ctx context.Context, id int) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if e := o.entry(id); e != nil {
+		sentAt := o.now()
+		e.Status, e.SentAt, e.LastError = outbox.StatusSent, &sentAt, ""
+	}
+	return nil
+}
+
+// MarkRetry records a failed attempt and when to try again.
+func (o *Outbox) MarkRetry(ctx context.Context, id int, cause string, at time.Time) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if e := o.entry(id); e != nil {
+		e.LastError, e.NextAttemptAt = cause, at
+	}
+	return nil
+}
+
+// MarkFailed gives up on the entry.
+func (o *Outbox) MarkFailed(ctx context.Context, id int, cause string) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if e := o.entry(id); e != nil {
+		e.Status, e.LastError = outbox.StatusFailed, cause
+	}
+	return nil
+}
+
+// Counts ...
+func (o *Outbox) Counts(ctx context.Context) (map[string]int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	counts := map[string]int{outbox.StatusPending: 0, outbox.StatusSent: 0, outbox.StatusFailed: 0}
+	for _, e := range o.entries {
+		counts[e.Status]++
+	}
+	return counts, nil
+}
+
+// Entries returns a copy of every entry, oldest first.
+func (o *Outbox) Entries() []outbox.Entry {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return append([]outbox.Entry(nil), o.entries...)
+}
@@ -0,0 +1,53 @@
+// internal/memstore/outbox_test.go
+
+package memstore
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/internal/outbox"
+	"github.com/jeniok12/golang-tdd-example/mailer"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestOutbox(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2021, 1, 1, 9, 0, 0, 0, time.UTC)
+	o := NewOutbox()
+	o.now = func() time.Time { return now }
+
+	o.Enqueue(ctx, []mailer.Message{{To: "a@testmail.com"}, {To: "b@testmail.com"}})
+
+	claimed, err := o.Claim(ctx, 1, time.Minute)
+	assert.NoError(t, err, "Got error when not expected")
+	if assert.Len(t, claimed, 1) {
+		assert.Equal(t, 1, claimed[0].ID)
+		assert.Equal(t, 1, claimed[0].Attempts)
+	}
+
+	claimed, _ = o.Claim(ctx, 10, time.Minute)
+	if assert.Len(t, claimed, 1, "Leased entries should not be claimed again") {
+		assert.Equal(t, 2, claimed[0].ID)
+	}
+
+	o.MarkSent(ctx, 1)
+	o.MarkRetry(ctx, 2, "timeout", now.Add(time.Hour))
+	claimed, _ = o.Claim(ctx, 10, time.Minute)
+	assert.Empty(t, claimed, "Entries should not be claimed before their retry")
+
+	now = now.Add(time.Hour)
+	claimed, _ = o.Claim(ctx, 10, time.Minute)
+	if assert.Len(t, claimed, 1) {
+		assert.Equal(t, 2, claimed[0].Attempts)
+	}
+	o.MarkFailed(ctx, 2, "rejected")
+
+	entries := o.Entries()
+	assert.Equal(t, outbox.StatusSent, entries[0].Status)
+	assert.Equal(t, outbox.StatusFailed, entries[1].Status)
+	assert.Equal(t, "rejected", entries[1].LastError)
+
+	counts, _ := o.Counts(ctx)
+	assert.Equal(t, map[string]int{outbox.StatusPending: 0, outbox.StatusSent: 1, outbox.StatusFailed: 1}, counts)
+}
@@ -0,0 +1,236 @@
+// internal/memstore/quotes.go
+
+package memstore
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/tenant"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Quotes keeps the history of served quotes, one per tenant.
+type Quotes struct {
+	mu      sync.Mutex
+	records []quote.Record
+	// tenants holds the tenant of each record.
+	tenants []string
+	// byHash indexes records by tenant and quote.Hash.
+	byHash map[string]int
+	now    func() time.Time
+}
+
+// NewQuotes returns an empty history.
+func NewQuotes() *Quotes {
+	return &Quotes{byHash: make(map[string]int), now: time.Now}
+}
+
+// SaveQuote stores q in the history, once: a quote served again, by
+// quote.Hash, is counted rather than stored again, and keeps its ID and first
+// text.
+func (s *Quotes) SaveQuote(ctx context.Context, q quote.Quote) (*quote.Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	key := tenant.FromContext(ctx) + "\x00" + quote.Hash(q)
+	if i, ok := s.byHash[key]; ok {
+		s.records[i].TimesServed++
+		s.records[i].LastServedAt = &now
+		r := s.records[i]
+		return &r, nil
+	}
+
+	r := quote.Record{ID: len(s.records) + 1, Quote: q, CreatedAt: now, TimesServed: 1, LastServedAt: &now}
+	s.byHash[key] = len(s.records)
+	s.records = append(s.records, r)
+	s.tenants = append(s.tenants, tenant.FromContext(ctx))
+
+	return &r, nil
+}
+
+// visible returns the records of the tenant of ctx, oldest first.
+func (s *Quotes) visible(ctx context.Context) []quote.Record {
+	id := tenant.FromContext(ctx)
+	var records []quote.Record
+	for i, r := range s.records {
+		if s.tenants[i] == id {
+			records = append(records, r)
+		}
+	}
+	return records
+}
+
+// ListQuotes returns the quotes served last first. An empty Lang matches all
+// languages.
+func (s *Quotes) ListQuotes(ctx context.Context, filter quote.ListFilter) ([]quote.Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	visible := s.visible(ctx)
+	records := []quote.Record{}
+	for i := len(visible) - 1; i >= 0; i-- {
+		if filter.Lang == "" || visible[i].Lang == filter.Lang {
+			records = append(records, visible[i])
+		}
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].LastServedAt.After(*records[j].LastServedAt)
+	})
+
+	if filter.Offset >= len(records) {
+		return []quote.Record{}, nil
+	}
+	records = records[filter.Offset:]
+	if filter.Limit < len(records) {
+		records = records[:filter.Limit]
+	}
+
+	return records, nil
+}
+
+// SearchQuotes finds the quotes containing every word of the query, best
+// matches first.
+func (s *Quotes) SearchQuotes(ctx context.Context, filter quote.SearchFilter) ([]quote.SearchResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	words := quote.SearchWords(filter.Query)
+	visible := s.visible(ctx)
+	results := []quote.SearchResult{}
+	for i := len(visible) - 1; i >= 0; i-- {
+		if filter.Lang != "" && visible[i].Lang != filter.Lang {
+			continue
+		}
+		if r, ok := quote.Match(visible[i], words); ok {
+			results = append(results, r)
+		}
+	}
+
+	return quote.PageResults(results, filter), nil
+}
+
+// EachQuote calls fn for every stored quote, oldest first, stopping at the
+// first error. An empty lang matches all languages.
+func (s *Quotes) EachQuote(ctx context.Context, lang string, fn func(quote.Record) error) error {
+	s.mu.Lock()
+	records := s.visible(ctx)
+	s.mu.Unlock()
+
+	for _, r := range records {
+		if lang != "" && r.Lang != lang {
+			continue
+		}
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetQuote ...
+func (s *Quotes) GetQuote(ctx context.Context, id int) (*quote.Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id < 1 || id > len(s.records) || s.tenants[id-1] != tenant.FromContext(ctx) {
+		return nil, quote.ErrNotFound
+	}
+	r := s.records[id-1]
+	return &r, nil
+}
+
+// LocalQuotes serves random quotes added by admins, like the local provider.
+type LocalQuotes struct {
+	mu      sync.Mutex
+	lastID  int
+	records map[int]quote.Record
+	now     func() time.Time
+}
+
+// NewLocalQuotes returns a local provider without quotes.
+func NewLocalQuotes() *LocalQuotes {
+	return &LocalQuotes{records: make(map[int]quote.Record), now: time.Now}
+}
+
+// matching returns the quotes keep holds for, in id order.
+func (s *LocalQuotes) matching(keep func(quote.Record) bool) []quote.Record {
+	records := []quote.Record{}
+	for _, r := range s.records {
+		if keep(r) {
+			records = append(records, r)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+	return records
+}
+
+func random(records []quote.Record) (*quote.Quote, error) {
+	if len(records) == 0 {
+		return nil, quote.ErrNoQuotes
+	}
+
+	r := records[rand.Intn(len(records))]
+	return &quote.Quote{Text: r.Text, Author: r.Author, Lang: r.Lang}, nil
+}
+
+// Generate ...
+func (s *LocalQuotes) Generate(ctx context.Context, lang string) (*quote.Quote, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return random(s.matching(func(r quote.Record) bool { return r.Lang == lang }))
+}
+
+// FindByAuthor returns a random quote whose author contains author, ignoring
+// case. An empty lang matches quotes in any language.
+func (s *LocalQuotes) FindByAuthor(ctx context.Context, lang, author string) (*quote.Quote, error) {
+	return s.Find(ctx, lang, quote.Constraints{Author: author})
+}
+
+// Find returns a random quote satisfying c. An empty lang matches quotes in
+// any language.
+func (s *LocalQuotes) Find(ctx context.Context, lang string, c quote.Constraints) (*quote.Quote, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return random(s.matching(func(r quote.Record) bool {
+		return (lang == "" || r.Lang == lang) && c.Match(r.Quote)
+	}))
+}
+
+// AddLocal ...
+func (s *LocalQuotes) AddLocal(ctx context.Context, q quote.Quote) (*quote.Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	r := quote.Record{ID: s.lastID, Quote: quote.Quote{Text: q.Text, Author: q.Author, Lang: q.Lang}, CreatedAt: s.now()}
+	s.records[r.ID] = r
+
+	return &r, nil
+}
+
+// ListLocal ...
+func (s *LocalQuotes) ListLocal(ctx context.Context, lang string) ([]quote.Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.matching(func(r quote.Record) bool { return lang == "" || r.Lang == lang }), nil
+}
+
+// RemoveLocal ...
+func (s *LocalQuotes) RemoveLocal(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.records[id]; !ok {
+		return quote.ErrNotFound
+	}
+	delete(s.records, id)
+
+	return nil
+}
@@ -0,0 +1,74 @@
+// internal/memstore/ratings.go
+
+package memstore
+
+import (
+	"context"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/rating"
+	"sync"
+	"time"
+)
+
+// Ratings keeps the ratings of the quotes in a history.
+type Ratings struct {
+	mu      sync.Mutex
+	quotes  *Quotes
+	ratings map[ratingKey]rating.Rating
+}
+
+type ratingKey struct {
+	quoteID int
+	rater   string
+}
+
+// NewRatings returns a store without ratings of the quotes in quotes.
+func NewRatings(quotes *Quotes) *Ratings {
+	return &Ratings{quotes: quotes, ratings: make(map[ratingKey]rating.Rating)}
+}
+
+// Rate ...
+func (s *Ratings) Rate(ctx context.Context, r rating.Rating) error {
+	if _, err := s.quotes.GetQuote(ctx, r.QuoteID); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ratings[ratingKey{r.QuoteID, r.Rater}] = r
+	return nil
+}
+
+// Trending ...
+func (s *Ratings) Trending(ctx context.Context, since time.Time, limit int) ([]rating.Trend, error) {
+	s.mu.Lock()
+	sums, votes := make(map[int]int), make(map[int]int)
+	for _, r := range s.ratings {
+		if !r.RatedAt.Before(since) {
+			sums[r.QuoteID] += r.Score
+			votes[r.QuoteID]++
+		}
+	}
+	s.mu.Unlock()
+
+	trends := []rating.Trend{}
+	for id, n := range votes {
+		record, err := s.quotes.GetQuote(ctx, id)
+		if errors.Is(err, quote.ErrNotFound) {
+			// A quote of another tenant.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		trends = append(trends, rating.Trend{Record: *record, Average: float64(sums[id]) / float64(n), Votes: n})
+	}
+
+	rating.SortTrends(trends)
+	if limit < len(trends) {
+		trends = trends[:limit]
+	}
+	return trends, nil
+}
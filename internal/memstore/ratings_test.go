@@ -0,0 +1,55 @@
+// internal/memstore/ratings_test.go
+
+package memstore
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/rating"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestRatings(t *testing.T) {
+	ctx := context.Background()
+	quotes := NewQuotes()
+	for _, text := range []string{"Bla", "Foo", "Bar"} {
+		quotes.SaveQuote(ctx, quote.Quote{Text: text})
+	}
+	s := NewRatings(quotes)
+	start := time.Date(2019, 8, 25, 10, 0, 0, 0, time.UTC)
+	s.Rate(ctx, rating.Rating{QuoteID: 1, Rater: "key:a", Score: 3, RatedAt: start})
+	s.Rate(ctx, rating.Rating{QuoteID: 1, Rater: "key:b", Score: 4, RatedAt: start.Add(time.Hour)})
+	s.Rate(ctx, rating.Rating{QuoteID: 2, Rater: "key:a", Score: 1, RatedAt: start.Add(time.Hour)})
+	s.Rate(ctx, rating.Rating{QuoteID: 2, Rater: "key:a", Score: 5, RatedAt: start.Add(2 * time.Hour)})
+	s.Rate(ctx, rating.Rating{QuoteID: 3, Rater: "key:a", Score: 2, RatedAt: start.Add(2 * time.Hour)})
+	assert.ErrorIs(t, s.Rate(ctx, rating.Rating{QuoteID: 42, Rater: "key:a", Score: 5}), quote.ErrNotFound)
+
+	testCases := []struct {
+		desc        string
+		since       time.Time
+		limit       int
+		expectedIDs []int
+	}{
+		{"All", start, 10, []int{2, 1, 3}},
+		{"Window", start.Add(90 * time.Minute), 10, []int{2, 3}},
+		{"Limit", start, 1, []int{2}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			trends, err := s.Trending(ctx, tC.since, tC.limit)
+			assert.NoError(t, err, "Got error when not expected")
+
+			ids := []int{}
+			for _, tr := range trends {
+				ids = append(ids, tr.ID)
+			}
+			assert.Equal(t, tC.expectedIDs, ids)
+		})
+	}
+
+	trends, _ := s.Trending(ctx, start, 10)
+	assert.Equal(t, rating.Trend{Record: trends[1].Record, Average: 3.5, Votes: 2}, trends[1], "Scores should be averaged over raters")
+	assert.Equal(t, "Bla", trends[1].Text)
+}
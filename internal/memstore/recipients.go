@@ -0,0 +1,491 @@
+// internal/memstore/recipients.go
+
+// Package memstore keeps everything the server persists in memory, for unit
+// tests and for running locally without Postgres. The stores mirror the
+// behavior and errors of their Postgres counterparts; data is lost on exit.
+package memstore
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/jeniok12/golang-tdd-example/tenant"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type favorite struct {
+	quoteID   int
+	createdAt time.Time
+}
+
+// Recipients keeps recipients, their groups, tags and favorites and the
+// suppression list. Favorites refer to the quote history in Quotes. Each
+// tenant sees its own recipients, the tenant of the context.
+type Recipients struct {
+	Quotes *Quotes
+
+	mu          sync.Mutex
+	lastID      int
+	recipients  map[int]recipient.Recipient
+	tenants     map[int]string
+	lastGroupID int
+	groups      map[int]recipient.Group
+	members     map[int]map[int]bool
+	tags        map[int]map[string]bool
+	favorites   map[int][]favorite
+	suppressed  map[string]bool
+	now         func() time.Time
+}
+
+// NewRecipients returns an empty store whose favorites refer to quotes.
+func NewRecipients(quotes *Quotes) *Recipients {
+	return &Recipients{
+		Quotes:     quotes,
+		recipients: make(map[int]recipient.Recipient),
+		tenants:    make(map[int]string),
+		groups:     make(map[int]recipient.Group),
+		members:    make(map[int]map[int]bool),
+		tags:       make(map[int]map[string]bool),
+		favorites:  make(map[int][]favorite),
+		suppressed: make(map[string]bool),
+		now:        time.Now,
+	}
+}
+
+// get returns the recipient with id, if it belongs to the tenant of ctx.
+func (s *Recipients) get(ctx context.Context, id int) (recipient.Recipient, bool) {
+	r, ok := s.recipients[id]
+	if !ok || s.tenants[id] != tenant.FromContext(ctx) {
+		return recipient.Recipient{}, false
+	}
+	return r, true
+}
+
+// add stores r as a new recipient of the tenant of ctx.
+func (s *Recipients) add(ctx context.Context, r recipient.Recipient) recipient.Recipient {
+	s.lastID++
+	r.ID = s.lastID
+	r.Verified = false
+	r.Version = 1
+	s.recipients[r.ID] = r
+	s.tenants[r.ID] = tenant.FromContext(ctx)
+	return r
+}
+
+// sorted returns the recipients of the tenant of ctx that keep holds, in id
+// order.
+func (s *Recipients) sorted(ctx context.Context, keep func(recipient.Recipient) bool) []recipient.Recipient {
+	id := tenant.FromContext(ctx)
+	var rs []recipient.Recipient
+	for _, r := range s.recipients {
+		if s.tenants[r.ID] == id && keep(r) {
+			rs = append(rs, r)
+		}
+	}
+	sort.Slice(rs, func(i, j int) bool { return rs[i].ID < rs[j].ID })
+	return rs
+}
+
+// emailTaken reports whether a recipient of the tenant of ctx other than id
+// uses email.
+func (s *Recipients) emailTaken(ctx context.Context, email string, id int) bool {
+	for _, r := range s.sorted(ctx, func(recipient.Recipient) bool { return true }) {
+		if r.ID != id && strings.EqualFold(r.Email, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllRecipients returns every verified recipient.
+func (s *Recipients) AllRecipients(ctx context.Context) ([]recipient.Recipient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sorted(ctx, func(r recipient.Recipient) bool { return r.Verified }), nil
+}
+
+// ListRecipients returns one page of the recipients matching opts.
+func (s *Recipients) ListRecipients(ctx context.Context, opts recipient.ListOptions) ([]recipient.Recipient, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	query := strings.ToLower(opts.Query)
+	rs := s.sorted(ctx, func(r recipient.Recipient) bool {
+		return strings.Contains(strings.ToLower(r.Name), query) || strings.Contains(strings.ToLower(r.Email), query)
+	})
+
+	field, desc := strings.TrimPrefix(opts.OrderBy, "-"), strings.HasPrefix(opts.OrderBy, "-")
+	key := func(r recipient.Recipient) string {
+		if field == "email" {
+			return r.Email
+		}
+		return r.Name
+	}
+	if field != "" && field != "id" {
+		// Stable, so recipients with equal keys stay in id order like in Postgres.
+		sort.SliceStable(rs, func(i, j int) bool {
+			if desc {
+				return key(rs[i]) > key(rs[j])
+			}
+			return key(rs[i]) < key(rs[j])
+		})
+	} else if desc {
+		sort.SliceStable(rs, func(i, j int) bool { return rs[i].ID > rs[j].ID })
+	}
+
+	if opts.Offset >= len(rs) {
+		return nil, nil
+	}
+	rs = rs[opts.Offset:]
+	if opts.Limit > 0 && opts.Limit < len(rs) {
+		rs = rs[:opts.Limit]
+	}
+
+	return rs, nil
+}
+
+// EachRecipient calls fn for every recipient, verified or not, in id order,
+// stopping at the first error.
+func (s *Recipients) EachRecipient(ctx context.Context, fn func(recipient.Recipient) error) error {
+	s.mu.Lock()
+	rs := s.sorted(ctx, func(recipient.Recipient) bool { return true })
+	s.mu.Unlock()
+
+	for _, r := range rs {
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Create adds an unverified recipient.
+func (s *Recipients) Create(ctx context.Context, r recipient.Recipient) (*recipient.Recipient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.emailTaken(ctx, r.Email, 0) {
+		return nil, recipient.ErrDuplicateEmail
+	}
+
+	r = s.add(ctx, r)
+	return &r, nil
+}
+
+// GetByID ...
+func (s *Recipients) GetByID(ctx context.Context, id int) (*recipient.Recipient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.get(ctx, id)
+	if !ok {
+		return nil, recipient.ErrNotFound
+	}
+	return &r, nil
+}
+
+// Update changes the recipient's name and email. Changing the email clears
+// the verified flag. A non-zero r.Version must match the stored one.
+func (s *Recipients) Update(ctx context.Context, r recipient.Recipient) (*recipient.Recipient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	old, ok := s.get(ctx, r.ID)
+	if !ok {
+		return nil, recipient.ErrNotFound
+	}
+	if r.Version != 0 && r.Version != old.Version {
+		return nil, recipient.ErrVersionMismatch
+	}
+	if s.emailTaken(ctx, r.Email, r.ID) {
+		return nil, recipient.ErrDuplicateEmail
+	}
+
+	r.Verified = old.Verified && strings.EqualFold(old.Email, r.Email)
+	r.Preferences = old.Preferences
+	r.Version = old.Version + 1
+	s.recipients[r.ID] = r
+
+	return &r, nil
+}
+
+// MarkVerified marks the recipient verified, provided its email is still the
+// one the verification was issued for.
+func (s *Recipients) MarkVerified(ctx context.Context, id int, email string) (*recipient.Recipient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.get(ctx, id)
+	if !ok || !strings.EqualFold(r.Email, email) {
+		return nil, recipient.ErrNotFound
+	}
+
+	r.Verified = true
+	r.Version++
+	s.recipients[id] = r
+
+	return &r, nil
+}
+
+// UpdatePreferences replaces the recipient's preferences.
+func (s *Recipients) UpdatePreferences(ctx context.Context, id int, prefs recipient.Preferences) (*recipient.Recipient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r, ok := s.get(ctx, id)
+	if !ok {
+		return nil, recipient.ErrNotFound
+	}
+
+	r.Preferences = prefs
+	r.Channels = slices.Clone(prefs.Channels)
+	r.Version++
+	s.recipients[id] = r
+
+	return &r, nil
+}
+
+// Delete removes the recipient along with its memberships, tags and favorites.
+func (s *Recipients) Delete(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.get(ctx, id); !ok {
+		return recipient.ErrNotFound
+	}
+
+	delete(s.recipients, id)
+	delete(s.tenants, id)
+	delete(s.favorites, id)
+	delete(s.tags, id)
+	for _, members := range s.members {
+		delete(members, id)
+	}
+
+	return nil
+}
+
+// Import adds rs as unverified recipients, skipping emails that are already
+// taken, including earlier in rs. The result is indexed like rs, holding nil
+// for skipped recipients.
+func (s *Recipients) Import(ctx context.Context, rs []recipient.Recipient) ([]*recipient.Recipient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	created := make([]*recipient.Recipient, len(rs))
+	for i, r := range rs {
+		if s.emailTaken(ctx, r.Email, 0) {
+			continue
+		}
+
+		r = s.add(ctx, r)
+		created[i] = &r
+	}
+
+	return created, nil
+}
+
+// CreateGroup ...
+func (s *Recipients) CreateGroup(ctx context.Context, name string) (*recipient.Group, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, g := range s.groups {
+		if g.Name == name {
+			return nil, recipient.ErrDuplicateGroup
+		}
+	}
+
+	s.lastGroupID++
+	g := recipient.Group{ID: s.lastGroupID, Name: name}
+	s.groups[g.ID] = g
+	s.members[g.ID] = make(map[int]bool)
+
+	return &g, nil
+}
+
+// ListGroups returns the groups ordered by name.
+func (s *Recipients) ListGroups(ctx context.Context) ([]recipient.Group, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	groups := []recipient.Group{}
+	for _, g := range s.groups {
+		groups = append(groups, g)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+
+	return groups, nil
+}
+
+// AddMember ...
+func (s *Recipients) AddMember(ctx context.Context, groupID, recipientID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	members, ok := s.members[groupID]
+	if _, found := s.get(ctx, recipientID); !ok || !found {
+		return recipient.ErrNotFound
+	}
+	members[recipientID] = true
+
+	return nil
+}
+
+// RemoveMember ...
+func (s *Recipients) RemoveMember(ctx context.Context, groupID, recipientID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.get(ctx, recipientID); !ok || !s.members[groupID][recipientID] {
+		return recipient.ErrNotFound
+	}
+	delete(s.members[groupID], recipientID)
+
+	return nil
+}
+
+// GroupRecipients returns the verified members of the group with the given name.
+func (s *Recipients) GroupRecipients(ctx context.Context, group string) ([]recipient.Recipient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, g := range s.groups {
+		if g.Name == group {
+			members := s.members[g.ID]
+			return s.sorted(ctx, func(r recipient.Recipient) bool { return r.Verified && members[r.ID] }), nil
+		}
+	}
+
+	return nil, recipient.ErrGroupNotFound
+}
+
+// AddTag tags the recipient. Adding a tag twice is a no-op.
+func (s *Recipients) AddTag(ctx context.Context, recipientID int, tag string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.get(ctx, recipientID); !ok {
+		return recipient.ErrNotFound
+	}
+	if s.tags[recipientID] == nil {
+		s.tags[recipientID] = make(map[string]bool)
+	}
+	s.tags[recipientID][tag] = true
+
+	return nil
+}
+
+// RemoveTag ...
+func (s *Recipients) RemoveTag(ctx context.Context, recipientID int, tag string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.get(ctx, recipientID); !ok || !s.tags[recipientID][tag] {
+		return recipient.ErrNotFound
+	}
+	delete(s.tags[recipientID], tag)
+
+	return nil
+}
+
+// ListTags returns the recipient's tags in alphabetical order.
+func (s *Recipients) ListTags(ctx context.Context, recipientID int) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.get(ctx, recipientID); !ok {
+		return nil, recipient.ErrNotFound
+	}
+	tags := []string{}
+	for tag := range s.tags[recipientID] {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	return tags, nil
+}
+
+// TaggedRecipients returns the verified recipients having any of tags.
+func (s *Recipients) TaggedRecipients(ctx context.Context, tags []string) ([]recipient.Recipient, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.sorted(ctx, func(r recipient.Recipient) bool {
+		if !r.Verified {
+			return false
+		}
+		for _, tag := range tags {
+			if s.tags[r.ID][tag] {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
+
+// AddFavorite saves the quote with quoteID as a favorite of the recipient.
+// Saving a favorite twice keeps the original one.
+func (s *Recipients) AddFavorite(ctx context.Context, recipientID, quoteID int) (*recipient.Favorite, error) {
+	q, err := s.Quotes.GetQuote(ctx, quoteID)
+	if err != nil {
+		return nil, recipient.ErrQuoteNotFound
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.get(ctx, recipientID); !ok {
+		return nil, recipient.ErrNotFound
+	}
+
+	createdAt := s.now()
+	saved := false
+	for _, f := range s.favorites[recipientID] {
+		if f.quoteID == quoteID {
+			createdAt, saved = f.createdAt, true
+		}
+	}
+	if !saved {
+		s.favorites[recipientID] = append(s.favorites[recipientID], favorite{quoteID: quoteID, createdAt: createdAt})
+	}
+
+	return &recipient.Favorite{QuoteID: q.ID, Text: q.Text, Author: q.Author, Lang: q.Lang, CreatedAt: createdAt}, nil
+}
+
+// ListFavorites returns the recipient's favorites, newest first.
+func (s *Recipients) ListFavorites(ctx context.Context, recipientID int) ([]recipient.Favorite, error) {
+	s.mu.Lock()
+	if _, ok := s.get(ctx, recipientID); !ok {
+		s.mu.Unlock()
+		return nil, recipient.ErrNotFound
+	}
+	saved := append([]favorite(nil), s.favorites[recipientID]...)
+	s.mu.Unlock()
+
+	sort.Slice(saved, func(i, j int) bool {
+		if !saved[i].createdAt.Equal(saved[j].createdAt) {
+			return saved[i].createdAt.After(saved[j].createdAt)
+		}
+		return saved[i].quoteID > saved[j].quoteID
+	})
+
+	favorites := []recipient.Favorite{}
+	for _, f := range saved {
+		q, err := s.Quotes.GetQuote(ctx, f.quoteID)
+		if err != nil {
+			continue
+		}
+		favorites = append(favorites, recipient.Favorite{QuoteID: q.ID, Text: q.Text, Author: q.Author, Lang: q.Lang, CreatedAt: f.createdAt})
+	}
+
+	return favorites, nil
+}
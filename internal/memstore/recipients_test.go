@@ -0,0 +1,270 @@
+// internal/memstore/recipients_test.go
+
+package memstore
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/jeniok12/golang-tdd-example/tenant"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func newRecipients(t *testing.T, names ...string) *Recipients {
+	s := NewRecipients(NewQuotes())
+	for _, name := range names {
+		_, err := s.Create(context.Background(), recipient.Recipient{Name: name, Email: name + "@testmail.com"})
+		assert.NoError(t, err, "Got error when not expected")
+	}
+	return s
+}
+
+func TestRecipients_ListRecipients(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		opts          recipient.ListOptions
+		expectedNames []string
+		expectedErr   bool
+	}{
+		{"All", recipient.ListOptions{Limit: 100}, []string{"carol", "alice", "bob"}, false},
+		{"Query", recipient.ListOptions{Limit: 100, Query: "AL"}, []string{"alice"}, false},
+		{"OrderByNameDesc", recipient.ListOptions{Limit: 100, OrderBy: "-name"}, []string{"carol", "bob", "alice"}, false},
+		{"Page", recipient.ListOptions{Limit: 1, Offset: 1}, []string{"alice"}, false},
+		{"PastTheEnd", recipient.ListOptions{Limit: 1, Offset: 3}, nil, false},
+		{"InvalidOrder", recipient.ListOptions{Limit: 100, OrderBy: "password"}, nil, true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			s := newRecipients(t, "carol", "alice", "bob")
+
+			rs, err := s.ListRecipients(context.Background(), tC.opts)
+			if tC.expectedErr {
+				assert.Error(t, err, "Got no error when expected")
+				return
+			}
+			assert.NoError(t, err, "Got error when not expected")
+
+			var names []string
+			for _, r := range rs {
+				names = append(names, r.Name)
+			}
+			assert.Equal(t, tC.expectedNames, names)
+		})
+	}
+}
+
+func TestRecipients_Lifecycle(t *testing.T) {
+	ctx := context.Background()
+	s := newRecipients(t, "alice")
+
+	_, err := s.Create(ctx, recipient.Recipient{Name: "other", Email: "ALICE@testmail.com"})
+	assert.Equal(t, recipient.ErrDuplicateEmail, err, "Emails should be unique regardless of case")
+
+	all, _ := s.AllRecipients(ctx)
+	assert.Empty(t, all, "Unverified recipients should not get quotes")
+
+	_, err = s.MarkVerified(ctx, 1, "old@testmail.com")
+	assert.Equal(t, recipient.ErrNotFound, err, "Verification should be bound to the email")
+
+	r, err := s.MarkVerified(ctx, 1, "alice@testmail.com")
+	assert.NoError(t, err, "Got error when not expected")
+	assert.True(t, r.Verified)
+
+	r, err = s.Update(ctx, recipient.Recipient{ID: 1, Name: "alice", Email: "new@testmail.com"})
+	assert.NoError(t, err, "Got error when not expected")
+	assert.False(t, r.Verified, "Changing the email should clear verification")
+
+	assert.NoError(t, s.Delete(ctx, 1), "Got error when not expected")
+	assert.Equal(t, recipient.ErrNotFound, s.Delete(ctx, 1))
+	_, err = s.GetByID(ctx, 1)
+	assert.Equal(t, recipient.ErrNotFound, err)
+}
+
+func TestRecipients_Versions(t *testing.T) {
+	ctx := context.Background()
+	s := newRecipients(t, "alice")
+
+	r, err := s.GetByID(ctx, 1)
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, 1, r.Version, "New recipients should start at version 1")
+
+	r, err = s.Update(ctx, recipient.Recipient{ID: 1, Name: "alice", Email: "alice@testmail.com", Version: 1})
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, 2, r.Version, "Updates should bump the version")
+
+	_, err = s.Update(ctx, recipient.Recipient{ID: 1, Name: "bob", Email: "alice@testmail.com", Version: 1})
+	assert.Equal(t, recipient.ErrVersionMismatch, err, "Updates based on a stale version should fail")
+	_, err = s.Update(ctx, recipient.Recipient{ID: 2, Name: "bob", Email: "bob@testmail.com", Version: 1})
+	assert.Equal(t, recipient.ErrNotFound, err)
+
+	r, err = s.MarkVerified(ctx, 1, "alice@testmail.com")
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, 3, r.Version, "Verifying should bump the version")
+
+	r, err = s.UpdatePreferences(ctx, 1, recipient.Preferences{Lang: "en"})
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, 4, r.Version, "Changing preferences should bump the version")
+
+	r, err = s.Update(ctx, recipient.Recipient{ID: 1, Name: "bob", Email: "alice@testmail.com"})
+	assert.NoError(t, err, "Updates without a version should not be checked")
+	assert.Equal(t, 5, r.Version)
+}
+
+func TestRecipients_Import(t *testing.T) {
+	s := newRecipients(t, "alice")
+
+	created, err := s.Import(context.Background(), []recipient.Recipient{
+		{Name: "alice", Email: "alice@testmail.com"},
+		{Name: "bob", Email: "bob@testmail.com"},
+		{Name: "bob again", Email: "bob@testmail.com"},
+	})
+	assert.NoError(t, err, "Got error when not expected")
+	if assert.Len(t, created, 3) {
+		assert.Nil(t, created[0], "Taken emails should be skipped")
+		assert.Equal(t, 2, created[1].ID)
+		assert.Nil(t, created[2], "Emails repeated in the import should be skipped")
+	}
+}
+
+func TestRecipients_Groups(t *testing.T) {
+	ctx := context.Background()
+	s := newRecipients(t, "alice", "bob")
+	s.MarkVerified(ctx, 1, "alice@testmail.com")
+
+	g, err := s.CreateGroup(ctx, "team")
+	assert.NoError(t, err, "Got error when not expected")
+	_, err = s.CreateGroup(ctx, "team")
+	assert.Equal(t, recipient.ErrDuplicateGroup, err)
+
+	assert.NoError(t, s.AddMember(ctx, g.ID, 1), "Got error when not expected")
+	assert.NoError(t, s.AddMember(ctx, g.ID, 2), "Got error when not expected")
+	assert.Equal(t, recipient.ErrNotFound, s.AddMember(ctx, g.ID, 3))
+	assert.Equal(t, recipient.ErrNotFound, s.AddMember(ctx, 42, 1))
+
+	rs, err := s.GroupRecipients(ctx, "team")
+	assert.NoError(t, err, "Got error when not expected")
+	if assert.Len(t, rs, 1, "Only verified members should be returned") {
+		assert.Equal(t, "alice", rs[0].Name)
+	}
+
+	assert.NoError(t, s.RemoveMember(ctx, g.ID, 1), "Got error when not expected")
+	assert.Equal(t, recipient.ErrNotFound, s.RemoveMember(ctx, g.ID, 1))
+
+	_, err = s.GroupRecipients(ctx, "nobody")
+	assert.Equal(t, recipient.ErrGroupNotFound, err)
+}
+
+func TestRecipients_Tags(t *testing.T) {
+	ctx := context.Background()
+	s := newRecipients(t, "alice", "bob", "carol")
+	s.MarkVerified(ctx, 1, "alice@testmail.com")
+	s.MarkVerified(ctx, 2, "bob@testmail.com")
+
+	assert.NoError(t, s.AddTag(ctx, 1, "devs"), "Got error when not expected")
+	assert.NoError(t, s.AddTag(ctx, 1, "devs"), "Adding a tag twice should be a no-op")
+	assert.NoError(t, s.AddTag(ctx, 1, "berlin"), "Got error when not expected")
+	assert.NoError(t, s.AddTag(ctx, 2, "managers"), "Got error when not expected")
+	assert.NoError(t, s.AddTag(ctx, 3, "devs"), "Got error when not expected")
+	assert.Equal(t, recipient.ErrNotFound, s.AddTag(ctx, 42, "devs"))
+
+	tags, err := s.ListTags(ctx, 1)
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, []string{"berlin", "devs"}, tags)
+	_, err = s.ListTags(ctx, 42)
+	assert.Equal(t, recipient.ErrNotFound, err)
+
+	rs, err := s.TaggedRecipients(ctx, []string{"devs", "managers"})
+	assert.NoError(t, err, "Got error when not expected")
+	if assert.Len(t, rs, 2, "Only verified recipients with any of the tags should be returned") {
+		assert.Equal(t, "alice", rs[0].Name)
+		assert.Equal(t, "bob", rs[1].Name)
+	}
+
+	assert.NoError(t, s.RemoveTag(ctx, 1, "devs"), "Got error when not expected")
+	assert.Equal(t, recipient.ErrNotFound, s.RemoveTag(ctx, 1, "devs"))
+
+	rs, err = s.TaggedRecipients(ctx, []string{"devs"})
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Empty(t, rs, "Removed tags should not match")
+}
+
+func TestRecipients_Favorites(t *testing.T) {
+	ctx := context.Background()
+	s := newRecipients(t, "alice")
+	s.Quotes.SaveQuote(ctx, quote.Quote{Text: "first", Author: "Bob", Lang: "en"})
+	s.Quotes.SaveQuote(ctx, quote.Quote{Text: "second", Author: "Bob", Lang: "en"})
+
+	for _, id := range []int{1, 2, 1} {
+		_, err := s.AddFavorite(ctx, 1, id)
+		assert.NoError(t, err, "Got error when not expected")
+	}
+	_, err := s.AddFavorite(ctx, 1, 3)
+	assert.Equal(t, recipient.ErrQuoteNotFound, err)
+	_, err = s.AddFavorite(ctx, 2, 1)
+	assert.Equal(t, recipient.ErrNotFound, err)
+
+	favorites, err := s.ListFavorites(ctx, 1)
+	assert.NoError(t, err, "Got error when not expected")
+	var texts []string
+	for _, f := range favorites {
+		texts = append(texts, f.Text)
+	}
+	assert.Equal(t, []string{"second", "first"}, texts, "Favorites should be listed once, newest first")
+}
+
+func TestRecipients_Tenants(t *testing.T) {
+	ctx := context.Background()
+	acme := tenant.NewContext(ctx, "acme")
+	s := newRecipients(t, "alice")
+	s.Quotes.SaveQuote(ctx, quote.Quote{Text: "first", Author: "Bob", Lang: "en"})
+
+	created, err := s.Create(acme, recipient.Recipient{Name: "alice", Email: "ALICE@testmail.com"})
+	assert.NoError(t, err, "Emails should only be unique within a tenant")
+
+	rs, err := s.ListRecipients(acme, recipient.ListOptions{Limit: 100})
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Len(t, rs, 1, "Tenants should only list their own recipients")
+	assert.Equal(t, created.ID, rs[0].ID)
+
+	_, err = s.GetByID(acme, 1)
+	assert.Equal(t, recipient.ErrNotFound, err, "Recipients of other tenants should be missing")
+	_, err = s.GetByID(ctx, created.ID)
+	assert.Equal(t, recipient.ErrNotFound, err, "Recipients of other tenants should be missing")
+	assert.Equal(t, recipient.ErrNotFound, s.Delete(ctx, created.ID), "Recipients of other tenants should not be deleted")
+
+	_, err = s.AddFavorite(acme, created.ID, 1)
+	assert.Equal(t, recipient.ErrQuoteNotFound, err, "Quotes of other tenants should be missing")
+}
+
+func TestRecipients_Preferences(t *testing.T) {
+	ctx := context.Background()
+	s := newRecipients(t, "alice")
+	prefs := recipient.Preferences{Lang: "ru", Frequency: recipient.FrequencyWeekly}
+
+	r, err := s.UpdatePreferences(ctx, 1, prefs)
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, prefs, r.Preferences, "Preferences should be updated")
+
+	r, err = s.Update(ctx, recipient.Recipient{ID: 1, Name: "alice", Email: "alice@testmail.com"})
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, prefs, r.Preferences, "Updating name and email should keep preferences")
+
+	r, _ = s.GetByID(ctx, 1)
+	assert.Equal(t, prefs, r.Preferences, "Preferences should be persisted")
+
+	_, err = s.UpdatePreferences(ctx, 2, prefs)
+	assert.Equal(t, recipient.ErrNotFound, err, "Should get ErrNotFound for unknown ID")
+}
+
+func TestRecipients_Suppressions(t *testing.T) {
+	ctx := context.Background()
+	s := newRecipients(t)
+
+	assert.NoError(t, s.Suppress(ctx, "User1@testmail.com"), "Got error when not expected")
+	assert.NoError(t, s.Suppress(ctx, "user1@testmail.com"), "Suppressing twice should not fail")
+
+	suppressed, err := s.Suppressed(ctx, []string{"USER1@testmail.com", "user2@testmail.com"})
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, map[string]bool{"user1@testmail.com": true}, suppressed, "Emails should be matched regardless of case")
+}
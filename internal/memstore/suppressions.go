@@ -0,0 +1,33 @@
+// internal/memstore/suppressions.go
+
+package memstore
+
+import (
+	"context"
+	"strings"
+)
+
+// Suppress adds email to the suppression list. Suppressing an email twice
+// is not an error.
+func (s *Recipients) Suppress(ctx context.Context, email string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.suppressed[strings.ToLower(email)] = true
+	return nil
+}
+
+// Suppressed returns which of emails are on the suppression list, keyed by
+// their lowercased form.
+func (s *Recipients) Suppressed(ctx context.Context, emails []string) (map[string]bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	suppressed := make(map[string]bool)
+	for _, email := range emails {
+		if email = strings.ToLower(email); s.suppressed[email] {
+			suppressed[email] = true
+		}
+	}
+	return suppressed, nil
+}
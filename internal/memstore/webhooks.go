@@ -0,0 +1,188 @@
+// internal/memstore/webhooks.go
+
+package memstore
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/jeniok12/golang-tdd-example/internal/webhook"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Webhooks keeps the registered hooks and their delivery log.
+type Webhooks struct {
+	mu         sync.Mutex
+	hooks      []webhook.Hook
+	deliveries []webhook.Delivery
+	nextHookID int
+	now        func() time.Time
+}
+
+// NewWebhooks returns a store without hooks.
+func NewWebhooks() *Webhooks {
+	return &Webhooks{now: time.Now}
+}
+
+// CreateHook ...
+func (s *Webhooks) CreateHook(ctx context.Context, h webhook.Hook) (*webhook.Hook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextHookID++
+	h.ID, h.CreatedAt = s.nextHookID, s.now()
+	s.hooks = append(s.hooks, h)
+
+	return &h, nil
+}
+
+// ListHooks returns every hook, oldest first.
+func (s *Webhooks) ListHooks(ctx context.Context) ([]webhook.Hook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]webhook.Hook{}, s.hooks...), nil
+}
+
+// hook returns the hook with id, or nil when there is none.
+func (s *Webhooks) hook(id int) *webhook.Hook {
+	for i := range s.hooks {
+		if s.hooks[i].ID == id {
+			return &s.hooks[i]
+		}
+	}
+	return nil
+}
+
+// DeleteHook ...
+func (s *Webhooks) DeleteHook(ctx context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.hook(id) == nil {
+		return webhook.ErrNotFound
+	}
+	s.hooks = slices.DeleteFunc(s.hooks, func(h webhook.Hook) bool { return h.ID == id })
+	// Deliveries keep their IDs, which are indexes into the log.
+	for i := range s.deliveries {
+		if s.deliveries[i].HookID == id {
+			s.deliveries[i].HookID = 0
+		}
+	}
+
+	return nil
+}
+
+// Enqueue ...
+func (s *Webhooks) Enqueue(ctx context.Context, event string, payload []byte) ([]webhook.Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	deliveries := make([]webhook.Delivery, 0, len(s.hooks))
+	for _, h := range s.hooks {
+		d := webhook.Delivery{
+			ID:            len(s.deliveries) + 1,
+			HookID:        h.ID,
+			Event:         event,
+			Payload:       json.RawMessage(slices.Clone(payload)),
+			Status:        webhook.StatusPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		}
+		s.deliveries = append(s.deliveries, d)
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, nil
+}
+
+// ListDeliveries ...
+func (s *Webhooks) ListDeliveries(ctx context.Context, hookID, limit int) ([]webhook.Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.hook(hookID) == nil {
+		return nil, webhook.ErrNotFound
+	}
+
+	deliveries := []webhook.Delivery{}
+	for i := len(s.deliveries) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		if s.deliveries[i].HookID == hookID {
+			deliveries = append(deliveries, s.deliveries[i])
+		}
+	}
+	return deliveries, nil
+}
+
+// Claim returns up to limit pending deliveries of existing hooks that are
+// due and counts an attempt for each. Claimed deliveries are not due again
+// until lease has passed.
+func (s *Webhooks) Claim(ctx context.Context, limit int, lease time.Duration) ([]webhook.Delivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	var claimed []webhook.Delivery
+	for i := range s.deliveries {
+		d := &s.deliveries[i]
+		if len(claimed) == limit {
+			break
+		}
+		h := s.hook(d.HookID)
+		if h == nil || d.Status != webhook.StatusPending || d.NextAttemptAt.After(now) {
+			continue
+		}
+
+		d.Attempts++
+		d.NextAttemptAt = now.Add(lease)
+		c := *d
+		c.URL, c.Secret = h.URL, h.Secret
+		claimed = append(claimed, c)
+	}
+
+	return claimed, nil
+}
+
+// delivery returns the delivery with id, or nil when there is none.
+func (s *Webhooks) delivery(id int) *webhook.Delivery {
+	if id < 1 || id > len(s.deliveries) {
+		return nil
+	}
+	return &s.deliveries[id-1]
+}
+
+// MarkDelivered ...
+func (s *Webhooks) MarkDelivered(ctx context.Context, id, status int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if d := s.delivery(id); d != nil {
+		deliveredAt := s.now()
+		d.Status, d.ResponseStatus, d.LastError, d.DeliveredAt = webhook.StatusDelivered, status, "", &deliveredAt
+	}
+	return nil
+}
+
+// MarkRetry records a failed attempt and when to try again.
+func (s *Webhooks) MarkRetry(ctx context.Context, id, status int, cause string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if d := s.delivery(id); d != nil {
+		d.ResponseStatus, d.LastError, d.NextAttemptAt = status, cause, at
+	}
+	return nil
+}
+
+// MarkFailed gives up on the delivery.
+func (s *Webhooks) MarkFailed(ctx context.Context, id, status int, cause string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if d := s.delivery(id); d != nil {
+		d.Status, d.ResponseStatus, d.LastError = webhook.StatusFailed, status, cause
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+// internal/memstore/webhooks_test.go
+
+package memstore
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/internal/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestWebhooks(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2019, 9, 1, 10, 0, 0, 0, time.UTC)
+	s := NewWebhooks()
+	s.now = func() time.Time { return now }
+
+	first, _ := s.CreateHook(ctx, webhook.Hook{URL: "https://one.example.com/hook", Secret: "s1"})
+	second, _ := s.CreateHook(ctx, webhook.Hook{URL: "https://two.example.com/hook", Secret: "s2"})
+	hooks, _ := s.ListHooks(ctx)
+	assert.Equal(t, []webhook.Hook{*first, *second}, hooks, "Hooks are different than expected")
+
+	queued, _ := s.Enqueue(ctx, webhook.EventQuoteGenerated, []byte(`{}`))
+	require.Len(t, queued, 2, "A delivery should be queued per hook")
+
+	claimed, _ := s.Claim(ctx, 10, time.Minute)
+	require.Len(t, claimed, 2, "Due deliveries should be claimed")
+	assert.Equal(t, "https://one.example.com/hook", claimed[0].URL, "Claimed deliveries should carry the hook URL")
+	assert.Equal(t, 1, claimed[0].Attempts, "Claim should count an attempt")
+	claimed, _ = s.Claim(ctx, 10, time.Minute)
+	assert.Empty(t, claimed, "Leased deliveries should not be claimed again")
+
+	s.MarkDelivered(ctx, queued[0].ID, 200)
+	s.MarkFailed(ctx, queued[1].ID, 500, "boom")
+
+	listed, err := s.ListDeliveries(ctx, first.ID, 10)
+	require.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, webhook.StatusDelivered, listed[0].Status, "Delivery status is different than expected")
+	listed, _ = s.ListDeliveries(ctx, second.ID, 10)
+	assert.Equal(t, webhook.StatusFailed, listed[0].Status, "Delivery status is different than expected")
+	assert.Equal(t, "boom", listed[0].LastError, "Failure should be recorded")
+
+	require.NoError(t, s.DeleteHook(ctx, first.ID), "Got error when not expected")
+	assert.ErrorIs(t, s.DeleteHook(ctx, first.ID), webhook.ErrNotFound)
+	_, err = s.ListDeliveries(ctx, first.ID, 10)
+	assert.ErrorIs(t, err, webhook.ErrNotFound)
+
+	queued, _ = s.Enqueue(ctx, webhook.EventQuoteDelivered, []byte(`{}`))
+	assert.Len(t, queued, 1, "Deleted hooks should get no deliveries")
+}
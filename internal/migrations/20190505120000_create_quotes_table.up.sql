@@ -0,0 +1,11 @@
+CREATE TABLE quotes
+(
+    id SERIAL,
+    text TEXT NOT NULL,
+    author TEXT NOT NULL,
+    lang TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT quotes_pkey PRIMARY KEY (id)
+);
+
+CREATE INDEX quotes_lang_created_at_idx ON quotes (lang, created_at DESC);
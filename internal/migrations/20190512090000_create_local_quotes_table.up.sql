@@ -0,0 +1,11 @@
+CREATE TABLE local_quotes
+(
+    id SERIAL,
+    text TEXT NOT NULL,
+    author TEXT NOT NULL,
+    lang TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT local_quotes_pkey PRIMARY KEY (id)
+);
+
+CREATE INDEX local_quotes_lang_idx ON local_quotes (lang);
@@ -0,0 +1,14 @@
+CREATE TABLE groups
+(
+    id SERIAL,
+    name TEXT NOT NULL,
+    CONSTRAINT groups_pkey PRIMARY KEY (id),
+    CONSTRAINT groups_name_key UNIQUE (name)
+);
+
+CREATE TABLE group_members
+(
+    group_id INTEGER NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+    recipient_id INTEGER NOT NULL REFERENCES recipients (id) ON DELETE CASCADE,
+    CONSTRAINT group_members_pkey PRIMARY KEY (group_id, recipient_id)
+);
@@ -0,0 +1 @@
+DROP INDEX recipients_email_key;
@@ -0,0 +1 @@
+CREATE UNIQUE INDEX recipients_email_key ON recipients (lower(email));
@@ -0,0 +1,3 @@
+-- Recipients added before verification existed keep receiving quotes.
+ALTER TABLE recipients ADD COLUMN verified BOOLEAN NOT NULL DEFAULT FALSE;
+UPDATE recipients SET verified = TRUE;
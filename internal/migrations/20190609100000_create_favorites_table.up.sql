@@ -0,0 +1,9 @@
+CREATE TABLE favorites
+(
+    recipient_id INTEGER NOT NULL REFERENCES recipients (id) ON DELETE CASCADE,
+    quote_id INTEGER NOT NULL REFERENCES quotes (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT favorites_pkey PRIMARY KEY (recipient_id, quote_id)
+);
+
+CREATE INDEX favorites_quote_id_idx ON favorites (quote_id);
@@ -0,0 +1,13 @@
+CREATE TABLE audit_log
+(
+    id SERIAL,
+    actor TEXT NOT NULL,
+    action TEXT NOT NULL,
+    entity TEXT NOT NULL,
+    entity_id INTEGER NOT NULL,
+    at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT audit_log_pkey PRIMARY KEY (id),
+    CONSTRAINT audit_log_action_check CHECK (action IN ('create', 'update', 'delete'))
+);
+
+CREATE INDEX audit_log_entity_at_idx ON audit_log (entity, at);
@@ -0,0 +1,2 @@
+ALTER TABLE recipients DROP COLUMN frequency;
+ALTER TABLE recipients DROP COLUMN lang;
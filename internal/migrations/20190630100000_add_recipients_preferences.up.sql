@@ -0,0 +1,3 @@
+-- Empty preferences mean every daily language, daily.
+ALTER TABLE recipients ADD COLUMN lang TEXT NOT NULL DEFAULT '';
+ALTER TABLE recipients ADD COLUMN frequency TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,7 @@
+-- Emails are stored lowercased, so each address is suppressed once.
+CREATE TABLE suppressions
+(
+    email TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT suppressions_pkey PRIMARY KEY (email)
+);
@@ -0,0 +1 @@
+ALTER TABLE outbox ADD COLUMN html TEXT NOT NULL DEFAULT '';
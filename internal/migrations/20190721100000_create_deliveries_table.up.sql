@@ -0,0 +1,14 @@
+CREATE TABLE deliveries
+(
+    id SERIAL,
+    message_id TEXT NOT NULL DEFAULT '',
+    email TEXT NOT NULL,
+    status TEXT NOT NULL,
+    detail TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT deliveries_pkey PRIMARY KEY (id),
+    CONSTRAINT deliveries_status_check CHECK (status IN ('sent', 'failed', 'delivered', 'bounced', 'complained'))
+);
+
+CREATE INDEX deliveries_message_id_idx ON deliveries (message_id) WHERE message_id <> '';
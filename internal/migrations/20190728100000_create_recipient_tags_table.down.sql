@@ -0,0 +1 @@
+DROP TABLE recipient_tags;
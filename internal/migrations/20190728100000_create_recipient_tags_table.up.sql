@@ -0,0 +1,8 @@
+CREATE TABLE recipient_tags
+(
+    recipient_id INTEGER NOT NULL REFERENCES recipients (id) ON DELETE CASCADE,
+    tag TEXT NOT NULL,
+    CONSTRAINT recipient_tags_pkey PRIMARY KEY (recipient_id, tag)
+);
+
+CREATE INDEX recipient_tags_tag_idx ON recipient_tags (tag);
@@ -0,0 +1,2 @@
+-- Bumped on every change, so concurrent edits can detect each other.
+ALTER TABLE recipients ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,3 @@
+-- postgres only
+DROP INDEX quotes_search_idx;
+ALTER TABLE quotes DROP COLUMN search;
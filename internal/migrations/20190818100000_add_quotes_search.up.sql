@@ -0,0 +1,7 @@
+-- postgres only
+-- Quotes come in several languages, so the simple configuration indexes
+-- words as they are, without stemming or stop words.
+ALTER TABLE quotes ADD COLUMN search tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', text || ' ' || author)) STORED;
+
+CREATE INDEX quotes_search_idx ON quotes USING GIN (search);
@@ -0,0 +1,11 @@
+CREATE TABLE ratings
+(
+    quote_id INTEGER NOT NULL REFERENCES quotes (id) ON DELETE CASCADE,
+    rater TEXT NOT NULL,
+    score INTEGER NOT NULL,
+    rated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT ratings_pkey PRIMARY KEY (quote_id, rater),
+    CONSTRAINT ratings_score_check CHECK (score BETWEEN 1 AND 5)
+);
+
+CREATE INDEX ratings_rated_at_idx ON ratings (rated_at);
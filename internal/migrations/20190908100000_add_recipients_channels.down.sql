@@ -0,0 +1,3 @@
+ALTER TABLE recipients DROP COLUMN telegram_chat_id;
+ALTER TABLE recipients DROP COLUMN slack_webhook;
+ALTER TABLE recipients DROP COLUMN channels;
@@ -0,0 +1,4 @@
+-- Empty channels mean email only. Channels are comma separated.
+ALTER TABLE recipients ADD COLUMN channels TEXT NOT NULL DEFAULT '';
+ALTER TABLE recipients ADD COLUMN slack_webhook TEXT NOT NULL DEFAULT '';
+ALTER TABLE recipients ADD COLUMN telegram_chat_id TEXT NOT NULL DEFAULT '';
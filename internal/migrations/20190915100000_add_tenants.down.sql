@@ -0,0 +1,9 @@
+DROP INDEX favorites_tenant_id_idx;
+DROP INDEX quotes_tenant_id_created_at_idx;
+
+DROP INDEX recipients_email_key;
+CREATE UNIQUE INDEX recipients_email_key ON recipients (lower(email));
+
+ALTER TABLE favorites DROP COLUMN tenant_id;
+ALTER TABLE quotes DROP COLUMN tenant_id;
+ALTER TABLE recipients DROP COLUMN tenant_id;
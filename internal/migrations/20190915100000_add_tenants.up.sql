@@ -0,0 +1,12 @@
+-- Rows from before tenants belong to the default tenant.
+ALTER TABLE recipients ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE quotes ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+ALTER TABLE favorites ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default';
+
+-- Each tenant has recipients of its own, so emails only need to be unique
+-- within a tenant.
+DROP INDEX recipients_email_key;
+CREATE UNIQUE INDEX recipients_email_key ON recipients (tenant_id, lower(email));
+
+CREATE INDEX quotes_tenant_id_created_at_idx ON quotes (tenant_id, created_at DESC);
+CREATE INDEX favorites_tenant_id_idx ON favorites (tenant_id);
@@ -0,0 +1,20 @@
+CREATE TABLE oauth_clients
+(
+    id TEXT NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    secret_hash TEXT NOT NULL,
+    -- Space separated, as in the scope parameter of OAuth2.
+    scopes TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    CONSTRAINT oauth_clients_pkey PRIMARY KEY (id)
+);
+
+-- Revoked tokens are kept until they would have expired anyway.
+CREATE TABLE oauth_revoked_tokens
+(
+    token_id TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    CONSTRAINT oauth_revoked_tokens_pkey PRIMARY KEY (token_id)
+);
+
+CREATE INDEX oauth_revoked_tokens_expires_at_idx ON oauth_revoked_tokens (expires_at);
@@ -0,0 +1,5 @@
+DROP INDEX quotes_tenant_id_last_served_at_idx;
+DROP INDEX quotes_tenant_id_hash_key;
+ALTER TABLE quotes DROP COLUMN last_served_at;
+ALTER TABLE quotes DROP COLUMN times_served;
+ALTER TABLE quotes DROP COLUMN hash;
@@ -0,0 +1,10 @@
+-- A quote served again is counted rather than stored again. hash is
+-- quote.Hash, computed by the application; quotes saved before are left
+-- without one, so they are never merged.
+ALTER TABLE quotes ADD COLUMN hash TEXT;
+ALTER TABLE quotes ADD COLUMN times_served INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE quotes ADD COLUMN last_served_at TIMESTAMPTZ;
+UPDATE quotes SET last_served_at = created_at;
+
+CREATE UNIQUE INDEX quotes_tenant_id_hash_key ON quotes (tenant_id, hash);
+CREATE INDEX quotes_tenant_id_last_served_at_idx ON quotes (tenant_id, last_served_at DESC);
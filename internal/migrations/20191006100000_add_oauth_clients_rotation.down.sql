@@ -0,0 +1,4 @@
+ALTER TABLE audit_log DROP COLUMN entity_key;
+ALTER TABLE oauth_clients DROP COLUMN previous_secret_expires_at;
+ALTER TABLE oauth_clients DROP COLUMN previous_secret_hash;
+ALTER TABLE oauth_clients DROP COLUMN owner;
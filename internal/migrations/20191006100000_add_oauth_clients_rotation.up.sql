@@ -0,0 +1,8 @@
+-- A rotated secret keeps working until previous_secret_expires_at, so
+-- clients can switch over. owner is told about rotations.
+ALTER TABLE oauth_clients ADD COLUMN owner TEXT NOT NULL DEFAULT '';
+ALTER TABLE oauth_clients ADD COLUMN previous_secret_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE oauth_clients ADD COLUMN previous_secret_expires_at TIMESTAMPTZ;
+
+-- OAuth clients have text IDs, which entity_id cannot hold.
+ALTER TABLE audit_log ADD COLUMN entity_key TEXT NOT NULL DEFAULT '';
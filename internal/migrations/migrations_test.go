@@ -0,0 +1,30 @@
+// internal/migrations/migrations_test.go
+
+package migrations
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestAll(t *testing.T) {
+	all, err := All()
+	require.NoError(t, err, "Got error when not expected")
+	require.NotEmpty(t, all, "Should find embedded migrations")
+
+	assert.Equal(t, int64(20190421133408), all[0].Version, "Wrong first version")
+	assert.Equal(t, "create_recipient_table", all[0].Name, "Wrong first name")
+	assert.Contains(t, all[0].Up, "CREATE TABLE recipients", "Wrong up migration")
+	assert.Contains(t, all[0].Down, "DROP TABLE recipients", "Wrong down migration")
+
+	for i := 1; i < len(all); i++ {
+		assert.True(t, all[i-1].Version < all[i].Version, "Migrations should be ordered by version")
+	}
+}
+
+func TestSQLiteTranslate(t *testing.T) {
+	assert.Equal(t, "CREATE TABLE t (id INTEGER, at TIMESTAMP DEFAULT CURRENT_TIMESTAMP)",
+		sqlite.translate("CREATE TABLE t (id SERIAL, at TIMESTAMPTZ DEFAULT now())"))
+	assert.Empty(t, sqlite.translate(postgresOnly+"\nCREATE INDEX i ON t USING GIN (search)"), "Postgres only migrations should be skipped")
+}
@@ -0,0 +1,125 @@
+// internal/migrations/up.go
+
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// dialect adapts the migrations, written for Postgres, to a database.
+type dialect struct {
+	// lock serializes instances migrating at once, when the database needs it.
+	lock      string
+	translate func(sql string) string
+}
+
+var postgres = dialect{
+	lock:      "LOCK TABLE schema_migrations IN EXCLUSIVE MODE",
+	translate: func(sql string) string { return sql },
+}
+
+// sqliteTypes maps what SQLite lacks to its closest equivalent. An INTEGER
+// primary key is an alias for the rowid, which autoincrements like SERIAL.
+var sqliteTypes = strings.NewReplacer(
+	"SERIAL", "INTEGER",
+	"TIMESTAMPTZ", "TIMESTAMP",
+	"DEFAULT now()", "DEFAULT CURRENT_TIMESTAMP",
+)
+
+// postgresOnly starts migrations SQLite has no equivalent for, such as full
+// text search indexes. SQLite records them as applied without running them.
+const postgresOnly = "-- postgres only"
+
+// SQLite locks the whole database for a write transaction, so no lock is needed.
+var sqlite = dialect{translate: func(sql string) string {
+	if strings.HasPrefix(sql, postgresOnly) {
+		return ""
+	}
+	return sqliteTypes.Replace(sql)
+}}
+
+// Up applies every embedded migration newer than the version recorded in
+// schema_migrations. The table layout matches golang-migrate, so databases set
+// up with the migrate CLI are picked up where they were left.
+func Up(ctx context.Context, db *sql.DB) error {
+	return up(ctx, db, postgres)
+}
+
+// UpSQLite applies the same migrations to a SQLite database.
+func UpSQLite(ctx context.Context, db *sql.DB) error {
+	return up(ctx, db, sqlite)
+}
+
+func up(ctx context.Context, db *sql.DB, d dialect) error {
+	all, err := All()
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS schema_migrations (version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)")
+	if err != nil {
+		return err
+	}
+
+	var current int64
+	var dirty bool
+	err = db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations LIMIT 1").Scan(&current, &dirty)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if dirty {
+		return fmt.Errorf("migrations: database is dirty at version %d, fix it manually", current)
+	}
+
+	for _, m := range all {
+		if m.Version <= current {
+			continue
+		}
+		if err := apply(ctx, db, d, m); err != nil {
+			return fmt.Errorf("migrations: %d_%s: %v", m.Version, m.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func apply(ctx context.Context, db *sql.DB, d dialect, m Migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if d.lock != "" {
+		if _, err := tx.ExecContext(ctx, d.lock); err != nil {
+			return err
+		}
+	}
+
+	var current int64
+	err = tx.QueryRowContext(ctx, "SELECT version FROM schema_migrations LIMIT 1").Scan(&current)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if m.Version <= current {
+		// Another instance applied it while we were waiting for the lock.
+		return nil
+	}
+
+	if up := d.translate(m.Up); up != "" {
+		if _, err := tx.ExecContext(ctx, up); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations"); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, dirty) VALUES ($1, false)", m.Version); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,202 @@
+// internal/oauth/oauth.go
+
+// Package oauth keeps the machine clients allowed to get tokens through the
+// OAuth2 client credentials grant, and the tokens revoked before they expire.
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned for unknown client IDs.
+var ErrNotFound = errors.New("oauth client not found")
+
+// Client is a machine client. Its secret is only kept hashed.
+type Client struct {
+	ID         string    `json:"clientId"`
+	Name       string    `json:"name"`
+	SecretHash string    `json:"-"`
+	Scopes     []string  `json:"scopes"`
+	CreatedAt  time.Time `json:"createdAt"`
+	// Owner is the email address told when the secret is rotated, if any.
+	Owner string `json:"owner,omitempty"`
+	// PreviousSecretHash is the secret replaced by the last rotation, valid
+	// until PreviousSecretExpiresAt.
+	PreviousSecretHash      string     `json:"-"`
+	PreviousSecretExpiresAt *time.Time `json:"previousSecretExpiresAt,omitempty"`
+}
+
+// NewClient returns a client with a random ID and secret, and the secret.
+func NewClient(name string, scopes []string) (Client, string, error) {
+	id, err := randomHex(8)
+	if err != nil {
+		return Client{}, "", err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return Client{}, "", err
+	}
+	return Client{ID: id, Name: name, SecretHash: hashSecret(secret), Scopes: scopes}, secret, nil
+}
+
+// Authenticate reports whether secret is the client's, or its previous one
+// while still valid at now. Secrets are random, so a plain hash is as good as
+// a slow one.
+func (c *Client) Authenticate(secret string, now time.Time) bool {
+	hash := []byte(hashSecret(secret))
+	if subtle.ConstantTimeCompare(hash, []byte(c.SecretHash)) == 1 {
+		return true
+	}
+	return c.PreviousSecretHash != "" && c.PreviousSecretExpiresAt != nil && now.Before(*c.PreviousSecretExpiresAt) &&
+		subtle.ConstantTimeCompare(hash, []byte(c.PreviousSecretHash)) == 1
+}
+
+// Rotate gives the client a new random secret and returns it. The current
+// secret stays valid for grace from now, and one rotated before is dropped.
+func (c *Client) Rotate(grace time.Duration, now time.Time) (string, error) {
+	secret, err := randomHex(32)
+	if err != nil {
+		return "", err
+	}
+
+	c.PreviousSecretHash, c.PreviousSecretExpiresAt = "", nil
+	if grace > 0 {
+		expires := now.Add(grace).UTC()
+		c.PreviousSecretHash, c.PreviousSecretExpiresAt = c.SecretHash, &expires
+	}
+	c.SecretHash = hashSecret(secret)
+	return secret, nil
+}
+
+// Grant returns the requested scopes if the client has them all, or all of
+// its scopes when none are requested.
+func (c *Client) Grant(requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return c.Scopes, nil
+	}
+	for _, scope := range requested {
+		if !slices.Contains(c.Scopes, scope) {
+			return nil, fmt.Errorf("scope %q is not granted to the client", scope)
+		}
+	}
+	return requested, nil
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// Store ...
+type Store interface {
+	CreateClient(ctx context.Context, c Client) error
+	// GetClient returns ErrNotFound for unknown IDs.
+	GetClient(ctx context.Context, id string) (*Client, error)
+	// UpdateSecrets saves the current and previous secrets of c, as left by
+	// Rotate.
+	UpdateSecrets(ctx context.Context, c Client) error
+	DeleteClient(ctx context.Context, id string) error
+	// Revoke rejects the token with tokenID until it expires, dropping the
+	// revocations of tokens expired at now.
+	Revoke(ctx context.Context, tokenID string, expiresAt, now time.Time) error
+	Revoked(ctx context.Context, tokenID string) (bool, error)
+}
+
+// Persistence keeps the clients and the revoked tokens in the oauth tables.
+// Like the audit log, its queries run on both Postgres and SQLite.
+type Persistence struct {
+	DB *sql.DB
+}
+
+// CreateClient ...
+func (p *Persistence) CreateClient(ctx context.Context, c Client) error {
+	query := "INSERT INTO oauth_clients (id, name, secret_hash, scopes, created_at, owner) VALUES ($1, $2, $3, $4, $5, $6)"
+	_, err := p.DB.ExecContext(ctx, query, c.ID, c.Name, c.SecretHash, strings.Join(c.Scopes, " "), c.CreatedAt.UTC(), c.Owner)
+	return err
+}
+
+// GetClient ...
+func (p *Persistence) GetClient(ctx context.Context, id string) (*Client, error) {
+	c := Client{ID: id}
+	var scopes string
+	query := `SELECT name, secret_hash, scopes, created_at, owner, previous_secret_hash, previous_secret_expires_at
+		FROM oauth_clients WHERE id = $1`
+	err := p.DB.QueryRowContext(ctx, query, id).Scan(&c.Name, &c.SecretHash, &scopes, &c.CreatedAt, &c.Owner, &c.PreviousSecretHash, &c.PreviousSecretExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.Scopes = strings.Fields(scopes)
+	return &c, nil
+}
+
+// UpdateSecrets ...
+func (p *Persistence) UpdateSecrets(ctx context.Context, c Client) error {
+	query := "UPDATE oauth_clients SET secret_hash = $1, previous_secret_hash = $2, previous_secret_expires_at = $3 WHERE id = $4"
+	res, err := p.DB.ExecContext(ctx, query, c.SecretHash, c.PreviousSecretHash, c.PreviousSecretExpiresAt, c.ID)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+// DeleteClient ...
+func (p *Persistence) DeleteClient(ctx context.Context, id string) error {
+	res, err := p.DB.ExecContext(ctx, "DELETE FROM oauth_clients WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+// checkAffected returns ErrNotFound when res changed no client.
+func checkAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Revoke ...
+func (p *Persistence) Revoke(ctx context.Context, tokenID string, expiresAt, now time.Time) error {
+	// Tokens past their expiry are rejected anyway, so their revocations go.
+	_, err := p.DB.ExecContext(ctx, "DELETE FROM oauth_revoked_tokens WHERE expires_at <= $1", now.UTC())
+	if err != nil {
+		return err
+	}
+	query := "INSERT INTO oauth_revoked_tokens (token_id, expires_at) VALUES ($1, $2) ON CONFLICT (token_id) DO NOTHING"
+	_, err = p.DB.ExecContext(ctx, query, tokenID, expiresAt.UTC())
+	return err
+}
+
+// Revoked ...
+func (p *Persistence) Revoked(ctx context.Context, tokenID string) (bool, error) {
+	var revoked bool
+	query := "SELECT EXISTS (SELECT 1 FROM oauth_revoked_tokens WHERE token_id = $1)"
+	err := p.DB.QueryRowContext(ctx, query, tokenID).Scan(&revoked)
+	return revoked, err
+}
@@ -0,0 +1,131 @@
+// internal/oauth/oauth_test.go
+
+package oauth
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/internal/dbtest"
+	"github.com/jeniok12/golang-tdd-example/internal/pgtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Main(m))
+}
+
+func TestClient(t *testing.T) {
+	now := time.Date(2019, 9, 22, 10, 0, 0, 0, time.UTC)
+	c, secret, err := NewClient("billing", []string{"reader", "editor"})
+	require.NoError(t, err, "Got error when not expected")
+
+	assert.NotEmpty(t, c.ID, "Clients should get an ID")
+	assert.NotContains(t, c.SecretHash, secret, "Secrets should only be kept hashed")
+	assert.True(t, c.Authenticate(secret, now), "The client secret should authenticate")
+	assert.False(t, c.Authenticate("guess", now), "Other secrets should not authenticate")
+
+	scopes, err := c.Grant(nil)
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, []string{"reader", "editor"}, scopes, "All scopes should be granted by default")
+	scopes, err = c.Grant([]string{"reader"})
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, []string{"reader"}, scopes, "Requested scopes should be granted")
+	_, err = c.Grant([]string{"reader", "admin"})
+	assert.Error(t, err, "Scopes of the client only should be granted")
+}
+
+func TestClient_Rotate(t *testing.T) {
+	now := time.Now()
+	c, first, err := NewClient("billing", []string{"reader"})
+	require.NoError(t, err, "Got error when not expected")
+
+	second, err := c.Rotate(time.Hour, now)
+	require.NoError(t, err, "Got error when not expected")
+	assert.True(t, c.Authenticate(second, now), "The new secret should authenticate")
+	assert.True(t, c.Authenticate(first, now), "The previous secret should authenticate during the grace period")
+	assert.False(t, c.Authenticate(first, now.Add(2*time.Hour)), "The previous secret should not authenticate past the grace period")
+
+	third, err := c.Rotate(time.Hour, now)
+	require.NoError(t, err, "Got error when not expected")
+	assert.True(t, c.Authenticate(second, now), "The previous secret should authenticate during the grace period")
+	assert.False(t, c.Authenticate(first, now), "Only the last previous secret should be kept")
+
+	_, err = c.Rotate(time.Hour, now.Add(-2*time.Hour))
+	require.NoError(t, err, "Got error when not expected")
+	assert.False(t, c.Authenticate(third, now), "The previous secret should not authenticate past the grace period")
+
+	fourth, err := c.Rotate(0, now)
+	require.NoError(t, err, "Got error when not expected")
+	assert.True(t, c.Authenticate(fourth, now), "The new secret should authenticate")
+	assert.Nil(t, c.PreviousSecretExpiresAt, "Without grace the previous secret should be dropped at once")
+}
+
+func TestPersistence_Clients(t *testing.T) {
+	for _, d := range dbtest.Dialects {
+		t.Run(d.Name, func(t *testing.T) {
+			testPersistenceClients(t, &Persistence{DB: d.Open(t)})
+		})
+	}
+}
+
+func testPersistenceClients(t *testing.T, p *Persistence) {
+	ctx := context.Background()
+	c, secret, err := NewClient("billing", []string{"reader", "editor"})
+	require.NoError(t, err, "Got error when not expected")
+	now := time.Date(2019, 9, 22, 10, 0, 0, 0, time.UTC)
+	c.CreatedAt = now
+	c.Owner = "ops@example.com"
+
+	require.NoError(t, p.CreateClient(ctx, c), "Got error when not expected")
+	got, err := p.GetClient(ctx, c.ID)
+	require.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, "billing", got.Name)
+	assert.Equal(t, "ops@example.com", got.Owner)
+	assert.Equal(t, []string{"reader", "editor"}, got.Scopes, "Scopes should be kept in order")
+	assert.True(t, got.Authenticate(secret, now), "The saved client should authenticate")
+	assert.Nil(t, got.PreviousSecretExpiresAt, "New clients should have no previous secret")
+
+	rotated, err := got.Rotate(time.Hour, now)
+	require.NoError(t, err, "Got error when not expected")
+	require.NoError(t, p.UpdateSecrets(ctx, *got), "Got error when not expected")
+	got, err = p.GetClient(ctx, c.ID)
+	require.NoError(t, err, "Got error when not expected")
+	assert.True(t, got.Authenticate(rotated, now), "The rotated secret should be saved")
+	assert.True(t, got.Authenticate(secret, now), "The previous secret should be saved")
+	assert.Equal(t, ErrNotFound, p.UpdateSecrets(ctx, Client{ID: "unknown"}), "Unknown clients should not be updated")
+
+	require.NoError(t, p.DeleteClient(ctx, c.ID), "Got error when not expected")
+	_, err = p.GetClient(ctx, c.ID)
+	assert.Equal(t, ErrNotFound, err, "Deleted clients should be gone")
+	assert.Equal(t, ErrNotFound, p.DeleteClient(ctx, c.ID), "Deleting twice should fail")
+}
+
+func TestPersistence_Revoke(t *testing.T) {
+	for _, d := range dbtest.Dialects {
+		t.Run(d.Name, func(t *testing.T) {
+			testPersistenceRevoke(t, &Persistence{DB: d.Open(t)})
+		})
+	}
+}
+
+func testPersistenceRevoke(t *testing.T, p *Persistence) {
+	ctx := context.Background()
+	now := time.Date(2019, 10, 20, 10, 0, 0, 0, time.UTC)
+
+	require.NoError(t, p.Revoke(ctx, "expired", now.Add(-time.Minute), now), "Got error when not expected")
+	require.NoError(t, p.Revoke(ctx, "live", now.Add(time.Hour), now), "Got error when not expected")
+	require.NoError(t, p.Revoke(ctx, "live", now.Add(time.Hour), now), "Revoking twice should be fine")
+
+	revoked, err := p.Revoked(ctx, "live")
+	assert.NoError(t, err, "Got error when not expected")
+	assert.True(t, revoked, "Revoked tokens should be reported")
+	revoked, err = p.Revoked(ctx, "expired")
+	assert.NoError(t, err, "Got error when not expected")
+	assert.False(t, revoked, "Revocations of expired tokens should be dropped")
+	revoked, err = p.Revoked(ctx, "other")
+	assert.NoError(t, err, "Got error when not expected")
+	assert.False(t, revoked, "Other tokens should not be revoked")
+}
@@ -0,0 +1,157 @@
+// internal/outbox/outbox.go
+
+package outbox
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/internal/dbtx"
+	"github.com/jeniok12/golang-tdd-example/mailer"
+	"time"
+)
+
+// Entry statuses.
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+)
+
+// Entry is an email waiting in, or drained from, the outbox.
+type Entry struct {
+	ID            int
+	Message       mailer.Message
+	Status        string
+	Attempts      int
+	LastError     string
+	NextAttemptAt time.Time
+	CreatedAt     time.Time
+	SentAt        *time.Time
+}
+
+// Persistence is the Postgres outbox.
+type Persistence struct {
+	DB *sql.DB
+
+	// tx, when set, is the transaction messages are queued in.
+	tx *sql.Tx
+}
+
+// WithTx returns the Persistence queueing messages in tx, so they are only
+// sent if the change that triggered them is committed.
+func (p *Persistence) WithTx(tx *sql.Tx) *Persistence {
+	return &Persistence{DB: p.DB, tx: tx}
+}
+
+func (p *Persistence) exec() dbtx.Executor {
+	if p.tx != nil {
+		return p.tx
+	}
+	return p.DB
+}
+
+// Enqueue stores msgs as pending entries in a single transaction, so either
+// every message of a send is queued or none is.
+func (p *Persistence) Enqueue(ctx context.Context, msgs []mailer.Message) ([]Entry, error) {
+	entries := make([]Entry, 0, len(msgs))
+	err := dbtx.Within(ctx, p.DB, p.tx, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, `INSERT INTO outbox (recipient, subject, body, html) VALUES ($1, $2, $3, $4)
+			RETURNING id, status, attempts, next_attempt_at, created_at`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for _, msg := range msgs {
+			e := Entry{Message: msg}
+			err := stmt.QueryRowContext(ctx, msg.To, msg.Subject, msg.Body, msg.HTML).
+				Scan(&e.ID, &e.Status, &e.Attempts, &e.NextAttemptAt, &e.CreatedAt)
+			if err != nil {
+				return fmt.Errorf("outbox: queueing message to %s: %w", msg.To, err)
+			}
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// Claim returns up to limit pending entries that are due and counts an
+// attempt for each. Claimed entries are not due again until lease has passed,
+// so concurrent workers skip them and a crashed worker's entries are retried.
+func (p *Persistence) Claim(ctx context.Context, limit int, lease time.Duration) ([]Entry, error) {
+	query := `UPDATE outbox SET attempts = attempts + 1, next_attempt_at = now() + $2 * interval '1 millisecond'
+		WHERE id IN (
+			SELECT id FROM outbox WHERE status = 'pending' AND next_attempt_at <= now()
+			ORDER BY next_attempt_at, id LIMIT $1 FOR UPDATE SKIP LOCKED
+		)
+		RETURNING id, recipient, subject, body, html, status, attempts, last_error, next_attempt_at, created_at`
+	rows, err := p.exec().QueryContext(ctx, query, limit, lease.Milliseconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var e Entry
+		err := rows.Scan(&e.ID, &e.Message.To, &e.Message.Subject, &e.Message.Body, &e.Message.HTML,
+			&e.Status, &e.Attempts, &e.LastError, &e.NextAttemptAt, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// MarkSent ...
+func (p *Persistence) MarkSent(ctx context.Context, id int) error {
+	_, err := p.exec().ExecContext(ctx, "UPDATE outbox SET status = 'sent', sent_at = now(), last_error = '' WHERE id = $1", id)
+	return err
+}
+
+// MarkRetry records a failed attempt and when to try again.
+func (p *Persistence) MarkRetry(ctx context.Context, id int, cause string, at time.Time) error {
+	_, err := p.exec().ExecContext(ctx, "UPDATE outbox SET last_error = $2, next_attempt_at = $3 WHERE id = $1", id, cause, at)
+	return err
+}
+
+// MarkFailed gives up on the entry.
+func (p *Persistence) MarkFailed(ctx context.Context, id int, cause string) error {
+	_, err := p.exec().ExecContext(ctx, "UPDATE outbox SET status = 'failed', last_error = $2 WHERE id = $1", id, cause)
+	return err
+}
+
+// Counts ...
+func (p *Persistence) Counts(ctx context.Context) (map[string]int, error) {
+	return CountStatuses(ctx, p.exec())
+}
+
+// CountStatuses counts the entries of the outbox table in db by status, every
+// status included. The query runs on both Postgres and SQLite.
+func CountStatuses(ctx context.Context, db dbtx.Executor) (map[string]int, error) {
+	rows, err := db.QueryContext(ctx, "SELECT status, COUNT(*) FROM outbox GROUP BY status")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{StatusPending: 0, StatusSent: 0, StatusFailed: 0}
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		counts[status] = n
+	}
+
+	return counts, rows.Err()
+}
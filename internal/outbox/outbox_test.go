@@ -0,0 +1,57 @@
+// internal/outbox/outbox_test.go
+
+package outbox
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/internal/pgtest"
+	"github.com/jeniok12/golang-tdd-example/mailer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Main(m))
+}
+
+// newTestPersistence returns a Persistence on a migrated schema of its own.
+func newTestPersistence(t *testing.T) *Persistence {
+	return &Persistence{DB: pgtest.Open(t)}
+}
+
+func TestPersistence_Outbox(t *testing.T) {
+	p := newTestPersistence(t)
+	ctx := context.Background()
+
+	queued, err := p.Enqueue(ctx, []mailer.Message{
+		{To: "bob@testmail.com", Subject: "Hi", Body: "Bla"},
+		{To: "alice@testmail.com", Subject: "Hi", Body: "Bla"},
+	})
+	require.NoError(t, err, "Should have no error when queueing")
+	require.Len(t, queued, 2, "Should queue every message")
+
+	claimed, err := p.Claim(ctx, 10, time.Minute)
+	require.NoError(t, err, "Should have no error when claiming")
+	assert.Len(t, claimed, 2, "Should claim the due entries")
+	assert.Equal(t, 1, claimed[0].Attempts, "Claiming should count an attempt")
+
+	again, err := p.Claim(ctx, 10, time.Minute)
+	assert.NoError(t, err, "Should have no error when claiming")
+	assert.Empty(t, again, "Claimed entries should be leased")
+
+	require.NoError(t, p.MarkSent(ctx, queued[0].ID), "Should have no error when marking sent")
+	require.NoError(t, p.MarkRetry(ctx, queued[1].ID, "mailbox unavailable", time.Now().Add(-time.Second)))
+
+	retried, err := p.Claim(ctx, 10, time.Minute)
+	assert.NoError(t, err, "Should have no error when claiming")
+	require.Len(t, retried, 1, "Only the retried entry should be due")
+	assert.Equal(t, "mailbox unavailable", retried[0].LastError, "Retry should keep the last error")
+
+	require.NoError(t, p.MarkFailed(ctx, queued[1].ID, "mailbox unavailable"))
+	var failed int
+	require.NoError(t, p.DB.QueryRow("SELECT count(*) FROM outbox WHERE status = 'failed'").Scan(&failed))
+	assert.Equal(t, 1, failed, "Entry should be marked failed")
+}
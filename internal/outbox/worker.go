@@ -0,0 +1,119 @@
+// internal/outbox/worker.go
+
+package outbox
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/mailer"
+	"time"
+)
+
+// Store is the outbox as seen by the Worker.
+type Store interface {
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]Entry, error)
+	MarkSent(ctx context.Context, id int) error
+	MarkRetry(ctx context.Context, id int, cause string, at time.Time) error
+	MarkFailed(ctx context.Context, id int, cause string) error
+}
+
+// Worker defaults used for zero fields.
+const (
+	DefaultMaxAttempts = 5
+	DefaultBaseDelay   = 30 * time.Second
+	DefaultMaxDelay    = time.Hour
+	DefaultBatchSize   = 100
+	DefaultLease       = 5 * time.Minute
+)
+
+// Worker sends the due outbox entries. Failed sends are retried with
+// exponential backoff until MaxAttempts, then the entry is marked failed.
+// Permanent mailer errors fail the entry right away, and rate limited sends
+// wait at least as long as the API asked.
+type Worker struct {
+	Store       Store
+	Sender      mailer.Sender
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	BatchSize   int
+	// Lease is how long a claimed entry is left alone before another drain
+	// may pick it up again. It should outlast sending a whole batch.
+	Lease time.Duration
+
+	now func() time.Time
+}
+
+// Drain sends due entries batch by batch until none is left, so it can be
+// used as a scheduler.Job. Send failures are recorded on the entries; only
+// store failures are returned.
+func (w *Worker) Drain(ctx context.Context) error {
+	batchSize := orDefault(w.BatchSize, DefaultBatchSize)
+	for {
+		entries, err := w.Store.Claim(ctx, batchSize, orDefaultDuration(w.Lease, DefaultLease))
+		if err != nil {
+			return err
+		}
+
+		for _, e := range entries {
+			if err := w.deliver(ctx, e); err != nil {
+				return err
+			}
+		}
+
+		if len(entries) < batchSize {
+			return nil
+		}
+	}
+}
+
+func (w *Worker) deliver(ctx context.Context, e Entry) error {
+	sendErr := w.Sender.Send(e.Message)
+	if sendErr == nil {
+		return w.Store.MarkSent(ctx, e.ID)
+	}
+
+	if e.Attempts >= orDefault(w.MaxAttempts, DefaultMaxAttempts) || mailer.IsPermanent(sendErr) {
+		return w.Store.MarkFailed(ctx, e.ID, sendErr.Error())
+	}
+
+	// A rate limited API says how long to wait; retrying sooner is wasted.
+	delay := w.backoff(e.Attempts)
+	if after := mailer.RetryAfter(sendErr); after > delay {
+		delay = after
+	}
+	return w.Store.MarkRetry(ctx, e.ID, sendErr.Error(), w.clock().Add(delay))
+}
+
+// backoff returns the delay after the given number of failed attempts.
+func (w *Worker) backoff(attempts int) time.Duration {
+	base := orDefaultDuration(w.BaseDelay, DefaultBaseDelay)
+	max := orDefaultDuration(w.MaxDelay, DefaultMaxDelay)
+
+	delay := base << uint(attempts-1)
+	if delay > max || delay <= 0 {
+		delay = max
+	}
+
+	return delay
+}
+
+func (w *Worker) clock() time.Time {
+	if w.now != nil {
+		return w.now()
+	}
+	return time.Now()
+}
+
+func orDefault(v, def int) int {
+	if v > 0 {
+		return v
+	}
+	return def
+}
+
+func orDefaultDuration(v, def time.Duration) time.Duration {
+	if v > 0 {
+		return v
+	}
+	return def
+}
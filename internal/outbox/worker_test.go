@@ -0,0 +1,135 @@
+// internal/outbox/worker_test.go
+
+package outbox
+
+import (
+	"context"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/mailer"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// memoryStore is a Store keeping entries in a slice, due when pending.
+type memoryStore struct {
+	entries  []Entry
+	claimErr error
+}
+
+func (s *memoryStore) Claim(ctx context.Context, limit int, lease time.Duration) ([]Entry, error) {
+	if s.claimErr != nil {
+		return nil, s.claimErr
+	}
+
+	var claimed []Entry
+	for i := range s.entries {
+		e := &s.entries[i]
+		if e.Status != StatusPending || !e.NextAttemptAt.IsZero() || len(claimed) == limit {
+			continue
+		}
+		e.Attempts++
+		e.NextAttemptAt = time.Unix(0, 0).Add(lease)
+		claimed = append(claimed, *e)
+	}
+	return claimed, nil
+}
+
+func (s *memoryStore) MarkSent(ctx context.Context, id int) error {
+	s.entries[id].Status = StatusSent
+	return nil
+}
+
+func (s *memoryStore) MarkRetry(ctx context.Context, id int, cause string, at time.Time) error {
+	s.entries[id].LastError = cause
+	s.entries[id].NextAttemptAt = at
+	return nil
+}
+
+func (s *memoryStore) MarkFailed(ctx context.Context, id int, cause string) error {
+	s.entries[id].Status = StatusFailed
+	s.entries[id].LastError = cause
+	return nil
+}
+
+// failingSender fails messages to the addresses in fail with their error.
+type failingSender struct {
+	fail map[string]error
+	sent []string
+}
+
+func (s *failingSender) Send(msg mailer.Message) error {
+	if err := s.fail[msg.To]; err != nil {
+		return err
+	}
+	s.sent = append(s.sent, msg.To)
+	return nil
+}
+
+func TestWorker_Drain(t *testing.T) {
+	now := time.Date(2019, 6, 16, 10, 0, 0, 0, time.UTC)
+
+	unavailable := errors.New("mailbox unavailable")
+	rejected := &mailer.Error{Provider: "sendgrid", Status: 400, Message: "invalid address", Permanent: true}
+	rateLimited := &mailer.Error{Provider: "sendgrid", Status: 429, RetryAfter: time.Minute}
+
+	testCases := []struct {
+		name             string
+		attempts         int
+		sendErr          error
+		expectedStatus   string
+		expectedNextTime time.Time
+	}{
+		{"Sent", 0, nil, StatusSent, time.Unix(0, 0).Add(DefaultLease)},
+		{"FirstFailure", 0, unavailable, StatusPending, now.Add(time.Second)},
+		{"ThirdFailure", 2, unavailable, StatusPending, now.Add(4 * time.Second)},
+		{"BackoffCapped", 3, unavailable, StatusPending, now.Add(5 * time.Second)},
+		{"LastFailure", 4, unavailable, StatusFailed, time.Unix(0, 0).Add(DefaultLease)},
+		{"PermanentFailure", 0, rejected, StatusFailed, time.Unix(0, 0).Add(DefaultLease)},
+		{"RateLimited", 0, rateLimited, StatusPending, now.Add(time.Minute)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			store := &memoryStore{entries: []Entry{
+				{ID: 0, Message: mailer.Message{To: "bob@testmail.com"}, Status: StatusPending, Attempts: tc.attempts},
+			}}
+			sender := &failingSender{fail: map[string]error{"bob@testmail.com": tc.sendErr}}
+			w := Worker{
+				Store:       store,
+				Sender:      sender,
+				MaxAttempts: 5,
+				BaseDelay:   time.Second,
+				MaxDelay:    5 * time.Second,
+				now:         func() time.Time { return now },
+			}
+
+			assert.NoError(t, w.Drain(context.Background()), "Should have no error when draining")
+
+			e := store.entries[0]
+			assert.Equal(t, tc.expectedStatus, e.Status, "Entry status is different than expected")
+			assert.Equal(t, tc.attempts+1, e.Attempts, "Drain should count one attempt")
+			assert.Equal(t, tc.expectedNextTime, e.NextAttemptAt, "Next attempt is different than expected")
+			if tc.sendErr != nil {
+				assert.Equal(t, tc.sendErr.Error(), e.LastError, "Failure should be recorded")
+			}
+		})
+	}
+}
+
+func TestWorker_DrainBatches(t *testing.T) {
+	store := &memoryStore{}
+	for i := 0; i < 5; i++ {
+		store.entries = append(store.entries, Entry{ID: i, Message: mailer.Message{To: "bob@testmail.com"}, Status: StatusPending})
+	}
+	sender := &failingSender{}
+	w := Worker{Store: store, Sender: sender, BatchSize: 2}
+
+	assert.NoError(t, w.Drain(context.Background()), "Should have no error when draining")
+	assert.Len(t, sender.sent, 5, "Every due entry should be sent")
+}
+
+func TestWorker_DrainStoreError(t *testing.T) {
+	w := Worker{Store: &memoryStore{claimErr: errors.New("sample error")}, Sender: &failingSender{}}
+
+	assert.EqualError(t, w.Drain(context.Background()), "sample error", "Store errors should be returned")
+}
@@ -0,0 +1,230 @@
+// internal/pgtest/pgtest.go
+
+// Package pgtest gives tests a migrated Postgres schema of their own, so
+// they neither need a hand-made database nor see each other's rows.
+//
+// The server is looked for, in order, at QUOTES_TEST_DATABASE_URL, in the
+// quotes_test database on localhost and in a container started with the
+// docker CLI. Without any, the Postgres tests are skipped so go test ./...
+// passes on a bare machine, but the Postgres queries then go untested: the
+// in-memory stores and the SQLite translations do not run them. The CI
+// workflow in .github/workflows/test.yml provides a Postgres service and sets
+// QUOTES_TEST_REQUIRE_POSTGRES, so a missing server fails the tests there
+// instead.
+package pgtest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/internal/migrations"
+	"github.com/lib/pq"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// EnvURL overrides where the test database is.
+const EnvURL = "QUOTES_TEST_DATABASE_URL"
+
+// EnvRequire, set to anything, fails the tests needing Postgres when there is
+// none instead of skipping them.
+const EnvRequire = "QUOTES_TEST_REQUIRE_POSTGRES"
+
+// localDSN is the database the tests used before there was a harness.
+const localDSN = "dbname=quotes_test host=localhost sslmode=disable"
+
+// Image is the Postgres image started when there is no server to reuse.
+const Image = "postgres:16-alpine"
+
+// startTimeout bounds the wait for a container to accept connections,
+// pulling the image aside.
+const startTimeout = 30 * time.Second
+
+var (
+	once      sync.Once
+	dsn       string
+	container string
+	findErr   error
+)
+
+// Main runs the tests of m and removes the container started for them, if
+// any. Call it from TestMain:
+//
+//	func TestMain(m *testing.M) { os.Exit(pgtest.Main(m)) }
+func Main(m *testing.M) int {
+	code := m.Run()
+	if container != "" {
+		exec.Command("docker", "rm", "-f", container).Run()
+	}
+	return code
+}
+
+// Available reports whether there is a Postgres to test against, starting
+// one if need be.
+func Available() bool {
+	_, err := server()
+	return err == nil
+}
+
+// Open returns a connection to a new schema migrated to the latest version.
+// The schema is dropped when t ends. Without Postgres, t is skipped.
+func Open(t testing.TB) *sql.DB {
+	t.Helper()
+
+	base, err := server()
+	if err != nil && os.Getenv(EnvRequire) != "" {
+		t.Fatalf("pgtest: no Postgres to test against: %v", err)
+	}
+	if err != nil {
+		t.Skipf("pgtest: no Postgres to test against: %v", err)
+	}
+
+	schema, err := schemaName()
+	if err != nil {
+		t.Fatalf("pgtest: naming schema: %v", err)
+	}
+
+	admin, err := sql.Open("postgres", base)
+	if err != nil {
+		t.Fatalf("pgtest: %v", err)
+	}
+	defer admin.Close()
+	if _, err := admin.Exec("CREATE SCHEMA " + pq.QuoteIdentifier(schema)); err != nil {
+		t.Fatalf("pgtest: creating schema: %v", err)
+	}
+
+	// search_path is sent as a connection parameter, so it holds on every
+	// connection of the pool.
+	db, err := sql.Open("postgres", base+" search_path="+schema)
+	if err != nil {
+		t.Fatalf("pgtest: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		if admin, err := sql.Open("postgres", base); err == nil {
+			admin.Exec("DROP SCHEMA " + pq.QuoteIdentifier(schema) + " CASCADE")
+			admin.Close()
+		}
+	})
+
+	if err := migrations.Up(context.Background(), db); err != nil {
+		t.Fatalf("pgtest: migrating: %v", err)
+	}
+	return db
+}
+
+// server returns the connection string of the test server, finding or
+// starting it on the first call.
+func server() (string, error) {
+	once.Do(func() {
+		dsn, findErr = find()
+	})
+	return dsn, findErr
+}
+
+func find() (string, error) {
+	if url := os.Getenv(EnvURL); url != "" {
+		conn, err := keyValue(url)
+		if err != nil {
+			return "", err
+		}
+		return conn, ping(conn, 5*time.Second)
+	}
+
+	if ping(localDSN, 2*time.Second) == nil {
+		return localDSN, nil
+	}
+
+	return startContainer()
+}
+
+// keyValue converts postgres:// URLs to the key=value form, which more
+// parameters can be appended to.
+func keyValue(conn string) (string, error) {
+	if strings.HasPrefix(conn, "postgres://") || strings.HasPrefix(conn, "postgresql://") {
+		return pq.ParseURL(conn)
+	}
+	return conn, nil
+}
+
+func ping(conn string, timeout time.Duration) error {
+	db, err := sql.Open("postgres", conn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return db.PingContext(ctx)
+}
+
+// startContainer runs Postgres in docker on a random local port.
+func startContainer() (string, error) {
+	if _, err := exec.LookPath("docker"); err != nil {
+		return "", errors.New("docker is not installed")
+	}
+
+	out, err := docker("run", "-d", "--rm",
+		"-e", "POSTGRES_PASSWORD=quotes",
+		"-e", "POSTGRES_DB=quotes_test",
+		"-p", "127.0.0.1::5432",
+		Image)
+	if err != nil {
+		return "", err
+	}
+	container = out
+
+	addr, err := docker("port", container, "5432/tcp")
+	if err != nil {
+		return "", err
+	}
+	// Docker lists one address per line when it binds several.
+	addr = strings.SplitN(addr, "\n", 2)[0]
+	i := strings.LastIndex(addr, ":")
+	if i < 0 {
+		return "", fmt.Errorf("docker port: unexpected address %q", addr)
+	}
+
+	conn := fmt.Sprintf("host=%s port=%s user=postgres password=quotes dbname=quotes_test sslmode=disable", addr[:i], addr[i+1:])
+	deadline := time.Now().Add(startTimeout)
+	for {
+		// The server listens on TCP only once initialized.
+		err = ping(conn, time.Second)
+		if err == nil {
+			return conn, nil
+		}
+		if time.Now().After(deadline) {
+			return "", fmt.Errorf("postgres container did not start: %w", err)
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
+
+func docker(args ...string) (string, error) {
+	var stderr bytes.Buffer
+	cmd := exec.Command("docker", args...)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("docker %s: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// schemaName is unique to a test, even across packages tested in parallel.
+func schemaName() (string, error) {
+	var b [6]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+	return "test_" + hex.EncodeToString(b[:]), nil
+}
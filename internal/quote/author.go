@@ -0,0 +1,62 @@
+// internal/quote/author.go
+
+package quote
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// ByAuthor reports whether the quote's author contains name, ignoring case,
+// so "einstein" matches "Albert Einstein".
+func (q Quote) ByAuthor(name string) bool {
+	return strings.Contains(strings.ToLower(q.Author), strings.ToLower(strings.TrimSpace(name)))
+}
+
+// AuthorFinder is implemented by stores that can look a quote up by author
+// instead of drawing random quotes until one matches.
+type AuthorFinder interface {
+	FindByAuthor(ctx context.Context, lang, author string) (*Quote, error)
+}
+
+type skipCacheKey struct{}
+
+// SkipCache returns a context that makes Cached ask its inner generator for a
+// fresh quote. The fresh quote still replaces the cached one.
+func SkipCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipCacheKey{}, true)
+}
+
+func skipsCache(ctx context.Context) bool {
+	skip, _ := ctx.Value(skipCacheKey{}).(bool)
+	return skip
+}
+
+// likeEscaper makes an author match literally inside a LIKE pattern.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// FindByAuthor returns a random local quote whose author contains author,
+// ignoring case. An empty lang matches quotes in any language.
+func (l *Local) FindByAuthor(ctx context.Context, lang, author string) (*Quote, error) {
+	return l.Find(ctx, lang, Constraints{Author: author})
+}
+
+// Find returns a random local quote satisfying c. An empty lang matches
+// quotes in any language.
+func (l *Local) Find(ctx context.Context, lang string, c Constraints) (*Quote, error) {
+	var q Quote
+
+	query := `SELECT text, author, lang FROM local_quotes WHERE ($1 = '' OR lang = $1) AND author ILIKE '%' || $2 || '%'
+		AND char_length(btrim(text, $5)) >= $3 AND ($4 = 0 OR char_length(btrim(text, $5)) <= $4)
+		ORDER BY random() LIMIT 1`
+	err := l.DB.QueryRowContext(ctx, query, lang, likeEscaper.Replace(strings.TrimSpace(c.Author)), c.MinLength, c.MaxLength, Whitespace).Scan(&q.Text, &q.Author, &q.Lang)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoQuotes
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &q, nil
+}
@@ -0,0 +1,29 @@
+// internal/quote/author_test.go
+
+package quote
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestQuote_ByAuthor(t *testing.T) {
+	testCases := []struct {
+		name     string
+		author   string
+		search   string
+		expected bool
+	}{
+		{"Exact", "Albert Einstein", "Albert Einstein", true},
+		{"Surname", "Albert Einstein", "einstein", true},
+		{"Padded", "Albert Einstein", " Einstein ", true},
+		{"Other", "Mark Twain", "Einstein", false},
+		{"Anonymous", "", "Einstein", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			q := Quote{Text: "Bla", Author: tc.author}
+			assert.Equal(t, tc.expected, q.ByAuthor(tc.search), "Author match is different than expected")
+		})
+	}
+}
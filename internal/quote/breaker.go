@@ -0,0 +1,183 @@
+// internal/quote/breaker.go
+
+package quote
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling the provider while the breaker is open.
+var ErrCircuitOpen = errors.New("quote: circuit breaker is open")
+
+// Breaker states.
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half-open"
+)
+
+// BreakerStats ...
+type BreakerStats struct {
+	State               string `json:"state"`
+	ConsecutiveFailures int    `json:"consecutiveFailures"`
+	Trips               int    `json:"trips"`
+}
+
+// Breaker stops calling the inner generator after Threshold consecutive
+// failures. After Cooldown a single trial call is let through (half-open);
+// its outcome closes the circuit again or restarts the cooldown. With
+// ServeStale the last good quote per language is served while open.
+type Breaker struct {
+	Inner      Generator
+	Threshold  int
+	Cooldown   time.Duration
+	ServeStale bool
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	trips    int
+	openedAt time.Time
+	probing  bool
+	last     map[string]Quote
+	now      func() time.Time
+}
+
+// Generate ...
+func (b *Breaker) Generate(ctx context.Context, lang string) (*Quote, error) {
+	if !b.allow() {
+		return b.fallback(lang)
+	}
+
+	q, err := b.Inner.Generate(ctx, lang)
+	if err != nil && ctx.Err() != nil {
+		// The caller gave up; that says nothing about the provider.
+		b.release()
+		return nil, err
+	}
+
+	b.record(lang, q, err)
+
+	return q, err
+}
+
+// Stats ...
+func (b *Breaker) Stats() BreakerStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return BreakerStats{
+		State:               b.currentState(),
+		ConsecutiveFailures: b.failures,
+		Trips:               b.trips,
+	}
+}
+
+// Reset closes the circuit regardless of its current state.
+func (b *Breaker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.state = StateClosed
+	b.failures = 0
+	b.probing = false
+}
+
+// Trip opens the circuit regardless of its current state.
+func (b *Breaker) Trip() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.open()
+}
+
+func (b *Breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.currentState() {
+	case StateOpen:
+		return false
+	case StateHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+	}
+
+	return true
+}
+
+func (b *Breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+}
+
+func (b *Breaker) record(lang string, q *Quote, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+
+	if err != nil {
+		b.failures++
+		if b.state == StateOpen || b.failures >= b.Threshold {
+			b.open()
+		}
+		return
+	}
+
+	b.state = StateClosed
+	b.failures = 0
+	if b.ServeStale {
+		if b.last == nil {
+			b.last = make(map[string]Quote)
+		}
+		b.last[lang] = *q
+	}
+}
+
+func (b *Breaker) fallback(lang string) (*Quote, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if q, ok := b.last[lang]; ok && b.ServeStale {
+		return &q, nil
+	}
+
+	return nil, ErrCircuitOpen
+}
+
+// open must be called with mu held.
+func (b *Breaker) open() {
+	if b.state != StateOpen {
+		b.trips++
+	}
+	b.state = StateOpen
+	b.openedAt = b.clock()
+}
+
+// currentState must be called with mu held. An open circuit whose cooldown
+// has elapsed reports half-open.
+func (b *Breaker) currentState() string {
+	switch {
+	case b.state == "":
+		return StateClosed
+	case b.state == StateOpen && b.clock().Sub(b.openedAt) >= b.Cooldown:
+		return StateHalfOpen
+	}
+
+	return b.state
+}
+
+func (b *Breaker) clock() time.Time {
+	if b.now != nil {
+		return b.now()
+	}
+	return time.Now()
+}
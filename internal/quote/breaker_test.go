@@ -0,0 +1,76 @@
+// internal/quote/breaker_test.go
+
+package quote
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestBreaker_Generate(t *testing.T) {
+	now := time.Now()
+	inner := &countingGenerator{err: errors.New("sample error")}
+	breaker := Breaker{
+		Inner:     inner,
+		Threshold: 2,
+		Cooldown:  time.Minute,
+		now:       func() time.Time { return now },
+	}
+	ctx := context.Background()
+
+	_, err := breaker.Generate(ctx, "en")
+	assert.EqualError(t, err, "sample error", "First failure should be returned")
+	assert.Equal(t, StateClosed, breaker.Stats().State, "One failure should not trip the breaker")
+
+	breaker.Generate(ctx, "en")
+	assert.Equal(t, StateOpen, breaker.Stats().State, "Threshold failures should trip the breaker")
+
+	_, err = breaker.Generate(ctx, "en")
+	assert.Equal(t, ErrCircuitOpen, err, "Open breaker should fail fast")
+	assert.Equal(t, 2, inner.calls, "Open breaker should not call the provider")
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, StateHalfOpen, breaker.Stats().State, "Breaker should half-open after cooldown")
+
+	_, err = breaker.Generate(ctx, "en")
+	assert.EqualError(t, err, "sample error", "Trial call should reach the provider")
+	assert.Equal(t, StateOpen, breaker.Stats().State, "Failed trial should reopen the breaker")
+
+	now = now.Add(time.Minute)
+	inner.err = nil
+	_, err = breaker.Generate(ctx, "en")
+	assert.NoError(t, err, "Successful trial should return the quote")
+	assert.Equal(t, BreakerStats{State: StateClosed, Trips: 1}, breaker.Stats(), "Successful trial should close the breaker")
+}
+
+func TestBreaker_ServeStale(t *testing.T) {
+	inner := &countingGenerator{}
+	breaker := Breaker{Inner: inner, Threshold: 1, Cooldown: time.Minute, ServeStale: true}
+	ctx := context.Background()
+
+	good, _ := breaker.Generate(ctx, "en")
+	inner.err = errors.New("sample error")
+	breaker.Generate(ctx, "en")
+
+	stale, err := breaker.Generate(ctx, "en")
+	assert.NoError(t, err, "Stale quote should be served while open")
+	assert.Equal(t, good, stale, "Last good quote should be served")
+
+	_, err = breaker.Generate(ctx, "ru")
+	assert.Equal(t, ErrCircuitOpen, err, "No stale quote for language should fail fast")
+}
+
+func TestBreaker_TripAndReset(t *testing.T) {
+	breaker := Breaker{Inner: &countingGenerator{}, Threshold: 5, Cooldown: time.Minute}
+
+	breaker.Trip()
+	_, err := breaker.Generate(context.Background(), "en")
+	assert.Equal(t, ErrCircuitOpen, err, "Tripped breaker should fail fast")
+
+	breaker.Reset()
+	_, err = breaker.Generate(context.Background(), "en")
+	assert.NoError(t, err, "Reset breaker should call the provider")
+}
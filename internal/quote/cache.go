@@ -0,0 +1,176 @@
+// internal/quote/cache.go
+
+package quote
+
+import (
+	"container/list"
+	"context"
+	"github.com/jeniok12/golang-tdd-example/clock"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Cache is the storage behind Cached. Implementations are expected to expire
+// entries after ttl on their own.
+type Cache interface {
+	Get(key string) (*Quote, bool)
+	Set(key string, q *Quote, ttl time.Duration)
+	// Flush drops every entry.
+	Flush() error
+}
+
+// CacheStats ...
+type CacheStats struct {
+	Hits   uint64 `json:"hits"`
+	Misses uint64 `json:"misses"`
+}
+
+// Cached ...
+type Cached struct {
+	Inner Generator
+	TTL   time.Duration
+	Cache Cache
+
+	mu     sync.RWMutex
+	hits   uint64
+	misses uint64
+}
+
+// Generate ...
+func (c *Cached) Generate(ctx context.Context, lang string) (*Quote, error) {
+	key := cacheKey(ctx, lang)
+	if !skipsCache(ctx) {
+		if q, ok := c.Cache.Get(key); ok {
+			atomic.AddUint64(&c.hits, 1)
+			cp := *q
+			return &cp, nil
+		}
+		atomic.AddUint64(&c.misses, 1)
+	}
+
+	q, err := c.Inner.Generate(ctx, lang)
+	if err != nil {
+		return nil, err
+	}
+
+	cp := *q
+	c.Cache.Set(key, &cp, c.CurrentTTL())
+
+	return q, nil
+}
+
+// CurrentTTL returns how long quotes are cached.
+func (c *Cached) CurrentTTL() time.Duration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return c.TTL
+}
+
+// SetTTL changes how long the next quotes are cached. Cached quotes keep
+// their expiry.
+func (c *Cached) SetTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.TTL = ttl
+}
+
+// Flush empties the cache, so the next quotes come from Inner.
+func (c *Cached) Flush() error {
+	return c.Cache.Flush()
+}
+
+// Stats ...
+func (c *Cached) Stats() CacheStats {
+	return CacheStats{
+		Hits:   atomic.LoadUint64(&c.hits),
+		Misses: atomic.LoadUint64(&c.misses),
+	}
+}
+
+type lruEntry struct {
+	key     string
+	quote   *Quote
+	expires time.Time
+}
+
+// LRU is an in-memory Cache holding at most size entries.
+type LRU struct {
+	size  int
+	clock clock.Clock
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+// NewLRU ...
+func NewLRU(size int) *LRU {
+	return &LRU{
+		size:  size,
+		clock: clock.Real,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// Get ...
+func (l *LRU) Get(key string) (*Quote, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	el, ok := l.items[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := el.Value.(*lruEntry)
+	if !l.clock.Now().Before(entry.expires) {
+		l.ll.Remove(el)
+		delete(l.items, key)
+		return nil, false
+	}
+
+	l.ll.MoveToFront(el)
+	return entry.quote, true
+}
+
+// Set ...
+func (l *LRU) Set(key string, q *Quote, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	expires := l.clock.Now().Add(ttl)
+	if el, ok := l.items[key]; ok {
+		entry := el.Value.(*lruEntry)
+		entry.quote, entry.expires = q, expires
+		l.ll.MoveToFront(el)
+		return
+	}
+
+	l.items[key] = l.ll.PushFront(&lruEntry{key: key, quote: q, expires: expires})
+	if l.ll.Len() > l.size {
+		oldest := l.ll.Back()
+		l.ll.Remove(oldest)
+		delete(l.items, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// Flush ...
+func (l *LRU) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.ll.Init()
+	l.items = make(map[string]*list.Element)
+	return nil
+}
+
+// Len ...
+func (l *LRU) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.ll.Len()
+}
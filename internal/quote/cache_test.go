@@ -0,0 +1,151 @@
+// internal/quote/cache_test.go
+
+package quote
+
+import (
+	"context"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/clock"
+	"github.com/jeniok12/golang-tdd-example/tenant"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type countingGenerator struct {
+	calls int
+	err   error
+}
+
+func (g *countingGenerator) Generate(ctx context.Context, lang string) (*Quote, error) {
+	g.calls++
+	if g.err != nil {
+		return nil, g.err
+	}
+	return &Quote{Text: "Bla Bla Bla", Author: "Bob", Lang: lang}, nil
+}
+
+func TestCached_Generate(t *testing.T) {
+	inner := &countingGenerator{}
+	cached := Cached{Inner: inner, TTL: time.Minute, Cache: NewLRU(10)}
+
+	q1, err := cached.Generate(context.Background(), "en")
+	assert.NoError(t, err, "Got error when not expected")
+	q2, err := cached.Generate(context.Background(), "en")
+	assert.NoError(t, err, "Got error when not expected")
+	_, err = cached.Generate(context.Background(), "ru")
+	assert.NoError(t, err, "Got error when not expected")
+
+	assert.Equal(t, q1, q2, "Cached quote should be returned")
+	assert.Equal(t, 2, inner.calls, "Inner generator should be called once per language")
+	assert.Equal(t, CacheStats{Hits: 1, Misses: 2}, cached.Stats(), "Wrong cache stats")
+}
+
+func TestCached_GenerateError(t *testing.T) {
+	inner := &countingGenerator{err: errors.New("sample error")}
+	lru := NewLRU(10)
+	cached := Cached{Inner: inner, TTL: time.Minute, Cache: lru}
+
+	_, err := cached.Generate(context.Background(), "en")
+
+	assert.Error(t, err, "Got no error when expected")
+	assert.Equal(t, 0, lru.Len(), "Errors should not be cached")
+}
+
+func TestCached_SetTTL(t *testing.T) {
+	lru := NewLRU(10)
+	now := clock.NewMock(time.Now())
+	lru.clock = now
+	cached := Cached{Inner: &countingGenerator{}, TTL: time.Minute, Cache: lru}
+
+	cached.SetTTL(time.Hour)
+	_, err := cached.Generate(context.Background(), "en")
+	assert.NoError(t, err, "Got error when not expected")
+	now.Add(30 * time.Minute)
+	_, ok := lru.Get(cacheKey(context.Background(), "en"))
+
+	assert.True(t, ok, "Quotes should be cached for the new TTL")
+	assert.Equal(t, time.Hour, cached.CurrentTTL())
+}
+
+func TestCached_Key(t *testing.T) {
+	inner := &countingGenerator{}
+	lru := NewLRU(10)
+	cached := Cached{Inner: inner, TTL: time.Minute, Cache: lru}
+
+	for _, ctx := range []context.Context{
+		context.Background(),
+		WithKey(context.Background(), 1),
+		WithKey(context.Background(), 1),
+		WithKey(context.Background(), 2),
+	} {
+		_, err := cached.Generate(ctx, "en")
+		assert.NoError(t, err, "Got error when not expected")
+	}
+
+	assert.Equal(t, 3, inner.calls, "Keyed quotes should be cached per key")
+	assert.Equal(t, 3, lru.Len(), "Keyed quotes should not replace the random one")
+}
+
+func TestCached_Tenant(t *testing.T) {
+	inner := &countingGenerator{}
+	lru := NewLRU(10)
+	cached := Cached{Inner: inner, TTL: time.Minute, Cache: lru}
+
+	for _, ctx := range []context.Context{
+		context.Background(),
+		tenant.NewContext(context.Background(), tenant.Default),
+		tenant.NewContext(context.Background(), "acme"),
+		tenant.NewContext(context.Background(), "acme"),
+	} {
+		_, err := cached.Generate(ctx, "en")
+		assert.NoError(t, err, "Got error when not expected")
+	}
+
+	assert.Equal(t, 2, inner.calls, "Quotes should be cached per tenant")
+	_, ok := lru.Get("en")
+	assert.True(t, ok, "The default tenant should keep its unprefixed key")
+	_, ok = lru.Get("acme:en")
+	assert.True(t, ok, "Other tenants should have keys of their own")
+}
+
+func TestCached_SkipCache(t *testing.T) {
+	inner := &countingGenerator{}
+	lru := NewLRU(10)
+	cached := Cached{Inner: inner, TTL: time.Minute, Cache: lru}
+
+	_, err := cached.Generate(context.Background(), "en")
+	assert.NoError(t, err, "Got error when not expected")
+	_, err = cached.Generate(SkipCache(context.Background()), "en")
+	assert.NoError(t, err, "Got error when not expected")
+
+	assert.Equal(t, 2, inner.calls, "Skipping the cache should call the inner generator")
+	assert.Equal(t, CacheStats{Misses: 1}, cached.Stats(), "Skipped lookups should not count")
+	assert.Equal(t, 1, lru.Len(), "Fresh quote should still be cached")
+}
+
+func TestLRU(t *testing.T) {
+	now := clock.NewMock(time.Now())
+	lru := NewLRU(2)
+	lru.clock = now
+
+	lru.Set("en", &Quote{Lang: "en"}, time.Minute)
+	lru.Set("ru", &Quote{Lang: "ru"}, time.Minute)
+	_, ok := lru.Get("en")
+	assert.True(t, ok, "Entry should be cached")
+
+	lru.Set("de", &Quote{Lang: "de"}, time.Minute)
+	_, ok = lru.Get("ru")
+	assert.False(t, ok, "Least recently used entry should be evicted")
+	assert.Equal(t, 2, lru.Len(), "Cache should be bounded")
+
+	now.Add(2 * time.Minute)
+	_, ok = lru.Get("en")
+	assert.False(t, ok, "Expired entry should not be returned")
+
+	lru.Set("en", &Quote{Lang: "en"}, time.Minute)
+	assert.NoError(t, lru.Flush(), "Got error when not expected")
+	assert.Equal(t, 0, lru.Len(), "Flushed cache should be empty")
+	_, ok = lru.Get("en")
+	assert.False(t, ok, "Flushed entry should not be returned")
+}
@@ -0,0 +1,47 @@
+// internal/quote/constraints.go
+
+package quote
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+)
+
+// Constraints narrow down the quotes a caller accepts. The zero value accepts
+// any quote.
+type Constraints struct {
+	// Author must be contained in the quote's author, ignoring case.
+	Author string
+	// MinLength and MaxLength bound the length of the text in characters,
+	// MaxLength only when not 0.
+	MinLength int
+	MaxLength int
+}
+
+// IsZero reports whether c accepts any quote.
+func (c Constraints) IsZero() bool {
+	return c == Constraints{}
+}
+
+// Match reports whether q satisfies c.
+func (c Constraints) Match(q Quote) bool {
+	n := q.Length()
+	return q.ByAuthor(c.Author) && n >= c.MinLength && (c.MaxLength == 0 || n <= c.MaxLength)
+}
+
+// Whitespace is what Length leaves out around the text of a quote. The stores
+// trim the same characters in SQL, so they count lengths alike.
+const Whitespace = " \t\n\v\f\r"
+
+// Length returns the length of the quote's text in characters, leaving out
+// the Whitespace around it.
+func (q Quote) Length() int {
+	return utf8.RuneCountInString(strings.Trim(q.Text, Whitespace))
+}
+
+// Finder is implemented by stores that can look a quote up by constraints
+// instead of drawing random quotes until one matches.
+type Finder interface {
+	Find(ctx context.Context, lang string, c Constraints) (*Quote, error)
+}
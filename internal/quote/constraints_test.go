@@ -0,0 +1,39 @@
+// internal/quote/constraints_test.go
+
+package quote
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestConstraints_Match(t *testing.T) {
+	twain := Quote{Text: " Bla Bla ", Author: "Mark Twain"}
+
+	testCases := []struct {
+		name        string
+		constraints Constraints
+		expected    bool
+	}{
+		{"None", Constraints{}, true},
+		{"Author", Constraints{Author: "twain"}, true},
+		{"OtherAuthor", Constraints{Author: "Einstein"}, false},
+		{"MaxLengthTrimmed", Constraints{MaxLength: 7}, true},
+		{"TooLong", Constraints{MaxLength: 6}, false},
+		{"MinLength", Constraints{MinLength: 7}, true},
+		{"TooShort", Constraints{MinLength: 8}, false},
+		{"AuthorAndLength", Constraints{Author: "twain", MaxLength: 6}, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.constraints.Match(twain), "Match is different than expected")
+		})
+	}
+}
+
+func TestQuote_Length(t *testing.T) {
+	assert.Equal(t, 3, Quote{Text: "Бла"}.Length(), "Length should count characters, not bytes")
+	assert.Equal(t, 140, Quote{Text: strings.Repeat("a", 140) + "\n"}.Length())
+	assert.Equal(t, 4, Quote{Text: "\u00a0Bla"}.Length(), "Only the Whitespace the stores trim should be left out")
+}
@@ -0,0 +1,127 @@
+// internal/quote/dedup.go
+
+package quote
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Hash identifies a quote by its language, text and author, ignoring case
+// and surrounding space, so providers' formatting quirks do not hide repeats.
+func Hash(q Quote) string {
+	normalize := func(s string) string { return strings.ToLower(strings.TrimSpace(s)) }
+
+	sum := sha256.Sum256([]byte(normalize(q.Lang) + "\x00" + normalize(q.Text) + "\x00" + normalize(q.Author)))
+	return hex.EncodeToString(sum[:])
+}
+
+// SeenStore remembers when quotes, identified by Hash, were last served.
+type SeenStore interface {
+	LastSeen(hash string) (time.Time, bool)
+	Remember(hash string, at time.Time)
+}
+
+// Deduplicated asks the inner generator again, up to Attempts times in all,
+// when it returns a quote already served within Window. When every attempt
+// is a repeat the last one is served anyway. Keyed requests are passed
+// through, since a key always picks the same quote.
+type Deduplicated struct {
+	Inner    Generator
+	Seen     SeenStore
+	Window   time.Duration
+	Attempts int
+
+	now func() time.Time
+}
+
+// Generate ...
+func (d *Deduplicated) Generate(ctx context.Context, lang string) (*Quote, error) {
+	if _, keyed := KeyFromContext(ctx); keyed {
+		return d.Inner.Generate(ctx, lang)
+	}
+
+	now := time.Now
+	if d.now != nil {
+		now = d.now
+	}
+
+	attempts := d.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var q *Quote
+	var hash string
+	for attempt := 0; attempt < attempts; attempt++ {
+		var err error
+		q, err = d.Inner.Generate(ctx, lang)
+		if err != nil {
+			return nil, err
+		}
+
+		hash = Hash(*q)
+		last, seen := d.Seen.LastSeen(hash)
+		if !seen || now().Sub(last) >= d.Window {
+			break
+		}
+	}
+
+	d.Seen.Remember(hash, now())
+	return q, nil
+}
+
+// sweepEvery is how many quotes are remembered between removals of the ones
+// older than the window.
+const sweepEvery = 1024
+
+// SeenMemory is an in-process SeenStore forgetting quotes once they are
+// older than Window.
+type SeenMemory struct {
+	Window time.Duration
+
+	mu    sync.Mutex
+	seen  map[string]time.Time
+	calls int
+}
+
+// LastSeen ...
+func (m *SeenMemory) LastSeen(hash string) (time.Time, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	at, ok := m.seen[hash]
+	return at, ok
+}
+
+// Remember ...
+func (m *SeenMemory) Remember(hash string, at time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.seen == nil {
+		m.seen = make(map[string]time.Time)
+	}
+	m.seen[hash] = at
+
+	m.calls++
+	if m.calls%sweepEvery == 0 {
+		for h, seenAt := range m.seen {
+			if at.Sub(seenAt) >= m.Window {
+				delete(m.seen, h)
+			}
+		}
+	}
+}
+
+// Len ...
+func (m *SeenMemory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.seen)
+}
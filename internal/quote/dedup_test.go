@@ -0,0 +1,88 @@
+// internal/quote/dedup_test.go
+
+package quote
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+// sequenceGenerator returns its quotes in order, repeating the last one.
+type sequenceGenerator struct {
+	quotes []Quote
+	calls  int
+}
+
+func (g *sequenceGenerator) Generate(ctx context.Context, lang string) (*Quote, error) {
+	q := g.quotes[len(g.quotes)-1]
+	if g.calls < len(g.quotes) {
+		q = g.quotes[g.calls]
+	}
+	g.calls++
+	return &q, nil
+}
+
+func TestHash(t *testing.T) {
+	q := Quote{Text: "Bla Bla", Author: "Bob", Lang: "en"}
+
+	assert.Equal(t, Hash(q), Hash(Quote{Text: " bla bla ", Author: "BOB", Lang: "en"}), "Formatting should not change the hash")
+	assert.NotEqual(t, Hash(q), Hash(Quote{Text: "Bla Bla", Author: "Bob", Lang: "ru"}), "Languages should be told apart")
+	assert.NotEqual(t, Hash(q), Hash(Quote{Text: "Bla", Author: "Bla Bob", Lang: "en"}), "Fields should not run together")
+}
+
+func TestDeduplicated_Generate(t *testing.T) {
+	now := time.Date(2019, 6, 23, 10, 0, 0, 0, time.UTC)
+	first := Quote{Text: "Bla", Author: "Bob", Lang: "en"}
+	second := Quote{Text: "Bla Bla", Author: "Alice", Lang: "en"}
+
+	testCases := []struct {
+		name          string
+		seenAgo       time.Duration
+		quotes        []Quote
+		expectedQuote Quote
+		expectedCalls int
+	}{
+		{"Fresh", -1, []Quote{first}, first, 1},
+		{"RepeatRetried", time.Hour, []Quote{first, second}, second, 2},
+		{"OutsideWindow", 24 * time.Hour, []Quote{first, second}, first, 1},
+		{"OnlyRepeats", time.Hour, []Quote{first}, first, 3},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			seen := &SeenMemory{Window: 24 * time.Hour}
+			if tc.seenAgo >= 0 {
+				seen.Remember(Hash(first), now.Add(-tc.seenAgo))
+			}
+			inner := &sequenceGenerator{quotes: tc.quotes}
+			d := Deduplicated{Inner: inner, Seen: seen, Window: 24 * time.Hour, Attempts: 3, now: func() time.Time { return now }}
+
+			q, err := d.Generate(context.Background(), "en")
+
+			assert.NoError(t, err, "Got error when not expected")
+			assert.Equal(t, &tc.expectedQuote, q, "Served quote is different than expected")
+			assert.Equal(t, tc.expectedCalls, inner.calls, "Provider calls are different than expected")
+			last, ok := seen.LastSeen(Hash(*q))
+			assert.True(t, ok && last.Equal(now), "Served quote should be remembered")
+		})
+	}
+}
+
+func TestDeduplicated_Passthrough(t *testing.T) {
+	seen := &SeenMemory{Window: time.Hour}
+
+	failing := Deduplicated{Inner: &countingGenerator{err: errors.New("sample error")}, Seen: seen, Window: time.Hour, Attempts: 3}
+	_, err := failing.Generate(context.Background(), "en")
+	assert.EqualError(t, err, "sample error", "Provider errors should be returned")
+
+	inner := &countingGenerator{}
+	keyed := Deduplicated{Inner: inner, Seen: seen, Window: time.Hour, Attempts: 3}
+	for i := 0; i < 2; i++ {
+		_, err := keyed.Generate(WithKey(context.Background(), 7), "en")
+		assert.NoError(t, err, "Got error when not expected")
+	}
+	assert.Equal(t, 2, inner.calls, "Keyed quotes should not be retried")
+	assert.Zero(t, seen.Len(), "Keyed quotes should not be remembered")
+}
@@ -0,0 +1,53 @@
+// internal/quote/filtered.go
+
+package quote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/filter"
+)
+
+// ErrRejected is returned when every quote generated was rejected by the
+// filter.
+var ErrRejected = errors.New("quote: every quote was rejected by the filter")
+
+// Filtered screens the quotes of the inner generator, text and author, and
+// asks it again, up to Attempts times in all, when one is rejected. Keyed
+// requests get a single attempt, since a key always picks the same quote.
+type Filtered struct {
+	Inner    Generator
+	Screener filter.Screener
+	Attempts int
+	// OnDecision, if set, is called with every screened quote.
+	OnDecision func(q Quote, d filter.Decision)
+}
+
+// Generate ...
+func (f *Filtered) Generate(ctx context.Context, lang string) (*Quote, error) {
+	attempts := f.Attempts
+	if _, keyed := KeyFromContext(ctx); keyed || attempts < 1 {
+		attempts = 1
+	}
+
+	for attempt := 0; attempt < attempts; attempt++ {
+		q, err := f.Inner.Generate(ctx, lang)
+		if err != nil {
+			return nil, err
+		}
+
+		d, err := f.Screener.Screen(ctx, q.Text+"\n"+q.Author)
+		if err != nil {
+			return nil, fmt.Errorf("quote: screening: %w", err)
+		}
+		if f.OnDecision != nil {
+			f.OnDecision(*q, d)
+		}
+		if d.Allowed {
+			return q, nil
+		}
+	}
+
+	return nil, ErrRejected
+}
@@ -0,0 +1,70 @@
+// internal/quote/filtered_test.go
+
+package quote
+
+import (
+	"context"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/filter"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type failingScreener struct{}
+
+func (failingScreener) Screen(ctx context.Context, text string) (filter.Decision, error) {
+	return filter.Decision{}, errors.New("sample error")
+}
+
+func TestFiltered_Generate(t *testing.T) {
+	clean := Quote{Text: "Bla Bla Bla", Author: "Bob", Lang: "en"}
+	rude := Quote{Text: "Damn it", Author: "Bob", Lang: "en"}
+
+	testCases := []struct {
+		desc               string
+		ctx                context.Context
+		quotes             []Quote
+		screener           filter.Screener
+		expectedQuote      *Quote
+		expectedCalls      int
+		expectedRejections int
+		expectedErr        error
+	}{
+		{"Clean", context.Background(), []Quote{clean}, filter.NewBlocklist("damn"), &clean, 1, 0, nil},
+		{"Retried", context.Background(), []Quote{rude, clean}, filter.NewBlocklist("damn"), &clean, 2, 1, nil},
+		{"AllRejected", context.Background(), []Quote{rude}, filter.NewBlocklist("damn"), nil, 3, 3, ErrRejected},
+		{"BlockedAuthor", context.Background(), []Quote{clean}, filter.NewBlocklist("bob"), nil, 3, 3, ErrRejected},
+		{"Keyed", WithKey(context.Background(), 42), []Quote{rude, clean}, filter.NewBlocklist("damn"), nil, 1, 1, ErrRejected},
+		{"ScreenerFails", context.Background(), []Quote{clean}, failingScreener{}, nil, 1, 0, nil},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			inner := &sequenceGenerator{quotes: tC.quotes}
+			rejections := 0
+			filtered := Filtered{
+				Inner:    inner,
+				Screener: tC.screener,
+				Attempts: 3,
+				OnDecision: func(q Quote, d filter.Decision) {
+					if !d.Allowed {
+						rejections++
+					}
+				},
+			}
+
+			q, err := filtered.Generate(tC.ctx, "en")
+
+			assert.Equal(t, tC.expectedCalls, inner.calls, "Provider calls are different than expected")
+			assert.Equal(t, tC.expectedRejections, rejections, "Logged rejections are different than expected")
+			if tC.expectedQuote == nil {
+				assert.Error(t, err, "Got no error when expected")
+				if tC.expectedErr != nil {
+					assert.ErrorIs(t, err, tC.expectedErr)
+				}
+				return
+			}
+			assert.NoError(t, err, "Got error when not expected")
+			assert.Equal(t, tC.expectedQuote, q)
+		})
+	}
+}
@@ -0,0 +1,162 @@
+// internal/quote/health.go
+
+package quote
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Defaults of NewHealthTracker.
+const (
+	DefaultHealthSamples = 100
+	DefaultHealthWindow  = 5 * time.Minute
+)
+
+// ProviderHealth sums up how a provider has been doing lately.
+type ProviderHealth struct {
+	Name     string `json:"name"`
+	Requests int    `json:"requests"`
+	// SuccessRate is 1 for providers without recent requests, so they get
+	// a chance to show how they do.
+	SuccessRate  float64    `json:"successRate"`
+	P95LatencyMs int64      `json:"p95LatencyMs"`
+	LastError    string     `json:"lastError,omitempty"`
+	LastErrorAt  *time.Time `json:"lastErrorAt,omitempty"`
+}
+
+type outcome struct {
+	at      time.Time
+	latency time.Duration
+	ok      bool
+}
+
+type providerRecord struct {
+	outcomes    []outcome
+	lastError   string
+	lastErrorAt time.Time
+}
+
+// HealthTracker keeps the outcome of the last calls to each provider. Only
+// the last Samples calls made within Window count, so a provider that
+// recovers is trusted again once its failures age out.
+type HealthTracker struct {
+	Samples int
+	Window  time.Duration
+
+	mu        sync.Mutex
+	providers map[string]*providerRecord
+	// now is time.Now unless a test replaces it.
+	now func() time.Time
+}
+
+// NewHealthTracker ...
+func NewHealthTracker(samples int, window time.Duration) *HealthTracker {
+	if samples <= 0 {
+		samples = DefaultHealthSamples
+	}
+	if window <= 0 {
+		window = DefaultHealthWindow
+	}
+	return &HealthTracker{Samples: samples, Window: window, providers: make(map[string]*providerRecord), now: time.Now}
+}
+
+// Record notes the outcome of a call to the named provider.
+func (h *HealthTracker) Record(name string, latency time.Duration, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	r, ok := h.providers[name]
+	if !ok {
+		r = &providerRecord{}
+		h.providers[name] = r
+	}
+
+	now := h.now()
+	r.outcomes = append(r.outcomes, outcome{at: now, latency: latency, ok: err == nil})
+	if len(r.outcomes) > h.Samples {
+		r.outcomes = r.outcomes[len(r.outcomes)-h.Samples:]
+	}
+	if err != nil {
+		r.lastError, r.lastErrorAt = err.Error(), now
+	}
+}
+
+// Health returns the recent health of the named provider.
+func (h *HealthTracker) Health(name string) ProviderHealth {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.health(name)
+}
+
+// Snapshot returns the health of every provider called so far, by name.
+func (h *HealthTracker) Snapshot() []ProviderHealth {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	health := make([]ProviderHealth, 0, len(h.providers))
+	for name := range h.providers {
+		health = append(health, h.health(name))
+	}
+	sort.Slice(health, func(i, j int) bool { return health[i].Name < health[j].Name })
+	return health
+}
+
+// Rank orders names from the healthiest provider to the least healthy: by
+// success rate, then by p95 latency. Ties keep their order.
+func (h *HealthTracker) Rank(names []string) []string {
+	h.mu.Lock()
+	health := make(map[string]ProviderHealth, len(names))
+	for _, name := range names {
+		health[name] = h.health(name)
+	}
+	h.mu.Unlock()
+
+	ranked := append([]string(nil), names...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := health[ranked[i]], health[ranked[j]]
+		if a.SuccessRate != b.SuccessRate {
+			return a.SuccessRate > b.SuccessRate
+		}
+		return a.P95LatencyMs < b.P95LatencyMs
+	})
+	return ranked
+}
+
+// health must be called with mu held.
+func (h *HealthTracker) health(name string) ProviderHealth {
+	ph := ProviderHealth{Name: name, SuccessRate: 1}
+	r, ok := h.providers[name]
+	if !ok {
+		return ph
+	}
+	if r.lastError != "" {
+		at := r.lastErrorAt
+		ph.LastError, ph.LastErrorAt = r.lastError, &at
+	}
+
+	since := h.now().Add(-h.Window)
+	var latencies []time.Duration
+	successes := 0
+	for _, o := range r.outcomes {
+		if o.at.Before(since) {
+			continue
+		}
+		latencies = append(latencies, o.latency)
+		if o.ok {
+			successes++
+		}
+	}
+	if len(latencies) == 0 {
+		return ph
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	ph.Requests = len(latencies)
+	ph.SuccessRate = float64(successes) / float64(len(latencies))
+	// Nearest rank: the smallest latency at or above 95% of the calls.
+	ph.P95LatencyMs = latencies[(len(latencies)*95+99)/100-1].Milliseconds()
+	return ph
+}
@@ -0,0 +1,57 @@
+// internal/quote/health_test.go
+
+package quote
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestHealthTracker_Health(t *testing.T) {
+	now := time.Date(2019, 7, 21, 10, 0, 0, 0, time.UTC)
+	health := NewHealthTracker(20, time.Minute)
+	health.now = func() time.Time { return now }
+
+	assert.Equal(t, ProviderHealth{Name: "first", SuccessRate: 1}, health.Health("first"), "Unknown providers should look healthy")
+
+	for i := 1; i <= 20; i++ {
+		var err error
+		if i%4 == 0 {
+			err = errors.New("sample error")
+		}
+		health.Record("first", time.Duration(i)*time.Millisecond, err)
+	}
+
+	h := health.Health("first")
+	assert.Equal(t, 20, h.Requests)
+	assert.Equal(t, 0.75, h.SuccessRate, "Every fourth call failed")
+	assert.Equal(t, int64(19), h.P95LatencyMs, "p95 of 1..20ms should be 19ms")
+	assert.Equal(t, "sample error", h.LastError)
+	assert.Equal(t, now, *h.LastErrorAt)
+
+	health.Record("first", time.Millisecond, nil)
+	assert.Equal(t, 20, health.Health("first").Requests, "Only the last Samples calls should count")
+
+	now = now.Add(2 * time.Minute)
+	h = health.Health("first")
+	assert.Equal(t, 0, h.Requests, "Calls outside the window should not count")
+	assert.Equal(t, 1.0, h.SuccessRate, "Providers should be trusted again once failures age out")
+	assert.Equal(t, "sample error", h.LastError, "Last error should be kept")
+}
+
+func TestHealthTracker_Rank(t *testing.T) {
+	health := NewHealthTracker(10, time.Minute)
+	health.Record("flaky", time.Millisecond, errors.New("sample error"))
+	health.Record("flaky", time.Millisecond, nil)
+	health.Record("slow", time.Second, nil)
+	health.Record("fast", 10*time.Millisecond, nil)
+
+	assert.Equal(t,
+		[]string{"unknown", "fast", "slow", "flaky"},
+		health.Rank([]string{"flaky", "slow", "unknown", "fast"}),
+		"Providers should be ranked by success rate, then latency, untried ones first",
+	)
+	assert.Equal(t, []string{"a", "b"}, health.Rank([]string{"a", "b"}), "Ties should keep their order")
+}
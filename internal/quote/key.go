@@ -0,0 +1,36 @@
+// internal/quote/key.go
+
+package quote
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/tenant"
+	"strconv"
+)
+
+type keyCtxKey struct{}
+
+// WithKey returns a context asking providers that support keys, such as
+// Forismatic, for the quote matching key instead of a random one. Providers
+// without keys ignore it. A zero key keeps quotes random.
+func WithKey(ctx context.Context, key int) context.Context {
+	if key <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, keyCtxKey{}, key)
+}
+
+// KeyFromContext returns the key set by WithKey.
+func KeyFromContext(ctx context.Context) (int, bool) {
+	key, ok := ctx.Value(keyCtxKey{}).(int)
+	return key, ok
+}
+
+// cacheKey keeps keyed quotes apart from the random quote of a language, and
+// the quotes of each tenant apart from those of the others.
+func cacheKey(ctx context.Context, lang string) string {
+	if key, ok := KeyFromContext(ctx); ok {
+		return tenant.Key(ctx, lang+"#"+strconv.Itoa(key))
+	}
+	return tenant.Key(ctx, lang)
+}
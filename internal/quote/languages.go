@@ -0,0 +1,35 @@
+// internal/quote/languages.go
+
+package quote
+
+import "sort"
+
+// LanguageLister is implemented by providers that only serve a fixed set of
+// languages.
+type LanguageLister interface {
+	SupportedLanguages() []string
+}
+
+// SupportedLanguages returns the languages g serves, or nil when g accepts
+// any language.
+func SupportedLanguages(g Generator) []string {
+	if l, ok := g.(LanguageLister); ok {
+		return l.SupportedLanguages()
+	}
+	return nil
+}
+
+// SupportedLanguages ...
+func (f *Forismatic) SupportedLanguages() []string {
+	return []string{"en", "ru"}
+}
+
+// SupportedLanguages ...
+func (s *Static) SupportedLanguages() []string {
+	langs := make([]string, 0, len(s.Quotes))
+	for lang := range s.Quotes {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
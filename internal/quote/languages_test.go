@@ -0,0 +1,25 @@
+// internal/quote/languages_test.go
+
+package quote
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestSupportedLanguages(t *testing.T) {
+	testCases := []struct {
+		name     string
+		provider Generator
+		expected []string
+	}{
+		{"Forismatic", &Forismatic{}, []string{"en", "ru"}},
+		{"Static", &Static{Quotes: map[string][]Quote{"ru": nil, "de": nil}}, []string{"de", "ru"}},
+		{"Local", &Local{}, nil},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			assert.Equal(t, tC.expected, SupportedLanguages(tC.provider), "Languages are different than expected")
+		})
+	}
+}
@@ -0,0 +1,95 @@
+// internal/quote/local.go
+
+package quote
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// ErrNoQuotes is returned by offline providers that have nothing to serve for a language.
+var ErrNoQuotes = errors.New("quote: no quotes available")
+
+// Local serves random quotes from the local_quotes table, so the service keeps
+// working when no upstream provider is reachable.
+type Local struct {
+	DB *sql.DB
+}
+
+func init() {
+	Register("local", func(opts Options) (Generator, error) {
+		if opts.DB == nil {
+			return nil, errors.New("quote: local provider needs a database")
+		}
+
+		return &Local{DB: opts.DB}, nil
+	})
+}
+
+// Generate ...
+func (l *Local) Generate(ctx context.Context, lang string) (*Quote, error) {
+	q := Quote{Lang: lang}
+
+	query := "SELECT text, author FROM local_quotes WHERE lang = $1 ORDER BY random() LIMIT 1"
+	err := l.DB.QueryRowContext(ctx, query, lang).Scan(&q.Text, &q.Author)
+	if err == sql.ErrNoRows {
+		return nil, ErrNoQuotes
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &q, nil
+}
+
+// AddLocal ...
+func (l *Local) AddLocal(ctx context.Context, q Quote) (*Record, error) {
+	r := Record{Quote: Quote{Text: q.Text, Author: q.Author, Lang: q.Lang}}
+
+	query := "INSERT INTO local_quotes (text, author, lang) VALUES ($1, $2, $3) RETURNING id, created_at"
+	if err := l.DB.QueryRowContext(ctx, query, q.Text, q.Author, q.Lang).Scan(&r.ID, &r.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// ListLocal ...
+func (l *Local) ListLocal(ctx context.Context, lang string) ([]Record, error) {
+	query := "SELECT id, text, author, lang, created_at FROM local_quotes WHERE ($1 = '' OR lang = $1) ORDER BY id"
+	rows, err := l.DB.QueryContext(ctx, query, lang)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := []Record{}
+	for rows.Next() {
+		var r Record
+		if err := rows.Scan(&r.ID, &r.Text, &r.Author, &r.Lang, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+
+	return records, rows.Err()
+}
+
+// RemoveLocal ...
+func (l *Local) RemoveLocal(ctx context.Context, id int) error {
+	res, err := l.DB.ExecContext(ctx, "DELETE FROM local_quotes WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+// internal/quote/local_test.go
+
+package quote
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	p := newTestPersistence(t)
+	local := Local{DB: p.DB}
+	ctx := context.Background()
+
+	_, err := local.Generate(ctx, "en")
+	assert.Equal(t, ErrNoQuotes, err, "Empty table should have no quotes")
+
+	added, err := local.AddLocal(ctx, Quote{Text: "Bla", Author: "Bob", Lang: "en"})
+	require.NoError(t, err, "Should have no error when adding a quote")
+
+	q, err := local.Generate(ctx, "en")
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, &Quote{Text: "Bla", Author: "Bob", Lang: "en"}, q, "Should serve the local quote")
+
+	q, err = local.FindByAuthor(ctx, "en", "bo")
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, &Quote{Text: "Bla", Author: "Bob", Lang: "en"}, q, "Should find the quote by author")
+
+	_, err = local.FindByAuthor(ctx, "en", "Alice")
+	assert.Equal(t, ErrNoQuotes, err, "Should find no quotes by another author")
+
+	q, err = local.Find(ctx, "en", Constraints{Author: "bo", MaxLength: 3})
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, &Quote{Text: "Bla", Author: "Bob", Lang: "en"}, q, "Should find the quote by length")
+
+	_, err = local.Find(ctx, "en", Constraints{MinLength: 4})
+	assert.Equal(t, ErrNoQuotes, err, "Should find no longer quotes")
+
+	records, err := local.ListLocal(ctx, "")
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Len(t, records, 1, "Should list the local quote")
+
+	assert.NoError(t, local.RemoveLocal(ctx, added.ID), "Should have no error when removing a quote")
+	assert.Equal(t, ErrNotFound, local.RemoveLocal(ctx, added.ID), "Should get ErrNotFound on second remove")
+}
@@ -0,0 +1,106 @@
+// internal/quote/multi.go
+
+package quote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Named ...
+type Named struct {
+	Name      string
+	Generator Generator
+}
+
+// Multi asks each provider in order and returns the first quote it gets,
+// tagged with the name of the provider that served it. With Health, every
+// call is recorded and the healthiest providers are asked first.
+type Multi struct {
+	Providers []Named
+	Health    *HealthTracker
+
+	mu sync.RWMutex
+}
+
+// Generate ...
+func (m *Multi) Generate(ctx context.Context, lang string) (*Quote, error) {
+	var errs []error
+	for _, p := range m.ordered() {
+		start := time.Now()
+		q, err := p.Generator.Generate(ctx, lang)
+		// A caller giving up says nothing about the provider.
+		if m.Health != nil && (err == nil || ctx.Err() == nil) {
+			m.Health.Record(p.Name, time.Since(start), err)
+		}
+		if err == nil {
+			q.Provider = p.Name
+			return q, nil
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", p.Name, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil, errors.New("quote: no providers configured")
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+// Select reorders the providers as in names, which must name each of them
+// once. Without Health, the first one is asked first; with it, the first one
+// wins among the equally healthy.
+func (m *Multi) Select(names []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	selected := make([]Named, 0, len(m.Providers))
+	for _, name := range names {
+		i := slices.IndexFunc(m.Providers, func(p Named) bool { return p.Name == name })
+		if i < 0 {
+			return fmt.Errorf("quote: provider %q is not one of the configured providers", name)
+		}
+		if slices.ContainsFunc(selected, func(p Named) bool { return p.Name == name }) {
+			return fmt.Errorf("quote: provider %q is selected twice", name)
+		}
+		selected = append(selected, m.Providers[i])
+	}
+	if len(selected) != len(m.Providers) {
+		return errors.New("quote: every configured provider must be selected")
+	}
+
+	m.Providers = selected
+	return nil
+}
+
+// ordered returns the providers in the order they should be asked.
+func (m *Multi) ordered() []Named {
+	m.mu.RLock()
+	providers := m.Providers
+	m.mu.RUnlock()
+
+	if m.Health == nil || len(providers) < 2 {
+		return providers
+	}
+
+	byName := make(map[string]Named, len(providers))
+	names := make([]string, len(providers))
+	for i, p := range providers {
+		byName[p.Name] = p
+		names[i] = p.Name
+	}
+
+	ordered := make([]Named, len(names))
+	for i, name := range m.Health.Rank(names) {
+		ordered[i] = byName[name]
+	}
+	return ordered
+}
@@ -0,0 +1,112 @@
+// internal/quote/multi_test.go
+
+package quote
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMulti_Generate(t *testing.T) {
+	failing := &countingGenerator{err: errors.New("sample error")}
+
+	testCases := []struct {
+		name               string
+		providers          []Named
+		expectedProvider   string
+		expectedToGetError bool
+	}{
+		{
+			"FirstSucceeds",
+			[]Named{{"first", &countingGenerator{}}, {"second", &countingGenerator{}}},
+			"first",
+			false,
+		},
+		{
+			"FailsOver",
+			[]Named{{"first", failing}, {"second", &countingGenerator{}}},
+			"second",
+			false,
+		},
+		{
+			"AllFail",
+			[]Named{{"first", failing}, {"second", failing}},
+			"",
+			true,
+		},
+		{
+			"NoProviders",
+			nil,
+			"",
+			true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			multi := Multi{Providers: tC.providers}
+
+			q, err := multi.Generate(context.Background(), "en")
+
+			if tC.expectedToGetError {
+				assert.Error(t, err, "Got no error when expected")
+				return
+			}
+			assert.NoError(t, err, "Got error when not expected")
+			assert.Equal(t, tC.expectedProvider, q.Provider, "Quote should be tagged with its provider")
+		})
+	}
+}
+
+func TestStatic_Generate(t *testing.T) {
+	static := Static{Quotes: map[string][]Quote{"en": {{Text: "Bla", Author: "Bob"}}}}
+
+	q, err := static.Generate(context.Background(), "en")
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, &Quote{Text: "Bla", Author: "Bob", Lang: "en"}, q, "Wrong static quote")
+
+	_, err = static.Generate(context.Background(), "de")
+	assert.Error(t, err, "Got no error for unknown language")
+}
+
+func TestMulti_Generate_Health(t *testing.T) {
+	first := &countingGenerator{err: errors.New("sample error")}
+	second := &countingGenerator{}
+	multi := Multi{
+		Providers: []Named{{"first", first}, {"second", second}},
+		Health:    NewHealthTracker(10, time.Minute),
+	}
+
+	q, err := multi.Generate(context.Background(), "en")
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, "second", q.Provider, "Should fail over to the second provider")
+
+	q, err = multi.Generate(context.Background(), "en")
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, "second", q.Provider)
+	assert.Equal(t, 1, first.calls, "The healthier provider should be asked first")
+	assert.Equal(t, "sample error", multi.Health.Health("first").LastError)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	second.err = context.Canceled
+	multi.Generate(ctx, "en")
+	assert.Equal(t, 1.0, multi.Health.Health("second").SuccessRate, "Caller cancellations should not count against providers")
+}
+
+func TestMulti_Select(t *testing.T) {
+	multi := Multi{Providers: []Named{{"first", &countingGenerator{}}, {"second", &countingGenerator{}}}}
+
+	assert.NoError(t, multi.Select([]string{"second", "first"}), "Got error when not expected")
+	q, err := multi.Generate(context.Background(), "en")
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, "second", q.Provider, "The selected provider should be asked first")
+
+	for _, names := range [][]string{{"second"}, {"second", "third"}, {"second", "second"}} {
+		assert.Error(t, multi.Select(names), "Got no error when expected")
+	}
+	q, _ = multi.Generate(context.Background(), "en")
+	assert.Equal(t, "second", q.Provider, "Invalid selections should change nothing")
+}
@@ -0,0 +1,98 @@
+// internal/quote/quotable.go
+
+package quote
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/jeniok12/golang-tdd-example/requestid"
+	"github.com/jeniok12/golang-tdd-example/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"net/http"
+	"time"
+)
+
+// QuotableURL ...
+const QuotableURL = "https://api.quotable.io/random"
+
+// quotableWindow is waited out when Quotable refuses a request without
+// saying for how long. Its limit is 180 requests a minute.
+const quotableWindow = time.Minute
+
+// Quotable serves English quotes from api.quotable.io.
+type Quotable struct {
+	URL    string
+	Client HTTPWrapper
+
+	throttle throttle
+}
+
+func init() {
+	Register("quotable", func(opts Options) (Generator, error) {
+		if opts.URL == "" {
+			opts.URL = QuotableURL
+		}
+		if opts.Client == nil {
+			opts.Client = http.DefaultClient
+		}
+
+		return &Quotable{URL: opts.URL, Client: opts.Client}, nil
+	})
+}
+
+// quotableQuote is a quote as Quotable returns it.
+type quotableQuote struct {
+	Content string `json:"content"`
+	Author  string `json:"author"`
+}
+
+// Generate ...
+func (qt *Quotable) Generate(ctx context.Context, lang string) (q *Quote, err error) {
+	ctx, span := tracing.Start(ctx, "Quotable.Generate", trace.WithSpanKind(trace.SpanKindClient))
+	defer func() { tracing.End(span, err) }()
+
+	if err := qt.throttle.check("quotable"); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", qt.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if id := requestid.FromContext(ctx); id != "" {
+		req.Header.Set(requestid.Header, id)
+	}
+	tracing.Inject(ctx, req.Header)
+
+	resp, err := qt.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return nil, qt.throttle.limit("quotable", retryAfter(resp.Header, qt.throttle.clock(), quotableWindow))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	var body quotableQuote
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, err
+	}
+
+	return &Quote{Text: body.Content, Author: body.Author, Lang: "en"}, nil
+}
+
+// Ping checks that the Quotable API is reachable. Any non-5xx answer counts.
+func (qt *Quotable) Ping(ctx context.Context) error {
+	return ping(ctx, qt.Client, qt.URL, "quotable")
+}
+
+// SupportedLanguages ...
+func (qt *Quotable) SupportedLanguages() []string {
+	return []string{"en"}
+}
@@ -0,0 +1,101 @@
+// internal/quote/quotable_test.go
+
+package quote
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestQuotable_Generate(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		status             int
+		header             http.Header
+		body               string
+		expectedQuote      *Quote
+		expectedToGetError bool
+	}{
+		{
+			"Success",
+			http.StatusOK,
+			nil,
+			`{"_id":"abc","content":"Bla Bla Bla","author":"Bob","tags":["wisdom"],"length":11}`,
+			&Quote{Text: "Bla Bla Bla", Author: "Bob", Lang: "en"},
+			false,
+		},
+		{"InvalidJSON", http.StatusOK, nil, `{`, nil, true},
+		{"NotFound", http.StatusNotFound, nil, `{"statusCode":404}`, nil, true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				rw.WriteHeader(tC.status)
+				rw.Write([]byte(tC.body))
+			}))
+			defer server.Close()
+
+			quotable := Quotable{URL: server.URL, Client: server.Client()}
+			q, err := quotable.Generate(context.Background(), "en")
+
+			if tC.expectedToGetError {
+				assert.Error(t, err, "Got no error when expected")
+				return
+			}
+			assert.NoError(t, err, "Got error when not expected")
+			assert.Equal(t, tC.expectedQuote, q)
+		})
+	}
+}
+
+func TestQuotable_RateLimit(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		header             http.Header
+		expectedRetryAfter time.Duration
+	}{
+		{"RetryAfter", http.Header{"Retry-After": {"20"}}, 20 * time.Second},
+		{"RateLimitReset", http.Header{"Ratelimit-Reset": {"42"}}, 42 * time.Second},
+		{"NoHeader", nil, quotableWindow},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			limited := true
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				if limited {
+					for k, v := range tC.header {
+						rw.Header()[k] = v
+					}
+					rw.WriteHeader(http.StatusTooManyRequests)
+					return
+				}
+				rw.Write([]byte(`{"content":"Bla","author":"Bob"}`))
+			}))
+			defer server.Close()
+
+			now := time.Date(2019, 7, 21, 10, 0, 0, 0, time.UTC)
+			quotable := Quotable{URL: server.URL, Client: server.Client()}
+			quotable.throttle.now = func() time.Time { return now }
+
+			_, err := quotable.Generate(context.Background(), "en")
+			var rateErr *RateLimitError
+			if assert.ErrorAs(t, err, &rateErr) {
+				assert.Equal(t, tC.expectedRetryAfter, rateErr.RetryAfter)
+				assert.False(t, Retryable(err), "Rate limits should not be retried right away")
+			}
+
+			limited = false
+			now = now.Add(tC.expectedRetryAfter - time.Second)
+			_, err = quotable.Generate(context.Background(), "en")
+			assert.ErrorIs(t, err, ErrRateLimited, "Calls should fail fast until the limit resets")
+
+			now = now.Add(time.Second)
+			_, err = quotable.Generate(context.Background(), "en")
+			assert.NoError(t, err, "Calls should go through once the limit resets")
+		})
+	}
+}
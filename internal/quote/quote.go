@@ -0,0 +1,243 @@
+// internal/quote/quote.go
+
+package quote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/requestid"
+	"github.com/jeniok12/golang-tdd-example/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"io/ioutil"
+	"net/http"
+)
+
+// Quote ...
+type Quote struct {
+	Text     string `json:"quoteText" xml:"text"`
+	Author   string `json:"quoteAuthor" xml:"author"`
+	Lang     string `json:"lang" xml:"lang"`
+	Provider string `json:"provider,omitempty" xml:"provider,omitempty"`
+	// OriginalLang is the language a translated quote was translated from.
+	OriginalLang string `json:"originalLang,omitempty" xml:"originalLang,omitempty"`
+}
+
+// StatusError is returned when the provider answers with a non-OK status.
+type StatusError struct {
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("Not OK response status %d", e.StatusCode)
+}
+
+// HTTPWrapper ...
+type HTTPWrapper interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// ForismaticURL ...
+const ForismaticURL = "http://api.forismatic.com/api/1.0/"
+
+// Forismatic ...
+type Forismatic struct {
+	URL    string
+	Client HTTPWrapper
+	// Format is the response format asked for, json or xml; json by default.
+	// JSON that cannot be decoded is asked for again as XML, which is another
+	// random quote unless the context holds a key.
+	Format string
+}
+
+func init() {
+	Register("forismatic", func(opts Options) (Generator, error) {
+		if opts.URL == "" {
+			opts.URL = ForismaticURL
+		}
+		if opts.Client == nil {
+			opts.Client = http.DefaultClient
+		}
+		if _, ok := forismaticFormats[opts.Format]; opts.Format != "" && !ok {
+			return nil, fmt.Errorf("quote: unknown Forismatic format %q, expected json or xml", opts.Format)
+		}
+
+		return &Forismatic{URL: opts.URL, Client: opts.Client, Format: opts.Format}, nil
+	})
+}
+
+// Ping checks that the Forismatic API is reachable. Any non-5xx answer counts.
+func (f *Forismatic) Ping(ctx context.Context) error {
+	return ping(ctx, f.Client, f.URL, "forismatic")
+}
+
+// ping checks that the provider API at url answers with anything but a 5xx.
+func ping(ctx context.Context, client HTTPWrapper, url, provider string) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("%s responded with status %d", provider, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// GenerateWithKey returns the quote Forismatic picks for key, the same one on
+// every call.
+func (f *Forismatic) GenerateWithKey(ctx context.Context, lang string, key int) (*Quote, error) {
+	return f.Generate(WithKey(ctx, key), lang)
+}
+
+// Generate ...
+func (f *Forismatic) Generate(ctx context.Context, lang string) (q *Quote, err error) {
+	ctx, span := tracing.Start(ctx, "Forismatic.Generate", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("quote.lang", lang)))
+	defer func() { tracing.End(span, err) }()
+
+	format := f.Format
+	if format == "" {
+		format = "json"
+	}
+	decode, ok := forismaticFormats[format]
+	if !ok {
+		return nil, fmt.Errorf("quote: unknown Forismatic format %q, expected json or xml", format)
+	}
+
+	body, err := f.fetch(ctx, span, lang, format)
+	if err != nil {
+		return nil, err
+	}
+	quote, err := decode(body)
+	// JSON the repairs cannot fix is asked again as XML, which Forismatic
+	// escapes properly. The key, if any, asks for the same quote again;
+	// without one, Forismatic picks another random quote.
+	if err != nil && format == "json" {
+		body, xmlErr := f.fetch(ctx, span, lang, "xml")
+		if xmlErr == nil {
+			quote, xmlErr = decodeForismaticXML(body)
+		}
+		if xmlErr != nil {
+			return nil, errors.Join(err, xmlErr)
+		}
+		err = nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	quote.Lang = lang
+
+	return quote, nil
+}
+
+// forismaticFormats decodes the quotes in each response format Forismatic is
+// asked for.
+var forismaticFormats = map[string]func(body []byte) (*Quote, error){
+	"json": decodeForismatic,
+	"xml":  decodeForismaticXML,
+}
+
+// fetch returns the body of a quote in lang from Forismatic, in format.
+func (f *Forismatic) fetch(ctx context.Context, span trace.Span, lang, format string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", f.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.URL.RawQuery = fmt.Sprintf("method=getQuote&format=%s&lang=%s", format, lang)
+	if key, ok := KeyFromContext(ctx); ok {
+		req.URL.RawQuery += fmt.Sprintf("&key=%d", key)
+	}
+	if id := requestid.FromContext(ctx); id != "" {
+		req.Header.Set(requestid.Header, id)
+	}
+	tracing.Inject(ctx, req.Header)
+
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{StatusCode: resp.StatusCode}
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// forismaticXML is a quote in Forismatic's XML format.
+type forismaticXML struct {
+	Quote struct {
+		Text   string `xml:"quoteText"`
+		Author string `xml:"quoteAuthor"`
+	} `xml:"quote"`
+}
+
+// decodeForismaticXML decodes a Forismatic quote in XML.
+func decodeForismaticXML(body []byte) (*Quote, error) {
+	var doc forismaticXML
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Quote.Text == "" {
+		return nil, errors.New("quote: no quote in Forismatic XML response")
+	}
+	return &Quote{Text: doc.Quote.Text, Author: doc.Quote.Author}, nil
+}
+
+// decodeForismatic decodes a Forismatic quote, repairing the invalid JSON the
+// API is known to send when the body does not parse as is.
+func decodeForismatic(body []byte) (*Quote, error) {
+	var quote Quote
+	err := json.Unmarshal(body, &quote)
+	if err == nil {
+		return &quote, nil
+	}
+
+	if repaired := repairJSON(body); !bytes.Equal(repaired, body) && json.Unmarshal(repaired, &quote) == nil {
+		return &quote, nil
+	}
+	return nil, err
+}
+
+// repairJSON fixes the malformations of Forismatic's JSON inside strings:
+// apostrophes escaped as \' and raw control characters such as line breaks.
+func repairJSON(body []byte) []byte {
+	out := make([]byte, 0, len(body))
+	inString := false
+	for i := 0; i < len(body); i++ {
+		c := body[i]
+		switch {
+		case !inString:
+			inString = c == '"'
+		case c == '\\' && i+1 < len(body) && body[i+1] == '\'':
+			// Dropping the backslash leaves the apostrophe.
+			continue
+		case c == '\\' && i+1 < len(body):
+			out = append(out, c, body[i+1])
+			i++
+			continue
+		case c == '"':
+			inString = false
+		case c < 0x20:
+			out = append(out, fmt.Sprintf("\\u%04x", c)...)
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
@@ -0,0 +1,330 @@
+// internal/quote/quote_test.go
+
+package quote
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/jeniok12/golang-tdd-example/requestid"
+	"github.com/jeniok12/golang-tdd-example/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+var mockForismaticServiceResponse = map[string]interface{}{
+	"quoteText":   "Bla Bla Bla",
+	"quoteAuthor": "Bob",
+}
+
+var expectedQuote = Quote{
+	Author: "Bob",
+	Text:   "Bla Bla Bla",
+	Lang:   "en",
+}
+
+func TestForismatic_Generate(t *testing.T) {
+	testCases := []struct {
+		name               string
+		lang               string
+		createMocks        func() *httptest.Server
+		expectedQuote      *Quote
+		expectedToGetError bool
+	}{
+		{
+			"SuccessResponseFromHTTPWrapper",
+			"en",
+			func() *httptest.Server {
+				server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+					assert.Equal(t, http.MethodGet, req.Method, "Should have different request method")
+
+					assert.Equal(t, "getQuote", req.URL.Query().Get("method"), "Wrong method query param")
+					assert.Equal(t, "json", req.URL.Query().Get("format"), "Wrong method query param")
+					assert.Equal(t, "en", req.URL.Query().Get("lang"), "Wrong method query param")
+
+					res, _ := json.Marshal(mockForismaticServiceResponse)
+					rw.WriteHeader(http.StatusOK)
+					rw.Write(res)
+				}))
+
+				return server
+			},
+			&expectedQuote,
+			false,
+		},
+		{
+			"ErrorFromHTTPWrapper",
+			"en",
+			func() *httptest.Server {
+				server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+					rw.WriteHeader(http.StatusInternalServerError)
+				}))
+
+				return server
+			},
+			nil,
+			true,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			server := tC.createMocks()
+			defer server.Close()
+
+			forismatic := Forismatic{
+				URL:    server.URL,
+				Client: server.Client(),
+			}
+
+			actulaQuote, err := forismatic.Generate(context.Background(), tC.lang)
+
+			assert.Equal(t, tC.expectedQuote, actulaQuote, "Expected Quote is different from actual")
+			if tC.expectedToGetError {
+				assert.Error(t, err, "Got no error when expected")
+			} else {
+				assert.NoError(t, err, "Got error when not expected")
+			}
+		})
+	}
+}
+
+func TestForismatic_Generate_InvalidJSON(t *testing.T) {
+	testCases := []struct {
+		name               string
+		body               string
+		expectedQuote      *Quote
+		expectedToGetError bool
+	}{
+		{"EscapedApostrophe", `{"quoteText":"Don\'t wait.","quoteAuthor":"Bob"}`, &Quote{Text: "Don't wait.", Author: "Bob", Lang: "en"}, false},
+		{"EscapedApostropheInAuthor", `{"quoteText":"Bla","quoteAuthor":"O\'Brien"}`, &Quote{Text: "Bla", Author: "O'Brien", Lang: "en"}, false},
+		{"EscapedBackslashBeforeApostrophe", `{"quoteText":"C:\\\'s","quoteAuthor":"Bob"}`, &Quote{Text: `C:\'s`, Author: "Bob", Lang: "en"}, false},
+		{"RawLineBreak", "{\"quoteText\":\"Bla\nBla\",\"quoteAuthor\":\"Bob\"}", &Quote{Text: "Bla\nBla", Author: "Bob", Lang: "en"}, false},
+		{"OtherEscapesKept", `{"quoteText":"\"Bla\" \u00e9\\n\'","quoteAuthor":"Bob"}`, &Quote{Text: `"Bla" é\n'`, Author: "Bob", Lang: "en"}, false},
+		{"Truncated", `{"quoteText":"Don\'t`, nil, true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				rw.Write([]byte(tC.body))
+			}))
+			defer server.Close()
+
+			forismatic := Forismatic{
+				URL:    server.URL,
+				Client: server.Client(),
+			}
+
+			actualQuote, err := forismatic.Generate(context.Background(), "en")
+
+			assert.Equal(t, tC.expectedQuote, actualQuote, "Expected Quote is different from actual")
+			if tC.expectedToGetError {
+				assert.Error(t, err, "Got no error when expected")
+			} else {
+				assert.NoError(t, err, "Got error when not expected")
+			}
+		})
+	}
+}
+
+func TestForismatic_Generate_Format(t *testing.T) {
+	const xmlQuote = `<?xml version="1.0" encoding="UTF-8"?>
+<forismatic><quote><quoteText>Bla Bla Bla</quoteText><quoteAuthor>Bob</quoteAuthor><senderName></senderName><senderLink></senderLink><quoteLink>http://forismatic.com/en/bla/</quoteLink></quote></forismatic>`
+
+	testCases := []struct {
+		name               string
+		format             string
+		responses          map[string]string
+		expectedFormats    []string
+		expectedQuote      *Quote
+		expectedToGetError bool
+	}{
+		{"XML", "xml", map[string]string{"xml": xmlQuote}, []string{"xml"}, &expectedQuote, false},
+		{"JSON", "", map[string]string{"json": `{"quoteText":"Bla Bla Bla","quoteAuthor":"Bob"}`}, []string{"json"}, &expectedQuote, false},
+		{"BrokenJSONFallsBackToXML", "json", map[string]string{"json": `{"quoteText":"Bla Bla Bla\x","quoteAuthor":"Bob"}`, "xml": xmlQuote}, []string{"json", "xml"}, &expectedQuote, false},
+		{"BrokenXML", "json", map[string]string{"json": `{"quoteText":`, "xml": `<forismatic>`}, []string{"json", "xml"}, nil, true},
+		{"XMLWithoutQuote", "xml", map[string]string{"xml": `<forismatic></forismatic>`}, []string{"xml"}, nil, true},
+		{"Unknown", "html", nil, nil, nil, true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			var formats []string
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				assert.Equal(t, "12345", req.URL.Query().Get("key"), "The fallback should ask for the same quote")
+				format := req.URL.Query().Get("format")
+				formats = append(formats, format)
+				rw.Write([]byte(tC.responses[format]))
+			}))
+			defer server.Close()
+
+			forismatic := Forismatic{
+				URL:    server.URL,
+				Client: server.Client(),
+				Format: tC.format,
+			}
+
+			actualQuote, err := forismatic.GenerateWithKey(context.Background(), "en", 12345)
+
+			assert.Equal(t, tC.expectedQuote, actualQuote, "Expected Quote is different from actual")
+			assert.Equal(t, tC.expectedFormats, formats, "Wrong formats asked for")
+			if tC.expectedToGetError {
+				assert.Error(t, err, "Got no error when expected")
+			} else {
+				assert.NoError(t, err, "Got error when not expected")
+			}
+		})
+	}
+}
+
+func TestForismatic_Generate_FallbackError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if req.URL.Query().Get("format") == "xml" {
+			rw.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		rw.Write([]byte(`{"quoteText":`))
+	}))
+	defer server.Close()
+
+	forismatic := Forismatic{
+		URL:    server.URL,
+		Client: server.Client(),
+	}
+
+	_, err := forismatic.Generate(context.Background(), "en")
+
+	var statusErr *StatusError
+	assert.ErrorAs(t, err, &statusErr, "The XML error should be kept")
+	var syntaxErr *json.SyntaxError
+	assert.ErrorAs(t, err, &syntaxErr, "The JSON error should be kept")
+}
+
+func TestForismatic_Generate_RequestID(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "abc123", req.Header.Get(requestid.Header), "Request ID should be propagated")
+
+		res, _ := json.Marshal(mockForismaticServiceResponse)
+		rw.WriteHeader(http.StatusOK)
+		rw.Write(res)
+	}))
+	defer server.Close()
+
+	forismatic := Forismatic{
+		URL:    server.URL,
+		Client: server.Client(),
+	}
+
+	_, err := forismatic.Generate(requestid.NewContext(context.Background(), "abc123"), "en")
+
+	assert.NoError(t, err, "Got error when not expected")
+}
+
+func TestForismatic_Generate_Trace(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(previous)
+	tracing.Setup(context.Background(), tracing.Config{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.NotEmpty(t, req.Header.Get("traceparent"), "Trace context should be propagated")
+		rw.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	forismatic := Forismatic{
+		URL:    server.URL,
+		Client: server.Client(),
+	}
+
+	_, err := forismatic.Generate(context.Background(), "ru")
+	assert.Error(t, err, "Got no error when expected")
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "Forismatic.Generate", spans[0].Name())
+		assert.Equal(t, codes.Error, spans[0].Status().Code, "Upstream failures should mark the span")
+		assert.Contains(t, spans[0].Attributes(), attribute.String("quote.lang", "ru"))
+		assert.Contains(t, spans[0].Attributes(), attribute.Int("http.response.status_code", http.StatusServiceUnavailable))
+	}
+}
+
+func TestForismatic_GenerateWithKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "12345", req.URL.Query().Get("key"), "Wrong key query param")
+
+		res, _ := json.Marshal(mockForismaticServiceResponse)
+		rw.WriteHeader(http.StatusOK)
+		rw.Write(res)
+	}))
+	defer server.Close()
+
+	forismatic := Forismatic{
+		URL:    server.URL,
+		Client: server.Client(),
+	}
+
+	q, err := forismatic.GenerateWithKey(context.Background(), "en", 12345)
+
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, &expectedQuote, q, "Expected Quote is different from actual")
+}
+
+func TestForismatic_Ping(t *testing.T) {
+	testCases := []struct {
+		name               string
+		status             int
+		expectedToGetError bool
+	}{
+		{"Reachable", http.StatusOK, false},
+		{"ClientError", http.StatusBadRequest, false},
+		{"ServerError", http.StatusBadGateway, true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				rw.WriteHeader(tC.status)
+			}))
+			defer server.Close()
+
+			forismatic := Forismatic{
+				URL:    server.URL,
+				Client: server.Client(),
+			}
+
+			err := forismatic.Ping(context.Background())
+
+			if tC.expectedToGetError {
+				assert.Error(t, err, "Got no error when expected")
+			} else {
+				assert.NoError(t, err, "Got error when not expected")
+			}
+		})
+	}
+}
+
+func TestForismatic_Generate_CanceledContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		rw.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	forismatic := Forismatic{
+		URL:    server.URL,
+		Client: server.Client(),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	actualQuote, err := forismatic.Generate(ctx, "en")
+
+	assert.Nil(t, actualQuote, "Expected no quote for canceled context")
+	assert.Error(t, err, "Got no error when expected")
+}
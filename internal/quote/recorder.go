@@ -0,0 +1,159 @@
+// internal/quote/recorder.go
+
+package quote
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RecordingMode tells a Recorder what to do with provider calls.
+type RecordingMode string
+
+const (
+	// ModeRecord calls the provider and saves each response as a fixture.
+	ModeRecord RecordingMode = "record"
+	// ModeReplay answers from the fixtures without calling the provider.
+	ModeReplay RecordingMode = "replay"
+)
+
+// ErrNoFixture is returned in replay mode for requests never recorded.
+var ErrNoFixture = errors.New("quote: no fixture recorded for request")
+
+// Recorder records the responses of the provider API to fixtures in Dir, one
+// file per request, and replays them, so tests and local development can run
+// without the network.
+type Recorder struct {
+	Mode RecordingMode
+	Dir  string
+	// Client calls the provider in record mode.
+	Client HTTPWrapper
+}
+
+// fixture is a recorded request and its response.
+type fixture struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body"`
+}
+
+// Do ...
+func (r *Recorder) Do(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		if body, err = io.ReadAll(req.Body); err != nil {
+			return nil, err
+		}
+		req.Body.Close()
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	path := filepath.Join(r.Dir, fixtureName(req, body))
+
+	switch r.Mode {
+	case ModeReplay:
+		return r.replay(req, path)
+	case ModeRecord:
+		return r.record(req, path)
+	default:
+		return nil, fmt.Errorf("quote: unknown recording mode %q, expected record or replay", r.Mode)
+	}
+}
+
+func (r *Recorder) replay(req *http.Request, path string) (*http.Response, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoFixture, req.Method, req.URL)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f fixture
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("quote: fixture %s: %v", path, err)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.Status, http.StatusText(f.Status)),
+		StatusCode:    f.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        f.Header,
+		Body:          io.NopCloser(strings.NewReader(f.Body)),
+		ContentLength: int64(len(f.Body)),
+		Request:       req,
+	}, nil
+}
+
+func (r *Recorder) record(req *http.Request, path string) (*http.Response, error) {
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	data, err := json.MarshalIndent(fixture{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Status: resp.StatusCode,
+		Header: resp.Header,
+		Body:   string(body),
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFixture(path, append(data, '\n')); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// writeFixture replaces the fixture at path whole, so concurrent calls
+// recording the same request never leave it half written.
+func writeFixture(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".fixture-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// fixtureName names the fixture of req after its host and a hash of its
+// method, URL and body. Headers, such as the request ID, are left out so the
+// same call matches across runs.
+func fixtureName(req *http.Request, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", req.Method, req.URL)
+	h.Write(body)
+	return req.URL.Hostname() + "-" + hex.EncodeToString(h.Sum(nil))[:16] + ".json"
+}
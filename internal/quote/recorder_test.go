@@ -0,0 +1,50 @@
+// internal/quote/recorder_test.go
+
+package quote
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestRecorder(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"quoteText":"Bla Bla Bla","quoteAuthor":"Bob","lang":"%s"}`, r.URL.Query().Get("lang"))
+	}))
+	dir := t.TempDir()
+
+	recorded, err := (&Forismatic{URL: server.URL, Client: &Recorder{Mode: ModeRecord, Dir: dir, Client: http.DefaultClient}}).Generate(context.Background(), "en")
+	require.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, &expectedQuote, recorded)
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err, "Got error when not expected")
+	assert.Len(t, entries, 1, "The response should be saved as a fixture")
+	server.Close()
+
+	replay := &Forismatic{URL: server.URL, Client: &Recorder{Mode: ModeReplay, Dir: dir}}
+	replayed, err := replay.Generate(context.Background(), "en")
+	require.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, recorded, replayed, "The recorded response should be replayed")
+	assert.Equal(t, 1, calls, "Replaying should not call the provider")
+
+	_, err = replay.Generate(context.Background(), "ru")
+	assert.ErrorIs(t, err, ErrNoFixture, "Requests never recorded should fail")
+}
+
+func TestRecorder_Fixtures(t *testing.T) {
+	f := &Forismatic{URL: ForismaticURL, Client: &Recorder{Mode: ModeReplay, Dir: "testdata/fixtures"}}
+
+	q, err := f.Generate(context.Background(), "en")
+
+	require.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, &Quote{Text: "Well done is better than well said. ", Author: "Benjamin Franklin", Lang: "en"}, q)
+}
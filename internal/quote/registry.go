@@ -0,0 +1,75 @@
+// internal/quote/registry.go
+
+package quote
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Generator ...
+type Generator interface {
+	Generate(ctx context.Context, lang string) (*Quote, error)
+}
+
+// Options ...
+type Options struct {
+	URL    string
+	Client HTTPWrapper
+	DB     *sql.DB
+	// Format is the response format asked of providers offering several.
+	Format string
+}
+
+// ProviderFactory ...
+type ProviderFactory func(opts Options) (Generator, error)
+
+var (
+	providersMu sync.RWMutex
+	providers   = make(map[string]ProviderFactory)
+)
+
+// Register makes a provider available by name. It panics if the name is
+// registered twice or the factory is nil, the same way database/sql does.
+func Register(name string, factory ProviderFactory) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	if factory == nil {
+		panic("quote: Register factory is nil")
+	}
+	if _, dup := providers[name]; dup {
+		panic("quote: Register called twice for provider " + name)
+	}
+	providers[name] = factory
+}
+
+// New ...
+func New(name string, opts Options) (Generator, error) {
+	providersMu.RLock()
+	factory, ok := providers[name]
+	providersMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("quote: unknown provider %q", name)
+	}
+
+	return factory(opts)
+}
+
+// Providers ...
+func Providers() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,54 @@
+// internal/quote/registry_test.go
+
+package quote
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type staticGenerator struct {
+	quote Quote
+}
+
+func (g *staticGenerator) Generate(ctx context.Context, lang string) (*Quote, error) {
+	q := g.quote
+	q.Lang = lang
+	return &q, nil
+}
+
+func TestRegistry(t *testing.T) {
+	Register("static_test", func(opts Options) (Generator, error) {
+		return &staticGenerator{quote: Quote{Text: opts.URL}}, nil
+	})
+	Register("failing_test", func(opts Options) (Generator, error) {
+		return nil, errors.New("sample error")
+	})
+
+	assert.Subset(t, Providers(), []string{"forismatic", "static_test", "failing_test"}, "Registered providers should be listed")
+
+	g, err := New("static_test", Options{URL: "bla"})
+	require.NoError(t, err, "Got error when not expected")
+	q, _ := g.Generate(context.Background(), "en")
+	assert.Equal(t, &Quote{Text: "bla", Lang: "en"}, q, "Generator should be built from options")
+
+	_, err = New("failing_test", Options{})
+	assert.Error(t, err, "Factory error should be returned")
+
+	_, err = New("unknown", Options{})
+	assert.Error(t, err, "Got no error for unknown provider")
+
+	g, err = New("forismatic", Options{})
+	require.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, ForismaticURL, g.(*Forismatic).URL, "Forismatic should default its URL")
+
+	_, err = New("forismatic", Options{Format: "html"})
+	assert.Error(t, err, "Got no error for unsupported format")
+
+	assert.Panics(t, func() {
+		Register("static_test", func(opts Options) (Generator, error) { return nil, nil })
+	}, "Duplicate registration should panic")
+}
@@ -0,0 +1,90 @@
+// internal/quote/retry.go
+
+package quote
+
+import (
+	"context"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/clock"
+	"math/rand"
+	"net"
+	"time"
+)
+
+// Retrying retries the inner generator with exponential backoff and full
+// jitter. Only 5xx responses and network errors are retried.
+type Retrying struct {
+	Inner       Generator
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	// Clock times the backoff, the system clock by default.
+	Clock clock.Clock
+
+	// jitter returns a random duration in [0, d). Tests replace it.
+	jitter func(d time.Duration) time.Duration
+}
+
+// Generate ...
+func (r *Retrying) Generate(ctx context.Context, lang string) (*Quote, error) {
+	var err error
+	for attempt := 0; ; attempt++ {
+		var q *Quote
+		q, err = r.Inner.Generate(ctx, lang)
+		if err == nil {
+			return q, nil
+		}
+
+		if attempt+1 >= r.MaxAttempts || !Retryable(err) || ctx.Err() != nil {
+			return nil, err
+		}
+
+		delay := r.backoff(attempt)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return nil, err
+		}
+
+		timer := clock.Or(r.Clock).NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C():
+		}
+	}
+}
+
+func (r *Retrying) backoff(attempt int) time.Duration {
+	delay := r.BaseDelay << uint(attempt)
+	if delay > r.MaxDelay || delay <= 0 {
+		delay = r.MaxDelay
+	}
+
+	jitter := r.jitter
+	if jitter == nil {
+		jitter = func(d time.Duration) time.Duration {
+			if d <= 0 {
+				return 0
+			}
+			return time.Duration(rand.Int63n(int64(d)))
+		}
+	}
+
+	return jitter(delay)
+}
+
+// Retryable reports whether err is worth retrying: an upstream 5xx or a
+// network failure.
+func Retryable(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
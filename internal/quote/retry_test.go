@@ -0,0 +1,117 @@
+// internal/quote/retry_test.go
+
+package quote
+
+import (
+	"context"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/clock"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+	"time"
+)
+
+type scriptedGenerator struct {
+	errs  []error
+	calls int
+}
+
+func (g *scriptedGenerator) Generate(ctx context.Context, lang string) (*Quote, error) {
+	g.calls++
+	if len(g.errs) >= g.calls && g.errs[g.calls-1] != nil {
+		return nil, g.errs[g.calls-1]
+	}
+	return &Quote{Text: "Bla Bla Bla", Lang: lang}, nil
+}
+
+func TestRetrying_Generate(t *testing.T) {
+	netErr := &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+
+	testCases := []struct {
+		name               string
+		errs               []error
+		expectedCalls      int
+		expectedToGetError bool
+	}{
+		{"FirstTry", nil, 1, false},
+		{"RecoversFrom5xx", []error{&StatusError{StatusCode: 503}, &StatusError{StatusCode: 502}}, 3, false},
+		{"RecoversFromNetworkError", []error{netErr}, 2, false},
+		{"GivesUpAfterMaxAttempts", []error{netErr, netErr, netErr, netErr}, 3, true},
+		{"NoRetryOn4xx", []error{&StatusError{StatusCode: 404}}, 1, true},
+		{"NoRetryOnDecodeError", []error{errors.New("invalid character")}, 1, true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			inner := &scriptedGenerator{errs: tC.errs}
+			var delays []time.Duration
+			retrying := Retrying{
+				Inner:       inner,
+				MaxAttempts: 3,
+				BaseDelay:   time.Millisecond,
+				MaxDelay:    3 * time.Millisecond,
+				jitter: func(d time.Duration) time.Duration {
+					delays = append(delays, d)
+					return d
+				},
+			}
+
+			q, err := retrying.Generate(context.Background(), "en")
+
+			assert.Equal(t, tC.expectedCalls, inner.calls, "Wrong number of attempts")
+			if tC.expectedToGetError {
+				assert.Error(t, err, "Got no error when expected")
+				assert.Nil(t, q, "Got quote when not expected")
+			} else {
+				assert.NoError(t, err, "Got error when not expected")
+				assert.NotNil(t, q, "Got no quote when expected")
+			}
+			for i, d := range delays {
+				expected := time.Millisecond << uint(i)
+				if expected > 3*time.Millisecond {
+					expected = 3 * time.Millisecond
+				}
+				assert.Equal(t, expected, d, "Backoff should grow exponentially up to max delay")
+			}
+		})
+	}
+}
+
+func TestRetrying_WaitsForBackoff(t *testing.T) {
+	inner := &scriptedGenerator{errs: []error{&StatusError{StatusCode: 503}}}
+	mock := clock.NewMock(time.Now())
+	retrying := Retrying{
+		Inner:       inner,
+		MaxAttempts: 2,
+		BaseDelay:   time.Minute,
+		MaxDelay:    time.Minute,
+		Clock:       mock,
+		jitter:      func(d time.Duration) time.Duration { return d },
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := retrying.Generate(context.Background(), "en")
+		done <- err
+	}()
+
+	mock.BlockUntil(1)
+	assert.Equal(t, 1, inner.calls, "Retry should wait for the backoff")
+	mock.Add(time.Minute)
+
+	assert.NoError(t, <-done, "Got error when not expected")
+	assert.Equal(t, 2, inner.calls, "Wrong number of attempts")
+}
+
+func TestRetrying_HonorsDeadline(t *testing.T) {
+	inner := &scriptedGenerator{errs: []error{&StatusError{StatusCode: 500}}}
+	retrying := Retrying{Inner: inner, MaxAttempts: 3, BaseDelay: time.Hour, MaxDelay: time.Hour}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err := retrying.Generate(ctx, "en")
+
+	assert.Error(t, err, "Got no error when expected")
+	assert.Equal(t, 1, inner.calls, "Should not wait past the context deadline")
+}
@@ -0,0 +1,167 @@
+// internal/quote/search.go
+
+package quote
+
+import (
+	"context"
+	"database/sql"
+	"github.com/jeniok12/golang-tdd-example/tenant"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// HighlightStart and HighlightEnd wrap the matched words of a SearchResult.
+const (
+	HighlightStart = "<mark>"
+	HighlightEnd   = "</mark>"
+)
+
+// SearchFilter ...
+type SearchFilter struct {
+	Query  string
+	Lang   string
+	Limit  int
+	Offset int
+}
+
+// SearchResult is a quote matching every word of a search.
+type SearchResult struct {
+	Record
+	// Rank orders the results, higher is better. It only compares results of
+	// the same search.
+	Rank float64 `json:"rank"`
+	// Highlight is the quote text with the matched words wrapped in <mark> tags.
+	Highlight string `json:"highlight"`
+}
+
+// SearchQuotes finds the quotes whose text or author contain every word of
+// the query, best matches first. Words match whole and case-insensitively,
+// like Postgres' simple text search configuration.
+func (p *Persistence) SearchQuotes(ctx context.Context, filter SearchFilter) ([]SearchResult, error) {
+	query := `SELECT ` + recordColumns + `, ts_rank(search, q),
+			ts_headline('simple', text, q, 'StartSel=` + HighlightStart + `, StopSel=` + HighlightEnd + `, HighlightAll=true')
+		FROM quotes, plainto_tsquery('simple', $1) q
+		WHERE search @@ q AND tenant_id = $2 AND ($3 = '' OR lang = $3)
+		ORDER BY 8 DESC, id DESC
+		LIMIT $4 OFFSET $5`
+
+	var results []SearchResult
+	err := p.read(ctx, func(db *sql.DB) error {
+		rows, err := db.QueryContext(ctx, query, filter.Query, tenant.FromContext(ctx), filter.Lang, filter.Limit, filter.Offset)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		results = []SearchResult{}
+		for rows.Next() {
+			var r SearchResult
+			if err := scanRecord(rows, &r.Record, &r.Rank, &r.Highlight); err != nil {
+				return err
+			}
+			results = append(results, r)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// SearchWords splits a search query into lower case words, the way stores
+// without full text search match them.
+func SearchWords(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), isNotWordRune)
+}
+
+// Match reports whether r contains every one of words, ranked by the share
+// of its words they make up. Stores without full text search use it.
+func Match(r Record, words []string) (SearchResult, bool) {
+	if len(words) == 0 {
+		return SearchResult{}, false
+	}
+
+	all := SearchWords(r.Text + " " + r.Author)
+	hits := 0
+	for _, w := range words {
+		n := count(all, w)
+		if n == 0 {
+			return SearchResult{}, false
+		}
+		hits += n
+	}
+
+	return SearchResult{
+		Record:    r,
+		Rank:      float64(hits) / float64(len(all)),
+		Highlight: highlight(r.Text, words),
+	}, true
+}
+
+// PageResults orders results by rank, newest first among equals, and returns
+// the page filter asks for.
+func PageResults(results []SearchResult, filter SearchFilter) []SearchResult {
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank > results[j].Rank
+		}
+		return results[i].ID > results[j].ID
+	})
+
+	if filter.Offset >= len(results) {
+		return []SearchResult{}
+	}
+	results = results[filter.Offset:]
+	if filter.Limit < len(results) {
+		results = results[:filter.Limit]
+	}
+	return results
+}
+
+func count(words []string, word string) int {
+	n := 0
+	for _, w := range words {
+		if w == word {
+			n++
+		}
+	}
+	return n
+}
+
+// highlight wraps the words of text found in words.
+func highlight(text string, words []string) string {
+	var b strings.Builder
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		w := text[start:end]
+		if count(words, strings.ToLower(w)) > 0 {
+			w = HighlightStart + w + HighlightEnd
+		}
+		b.WriteString(w)
+		start = -1
+	}
+
+	for i, c := range text {
+		if !isNotWordRune(c) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+		b.WriteRune(c)
+	}
+	flush(len(text))
+
+	return b.String()
+}
+
+func isNotWordRune(c rune) bool {
+	return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+}
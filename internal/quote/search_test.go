@@ -0,0 +1,93 @@
+// internal/quote/search_test.go
+
+package quote
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSearchWords(t *testing.T) {
+	assert.Equal(t, []string{"don", "t", "panic", "42"}, SearchWords(" Don't PANIC, 42! "))
+	assert.Empty(t, SearchWords(" ,.! "), "Punctuation alone has no words")
+}
+
+func TestMatch(t *testing.T) {
+	r := Record{ID: 1, Quote: Quote{Text: "Life is life, and Lifetime is not", Author: "Bob"}}
+
+	testCases := []struct {
+		desc              string
+		query             string
+		expectedMatch     bool
+		expectedHighlight string
+	}{
+		{"Word", "life", true, "<mark>Life</mark> is <mark>life</mark>, and Lifetime is not"},
+		{"EveryWord", "LIFE not", true, "<mark>Life</mark> is <mark>life</mark>, and Lifetime is <mark>not</mark>"},
+		{"Author", "bob life", true, "<mark>Life</mark> is <mark>life</mark>, and Lifetime is not"},
+		{"MissingWord", "life death", false, ""},
+		{"WholeWordsOnly", "lif", false, ""},
+		{"NoWords", "!", false, ""},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			result, ok := Match(r, SearchWords(tC.query))
+
+			assert.Equal(t, tC.expectedMatch, ok)
+			assert.Equal(t, tC.expectedHighlight, result.Highlight)
+		})
+	}
+
+	often, _ := Match(r, []string{"life"})
+	once, _ := Match(r, []string{"lifetime"})
+	assert.Greater(t, often.Rank, once.Rank, "Quotes repeating the words should rank higher")
+}
+
+func TestPageResults(t *testing.T) {
+	results := []SearchResult{
+		{Record: Record{ID: 1}, Rank: 0.5},
+		{Record: Record{ID: 2}, Rank: 0.1},
+		{Record: Record{ID: 3}, Rank: 0.5},
+	}
+
+	page := PageResults(results, SearchFilter{Limit: 2})
+	assert.Equal(t, []int{3, 1}, resultIDs(page), "Best matches should come first, newest among equals")
+	assert.Equal(t, []int{2}, resultIDs(PageResults(results, SearchFilter{Limit: 2, Offset: 2})))
+	assert.Empty(t, PageResults(results, SearchFilter{Limit: 2, Offset: 5}))
+}
+
+func TestPersistence_SearchQuotes(t *testing.T) {
+	p := newTestPersistence(t)
+	ctx := context.Background()
+
+	once, err := p.SaveQuote(ctx, Quote{Text: "Life is short", Author: "Bob", Lang: "en"})
+	require.NoError(t, err, "Should have no error when saving a quote")
+	_, err = p.SaveQuote(ctx, Quote{Text: "Art is long", Author: "Alice", Lang: "en"})
+	require.NoError(t, err, "Should have no error when saving a quote")
+	twice, err := p.SaveQuote(ctx, Quote{Text: "Life, life", Author: "Carol", Lang: "en"})
+	require.NoError(t, err, "Should have no error when saving a quote")
+
+	results, err := p.SearchQuotes(ctx, SearchFilter{Query: "LIFE", Limit: 10})
+	assert.NoError(t, err, "Should have no error when searching quotes")
+	assert.Equal(t, []int{twice.ID, once.ID}, resultIDs(results), "Better matches should come first")
+	if assert.NotEmpty(t, results) {
+		assert.Equal(t, "<mark>Life</mark>, <mark>life</mark>", results[0].Highlight, "Matched words should be highlighted")
+	}
+
+	results, err = p.SearchQuotes(ctx, SearchFilter{Query: "life alice", Limit: 10})
+	assert.NoError(t, err, "Should have no error when searching quotes")
+	assert.Empty(t, results, "Every word should match")
+
+	results, err = p.SearchQuotes(ctx, SearchFilter{Query: "life", Lang: "ru", Limit: 10})
+	assert.NoError(t, err, "Should have no error when searching quotes")
+	assert.Empty(t, results, "Should respect lang")
+}
+
+func resultIDs(results []SearchResult) []int {
+	ids := []int{}
+	for _, r := range results {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
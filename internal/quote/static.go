@@ -0,0 +1,45 @@
+// internal/quote/static.go
+
+package quote
+
+import (
+	"context"
+	"math/rand"
+)
+
+// Static serves quotes from a fixed in-memory list. It is the offline
+// fallback of last resort.
+type Static struct {
+	Quotes map[string][]Quote
+}
+
+var defaultStaticQuotes = map[string][]Quote{
+	"en": {
+		{Text: "The only way to do great work is to love what you do.", Author: "Steve Jobs"},
+		{Text: "Simplicity is prerequisite for reliability.", Author: "Edsger W. Dijkstra"},
+		{Text: "Well begun is half done.", Author: "Aristotle"},
+	},
+	"ru": {
+		{Text: "Терпение и труд всё перетрут.", Author: "Пословица"},
+		{Text: "Тише едешь — дальше будешь.", Author: "Пословица"},
+	},
+}
+
+func init() {
+	Register("static", func(opts Options) (Generator, error) {
+		return &Static{Quotes: defaultStaticQuotes}, nil
+	})
+}
+
+// Generate ...
+func (s *Static) Generate(ctx context.Context, lang string) (*Quote, error) {
+	quotes := s.Quotes[lang]
+	if len(quotes) == 0 {
+		return nil, ErrNoQuotes
+	}
+
+	q := quotes[rand.Intn(len(quotes))]
+	q.Lang = lang
+
+	return &q, nil
+}
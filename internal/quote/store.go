@@ -0,0 +1,214 @@
+// internal/quote/store.go
+
+package quote
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/internal/replica"
+	"github.com/jeniok12/golang-tdd-example/tenant"
+	"time"
+)
+
+// ErrNotFound ...
+var ErrNotFound = errors.New("quote not found")
+
+// Record is a quote that has been served, as stored in the history.
+type Record struct {
+	ID int `json:"id"`
+	Quote
+	CreatedAt time.Time `json:"createdAt"`
+	// TimesServed counts the times the quote was served, as the history
+	// stores each quote once. It is zero outside the history.
+	TimesServed int `json:"timesServed,omitempty"`
+	// LastServedAt is when the quote was last served, nil outside the history.
+	LastServedAt *time.Time `json:"lastServedAt,omitempty"`
+}
+
+// ServedAt returns when r was last served.
+func (r Record) ServedAt() time.Time {
+	if r.LastServedAt != nil {
+		return *r.LastServedAt
+	}
+	return r.CreatedAt
+}
+
+// recordColumns lists the columns scanned into a Record.
+const recordColumns = "id, text, author, lang, created_at, times_served, last_served_at"
+
+// scanRecord scans the recordColumns of row into r, then the extra columns
+// into extra.
+func scanRecord(row interface{ Scan(...interface{}) error }, r *Record, extra ...interface{}) error {
+	dest := []interface{}{&r.ID, &r.Text, &r.Author, &r.Lang, &r.CreatedAt, &r.TimesServed, &r.LastServedAt}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// ListFilter ...
+type ListFilter struct {
+	Lang   string
+	Limit  int
+	Offset int
+}
+
+// Store ...
+type Store interface {
+	SaveQuote(ctx context.Context, q Quote) (*Record, error)
+	ListQuotes(ctx context.Context, filter ListFilter) ([]Record, error)
+	GetQuote(ctx context.Context, id int) (*Record, error)
+}
+
+// Persistence is the Postgres Store. Each tenant has a history of its own,
+// the tenant of the context.
+type Persistence struct {
+	DB *sql.DB
+	// Replicas, when set, serve ListQuotes and SearchQuotes, which tolerate
+	// replication lag.
+	Replicas *replica.Set
+}
+
+// read runs fn on a replica, if any, or on DB.
+func (p *Persistence) read(ctx context.Context, fn func(db *sql.DB) error) error {
+	if p.Replicas == nil {
+		return fn(p.DB)
+	}
+	return p.Replicas.Read(ctx, fn)
+}
+
+// SaveQuote stores q in the history, once: a quote served again, by Hash,
+// is counted rather than stored again, and keeps its ID and first text.
+func (p *Persistence) SaveQuote(ctx context.Context, q Quote) (*Record, error) {
+	var r Record
+
+	query := `INSERT INTO quotes (tenant_id, text, author, lang, hash, last_served_at) VALUES ($1, $2, $3, $4, $5, now())
+		ON CONFLICT (tenant_id, hash) DO UPDATE SET times_served = quotes.times_served + 1, last_served_at = now()
+		RETURNING ` + recordColumns
+	row := p.DB.QueryRowContext(ctx, query, tenant.FromContext(ctx), q.Text, q.Author, q.Lang, Hash(q))
+	if err := scanRecord(row, &r); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// ListQuotes returns the quotes served last first. An empty Lang matches all
+// languages.
+func (p *Persistence) ListQuotes(ctx context.Context, filter ListFilter) ([]Record, error) {
+	query := `SELECT ` + recordColumns + ` FROM quotes
+		WHERE tenant_id = $1 AND ($2 = '' OR lang = $2)
+		ORDER BY last_served_at DESC, id DESC
+		LIMIT $3 OFFSET $4`
+
+	var records []Record
+	err := p.read(ctx, func(db *sql.DB) error {
+		rows, err := db.QueryContext(ctx, query, tenant.FromContext(ctx), filter.Lang, filter.Limit, filter.Offset)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		records = []Record{}
+		for rows.Next() {
+			var r Record
+			if err := scanRecord(rows, &r); err != nil {
+				return err
+			}
+			records = append(records, r)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// eachPageSize is the number of rows fetched per query when iterating.
+const eachPageSize = 500
+
+// EachQuote calls fn for every stored quote, oldest first, stopping at the
+// first error. Quotes are fetched in pages keyed by id, so no query holds the
+// whole history. An empty lang matches all languages.
+func (p *Persistence) EachQuote(ctx context.Context, lang string, fn func(Record) error) error {
+	query := `SELECT ` + recordColumns + ` FROM quotes
+		WHERE tenant_id = $1 AND ($2 = '' OR lang = $2) AND id > $3
+		ORDER BY id
+		LIMIT $4`
+
+	lastID := 0
+	for {
+		page, err := p.quotePage(ctx, query, lang, lastID)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range page {
+			if err := fn(r); err != nil {
+				return err
+			}
+		}
+
+		if len(page) < eachPageSize {
+			return nil
+		}
+		lastID = page[len(page)-1].ID
+	}
+}
+
+func (p *Persistence) quotePage(ctx context.Context, query, lang string, afterID int) ([]Record, error) {
+	rows, err := p.DB.QueryContext(ctx, query, tenant.FromContext(ctx), lang, afterID, eachPageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var page []Record
+	for rows.Next() {
+		var r Record
+		if err := scanRecord(rows, &r); err != nil {
+			return nil, err
+		}
+		page = append(page, r)
+	}
+
+	return page, rows.Err()
+}
+
+// GetQuote ...
+func (p *Persistence) GetQuote(ctx context.Context, id int) (*Record, error) {
+	var r Record
+
+	query := "SELECT " + recordColumns + " FROM quotes WHERE tenant_id = $1 AND id = $2"
+	err := scanRecord(p.DB.QueryRowContext(ctx, query, tenant.FromContext(ctx), id), &r)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// Recording saves every quote the inner generator returns. Failing to save
+// does not fail the request; OnError is told instead.
+type Recording struct {
+	Inner   Generator
+	Store   Store
+	OnError func(err error)
+}
+
+// Generate ...
+func (r *Recording) Generate(ctx context.Context, lang string) (*Quote, error) {
+	q, err := r.Inner.Generate(ctx, lang)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := r.Store.SaveQuote(ctx, *q); err != nil && r.OnError != nil {
+		r.OnError(err)
+	}
+
+	return q, nil
+}
@@ -0,0 +1,178 @@
+// internal/quote/store_test.go
+
+package quote
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/internal/pgtest"
+	"github.com/jeniok12/golang-tdd-example/internal/replica"
+	"github.com/jeniok12/golang-tdd-example/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Main(m))
+}
+
+// newTestPersistence returns a Persistence on a migrated schema of its own.
+func newTestPersistence(t *testing.T) *Persistence {
+	return &Persistence{DB: pgtest.Open(t)}
+}
+
+func TestPersistence_SaveListGet(t *testing.T) {
+	p := newTestPersistence(t)
+	ctx := context.Background()
+
+	first, err := p.SaveQuote(ctx, Quote{Text: "Bla", Author: "Bob", Lang: "en"})
+	require.NoError(t, err, "Should have no error when saving a quote")
+	_, err = p.SaveQuote(ctx, Quote{Text: "Бла", Author: "Боб", Lang: "ru"})
+	require.NoError(t, err, "Should have no error when saving a quote")
+	last, err := p.SaveQuote(ctx, Quote{Text: "Bla Bla", Author: "Alice", Lang: "en"})
+	require.NoError(t, err, "Should have no error when saving a quote")
+
+	records, err := p.ListQuotes(ctx, ListFilter{Lang: "en", Limit: 10})
+	assert.NoError(t, err, "Should have no error when listing quotes")
+	assert.Equal(t, []int{last.ID, first.ID}, recordIDs(records), "Should list newest quotes of the language first")
+
+	records, err = p.ListQuotes(ctx, ListFilter{Limit: 1, Offset: 1})
+	assert.NoError(t, err, "Should have no error when listing quotes")
+	assert.Len(t, records, 1, "Should respect limit")
+
+	found, err := p.GetQuote(ctx, first.ID)
+	assert.NoError(t, err, "Should have no error when fetching a quote")
+	assert.Equal(t, first.Quote, found.Quote, "Fetched quote should be as saved")
+
+	_, err = p.GetQuote(ctx, last.ID+1)
+	assert.Equal(t, ErrNotFound, err, "Should get ErrNotFound for unknown ID")
+}
+
+func TestPersistence_SaveQuote_Dedup(t *testing.T) {
+	p := newTestPersistence(t)
+	ctx := context.Background()
+
+	first, err := p.SaveQuote(ctx, Quote{Text: "Bla", Author: "Bob", Lang: "en"})
+	require.NoError(t, err, "Should have no error when saving a quote")
+	other, err := p.SaveQuote(ctx, Quote{Text: "Foo", Author: "Alice", Lang: "en"})
+	require.NoError(t, err, "Should have no error when saving a quote")
+	again, err := p.SaveQuote(ctx, Quote{Text: " BLA ", Author: "bob", Lang: "en"})
+	require.NoError(t, err, "Should have no error when saving a quote")
+
+	assert.Equal(t, first.ID, again.ID, "Should store the same quote once")
+	assert.Equal(t, 2, again.TimesServed, "Should count serving a quote again")
+	assert.Equal(t, "Bla", again.Text, "Should keep the first text")
+	assert.False(t, again.LastServedAt.Before(*first.LastServedAt), "Should update the last serve time")
+
+	records, err := p.ListQuotes(ctx, ListFilter{Limit: 10})
+	assert.NoError(t, err, "Should have no error when listing quotes")
+	assert.Equal(t, []int{first.ID, other.ID}, recordIDs(records), "Should list quotes served last first")
+
+	acme, err := p.SaveQuote(tenant.NewContext(ctx, "acme"), Quote{Text: "Bla", Author: "Bob", Lang: "en"})
+	require.NoError(t, err, "Should have no error when saving a quote")
+	assert.NotEqual(t, first.ID, acme.ID, "Should keep a history per tenant")
+}
+
+func TestPersistence_Replicas(t *testing.T) {
+	p := newTestPersistence(t)
+	ctx := context.Background()
+	saved, err := p.SaveQuote(ctx, Quote{Text: "Bla", Author: "Bob", Lang: "en"})
+	require.NoError(t, err, "Should have no error when saving a quote")
+
+	down, err := sql.Open("postgres", "")
+	require.NoError(t, err, "Got error when not expected")
+	down.Close()
+	p.Replicas = replica.New(p.DB)
+	p.Replicas.Add("down", down)
+
+	records, err := p.ListQuotes(ctx, ListFilter{Limit: 10})
+	assert.NoError(t, err, "Should fall back to the primary when the replica fails")
+	assert.Equal(t, []int{saved.ID}, recordIDs(records))
+	assert.Empty(t, p.Replicas.Healthy(), "The failed replica should be taken out")
+
+	results, err := p.SearchQuotes(ctx, SearchFilter{Query: "bla", Limit: 10})
+	assert.NoError(t, err, "Should have no error when searching quotes")
+	assert.Len(t, results, 1)
+}
+
+func TestPersistence_EachQuote(t *testing.T) {
+	p := newTestPersistence(t)
+	ctx := context.Background()
+
+	for i := 0; i < eachPageSize+1; i++ {
+		_, err := p.SaveQuote(ctx, Quote{Text: fmt.Sprintf("Bla %d", i), Author: "Bob", Lang: "en"})
+		require.NoError(t, err, "Should have no error when saving a quote")
+	}
+	_, err := p.SaveQuote(ctx, Quote{Text: "Бла", Author: "Боб", Lang: "ru"})
+	require.NoError(t, err, "Should have no error when saving a quote")
+
+	var ids []int
+	err = p.EachQuote(ctx, "en", func(r Record) error {
+		ids = append(ids, r.ID)
+		return nil
+	})
+	assert.NoError(t, err, "Should have no error when iterating quotes")
+	assert.Len(t, ids, eachPageSize+1, "Should visit every quote of the language across pages")
+	assert.IsIncreasing(t, ids, "Should visit quotes oldest first")
+
+	stop := errors.New("stop")
+	calls := 0
+	err = p.EachQuote(ctx, "", func(r Record) error {
+		calls++
+		return stop
+	})
+	assert.Equal(t, stop, err, "Should return the callback error")
+	assert.Equal(t, 1, calls, "Should stop at the first callback error")
+}
+
+func recordIDs(records []Record) []int {
+	ids := make([]int, 0, len(records))
+	for _, r := range records {
+		ids = append(ids, r.ID)
+	}
+	return ids
+}
+
+type memoryStore struct {
+	saved []Quote
+	err   error
+}
+
+func (m *memoryStore) SaveQuote(ctx context.Context, q Quote) (*Record, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	m.saved = append(m.saved, q)
+	return &Record{ID: len(m.saved), Quote: q}, nil
+}
+
+func (m *memoryStore) ListQuotes(ctx context.Context, filter ListFilter) ([]Record, error) {
+	return nil, nil
+}
+
+func (m *memoryStore) GetQuote(ctx context.Context, id int) (*Record, error) {
+	return nil, ErrNotFound
+}
+
+func TestRecording_Generate(t *testing.T) {
+	store := &memoryStore{}
+	recording := Recording{Inner: &countingGenerator{}, Store: store}
+
+	q, err := recording.Generate(context.Background(), "en")
+
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, []Quote{*q}, store.saved, "Generated quote should be saved")
+
+	var reported error
+	store.err = errors.New("sample error")
+	recording.OnError = func(err error) { reported = err }
+
+	_, err = recording.Generate(context.Background(), "en")
+
+	assert.NoError(t, err, "Store failure should not fail generation")
+	assert.EqualError(t, reported, "sample error", "Store failure should be reported")
+}
@@ -0,0 +1,87 @@
+// internal/quote/throttle.go
+
+package quote
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned by providers that were told to slow down.
+var ErrRateLimited = errors.New("quote: provider rate limit exceeded")
+
+// RateLimitError is returned while a provider's rate limit is exceeded.
+// errors.Is matches it against ErrRateLimited.
+type RateLimitError struct {
+	Provider string
+	// RetryAfter is how long until the provider takes requests again.
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("quote: %s rate limit exceeded, retry in %s", e.Provider, e.RetryAfter.Round(time.Second))
+}
+
+// Is ...
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// throttle remembers until when a provider asked to be left alone, so calls
+// fail fast instead of spending quota on requests bound to be refused.
+type throttle struct {
+	mu    sync.Mutex
+	until time.Time
+	// now is time.Now unless a test replaces it.
+	now func() time.Time
+}
+
+func (t *throttle) clock() time.Time {
+	if t.now == nil {
+		return time.Now()
+	}
+	return t.now()
+}
+
+// check returns a RateLimitError while the provider is rate limited.
+func (t *throttle) check(provider string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if wait := t.until.Sub(t.clock()); wait > 0 {
+		return &RateLimitError{Provider: provider, RetryAfter: wait}
+	}
+	return nil
+}
+
+// limit holds off calls for d and returns the matching RateLimitError.
+func (t *throttle) limit(provider string, d time.Duration) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if until := t.clock().Add(d); until.After(t.until) {
+		t.until = until
+	}
+	return &RateLimitError{Provider: provider, RetryAfter: d}
+}
+
+// retryAfter reads how long to wait from the Retry-After header, in seconds
+// or as an HTTP date, or from the RateLimit-Reset header, falling back to def.
+func retryAfter(h http.Header, now time.Time, def time.Duration) time.Duration {
+	if v := h.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+			return time.Duration(secs) * time.Second
+		}
+		if at, err := http.ParseTime(v); err == nil && at.After(now) {
+			return at.Sub(now)
+		}
+	}
+	if secs, err := strconv.Atoi(h.Get("RateLimit-Reset")); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return def
+}
@@ -0,0 +1,88 @@
+// internal/quote/zenquotes_test.go
+
+package quote
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestZenQuotes_Generate(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		status             int
+		header             http.Header
+		body               string
+		expectedQuote      *Quote
+		expectedRetryAfter time.Duration
+		expectedToGetError bool
+	}{
+		{
+			"Success",
+			http.StatusOK,
+			nil,
+			`[{"q":"Bla Bla Bla","a":"Bob","h":"<blockquote>Bla Bla Bla</blockquote>"}]`,
+			&Quote{Text: "Bla Bla Bla", Author: "Bob", Lang: "en"},
+			0,
+			false,
+		},
+		{
+			"RateLimitQuote",
+			http.StatusOK,
+			nil,
+			`[{"q":"Too many requests. Obtain an auth key for unlimited access.","a":"zenquotes.io"}]`,
+			nil,
+			zenQuotesWindow,
+			true,
+		},
+		{"TooManyRequests", http.StatusTooManyRequests, http.Header{"Retry-After": {"10"}}, ``, nil, 10 * time.Second, true},
+		{"Empty", http.StatusOK, nil, `[]`, nil, 0, true},
+		{"InvalidJSON", http.StatusOK, nil, `{`, nil, 0, true},
+		{"ServerError", http.StatusInternalServerError, nil, ``, nil, 0, true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				calls++
+				for k, v := range tC.header {
+					rw.Header()[k] = v
+				}
+				rw.WriteHeader(tC.status)
+				rw.Write([]byte(tC.body))
+			}))
+			defer server.Close()
+
+			zen := ZenQuotes{URL: server.URL, Client: server.Client()}
+			q, err := zen.Generate(context.Background(), "en")
+
+			if !tC.expectedToGetError {
+				assert.NoError(t, err, "Got error when not expected")
+				assert.Equal(t, tC.expectedQuote, q)
+				return
+			}
+			assert.Error(t, err, "Got no error when expected")
+			if tC.expectedRetryAfter == 0 {
+				assert.False(t, errors.Is(err, ErrRateLimited))
+				return
+			}
+
+			var rateErr *RateLimitError
+			if assert.ErrorAs(t, err, &rateErr) {
+				assert.Equal(t, tC.expectedRetryAfter, rateErr.RetryAfter)
+			}
+			_, err = zen.Generate(context.Background(), "en")
+			assert.ErrorIs(t, err, ErrRateLimited)
+			assert.Equal(t, 1, calls, "Calls should fail fast while rate limited")
+		})
+	}
+}
+
+func TestZenQuotes_SupportedLanguages(t *testing.T) {
+	assert.Equal(t, []string{"en"}, SupportedLanguages(&ZenQuotes{}))
+}
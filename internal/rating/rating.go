@@ -0,0 +1,112 @@
+// internal/rating/rating.go
+
+// Package rating keeps the scores clients give quotes and ranks the quotes
+// by them.
+package rating
+
+import (
+	"context"
+	"database/sql"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/tenant"
+	"sort"
+	"time"
+)
+
+// Scores range from MinScore, the worst, to MaxScore, the best.
+const (
+	MinScore = 1
+	MaxScore = 5
+)
+
+// Rating is the score a client gave a quote. A client has one rating per
+// quote; rating it again replaces the score.
+type Rating struct {
+	QuoteID int `json:"quoteId"`
+	// Rater identifies the client by a fingerprint of their API key.
+	Rater   string    `json:"-"`
+	Score   int       `json:"score"`
+	RatedAt time.Time `json:"ratedAt"`
+}
+
+// Trend is a quote with the ratings it got over a window.
+type Trend struct {
+	quote.Record
+	Average float64 `json:"average"`
+	Votes   int     `json:"votes"`
+}
+
+// Store ...
+type Store interface {
+	// Rate saves r, replacing the earlier rating of the same rater. It fails
+	// with quote.ErrNotFound for quotes not in the history.
+	Rate(ctx context.Context, r Rating) error
+	// Trending returns up to limit quotes rated since then, best first.
+	Trending(ctx context.Context, since time.Time, limit int) ([]Trend, error)
+}
+
+// SortTrends orders trends by average score, then by votes and newest first
+// among equals, the order Trending returns them in.
+func SortTrends(trends []Trend) {
+	sort.SliceStable(trends, func(i, j int) bool {
+		a, b := trends[i], trends[j]
+		if a.Average != b.Average {
+			return a.Average > b.Average
+		}
+		if a.Votes != b.Votes {
+			return a.Votes > b.Votes
+		}
+		return a.ID > b.ID
+	})
+}
+
+// Persistence keeps the ratings in the ratings table. Like the audit log, its
+// queries run on both Postgres and SQLite, with times stored in UTC.
+type Persistence struct {
+	DB *sql.DB
+}
+
+// Rate ...
+func (p *Persistence) Rate(ctx context.Context, r Rating) error {
+	var exists bool
+	query := "SELECT EXISTS (SELECT 1 FROM quotes WHERE id = $1 AND tenant_id = $2)"
+	err := p.DB.QueryRowContext(ctx, query, r.QuoteID, tenant.FromContext(ctx)).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return quote.ErrNotFound
+	}
+
+	query = `INSERT INTO ratings (quote_id, rater, score, rated_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (quote_id, rater) DO UPDATE SET score = excluded.score, rated_at = excluded.rated_at`
+	_, err = p.DB.ExecContext(ctx, query, r.QuoteID, r.Rater, r.Score, r.RatedAt.UTC())
+	return err
+}
+
+// Trending ...
+func (p *Persistence) Trending(ctx context.Context, since time.Time, limit int) ([]Trend, error) {
+	query := `SELECT q.id, q.text, q.author, q.lang, q.created_at, avg(r.score), count(*)
+		FROM ratings r JOIN quotes q ON q.id = r.quote_id
+		WHERE r.rated_at >= $1 AND q.tenant_id = $2
+		GROUP BY q.id, q.text, q.author, q.lang, q.created_at
+		ORDER BY 6 DESC, 7 DESC, q.id DESC
+		LIMIT $3`
+
+	rows, err := p.DB.QueryContext(ctx, query, since.UTC(), tenant.FromContext(ctx), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	trends := []Trend{}
+	for rows.Next() {
+		var t Trend
+		if err := rows.Scan(&t.ID, &t.Text, &t.Author, &t.Lang, &t.CreatedAt, &t.Average, &t.Votes); err != nil {
+			return nil, err
+		}
+		trends = append(trends, t)
+	}
+
+	return trends, rows.Err()
+}
@@ -0,0 +1,101 @@
+// internal/rating/rating_test.go
+
+package rating
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/internal/dbtest"
+	"github.com/jeniok12/golang-tdd-example/internal/pgtest"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Main(m))
+}
+
+func TestPersistence(t *testing.T) {
+	for _, d := range dbtest.Dialects {
+		t.Run(d.Name, func(t *testing.T) {
+			testPersistence(t, &Persistence{DB: d.Open(t)})
+		})
+	}
+}
+
+func testPersistence(t *testing.T, p *Persistence) {
+	ctx := context.Background()
+	start := time.Date(2019, 8, 25, 10, 0, 0, 0, time.UTC)
+
+	query := `INSERT INTO quotes (tenant_id, text, author, lang, hash, created_at, last_served_at) VALUES ($1, $2, $3, $4, $5, $6, $6)`
+	for _, text := range []string{"Bla", "Foo", "Bar"} {
+		q := quote.Quote{Text: text, Author: "Me", Lang: "en"}
+		_, err := p.DB.ExecContext(ctx, query, tenant.FromContext(ctx), q.Text, q.Author, q.Lang, quote.Hash(q), start)
+		require.NoError(t, err, "Got error when not expected")
+	}
+
+	for _, r := range []Rating{
+		{QuoteID: 1, Rater: "key:a", Score: 3, RatedAt: start},
+		{QuoteID: 1, Rater: "key:b", Score: 4, RatedAt: start.Add(time.Hour)},
+		{QuoteID: 2, Rater: "key:a", Score: 1, RatedAt: start.Add(time.Hour)},
+		{QuoteID: 2, Rater: "key:a", Score: 5, RatedAt: start.Add(2 * time.Hour)},
+		{QuoteID: 3, Rater: "key:a", Score: 2, RatedAt: start.Add(2 * time.Hour)},
+	} {
+		require.NoError(t, p.Rate(ctx, r), "Got error when not expected")
+	}
+	assert.ErrorIs(t, p.Rate(ctx, Rating{QuoteID: 42, Rater: "key:a", Score: 5, RatedAt: start}), quote.ErrNotFound)
+
+	testCases := []struct {
+		desc          string
+		since         time.Time
+		limit         int
+		expectedIDs   []int
+		expectedFirst Trend
+	}{
+		{"All", start, 10, []int{2, 1, 3}, Trend{Average: 5, Votes: 1}},
+		{"Window", start.Add(time.Hour), 10, []int{2, 1, 3}, Trend{Average: 5, Votes: 1}},
+		{"WindowDropsVotes", start.Add(90 * time.Minute), 10, []int{2, 3}, Trend{Average: 5, Votes: 1}},
+		{"SinceInAnotherZone", start.Add(90 * time.Minute).In(time.FixedZone("CEST", 2*60*60)), 10, []int{2, 3}, Trend{Average: 5, Votes: 1}},
+		{"Limit", start, 1, []int{2}, Trend{Average: 5, Votes: 1}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			trends, err := p.Trending(ctx, tC.since, tC.limit)
+			require.NoError(t, err, "Got error when not expected")
+
+			var ids []int
+			for _, tr := range trends {
+				ids = append(ids, tr.ID)
+			}
+			assert.Equal(t, tC.expectedIDs, ids, "Trending quotes are different than expected")
+			assert.Equal(t, "Foo", trends[0].Text)
+			assert.Equal(t, tC.expectedFirst.Average, trends[0].Average)
+			assert.Equal(t, tC.expectedFirst.Votes, trends[0].Votes)
+		})
+	}
+
+	trends, _ := p.Trending(ctx, start, 10)
+	assert.Equal(t, 3.5, trends[1].Average, "Scores should be averaged over raters")
+	assert.Equal(t, 2, trends[1].Votes)
+}
+
+func TestSortTrends(t *testing.T) {
+	trends := []Trend{
+		{Record: quote.Record{ID: 1}, Average: 4, Votes: 2},
+		{Record: quote.Record{ID: 2}, Average: 4, Votes: 3},
+		{Record: quote.Record{ID: 3}, Average: 5, Votes: 1},
+		{Record: quote.Record{ID: 4}, Average: 4, Votes: 2},
+	}
+
+	SortTrends(trends)
+
+	var ids []int
+	for _, tr := range trends {
+		ids = append(ids, tr.ID)
+	}
+	assert.Equal(t, []int{3, 2, 4, 1}, ids, "Trends should be ordered by average, votes, then newest first")
+}
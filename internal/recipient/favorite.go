@@ -0,0 +1,89 @@
+// internal/recipient/favorite.go
+
+package recipient
+
+import (
+	"context"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/tenant"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"time"
+)
+
+// ErrQuoteNotFound is returned when favoriting a quote missing from the
+// quote history.
+var ErrQuoteNotFound = errors.New("quote not found")
+
+// Favorite is a quote from the history that a recipient saved.
+type Favorite struct {
+	QuoteID int    `json:"quoteId" db:"quote_id"`
+	Text    string `json:"quoteText" db:"text"`
+	Author  string `json:"quoteAuthor" db:"author"`
+	Lang    string `json:"lang" db:"lang"`
+	// CreatedAt is when the quote was favorited.
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
+
+// AddFavorite saves the quote with quoteID as a favorite of the recipient.
+// Saving a favorite twice keeps the original one.
+func (p *Persistence) AddFavorite(ctx context.Context, recipientID, quoteID int) (*Favorite, error) {
+	// The recipient and the quote of other tenants are as good as missing.
+	if _, err := p.GetByID(ctx, recipientID); err != nil {
+		return nil, err
+	}
+	var exists bool
+	query := "SELECT EXISTS (SELECT 1 FROM quotes WHERE id = $1 AND tenant_id = $2)"
+	if err := p.exec().QueryRowContext(ctx, query, quoteID, tenant.FromContext(ctx)).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrQuoteNotFound
+	}
+
+	var f Favorite
+
+	// The no-op update makes RETURNING yield the existing row on conflict.
+	query = `WITH saved AS (
+			INSERT INTO favorites (tenant_id, recipient_id, quote_id) VALUES ($3, $1, $2)
+			ON CONFLICT (recipient_id, quote_id) DO UPDATE SET created_at = favorites.created_at
+			RETURNING quote_id, created_at
+		)
+		SELECT q.id AS quote_id, q.text, q.author, q.lang, saved.created_at FROM saved JOIN quotes q ON q.id = saved.quote_id`
+	err := sqlx.GetContext(ctx, p.queryer(), &f, query, recipientID, quoteID, tenant.FromContext(ctx))
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && string(pqErr.Code) == foreignKeyViolation {
+		if pqErr.Constraint == "favorites_quote_id_fkey" {
+			return nil, ErrQuoteNotFound
+		}
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
+
+// ListFavorites returns the recipient's favorites, newest first.
+func (p *Persistence) ListFavorites(ctx context.Context, recipientID int) ([]Favorite, error) {
+	var exists bool
+	query := "SELECT EXISTS (SELECT 1 FROM recipients WHERE id = $1 AND tenant_id = $2)"
+	err := p.exec().QueryRowContext(ctx, query, recipientID, tenant.FromContext(ctx)).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	query = `SELECT q.id AS quote_id, q.text, q.author, q.lang, f.created_at FROM favorites f
+		JOIN quotes q ON q.id = f.quote_id
+		WHERE f.recipient_id = $1 AND f.tenant_id = $2 ORDER BY f.created_at DESC, q.id DESC`
+	favorites := []Favorite{}
+	if err := sqlx.SelectContext(ctx, p.queryer(), &favorites, query, recipientID, tenant.FromContext(ctx)); err != nil {
+		return nil, err
+	}
+
+	return favorites, nil
+}
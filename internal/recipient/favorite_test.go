@@ -0,0 +1,51 @@
+// internal/recipient/favorite_test.go
+
+package recipient
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestFavorites(t *testing.T) {
+	ctx := context.Background()
+
+	p := newTestPersistence(t)
+
+	user, err := p.Create(ctx, Recipient{Name: "user1", Email: "user1@testmail.com"})
+	require.NoError(t, err, "Should have no error when creating a recipient")
+
+	var quoteID int
+	err = p.DB.QueryRow("INSERT INTO quotes (text, author, lang) VALUES ('Bla', 'Bob', 'en') RETURNING id").Scan(&quoteID)
+	require.NoError(t, err, "Should have no error when saving a quote")
+
+	favorites, err := p.ListFavorites(ctx, user.ID)
+	assert.NoError(t, err, "Should have no error when listing favorites")
+	assert.Empty(t, favorites, "New recipient should have no favorites")
+
+	fav, err := p.AddFavorite(ctx, user.ID, quoteID)
+	require.NoError(t, err, "Should have no error when adding a favorite")
+	assert.Equal(t, Favorite{QuoteID: quoteID, Text: "Bla", Author: "Bob", Lang: "en", CreatedAt: fav.CreatedAt}, *fav, "Favorite is different than expected")
+
+	again, err := p.AddFavorite(ctx, user.ID, quoteID)
+	assert.NoError(t, err, "Adding a favorite twice should be a no-op")
+	assert.Equal(t, fav, again, "Adding a favorite twice should keep the original")
+
+	_, err = p.AddFavorite(ctx, user.ID, quoteID+100)
+	assert.Equal(t, ErrQuoteNotFound, err, "Unknown quote should not be favorited")
+	_, err = p.AddFavorite(ctx, user.ID+100, quoteID)
+	assert.Equal(t, ErrNotFound, err, "Unknown recipient should not favorite")
+	_, err = p.ListFavorites(ctx, user.ID+100)
+	assert.Equal(t, ErrNotFound, err, "Unknown recipient should have no favorites")
+
+	favorites, err = p.ListFavorites(ctx, user.ID)
+	assert.NoError(t, err, "Should have no error when listing favorites")
+	assert.Equal(t, []Favorite{*fav}, favorites, "Should list the favorite")
+
+	require.NoError(t, p.Delete(ctx, user.ID), "Should have no error when deleting the recipient")
+	var left int
+	require.NoError(t, p.DB.QueryRow("SELECT count(*) FROM favorites").Scan(&left))
+	assert.Zero(t, left, "Deleting a recipient should delete their favorites")
+}
@@ -0,0 +1,114 @@
+// internal/recipient/group.go
+
+package recipient
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/tenant"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// ErrGroupNotFound ...
+var ErrGroupNotFound = errors.New("group not found")
+
+// ErrDuplicateGroup ...
+var ErrDuplicateGroup = errors.New("group already exists")
+
+// Group ...
+type Group struct {
+	ID   int    `json:"id" db:"id"`
+	Name string `json:"name" db:"name"`
+}
+
+// Postgres error codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	foreignKeyViolation = "23503"
+	uniqueViolation     = "23505"
+)
+
+func isPQError(err error, code string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && string(pqErr.Code) == code
+}
+
+// CreateGroup ...
+func (p *Persistence) CreateGroup(ctx context.Context, name string) (*Group, error) {
+	g := Group{Name: name}
+
+	err := p.exec().QueryRowContext(ctx, "INSERT INTO groups (name) VALUES ($1) RETURNING id", name).Scan(&g.ID)
+	if isPQError(err, uniqueViolation) {
+		return nil, ErrDuplicateGroup
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &g, nil
+}
+
+// ListGroups ...
+func (p *Persistence) ListGroups(ctx context.Context) ([]Group, error) {
+	groups := []Group{}
+	if err := sqlx.SelectContext(ctx, p.queryer(), &groups, "SELECT id, name FROM groups ORDER BY name"); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// AddMember ...
+func (p *Persistence) AddMember(ctx context.Context, groupID, recipientID int) error {
+	// Groups are shared, their members are not.
+	if _, err := p.GetByID(ctx, recipientID); err != nil {
+		return err
+	}
+
+	query := "INSERT INTO group_members (group_id, recipient_id) VALUES ($1, $2) ON CONFLICT DO NOTHING"
+	_, err := p.exec().ExecContext(ctx, query, groupID, recipientID)
+	if isPQError(err, foreignKeyViolation) {
+		return ErrNotFound
+	}
+
+	return err
+}
+
+// RemoveMember ...
+func (p *Persistence) RemoveMember(ctx context.Context, groupID, recipientID int) error {
+	query := `DELETE FROM group_members WHERE group_id = $1 AND recipient_id = $2
+		AND recipient_id IN (SELECT id FROM recipients WHERE tenant_id = $3)`
+	res, err := p.exec().ExecContext(ctx, query, groupID, recipientID, tenant.FromContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res)
+}
+
+// GroupRecipients returns the members of the group with the given name.
+func (p *Persistence) GroupRecipients(ctx context.Context, group string) ([]Recipient, error) {
+	var groupID int
+	err := p.exec().QueryRowContext(ctx, "SELECT id FROM groups WHERE name = $1", group).Scan(&groupID)
+	if err == sql.ErrNoRows {
+		return nil, ErrGroupNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stmt, err := p.prepared(ctx, "SELECT "+qualifiedColumns("r", recipientColumns)+` FROM recipients r
+		JOIN group_members m ON m.recipient_id = r.id
+		WHERE m.group_id = $1 AND r.tenant_id = $2 AND r.verified ORDER BY r.id`)
+	if err != nil {
+		return nil, err
+	}
+
+	var recipients []Recipient
+	if err := stmt.SelectContext(ctx, &recipients, groupID, tenant.FromContext(ctx)); err != nil {
+		return nil, err
+	}
+
+	return recipients, nil
+}
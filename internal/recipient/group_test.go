@@ -0,0 +1,49 @@
+// internal/recipient/group_test.go
+
+package recipient
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestGroups(t *testing.T) {
+	ctx := context.Background()
+
+	p := newTestPersistence(t)
+
+	user1, err := p.Create(ctx, Recipient{Name: "user1", Email: "user1@testmail.com"})
+	require.NoError(t, err, "Should have no error when creating a recipient")
+	user1, err = p.MarkVerified(ctx, user1.ID, user1.Email)
+	require.NoError(t, err, "Should have no error when verifying a recipient")
+	user2, err := p.Create(ctx, Recipient{Name: "user2", Email: "user2@testmail.com"})
+	require.NoError(t, err, "Should have no error when creating a recipient")
+
+	group, err := p.CreateGroup(ctx, "marketing")
+	require.NoError(t, err, "Should have no error when creating a group")
+
+	_, err = p.CreateGroup(ctx, "marketing")
+	assert.Equal(t, ErrDuplicateGroup, err, "Group names should be unique")
+
+	assert.NoError(t, p.AddMember(ctx, group.ID, user1.ID), "Should have no error when adding a member")
+	assert.NoError(t, p.AddMember(ctx, group.ID, user1.ID), "Adding a member twice should be a no-op")
+	assert.Equal(t, ErrNotFound, p.AddMember(ctx, group.ID, user1.ID+100), "Unknown recipient should not be added")
+
+	assert.NoError(t, p.AddMember(ctx, group.ID, user2.ID), "Should have no error when adding a member")
+
+	members, err := p.GroupRecipients(ctx, "marketing")
+	assert.NoError(t, err, "Should have no error when fetching group recipients")
+	assert.Equal(t, []Recipient{*user1}, members, "Only verified group members should be returned")
+
+	_, err = p.GroupRecipients(ctx, "sales")
+	assert.Equal(t, ErrGroupNotFound, err, "Unknown group should not be found")
+
+	groups, err := p.ListGroups(ctx)
+	assert.NoError(t, err, "Should have no error when listing groups")
+	assert.Equal(t, []Group{*group}, groups, "Should list created groups")
+
+	assert.NoError(t, p.RemoveMember(ctx, group.ID, user1.ID), "Should have no error when removing a member")
+	assert.Equal(t, ErrNotFound, p.RemoveMember(ctx, group.ID, user1.ID), "Should get ErrNotFound on second remove")
+}
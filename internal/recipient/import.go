@@ -0,0 +1,102 @@
+// internal/recipient/import.go
+
+package recipient
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/tenant"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"strings"
+)
+
+// Import inserts rs as unverified recipients in a single transaction. Emails
+// that are already taken, including earlier in rs, are skipped. The result is
+// indexed like rs, holding nil for skipped recipients.
+//
+// The rows are streamed with COPY into a temporary table and inserted from
+// there with one statement, which loads thousands of rows many times faster
+// than inserting them one by one.
+func (p *Persistence) Import(ctx context.Context, rs []Recipient) ([]*Recipient, error) {
+	tx := p.tx
+	if tx == nil {
+		var err error
+		if tx, err = p.dbx.BeginTxx(ctx, nil); err != nil {
+			return nil, err
+		}
+		defer tx.Rollback()
+	}
+
+	created := make([]*Recipient, len(rs))
+	if err := importRows(ctx, tx, rs, created); err != nil {
+		return nil, err
+	}
+
+	// Inside a transaction of the caller, the caller commits.
+	if p.tx == nil {
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+	}
+
+	return created, nil
+}
+
+func importRows(ctx context.Context, tx *sqlx.Tx, rs []Recipient, created []*Recipient) error {
+	// Dropped below rather than on commit, as the caller's transaction may
+	// import again.
+	_, err := tx.ExecContext(ctx, "CREATE TEMPORARY TABLE recipients_import (position INT, name TEXT, email TEXT)")
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("recipients_import", "position", "name", "email"))
+	if err != nil {
+		return err
+	}
+	// Rows come back keyed by email; only the first of duplicate emails can be inserted.
+	pending := make(map[string]int, len(rs))
+	for i, r := range rs {
+		key := strings.ToLower(r.Email)
+		if _, ok := pending[key]; ok {
+			continue
+		}
+		pending[key] = i
+
+		if _, err := stmt.ExecContext(ctx, i, r.Name, r.Email); err != nil {
+			stmt.Close()
+			return err
+		}
+	}
+	// The final Exec flushes the rows.
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		stmt.Close()
+		return err
+	}
+	if err := stmt.Close(); err != nil {
+		return err
+	}
+
+	query := `INSERT INTO recipients (tenant_id, name, email)
+		SELECT $1, name, email FROM recipients_import ORDER BY position
+		ON CONFLICT (tenant_id, (lower(email))) DO NOTHING RETURNING ` + recipientColumns
+	rows, err := tx.QueryxContext(ctx, query, tenant.FromContext(ctx))
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var r Recipient
+		if err := rows.StructScan(&r); err != nil {
+			rows.Close()
+			return err
+		}
+		created[pending[strings.ToLower(r.Email)]] = &r
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "DROP TABLE recipients_import")
+	return err
+}
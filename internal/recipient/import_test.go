@@ -0,0 +1,83 @@
+// internal/recipient/import_test.go
+
+package recipient
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestImport(t *testing.T) {
+	ctx := context.Background()
+	p := newTestPersistence(t)
+
+	_, err := p.Create(ctx, Recipient{Name: "user1", Email: "user1@testmail.com"})
+	require.NoError(t, err, "Should have no error when creating a recipient")
+
+	rs := []Recipient{
+		{Name: "user1", Email: "User1@testmail.com"},
+		{Name: "user2", Email: "user2@testmail.com"},
+		{Name: "again", Email: "user2@testmail.com"},
+	}
+	rs = append(rs, newRecipients(3, 1000)...)
+
+	created, err := p.Import(ctx, rs)
+	require.NoError(t, err, "Should have no error when importing recipients")
+	require.Len(t, created, len(rs), "Result should be indexed like the input")
+
+	assert.Nil(t, created[0], "Taken emails should be skipped")
+	assert.Equal(t, "user2", created[1].Name, "New emails should be imported")
+	assert.False(t, created[1].Verified, "Imported recipients should not be verified")
+	assert.Nil(t, created[2], "Repeated emails should be skipped")
+	assert.Equal(t, fmt.Sprintf("user%d", len(rs)-1), created[len(rs)-1].Name, "Every row should be imported")
+
+	again, err := p.Import(ctx, []Recipient{{Name: "late", Email: "late@testmail.com"}})
+	require.NoError(t, err, "Importing twice in a session should work")
+	assert.NotNil(t, again[0])
+
+	all, err := p.ListRecipients(ctx, ListOptions{Limit: 1000})
+	assert.NoError(t, err, "Should have no error when listing recipients")
+	assert.Len(t, all, len(rs), "Every distinct email should be stored once")
+}
+
+// newRecipients returns n recipients numbered from first.
+func newRecipients(first, n int) []Recipient {
+	rs := make([]Recipient, n)
+	for i := range rs {
+		rs[i] = Recipient{Name: fmt.Sprintf("user%d", first+i), Email: fmt.Sprintf("user%d@testmail.com", first+i)}
+	}
+	return rs
+}
+
+// BenchmarkImport compares importing 10000 recipients with creating them one
+// by one.
+func BenchmarkImport(b *testing.B) {
+	ctx := context.Background()
+	rs := newRecipients(0, 10000)
+
+	b.Run("Create", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			p := newTestPersistence(b)
+			b.StartTimer()
+			for _, r := range rs {
+				if _, err := p.Create(ctx, r); err != nil {
+					b.Fatal(err)
+				}
+			}
+		}
+	})
+	b.Run("Import", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			p := newTestPersistence(b)
+			b.StartTimer()
+			if _, err := p.Import(ctx, rs); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
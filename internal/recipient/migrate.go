@@ -0,0 +1,13 @@
+// internal/recipient/migrate.go
+
+package recipient
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/internal/migrations"
+)
+
+// Migrate brings the database schema up to date.
+func (p *Persistence) Migrate(ctx context.Context) error {
+	return migrations.Up(ctx, p.DB)
+}
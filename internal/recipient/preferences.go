@@ -0,0 +1,147 @@
+// internal/recipient/preferences.go
+
+package recipient
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/tenant"
+	"github.com/jmoiron/sqlx"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// Delivery frequencies.
+const (
+	FrequencyDaily  = "daily"
+	FrequencyWeekly = "weekly"
+	FrequencyNever  = "never"
+)
+
+// Frequencies lists every frequency, for validating preferences.
+var Frequencies = []string{FrequencyDaily, FrequencyWeekly, FrequencyNever}
+
+// Delivery channels.
+const (
+	ChannelEmail    = "email"
+	ChannelSlack    = "slack"
+	ChannelTelegram = "telegram"
+)
+
+// AllChannels lists every channel, for validating preferences.
+var AllChannels = []string{ChannelEmail, ChannelSlack, ChannelTelegram}
+
+// Preferences say how a recipient wants their quotes. Empty fields keep the
+// defaults: every daily language, daily, by email.
+type Preferences struct {
+	Lang      string   `json:"lang,omitempty" xml:"lang,omitempty" db:"lang"`
+	Frequency string   `json:"frequency,omitempty" xml:"frequency,omitempty" db:"frequency"`
+	Channels  Channels `json:"channels,omitempty" xml:"channel,omitempty" db:"channels"`
+	// SlackWebhook is the Slack incoming webhook URL quotes are posted to.
+	SlackWebhook string `json:"slackWebhook,omitempty" xml:"slackWebhook,omitempty" db:"slack_webhook"`
+	// TelegramChatID is the chat the Telegram bot sends quotes to.
+	TelegramChatID string `json:"telegramChatId,omitempty" xml:"telegramChatId,omitempty" db:"telegram_chat_id"`
+}
+
+// Channels are the channels a recipient gets quotes on. They are stored
+// comma separated.
+type Channels []string
+
+// Value ...
+func (c Channels) Value() (driver.Value, error) {
+	return strings.Join(c, ","), nil
+}
+
+// Scan ...
+func (c *Channels) Scan(src interface{}) error {
+	var s string
+	switch v := src.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	case nil:
+	default:
+		return fmt.Errorf("recipient: cannot scan %T into channels", src)
+	}
+
+	*c = nil
+	if s != "" {
+		*c = strings.Split(s, ",")
+	}
+	return nil
+}
+
+// Validate checks the frequency; which languages are valid depends on the
+// quote providers.
+func (p Preferences) Validate() error {
+	if p.Frequency != "" && !slices.Contains(Frequencies, p.Frequency) {
+		return fmt.Errorf("invalid frequency %q, expected %s", p.Frequency, strings.Join(Frequencies, ", "))
+	}
+	return nil
+}
+
+// ValidateChannels checks that the channels are known and that the chat
+// channels have somewhere to send to.
+func (p Preferences) ValidateChannels() error {
+	for i, c := range p.Channels {
+		if !slices.Contains(AllChannels, c) {
+			return fmt.Errorf("invalid channel %q, expected %s", c, strings.Join(AllChannels, ", "))
+		}
+		if slices.Contains(p.Channels[:i], c) {
+			return fmt.Errorf("channel %q is listed twice", c)
+		}
+	}
+
+	if p.SlackWebhook != "" {
+		u, err := url.Parse(p.SlackWebhook)
+		if err != nil || u.Scheme != "https" || u.Host == "" {
+			return errors.New("slack webhook must be an https URL")
+		}
+	}
+	if slices.Contains(p.Channels, ChannelSlack) && p.SlackWebhook == "" {
+		return errors.New("the slack channel needs a slack webhook")
+	}
+	if slices.Contains(p.Channels, ChannelTelegram) && p.TelegramChatID == "" {
+		return errors.New("the telegram channel needs a telegram chat id")
+	}
+
+	return nil
+}
+
+// EffectiveChannels is Channels, or email when unset.
+func (p Preferences) EffectiveChannels() []string {
+	if len(p.Channels) == 0 {
+		return []string{ChannelEmail}
+	}
+	return p.Channels
+}
+
+// EffectiveFrequency is Frequency, or daily when unset.
+func (p Preferences) EffectiveFrequency() string {
+	if p.Frequency == "" {
+		return FrequencyDaily
+	}
+	return p.Frequency
+}
+
+// UpdatePreferences replaces the recipient's preferences.
+func (p *Persistence) UpdatePreferences(ctx context.Context, id int, prefs Preferences) (*Recipient, error) {
+	var r Recipient
+
+	query := `UPDATE recipients SET lang = $1, frequency = $2, channels = $3, slack_webhook = $4, telegram_chat_id = $5,
+		version = version + 1 WHERE id = $6 AND tenant_id = $7 RETURNING ` + recipientColumns
+	err := sqlx.GetContext(ctx, p.queryer(), &r, query, prefs.Lang, prefs.Frequency, prefs.Channels, prefs.SlackWebhook, prefs.TelegramChatID, id, tenant.FromContext(ctx))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
@@ -0,0 +1,50 @@
+// internal/recipient/query.go
+
+package recipient
+
+import (
+	"fmt"
+	"strings"
+)
+
+// recipientColumns lists the columns scanned into a Recipient. Queries name
+// them instead of using *, so a new column cannot shift the scan.
+const recipientColumns = "id, name, email, verified, lang, frequency, channels, slack_webhook, telegram_chat_id, version"
+
+// qualifiedColumns returns columns, a comma separated list, each qualified by
+// the table alias, for queries that join recipients to another table.
+func qualifiedColumns(alias, columns string) string {
+	cols := strings.Split(columns, ", ")
+	for i, c := range cols {
+		cols[i] = alias + "." + c
+	}
+	return strings.Join(cols, ", ")
+}
+
+// query builds SQL with numbered placeholders for its arguments.
+type query struct {
+	sql  strings.Builder
+	args []interface{}
+}
+
+func newQuery(sql string) *query {
+	q := &query{}
+	q.sql.WriteString(sql)
+	return q
+}
+
+// arg adds v as an argument and returns its placeholder.
+func (q *query) arg(v interface{}) string {
+	q.args = append(q.args, v)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+// add appends SQL, formatted like fmt.Sprintf. Values must go through arg.
+func (q *query) add(format string, a ...interface{}) *query {
+	fmt.Fprintf(&q.sql, format, a...)
+	return q
+}
+
+func (q *query) String() string {
+	return q.sql.String()
+}
@@ -0,0 +1,24 @@
+// internal/recipient/query_test.go
+
+package recipient
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestQuery(t *testing.T) {
+	q := newQuery("SELECT " + recipientColumns + " FROM recipients")
+	pattern := q.arg("%bob%")
+	q.add(" WHERE name ILIKE %s OR email ILIKE %s", pattern, pattern)
+	q.add(" ORDER BY %s %s", "name", "DESC").add(" LIMIT %s", q.arg(10))
+
+	assert.Equal(t, "SELECT id, name, email, verified, lang, frequency, channels, slack_webhook, telegram_chat_id, version FROM recipients WHERE name ILIKE $1 OR email ILIKE $1 ORDER BY name DESC LIMIT $2", q.String(), "Wrong SQL")
+	assert.Equal(t, []interface{}{"%bob%", 10}, q.args, "Wrong arguments")
+}
+
+func TestQualifiedColumns(t *testing.T) {
+	assert.Equal(t, "r.id, r.name", qualifiedColumns("r", "id, name"), "Wrong columns")
+	assert.Equal(t, len(strings.Split(recipientColumns, ", ")), strings.Count(qualifiedColumns("r", recipientColumns), "r."), "Every column should be qualified")
+}
@@ -0,0 +1,482 @@
+// internal/recipient/recipient.go
+
+package recipient
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/internal/dbtx"
+	"github.com/jeniok12/golang-tdd-example/internal/replica"
+	"github.com/jeniok12/golang-tdd-example/tenant"
+	"github.com/jeniok12/golang-tdd-example/tracing"
+	"github.com/jmoiron/sqlx"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotFound ...
+var ErrNotFound = errors.New("recipient not found")
+
+// ErrDuplicateEmail is returned when another recipient already uses the email,
+// compared case-insensitively.
+var ErrDuplicateEmail = errors.New("email already exists")
+
+// ErrVersionMismatch is returned when a recipient changed since the version
+// an update was based on.
+var ErrVersionMismatch = errors.New("recipient was changed by another request")
+
+// Recipient ...
+type Recipient struct {
+	ID    int    `json:"id" xml:"id" db:"id"`
+	Name  string `json:"name" xml:"name" db:"name"`
+	Email string `json:"email" xml:"email" db:"email"`
+	// Verified is set once the recipient confirms their email. Only verified
+	// recipients are sent quotes.
+	Verified bool `json:"verified" xml:"verified" db:"verified"`
+	// Version is bumped on every change to the recipient.
+	Version int `json:"version,omitempty" xml:"version,omitempty" db:"version"`
+	Preferences
+}
+
+// Validate ...
+func (r Recipient) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return errors.New("name is required")
+	}
+
+	addr, err := mail.ParseAddress(r.Email)
+	if err != nil || addr.Address != r.Email {
+		return fmt.Errorf("invalid email %q", r.Email)
+	}
+
+	return nil
+}
+
+// Persistence ...
+type Persistence struct {
+	DB *sql.DB
+	// Replicas, when set, serve ListRecipients, which tolerates replication
+	// lag.
+	Replicas *replica.Set
+
+	// dbx shares DB's pool and scans rows into structs by their db tags.
+	dbx   *sqlx.DB
+	mu    sync.Mutex
+	stmts map[string]*sqlx.Stmt
+
+	// tx, when set, is the transaction the queries run in. root is the
+	// Persistence it was started from, which keeps the prepared statements.
+	tx   *sqlx.Tx
+	root *Persistence
+}
+
+// Pool sizes the database connection pool. Zero values keep the
+// database/sql defaults.
+type Pool struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxIdleTime time.Duration
+	ConnMaxLifetime time.Duration
+}
+
+// NewPersistence ...
+func NewPersistence(host, dbName string, pool Pool) (*Persistence, error) {
+	db, err := OpenDB(host, dbName, pool)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewPersistenceDB(db), nil
+}
+
+// OpenDB opens a Postgres connection pool sized by pool, such as one to a
+// read replica.
+func OpenDB(host, dbName string, pool Pool) (*sql.DB, error) {
+	db, err := tracing.OpenDB("postgres", fmt.Sprintf("dbname=%s host=%s sslmode=disable", dbName, host), "postgresql")
+	if err != nil {
+		return nil, err
+	}
+
+	if pool.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(pool.MaxOpenConns)
+	}
+	if pool.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(pool.MaxIdleConns)
+	}
+	if pool.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(pool.ConnMaxIdleTime)
+	}
+	if pool.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(pool.ConnMaxLifetime)
+	}
+
+	return db, nil
+}
+
+// NewPersistenceDB returns a Persistence on an open Postgres connection pool.
+func NewPersistenceDB(db *sql.DB) *Persistence {
+	return &Persistence{
+		DB:    db,
+		dbx:   sqlx.NewDb(db, "postgres"),
+		stmts: make(map[string]*sqlx.Stmt),
+	}
+}
+
+// prepared returns a statement for query, preparing it on first use. The
+// connection is only needed once a query runs, so nothing is prepared up front.
+func (p *Persistence) prepared(ctx context.Context, query string) (*sqlx.Stmt, error) {
+	if p.root != nil {
+		stmt, err := p.root.prepared(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		// Closed along with the transaction.
+		return p.tx.StmtxContext(ctx, stmt), nil
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if stmt, ok := p.stmts[query]; ok {
+		return stmt, nil
+	}
+
+	stmt, err := p.dbx.PreparexContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	p.stmts[query] = stmt
+
+	return stmt, nil
+}
+
+// WithTx returns the Persistence running its queries in tx, so they commit
+// or roll back together with those of other repositories.
+func (p *Persistence) WithTx(tx *sql.Tx) *Persistence {
+	return &Persistence{
+		DB:   p.DB,
+		dbx:  p.dbx,
+		tx:   &sqlx.Tx{Tx: tx, Mapper: p.dbx.Mapper},
+		root: p,
+	}
+}
+
+// exec is the transaction, if any, or the pool.
+func (p *Persistence) exec() dbtx.Executor {
+	if p.tx != nil {
+		return p.tx
+	}
+	return p.DB
+}
+
+// queryer is exec for sqlx, which scans rows into structs.
+func (p *Persistence) queryer() sqlx.QueryerContext {
+	if p.tx != nil {
+		return p.tx
+	}
+	return p.dbx
+}
+
+// Ping ...
+func (p *Persistence) Ping(ctx context.Context) error {
+	return p.DB.PingContext(ctx)
+}
+
+// Stats ...
+func (p *Persistence) Stats() sql.DBStats {
+	return p.DB.Stats()
+}
+
+// Close releases the prepared statements and the connection pool.
+func (p *Persistence) Close() error {
+	p.mu.Lock()
+	for query, stmt := range p.stmts {
+		stmt.Close()
+		delete(p.stmts, query)
+	}
+	p.mu.Unlock()
+
+	return p.DB.Close()
+}
+
+// AllRecipients returns every verified recipient of the tenant of ctx. Like
+// every other method, it only sees the rows of that tenant.
+func (p *Persistence) AllRecipients(ctx context.Context) ([]Recipient, error) {
+	stmt, err := p.prepared(ctx, "SELECT "+recipientColumns+" FROM recipients WHERE tenant_id = $1 AND verified ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+
+	var recipients []Recipient
+	if err := stmt.SelectContext(ctx, &recipients, tenant.FromContext(ctx)); err != nil {
+		return nil, err
+	}
+
+	return recipients, nil
+}
+
+// ListOptions narrows and orders the recipients returned by ListRecipients.
+// A zero Limit returns every matching recipient.
+type ListOptions struct {
+	Limit  int
+	Offset int
+	// Query matches recipients whose name or email contains it, ignoring case.
+	Query string
+	// OrderBy is one of id, name or email, prefixed with "-" for descending
+	// order. Recipients are ordered by id when empty.
+	OrderBy string
+}
+
+var orderColumns = map[string]string{
+	"id":    "id",
+	"name":  "name",
+	"email": "email",
+}
+
+// likeEscaper makes a search term match literally inside a LIKE pattern.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// Validate ...
+func (o ListOptions) Validate() error {
+	if o.Limit < 0 || o.Offset < 0 {
+		return errors.New("limit and offset must not be negative")
+	}
+
+	if _, _, err := o.order(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (o ListOptions) order() (column, direction string, err error) {
+	if o.OrderBy == "" {
+		return "id", "ASC", nil
+	}
+
+	field, direction := o.OrderBy, "ASC"
+	if strings.HasPrefix(field, "-") {
+		field, direction = field[1:], "DESC"
+	}
+
+	column, ok := orderColumns[field]
+	if !ok {
+		return "", "", fmt.Errorf("invalid order %q", o.OrderBy)
+	}
+
+	return column, direction, nil
+}
+
+// ListRecipients returns one page of the recipients matching opts.
+func (p *Persistence) ListRecipients(ctx context.Context, opts ListOptions) ([]Recipient, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	column, direction, _ := opts.order()
+
+	q := newQuery("SELECT " + recipientColumns + " FROM recipients")
+	q.add(" WHERE tenant_id = %s", q.arg(tenant.FromContext(ctx)))
+	if opts.Query != "" {
+		pattern := q.arg("%" + likeEscaper.Replace(opts.Query) + "%")
+		q.add(" AND (name ILIKE %s OR email ILIKE %s)", pattern, pattern)
+	}
+
+	q.add(" ORDER BY %s %s", column, direction)
+	if column != "id" {
+		// The tie-breaker keeps pages stable when ordering by a non-unique column.
+		q.add(", id")
+	}
+
+	if opts.Limit > 0 {
+		q.add(" LIMIT %s", q.arg(opts.Limit))
+	}
+	if opts.Offset > 0 {
+		q.add(" OFFSET %s", q.arg(opts.Offset))
+	}
+
+	if p.Replicas != nil && p.tx == nil {
+		// Not prepared: statements are prepared on the primary's pool.
+		var recipients []Recipient
+		err := p.Replicas.Read(ctx, func(db *sql.DB) error {
+			recipients = nil
+			return sqlx.SelectContext(ctx, sqlx.NewDb(db, "postgres"), &recipients, q.String(), q.args...)
+		})
+		return recipients, err
+	}
+
+	stmt, err := p.prepared(ctx, q.String())
+	if err != nil {
+		return nil, err
+	}
+
+	var recipients []Recipient
+	if err := stmt.SelectContext(ctx, &recipients, q.args...); err != nil {
+		return nil, err
+	}
+
+	return recipients, nil
+}
+
+// eachPageSize is the number of rows fetched per query when iterating.
+const eachPageSize = 500
+
+// EachRecipient calls fn for every recipient, verified or not, in id order,
+// stopping at the first error. Recipients are fetched in pages keyed by id, so
+// no query holds the whole table.
+func (p *Persistence) EachRecipient(ctx context.Context, fn func(Recipient) error) error {
+	query := "SELECT " + recipientColumns + " FROM recipients WHERE tenant_id = $1 AND id > $2 ORDER BY id LIMIT $3"
+
+	lastID := 0
+	for {
+		page, err := p.recipientPage(ctx, query, lastID)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range page {
+			if err := fn(r); err != nil {
+				return err
+			}
+		}
+
+		if len(page) < eachPageSize {
+			return nil
+		}
+		lastID = page[len(page)-1].ID
+	}
+}
+
+func (p *Persistence) recipientPage(ctx context.Context, query string, afterID int) ([]Recipient, error) {
+	stmt, err := p.prepared(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var page []Recipient
+	if err := stmt.SelectContext(ctx, &page, tenant.FromContext(ctx), afterID, eachPageSize); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+// Create adds an unverified recipient.
+func (p *Persistence) Create(ctx context.Context, r Recipient) (*Recipient, error) {
+	r.Verified = false
+
+	query := "INSERT INTO recipients (tenant_id, name, email) VALUES ($1, $2, $3) RETURNING id, version"
+	err := p.exec().QueryRowContext(ctx, query, tenant.FromContext(ctx), r.Name, r.Email).Scan(&r.ID, &r.Version)
+	if isPQError(err, uniqueViolation) {
+		return nil, ErrDuplicateEmail
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// GetByID ...
+func (p *Persistence) GetByID(ctx context.Context, id int) (*Recipient, error) {
+	stmt, err := p.prepared(ctx, "SELECT "+recipientColumns+" FROM recipients WHERE tenant_id = $1 AND id = $2")
+	if err != nil {
+		return nil, err
+	}
+
+	var r Recipient
+	err = stmt.GetContext(ctx, &r, tenant.FromContext(ctx), id)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// Update changes the recipient's name and email. Changing the email clears
+// the verified flag. A non-zero r.Version must match the stored one, or
+// ErrVersionMismatch is returned.
+func (p *Persistence) Update(ctx context.Context, r Recipient) (*Recipient, error) {
+	query := `UPDATE recipients SET name = $1, email = $2, verified = verified AND lower(email) = lower($2),
+		version = version + 1
+		WHERE id = $3 AND ($4 = 0 OR version = $4) AND tenant_id = $5
+		RETURNING verified, lang, frequency, channels, slack_webhook, telegram_chat_id, version`
+	err := p.exec().QueryRowContext(ctx, query, r.Name, r.Email, r.ID, r.Version, tenant.FromContext(ctx)).Scan(&r.Verified, &r.Lang, &r.Frequency, &r.Channels, &r.SlackWebhook, &r.TelegramChatID, &r.Version)
+	if isPQError(err, uniqueViolation) {
+		return nil, ErrDuplicateEmail
+	}
+	if err == sql.ErrNoRows {
+		return nil, p.missing(ctx, r)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// MarkVerified marks the recipient verified, provided its email is still the
+// one the verification was issued for.
+func (p *Persistence) MarkVerified(ctx context.Context, id int, email string) (*Recipient, error) {
+	var r Recipient
+
+	query := `UPDATE recipients SET verified = TRUE, version = version + 1
+		WHERE id = $1 AND lower(email) = lower($2) AND tenant_id = $3 RETURNING ` + recipientColumns
+	err := sqlx.GetContext(ctx, p.queryer(), &r, query, id, email, tenant.FromContext(ctx))
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// Delete ...
+func (p *Persistence) Delete(ctx context.Context, id int) error {
+	res, err := p.exec().ExecContext(ctx, "DELETE FROM recipients WHERE id = $1 AND tenant_id = $2", id, tenant.FromContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res)
+}
+
+// missing tells why updating r matched no row: the recipient is gone, or it
+// changed since r.Version.
+func (p *Persistence) missing(ctx context.Context, r Recipient) error {
+	if r.Version == 0 {
+		return ErrNotFound
+	}
+
+	var exists bool
+	query := "SELECT EXISTS (SELECT 1 FROM recipients WHERE id = $1 AND tenant_id = $2)"
+	err := p.exec().QueryRowContext(ctx, query, r.ID, tenant.FromContext(ctx)).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrNotFound
+	}
+
+	return ErrVersionMismatch
+}
+
+func checkAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
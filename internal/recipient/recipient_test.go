@@ -0,0 +1,417 @@
+// internal/recipient/recipient_test.go
+package recipient
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/internal/pgtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+)
+
+var expectedRecipients = []Recipient{
+	{
+		ID:       1,
+		Name:     "user1",
+		Email:    "user1@testmail.com",
+		Verified: true,
+		Version:  1,
+	},
+	{
+		ID:       2,
+		Name:     "user2",
+		Email:    "user2@testmail.com",
+		Verified: true,
+		Version:  1,
+	},
+	{
+		ID:       3,
+		Name:     "user3",
+		Email:    "user3@testmail.com",
+		Verified: true,
+		Version:  1,
+	},
+}
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Main(m))
+}
+
+// newTestPersistence returns a Persistence on a migrated schema of its own.
+func newTestPersistence(t testing.TB) *Persistence {
+	return NewPersistenceDB(pgtest.Open(t))
+}
+
+func TestAllRecipients(t *testing.T) {
+	testCases := []struct {
+		name               string
+		presetDB           func(db *sql.DB) error
+		expectedRecipients []Recipient
+		err                error
+	}{
+		{
+			"RecipientsFound",
+			func(db *sql.DB) error {
+				query := "INSERT INTO recipients (id, name, email, verified) VALUES ($1, $2, $3, $4);"
+				tx, err := db.Begin()
+
+				for _, r := range expectedRecipients {
+					_, err = tx.Exec(query, r.ID, r.Name, r.Email, r.Verified)
+					if err != nil {
+						fmt.Println(fmt.Sprintf("Error: %+v", err))
+					}
+				}
+
+				tx.Commit()
+				return err
+			},
+			expectedRecipients,
+			nil,
+		},
+		{
+			"RecipientsNotFound",
+			func(db *sql.DB) error {
+				return nil
+			},
+			nil,
+			nil,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			p := newTestPersistence(t)
+
+			err := tC.presetDB(p.DB)
+			require.NoErrorf(t, err, "Should have no error when pre-setting the DB")
+
+			recipients, err := p.AllRecipients(context.Background())
+
+			assert.Equal(t, err, tC.err, "Error should be as expected")
+			assert.ElementsMatch(t, recipients, tC.expectedRecipients, "Response should be as expected")
+		})
+	}
+}
+
+func TestCreateAndGetByID(t *testing.T) {
+	ctx := context.Background()
+	p := newTestPersistence(t)
+
+	created, err := p.Create(ctx, Recipient{Name: "user1", Email: "user1@testmail.com"})
+	require.NoError(t, err, "Should have no error when creating a recipient")
+	assert.NotZero(t, created.ID, "Created recipient should have an ID")
+
+	found, err := p.GetByID(ctx, created.ID)
+	assert.NoError(t, err, "Should have no error when fetching a recipient")
+	assert.Equal(t, created, found, "Fetched recipient should be as created")
+
+	_, err = p.GetByID(ctx, created.ID+1)
+	assert.Equal(t, ErrNotFound, err, "Should get ErrNotFound for unknown ID")
+}
+
+func TestCreate_DuplicateEmail(t *testing.T) {
+	ctx := context.Background()
+	p := newTestPersistence(t)
+
+	_, err := p.Create(ctx, Recipient{Name: "user1", Email: "user1@testmail.com"})
+	require.NoError(t, err, "Should have no error when creating a recipient")
+
+	_, err = p.Create(ctx, Recipient{Name: "other", Email: "User1@TestMail.com"})
+	assert.Equal(t, ErrDuplicateEmail, err, "Should get ErrDuplicateEmail for a taken email")
+
+	other, err := p.Create(ctx, Recipient{Name: "user2", Email: "user2@testmail.com"})
+	require.NoError(t, err, "Should have no error when creating a recipient")
+
+	other.Email = "user1@testmail.com"
+	_, err = p.Update(ctx, *other)
+	assert.Equal(t, ErrDuplicateEmail, err, "Should get ErrDuplicateEmail when updating to a taken email")
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	p := newTestPersistence(t)
+
+	created, err := p.Create(ctx, Recipient{Name: "user1", Email: "user1@testmail.com"})
+	require.NoError(t, err, "Should have no error when creating a recipient")
+
+	created.Email = "new@testmail.com"
+	_, err = p.Update(ctx, *created)
+	assert.NoError(t, err, "Should have no error when updating a recipient")
+
+	found, err := p.GetByID(ctx, created.ID)
+	assert.NoError(t, err, "Should have no error when fetching a recipient")
+	assert.Equal(t, "new@testmail.com", found.Email, "Email should be updated")
+
+	assert.Equal(t, 2, found.Version, "Version should be bumped")
+
+	_, err = p.Update(ctx, *created)
+	assert.Equal(t, ErrVersionMismatch, err, "Should get ErrVersionMismatch for a stale version")
+
+	_, err = p.Update(ctx, Recipient{ID: created.ID + 1, Name: "x", Email: "x@testmail.com"})
+	assert.Equal(t, ErrNotFound, err, "Should get ErrNotFound for unknown ID")
+}
+
+func TestUpdatePreferences(t *testing.T) {
+	ctx := context.Background()
+	p := newTestPersistence(t)
+
+	created, err := p.Create(ctx, Recipient{Name: "user1", Email: "user1@testmail.com"})
+	require.NoError(t, err, "Should have no error when creating a recipient")
+
+	prefs := Preferences{Lang: "ru", Frequency: FrequencyWeekly}
+	updated, err := p.UpdatePreferences(ctx, created.ID, prefs)
+	assert.NoError(t, err, "Should have no error when updating preferences")
+	assert.Equal(t, prefs, updated.Preferences, "Preferences should be updated")
+
+	found, err := p.GetByID(ctx, created.ID)
+	assert.NoError(t, err, "Should have no error when fetching a recipient")
+	assert.Equal(t, prefs, found.Preferences, "Preferences should be persisted")
+
+	_, err = p.UpdatePreferences(ctx, created.ID+1, prefs)
+	assert.Equal(t, ErrNotFound, err, "Should get ErrNotFound for unknown ID")
+}
+
+func TestSuppressions(t *testing.T) {
+	ctx := context.Background()
+	p := newTestPersistence(t)
+
+	assert.NoError(t, p.Suppress(ctx, "User1@testmail.com"), "Should have no error when suppressing an email")
+	assert.NoError(t, p.Suppress(ctx, "user1@testmail.com"), "Suppressing twice should not fail")
+
+	suppressed, err := p.Suppressed(ctx, []string{"USER1@testmail.com", "user2@testmail.com"})
+	assert.NoError(t, err, "Should have no error when checking suppressions")
+	assert.Equal(t, map[string]bool{"user1@testmail.com": true}, suppressed, "Emails should be matched regardless of case")
+}
+
+func TestPreferences_Validate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		prefs       Preferences
+		expectedErr bool
+	}{
+		{"Empty", Preferences{}, false},
+		{"Weekly", Preferences{Lang: "ru", Frequency: FrequencyWeekly}, false},
+		{"UnknownFrequency", Preferences{Frequency: "hourly"}, true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			err := tC.prefs.Validate()
+			if tC.expectedErr {
+				assert.Error(t, err, "Got no error when expected")
+			} else {
+				assert.NoError(t, err, "Got error when not expected")
+			}
+		})
+	}
+}
+
+func TestPreferences_ValidateChannels(t *testing.T) {
+	testCases := []struct {
+		name        string
+		prefs       Preferences
+		expectedErr bool
+	}{
+		{"Empty", Preferences{}, false},
+		{"Slack", Preferences{Channels: Channels{ChannelEmail, ChannelSlack}, SlackWebhook: "https://hooks.slack.com/services/T0/B0/x"}, false},
+		{"Telegram", Preferences{Channels: Channels{ChannelTelegram}, TelegramChatID: "42"}, false},
+		{"UnknownChannel", Preferences{Channels: Channels{"sms"}}, true},
+		{"Duplicate", Preferences{Channels: Channels{ChannelEmail, ChannelEmail}}, true},
+		{"SlackWithoutWebhook", Preferences{Channels: Channels{ChannelSlack}}, true},
+		{"PlainHTTPWebhook", Preferences{SlackWebhook: "http://hooks.slack.com/services/T0/B0/x"}, true},
+		{"TelegramWithoutChat", Preferences{Channels: Channels{ChannelTelegram}}, true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			err := tC.prefs.ValidateChannels()
+			if tC.expectedErr {
+				assert.Error(t, err, "Got no error when expected")
+			} else {
+				assert.NoError(t, err, "Got error when not expected")
+			}
+		})
+	}
+}
+
+func TestChannels_Scan(t *testing.T) {
+	var c Channels
+	assert.NoError(t, c.Scan("email,slack"), "Got error when not expected")
+	assert.Equal(t, Channels{ChannelEmail, ChannelSlack}, c, "Channels are different than expected")
+
+	assert.NoError(t, c.Scan([]byte("")), "Got error when not expected")
+	assert.Nil(t, c, "An empty column should mean no channels")
+
+	v, _ := Channels{ChannelEmail, ChannelTelegram}.Value()
+	assert.Equal(t, "email,telegram", v, "Channels should be stored comma separated")
+}
+
+func TestMarkVerified(t *testing.T) {
+	ctx := context.Background()
+	p := newTestPersistence(t)
+
+	created, err := p.Create(ctx, Recipient{Name: "user1", Email: "user1@testmail.com", Verified: true})
+	require.NoError(t, err, "Should have no error when creating a recipient")
+	assert.False(t, created.Verified, "New recipients should not be verified")
+
+	all, err := p.AllRecipients(ctx)
+	assert.NoError(t, err, "Should have no error when fetching recipients")
+	assert.Empty(t, all, "Unverified recipients should not receive quotes")
+
+	_, err = p.MarkVerified(ctx, created.ID, "other@testmail.com")
+	assert.Equal(t, ErrNotFound, err, "Should not verify a stale email")
+
+	verified, err := p.MarkVerified(ctx, created.ID, "User1@TestMail.com")
+	require.NoError(t, err, "Should have no error when verifying a recipient")
+	assert.True(t, verified.Verified, "Recipient should be verified")
+
+	all, err = p.AllRecipients(ctx)
+	assert.NoError(t, err, "Should have no error when fetching recipients")
+	assert.Equal(t, []Recipient{*verified}, all, "Verified recipients should receive quotes")
+
+	verified.Name = "renamed"
+	updated, err := p.Update(ctx, *verified)
+	assert.NoError(t, err, "Should have no error when updating a recipient")
+	assert.True(t, updated.Verified, "Keeping the email should keep the recipient verified")
+
+	updated.Email = "new@testmail.com"
+	updated, err = p.Update(ctx, *updated)
+	assert.NoError(t, err, "Should have no error when updating a recipient")
+	assert.False(t, updated.Verified, "Changing the email should require verifying again")
+}
+
+func TestEachRecipient(t *testing.T) {
+	ctx := context.Background()
+	p := newTestPersistence(t)
+
+	for i := 0; i < eachPageSize+1; i++ {
+		_, err := p.Create(ctx, Recipient{Name: "user", Email: fmt.Sprintf("user%d@testmail.com", i)})
+		require.NoError(t, err, "Should have no error when creating a recipient")
+	}
+
+	var ids []int
+	err := p.EachRecipient(ctx, func(r Recipient) error {
+		ids = append(ids, r.ID)
+		return nil
+	})
+	assert.NoError(t, err, "Should have no error when iterating recipients")
+	assert.Len(t, ids, eachPageSize+1, "Should visit every recipient across pages")
+	assert.IsIncreasing(t, ids, "Should visit recipients in id order")
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+	p := newTestPersistence(t)
+
+	created, err := p.Create(ctx, Recipient{Name: "user1", Email: "user1@testmail.com"})
+	require.NoError(t, err, "Should have no error when creating a recipient")
+
+	assert.NoError(t, p.Delete(ctx, created.ID), "Should have no error when deleting a recipient")
+	assert.Equal(t, ErrNotFound, p.Delete(ctx, created.ID), "Should get ErrNotFound on second delete")
+}
+
+func TestListRecipients(t *testing.T) {
+	ctx := context.Background()
+	p := newTestPersistence(t)
+
+	for _, r := range []Recipient{
+		{Name: "carol", Email: "carol@testmail.com"},
+		{Name: "alice", Email: "alice@example.com"},
+		{Name: "bob", Email: "bob@testmail.com"},
+	} {
+		_, err := p.Create(ctx, r)
+		require.NoError(t, err, "Should have no error when creating a recipient")
+	}
+
+	names := func(recipients []Recipient) []string {
+		var n []string
+		for _, r := range recipients {
+			n = append(n, r.Name)
+		}
+		return n
+	}
+
+	testCases := []struct {
+		name     string
+		opts     ListOptions
+		expected []string
+	}{
+		{"All", ListOptions{}, []string{"carol", "alice", "bob"}},
+		{"Page", ListOptions{Limit: 1, Offset: 1}, []string{"alice"}},
+		{"ByName", ListOptions{OrderBy: "name"}, []string{"alice", "bob", "carol"}},
+		{"ByNameDesc", ListOptions{OrderBy: "-name", Limit: 2}, []string{"carol", "bob"}},
+		{"QueryEmail", ListOptions{Query: "TESTMAIL", OrderBy: "email"}, []string{"bob", "carol"}},
+		{"QueryLiteral", ListOptions{Query: "%"}, nil},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			recipients, err := p.ListRecipients(ctx, tC.opts)
+			assert.NoError(t, err, "Should have no error when listing recipients")
+			assert.Equal(t, tC.expected, names(recipients), "Recipients should be as expected")
+		})
+	}
+}
+
+func TestListOptions_Validate(t *testing.T) {
+	testCases := []struct {
+		name  string
+		opts  ListOptions
+		valid bool
+	}{
+		{"Zero", ListOptions{}, true},
+		{"Descending", ListOptions{OrderBy: "-email"}, true},
+		{"NegativeLimit", ListOptions{Limit: -1}, false},
+		{"NegativeOffset", ListOptions{Offset: -1}, false},
+		{"UnknownOrder", ListOptions{OrderBy: "password"}, false},
+		{"Injection", ListOptions{OrderBy: "id; DROP TABLE recipients"}, false},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			err := tC.opts.Validate()
+			if tC.valid {
+				assert.NoError(t, err, "Got error when not expected")
+			} else {
+				assert.Error(t, err, "Got no error when expected")
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name      string
+		recipient Recipient
+		valid     bool
+	}{
+		{"Valid", Recipient{Name: "user1", Email: "user1@testmail.com"}, true},
+		{"MissingName", Recipient{Name: " ", Email: "user1@testmail.com"}, false},
+		{"InvalidEmail", Recipient{Name: "user1", Email: "user1"}, false},
+		{"EmailWithDisplayName", Recipient{Name: "user1", Email: "User <user1@testmail.com>"}, false},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			err := tC.recipient.Validate()
+			if tC.valid {
+				assert.NoError(t, err, "Got error when not expected")
+			} else {
+				assert.Error(t, err, "Got no error when expected")
+			}
+		})
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	p := newTestPersistence(t)
+
+	err := p.Migrate(ctx)
+	require.NoError(t, err, "Should have no error when migrating")
+
+	err = p.Migrate(ctx)
+	assert.NoError(t, err, "Migrating twice should be a no-op")
+
+	var version int64
+	err = p.DB.QueryRow("SELECT version FROM schema_migrations").Scan(&version)
+	assert.NoError(t, err, "Should record the schema version")
+	assert.NotZero(t, version, "Schema version should be set")
+}
@@ -0,0 +1,43 @@
+// internal/recipient/suppression.go
+
+package recipient
+
+import (
+	"context"
+	"github.com/lib/pq"
+	"strings"
+)
+
+// Suppress adds email to the suppression list. Suppressing an email twice
+// is not an error.
+func (p *Persistence) Suppress(ctx context.Context, email string) error {
+	query := "INSERT INTO suppressions (email) VALUES (lower($1)) ON CONFLICT (email) DO NOTHING"
+	_, err := p.exec().ExecContext(ctx, query, email)
+	return err
+}
+
+// Suppressed returns which of emails are on the suppression list, keyed by
+// their lowercased form.
+func (p *Persistence) Suppressed(ctx context.Context, emails []string) (map[string]bool, error) {
+	lowered := make([]string, len(emails))
+	for i, email := range emails {
+		lowered[i] = strings.ToLower(email)
+	}
+
+	rows, err := p.exec().QueryContext(ctx, "SELECT email FROM suppressions WHERE email = ANY($1)", pq.Array(lowered))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suppressed := make(map[string]bool)
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		suppressed[email] = true
+	}
+
+	return suppressed, rows.Err()
+}
@@ -0,0 +1,93 @@
+// internal/recipient/tag.go
+
+package recipient
+
+import (
+	"context"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/tenant"
+	"github.com/jmoiron/sqlx"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidTag ...
+var ErrInvalidTag = errors.New("tags are 1 to 50 letters, digits, dots, dashes or underscores")
+
+var tagPattern = regexp.MustCompile(`^[\p{L}\p{N}][\p{L}\p{N}._-]{0,49}$`)
+
+// NormalizeTag lowercases tag and checks it is a valid tag. Tags compare
+// case-insensitively, so Devs and devs are the same tag.
+func NormalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if !tagPattern.MatchString(tag) {
+		return "", ErrInvalidTag
+	}
+	return tag, nil
+}
+
+// AddTag tags the recipient. Adding a tag twice is a no-op.
+func (p *Persistence) AddTag(ctx context.Context, recipientID int, tag string) error {
+	if _, err := p.GetByID(ctx, recipientID); err != nil {
+		return err
+	}
+
+	query := "INSERT INTO recipient_tags (recipient_id, tag) VALUES ($1, $2) ON CONFLICT DO NOTHING"
+	_, err := p.exec().ExecContext(ctx, query, recipientID, tag)
+	if isPQError(err, foreignKeyViolation) {
+		return ErrNotFound
+	}
+
+	return err
+}
+
+// RemoveTag ...
+func (p *Persistence) RemoveTag(ctx context.Context, recipientID int, tag string) error {
+	query := `DELETE FROM recipient_tags WHERE recipient_id = $1 AND tag = $2
+		AND recipient_id IN (SELECT id FROM recipients WHERE tenant_id = $3)`
+	res, err := p.exec().ExecContext(ctx, query, recipientID, tag, tenant.FromContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res)
+}
+
+// ListTags returns the recipient's tags in alphabetical order.
+func (p *Persistence) ListTags(ctx context.Context, recipientID int) ([]string, error) {
+	if _, err := p.GetByID(ctx, recipientID); err != nil {
+		return nil, err
+	}
+
+	tags := []string{}
+	query := "SELECT tag FROM recipient_tags WHERE recipient_id = $1 ORDER BY tag"
+	if err := sqlx.SelectContext(ctx, p.queryer(), &tags, query, recipientID); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// TaggedRecipients returns the verified recipients having any of tags.
+func (p *Persistence) TaggedRecipients(ctx context.Context, tags []string) ([]Recipient, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+
+	q := newQuery("SELECT " + recipientColumns + " FROM recipients")
+	q.add(" WHERE tenant_id = %s AND verified AND id IN (SELECT recipient_id FROM recipient_tags WHERE tag IN (", q.arg(tenant.FromContext(ctx)))
+	for i, tag := range tags {
+		if i > 0 {
+			q.add(", ")
+		}
+		q.add("%s", q.arg(tag))
+	}
+	q.add(")) ORDER BY id")
+
+	var recipients []Recipient
+	if err := sqlx.SelectContext(ctx, p.queryer(), &recipients, q.String(), q.args...); err != nil {
+		return nil, err
+	}
+
+	return recipients, nil
+}
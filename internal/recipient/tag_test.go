@@ -0,0 +1,70 @@
+// internal/recipient/tag_test.go
+
+package recipient
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		tag                string
+		expected           string
+		expectedToGetError bool
+	}{
+		{"Lowercased", " Devs ", "devs", false},
+		{"Punctuation", "team-a_1.0", "team-a_1.0", false},
+		{"Unicode", "Команда", "команда", false},
+		{"Empty", "", "", true},
+		{"Space", "dev ops", "", true},
+		{"LeadingDash", "-devs", "", true},
+		{"TooLong", strings.Repeat("a", 51), "", true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			tag, err := NormalizeTag(tC.tag)
+
+			if tC.expectedToGetError {
+				assert.Equal(t, ErrInvalidTag, err, "Got no error when expected")
+				return
+			}
+			assert.NoError(t, err, "Got error when not expected")
+			assert.Equal(t, tC.expected, tag)
+		})
+	}
+}
+
+func TestTags(t *testing.T) {
+	ctx := context.Background()
+
+	p := newTestPersistence(t)
+
+	user1, err := p.Create(ctx, Recipient{Name: "user1", Email: "user1@testmail.com"})
+	require.NoError(t, err, "Should have no error when creating a recipient")
+	user1, err = p.MarkVerified(ctx, user1.ID, user1.Email)
+	require.NoError(t, err, "Should have no error when verifying a recipient")
+	user2, err := p.Create(ctx, Recipient{Name: "user2", Email: "user2@testmail.com"})
+	require.NoError(t, err, "Should have no error when creating a recipient")
+
+	assert.NoError(t, p.AddTag(ctx, user1.ID, "devs"), "Should have no error when adding a tag")
+	assert.NoError(t, p.AddTag(ctx, user1.ID, "devs"), "Adding a tag twice should be a no-op")
+	assert.NoError(t, p.AddTag(ctx, user1.ID, "berlin"), "Should have no error when adding a tag")
+	assert.NoError(t, p.AddTag(ctx, user2.ID, "devs"), "Should have no error when adding a tag")
+	assert.Equal(t, ErrNotFound, p.AddTag(ctx, user1.ID+100, "devs"), "Unknown recipient should not be tagged")
+
+	tags, err := p.ListTags(ctx, user1.ID)
+	assert.NoError(t, err, "Should have no error when listing tags")
+	assert.Equal(t, []string{"berlin", "devs"}, tags, "Tags should be listed alphabetically")
+
+	tagged, err := p.TaggedRecipients(ctx, []string{"devs", "managers"})
+	assert.NoError(t, err, "Should have no error when fetching tagged recipients")
+	assert.Equal(t, []Recipient{*user1}, tagged, "Only verified tagged recipients should be returned")
+
+	assert.NoError(t, p.RemoveTag(ctx, user1.ID, "devs"), "Should have no error when removing a tag")
+	assert.Equal(t, ErrNotFound, p.RemoveTag(ctx, user1.ID, "devs"), "Should get ErrNotFound on second remove")
+}
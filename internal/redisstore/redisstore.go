@@ -0,0 +1,181 @@
+// internal/redisstore/redisstore.go
+
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"time"
+)
+
+// DefaultTimeout bounds each Redis call when Timeout is zero. The cache and
+// rate-limit interfaces take no context, so the stores bring their own.
+const DefaultTimeout = time.Second
+
+func withTimeout(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// Cache is a quote.Cache shared through Redis by every server instance.
+// Redis failures count as misses and are passed to OnError.
+type Cache struct {
+	Client  redis.UniversalClient
+	Prefix  string
+	Timeout time.Duration
+	OnError func(err error)
+}
+
+// Get ...
+func (c *Cache) Get(key string) (*quote.Quote, bool) {
+	ctx, cancel := withTimeout(c.Timeout)
+	defer cancel()
+
+	data, err := c.Client.Get(ctx, c.Prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false
+	}
+	if err != nil {
+		c.fail(err)
+		return nil, false
+	}
+
+	var q quote.Quote
+	if err := json.Unmarshal(data, &q); err != nil {
+		c.fail(err)
+		return nil, false
+	}
+
+	return &q, true
+}
+
+// Set ...
+func (c *Cache) Set(key string, q *quote.Quote, ttl time.Duration) {
+	data, err := json.Marshal(q)
+	if err != nil {
+		c.fail(err)
+		return
+	}
+
+	ctx, cancel := withTimeout(c.Timeout)
+	defer cancel()
+
+	if err := c.Client.Set(ctx, c.Prefix+key, data, ttl).Err(); err != nil {
+		c.fail(err)
+	}
+}
+
+// flushBatch is how many keys Flush scans for and deletes at once.
+const flushBatch = 100
+
+// Flush deletes the keys under Prefix. Unlike Get and Set it reports Redis
+// failures, and it is not bounded by Timeout since there may be many keys.
+func (c *Cache) Flush() error {
+	ctx := context.Background()
+
+	// Keys are collected before deleting any, as deleting while scanning
+	// may skip some.
+	var keys []string
+	iter := c.Client.Scan(ctx, 0, c.Prefix+"*", flushBatch).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return err
+	}
+
+	for len(keys) > 0 {
+		n := min(len(keys), flushBatch)
+		if err := c.Client.Del(ctx, keys[:n]...).Err(); err != nil {
+			return err
+		}
+		keys = keys[n:]
+	}
+	return nil
+}
+
+// Ping checks that Redis is reachable, so the cache can back a readiness check.
+func (c *Cache) Ping(ctx context.Context) error {
+	return c.Client.Ping(ctx).Err()
+}
+
+func (c *Cache) fail(err error) {
+	if c.OnError != nil {
+		c.OnError(err)
+	}
+}
+
+// takeScript is the token bucket of ratelimit.Memory run atomically in Redis.
+// Numbers travel as strings since Redis truncates Lua floats to integers.
+var takeScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens = tonumber(bucket[1]) or burst
+local last = tonumber(bucket[2]) or now
+
+tokens = math.min(burst, tokens + math.max(0, now - last) * rate)
+
+local allowed = 0
+local wait = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = (1 - tokens) / rate
+end
+
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'last', tostring(now))
+-- A bucket that has refilled completely behaves like a missing one.
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000))
+
+return {allowed, tostring(wait)}
+`)
+
+// RateLimit is a ratelimit.Store shared through Redis, so a client's budget
+// holds across server instances. Instances should keep their clocks in sync.
+type RateLimit struct {
+	Client  redis.UniversalClient
+	Prefix  string
+	Timeout time.Duration
+
+	now func() time.Time
+}
+
+// Take ...
+func (r *RateLimit) Take(key string, rate float64, burst int) (bool, time.Duration, error) {
+	now := time.Now
+	if r.now != nil {
+		now = r.now
+	}
+	seconds := float64(now().UnixNano()) / float64(time.Second)
+
+	ctx, cancel := withTimeout(r.Timeout)
+	defer cancel()
+
+	res, err := takeScript.Run(ctx, r.Client, []string{r.Prefix + key},
+		strconv.FormatFloat(rate, 'f', -1, 64), burst, strconv.FormatFloat(seconds, 'f', -1, 64)).Slice()
+	if err != nil {
+		return false, 0, err
+	}
+	if len(res) != 2 {
+		return false, 0, errors.New("redisstore: unexpected rate limit reply")
+	}
+
+	allowed, _ := res[0].(int64)
+	waitText, _ := res[1].(string)
+	wait, err := strconv.ParseFloat(waitText, 64)
+	if err != nil {
+		return false, 0, err
+	}
+
+	return allowed == 1, time.Duration(wait * float64(time.Second)), nil
+}
@@ -0,0 +1,98 @@
+// internal/redisstore/redisstore_test.go
+
+package redisstore
+
+import (
+	"context"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	return mr, client
+}
+
+func TestCache(t *testing.T) {
+	mr, client := newTestClient(t)
+	var errs []error
+	cache := Cache{Client: client, Prefix: "test:", OnError: func(err error) { errs = append(errs, err) }}
+
+	_, ok := cache.Get("en")
+	assert.False(t, ok, "Empty cache should miss")
+
+	q := &quote.Quote{Text: "Bla", Author: "Bob", Lang: "en"}
+	cache.Set("en", q, time.Minute)
+
+	got, ok := cache.Get("en")
+	assert.True(t, ok, "Cached quote should hit")
+	assert.Equal(t, q, got, "Cached quote is different than expected")
+	assert.True(t, mr.Exists("test:en"), "Key should be prefixed")
+
+	mr.FastForward(time.Minute)
+	_, ok = cache.Get("en")
+	assert.False(t, ok, "Expired quote should miss")
+	assert.Empty(t, errs, "Misses should not be reported as errors")
+
+	assert.NoError(t, cache.Ping(context.Background()), "Should ping a running Redis")
+
+	mr.Close()
+	_, ok = cache.Get("en")
+	assert.False(t, ok, "Unreachable Redis should miss")
+	assert.Len(t, errs, 1, "Redis failure should be reported")
+}
+
+func TestCache_Flush(t *testing.T) {
+	mr, client := newTestClient(t)
+	cache := Cache{Client: client, Prefix: "test:"}
+	for i := 0; i < flushBatch+1; i++ {
+		cache.Set(strconv.Itoa(i), &quote.Quote{Text: "Bla"}, time.Minute)
+	}
+	mr.Set("other", "kept")
+
+	assert.NoError(t, cache.Flush(), "Got error when not expected")
+	assert.Equal(t, []string{"other"}, mr.Keys(), "Only keys under the prefix should be deleted")
+}
+
+func TestRateLimit(t *testing.T) {
+	mr, client := newTestClient(t)
+	now := time.Unix(1560000000, 0)
+	store := RateLimit{Client: client, Prefix: "test:", now: func() time.Time { return now }}
+
+	testCases := []struct {
+		name            string
+		advance         time.Duration
+		expectedAllowed bool
+		expectedWait    time.Duration
+	}{
+		{"FirstOfBurst", 0, true, 0},
+		{"SecondOfBurst", 0, true, 0},
+		{"Exhausted", 0, false, 500 * time.Millisecond},
+		{"PartlyRefilled", 250 * time.Millisecond, false, 250 * time.Millisecond},
+		{"Refilled", 250 * time.Millisecond, true, 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			now = now.Add(tc.advance)
+
+			allowed, wait, err := store.Take("client", 2, 2)
+
+			assert.NoError(t, err, "Got error when not expected")
+			assert.Equal(t, tc.expectedAllowed, allowed, "Allowed is different than expected")
+			assert.InDelta(t, tc.expectedWait, wait, float64(time.Millisecond), "Retry after is different than expected")
+		})
+	}
+
+	assert.True(t, mr.Exists("test:client"), "Bucket key should be prefixed")
+	allowed, _, err := store.Take("other", 2, 2)
+	assert.NoError(t, err, "Got error when not expected")
+	assert.True(t, allowed, "Buckets should be per key")
+}
@@ -0,0 +1,126 @@
+// internal/replica/replica.go
+
+// Package replica sends read-only queries to read replicas of the database,
+// falling back to the primary while no replica is healthy.
+package replica
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// Replica is a read replica, named for logs and errors.
+type Replica struct {
+	Name string
+	DB   *sql.DB
+
+	healthy atomic.Bool
+}
+
+// Set is a primary and its read replicas. Replicas start out healthy; Check
+// and failed reads tell otherwise.
+type Set struct {
+	Primary  *sql.DB
+	Replicas []*Replica
+
+	next atomic.Uint64
+	mu   sync.Mutex
+}
+
+// New returns the set of primary, without replicas yet.
+func New(primary *sql.DB) *Set {
+	return &Set{Primary: primary}
+}
+
+// Add adds the replica db, named name.
+func (s *Set) Add(name string, db *sql.DB) {
+	r := &Replica{Name: name, DB: db}
+	r.healthy.Store(true)
+	s.Replicas = append(s.Replicas, r)
+}
+
+// reader returns the next healthy replica, in turn, or nil when none is.
+func (s *Set) reader() *Replica {
+	n := uint64(len(s.Replicas))
+	start := s.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		if r := s.Replicas[(start+i)%n]; r.healthy.Load() {
+			return r
+		}
+	}
+	return nil
+}
+
+// Read runs fn on a healthy replica, or on the primary when none is. A replica
+// fn cannot reach is taken out until the next Check, and fn runs again on the
+// primary, so replication lag is all callers have to tolerate. Other errors,
+// such as sql.ErrNoRows, are fn's own and are returned as they are. fn must
+// only read.
+func (s *Set) Read(ctx context.Context, fn func(db *sql.DB) error) error {
+	r := s.reader()
+	if r == nil {
+		return fn(s.Primary)
+	}
+
+	err := fn(r.DB)
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+	if !isConnError(err) && r.DB.PingContext(ctx) == nil {
+		return err
+	}
+	r.healthy.Store(false)
+	return fn(s.Primary)
+}
+
+// isConnError reports whether err tells the database could not be reached,
+// rather than that a query failed.
+func isConnError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) || errors.As(err, &netErr)
+}
+
+// Check pings every replica, bringing back those that answer and taking out
+// those that do not. It returns the errors of the replicas it takes out, so
+// each outage is reported once.
+func (s *Set) Check(ctx context.Context) error {
+	// One check at a time, so an outage is not reported twice.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	for _, r := range s.Replicas {
+		err := r.DB.PingContext(ctx)
+		if err != nil && r.healthy.Load() {
+			errs = append(errs, fmt.Errorf("replica %s: %w", r.Name, err))
+		}
+		r.healthy.Store(err == nil)
+	}
+	return errors.Join(errs...)
+}
+
+// Healthy returns the names of the replicas reads currently go to.
+func (s *Set) Healthy() []string {
+	names := []string{}
+	for _, r := range s.Replicas {
+		if r.healthy.Load() {
+			names = append(names, r.Name)
+		}
+	}
+	return names
+}
+
+// Close closes the replicas. The primary belongs to whoever opened it.
+func (s *Set) Close() error {
+	var errs []error
+	for _, r := range s.Replicas {
+		errs = append(errs, r.DB.Close())
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,94 @@
+// internal/replica/replica_test.go
+
+package replica
+
+import (
+	"context"
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+	"testing"
+)
+
+// newDB returns a database whose single row names it.
+func newDB(t *testing.T, name string) *sql.DB {
+	db, err := sql.Open("sqlite", ":memory:")
+	require.NoError(t, err, "Got error when not expected")
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	_, err = db.Exec("CREATE TABLE info (name TEXT)")
+	require.NoError(t, err, "Got error when not expected")
+	_, err = db.Exec("INSERT INTO info (name) VALUES ($1)", name)
+	require.NoError(t, err, "Got error when not expected")
+	return db
+}
+
+// readName returns the name of the database s reads from.
+func readName(t *testing.T, s *Set) string {
+	var name string
+	err := s.Read(context.Background(), func(db *sql.DB) error {
+		return db.QueryRow("SELECT name FROM info").Scan(&name)
+	})
+	require.NoError(t, err, "Got error when not expected")
+	return name
+}
+
+func TestSet_Read(t *testing.T) {
+	s := New(newDB(t, "primary"))
+	assert.Equal(t, "primary", readName(t, s), "Without replicas, reads should go to the primary")
+
+	s.Add("replica-1", newDB(t, "replica-1"))
+	s.Add("replica-2", newDB(t, "replica-2"))
+	seen := map[string]bool{}
+	for i := 0; i < 4; i++ {
+		seen[readName(t, s)] = true
+	}
+	assert.Equal(t, map[string]bool{"replica-1": true, "replica-2": true}, seen, "Reads should go to the replicas in turn")
+
+	s.Replicas[0].DB.Close()
+	for i := 0; i < 2; i++ {
+		assert.Contains(t, []string{"replica-2", "primary"}, readName(t, s), "Reads failing on a replica should be retried on the primary")
+	}
+	for i := 0; i < 4; i++ {
+		assert.Equal(t, "replica-2", readName(t, s), "Failed replicas should be taken out")
+	}
+
+	s.Replicas[1].DB.Close()
+	assert.Equal(t, "primary", readName(t, s), "Reads should fall back to the primary")
+	assert.Empty(t, s.Healthy())
+}
+
+func TestSet_ReadQueryError(t *testing.T) {
+	s := New(newDB(t, "primary"))
+	s.Add("replica-1", newDB(t, "replica-1"))
+
+	var reads []string
+	err := s.Read(context.Background(), func(db *sql.DB) error {
+		var name string
+		db.QueryRow("SELECT name FROM info").Scan(&name)
+		reads = append(reads, name)
+		return db.QueryRow("SELECT name FROM info WHERE name = 'nobody'").Scan(&name)
+	})
+	assert.ErrorIs(t, err, sql.ErrNoRows, "Query errors should be returned as they are")
+	assert.Equal(t, []string{"replica-1"}, reads, "Query errors should not be retried on the primary")
+	assert.Equal(t, []string{"replica-1"}, s.Healthy(), "Query errors should keep the replica in rotation")
+}
+
+func TestSet_Check(t *testing.T) {
+	s := New(newDB(t, "primary"))
+	s.Add("replica-1", newDB(t, "replica-1"))
+	s.Add("replica-2", newDB(t, "replica-2"))
+	s.Replicas[1].healthy.Store(false)
+
+	assert.NoError(t, s.Check(context.Background()), "Got error when not expected")
+	assert.Equal(t, []string{"replica-1", "replica-2"}, s.Healthy(), "Replicas answering should be brought back")
+
+	s.Replicas[0].DB.Close()
+	err := s.Check(context.Background())
+	assert.ErrorContains(t, err, "replica-1", "Replicas going down should be reported")
+	assert.Equal(t, []string{"replica-2"}, s.Healthy())
+
+	assert.NoError(t, s.Check(context.Background()), "Replicas still down should be reported once")
+}
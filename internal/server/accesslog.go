@@ -0,0 +1,174 @@
+// internal/server/accesslog.go
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/requestid"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Access log formats.
+const (
+	// AccessLogCombined is the Apache combined format followed by the
+	// latency in microseconds and the API key ID, "-" without a key.
+	AccessLogCombined = "combined"
+	// AccessLogJSON writes an accessLogEntry per line.
+	AccessLogJSON = "json"
+)
+
+// AccessLog configures the access log.
+type AccessLog struct {
+	Format string
+	Output io.Writer
+	// SampleRate is the share of requests logged, from 0 to 1. Server
+	// errors are always logged.
+	SampleRate float64
+}
+
+// accessLogger writes the access log lines, one request at a time.
+type accessLogger struct {
+	AccessLog
+	mu     sync.Mutex
+	random func() float64
+}
+
+type accessLogEntry struct {
+	Time       time.Time `json:"time"`
+	RemoteAddr string    `json:"remoteAddr"`
+	Method     string    `json:"method"`
+	URI        string    `json:"uri"`
+	Proto      string    `json:"proto"`
+	Status     int       `json:"status"`
+	Bytes      int64     `json:"bytes"`
+	LatencyMs  float64   `json:"latencyMs"`
+	Referer    string    `json:"referer,omitempty"`
+	UserAgent  string    `json:"userAgent,omitempty"`
+	APIKeyID   string    `json:"apiKeyId,omitempty"`
+	RequestID  string    `json:"requestId,omitempty"`
+
+	latency time.Duration
+}
+
+// WithAccessLog logs every request, or a sample of them, to l.Output.
+func WithAccessLog(l AccessLog) Option {
+	return func(s *Server) error {
+		if l.Format != AccessLogCombined && l.Format != AccessLogJSON {
+			return fmt.Errorf("server: unknown access log format %q, expected %s or %s", l.Format, AccessLogCombined, AccessLogJSON)
+		}
+		if l.SampleRate < 0 || l.SampleRate > 1 {
+			return fmt.Errorf("server: access log sample rate must be between 0 and 1, got %g", l.SampleRate)
+		}
+		if l.Output == nil {
+			return errors.New("server: access log output is nil")
+		}
+		s.accessLog = &accessLogger{AccessLog: l, random: rand.Float64}
+		return nil
+	}
+}
+
+// countingRecorder records the status and the size of a response.
+type countingRecorder struct {
+	statusRecorder
+	bytes int64
+}
+
+func (r *countingRecorder) Write(b []byte) (int, error) {
+	n, err := r.statusRecorder.Write(b)
+	r.bytes += int64(n)
+	return n, err
+}
+
+func (s *Server) withAccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.accessLog == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		rec := &countingRecorder{statusRecorder: statusRecorder{ResponseWriter: w}}
+
+		next.ServeHTTP(rec, r)
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		latency := time.Since(start)
+		s.accessLog.log(accessLogEntry{
+			Time:       start,
+			RemoteAddr: clientIP(r),
+			Method:     r.Method,
+			URI:        r.URL.RequestURI(),
+			Proto:      r.Proto,
+			Status:     rec.status,
+			Bytes:      rec.bytes,
+			LatencyMs:  float64(latency.Microseconds()) / 1000,
+			Referer:    r.Referer(),
+			UserAgent:  r.UserAgent(),
+			APIKeyID:   apiKeyID(r),
+			RequestID:  requestid.FromContext(r.Context()),
+			latency:    latency,
+		})
+	})
+}
+
+// log writes e unless it is sampled out.
+func (l *accessLogger) log(e accessLogEntry) {
+	if e.Status < http.StatusInternalServerError && l.SampleRate < 1 && l.random() >= l.SampleRate {
+		return
+	}
+
+	var line []byte
+	if l.Format == AccessLogJSON {
+		line, _ = json.Marshal(e)
+	} else {
+		line = combinedLine(e)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.Output.Write(append(line, '\n'))
+}
+
+// combinedLine formats e like Apache's
+// %h %l %u %t "%r" %>s %b "%{Referer}i" "%{User-Agent}i", then appends %D and
+// the API key ID.
+func combinedLine(e accessLogEntry) []byte {
+	size := "-"
+	if e.Bytes > 0 {
+		size = strconv.FormatInt(e.Bytes, 10)
+	}
+	keyID := "-"
+	if e.APIKeyID != "" {
+		keyID = e.APIKeyID
+	}
+
+	return []byte(fmt.Sprintf("%s - - [%s] %s %d %s %s %s %d %s",
+		e.RemoteAddr,
+		e.Time.Format("02/Jan/2006:15:04:05 -0700"),
+		quoteLogField(e.Method+" "+e.URI+" "+e.Proto),
+		e.Status,
+		size,
+		quoteLogField(e.Referer),
+		quoteLogField(e.UserAgent),
+		e.latency.Microseconds(),
+		keyID,
+	))
+}
+
+// quoteLogField quotes v with its quotes and control characters escaped, so
+// clients cannot forge log lines. Empty values are logged as "-".
+func quoteLogField(v string) string {
+	if v == "" {
+		return `"-"`
+	}
+	return strconv.Quote(v)
+}
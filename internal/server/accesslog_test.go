@@ -0,0 +1,101 @@
+// internal/server/accesslog_test.go
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/requestid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestAccessLog_Combined(t *testing.T) {
+	var out bytes.Buffer
+	svr := newTestServer(t, WithAccessLog(AccessLog{Format: AccessLogCombined, Output: &out, SampleRate: 1}))
+
+	req := asAdmin(httptest.NewRequest(http.MethodGet, "/admin/webhooks?x=1", nil))
+	req.RemoteAddr = "10.0.0.1:1234"
+	req.Header.Set("User-Agent", `curl/8.0 "evil"`)
+	req.Header.Set(APIKeyHeader, "s3cret")
+	rr := makeHTTPCall(svr.router, req)
+
+	line := strings.TrimSuffix(out.String(), "\n")
+	expected := `^10\.0\.0\.1 - - \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] "GET /admin/webhooks\?x=1 HTTP/1\.1" 404 ` +
+		regexp.QuoteMeta(strconv.Itoa(rr.Body.Len())) + ` "-" "curl/8\.0 \\"evil\\"" \d+ ` + apiKeyID(req) + `$`
+	assert.Regexp(t, expected, line, "Access log line is different than expected")
+	assert.NotContains(t, line, "s3cret", "The API key should not be logged")
+}
+
+func TestAccessLog_JSON(t *testing.T) {
+	var out bytes.Buffer
+	svr := newTestServer(t, WithAccessLog(AccessLog{Format: AccessLogJSON, Output: &out, SampleRate: 1}))
+
+	req := asAdmin(httptest.NewRequest(http.MethodGet, "/admin/webhooks", nil))
+	req.Header.Set("User-Agent", "curl/8.0")
+	req.Header.Set("Referer", "https://example.com/")
+	req.Header.Set(APIKeyHeader, "s3cret")
+	rr := makeHTTPCall(svr.router, req)
+
+	var entry accessLogEntry
+	require.NoError(t, json.Unmarshal(out.Bytes(), &entry), "Access log line should be JSON")
+	assert.Equal(t, http.MethodGet, entry.Method, "Method is different than expected")
+	assert.Equal(t, "/admin/webhooks", entry.URI, "URI is different than expected")
+	assert.Equal(t, http.StatusNotFound, entry.Status, "Status is different than expected")
+	assert.Equal(t, int64(rr.Body.Len()), entry.Bytes, "Bytes should be the body size")
+	assert.Equal(t, "curl/8.0", entry.UserAgent, "User agent is different than expected")
+	assert.Equal(t, "https://example.com/", entry.Referer, "Referer is different than expected")
+	assert.Equal(t, apiKeyID(req), entry.APIKeyID, "API key ID is different than expected")
+	assert.Equal(t, rr.Header().Get(requestid.Header), entry.RequestID, "Request ID is different than expected")
+	assert.GreaterOrEqual(t, entry.LatencyMs, 0.0, "Latency should be set")
+}
+
+func TestAccessLog_Sampling(t *testing.T) {
+	mockQuoteStore := MockQuoteStore{}
+	mockQuoteStore.On("ListQuotes", mock.Anything, mock.Anything).Return(nil, errors.New("sample error"))
+
+	var out bytes.Buffer
+	svr := newTestServer(t,
+		WithQuoteStore(&mockQuoteStore),
+		WithAccessLog(AccessLog{Format: AccessLogJSON, Output: &out, SampleRate: 0.1}),
+	)
+	svr.accessLog.random = func() float64 { return 0.5 }
+
+	makeHTTPCall(svr.router, asAdmin(httptest.NewRequest(http.MethodGet, "/admin/webhooks", nil)))
+	assert.Empty(t, out.String(), "Sampled out requests should not be logged")
+
+	makeHTTPCall(svr.router, httptest.NewRequest(http.MethodGet, "/quotes/feed.rss", nil))
+	assert.Contains(t, out.String(), `"status":503`, "Server errors should always be logged")
+
+	out.Reset()
+	svr.accessLog.random = func() float64 { return 0.05 }
+	makeHTTPCall(svr.router, asAdmin(httptest.NewRequest(http.MethodGet, "/admin/webhooks", nil)))
+	assert.Contains(t, out.String(), `"status":404`, "Sampled in requests should be logged")
+}
+
+func TestWithAccessLog_Invalid(t *testing.T) {
+	testCases := []struct {
+		desc string
+		log  AccessLog
+	}{
+		{"UnknownFormat", AccessLog{Format: "common", Output: &bytes.Buffer{}, SampleRate: 1}},
+		{"SampleRateTooHigh", AccessLog{Format: AccessLogJSON, Output: &bytes.Buffer{}, SampleRate: 1.5}},
+		{"NegativeSampleRate", AccessLog{Format: AccessLogJSON, Output: &bytes.Buffer{}, SampleRate: -1}},
+		{"NoOutput", AccessLog{Format: AccessLogJSON, SampleRate: 1}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			_, err := NewServer(WithAccessLog(tC.log))
+
+			assert.Error(t, err, "Invalid access log should be rejected")
+		})
+	}
+}
@@ -0,0 +1,182 @@
+// internal/server/admin.go
+
+package server
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/internal/auth"
+	"github.com/jeniok12/golang-tdd-example/internal/config"
+	"github.com/jeniok12/golang-tdd-example/scheduler"
+	"net/http"
+	"strings"
+)
+
+// outboxCounter is an outbox able to report its depth.
+type outboxCounter interface {
+	Counts(ctx context.Context) (map[string]int, error)
+}
+
+// OutboxResponse counts the outbox entries by status.
+type OutboxResponse struct {
+	Counts map[string]int `json:"counts"`
+}
+
+// JobRunResponse reports a scheduled job run through /admin.
+type JobRunResponse struct {
+	Job   string `json:"job"`
+	Error string `json:"error,omitempty"`
+}
+
+// WithAdminToken requires token as a bearer token on every /admin route.
+// Without it or WithJWT, the /admin routes are not served at all.
+func WithAdminToken(token string) Option {
+	return func(s *Server) error {
+		if token == "" {
+			return errors.New("server: admin token is empty")
+		}
+		s.adminToken = token
+		return nil
+	}
+}
+
+// WithConfig exposes cfg, secrets redacted, on /admin/config.
+func WithConfig(cfg *config.Config) Option {
+	return func(s *Server) error {
+		s.config = cfg
+		return nil
+	}
+}
+
+// adminEnabled reports whether admins can be told apart, so the /admin and
+// /debug routes can be served.
+func (s *Server) adminEnabled() bool {
+	return s.adminToken != "" || len(s.verifiers) > 0
+}
+
+// requireAdmin rejects requests without the admin token, or without the admin
+// role when tokens are JWTs.
+func (s *Server) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	if len(s.verifiers) > 0 {
+		return s.requireRole(auth.RoleAdmin, next)
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if s.adminToken == "" || !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			s.writeError(w, r, apierror.Unauthorized("a valid admin token is required"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) handleFlushCache() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.quoteCache == nil {
+			s.writeError(w, r, apierror.NotFound("no quote cache is configured"))
+			return
+		}
+
+		if err := s.quoteCache.Flush(); err != nil {
+			s.writeError(w, r, apierror.Internal(err))
+			return
+		}
+		s.logger.Info().Msg("quote cache flushed")
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// handleBreaker trips or resets the circuit breaker of the provider in the
+// path, as told by action.
+func (s *Server) handleBreaker(action string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		provider := mux.Vars(r)["provider"]
+		b, ok := s.breakers[provider]
+		if !ok {
+			s.writeError(w, r, apierror.NotFound("no circuit breaker for provider "+provider))
+			return
+		}
+
+		if action == "trip" {
+			b.Trip()
+		} else {
+			b.Reset()
+		}
+		s.logger.Info().Str("provider", provider).Str("action", action).Msg("circuit breaker changed by admin")
+
+		writeJSON(w, http.StatusOK, b.Stats())
+	}
+}
+
+// jobs returns the scheduled jobs by name.
+func (s *Server) jobs() map[string]*scheduler.Scheduler {
+	jobs := make(map[string]*scheduler.Scheduler)
+	if s.scheduler != nil {
+		jobs["daily-quote"] = s.scheduler
+	}
+	if s.outboxDrainer != nil {
+		jobs["outbox-drain"] = s.outboxDrainer
+	}
+	if s.idempotencyPurger != nil {
+		jobs["idempotency-purge"] = s.idempotencyPurger
+	}
+	if s.hookDrainer != nil {
+		jobs["webhook-drain"] = s.hookDrainer
+	}
+	return jobs
+}
+
+// handleRunJob runs a scheduled job now. A run already in progress is waited
+// for rather than overlapped.
+func (s *Server) handleRunJob() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := mux.Vars(r)["job"]
+		job, ok := s.jobs()[name]
+		if !ok {
+			s.writeError(w, r, apierror.NotFound("no scheduled job "+name))
+			return
+		}
+
+		resp := JobRunResponse{Job: name}
+		if err := job.Run(r.Context()); err != nil {
+			resp.Error = err.Error()
+		}
+
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+func (s *Server) handleOutboxDepth() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		counter, ok := s.outbox.(outboxCounter)
+		if !ok {
+			s.writeError(w, r, apierror.NotFound("the outbox is disabled"))
+			return
+		}
+
+		counts, err := counter.Counts(r.Context())
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, OutboxResponse{Counts: counts})
+	}
+}
+
+func (s *Server) handleConfig() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cfg := s.currentConfig()
+		if cfg == nil {
+			s.writeError(w, r, apierror.NotFound("no config to show"))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, cfg.Redacted())
+	}
+}
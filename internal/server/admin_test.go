@@ -0,0 +1,186 @@
+// internal/server/admin_test.go
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/internal/config"
+	"github.com/jeniok12/golang-tdd-example/internal/memstore"
+	"github.com/jeniok12/golang-tdd-example/internal/outbox"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/mailer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRequireAdmin(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		adminToken     string
+		authorization  string
+		expectedStatus int
+	}{
+		{"NoTokenConfigured", "", "Bearer s3cret", http.StatusNotFound},
+		{"ValidToken", "s3cret", "Bearer s3cret", http.StatusOK},
+		{"WrongToken", "s3cret", "Bearer guess", http.StatusUnauthorized},
+		{"NotBearer", "s3cret", "Basic s3cret", http.StatusUnauthorized},
+		{"Missing", "s3cret", "", http.StatusUnauthorized},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			opts := []Option{
+				WithQuoteGenerator(&MockQuoteGenerator{}),
+				WithRecipientsFetcher(&MockRecipientsFetcher{}),
+				WithConfig(config.Default()),
+			}
+			if tC.adminToken != "" {
+				opts = append(opts, WithAdminToken(tC.adminToken))
+			}
+			svr, err := NewServer(opts...)
+			require.NoError(t, err, "Should have no error when building the server")
+
+			req := httptest.NewRequest("GET", "/admin/config", nil)
+			if tC.authorization != "" {
+				req.Header.Set("Authorization", tC.authorization)
+			}
+			rr := makeHTTPCall(svr.router, req)
+			assert.Equal(t, tC.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			if tC.expectedStatus == http.StatusUnauthorized {
+				assert.Equal(t, `Bearer realm="admin"`, rr.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
+
+func TestHandleFlushCache(t *testing.T) {
+	lru := quote.NewLRU(10)
+	lru.Set("en", &quote.Quote{Text: "Bla"}, time.Minute)
+
+	testCases := []struct {
+		desc           string
+		opts           []Option
+		expectedStatus int
+	}{
+		{"Flushed", []Option{WithQuoteCache(&quote.Cached{Cache: lru})}, http.StatusNoContent},
+		{"NoCache", nil, http.StatusNotFound},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			svr := newTestServer(t, tC.opts...)
+
+			req := asAdmin(httptest.NewRequest("POST", "/admin/cache/flush", nil))
+			rr := makeHTTPCall(svr.router, req)
+			assert.Equal(t, tC.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+		})
+	}
+	assert.Equal(t, 0, lru.Len(), "Cache should be flushed")
+}
+
+func TestHandleBreaker(t *testing.T) {
+	breaker := &quote.Breaker{Inner: &MockQuoteGenerator{}, Threshold: 5, Cooldown: time.Minute}
+	svr := newTestServer(t, WithBreakers(map[string]*quote.Breaker{"forismatic": breaker}))
+
+	testCases := []struct {
+		desc           string
+		path           string
+		expectedStatus int
+		expectedState  string
+	}{
+		{"Trip", "/admin/breakers/forismatic/trip", http.StatusOK, quote.StateOpen},
+		{"Reset", "/admin/breakers/forismatic/reset", http.StatusOK, quote.StateClosed},
+		{"UnknownProvider", "/admin/breakers/zenquotes/trip", http.StatusNotFound, quote.StateClosed},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			req := asAdmin(httptest.NewRequest("POST", tC.path, nil))
+			rr := makeHTTPCall(svr.router, req)
+			assert.Equal(t, tC.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			assert.Equal(t, tC.expectedState, breaker.Stats().State)
+		})
+	}
+}
+
+func TestHandleRunJob(t *testing.T) {
+	store := memstore.NewOutbox()
+	store.Enqueue(context.Background(), []mailer.Message{{To: "user1@testmail.com"}})
+	mockMailSender := MockMailSender{}
+	mockMailSender.On("Send", mailer.Message{To: "user1@testmail.com"}).Return(errors.New("sample error"))
+	w := &outbox.Worker{Store: store, Sender: &mockMailSender}
+
+	svr := newTestServer(t, WithOutbox(store, w, time.Hour))
+
+	testCases := []struct {
+		desc             string
+		job              string
+		expectedStatus   int
+		expectedResponse JobRunResponse
+	}{
+		{"OutboxDrain", "outbox-drain", http.StatusOK, JobRunResponse{Job: "outbox-drain"}},
+		{"NotScheduled", "daily-quote", http.StatusNotFound, JobRunResponse{}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			req := asAdmin(httptest.NewRequest("POST", "/admin/jobs/"+tC.job+"/run", nil))
+			rr := makeHTTPCall(svr.router, req)
+			assert.Equal(t, tC.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			if tC.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp JobRunResponse
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp), "Got error when not expected")
+			assert.Equal(t, tC.expectedResponse, resp)
+		})
+	}
+	mockMailSender.AssertExpectations(t)
+	assert.Equal(t, 1, store.Entries()[0].Attempts, "The drain should have run")
+}
+
+func TestHandleOutboxDepth(t *testing.T) {
+	store := memstore.NewOutbox()
+	store.Enqueue(context.Background(), []mailer.Message{{To: "user1@testmail.com"}, {To: "user2@testmail.com"}})
+
+	testCases := []struct {
+		desc           string
+		opts           []Option
+		expectedStatus int
+		expectedCounts map[string]int
+	}{
+		{"Outbox", []Option{WithOutbox(store, &outbox.Worker{Store: store}, time.Hour)}, http.StatusOK, map[string]int{"pending": 2, "sent": 0, "failed": 0}},
+		{"NoOutbox", nil, http.StatusNotFound, nil},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			svr := newTestServer(t, tC.opts...)
+
+			req := asAdmin(httptest.NewRequest("GET", "/admin/outbox", nil))
+			rr := makeHTTPCall(svr.router, req)
+			assert.Equal(t, tC.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			if tC.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp OutboxResponse
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp), "Got error when not expected")
+			assert.Equal(t, tC.expectedCounts, resp.Counts)
+		})
+	}
+}
+
+func TestHandleConfig(t *testing.T) {
+	cfg := config.Default()
+	cfg.SMTP.Password = "smtp-secret"
+	svr := newTestServer(t, WithConfig(cfg))
+
+	req := asAdmin(httptest.NewRequest("GET", "/admin/config", nil))
+	rr := makeHTTPCall(svr.router, req)
+	assert.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+	assert.NotContains(t, rr.Body.String(), "smtp-secret", "Secrets should be redacted")
+	assert.Contains(t, rr.Body.String(), `"password":"[redacted]"`)
+}
@@ -0,0 +1,167 @@
+// internal/server/apikeys.go
+
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/internal/audit"
+	"github.com/jeniok12/golang-tdd-example/internal/verify"
+	"github.com/jeniok12/golang-tdd-example/mailer"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// WithKeyRotation lets POST /admin/apikeys/{id}/rotate give a tenant key a
+// new key, the previous one still working for grace.
+func WithKeyRotation(grace time.Duration) Option {
+	return func(s *Server) error {
+		if grace < 0 {
+			return fmt.Errorf("server: negative key rotation grace %s", grace)
+		}
+		s.keyRotationGrace = grace
+		return nil
+	}
+}
+
+// apiKey is an X-API-Key bound to a tenant by WithTenantKeys.
+type apiKey struct {
+	// ID names the key in logs, the audit log, rate limits and ratings. A
+	// rotated key keeps the ID of the key it replaces, so its client stays the
+	// same client.
+	ID     string
+	Tenant string
+	// Owner is emailed when the key is rotated, if set.
+	Owner string
+	// ExpiresAt is set once the key is rotated away; it works until then.
+	ExpiresAt *time.Time
+}
+
+// expired reports whether k was rotated away and no longer works at now.
+func (k apiKey) expired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
+
+// apiKeys are the tenant keys of the server, by key. Rotated keys are only
+// kept in memory, so the new key must replace the old one in the config
+// before the server restarts.
+type apiKeys struct {
+	mu   sync.RWMutex
+	keys map[string]*apiKey
+}
+
+// add binds key to tenant, for owner.
+func (k *apiKeys) add(key, tenant, owner string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if k.keys == nil {
+		k.keys = map[string]*apiKey{}
+	}
+	k.keys[key] = &apiKey{ID: keyID(key), Tenant: tenant, Owner: owner}
+}
+
+// lookup returns the API key key, if the server knows it.
+func (k *apiKeys) lookup(key string) (apiKey, bool) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	if a, ok := k.keys[key]; ok {
+		return *a, true
+	}
+	return apiKey{}, false
+}
+
+// rotate replaces the key named id with a new random one and returns it. The
+// replaced key works for grace from now, and one rotated before stops at
+// once.
+func (k *apiKeys) rotate(id string, grace time.Duration, now time.Time) (string, apiKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	var current *apiKey
+	for _, a := range k.keys {
+		switch {
+		case a.ID != id:
+		case a.ExpiresAt == nil:
+			current = a
+		case now.Before(*a.ExpiresAt):
+			a.ExpiresAt = &now
+		}
+	}
+	if current == nil {
+		return "", apiKey{}, apierror.NotFound("no API key " + id)
+	}
+
+	b, err := verify.RandomKey()
+	if err != nil {
+		return "", apiKey{}, err
+	}
+	key := hex.EncodeToString(b)
+	rotated := &apiKey{ID: current.ID, Tenant: current.Tenant, Owner: current.Owner}
+	k.keys[key] = rotated
+
+	expires := now.Add(grace).UTC()
+	current.ExpiresAt = &expires
+	return key, *current, nil
+}
+
+// keyID fingerprints key, so logs can name it without holding the secret.
+func keyID(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:8])
+}
+
+type apiKeyIDCtxKey struct{}
+
+// withAPIKeyID returns a copy of ctx naming the caller's API key by id.
+func withAPIKeyID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, apiKeyIDCtxKey{}, id)
+}
+
+// RotateAPIKeyResponse is the API key rotated. The new key is only ever
+// shown here.
+type RotateAPIKeyResponse struct {
+	ID     string `json:"id"`
+	Tenant string `json:"tenant"`
+	Key    string `json:"key"`
+	// PreviousKeyExpiresAt is when the replaced key stops working.
+	PreviousKeyExpiresAt time.Time `json:"previousKeyExpiresAt"`
+}
+
+func (s *Server) handleRotateAPIKey() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key, previous, err := s.apiKeys.rotate(mux.Vars(r)["id"], s.keyRotationGrace, s.now())
+		if err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+		s.auditKey(r, audit.ActionUpdate, audit.EntityAPIKey, previous.ID)
+		s.logger.Info().Str("apiKey", previous.ID).Str("tenant", previous.Tenant).Msg("api key rotated")
+		s.mailKeyRotation(previous)
+
+		writeJSON(w, http.StatusOK, RotateAPIKeyResponse{ID: previous.ID, Tenant: previous.Tenant, Key: key, PreviousKeyExpiresAt: *previous.ExpiresAt})
+	}
+}
+
+// mailKeyRotation tells the owner of k that it was rotated, if it has one.
+// Failing to is logged; the key is rotated anyway.
+func (s *Server) mailKeyRotation(k apiKey) {
+	if k.Owner == "" || s.mailSender == nil {
+		return
+	}
+	msg := mailer.Message{
+		To:      k.Owner,
+		Subject: "Your inspiring quotes API key was rotated",
+		Body: fmt.Sprintf("Hi,\n\nThe API key %s of tenant %s was rotated. The previous key keeps working until %s.\nAsk an admin for the new one.\n",
+			k.ID, k.Tenant, k.ExpiresAt.Format(time.RFC1123)),
+	}
+	if err := s.mailSender.Send(msg); err != nil {
+		s.logger.Error().Err(err).Str("apiKey", k.ID).Msg("sending rotation email")
+	}
+}
@@ -0,0 +1,117 @@
+// internal/server/apikeys_test.go
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/jeniok12/golang-tdd-example/internal/audit"
+	"github.com/jeniok12/golang-tdd-example/internal/memstore"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/jeniok12/golang-tdd-example/mailer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotateAPIKey(t *testing.T) {
+	recipients := memstore.NewRecipients(memstore.NewQuotes())
+	auditLog := memstore.NewAudit()
+	mockMailSender := MockMailSender{}
+	mockMailSender.On("Send", mock.MatchedBy(func(msg mailer.Message) bool {
+		return msg.To == "ops@acme.com" && strings.Contains(msg.Body, "keeps working until")
+	})).Return(nil).Once()
+	svr := newTestServer(t,
+		WithRecipientsFetcher(recipients),
+		WithRecipientStore(recipients),
+		WithTenantKeys("acme-key:acme:ops@acme.com"),
+		WithKeyRotation(time.Hour),
+		WithAuditLog(auditLog),
+		WithMailSender(&mockMailSender),
+	)
+	now := time.Date(2019, 10, 20, 10, 0, 0, 0, time.UTC)
+	svr.now = func() time.Time { return now }
+
+	req := httptest.NewRequest(http.MethodPost, "/recipients", strings.NewReader(`{"name":"alice","email":"alice@testmail.com"}`))
+	req.Header.Set(APIKeyHeader, "acme-key")
+	require.Equal(t, http.StatusCreated, makeHTTPCall(svr.router, req).Code, "Response HTTP status in different than expected")
+
+	// listed returns the status of listing the recipients with key, and how
+	// many were listed.
+	listed := func(key string) (int, int) {
+		req := httptest.NewRequest(http.MethodGet, "/recipients", nil)
+		req.Header.Set(APIKeyHeader, key)
+		rr := makeHTTPCall(svr.router, req)
+		var rs []recipient.Recipient
+		json.Unmarshal(rr.Body.Bytes(), &rs)
+		return rr.Code, len(rs)
+	}
+
+	id := keyID("acme-key")
+	rr := makeHTTPCall(svr.router, httptest.NewRequest(http.MethodPost, "/admin/apikeys/"+id+"/rotate", nil))
+	assert.Equal(t, http.StatusUnauthorized, rr.Code, "Only admins should rotate keys")
+
+	rr = makeHTTPCall(svr.router, asAdmin(httptest.NewRequest(http.MethodPost, "/admin/apikeys/"+id+"/rotate", nil)))
+	require.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+	var rotated RotateAPIKeyResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &rotated), "Got error when not expected")
+	assert.Equal(t, id, rotated.ID, "The new key should keep the ID of the old one")
+	assert.Equal(t, "acme", rotated.Tenant)
+	assert.NotEmpty(t, rotated.Key, "A new key should be issued")
+	assert.NotEqual(t, "acme-key", rotated.Key, "A new key should be issued")
+	assert.Equal(t, now.Add(time.Hour), rotated.PreviousKeyExpiresAt, "The end of the grace period should be reported")
+
+	for _, key := range []string{"acme-key", rotated.Key} {
+		status, n := listed(key)
+		assert.Equal(t, http.StatusOK, status, "Both keys should work during the grace period")
+		assert.Equal(t, 1, n, "Both keys should act for the tenant")
+	}
+	key, ok := svr.apiKeys.lookup(rotated.Key)
+	require.True(t, ok, "The new key should be known")
+	assert.Equal(t, "key:"+id, clientKey(httptest.NewRequest(http.MethodGet, "/", nil).WithContext(withAPIKeyID(context.Background(), key.ID))),
+		"Rate limits should follow the key across rotations")
+
+	now = now.Add(time.Hour)
+	status, _ := listed("acme-key")
+	assert.Equal(t, http.StatusUnauthorized, status, "The previous key should stop working past the grace period")
+	status, n := listed(rotated.Key)
+	assert.Equal(t, http.StatusOK, status, "The new key should keep working")
+	assert.Equal(t, 1, n)
+
+	entries, err := auditLog.List(context.Background(), audit.Filter{Entity: audit.EntityAPIKey, Limit: 10})
+	require.NoError(t, err, "Got error when not expected")
+	require.Len(t, entries, 1, "The rotation should be audited")
+	assert.Equal(t, audit.ActionUpdate, entries[0].Action)
+	assert.Equal(t, id, entries[0].EntityKey, "The key should be named by its ID")
+	mockMailSender.AssertExpectations(t)
+
+	rr = makeHTTPCall(svr.router, asAdmin(httptest.NewRequest(http.MethodPost, "/admin/apikeys/unknown/rotate", nil)))
+	assert.Equal(t, http.StatusNotFound, rr.Code, "Response HTTP status in different than expected")
+}
+
+func TestAPIKeys_RotateTwice(t *testing.T) {
+	var keys apiKeys
+	keys.add("acme-key", "acme", "")
+	now := time.Date(2019, 10, 20, 10, 0, 0, 0, time.UTC)
+
+	second, _, err := keys.rotate(keyID("acme-key"), time.Hour, now)
+	require.NoError(t, err, "Got error when not expected")
+	third, _, err := keys.rotate(keyID("acme-key"), time.Hour, now)
+	require.NoError(t, err, "Got error when not expected")
+
+	for key, expired := range map[string]bool{"acme-key": true, second: false, third: false} {
+		k, ok := keys.lookup(key)
+		require.True(t, ok, "Rotated keys should still be known")
+		assert.Equal(t, expired, k.expired(now), "Only the last previous key should be kept for %s", key)
+	}
+
+	_, _, err = keys.rotate(keyID("acme-key"), 0, now)
+	require.NoError(t, err, "Got error when not expected")
+	k, _ := keys.lookup(third)
+	assert.True(t, k.expired(now), "Without grace the previous key should stop at once")
+}
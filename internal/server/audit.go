@@ -0,0 +1,138 @@
+// internal/server/audit.go
+
+package server
+
+import (
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/internal/audit"
+	"github.com/jeniok12/golang-tdd-example/internal/auth"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAuditLimit = 100
+	maxAuditLimit     = 1000
+)
+
+// WithAuditLog records every mutation made through the API in store and
+// serves the log on /audit.
+func WithAuditLog(store audit.Store) Option {
+	return func(s *Server) error {
+		s.auditLog = store
+		return nil
+	}
+}
+
+// actor identifies the caller by the subject of their token, or by a
+// fingerprint of their API key, so the log holds no secrets, falling back to
+// the client IP.
+func actor(r *http.Request) string {
+	if p := auth.FromContext(r.Context()); p != nil {
+		return "sub:" + p.Subject
+	}
+	if id := apiKeyID(r); id != "" {
+		return "key:" + id
+	}
+
+	return "ip:" + clientIP(r)
+}
+
+// apiKeyID returns the ID of the caller's API key, or "" without one. Logs
+// name keys by their ID rather than by the secret itself. Tenant keys keep
+// their ID across rotations; other keys are fingerprinted.
+func apiKeyID(r *http.Request) string {
+	if id, ok := r.Context().Value(apiKeyIDCtxKey{}).(string); ok {
+		return id
+	}
+	key := r.Header.Get(APIKeyHeader)
+	if key == "" {
+		return ""
+	}
+	return keyID(key)
+}
+
+// auditEntry records that the caller applied action to the entity.
+func auditEntry(r *http.Request, action, entity string, id int) audit.Entry {
+	return audit.Entry{
+		Actor:    actor(r),
+		Action:   action,
+		Entity:   entity,
+		EntityID: id,
+		At:       time.Now(),
+	}
+}
+
+// audit records that the caller applied action to the entity. The mutation
+// has already happened, so failing to record it is logged rather than
+// failing the request.
+func (s *Server) audit(r *http.Request, action, entity string, id int) {
+	if s.auditLog == nil {
+		return
+	}
+
+	if err := s.auditLog.Record(r.Context(), auditEntry(r, action, entity, id)); err != nil {
+		s.logger.Error().Err(err).Str("entity", entity).Int("id", id).Msg("recording audit entry")
+	}
+}
+
+// auditKey is audit for entities identified by a key rather than a number.
+func (s *Server) auditKey(r *http.Request, action, entity, key string) {
+	if s.auditLog == nil {
+		return
+	}
+
+	e := auditEntry(r, action, entity, 0)
+	e.EntityKey = key
+	if err := s.auditLog.Record(r.Context(), e); err != nil {
+		s.logger.Error().Err(err).Str("entity", entity).Str("key", key).Msg("recording audit entry")
+	}
+}
+
+// auditTx records the entry in the audit log of a transaction, so the
+// mutation is rolled back when it cannot be recorded.
+func auditTx(r *http.Request, repos TxRepositories, action, entity string, id int) error {
+	if repos.Audit == nil {
+		return nil
+	}
+	return repos.Audit.Record(r.Context(), auditEntry(r, action, entity, id))
+}
+
+func (s *Server) handleAuditLog() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		filter := audit.Filter{Entity: query.Get("entity")}
+		if filter.Entity != "" && !slices.Contains(audit.Entities, filter.Entity) {
+			s.writeError(w, r, apierror.BadRequest(apierror.CodeBadRequest, "entity must be one of "+strings.Join(audit.Entities, ", ")))
+			return
+		}
+
+		if since := query.Get("since"); since != "" {
+			t, err := time.Parse(time.RFC3339, since)
+			if err != nil {
+				s.writeError(w, r, apierror.BadRequest(apierror.CodeBadRequest, "since must be an RFC 3339 time"))
+				return
+			}
+			filter.Since = t
+		}
+
+		limit, err := intParam(query.Get("limit"), defaultAuditLimit)
+		if err != nil || limit < 1 || limit > maxAuditLimit {
+			s.writeError(w, r, apierror.BadRequest(apierror.CodeBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxAuditLimit)))
+			return
+		}
+		filter.Limit = limit
+
+		entries, err := s.auditLog.List(r.Context(), filter)
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, entries)
+	}
+}
@@ -0,0 +1,94 @@
+// internal/server/audit_test.go
+
+package server
+
+import (
+	"encoding/json"
+	"github.com/jeniok12/golang-tdd-example/internal/audit"
+	"github.com/jeniok12/golang-tdd-example/internal/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	recipients := memstore.NewRecipients(memstore.NewQuotes())
+	svr := newTestServer(t,
+		WithRecipientStore(recipients),
+		WithGroupStore(recipients),
+		WithAuditLog(memstore.NewAudit()),
+	)
+
+	calls := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPost, "/recipients", `{"name":"user1","email":"user1@testmail.com"}`},
+		{http.MethodPost, "/groups", `{"name":"team"}`},
+		{http.MethodPut, "/groups/1/members/1", ""},
+		{http.MethodDelete, "/recipients/1", ""},
+		{http.MethodDelete, "/recipients/1", ""},
+	}
+	for _, c := range calls {
+		req := httptest.NewRequest(c.method, c.path, strings.NewReader(c.body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(APIKeyHeader, "secret")
+		makeHTTPCall(svr.router, req)
+	}
+
+	testCases := []struct {
+		desc            string
+		query           string
+		expectedStatus  int
+		expectedActions []string
+	}{
+		{"All", "", http.StatusOK, []string{"delete recipient", "update group", "create group", "create recipient"}},
+		{"Entity", "?entity=recipient", http.StatusOK, []string{"delete recipient", "create recipient"}},
+		{"Limit", "?limit=1", http.StatusOK, []string{"delete recipient"}},
+		{"Since", "?since=2999-01-01T00:00:00Z", http.StatusOK, []string{}},
+		{"UnknownEntity", "?entity=favorite", http.StatusBadRequest, nil},
+		{"InvalidSince", "?since=yesterday", http.StatusBadRequest, nil},
+		{"InvalidLimit", "?limit=0", http.StatusBadRequest, nil},
+	}
+	rr := makeHTTPCall(svr.router, httptest.NewRequest(http.MethodGet, "/admin/audit", nil))
+	assert.Equal(t, http.StatusUnauthorized, rr.Code, "The audit log should only be served to admins")
+
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			rr := makeHTTPCall(svr.router, asAdmin(httptest.NewRequest(http.MethodGet, "/admin/audit"+tC.query, nil)))
+			require.Equal(t, tC.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			if tC.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var entries []audit.Entry
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &entries), "Got error when not expected")
+
+			actions := []string{}
+			for _, e := range entries {
+				actions = append(actions, e.Action+" "+e.Entity)
+				assert.Regexp(t, "^key:[0-9a-f]{16}$", e.Actor, "The actor should be a fingerprint of the API key")
+			}
+			assert.Equal(t, tC.expectedActions, actions)
+		})
+	}
+}
+
+func TestActor(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Equal(t, "ip:192.0.2.1", actor(req), "Callers without an API key should be identified by IP")
+
+	req.Header.Set(APIKeyHeader, "secret")
+	assert.Equal(t, "key:2bb80d537b1da3e3", actor(req), "Callers should be identified by a fingerprint of their API key")
+}
+
+func TestAuditLog_Disabled(t *testing.T) {
+	svr := newTestServer(t)
+
+	rr := makeHTTPCall(svr.router, asAdmin(httptest.NewRequest(http.MethodGet, "/admin/audit", nil)))
+	assert.Equal(t, http.StatusNotFound, rr.Code, "Response HTTP status in different than expected")
+}
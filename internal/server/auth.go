@@ -0,0 +1,133 @@
+// internal/server/auth.go
+
+package server
+
+import (
+	"crypto/subtle"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/internal/auth"
+	"github.com/jeniok12/golang-tdd-example/openapi"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// WithJWT requires a bearer JWT checked by v on every route but the public
+// ones, and a role allowing the route: reader to read, editor to change
+// recipients or send quotes, admin for the admin routes. The admin token, if
+// set, still acts as an admin.
+func WithJWT(v *auth.Verifier) Option {
+	return func(s *Server) error {
+		if v.Issuer == "" || v.Keys == nil {
+			return errors.New("server: JWT verification needs an issuer and its keys")
+		}
+		s.verifiers = append(s.verifiers, v)
+		return nil
+	}
+}
+
+// publicPaths take no token. They are called by probes, by the links in
+// emails, by the mail provider and by OAuth2 clients getting a token, which
+// have their own safeguards.
+var publicPaths = map[string]bool{
+	"/healthz":           true,
+	"/readyz":            true,
+	"/recipients/verify": true,
+	"/unsubscribe":       true,
+	"/webhooks/email":    true,
+	"/oauth/token":       true,
+	"/oauth/revoke":      true,
+}
+
+// routeRole is the role op takes, empty for public routes.
+func routeRole(op openapi.Operation) auth.Role {
+	switch {
+	case publicPaths[op.Path]:
+		return ""
+	case adminRoute(op):
+		return auth.RoleAdmin
+	case op.Method == http.MethodGet:
+		return auth.RoleReader
+	default:
+		return auth.RoleEditor
+	}
+}
+
+// adminRoute reports whether op is for admins only: it is under /admin or
+// tagged admin.
+func adminRoute(op openapi.Operation) bool {
+	return strings.HasPrefix(op.Path, "/admin/") || slices.Contains(op.Tags, "admin")
+}
+
+// withAuth puts the principal of the request's bearer token in its context.
+// Requests without a token stay anonymous, for requireRole to turn away.
+func (s *Server) withAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if len(s.verifiers) == 0 || !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		p, err := s.authenticate(r, token)
+		if errors.Is(err, auth.ErrInvalidToken) || errors.Is(err, auth.ErrExpiredToken) || errors.Is(err, auth.ErrRevokedToken) {
+			w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			s.writeError(w, r, apierror.Unauthorized(err.Error()))
+			return
+		}
+		if err != nil {
+			s.writeError(w, r, apierror.Wrap(err, http.StatusServiceUnavailable, apierror.CodeUnavailable, "cannot check tokens right now, retry later"))
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), p)))
+	})
+}
+
+// authenticate returns the principal of token: the admin if it is the admin
+// token, else whom the first verifier of its issuer says it is.
+func (s *Server) authenticate(r *http.Request, token string) (*auth.Principal, error) {
+	if s.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) == 1 {
+		return &auth.Principal{Subject: "admin-token", Role: auth.RoleAdmin}, nil
+	}
+
+	for _, v := range s.verifiers {
+		p, err := v.Verify(r.Context(), token)
+		if !errors.Is(err, auth.ErrInvalidToken) {
+			return p, err
+		}
+	}
+	return nil, auth.ErrInvalidToken
+}
+
+// isAdmin reports whether r is made by an admin, with the admin token or a
+// token of the admin role.
+func (s *Server) isAdmin(r *http.Request) bool {
+	if p := auth.FromContext(r.Context()); p != nil {
+		return p.Role.Allows(auth.RoleAdmin)
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && s.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.adminToken)) == 1
+}
+
+// requireRole rejects requests whose token does not allow role. Without a
+// role, the route is public.
+func (s *Server) requireRole(role auth.Role, next http.HandlerFunc) http.HandlerFunc {
+	if role == "" {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		p := auth.FromContext(r.Context())
+		if p == nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			s.writeError(w, r, apierror.Unauthorized("a bearer token is required"))
+			return
+		}
+		if !p.Role.Allows(role) {
+			s.writeError(w, r, apierror.Forbidden("the "+string(role)+" role is required"))
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,98 @@
+// internal/server/auth_test.go
+
+package server
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/jeniok12/golang-tdd-example/internal/auth"
+	"github.com/jeniok12/golang-tdd-example/internal/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testKeys struct {
+	key *rsa.PublicKey
+}
+
+func (k testKeys) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	return k.key, nil
+}
+
+// signJWT returns an RS256 token of the test issuer granting role.
+func signJWT(t *testing.T, key *rsa.PrivateKey, role string) string {
+	segment := func(v interface{}) string {
+		b, err := json.Marshal(v)
+		require.NoError(t, err, "Got error when not expected")
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	signed := segment(map[string]string{"alg": "RS256", "typ": "JWT"}) + "." + segment(map[string]interface{}{
+		"iss":   "https://issuer.test/",
+		"sub":   role + "-user",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"roles": []string{role},
+	})
+	digest := sha256.Sum256([]byte(signed))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err, "Got error when not expected")
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestJWTAuth(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err, "Got error when not expected")
+	recipients := memstore.NewRecipients(memstore.NewQuotes())
+	svr := newTestServer(t,
+		WithRecipientsFetcher(recipients),
+		WithRecipientStore(recipients),
+		WithAdminToken("admin-secret"),
+		WithJWT(&auth.Verifier{Issuer: "https://issuer.test/", Keys: testKeys{&key.PublicKey}}),
+	)
+	reader, editor, admin := signJWT(t, key, "reader"), signJWT(t, key, "editor"), signJWT(t, key, "admin")
+
+	testCases := []struct {
+		desc           string
+		method         string
+		path           string
+		token          string
+		expectedStatus int
+	}{
+		{"Anonymous", http.MethodGet, "/recipients", "", http.StatusUnauthorized},
+		{"InvalidToken", http.MethodGet, "/recipients", "not-a-token", http.StatusUnauthorized},
+		{"PublicRoute", http.MethodGet, "/healthz", "", http.StatusOK},
+		{"ReaderReads", http.MethodGet, "/recipients", reader, http.StatusOK},
+		{"ReaderCreates", http.MethodPost, "/recipients", reader, http.StatusForbidden},
+		{"ReaderSends", http.MethodPost, "/quote/send", reader, http.StatusForbidden},
+		{"EditorCreates", http.MethodPost, "/recipients", editor, http.StatusCreated},
+		{"EditorAdministers", http.MethodGet, "/admin/webhooks", editor, http.StatusForbidden},
+		{"AdminAdministers", http.MethodGet, "/admin/webhooks", admin, http.StatusNotFound},
+		{"AdminToken", http.MethodGet, "/admin/webhooks", "admin-secret", http.StatusNotFound},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			req := httptest.NewRequest(tC.method, tC.path, strings.NewReader(`{"name":"alice","email":"alice@testmail.com"}`))
+			if tC.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tC.token)
+			}
+
+			rr := makeHTTPCall(svr.router, req)
+
+			assert.Equal(t, tC.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+		})
+	}
+}
+
+func TestWithJWT_Invalid(t *testing.T) {
+	_, err := NewServer(WithJWT(&auth.Verifier{Keys: testKeys{}}))
+	assert.Error(t, err, "An issuer should be required")
+}
@@ -0,0 +1,108 @@
+// internal/server/author.go
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"strings"
+)
+
+// defaultAuthorAttempts is how many quotes are drawn looking for an author or
+// a length unless WithAuthorAttempts says otherwise.
+const defaultAuthorAttempts = 10
+
+// findQuote returns a quote in lang satisfying c within the provider's share
+// of the request budget. Errors are API errors.
+func (s *Server) findQuote(ctx context.Context, lang string, c quote.Constraints) (*quote.Quote, error) {
+	if !s.enabled(featureQuoteCache) {
+		ctx = quote.SkipCache(ctx)
+	}
+	ctx, cancel := budget(ctx, s.providerBudget)
+	defer cancel()
+
+	q, err := s.searchQuote(ctx, lang, c)
+	if err != nil {
+		return nil, budgetError(ctx, err)
+	}
+	return q, nil
+}
+
+// searchQuote does the work of findQuote. Providers cannot be asked for an
+// author or a length, so quotes are drawn until one matches, then the local
+// quotes are searched.
+func (s *Server) searchQuote(ctx context.Context, lang string, c quote.Constraints) (*quote.Quote, error) {
+	if c.IsZero() {
+		q, err := s.quoteGenerator.Generate(ctx, lang)
+		if err != nil {
+			return nil, providerError(err)
+		}
+		return q, nil
+	}
+
+	// A cached quote would come back on every draw.
+	drawCtx := quote.SkipCache(ctx)
+
+	attempts := s.authorAttempts
+	if _, keyed := quote.KeyFromContext(ctx); keyed {
+		// A keyed draw returns the same quote every time.
+		attempts = 1
+	}
+
+	var providerErr error
+	for i := 0; i < attempts; i++ {
+		q, err := s.quoteGenerator.Generate(drawCtx, lang)
+		if err != nil {
+			providerErr = err
+			break
+		}
+		if c.Match(*q) {
+			return q, nil
+		}
+	}
+
+	q, err := s.findLocal(ctx, lang, c)
+	if err == nil {
+		q.Provider = "local"
+		return q, nil
+	}
+	if !errors.Is(err, quote.ErrNoQuotes) {
+		return nil, storeError(err)
+	}
+
+	if providerErr != nil {
+		return nil, providerError(providerErr)
+	}
+	return nil, apierror.NotFound("no quote " + describeConstraints(c) + " found")
+}
+
+// findLocal looks up a local quote satisfying c, returning quote.ErrNoQuotes
+// when the local quotes cannot be searched that way.
+func (s *Server) findLocal(ctx context.Context, lang string, c quote.Constraints) (*quote.Quote, error) {
+	if finder, ok := s.localQuotes.(quote.Finder); ok {
+		return finder.Find(ctx, lang, c)
+	}
+	if finder, ok := s.localQuotes.(quote.AuthorFinder); ok && c == (quote.Constraints{Author: c.Author}) {
+		return finder.FindByAuthor(ctx, lang, c.Author)
+	}
+	return nil, quote.ErrNoQuotes
+}
+
+// describeConstraints words c for error messages, as in "by "Twain" of at
+// most 140 characters".
+func describeConstraints(c quote.Constraints) string {
+	var parts []string
+	if c.Author != "" {
+		parts = append(parts, fmt.Sprintf("by %q", c.Author))
+	}
+	if c.MinLength > 0 {
+		parts = append(parts, fmt.Sprintf("of at least %d characters", c.MinLength))
+	}
+	if c.MaxLength > 0 {
+		parts = append(parts, fmt.Sprintf("of at most %d characters", c.MaxLength))
+	}
+	return strings.Join(parts, " ")
+}
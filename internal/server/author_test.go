@@ -0,0 +1,194 @@
+// internal/server/author_test.go
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/internal/memstore"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type MockAuthorFinder struct {
+	MockLocalQuoteStore
+}
+
+func (m *MockAuthorFinder) FindByAuthor(ctx context.Context, lang, author string) (*quote.Quote, error) {
+	args := m.Called(ctx, lang, author)
+	q, _ := args.Get(0).(*quote.Quote)
+	return q, args.Error(1)
+}
+
+func TestHandleQuotes_Author(t *testing.T) {
+	twain := &quote.Quote{Text: "Bla", Author: "Mark Twain", Lang: "en"}
+	einstein := &quote.Quote{Text: "Bla Bla", Author: "Albert Einstein", Lang: "en"}
+
+	testCases := []struct {
+		name           string
+		createMocks    func() (*MockQuoteGenerator, *MockAuthorFinder)
+		expectedStatus int
+		expectedQuote  *quote.Quote
+	}{
+		{
+			"FoundOnSecondDraw",
+			func() (*MockQuoteGenerator, *MockAuthorFinder) {
+				g := MockQuoteGenerator{}
+				g.On("Generate", mock.Anything, "en").Return(twain, nil).Once()
+				g.On("Generate", mock.Anything, "en").Return(einstein, nil).Once()
+				return &g, &MockAuthorFinder{}
+			},
+			http.StatusOK,
+			einstein,
+		},
+		{
+			"FoundLocally",
+			func() (*MockQuoteGenerator, *MockAuthorFinder) {
+				g := MockQuoteGenerator{}
+				g.On("Generate", mock.Anything, "en").Return(twain, nil).Times(3)
+				local := MockAuthorFinder{}
+				local.On("FindByAuthor", mock.Anything, "en", "einstein").Return(&quote.Quote{Text: "Bla Bla", Author: "Albert Einstein", Lang: "en"}, nil)
+				return &g, &local
+			},
+			http.StatusOK,
+			&quote.Quote{Text: "Bla Bla", Author: "Albert Einstein", Lang: "en", Provider: "local"},
+		},
+		{
+			"NotFound",
+			func() (*MockQuoteGenerator, *MockAuthorFinder) {
+				g := MockQuoteGenerator{}
+				g.On("Generate", mock.Anything, "en").Return(twain, nil).Times(3)
+				local := MockAuthorFinder{}
+				local.On("FindByAuthor", mock.Anything, "en", "einstein").Return(nil, quote.ErrNoQuotes)
+				return &g, &local
+			},
+			http.StatusNotFound,
+			nil,
+		},
+		{
+			"ProviderFail",
+			func() (*MockQuoteGenerator, *MockAuthorFinder) {
+				g := MockQuoteGenerator{}
+				g.On("Generate", mock.Anything, "en").Return(nil, errors.New("sample error")).Once()
+				local := MockAuthorFinder{}
+				local.On("FindByAuthor", mock.Anything, "en", "einstein").Return(nil, quote.ErrNoQuotes)
+				return &g, &local
+			},
+			http.StatusBadGateway,
+			nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockQuoteGenerator, mockLocalQuotes := tc.createMocks()
+			mockRecipientsFetcher := MockRecipientsFetcher{}
+			mockRecipientsFetcher.On("ListRecipients", mock.Anything, defaultListOptions).Return([]recipient.Recipient{}, nil)
+
+			svr := newTestServer(t,
+				WithQuoteGenerator(mockQuoteGenerator),
+				WithRecipientsFetcher(&mockRecipientsFetcher),
+				WithLocalQuotes(mockLocalQuotes),
+				WithAuthorAttempts(3),
+			)
+
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/quote?lang=en&author=einstein", nil)
+
+			svr.handleQuotes()(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			if tc.expectedQuote != nil {
+				var resp HandleQuoteResponse
+				assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp), "Response body should be JSON")
+				assert.Equal(t, tc.expectedQuote, resp.Quote, "Quote is different than expected")
+			}
+			mockQuoteGenerator.AssertExpectations(t)
+			mockLocalQuotes.AssertExpectations(t)
+		})
+	}
+}
+
+func TestFindQuote_SkipsCache(t *testing.T) {
+	inner := MockQuoteGenerator{}
+	inner.On("Generate", mock.Anything, "en").Return(&quote.Quote{Author: "Mark Twain"}, nil).Once()
+	inner.On("Generate", mock.Anything, "en").Return(&quote.Quote{Author: "Albert Einstein"}, nil).Once()
+
+	cached := &quote.Cached{Inner: &inner, TTL: time.Minute, Cache: quote.NewLRU(10)}
+	svr := newTestServer(t, WithQuoteGenerator(cached))
+
+	_, err := svr.findQuote(context.Background(), "en", quote.Constraints{})
+	assert.NoError(t, err, "Got error when not expected")
+	q, err := svr.findQuote(context.Background(), "en", quote.Constraints{Author: "Einstein"})
+
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, "Albert Einstein", q.Author, "Author search should not be served from the cache")
+	inner.AssertExpectations(t)
+}
+
+func TestHandleQuotes_Length(t *testing.T) {
+	short := &quote.Quote{Text: "Bla", Author: "Mark Twain", Lang: "en"}
+	long := &quote.Quote{Text: strings.Repeat("Bla ", 40), Author: "Albert Einstein", Lang: "en"}
+
+	testCases := []struct {
+		name           string
+		query          string
+		draws          []*quote.Quote
+		local          []quote.Quote
+		expectedStatus int
+		expectedQuote  *quote.Quote
+		expectedError  string
+	}{
+		{"MaxLength", "max_length=10", []*quote.Quote{long, short}, nil, http.StatusOK, short, ""},
+		{"Short", "style=short", []*quote.Quote{long, short}, nil, http.StatusOK, short, ""},
+		{"Long", "style=long", []*quote.Quote{short, long}, nil, http.StatusOK, long, ""},
+		{"ShortByAuthor", "style=short&author=einstein", []*quote.Quote{short, long, short}, nil, http.StatusNotFound, nil, `no quote by \"einstein\" of at most 140 characters found`},
+		{"FoundLocally", "max_length=10", []*quote.Quote{long, long, long}, []quote.Quote{{Text: "Bla Bla", Author: "Bob", Lang: "en"}}, http.StatusOK, &quote.Quote{Text: "Bla Bla", Author: "Bob", Lang: "en", Provider: "local"}, ""},
+		{"NotFound", "max_length=2", []*quote.Quote{short, short, short}, nil, http.StatusNotFound, nil, "no quote of at most 2 characters found"},
+		{"UnknownStyle", "style=haiku", nil, nil, http.StatusBadRequest, nil, "must be one of short, long"},
+		{"NegativeMaxLength", "max_length=-1", nil, nil, http.StatusBadRequest, nil, "must be at least 0"},
+		{"LongerThanMaxLength", "style=long&max_length=100", nil, nil, http.StatusBadRequest, nil, "must be at least 141 for long quotes"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			g := MockQuoteGenerator{}
+			for _, q := range tc.draws {
+				g.On("Generate", mock.Anything, "en").Return(q, nil).Once()
+			}
+			local := memstore.NewLocalQuotes()
+			for _, q := range tc.local {
+				local.AddLocal(context.Background(), q)
+			}
+			mockRecipientsFetcher := MockRecipientsFetcher{}
+			mockRecipientsFetcher.On("ListRecipients", mock.Anything, defaultListOptions).Return([]recipient.Recipient{}, nil)
+
+			svr := newTestServer(t,
+				WithQuoteGenerator(&g),
+				WithRecipientsFetcher(&mockRecipientsFetcher),
+				WithLocalQuotes(local),
+				WithAuthorAttempts(3),
+			)
+
+			req, _ := http.NewRequest("GET", "/quote?lang=en&"+tc.query, nil)
+			rr := makeHTTPCall(svr.router, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			if tc.expectedQuote != nil {
+				var resp HandleQuoteResponse
+				assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp), "Response body should be JSON")
+				assert.Equal(t, tc.expectedQuote, resp.Quote, "Quote is different than expected")
+			}
+			assert.Contains(t, rr.Body.String(), tc.expectedError)
+			g.AssertExpectations(t)
+		})
+	}
+}
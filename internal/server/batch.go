@@ -0,0 +1,92 @@
+// internal/server/batch.go
+
+package server
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"net/http"
+	"sync"
+)
+
+// batchConcurrency bounds the provider calls a single batch makes at once.
+const batchConcurrency = 4
+
+type quoteBatchRequest struct {
+	Lang  string `query:"lang" validate:"lang" doc:"quote language, left to the provider when empty"`
+	Count int    `query:"count" default:"5" validate:"min=1,max=20" doc:"number of quotes to draw"`
+}
+
+// QuoteBatchResponse holds the distinct quotes of a batch. Failed counts the
+// draws that failed, so fewer quotes than asked for are not a surprise.
+type QuoteBatchResponse struct {
+	Quotes []*quote.Quote `json:"quotes"`
+	Failed int            `json:"failed"`
+}
+
+// drawQuotes draws count quotes, at most batchConcurrency at a time,
+// dropping repeats. It fails only when every draw fails.
+func (s *Server) drawQuotes(ctx context.Context, lang string, count int) (QuoteBatchResponse, error) {
+	ctx, cancel := budget(ctx, s.providerBudget)
+	defer cancel()
+	// A cached quote would fill the whole batch.
+	drawCtx := quote.SkipCache(ctx)
+
+	results := make([]*quote.Quote, count)
+	errs := make([]error, count)
+	sem := make(chan struct{}, batchConcurrency)
+	var wg sync.WaitGroup
+	for i := 0; i < count; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i], errs[i] = s.quoteGenerator.Generate(drawCtx, lang)
+		}(i)
+	}
+	wg.Wait()
+
+	resp := QuoteBatchResponse{Quotes: []*quote.Quote{}}
+	seen := make(map[string]bool)
+	var firstErr error
+	for i, q := range results {
+		if errs[i] != nil {
+			resp.Failed++
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+			continue
+		}
+		if hash := quote.Hash(*q); !seen[hash] {
+			seen[hash] = true
+			resp.Quotes = append(resp.Quotes, q)
+		}
+	}
+
+	if resp.Failed == count {
+		return resp, budgetError(ctx, providerError(firstErr))
+	}
+	return resp, nil
+}
+
+func (s *Server) handleQuoteBatch() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req quoteBatchRequest
+		if !s.bindRequest(w, r, &req) {
+			return
+		}
+
+		if req.Lang == "" {
+			req.Lang = s.defaultLang
+		}
+
+		resp, err := s.drawQuotes(r.Context(), req.Lang, req.Count)
+		if err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
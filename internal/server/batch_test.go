@@ -0,0 +1,88 @@
+// internal/server/batch_test.go
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// sequenceGenerator returns quote n on its nth call, failing calls listed in
+// fail. Quotes repeat every distinct calls.
+type sequenceGenerator struct {
+	calls    atomic.Int32
+	distinct int
+	fail     map[int]bool
+}
+
+func (g *sequenceGenerator) Generate(ctx context.Context, lang string) (*quote.Quote, error) {
+	n := int(g.calls.Add(1))
+	if g.fail[n] {
+		return nil, errors.New("sample error")
+	}
+	return &quote.Quote{Text: fmt.Sprintf("quote %d", n%g.distinct), Lang: lang}, nil
+}
+
+func TestHandleQuoteBatch(t *testing.T) {
+	testCases := []struct {
+		name           string
+		query          string
+		generator      *sequenceGenerator
+		expectedStatus int
+		expectedQuotes int
+		expectedFailed int
+	}{
+		{"Distinct", "count=5&lang=en", &sequenceGenerator{distinct: 100}, http.StatusOK, 5, 0},
+		{"DefaultCount", "lang=en", &sequenceGenerator{distinct: 100}, http.StatusOK, 5, 0},
+		{"Duplicates", "count=6", &sequenceGenerator{distinct: 2}, http.StatusOK, 2, 0},
+		{"PartialFailure", "count=4", &sequenceGenerator{distinct: 100, fail: map[int]bool{1: true, 3: true}}, http.StatusOK, 2, 2},
+		{"AllFail", "count=2", &sequenceGenerator{distinct: 100, fail: map[int]bool{1: true, 2: true}}, http.StatusBadGateway, 0, 0},
+		{"CountTooLarge", "count=21", &sequenceGenerator{distinct: 100}, http.StatusBadRequest, 0, 0},
+		{"CountZero", "count=0", &sequenceGenerator{distinct: 100}, http.StatusBadRequest, 0, 0},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			svr := newTestServer(t, WithQuoteGenerator(tC.generator))
+
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/quotes?"+tC.query, nil)
+
+			svr.handleQuoteBatch()(rr, req)
+
+			assert.Equal(t, tC.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			if tC.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp QuoteBatchResponse
+			_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+			assert.Len(t, resp.Quotes, tC.expectedQuotes, "Number of quotes is different than expected")
+			assert.Equal(t, tC.expectedFailed, resp.Failed, "Number of failed draws is different than expected")
+		})
+	}
+}
+
+func TestHandleQuoteBatch_SkipsCache(t *testing.T) {
+	cached := &quote.Cached{Inner: &sequenceGenerator{distinct: 100}, Cache: quote.NewLRU(10), TTL: time.Minute}
+	_, _ = cached.Generate(context.Background(), "en")
+	svr := newTestServer(t, WithQuoteGenerator(cached))
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/quotes?count=3&lang=en", nil)
+
+	svr.handleQuoteBatch()(rr, req)
+
+	var resp QuoteBatchResponse
+	_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+	assert.Len(t, resp.Quotes, 3, "A cached quote should not fill the batch")
+}
@@ -0,0 +1,189 @@
+// internal/server/binding.go
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/bind"
+	"github.com/jeniok12/golang-tdd-example/internal/auth"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// recipientListRequest pages and filters recipient listings.
+type recipientListRequest struct {
+	Limit  int    `query:"limit" default:"100" validate:"min=1,max=1000" doc:"maximum number of recipients"`
+	Offset int    `query:"offset" validate:"min=0" doc:"number of recipients to skip"`
+	Query  string `query:"q" doc:"case-insensitive name or email substring"`
+	Order  string `query:"order" validate:"order" doc:"sort column: id, name or email, prefixed with - for descending"`
+}
+
+// recipientsRequest is the query of GET /recipients.
+type recipientsRequest struct {
+	recipientListRequest
+	// All streams every matching recipient instead of a page.
+	All bool `query:"all" doc:"list every recipient matching q, in id order, streamed; offset and order do not apply"`
+}
+
+func (req recipientListRequest) options() recipient.ListOptions {
+	return recipient.ListOptions{
+		Limit:   req.Limit,
+		Offset:  req.Offset,
+		Query:   req.Query,
+		OrderBy: req.Order,
+	}
+}
+
+type quoteRequest struct {
+	Lang   string `query:"lang" validate:"target" doc:"quote language, left to the provider when empty"`
+	Source string `query:"source" validate:"lang" doc:"fetch the quote in this language and translate it to lang"`
+	Author string `query:"author" validate:"max=100" doc:"only return a quote whose author contains this, ignoring case"`
+	// MaxLength and Style bound the text served, so a translated quote is
+	// measured once translated.
+	MaxLength int      `query:"max_length" validate:"min=0,max=10000" doc:"only return a quote of at most this many characters, for SMS and other small channels"`
+	Style     string   `query:"style" validate:"oneof=short long" doc:"short for quotes of at most 140 characters, long for longer ones"`
+	Key       int      `query:"key" validate:"min=0,max=999999" doc:"Forismatic key picking the same quote every time, random when 0"`
+	Group     string   `query:"group" doc:"restrict recipients to the named group"`
+	Tags      []string `query:"tags" doc:"restrict recipients to those with any of these comma separated tags"`
+	// Include lists the optional parts of the response. Recipients are
+	// included by default in v1 only.
+	Include []string `query:"include" validate:"include" doc:"comma separated parts to add to the response: recipients"`
+	recipientListRequest
+}
+
+// includeRecipients is the include of /quote asking for recipients.
+const includeRecipients = "recipients"
+
+// shortQuoteLength is the most characters of a short quote, which fits in an
+// SMS along with its author.
+const shortQuoteLength = 140
+
+// quoteStyles are the lengths of the quotes each style asks for.
+var quoteStyles = map[string]quote.Constraints{
+	"short": {MaxLength: shortQuoteLength},
+	"long":  {MinLength: shortQuoteLength + 1},
+}
+
+// constraints returns what the quote asked for must satisfy. The tighter of
+// max_length and the style's length wins.
+func (req quoteRequest) constraints() quote.Constraints {
+	c := quoteStyles[req.Style]
+	c.Author = req.Author
+	if req.MaxLength > 0 && (c.MaxLength == 0 || req.MaxLength < c.MaxLength) {
+		c.MaxLength = req.MaxLength
+	}
+	return c
+}
+
+// includes reports whether the request asked for part, or def when it did
+// not say.
+func (req quoteRequest) includes(part string, def bool) bool {
+	if len(req.Include) == 0 {
+		return def
+	}
+	return slices.Contains(req.Include, part)
+}
+
+// RecipientRequest is the body of recipient create and update requests.
+type RecipientRequest struct {
+	Name  string `json:"name" validate:"required,max=200"`
+	Email string `json:"email" validate:"required,email"`
+}
+
+// AddFavoriteRequest is the body of favorite requests.
+type AddFavoriteRequest struct {
+	QuoteID int `json:"quoteId" validate:"required,min=1" doc:"id of a quote from the quote history"`
+}
+
+type verifyRequest struct {
+	Token string `query:"token" validate:"required" doc:"token from the verification email"`
+}
+
+// newBinder returns a Binder that also knows the rules depending on the
+// server's configuration.
+func (s *Server) newBinder() *bind.Binder {
+	b := bind.New()
+	b.Register("lang", func(v reflect.Value, _ string) string {
+		return s.langMessage(v.String())
+	})
+	// A translation target need not be supported by the providers; the
+	// handler checks lang once it knows whether a source was given. Nor need
+	// a language falling back to a supported one.
+	b.Register("target", func(v reflect.Value, _ string) string {
+		if s.translates() || s.fallsBack(v.String()) {
+			return ""
+		}
+		return s.langMessage(v.String())
+	})
+	b.Register("order", func(v reflect.Value, _ string) string {
+		if err := (recipient.ListOptions{OrderBy: v.String()}).Validate(); err != nil {
+			return err.Error()
+		}
+		return ""
+	})
+	b.Register("include", func(v reflect.Value, _ string) string {
+		for _, part := range v.Interface().([]string) {
+			if part != includeRecipients {
+				return fmt.Sprintf("unknown part %q, expected %s", part, includeRecipients)
+			}
+		}
+		return ""
+	})
+	b.Register("scopes", func(v reflect.Value, _ string) string {
+		for _, scope := range v.Interface().([]string) {
+			if !auth.Role(scope).Valid() {
+				return fmt.Sprintf("unknown scope %q, expected reader, editor or admin", scope)
+			}
+		}
+		return ""
+	})
+	b.Register("webhookurl", func(v reflect.Value, _ string) string {
+		u, err := url.Parse(v.String())
+		if v.String() != "" && (err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "") {
+			return "must be an absolute http or https URL"
+		}
+		return ""
+	})
+	return b
+}
+
+// langMessage explains why lang is not supported, or returns "".
+func (s *Server) langMessage(lang string) string {
+	if err := s.checkLang(lang); err != nil {
+		return fmt.Sprintf("%s, supported: %s", err.Message, strings.Join(s.languages, ", "))
+	}
+	return ""
+}
+
+// bindRequest fills dst from r, answering 400 with the invalid fields when
+// it fails.
+func (s *Server) bindRequest(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	err := s.binder.Bind(r, dst)
+	if err == nil {
+		return true
+	}
+
+	var fieldErrs bind.Errors
+	switch {
+	case errors.Is(err, bind.ErrInvalidJSON):
+		s.writeError(w, r, errInvalidJSON)
+	case errors.As(err, &fieldErrs):
+		s.writeFieldErrors(w, r, fieldErrs)
+	default:
+		s.writeError(w, r, err)
+	}
+	return false
+}
+
+// writeFieldErrors answers 400 with the invalid fields.
+func (s *Server) writeFieldErrors(w http.ResponseWriter, r *http.Request, fieldErrs bind.Errors) {
+	s.writeError(w, r, apierror.BadRequest(apierror.CodeValidation, "request validation failed").
+		WithDetails(map[string]bind.Errors{"fields": fieldErrs}))
+}
@@ -0,0 +1,122 @@
+// internal/server/compress_test.go
+
+package server
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcceptedEncoding(t *testing.T) {
+	testCases := []struct {
+		header   string
+		expected string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"deflate, gzip;q=0.5", "gzip"},
+		{"deflate", "deflate"},
+		{"gzip;q=0, deflate", "deflate"},
+		{"br", ""},
+		{"*", "gzip"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.header, func(t *testing.T) {
+			assert.Equal(t, tC.expected, acceptedEncoding(tC.header), "Wrong encoding")
+		})
+	}
+}
+
+func TestWithCompression(t *testing.T) {
+	large := `{"text":"` + strings.Repeat("Bla ", 500) + `"}`
+
+	testCases := []struct {
+		name             string
+		acceptEncoding   string
+		contentType      string
+		body             string
+		expectedEncoding string
+	}{
+		{"Gzip", "gzip", "application/json", large, "gzip"},
+		{"Deflate", "deflate", "application/json", large, "deflate"},
+		{"NotAccepted", "", "application/json", large, ""},
+		{"BelowThreshold", "gzip", "application/json", `{"text":"Bla"}`, ""},
+		{"AlreadyCompressed", "gzip", "image/png", large, ""},
+		{"SniffedType", "gzip", "", large, "gzip"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			svr := newTestServer(t, WithCompression(1024))
+			svr.router.HandleFunc("/teapot", func(w http.ResponseWriter, r *http.Request) {
+				if tC.contentType != "" {
+					w.Header().Set("Content-Type", tC.contentType)
+				}
+				w.Header().Set("ETag", `"abc"`)
+				w.WriteHeader(http.StatusTeapot)
+				// Written in two parts to cross the threshold mid-body.
+				io.WriteString(w, tC.body[:len(tC.body)/2])
+				io.WriteString(w, tC.body[len(tC.body)/2:])
+			})
+
+			req, _ := http.NewRequest("GET", "/teapot", nil)
+			if tC.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tC.acceptEncoding)
+			}
+			response := makeHTTPCall(svr.router, req)
+
+			assert.Equal(t, http.StatusTeapot, response.Code, "Response HTTP status in different than expected")
+			assert.Equal(t, tC.expectedEncoding, response.Header().Get("Content-Encoding"), "Wrong content encoding")
+			assert.Contains(t, response.Header().Values("Vary"), "Accept-Encoding", "Response should vary by encoding")
+
+			var body io.Reader = response.Body
+			switch tC.expectedEncoding {
+			case "gzip":
+				zr, err := gzip.NewReader(response.Body)
+				require.NoError(t, err, "Body should be gzipped")
+				body = zr
+				assert.Equal(t, `W/"abc"`, response.Header().Get("ETag"), "Compressed responses should have a weak ETag")
+			case "deflate":
+				body = flate.NewReader(response.Body)
+			default:
+				assert.Equal(t, `"abc"`, response.Header().Get("ETag"), "Uncompressed responses should keep their ETag")
+			}
+
+			decoded, err := ioutil.ReadAll(body)
+			require.NoError(t, err, "Body should decode")
+			assert.Equal(t, tC.body, string(decoded), "Response HTTP body in different than expected")
+		})
+	}
+
+	_, err := NewServer(WithCompression(-1))
+	assert.Error(t, err, "Negative threshold should be rejected")
+}
+
+func TestWithCompression_ConditionalRequest(t *testing.T) {
+	svr := newTestServer(t, WithCompression(0))
+	body := bytes.Repeat([]byte("Bla "), 500)
+	svr.router.HandleFunc("/teapot", func(w http.ResponseWriter, r *http.Request) {
+		writeConditional(w, r, "text/plain", body, time.Time{})
+	})
+
+	req, _ := http.NewRequest("GET", "/teapot", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	response := makeHTTPCall(svr.router, req)
+	require.Equal(t, "gzip", response.Header().Get("Content-Encoding"), "Response should be gzipped")
+
+	req, _ = http.NewRequest("GET", "/teapot", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("If-None-Match", response.Header().Get("ETag"))
+	response = makeHTTPCall(svr.router, req)
+
+	assert.Equal(t, http.StatusNotModified, response.Code, "Weak ETag should still match")
+	assert.Empty(t, response.Header().Get("Content-Encoding"), "Empty 304 should not be compressed")
+}
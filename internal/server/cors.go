@@ -0,0 +1,118 @@
+// internal/server/cors.go
+
+package server
+
+import (
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/requestid"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORS configures which browser origins may call the API.
+type CORS struct {
+	// Origins allowed to make requests, such as https://quotes.example.com.
+	// "*" allows any origin.
+	Origins []string
+	// Methods and Headers a preflighted request may use.
+	Methods []string
+	Headers []string
+	// MaxAge is how long browsers may cache a preflight response.
+	MaxAge time.Duration
+}
+
+// corsExposedHeaders are response headers browser clients may read.
+var corsExposedHeaders = strings.Join([]string{requestid.Header, "ETag", "Retry-After"}, ", ")
+
+func (c *CORS) allowsOrigin(origin string) bool {
+	for _, o := range c.Origins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *CORS) wildcard() bool {
+	for _, o := range c.Origins {
+		if o == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// WithCORS lets browsers call the API from the configured origins.
+func WithCORS(c CORS) Option {
+	return func(s *Server) error {
+		if len(c.Origins) == 0 {
+			return errors.New("server: CORS needs at least one origin")
+		}
+		if c.MaxAge < 0 {
+			return errors.New("server: negative CORS max-age")
+		}
+		s.cors = &c
+		return nil
+	}
+}
+
+// withCORS adds CORS headers for allowed origins and answers preflight
+// requests itself. Requests from other origins are served without CORS
+// headers, so browsers refuse to hand the response to the calling page.
+func (s *Server) withCORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if s.cors == nil || origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h := w.Header()
+		h.Add("Vary", "Origin")
+		if !s.cors.allowsOrigin(origin) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if s.cors.wildcard() {
+			h.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			h.Set("Access-Control-Allow-Origin", origin)
+		}
+
+		if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+			h.Set("Access-Control-Expose-Headers", corsExposedHeaders)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		h.Add("Vary", "Access-Control-Request-Method")
+		h.Add("Vary", "Access-Control-Request-Headers")
+		h.Set("Access-Control-Allow-Methods", strings.Join(s.cors.Methods, ", "))
+		if len(s.cors.Headers) > 0 {
+			h.Set("Access-Control-Allow-Headers", strings.Join(s.cors.Headers, ", "))
+		}
+		if s.cors.MaxAge > 0 {
+			h.Set("Access-Control-Max-Age", strconv.Itoa(int(s.cors.MaxAge.Seconds())))
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// handleOptions answers OPTIONS requests that are not CORS preflights with
+// the methods the path supports.
+func (s *Server) handleOptions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		allowed := s.allowedMethods(r)
+		if len(allowed) == 0 {
+			s.writeError(w, r, apierror.NotFound("not found"))
+			return
+		}
+
+		w.Header().Set("Allow", strings.Join(append(allowed, http.MethodOptions), ", "))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,141 @@
+// internal/server/cors_test.go
+
+package server
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestWithCORS(t *testing.T) {
+	cors := CORS{
+		Origins: []string{"https://app.example.com"},
+		Methods: []string{"GET", "POST"},
+		Headers: []string{"Content-Type", "X-API-Key"},
+		MaxAge:  10 * time.Minute,
+	}
+
+	testCases := []struct {
+		name            string
+		cors            CORS
+		method          string
+		path            string
+		headers         map[string]string
+		expectedStatus  int
+		expectedOrigin  string
+		expectedMethods string
+		expectedMaxAge  string
+	}{
+		{
+			"Preflight",
+			cors,
+			"OPTIONS",
+			"/recipients/1",
+			map[string]string{"Origin": "https://app.example.com", "Access-Control-Request-Method": "PUT"},
+			http.StatusNoContent,
+			"https://app.example.com",
+			"GET, POST",
+			"600",
+		},
+		{
+			"PreflightFromOtherOrigin",
+			cors,
+			"OPTIONS",
+			"/recipients/1",
+			map[string]string{"Origin": "https://evil.example.com", "Access-Control-Request-Method": "PUT"},
+			http.StatusNoContent,
+			"",
+			"",
+			"",
+		},
+		{
+			"SimpleRequest",
+			cors,
+			"GET",
+			"/healthz",
+			map[string]string{"Origin": "https://app.example.com"},
+			http.StatusOK,
+			"https://app.example.com",
+			"",
+			"",
+		},
+		{
+			"AnyOrigin",
+			CORS{Origins: []string{"*"}, Methods: []string{"GET"}},
+			"GET",
+			"/healthz",
+			map[string]string{"Origin": "https://app.example.com"},
+			http.StatusOK,
+			"*",
+			"",
+			"",
+		},
+		{
+			"MethodNotAllowed",
+			cors,
+			"PATCH",
+			"/quote",
+			map[string]string{"Origin": "https://app.example.com"},
+			http.StatusMethodNotAllowed,
+			"https://app.example.com",
+			"",
+			"",
+		},
+		{
+			"NoOrigin",
+			cors,
+			"GET",
+			"/healthz",
+			nil,
+			http.StatusOK,
+			"",
+			"",
+			"",
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			svr := newTestServer(t, WithCORS(tC.cors))
+
+			req, _ := http.NewRequest(tC.method, tC.path, nil)
+			for k, v := range tC.headers {
+				req.Header.Set(k, v)
+			}
+			response := makeHTTPCall(svr.router, req)
+
+			assert.Equal(t, tC.expectedStatus, response.Code, "Response HTTP status in different than expected")
+			assert.Equal(t, tC.expectedOrigin, response.Header().Get("Access-Control-Allow-Origin"), "Wrong allowed origin")
+			assert.Equal(t, tC.expectedMethods, response.Header().Get("Access-Control-Allow-Methods"), "Wrong allowed methods")
+			assert.Equal(t, tC.expectedMaxAge, response.Header().Get("Access-Control-Max-Age"), "Wrong max-age")
+		})
+	}
+
+	_, err := NewServer(WithCORS(CORS{}))
+	assert.Error(t, err, "CORS without origins should be rejected")
+}
+
+func TestRoutes_Options(t *testing.T) {
+	svr := newTestServer(t)
+
+	testCases := []struct {
+		name           string
+		path           string
+		expectedStatus int
+		expectedAllow  string
+	}{
+		{"Recipient", "/recipients/1", http.StatusNoContent, "GET, PUT, DELETE, OPTIONS"},
+		{"Quote", "/quote", http.StatusNoContent, "GET, OPTIONS"},
+		{"UnknownPath", "/nope", http.StatusNotFound, ""},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			req, _ := http.NewRequest("OPTIONS", tC.path, nil)
+			response := makeHTTPCall(svr.router, req)
+
+			assert.Equal(t, tC.expectedStatus, response.Code, "Response HTTP status in different than expected")
+			assert.Equal(t, tC.expectedAllow, response.Header().Get("Allow"), "Allow header is different than expected")
+		})
+	}
+}
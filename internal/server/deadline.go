@@ -0,0 +1,94 @@
+// internal/server/deadline.go
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// untimedPaths hold connections open on purpose, so they get no deadline,
+// and are exempt from the read and write timeouts of the http.Server.
+var untimedPaths = map[string]bool{
+	"/quote/stream":          true,
+	"/quote/events":          true,
+	"/quotes/history/export": true,
+	"/recipients/export":     true,
+	"/recipients/import":     true,
+	"/debug/pprof/profile":   true,
+	"/debug/pprof/trace":     true,
+}
+
+// untimed reports whether r holds its connection open on purpose: a request
+// to one of untimedPaths, or for the whole recipient list.
+func untimed(r *http.Request) bool {
+	if untimedPaths[r.URL.Path] {
+		return true
+	}
+	all, _ := strconv.ParseBool(r.URL.Query().Get("all"))
+	return r.URL.Path == "/recipients" && r.Method == http.MethodGet && all
+}
+
+// WithRequestTimeout gives every request timeout to complete, of which the
+// quote provider may use the providerBudget share, leaving the rest for the
+// database. A zero timeout disables the deadline.
+func WithRequestTimeout(timeout time.Duration, providerBudget float64) Option {
+	return func(s *Server) error {
+		if timeout < 0 {
+			return fmt.Errorf("server: negative request timeout %s", timeout)
+		}
+		if providerBudget <= 0 || providerBudget > 1 {
+			return fmt.Errorf("server: provider budget %v is not in (0, 1]", providerBudget)
+		}
+		s.requestTimeout = timeout
+		s.providerBudget = providerBudget
+		return nil
+	}
+}
+
+func (s *Server) withDeadline(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if untimed(r) {
+			// Writers that cannot change deadlines, as in tests, have none.
+			rc := http.NewResponseController(w)
+			_ = rc.SetReadDeadline(time.Time{})
+			_ = rc.SetWriteDeadline(time.Time{})
+			next.ServeHTTP(w, r)
+			return
+		}
+		if s.requestTimeout <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), s.requestTimeout)
+		defer cancel()
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// budget derives a context that may use share of the time left before ctx's
+// deadline. Without a deadline there is no budget to split.
+func budget(ctx context.Context, share float64) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok || share <= 0 || share >= 1 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, time.Duration(float64(time.Until(deadline))*share))
+}
+
+// budgetError turns a server side failure into a 504 when ctx ran out of
+// time, since the deadline rather than the dependency is then to blame.
+func budgetError(ctx context.Context, err error) error {
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) || apierror.From(err).Status < http.StatusInternalServerError {
+		return err
+	}
+
+	return apierror.Wrap(err, http.StatusGatewayTimeout, apierror.CodeTimeout, "request deadline exceeded")
+}
@@ -0,0 +1,99 @@
+// internal/server/deadline_test.go
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestWithDeadline(t *testing.T) {
+	waitForDeadline := func(args mock.Arguments) {
+		<-args.Get(0).(context.Context).Done()
+	}
+
+	testCases := []struct {
+		name           string
+		setupMocks     func(g *MockQuoteGenerator, f *MockRecipientsFetcher)
+		expectedStatus int
+		expectedCode   apierror.Code
+	}{
+		{
+			"SlowProvider",
+			func(g *MockQuoteGenerator, f *MockRecipientsFetcher) {
+				g.On("Generate", mock.Anything, "en").Run(waitForDeadline).Return(nil, context.DeadlineExceeded)
+				f.On("ListRecipients", mock.Anything, defaultListOptions).Return([]recipient.Recipient{}, nil)
+			},
+			http.StatusGatewayTimeout,
+			apierror.CodeTimeout,
+		},
+		{
+			"SlowDatabase",
+			func(g *MockQuoteGenerator, f *MockRecipientsFetcher) {
+				g.On("Generate", mock.Anything, "en").Return(&quote.Quote{Text: "Bla", Author: "Bob", Lang: "en"}, nil)
+				f.On("ListRecipients", mock.Anything, defaultListOptions).Run(waitForDeadline).Return(nil, context.DeadlineExceeded)
+			},
+			// The quote is served without its recipients.
+			http.StatusOK,
+			"",
+		},
+		{
+			"ProviderFailsInTime",
+			func(g *MockQuoteGenerator, f *MockRecipientsFetcher) {
+				g.On("Generate", mock.Anything, "en").Return(nil, quote.ErrNoQuotes)
+				f.On("ListRecipients", mock.Anything, defaultListOptions).Return([]recipient.Recipient{}, nil)
+			},
+			http.StatusServiceUnavailable,
+			apierror.CodeUpstreamUnavailable,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			g := &MockQuoteGenerator{}
+			f := &MockRecipientsFetcher{}
+			tC.setupMocks(g, f)
+			svr := newTestServer(t, WithQuoteGenerator(g), WithRecipientsFetcher(f), WithRequestTimeout(50*time.Millisecond, 0.5))
+
+			start := time.Now()
+			req, _ := http.NewRequest("GET", "/quote?lang=en", nil)
+			response := makeHTTPCall(svr.router, req)
+
+			var body apierror.Error
+			_ = json.Unmarshal(response.Body.Bytes(), &body)
+
+			assert.Equal(t, tC.expectedStatus, response.Code, "Response HTTP status in different than expected")
+			assert.Equal(t, tC.expectedCode, body.Code, "Wrong error code")
+			assert.Less(t, time.Since(start), time.Second, "Request should give up at its deadline")
+		})
+	}
+
+	_, err := NewServer(WithRequestTimeout(time.Second, 1.5))
+	assert.Error(t, err, "Provider budget above 1 should be rejected")
+}
+
+func TestBudget(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	requestDeadline, _ := ctx.Deadline()
+
+	providerCtx, providerCancel := budget(ctx, 0.25)
+	defer providerCancel()
+	providerDeadline, ok := providerCtx.Deadline()
+
+	assert.True(t, ok, "Budget should have a deadline")
+	assert.WithinDuration(t, time.Now().Add(250*time.Millisecond), providerDeadline, 50*time.Millisecond, "Budget should get its share of the time left")
+	assert.True(t, providerDeadline.Before(requestDeadline), "Budget should end before the request")
+
+	unbounded, unboundedCancel := budget(context.Background(), 0.25)
+	defer unboundedCancel()
+	_, ok = unbounded.Deadline()
+	assert.False(t, ok, "Without a deadline there is no budget to split")
+}
@@ -0,0 +1,93 @@
+// internal/server/debug.go
+
+package server
+
+import (
+	"database/sql"
+	"encoding/json"
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+)
+
+// sizer is a cache able to tell how many entries it holds.
+type sizer interface {
+	Len() int
+}
+
+// dbStatser is a database able to report on its connection pool.
+type dbStatser interface {
+	Stats() sql.DBStats
+}
+
+// WithDebug serves the pprof profiles under /debug/pprof/ and the expvar
+// variables on /debug/vars, both behind the admin token. NewServer fails
+// without an admin token or WithJWT.
+func WithDebug() Option {
+	return func(s *Server) error {
+		s.debug = true
+		return nil
+	}
+}
+
+// debugRoutes are kept out of the API spec, as they are for operators.
+func (s *Server) debugRoutes() {
+	s.router.HandleFunc("/debug/pprof/cmdline", s.requireAdmin(pprof.Cmdline))
+	s.router.HandleFunc("/debug/pprof/profile", s.requireAdmin(pprof.Profile))
+	s.router.HandleFunc("/debug/pprof/symbol", s.requireAdmin(pprof.Symbol))
+	s.router.HandleFunc("/debug/pprof/trace", s.requireAdmin(pprof.Trace))
+	// Index also serves the named profiles, such as /debug/pprof/heap.
+	s.router.PathPrefix("/debug/pprof/").HandlerFunc(s.requireAdmin(pprof.Index))
+	s.router.HandleFunc("/debug/vars", s.requireAdmin(s.handleDebugVars())).Methods("GET")
+}
+
+// withInFlight counts the requests being served.
+func (s *Server) withInFlight(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.inFlight.Add(1)
+		defer s.inFlight.Add(-1)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// debugVars are the variables of this server. They are not published to
+// the expvar package, which would panic on a second server in the process.
+func (s *Server) debugVars() *expvar.Map {
+	vars := new(expvar.Map).Init()
+	vars.Set("requestsInFlight", expvar.Func(func() any { return s.inFlight.Load() }))
+	vars.Set("requestsShed", expvar.Func(func() any { return s.shed.Load() }))
+	if s.quoteCache != nil {
+		if c, ok := s.quoteCache.Cache.(sizer); ok {
+			vars.Set("quoteCacheSize", expvar.Func(func() any { return c.Len() }))
+		}
+	}
+	if db, ok := s.persistence.(dbStatser); ok {
+		vars.Set("dbOpenConnections", expvar.Func(func() any { return db.Stats().OpenConnections }))
+		vars.Set("dbInUseConnections", expvar.Func(func() any { return db.Stats().InUse }))
+	}
+	return vars
+}
+
+// handleDebugVars writes the published expvar variables, such as memstats,
+// followed by the server's, in the format of expvar.Handler.
+func (s *Server) handleDebugVars() http.HandlerFunc {
+	vars := s.debugVars()
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+
+		fmt.Fprint(w, "{\n")
+		first := true
+		write := func(kv expvar.KeyValue) {
+			if !first {
+				fmt.Fprint(w, ",\n")
+			}
+			first = false
+			key, _ := json.Marshal(kv.Key)
+			fmt.Fprintf(w, "%s: %s", key, kv.Value)
+		}
+		expvar.Do(write)
+		vars.Do(write)
+		fmt.Fprint(w, "\n}\n")
+	}
+}
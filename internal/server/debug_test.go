@@ -0,0 +1,79 @@
+// internal/server/debug_test.go
+
+package server
+
+import (
+	"encoding/json"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/sqlitestore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDebugRoutes(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		debug          bool
+		path           string
+		authorization  string
+		expectedStatus int
+	}{
+		{"Vars", true, "/debug/vars", "Bearer s3cret", http.StatusOK},
+		{"PprofIndex", true, "/debug/pprof/", "Bearer s3cret", http.StatusOK},
+		{"PprofProfile", true, "/debug/pprof/goroutine?debug=1", "Bearer s3cret", http.StatusOK},
+		{"NoToken", true, "/debug/vars", "", http.StatusUnauthorized},
+		{"PprofNoToken", true, "/debug/pprof/heap", "", http.StatusUnauthorized},
+		{"Disabled", false, "/debug/vars", "Bearer s3cret", http.StatusNotFound},
+		{"PprofDisabled", false, "/debug/pprof/", "Bearer s3cret", http.StatusNotFound},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			opts := []Option{WithAdminToken("s3cret")}
+			if tC.debug {
+				opts = append(opts, WithDebug())
+			}
+			svr := newTestServer(t, opts...)
+
+			req, _ := http.NewRequest("GET", tC.path, nil)
+			if tC.authorization != "" {
+				req.Header.Set("Authorization", tC.authorization)
+			}
+			rr := makeHTTPCall(svr.router, req)
+			assert.Equal(t, tC.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+		})
+	}
+}
+
+func TestNewServer_DebugWithoutAdminToken(t *testing.T) {
+	_, err := NewServer(
+		WithQuoteGenerator(&MockQuoteGenerator{}),
+		WithRecipientsFetcher(&MockRecipientsFetcher{}),
+		WithDebug(),
+	)
+	assert.Error(t, err, "Should refuse to serve the debug routes without an admin token")
+}
+
+func TestHandleDebugVars(t *testing.T) {
+	lru := quote.NewLRU(10)
+	lru.Set("en", &quote.Quote{Text: "Bla"}, time.Minute)
+	db, err := sqlitestore.Open(":memory:")
+	require.NoError(t, err, "Got error when not expected")
+	defer db.Close()
+
+	svr := newTestServer(t, WithDebug(), WithQuoteCache(&quote.Cached{Cache: lru}), WithPersistence(db))
+
+	req := asAdmin(httptest.NewRequest("GET", "/debug/vars", nil))
+	rr := makeHTTPCall(svr.router, req)
+	assert.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+
+	var vars map[string]interface{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &vars), "Got error when not expected")
+	assert.Contains(t, vars, "memstats", "Published variables should be listed")
+	assert.Equal(t, float64(1), vars["requestsInFlight"], "The request itself is in flight")
+	assert.Equal(t, float64(1), vars["quoteCacheSize"])
+	assert.Contains(t, vars, "dbOpenConnections")
+}
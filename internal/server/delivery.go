@@ -0,0 +1,279 @@
+// internal/server/delivery.go
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/jeniok12/golang-tdd-example/mailer"
+	"slices"
+	"strings"
+)
+
+// Delivery ...
+type Delivery struct {
+	Recipient recipient.Recipient `json:"recipient"`
+	// Channel is the chat channel the quote went to, empty for emails.
+	Channel string `json:"channel,omitempty"`
+	Sent    bool   `json:"sent"`
+	// Queued is set instead of Sent when the email waits in the outbox.
+	Queued bool `json:"queued,omitempty"`
+	// MessageID is the ID the mail API gave the email, when it reports one.
+	MessageID string `json:"messageId,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// DailyRun ...
+type DailyRun struct {
+	Lang       string       `json:"lang"`
+	Quote      *quote.Quote `json:"quote,omitempty"`
+	Deliveries []Delivery   `json:"deliveries,omitempty"`
+	Error      string       `json:"error,omitempty"`
+}
+
+// recipientsFor returns the members of group having any of tags. An empty
+// group or no tags lift that restriction.
+func (s *Server) recipientsFor(ctx context.Context, group string, tags []string) ([]recipient.Recipient, error) {
+	if len(tags) == 0 {
+		if group == "" {
+			return s.recipientsFetcher.AllRecipients(ctx)
+		}
+		return s.groupStore.GroupRecipients(ctx, group)
+	}
+
+	tagged, err := s.tagStore.TaggedRecipients(ctx, tags)
+	if err != nil || group == "" {
+		return tagged, err
+	}
+
+	members, err := s.groupStore.GroupRecipients(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[int]bool, len(tagged))
+	for _, r := range tagged {
+		ids[r.ID] = true
+	}
+	return slices.DeleteFunc(members, func(r recipient.Recipient) bool { return !ids[r.ID] }), nil
+}
+
+// deliverQuote generates a quote and emails it to every recipient of group
+// having any of tags.
+func (s *Server) deliverQuote(ctx context.Context, lang, group string, tags []string) (*quote.Quote, []Delivery, error) {
+	q, err := s.generateQuote(ctx, lang)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	recipients, err := s.recipientsFor(ctx, group, tags)
+	if err != nil {
+		return nil, nil, storeError(err)
+	}
+
+	return s.sendQuote(ctx, q, recipients)
+}
+
+// generateQuote asks the provider for a quote within its share of the
+// request deadline.
+func (s *Server) generateQuote(ctx context.Context, lang string) (*quote.Quote, error) {
+	quoteCtx, cancel := budget(ctx, s.providerBudget)
+	defer cancel()
+
+	q, err := s.quoteGenerator.Generate(quoteCtx, lang)
+	if err != nil {
+		return nil, budgetError(quoteCtx, providerError(err))
+	}
+	return q, nil
+}
+
+// sendQuote sends q to recipients on each of their channels. Failing to
+// reach a single recipient is reported in its Delivery, not as an error.
+// With an outbox the emails are queued instead and the outbox retries
+// failures.
+func (s *Server) sendQuote(ctx context.Context, q *quote.Quote, recipients []recipient.Recipient) (*quote.Quote, []Delivery, error) {
+	recipients, err := s.dropSuppressed(ctx, recipients)
+	if err != nil {
+		return nil, nil, storeError(err)
+	}
+
+	var emails []recipient.Recipient
+	for _, rcpt := range recipients {
+		if slices.Contains(rcpt.EffectiveChannels(), recipient.ChannelEmail) {
+			emails = append(emails, rcpt)
+		}
+	}
+
+	var deliveries []Delivery
+	if s.outbox != nil {
+		if _, deliveries, err = s.queueQuote(ctx, q, emails); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		deliveries = s.emailQuote(q, emails)
+	}
+	deliveries = append(deliveries, s.chatQuote(ctx, q, recipients)...)
+	s.notifyDelivered(ctx, q, deliveries)
+
+	return q, deliveries, nil
+}
+
+// emailQuote emails q to recipients right away.
+func (s *Server) emailQuote(q *quote.Quote, recipients []recipient.Recipient) []Delivery {
+	deliveries := make([]Delivery, 0, len(recipients))
+	for _, rcpt := range recipients {
+		d := Delivery{Recipient: rcpt, Sent: true}
+		msg, err := s.quoteMessage(q, rcpt)
+		if err == nil {
+			d.MessageID, err = mailer.SendWithID(s.mailSender, msg)
+		}
+		if err != nil {
+			d.Sent = false
+			d.Error = err.Error()
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries
+}
+
+// chatQuote posts q to the chat channels of recipients. A channel the server
+// has no sender for fails its deliveries.
+func (s *Server) chatQuote(ctx context.Context, q *quote.Quote, recipients []recipient.Recipient) []Delivery {
+	var deliveries []Delivery
+	for _, rcpt := range recipients {
+		for _, name := range rcpt.EffectiveChannels() {
+			if name == recipient.ChannelEmail {
+				continue
+			}
+
+			d := Delivery{Recipient: rcpt, Channel: name, Sent: true}
+			err := fmt.Errorf("channel %s is not configured", name)
+			if sender, ok := s.channels[name]; ok {
+				err = sender.Send(ctx, rcpt, chatText(q))
+			}
+			if err != nil {
+				d.Sent = false
+				d.Error = err.Error()
+			}
+			deliveries = append(deliveries, d)
+		}
+	}
+	return deliveries
+}
+
+// chatText is the message posted to chat channels.
+func chatText(q *quote.Quote) string {
+	return fmt.Sprintf("“%s”\n— %s", q.Text, q.Author)
+}
+
+// queueQuote puts the emails of a delivery in the outbox, all or none.
+func (s *Server) queueQuote(ctx context.Context, q *quote.Quote, recipients []recipient.Recipient) (*quote.Quote, []Delivery, error) {
+	msgs := make([]mailer.Message, 0, len(recipients))
+	deliveries := make([]Delivery, 0, len(recipients))
+	for _, rcpt := range recipients {
+		msg, err := s.quoteMessage(q, rcpt)
+		if err != nil {
+			return nil, nil, apierror.Internal(err)
+		}
+		msgs = append(msgs, msg)
+		deliveries = append(deliveries, Delivery{Recipient: rcpt, Queued: true})
+	}
+
+	if _, err := s.outbox.Enqueue(ctx, msgs); err != nil {
+		return nil, nil, storeError(err)
+	}
+
+	return q, deliveries, nil
+}
+
+// runDailyQuotes sends a quote in each language to the recipients due for
+// one. Recipients without a preferred language get every daily language;
+// the others only theirs, which gets a run even when it is not a daily
+// language.
+func (s *Server) runDailyQuotes(ctx context.Context) []DailyRun {
+	recipients, err := s.recipientsFetcher.AllRecipients(ctx)
+	if err != nil {
+		runs := make([]DailyRun, 0, len(s.dailyLanguages))
+		for _, lang := range s.dailyLanguages {
+			runs = append(runs, DailyRun{Lang: lang, Error: storeError(err).Error()})
+		}
+		return runs
+	}
+
+	now := s.now()
+	langs := slices.Clone(s.dailyLanguages)
+	byLang := make(map[string][]recipient.Recipient)
+	for _, rcpt := range recipients {
+		if !due(rcpt, now) {
+			continue
+		}
+		if rcpt.Lang == "" {
+			for _, lang := range s.dailyLanguages {
+				byLang[lang] = append(byLang[lang], rcpt)
+			}
+			continue
+		}
+		if !slices.Contains(langs, rcpt.Lang) {
+			langs = append(langs, rcpt.Lang)
+		}
+		byLang[rcpt.Lang] = append(byLang[rcpt.Lang], rcpt)
+	}
+
+	runs := make([]DailyRun, 0, len(langs))
+	for _, lang := range langs {
+		run := DailyRun{Lang: lang}
+
+		q, err := s.generateQuote(ctx, lang)
+		if err == nil {
+			q, run.Deliveries, err = s.sendQuote(ctx, q, byLang[lang])
+		}
+		if err != nil {
+			run.Error = err.Error()
+		} else {
+			run.Quote = q
+		}
+		runs = append(runs, run)
+	}
+
+	return runs
+}
+
+// dailyJob adapts runDailyQuotes to the scheduler, turning failed languages
+// into a single error.
+func (s *Server) dailyJob(ctx context.Context) error {
+	var failed []string
+	for _, run := range s.runDailyQuotes(ctx) {
+		if run.Error != "" {
+			failed = append(failed, fmt.Sprintf("%s: %s", run.Lang, run.Error))
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("daily quote failed for %s", strings.Join(failed, "; "))
+	}
+
+	return nil
+}
+
+// quoteEmail is the data of the quote email templates.
+type quoteEmail struct {
+	Recipient      recipient.Recipient
+	Quote          *quote.Quote
+	UnsubscribeURL string
+}
+
+// quoteMessage renders the quote email to rcpt.
+func (s *Server) quoteMessage(q *quote.Quote, rcpt recipient.Recipient) (mailer.Message, error) {
+	e, err := s.templates.Render("quote", quoteEmail{
+		Recipient:      rcpt,
+		Quote:          q,
+		UnsubscribeURL: s.unsubscribeURL(rcpt.ID, rcpt.Email),
+	})
+	if err != nil {
+		return mailer.Message{}, err
+	}
+
+	return mailer.Message{To: rcpt.Email, Subject: e.Subject, Body: e.Text, HTML: e.HTML}, nil
+}
@@ -0,0 +1,73 @@
+// internal/server/errors.go
+
+package server
+
+import (
+	"context"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/errtrack"
+	"github.com/jeniok12/golang-tdd-example/internal/oauth"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/jeniok12/golang-tdd-example/internal/webhook"
+	"github.com/jeniok12/golang-tdd-example/requestid"
+	"net/http"
+)
+
+var (
+	errInvalidJSON  = apierror.BadRequest(apierror.CodeInvalidJSON, "request body is not valid JSON")
+	errInvalidID    = apierror.BadRequest(apierror.CodeBadRequest, "invalid id")
+	errInvalidToken = apierror.BadRequest(apierror.CodeInvalidToken, "invalid verification token")
+)
+
+// providerError maps a quote provider failure to an API error. Failures that
+// should clear up by themselves are 503s, anything else the provider did is a 502.
+func providerError(err error) *apierror.Error {
+	switch {
+	case errors.Is(err, quote.ErrCircuitOpen),
+		errors.Is(err, quote.ErrNoQuotes),
+		errors.Is(err, quote.ErrRateLimited),
+		errors.Is(err, quote.ErrRejected),
+		errors.Is(err, context.DeadlineExceeded):
+		return apierror.Wrap(err, http.StatusServiceUnavailable, apierror.CodeUpstreamUnavailable, "quote provider unavailable")
+	default:
+		return apierror.Wrap(err, http.StatusBadGateway, apierror.CodeUpstreamError, "quote provider failed")
+	}
+}
+
+// storeError maps a persistence failure to an API error.
+func storeError(err error) *apierror.Error {
+	switch {
+	case errors.Is(err, recipient.ErrNotFound),
+		errors.Is(err, recipient.ErrGroupNotFound),
+		errors.Is(err, recipient.ErrQuoteNotFound),
+		errors.Is(err, quote.ErrNotFound),
+		errors.Is(err, webhook.ErrNotFound),
+		errors.Is(err, oauth.ErrNotFound):
+		return apierror.NotFound(err.Error())
+	case errors.Is(err, recipient.ErrDuplicateGroup),
+		errors.Is(err, recipient.ErrDuplicateEmail):
+		return apierror.Conflict(err.Error())
+	case errors.Is(err, recipient.ErrVersionMismatch):
+		return apierror.Wrap(err, http.StatusPreconditionFailed, apierror.CodePreconditionFailed, err.Error())
+	default:
+		return apierror.Wrap(err, http.StatusServiceUnavailable, apierror.CodeDBError, "database unavailable")
+	}
+}
+
+// writeError writes err as an API error, logging server side failures.
+func (s *Server) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	e := apierror.From(budgetError(r.Context(), err))
+	if e.Status >= http.StatusInternalServerError {
+		s.logger.Error().
+			Err(err).
+			Str("requestId", requestid.FromContext(r.Context())).
+			Func(logTrace(r.Context())).
+			Str("code", string(e.Code)).
+			Msg("request failed")
+		s.report(r.Context(), errtrack.Event{Err: err, Request: r})
+	}
+
+	apierror.Write(w, r, e)
+}
@@ -0,0 +1,65 @@
+// internal/server/errors_test.go
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestProviderError(t *testing.T) {
+	testCases := []struct {
+		name           string
+		err            error
+		expectedStatus int
+		expectedCode   apierror.Code
+	}{
+		{"CircuitOpen", quote.ErrCircuitOpen, http.StatusServiceUnavailable, apierror.CodeUpstreamUnavailable},
+		{"FailoverExhausted", errors.Join(fmt.Errorf("forismatic: %w", quote.ErrCircuitOpen), quote.ErrNoQuotes), http.StatusServiceUnavailable, apierror.CodeUpstreamUnavailable},
+		{"Timeout", fmt.Errorf("get: %w", context.DeadlineExceeded), http.StatusServiceUnavailable, apierror.CodeUpstreamUnavailable},
+		{"RateLimited", &quote.RateLimitError{Provider: "zenquotes", RetryAfter: time.Minute}, http.StatusServiceUnavailable, apierror.CodeUpstreamUnavailable},
+		{"Rejected", quote.ErrRejected, http.StatusServiceUnavailable, apierror.CodeUpstreamUnavailable},
+		{"BadStatus", &quote.StatusError{StatusCode: http.StatusInternalServerError}, http.StatusBadGateway, apierror.CodeUpstreamError},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			e := providerError(tC.err)
+
+			assert.Equal(t, tC.expectedStatus, e.Status, "Status is different than expected")
+			assert.Equal(t, tC.expectedCode, e.Code, "Code is different than expected")
+			assert.True(t, errors.Is(e, tC.err), "Cause should be kept")
+		})
+	}
+}
+
+func TestStoreError(t *testing.T) {
+	testCases := []struct {
+		name           string
+		err            error
+		expectedStatus int
+		expectedCode   apierror.Code
+	}{
+		{"RecipientNotFound", recipient.ErrNotFound, http.StatusNotFound, apierror.CodeNotFound},
+		{"GroupNotFound", recipient.ErrGroupNotFound, http.StatusNotFound, apierror.CodeNotFound},
+		{"QuoteNotFound", quote.ErrNotFound, http.StatusNotFound, apierror.CodeNotFound},
+		{"DuplicateGroup", recipient.ErrDuplicateGroup, http.StatusConflict, apierror.CodeConflict},
+		{"DuplicateEmail", recipient.ErrDuplicateEmail, http.StatusConflict, apierror.CodeConflict},
+		{"Unavailable", errors.New("sample error"), http.StatusServiceUnavailable, apierror.CodeDBError},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			e := storeError(tC.err)
+
+			assert.Equal(t, tC.expectedStatus, e.Status, "Status is different than expected")
+			assert.Equal(t, tC.expectedCode, e.Code, "Code is different than expected")
+		})
+	}
+}
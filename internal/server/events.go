@@ -0,0 +1,102 @@
+// internal/server/events.go
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// handleQuoteEvents streams quotes as Server-Sent Events, one every interval.
+// Event IDs are the Unix milliseconds the quote was sent at, so a client
+// reconnecting with Last-Event-ID keeps its cadence instead of getting a
+// quote straight away.
+func (s *Server) handleQuoteEvents() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		lang := query.Get("lang")
+		if err := s.checkLang(lang); err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+
+		interval, err := streamInterval(query.Get("interval"))
+		if err != nil {
+			s.writeError(w, r, apierror.BadRequest(apierror.CodeBadRequest, err.Error()))
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			s.writeError(w, r, apierror.Internal(errors.New("server: response does not support streaming")))
+			return
+		}
+
+		if !s.acquireStream() {
+			s.writeError(w, r, apierror.New(http.StatusServiceUnavailable, apierror.CodeUnavailable, "too many open streams"))
+			return
+		}
+		defer s.releaseStream()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		// Let clients that lose the connection come back after one interval.
+		fmt.Fprintf(w, "retry: %d\n\n", interval.Milliseconds())
+		flusher.Flush()
+
+		timer := time.NewTimer(resumeDelay(r.Header.Get("Last-Event-ID"), interval, time.Now()))
+		defer timer.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-s.shutdown:
+				return
+			case <-timer.C:
+			}
+
+			now := time.Now()
+			q, err := s.quoteGenerator.Generate(r.Context(), lang)
+			if err != nil {
+				writeEvent(w, "error", now, providerError(err))
+			} else {
+				writeEvent(w, "quote", now, q)
+			}
+			flusher.Flush()
+
+			timer.Reset(interval)
+		}
+	}
+}
+
+// resumeDelay returns how long to wait before the first event. A valid
+// lastEventID schedules it one interval after the event the client last saw.
+func resumeDelay(lastEventID string, interval time.Duration, now time.Time) time.Duration {
+	ms, err := strconv.ParseInt(lastEventID, 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	delay := time.UnixMilli(ms).Add(interval).Sub(now)
+	if delay < 0 || delay > interval {
+		return 0
+	}
+	return delay
+}
+
+func writeEvent(w http.ResponseWriter, event string, at time.Time, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", at.UnixMilli(), event, data)
+}
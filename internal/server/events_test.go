@@ -0,0 +1,110 @@
+// internal/server/events_test.go
+
+package server
+
+import (
+	"bufio"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readEvent reads the next event, skipping retry-only blocks.
+func readEvent(t *testing.T, r *bufio.Reader) map[string]string {
+	t.Helper()
+
+	for {
+		event := map[string]string{}
+		for {
+			line, err := r.ReadString('\n')
+			require.NoError(t, err, "Should have no error when reading the event stream")
+
+			line = strings.TrimSuffix(line, "\n")
+			if line == "" {
+				break
+			}
+			field := strings.SplitN(line, ": ", 2)
+			event[field[0]] = field[1]
+		}
+		if _, ok := event["event"]; ok {
+			return event
+		}
+	}
+}
+
+func TestHandleQuoteEvents(t *testing.T) {
+	mockQuoteGenerator := MockQuoteGenerator{}
+	mockQuoteGenerator.On("Generate", mock.Anything, "en").Return(&quote.Quote{Text: "Bla Bla Bla", Author: "Bob", Lang: "en"}, nil)
+
+	svr := newTestServer(t,
+		WithQuoteGenerator(&mockQuoteGenerator),
+		WithLanguages([]string{"en"}, true),
+	)
+	ts := httptest.NewServer(svr.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/quote/events?lang=en&interval=1s")
+	require.NoError(t, err, "Should have no error when opening the feed")
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "Response HTTP status in different than expected")
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"), "Response content type is different than expected")
+
+	body := bufio.NewReader(resp.Body)
+	first := readEvent(t, body)
+	assert.Equal(t, "quote", first["event"], "Should stream quote events")
+	assert.JSONEq(t, `{"quoteText":"Bla Bla Bla","quoteAuthor":"Bob","lang":"en"}`, first["data"], "Event data is different than expected")
+
+	second := readEvent(t, body)
+	firstID, _ := strconv.ParseInt(first["id"], 10, 64)
+	secondID, _ := strconv.ParseInt(second["id"], 10, 64)
+	assert.GreaterOrEqual(t, secondID-firstID, int64(900), "Events should be an interval apart")
+
+	svr.EndStreams()
+	_, err = io.ReadAll(body)
+	assert.NoError(t, err, "Feed should end cleanly on shutdown")
+}
+
+func TestHandleQuoteEvents_Rejected(t *testing.T) {
+	svr := newTestServer(t, WithLanguages([]string{"en"}, true))
+	ts := httptest.NewServer(svr.Handler())
+	defer ts.Close()
+
+	for _, query := range []string{"?lang=xx", "?interval=1ms"} {
+		resp, err := http.Get(ts.URL + "/quote/events" + query)
+		require.NoError(t, err, "Should have no error when requesting the feed")
+		resp.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "Response HTTP status in different than expected")
+	}
+}
+
+func TestResumeDelay(t *testing.T) {
+	now := time.Date(2019, 6, 1, 10, 0, 0, 0, time.UTC)
+	ms := func(t time.Time) string { return strconv.FormatInt(t.UnixMilli(), 10) }
+
+	testCases := []struct {
+		name        string
+		lastEventID string
+		expected    time.Duration
+	}{
+		{"NoLastEvent", "", 0},
+		{"Invalid", "abc", 0},
+		{"WithinInterval", ms(now.Add(-20 * time.Second)), 10 * time.Second},
+		{"IntervalPassed", ms(now.Add(-time.Minute)), 0},
+		{"FromTheFuture", ms(now.Add(time.Hour)), 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, resumeDelay(tc.lastEventID, 30*time.Second, now), "Resume delay is different than expected")
+		})
+	}
+}
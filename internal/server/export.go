@@ -0,0 +1,243 @@
+// internal/server/export.go
+
+package server
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvExport streams CSV records as a download. Nothing is written until the
+// first record or finish, so an error before that can still be answered with
+// an error status.
+type csvExport struct {
+	w        http.ResponseWriter
+	cw       *csv.Writer
+	filename string
+	header   []string
+	started  bool
+}
+
+func newCSVExport(w http.ResponseWriter, filename string, header ...string) *csvExport {
+	return &csvExport{w: w, cw: csv.NewWriter(w), filename: filename, header: header}
+}
+
+func (e *csvExport) start() error {
+	e.started = true
+	e.w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	e.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", e.filename))
+	e.w.WriteHeader(http.StatusOK)
+	return e.cw.Write(e.header)
+}
+
+func (e *csvExport) write(record ...string) error {
+	if !e.started {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+	return e.cw.Write(record)
+}
+
+func (e *csvExport) finish() error {
+	if !e.started {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+	e.cw.Flush()
+	return e.cw.Error()
+}
+
+func (e *csvExport) begun() bool {
+	return e.started
+}
+
+func (e *csvExport) abort() {
+	e.cw.Flush()
+}
+
+func (e *csvExport) name() string {
+	return e.filename
+}
+
+// jsonExport streams values as the elements of a JSON array, encoding each
+// as it is written, so the whole list is never held in memory. Like
+// csvExport, nothing is written until the first element or finish. An empty
+// filename makes it a plain response rather than a download.
+type jsonExport struct {
+	w        http.ResponseWriter
+	bw       *bufio.Writer
+	filename string
+	started  bool
+}
+
+func newJSONExport(w http.ResponseWriter, filename string) *jsonExport {
+	return &jsonExport{w: w, bw: bufio.NewWriter(w), filename: filename}
+}
+
+func (e *jsonExport) start() error {
+	e.started = true
+	e.w.Header().Set("Content-Type", "application/json")
+	if e.filename != "" {
+		e.w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", e.filename))
+	}
+	e.w.WriteHeader(http.StatusOK)
+	return e.bw.WriteByte('[')
+}
+
+func (e *jsonExport) write(v interface{}) error {
+	elem, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if !e.started {
+		if err := e.start(); err != nil {
+			return err
+		}
+	} else if err := e.bw.WriteByte(','); err != nil {
+		return err
+	}
+	_, err = e.bw.Write(elem)
+	return err
+}
+
+func (e *jsonExport) finish() error {
+	if !e.started {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+	if err := e.bw.WriteByte(']'); err != nil {
+		return err
+	}
+	return e.bw.Flush()
+}
+
+func (e *jsonExport) begun() bool {
+	return e.started
+}
+
+// abort sends what was written, leaving the array unclosed so that clients
+// cannot take it for the whole list.
+func (e *jsonExport) abort() {
+	e.bw.Flush()
+}
+
+func (e *jsonExport) name() string {
+	return e.filename
+}
+
+// export is a csvExport or a jsonExport.
+type export interface {
+	finish() error
+	begun() bool
+	abort()
+	name() string
+}
+
+// exportFormat returns the format of the export asked for by r, answering 400
+// when it is neither csv nor json.
+func (s *Server) exportFormat(w http.ResponseWriter, r *http.Request) (string, bool) {
+	switch format := r.URL.Query().Get("format"); format {
+	case "", "csv":
+		return "csv", true
+	case "json":
+		return format, true
+	default:
+		s.writeError(w, r, apierror.BadRequest(apierror.CodeBadRequest, fmt.Sprintf("unknown format %q, expected csv or json", format)))
+		return "", false
+	}
+}
+
+// finishOrFail completes the export, or reports err. Once the download has
+// started the status is sent, so err can only be logged.
+func (s *Server) finishOrFail(w http.ResponseWriter, r *http.Request, e export, err error) {
+	if err == nil {
+		err = e.finish()
+	}
+	if err == nil {
+		return
+	}
+
+	if !e.begun() {
+		s.writeError(w, r, storeError(err))
+		return
+	}
+	e.abort()
+	s.logger.Error().Err(err).Str("file", e.name()).Str("path", r.URL.Path).Msg("export aborted")
+}
+
+func (s *Server) handleExportRecipients() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, ok := s.exportFormat(w, r)
+		if !ok {
+			return
+		}
+
+		if format == "json" {
+			export := newJSONExport(w, "recipients.json")
+			err := s.recipientStore.EachRecipient(r.Context(), func(rcpt recipient.Recipient) error {
+				return export.write(rcpt)
+			})
+			s.finishOrFail(w, r, export, err)
+			return
+		}
+
+		export := newCSVExport(w, "recipients.csv", "id", "name", "email", "verified")
+		err := s.recipientStore.EachRecipient(r.Context(), func(rcpt recipient.Recipient) error {
+			return export.write(strconv.Itoa(rcpt.ID), rcpt.Name, rcpt.Email, strconv.FormatBool(rcpt.Verified))
+		})
+		s.finishOrFail(w, r, export, err)
+	}
+}
+
+func (s *Server) handleExportQuoteHistory() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, ok := s.exportFormat(w, r)
+		if !ok {
+			return
+		}
+		lang := r.URL.Query().Get("lang")
+
+		if format == "json" {
+			export := newJSONExport(w, "quotes.json")
+			err := s.quoteStore.EachQuote(r.Context(), lang, func(rec quote.Record) error {
+				return export.write(rec)
+			})
+			s.finishOrFail(w, r, export, err)
+			return
+		}
+
+		export := newCSVExport(w, "quotes.csv", "id", "text", "author", "lang", "createdAt")
+		err := s.quoteStore.EachQuote(r.Context(), lang, func(rec quote.Record) error {
+			return export.write(strconv.Itoa(rec.ID), rec.Text, rec.Author, rec.Lang, rec.CreatedAt.UTC().Format(time.RFC3339))
+		})
+		s.finishOrFail(w, r, export, err)
+	}
+}
+
+// streamRecipients answers with every recipient whose name or email contains
+// query, ignoring case, in id order. The recipients are encoded as they are
+// read, so the response never sits in memory whole.
+func (s *Server) streamRecipients(w http.ResponseWriter, r *http.Request, query string) {
+	query = strings.ToLower(query)
+	export := newJSONExport(w, "")
+	err := s.recipientStore.EachRecipient(r.Context(), func(rcpt recipient.Recipient) error {
+		if !strings.Contains(strings.ToLower(rcpt.Name), query) && !strings.Contains(strings.ToLower(rcpt.Email), query) {
+			return nil
+		}
+		return export.write(rcpt)
+	})
+	s.finishOrFail(w, r, export, err)
+}
@@ -0,0 +1,253 @@
+// internal/server/export_test.go
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandleExportRecipients(t *testing.T) {
+	recipients := []recipient.Recipient{
+		{ID: 1, Name: "user1", Email: "user1@testmail.com", Verified: true},
+		{ID: 2, Name: "Doe, Jane", Email: "jane@testmail.com"},
+	}
+
+	testCases := []struct {
+		name           string
+		recipients     []recipient.Recipient
+		err            error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			"Exported",
+			recipients,
+			nil,
+			http.StatusOK,
+			"id,name,email,verified\n1,user1,user1@testmail.com,true\n2,\"Doe, Jane\",jane@testmail.com,false\n",
+		},
+		{
+			"Empty",
+			nil,
+			nil,
+			http.StatusOK,
+			"id,name,email,verified\n",
+		},
+		{
+			"StoreFail",
+			nil,
+			errors.New("sample error"),
+			http.StatusServiceUnavailable,
+			`{"code":"DB_ERROR","message":"database unavailable"}`,
+		},
+		{
+			"StoreFailMidway",
+			recipients[:1],
+			errors.New("sample error"),
+			http.StatusOK,
+			"id,name,email,verified\n1,user1,user1@testmail.com,true\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRecipientStore := MockRecipientStore{}
+			mockRecipientStore.On("EachRecipient", mock.Anything).Return(tc.recipients, tc.err)
+			svr := newTestServer(t, WithRecipientStore(&mockRecipientStore))
+
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/recipients/export", nil)
+
+			svr.handleExportRecipients()(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			assert.Equal(t, tc.expectedBody, rr.Body.String(), "Response HTTP body in different than expected")
+			if tc.expectedStatus == http.StatusOK {
+				assert.Equal(t, "text/csv; charset=utf-8", rr.Header().Get("Content-Type"), "Response content type is different than expected")
+				assert.Equal(t, `attachment; filename="recipients.csv"`, rr.Header().Get("Content-Disposition"), "Response disposition is different than expected")
+			}
+		})
+	}
+}
+
+func TestHandleExportQuoteHistory(t *testing.T) {
+	createdAt := time.Date(2019, 6, 1, 10, 0, 0, 0, time.UTC)
+	records := []quote.Record{
+		{ID: 1, Quote: quote.Quote{Text: "Bla \"Bla\"", Author: "Bob", Lang: "en"}, CreatedAt: createdAt},
+	}
+
+	mockQuoteStore := MockQuoteStore{}
+	mockQuoteStore.On("EachQuote", mock.Anything, "en").Return(records, nil)
+	svr := newTestServer(t, WithQuoteStore(&mockQuoteStore))
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/quotes/history/export?lang=en", nil)
+
+	svr.handleExportQuoteHistory()(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+	assert.Equal(t, "id,text,author,lang,createdAt\n1,\"Bla \"\"Bla\"\"\",Bob,en,2019-06-01T10:00:00Z\n", rr.Body.String(), "Response HTTP body in different than expected")
+	assert.Equal(t, `attachment; filename="quotes.csv"`, rr.Header().Get("Content-Disposition"), "Response disposition is different than expected")
+	mockQuoteStore.AssertExpectations(t)
+}
+
+func TestHandleExportRecipients_JSON(t *testing.T) {
+	recipients := []recipient.Recipient{
+		{ID: 1, Name: "user1", Email: "user1@testmail.com", Verified: true},
+		{ID: 2, Name: "Doe, Jane", Email: "jane@testmail.com"},
+	}
+
+	testCases := []struct {
+		name           string
+		recipients     []recipient.Recipient
+		err            error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			"Exported",
+			recipients,
+			nil,
+			http.StatusOK,
+			`[{"id":1,"name":"user1","email":"user1@testmail.com","verified":true},{"id":2,"name":"Doe, Jane","email":"jane@testmail.com","verified":false}]`,
+		},
+		{
+			"Empty",
+			nil,
+			nil,
+			http.StatusOK,
+			"[]",
+		},
+		{
+			"StoreFail",
+			nil,
+			errors.New("sample error"),
+			http.StatusServiceUnavailable,
+			`{"code":"DB_ERROR","message":"database unavailable"}`,
+		},
+		{
+			"StoreFailMidway",
+			recipients[:1],
+			errors.New("sample error"),
+			http.StatusOK,
+			`[{"id":1,"name":"user1","email":"user1@testmail.com","verified":true}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRecipientStore := MockRecipientStore{}
+			mockRecipientStore.On("EachRecipient", mock.Anything).Return(tc.recipients, tc.err)
+			svr := newTestServer(t, WithRecipientStore(&mockRecipientStore))
+
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/recipients/export?format=json", nil)
+
+			svr.handleExportRecipients()(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			assert.Equal(t, tc.expectedBody, rr.Body.String(), "Response HTTP body in different than expected")
+			if tc.expectedStatus == http.StatusOK {
+				assert.Equal(t, "application/json", rr.Header().Get("Content-Type"), "Response content type is different than expected")
+				assert.Equal(t, `attachment; filename="recipients.json"`, rr.Header().Get("Content-Disposition"), "Response disposition is different than expected")
+			}
+		})
+	}
+}
+
+func TestHandleExportQuoteHistory_JSON(t *testing.T) {
+	createdAt := time.Date(2019, 6, 1, 10, 0, 0, 0, time.UTC)
+	records := []quote.Record{
+		{ID: 1, Quote: quote.Quote{Text: "Bla \"Bla\"", Author: "Bob", Lang: "en"}, CreatedAt: createdAt},
+	}
+
+	mockQuoteStore := MockQuoteStore{}
+	mockQuoteStore.On("EachQuote", mock.Anything, "en").Return(records, nil)
+	svr := newTestServer(t, WithQuoteStore(&mockQuoteStore))
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/quotes/history/export?lang=en&format=json", nil)
+
+	svr.handleExportQuoteHistory()(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+	var got []quote.Record
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got), "Got error when not expected")
+	assert.Equal(t, records, got, "Response HTTP body in different than expected")
+	assert.Equal(t, `attachment; filename="quotes.json"`, rr.Header().Get("Content-Disposition"), "Response disposition is different than expected")
+	mockQuoteStore.AssertExpectations(t)
+}
+
+func TestHandleExport_UnknownFormat(t *testing.T) {
+	svr := newTestServer(t, WithRecipientStore(&MockRecipientStore{}))
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/recipients/export?format=xml", nil)
+
+	svr.handleExportRecipients()(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Response HTTP status in different than expected")
+}
+
+func TestHandleListRecipients_All(t *testing.T) {
+	recipients := []recipient.Recipient{
+		{ID: 1, Name: "user1", Email: "user1@testmail.com", Verified: true},
+		{ID: 2, Name: "Doe, Jane", Email: "jane@testmail.com"},
+	}
+
+	testCases := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			"All",
+			"all=true",
+			http.StatusOK,
+			`[{"id":1,"name":"user1","email":"user1@testmail.com","verified":true},{"id":2,"name":"Doe, Jane","email":"jane@testmail.com","verified":false}]`,
+		},
+		{
+			"Query",
+			"all=true&q=JANE",
+			http.StatusOK,
+			`[{"id":2,"name":"Doe, Jane","email":"jane@testmail.com","verified":false}]`,
+		},
+		{
+			"NoMatch",
+			"all=true&q=nobody",
+			http.StatusOK,
+			"[]",
+		},
+		{
+			"WithOrder",
+			"all=true&order=name",
+			http.StatusBadRequest,
+			"all cannot be combined with offset or order",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRecipientStore := MockRecipientStore{}
+			mockRecipientStore.On("EachRecipient", mock.Anything).Return(recipients, nil)
+			svr := newTestServer(t, WithRecipientStore(&mockRecipientStore))
+
+			req, _ := http.NewRequest("GET", "/recipients?"+tc.query, nil)
+			rr := makeHTTPCall(svr.router, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			assert.Contains(t, rr.Body.String(), tc.expectedBody, "Response HTTP body in different than expected")
+			mockRecipientStore.AssertNotCalled(t, "ListRecipients", mock.Anything, mock.Anything)
+		})
+	}
+}
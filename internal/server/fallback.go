@@ -0,0 +1,104 @@
+// internal/server/fallback.go
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"net/http"
+	"strings"
+)
+
+// WithDefaultLang sets the language of quotes requested without one.
+func WithDefaultLang(lang string) Option {
+	return func(s *Server) error {
+		s.defaultLang = lang
+		return nil
+	}
+}
+
+// WithLangFallbacks sets the languages GET /quote falls back to, as pairs
+// such as "de:en", when a language is unsupported or its provider fails.
+// Fallbacks chain, so "de:fr" and "fr:en" try de, then fr, then en.
+func WithLangFallbacks(pairs ...string) Option {
+	return func(s *Server) error {
+		fallbacks := make(map[string]string, len(pairs))
+		for _, pair := range pairs {
+			from, to, ok := strings.Cut(pair, ":")
+			if !ok || from == "" || to == "" || from == to {
+				return fmt.Errorf("server: language fallback %q is not from:to", pair)
+			}
+			fallbacks[from] = to
+		}
+		s.langFallbacks = fallbacks
+		return nil
+	}
+}
+
+// fallbackChain lists lang followed by the languages it falls back to, in
+// order, stopping at the first repeat. Only lang is listed while the
+// lang-fallbacks feature is off.
+func (s *Server) fallbackChain(lang string) []string {
+	chain := []string{lang}
+	if !s.enabled(featureLangFallbacks) {
+		return chain
+	}
+	for next, ok := s.langFallbacks[lang]; ok; next, ok = s.langFallbacks[next] {
+		for _, l := range chain {
+			if l == next {
+				return chain
+			}
+		}
+		chain = append(chain, next)
+	}
+	return chain
+}
+
+// fallsBack reports whether lang, unsupported or not, falls back to a
+// supported language.
+func (s *Server) fallsBack(lang string) bool {
+	for _, l := range s.fallbackChain(lang)[1:] {
+		if s.checkLang(l) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// findQuoteFallback finds a quote like findQuote, moving down the fallback
+// chain of lang past unsupported languages and failing providers. fallback
+// reports whether the quote is in another language than lang.
+func (s *Server) findQuoteFallback(ctx context.Context, lang string, c quote.Constraints) (q *quote.Quote, fallback bool, err error) {
+	for _, l := range s.fallbackChain(lang) {
+		if s.checkLang(l) != nil {
+			continue
+		}
+
+		q, lerr := s.findQuote(ctx, l, c)
+		if lerr == nil {
+			return q, l != lang, nil
+		}
+		if err == nil {
+			err = lerr
+		}
+		if !providerFailed(lerr) {
+			return nil, false, err
+		}
+	}
+
+	if err == nil {
+		// No language of the chain is supported.
+		return nil, false, s.checkLang(lang)
+	}
+	return nil, false, err
+}
+
+// providerFailed reports whether err is the quote provider failing, which
+// another language may not. Running out of time is not, since the next
+// language would run out too.
+func providerFailed(err error) bool {
+	status := apierror.From(err).Status
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable
+}
@@ -0,0 +1,149 @@
+// internal/server/fallback_test.go
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHandleQuotes_Fallback(t *testing.T) {
+	testCases := []struct {
+		name             string
+		query            string
+		opts             []Option
+		failing          []string
+		expectedStatus   int
+		expectedLang     string
+		expectedFallback bool
+	}{
+		{
+			"Supported",
+			"lang=ru",
+			[]Option{WithLanguages([]string{"en", "ru"}, true), WithLangFallbacks("ru:en")},
+			nil,
+			http.StatusOK, "ru", false,
+		},
+		{
+			"Unsupported",
+			"lang=de",
+			[]Option{WithLanguages([]string{"en", "ru"}, true), WithLangFallbacks("de:en")},
+			nil,
+			http.StatusOK, "en", true,
+		},
+		{
+			"ProviderFails",
+			"lang=de",
+			[]Option{WithLangFallbacks("de:en")},
+			[]string{"de"},
+			http.StatusOK, "en", true,
+		},
+		{
+			"Chain",
+			"lang=de",
+			[]Option{WithLangFallbacks("de:fr", "fr:en")},
+			[]string{"de", "fr"},
+			http.StatusOK, "en", true,
+		},
+		{
+			"Cycle",
+			"lang=de",
+			[]Option{WithLangFallbacks("de:en", "en:de")},
+			[]string{"de", "en"},
+			http.StatusBadGateway, "", false,
+		},
+		{
+			"WithoutFallback",
+			"lang=de",
+			nil,
+			[]string{"de"},
+			http.StatusBadGateway, "", false,
+		},
+		{
+			"UnsupportedWithoutFallback",
+			"lang=de",
+			[]Option{WithLanguages([]string{"en", "ru"}, true), WithLangFallbacks("ru:en")},
+			nil,
+			http.StatusBadRequest, "", false,
+		},
+		{
+			"DefaultLang",
+			"",
+			[]Option{WithDefaultLang("ru")},
+			nil,
+			http.StatusOK, "ru", false,
+		},
+		{
+			"DefaultLangFallsBack",
+			"",
+			[]Option{WithLanguages([]string{"en"}, true), WithDefaultLang("de"), WithLangFallbacks("de:en")},
+			nil,
+			http.StatusOK, "en", true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			mockQuoteGenerator := MockQuoteGenerator{}
+			for _, lang := range tC.failing {
+				mockQuoteGenerator.On("Generate", mock.Anything, lang).Return(nil, errors.New("sample error"))
+			}
+			for _, lang := range []string{"en", "ru", "de", "fr"} {
+				mockQuoteGenerator.On("Generate", mock.Anything, lang).Return(&quote.Quote{Text: "Bla", Lang: lang}, nil)
+			}
+			mockRecipientsFetcher := MockRecipientsFetcher{}
+			mockRecipientsFetcher.On("ListRecipients", mock.Anything, defaultListOptions).Return([]recipient.Recipient{}, nil)
+
+			svr := newTestServer(t, append([]Option{
+				WithQuoteGenerator(&mockQuoteGenerator),
+				WithRecipientsFetcher(&mockRecipientsFetcher),
+			}, tC.opts...)...)
+
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/quote?"+tC.query, nil)
+
+			svr.handleQuotes()(rr, req)
+
+			assert.Equal(t, tC.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			if tC.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp HandleQuoteResponse
+			_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+			assert.Equal(t, tC.expectedLang, resp.Quote.Lang, "Quote language is different than expected")
+			assert.Equal(t, tC.expectedFallback, resp.Fallback, "Fallback flag is different than expected")
+		})
+	}
+}
+
+func TestWithLangFallbacks_Invalid(t *testing.T) {
+	for _, pair := range []string{"de", "de:", ":en", "en:en"} {
+		t.Run(pair, func(t *testing.T) {
+			_, err := NewServer(
+				WithQuoteGenerator(&MockQuoteGenerator{}),
+				WithRecipientsFetcher(&MockRecipientsFetcher{}),
+				WithLangFallbacks(pair),
+			)
+
+			assert.Error(t, err, "Got no error when expected")
+		})
+	}
+}
+
+func TestNewServer_UnsupportedDefaultLang(t *testing.T) {
+	_, err := NewServer(
+		WithQuoteGenerator(&MockQuoteGenerator{}),
+		WithRecipientsFetcher(&MockRecipientsFetcher{}),
+		WithLanguages([]string{"en"}, true),
+		WithDefaultLang("de"),
+	)
+
+	assert.EqualError(t, err, `server: default language: unsupported language "de"`, "Error is different than expected")
+}
@@ -0,0 +1,52 @@
+// internal/server/favorites.go
+
+package server
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/jeniok12/golang-tdd-example/internal/audit"
+	"net/http"
+	"strconv"
+)
+
+func (s *Server) handleAddFavorite() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.writeError(w, r, errInvalidID)
+			return
+		}
+
+		var req AddFavoriteRequest
+		if !s.bindRequest(w, r, &req) {
+			return
+		}
+
+		favorite, err := s.favoriteStore.AddFavorite(r.Context(), id, req.QuoteID)
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+		s.audit(r, audit.ActionUpdate, audit.EntityRecipient, id)
+
+		writeJSON(w, http.StatusCreated, favorite)
+	}
+}
+
+func (s *Server) handleListFavorites() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.writeError(w, r, errInvalidID)
+			return
+		}
+
+		favorites, err := s.favoriteStore.ListFavorites(r.Context(), id)
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, favorites)
+	}
+}
@@ -0,0 +1,138 @@
+// internal/server/favorites_test.go
+
+package server
+
+import (
+	"context"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type MockFavoriteStore struct {
+	mock.Mock
+}
+
+func (m *MockFavoriteStore) AddFavorite(ctx context.Context, recipientID, quoteID int) (*recipient.Favorite, error) {
+	args := m.Called(ctx, recipientID, quoteID)
+	f, _ := args.Get(0).(*recipient.Favorite)
+	return f, args.Error(1)
+}
+
+func (m *MockFavoriteStore) ListFavorites(ctx context.Context, recipientID int) ([]recipient.Favorite, error) {
+	args := m.Called(ctx, recipientID)
+	f, _ := args.Get(0).([]recipient.Favorite)
+	return f, args.Error(1)
+}
+
+func TestHandleAddFavorite(t *testing.T) {
+	testCases := []struct {
+		name           string
+		body           string
+		createMock     func() *MockFavoriteStore
+		expectedStatus int
+	}{
+		{
+			"Created",
+			`{"quoteId":7}`,
+			func() *MockFavoriteStore {
+				m := MockFavoriteStore{}
+				m.On("AddFavorite", mock.Anything, 1, 7).Return(&recipient.Favorite{QuoteID: 7}, nil)
+				return &m
+			},
+			http.StatusCreated,
+		},
+		{
+			"MissingQuoteID",
+			`{}`,
+			func() *MockFavoriteStore { return &MockFavoriteStore{} },
+			http.StatusBadRequest,
+		},
+		{
+			"RecipientNotFound",
+			`{"quoteId":7}`,
+			func() *MockFavoriteStore {
+				m := MockFavoriteStore{}
+				m.On("AddFavorite", mock.Anything, 1, 7).Return(nil, recipient.ErrNotFound)
+				return &m
+			},
+			http.StatusNotFound,
+		},
+		{
+			"QuoteNotFound",
+			`{"quoteId":7}`,
+			func() *MockFavoriteStore {
+				m := MockFavoriteStore{}
+				m.On("AddFavorite", mock.Anything, 1, 7).Return(nil, recipient.ErrQuoteNotFound)
+				return &m
+			},
+			http.StatusNotFound,
+		},
+		{
+			"StoreFail",
+			`{"quoteId":7}`,
+			func() *MockFavoriteStore {
+				m := MockFavoriteStore{}
+				m.On("AddFavorite", mock.Anything, 1, 7).Return(nil, errors.New("sample error"))
+				return &m
+			},
+			http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockFavoriteStore := tc.createMock()
+			svr := newTestServer(t, WithFavoriteStore(mockFavoriteStore))
+
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/recipients/1/favorites", strings.NewReader(tc.body))
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+
+			svr.handleAddFavorite()(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			mockFavoriteStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandleListFavorites(t *testing.T) {
+	testCases := []struct {
+		name           string
+		favorites      []recipient.Favorite
+		err            error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{"Empty", []recipient.Favorite{}, nil, http.StatusOK, `[]`},
+		{"NotFound", nil, recipient.ErrNotFound, http.StatusNotFound, ""},
+		{"StoreFail", nil, errors.New("sample error"), http.StatusServiceUnavailable, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockFavoriteStore := MockFavoriteStore{}
+			mockFavoriteStore.On("ListFavorites", mock.Anything, 1).Return(tc.favorites, tc.err)
+			svr := newTestServer(t, WithFavoriteStore(&mockFavoriteStore))
+
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/recipients/1/favorites", nil)
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+
+			svr.handleListFavorites()(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			if tc.expectedBody != "" {
+				assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Response HTTP body in different than expected")
+			}
+			mockFavoriteStore.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,118 @@
+// internal/server/features.go
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/featureflag"
+	"net/http"
+)
+
+// Features that can be turned off without a redeploy.
+const (
+	featureTranslation   = "translation"
+	featureQuoteCache    = "quote-cache"
+	featureQuoteBatch    = "quote-batch"
+	featureLangFallbacks = "lang-fallbacks"
+)
+
+// newFeatures registers the server's feature flags, all on by default.
+func newFeatures() *featureflag.Registry {
+	r := featureflag.NewRegistry()
+	r.Register(featureTranslation, "translate quotes from a source language on GET /quote", true)
+	r.Register(featureQuoteCache, "serve quotes from the quote cache", true)
+	r.Register(featureQuoteBatch, "draw several quotes at once on GET /quotes", true)
+	r.Register(featureLangFallbacks, "fall back to other languages for unsupported or failing ones", true)
+	return r
+}
+
+// WithFeatures sets feature flags from specs such as "translation=off".
+func WithFeatures(specs ...string) Option {
+	return func(s *Server) error {
+		if err := s.features.Configure(specs); err != nil {
+			return fmt.Errorf("server: %v", err)
+		}
+		return nil
+	}
+}
+
+// enabled reports whether feature is on. Features are on in servers not
+// built by NewServer.
+func (s *Server) enabled(feature string) bool {
+	return s.features == nil || s.features.Enabled(feature)
+}
+
+// requireFeature answers 404 while feature is off, as if the route did not
+// exist.
+func (s *Server) requireFeature(feature string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !s.enabled(feature) {
+			s.writeError(w, r, apierror.NotFound("the "+feature+" feature is turned off"))
+			return
+		}
+		next(w, r)
+	}
+}
+
+// FeaturesResponse lists the feature flags.
+type FeaturesResponse struct {
+	Features []featureflag.State `json:"features"`
+}
+
+// FeatureOverride turns a feature on or off at runtime.
+type FeatureOverride struct {
+	Enabled *bool `json:"enabled" validate:"required" doc:"whether the feature is on"`
+}
+
+func (s *Server) handleFeatures() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, FeaturesResponse{Features: s.features.States()})
+	}
+}
+
+// handleOverrideFeature overrides the feature in the path until the override
+// is deleted or the server restarts.
+func (s *Server) handleOverrideFeature() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req FeatureOverride
+		if !s.bindRequest(w, r, &req) {
+			return
+		}
+
+		name := mux.Vars(r)["name"]
+		state, err := s.features.Override(name, *req.Enabled)
+		if err != nil {
+			s.writeError(w, r, featureError(name, err))
+			return
+		}
+		s.logger.Info().Str("feature", name).Bool("enabled", state.Enabled).Msg("feature overridden by admin")
+
+		writeJSON(w, http.StatusOK, state)
+	}
+}
+
+// handleClearFeature brings the feature in the path back to its configured
+// value.
+func (s *Server) handleClearFeature() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := mux.Vars(r)["name"]
+		state, err := s.features.ClearOverride(name)
+		if err != nil {
+			s.writeError(w, r, featureError(name, err))
+			return
+		}
+		s.logger.Info().Str("feature", name).Bool("enabled", state.Enabled).Msg("feature override cleared by admin")
+
+		writeJSON(w, http.StatusOK, state)
+	}
+}
+
+func featureError(name string, err error) error {
+	if errors.Is(err, featureflag.ErrUnknown) {
+		return apierror.NotFound("no feature " + name)
+	}
+	return apierror.Internal(err)
+}
@@ -0,0 +1,131 @@
+// internal/server/features_test.go
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/jeniok12/golang-tdd-example/featureflag"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithFeatures(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		specs              []string
+		expectedToGetError bool
+	}{
+		{"Known", []string{"translation=off", "quote-cache=on"}, false},
+		{"Unknown", []string{"teleport=on"}, true},
+		{"InvalidValue", []string{"translation=maybe"}, true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			_, err := NewServer(
+				WithQuoteGenerator(&MockQuoteGenerator{}),
+				WithRecipientsFetcher(&MockRecipientsFetcher{}),
+				WithFeatures(tC.specs...),
+			)
+			if tC.expectedToGetError {
+				assert.Error(t, err, "Got no error when expected")
+				return
+			}
+			assert.NoError(t, err, "Got error when not expected")
+		})
+	}
+}
+
+func TestHandleFeatures(t *testing.T) {
+	svr := newTestServer(t, WithFeatures("quote-batch=off"))
+
+	testCases := []struct {
+		desc           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+		expectedState  featureflag.State
+	}{
+		{"TurnOn", "PUT", "/admin/features/quote-batch", `{"enabled":true}`, http.StatusOK, featureflag.State{Enabled: true, Overridden: true}},
+		{"MissingEnabled", "PUT", "/admin/features/quote-batch", `{}`, http.StatusBadRequest, featureflag.State{Enabled: true, Overridden: true}},
+		{"InvalidJSON", "PUT", "/admin/features/quote-batch", `{`, http.StatusBadRequest, featureflag.State{Enabled: true, Overridden: true}},
+		{"Unknown", "PUT", "/admin/features/teleport", `{"enabled":true}`, http.StatusNotFound, featureflag.State{Enabled: true, Overridden: true}},
+		{"Clear", "DELETE", "/admin/features/quote-batch", "", http.StatusOK, featureflag.State{Enabled: false}},
+		{"ClearUnknown", "DELETE", "/admin/features/teleport", "", http.StatusNotFound, featureflag.State{Enabled: false}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			req := asAdmin(httptest.NewRequest(tC.method, tC.path, strings.NewReader(tC.body)))
+			rr := makeHTTPCall(svr.router, req)
+			assert.Equal(t, tC.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+
+			req = asAdmin(httptest.NewRequest("GET", "/admin/features", nil))
+			rr = makeHTTPCall(svr.router, req)
+			assert.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+
+			var resp FeaturesResponse
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp), "Got error when not expected")
+			for _, s := range resp.Features {
+				if s.Name == featureQuoteBatch {
+					assert.Equal(t, tC.expectedState, featureflag.State{Enabled: s.Enabled, Overridden: s.Overridden})
+				}
+			}
+		})
+	}
+}
+
+func TestFeatures_QuoteBatch(t *testing.T) {
+	svr := newTestServer(t, WithQuoteGenerator(&sequenceGenerator{distinct: 100}), WithFeatures("quote-batch=off"))
+
+	req, _ := http.NewRequest("GET", "/quotes?count=2", nil)
+	rr := makeHTTPCall(svr.router, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code, "Response HTTP status in different than expected")
+
+	svr.features.Override(featureQuoteBatch, true)
+	rr = makeHTTPCall(svr.router, req)
+	assert.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+}
+
+func TestFeatures_Translation(t *testing.T) {
+	svr := newTestServer(t, WithLanguages([]string{"en"}, true), WithTranslator(stubTranslator{}), WithFeatures("translation=off"))
+
+	_, _, err := svr.requestedQuote(context.Background(), quoteRequest{Lang: "fr", Source: "en"})
+	assert.Error(t, err, "Translation should be refused while turned off")
+}
+
+func TestFeatures_QuoteCache(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		specs         []string
+		expectedQuote string
+	}{
+		{"On", nil, "quote 1"},
+		{"Off", []string{"quote-cache=off"}, "quote 2"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			cached := &quote.Cached{Inner: &sequenceGenerator{distinct: 100}, Cache: quote.NewLRU(10), TTL: time.Minute}
+			_, _ = cached.Generate(context.Background(), "en")
+			svr := newTestServer(t, WithQuoteGenerator(cached), WithFeatures(tC.specs...))
+
+			q, err := svr.findQuote(context.Background(), "en", quote.Constraints{})
+			assert.NoError(t, err, "Got error when not expected")
+			assert.Equal(t, tC.expectedQuote, q.Text)
+		})
+	}
+}
+
+func TestFeatures_LangFallbacks(t *testing.T) {
+	svr := newTestServer(t, WithLanguages([]string{"en"}, true), WithLangFallbacks("de:en"), WithFeatures("lang-fallbacks=off"))
+
+	assert.Equal(t, []string{"de"}, svr.fallbackChain("de"))
+
+	svr.features.Override(featureLangFallbacks, true)
+	assert.Equal(t, []string{"de", "en"}, svr.fallbackChain("de"))
+}
@@ -0,0 +1,262 @@
+// internal/server/feed.go
+
+package server
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// defaultFeedItems is how many quotes the feeds list unless WithFeedItems
+// says otherwise.
+const defaultFeedItems = 20
+
+const (
+	rssContentType  = "application/rss+xml; charset=utf-8"
+	atomContentType = "application/atom+xml; charset=utf-8"
+)
+
+// feedTitle names both feeds.
+const feedTitle = "InspiringQuotes"
+
+// rssFeed is an RSS 2.0 document, see https://www.rssboard.org/rss-specification.
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	AtomNS  string     `xml:"xmlns:atom,attr"`
+	DCNS    string     `xml:"xmlns:dc,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	Self          atomLink  `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Description string  `xml:"description"`
+	Creator     string  `xml:"dc:creator,omitempty"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// atomFeed is an Atom document, see RFC 4287.
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  atomPerson  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	ID        string      `xml:"id"`
+	Title     string      `xml:"title"`
+	Updated   string      `xml:"updated"`
+	Published string      `xml:"published"`
+	Author    atomPerson  `xml:"author"`
+	Content   atomContent `xml:"content"`
+}
+
+type atomContent struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// WithFeedItems sets how many of the latest quotes the RSS and Atom feeds
+// list.
+func WithFeedItems(n int) Option {
+	return func(s *Server) error {
+		if n < 1 {
+			return fmt.Errorf("server: feed items must be positive, got %d", n)
+		}
+		s.feedItems = n
+		return nil
+	}
+}
+
+// feedBaseURL is the public URL of the server, or the one r was sent to when
+// none is configured.
+func (s *Server) feedBaseURL(r *http.Request) string {
+	if s.publicURL != "" {
+		return s.publicURL
+	}
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+// quoteGUID identifies a history quote for good, as a tag URI (RFC 4151).
+// Feed readers use it to tell new quotes from ones already shown.
+func quoteGUID(baseURL string, rec quote.Record) string {
+	host := baseURL
+	if u, err := url.Parse(baseURL); err == nil && u.Hostname() != "" {
+		host = u.Hostname()
+	}
+	return fmt.Sprintf("tag:%s,%d:quotes/%d", host, rec.CreatedAt.UTC().Year(), rec.ID)
+}
+
+// feedItemTitle shortens the quote so readers show something sensible as a
+// headline; the whole quote is in the item body.
+func feedItemTitle(q quote.Quote) string {
+	const maxLen = 80
+	title := q.Text
+	if runes := []rune(title); len(runes) > maxLen {
+		title = strings.TrimSpace(string(runes[:maxLen-1])) + "…"
+	}
+	if q.Author != "" {
+		title += " — " + q.Author
+	}
+	return title
+}
+
+// latestQuotes returns the quotes the feeds list and when the newest of them
+// was served.
+func (s *Server) latestQuotes(r *http.Request) ([]quote.Record, time.Time, error) {
+	records, err := s.quoteStore.ListQuotes(r.Context(), quote.ListFilter{
+		Lang:  r.URL.Query().Get("lang"),
+		Limit: s.feedItems,
+	})
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	var updated time.Time
+	for _, rec := range records {
+		if rec.ServedAt().After(updated) {
+			updated = rec.ServedAt()
+		}
+	}
+	return records, updated, nil
+}
+
+// writeFeed marshals feed after the XML declaration. Feeds revalidate like
+// the history they are built from.
+func writeFeed(w http.ResponseWriter, r *http.Request, contentType string, feed interface{}, updated time.Time) error {
+	body, err := xml.Marshal(feed)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	writeConditional(w, r, contentType, append([]byte(xml.Header), body...), updated)
+	return nil
+}
+
+func (s *Server) handleRSSFeed() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		records, updated, err := s.latestQuotes(r)
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+
+		base := s.feedBaseURL(r)
+		feed := rssFeed{
+			Version: "2.0",
+			AtomNS:  "http://www.w3.org/2005/Atom",
+			DCNS:    "http://purl.org/dc/elements/1.1/",
+			Channel: rssChannel{
+				Title:       feedTitle,
+				Link:        base + "/quotes/history",
+				Description: "The latest quotes served by " + feedTitle,
+				Self:        atomLink{Href: base + r.URL.RequestURI(), Rel: "self", Type: "application/rss+xml"},
+				Items:       make([]rssItem, 0, len(records)),
+			},
+		}
+		if !updated.IsZero() {
+			feed.Channel.LastBuildDate = updated.UTC().Format(time.RFC1123Z)
+		}
+		for _, rec := range records {
+			feed.Channel.Items = append(feed.Channel.Items, rssItem{
+				Title:       feedItemTitle(rec.Quote),
+				Description: rec.Text,
+				Creator:     rec.Author,
+				GUID:        rssGUID{Value: quoteGUID(base, rec)},
+				PubDate:     rec.CreatedAt.UTC().Format(time.RFC1123Z),
+			})
+		}
+
+		if err := writeFeed(w, r, rssContentType, feed, updated); err != nil {
+			s.writeError(w, r, err)
+		}
+	}
+}
+
+func (s *Server) handleAtomFeed() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		records, updated, err := s.latestQuotes(r)
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+
+		// An Atom feed always has an updated date, even when empty.
+		feedUpdated := updated
+		if feedUpdated.IsZero() {
+			feedUpdated = time.Unix(0, 0)
+		}
+
+		base := s.feedBaseURL(r)
+		self := base + r.URL.RequestURI()
+		feed := atomFeed{
+			ID:      self,
+			Title:   feedTitle,
+			Updated: feedUpdated.UTC().Format(time.RFC3339),
+			Author:  atomPerson{Name: feedTitle},
+			Links: []atomLink{
+				{Href: self, Rel: "self", Type: "application/atom+xml"},
+				{Href: base + "/quotes/history", Rel: "alternate", Type: "application/json"},
+			},
+			Entries: make([]atomEntry, 0, len(records)),
+		}
+		for _, rec := range records {
+			at := rec.CreatedAt.UTC().Format(time.RFC3339)
+			author := rec.Author
+			if author == "" {
+				author = "Unknown"
+			}
+			feed.Entries = append(feed.Entries, atomEntry{
+				ID:        quoteGUID(base, rec),
+				Title:     feedItemTitle(rec.Quote),
+				Updated:   at,
+				Published: at,
+				Author:    atomPerson{Name: author},
+				Content:   atomContent{Type: "text", Value: rec.Text},
+			})
+		}
+
+		if err := writeFeed(w, r, atomContentType, feed, updated); err != nil {
+			s.writeError(w, r, err)
+		}
+	}
+}
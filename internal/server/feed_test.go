@@ -0,0 +1,110 @@
+// internal/server/feed_test.go
+
+package server
+
+import (
+	"encoding/xml"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandleFeeds(t *testing.T) {
+	records := []quote.Record{
+		{ID: 2, Quote: quote.Quote{Text: "Bla Bla Bla", Author: "Bob", Lang: "en"}, CreatedAt: time.Date(2019, 9, 2, 10, 0, 0, 0, time.UTC)},
+		{ID: 1, Quote: quote.Quote{Text: "Bla & Bla", Lang: "en"}, CreatedAt: time.Date(2019, 9, 1, 10, 0, 0, 0, time.UTC)},
+	}
+
+	testCases := []struct {
+		name                string
+		path                string
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			"RSS",
+			"/quotes/feed.rss?lang=en",
+			"application/rss+xml; charset=utf-8",
+			xml.Header + `<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom" xmlns:dc="http://purl.org/dc/elements/1.1/"><channel>` +
+				`<title>InspiringQuotes</title><link>https://quotes.example.com/quotes/history</link><description>The latest quotes served by InspiringQuotes</description>` +
+				`<atom:link href="https://quotes.example.com/quotes/feed.rss?lang=en" rel="self" type="application/rss+xml"></atom:link>` +
+				`<lastBuildDate>Mon, 02 Sep 2019 10:00:00 +0000</lastBuildDate>` +
+				`<item><title>Bla Bla Bla — Bob</title><description>Bla Bla Bla</description><dc:creator>Bob</dc:creator>` +
+				`<guid isPermaLink="false">tag:quotes.example.com,2019:quotes/2</guid><pubDate>Mon, 02 Sep 2019 10:00:00 +0000</pubDate></item>` +
+				`<item><title>Bla &amp; Bla</title><description>Bla &amp; Bla</description>` +
+				`<guid isPermaLink="false">tag:quotes.example.com,2019:quotes/1</guid><pubDate>Sun, 01 Sep 2019 10:00:00 +0000</pubDate></item>` +
+				`</channel></rss>`,
+		},
+		{
+			"Atom",
+			"/quotes/feed.atom?lang=en",
+			"application/atom+xml; charset=utf-8",
+			xml.Header + `<feed xmlns="http://www.w3.org/2005/Atom"><id>https://quotes.example.com/quotes/feed.atom?lang=en</id>` +
+				`<title>InspiringQuotes</title><updated>2019-09-02T10:00:00Z</updated><author><name>InspiringQuotes</name></author>` +
+				`<link href="https://quotes.example.com/quotes/feed.atom?lang=en" rel="self" type="application/atom+xml"></link>` +
+				`<link href="https://quotes.example.com/quotes/history" rel="alternate" type="application/json"></link>` +
+				`<entry><id>tag:quotes.example.com,2019:quotes/2</id><title>Bla Bla Bla — Bob</title><updated>2019-09-02T10:00:00Z</updated>` +
+				`<published>2019-09-02T10:00:00Z</published><author><name>Bob</name></author><content type="text">Bla Bla Bla</content></entry>` +
+				`<entry><id>tag:quotes.example.com,2019:quotes/1</id><title>Bla &amp; Bla</title><updated>2019-09-01T10:00:00Z</updated>` +
+				`<published>2019-09-01T10:00:00Z</published><author><name>Unknown</name></author><content type="text">Bla &amp; Bla</content></entry>` +
+				`</feed>`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockQuoteStore := MockQuoteStore{}
+			mockQuoteStore.On("ListQuotes", mock.Anything, quote.ListFilter{Lang: "en", Limit: 5}).Return(records, nil)
+			svr := newTestServer(t, WithQuoteStore(&mockQuoteStore), WithFeedItems(5))
+			svr.publicURL = "https://quotes.example.com"
+
+			rr := makeHTTPCall(svr.router, httptest.NewRequest(http.MethodGet, tc.path, nil))
+
+			require.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+			assert.Equal(t, tc.expectedContentType, rr.Header().Get("Content-Type"), "Content-Type is different than expected")
+			assert.Equal(t, "Mon, 02 Sep 2019 10:00:00 GMT", rr.Header().Get("Last-Modified"), "Last-Modified should be the newest quote")
+			assert.Equal(t, tc.expectedBody, rr.Body.String(), "Feed is different than expected")
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Header.Set("If-None-Match", rr.Header().Get("ETag"))
+			rr = makeHTTPCall(svr.router, req)
+			assert.Equal(t, http.StatusNotModified, rr.Code, "Unchanged feed should not be resent")
+		})
+	}
+}
+
+func TestHandleFeeds_Empty(t *testing.T) {
+	mockQuoteStore := MockQuoteStore{}
+	mockQuoteStore.On("ListQuotes", mock.Anything, quote.ListFilter{Limit: defaultFeedItems}).Return([]quote.Record{}, nil)
+	svr := newTestServer(t, WithQuoteStore(&mockQuoteStore))
+
+	rr := makeHTTPCall(svr.router, httptest.NewRequest(http.MethodGet, "http://localhost:8080/quotes/feed.atom", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+	assert.Contains(t, rr.Body.String(), "<id>http://localhost:8080/quotes/feed.atom</id>", "The feed should be identified by the request URL")
+	assert.Contains(t, rr.Body.String(), "<updated>1970-01-01T00:00:00Z</updated>", "An empty feed should still have an updated date")
+}
+
+func TestHandleFeeds_StoreFail(t *testing.T) {
+	mockQuoteStore := MockQuoteStore{}
+	mockQuoteStore.On("ListQuotes", mock.Anything, mock.Anything).Return(nil, errors.New("sample error"))
+	svr := newTestServer(t, WithQuoteStore(&mockQuoteStore))
+
+	for _, path := range []string{"/quotes/feed.rss", "/quotes/feed.atom"} {
+		rr := makeHTTPCall(svr.router, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusServiceUnavailable, rr.Code, "Response HTTP status in different than expected")
+	}
+}
+
+func TestFeedItemTitle(t *testing.T) {
+	long := quote.Quote{Text: "Ночь, улица, фонарь, аптека, бессмысленный и тусклый свет. Живи ещё хоть четверть века — всё будет так. Исхода нет.", Author: "Блок"}
+
+	title := feedItemTitle(long)
+
+	assert.Equal(t, "Ночь, улица, фонарь, аптека, бессмысленный и тусклый свет. Живи ещё хоть четвер… — Блок", title, "Long quotes should be cut on runes")
+}
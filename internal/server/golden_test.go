@@ -0,0 +1,76 @@
+// internal/server/golden_test.go
+
+package server
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/internal/golden"
+	"github.com/jeniok12/golang-tdd-example/internal/memstore"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/jeniok12/golang-tdd-example/requestid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestGoldenResponses pins the JSON clients get back. Run it with -update to
+// accept a schema change, then review the diff of testdata/golden.
+func TestGoldenResponses(t *testing.T) {
+	generator := &MockQuoteGenerator{}
+	generator.On("Generate", mock.Anything, "en").Return(&quote.Quote{Text: "Bla Bla Bla", Author: "Bob", Lang: "en"}, nil)
+
+	recipients := memstore.NewRecipients(memstore.NewQuotes())
+	for _, rcpt := range []recipient.Recipient{
+		{Name: "user1", Email: "user1@testmail.com"},
+		{Name: "user2", Email: "user2@testmail.com"},
+	} {
+		_, err := recipients.Create(context.Background(), rcpt)
+		require.NoError(t, err, "Should have no error when adding recipients")
+	}
+
+	svr := newTestServer(t,
+		WithQuoteGenerator(generator),
+		WithRecipientsFetcher(recipients),
+		WithRecipientStore(recipients),
+		WithQuoteProvider("forismatic"),
+	)
+	// The v2 meta block reports the quote latency, which is kept at 0.
+	svr.now = func() time.Time { return time.Date(2019, 9, 1, 10, 0, 0, 0, time.UTC) }
+
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{"quote", http.MethodGet, "/quote?lang=en", "", http.StatusOK},
+		{"quote_v2", http.MethodGet, "/v2/quote?lang=en&include=recipients", "", http.StatusOK},
+		{"recipients_list", http.MethodGet, "/recipients", "", http.StatusOK},
+		{"recipients_get", http.MethodGet, "/recipients/1", "", http.StatusOK},
+		{"error_not_found", http.MethodGet, "/recipients/42", "", http.StatusNotFound},
+		{"error_validation", http.MethodPost, "/recipients", `{"name":"","email":"not an email"}`, http.StatusBadRequest},
+		{"error_bad_json", http.MethodPost, "/recipients", `{"name":`, http.StatusBadRequest},
+		{"error_bad_query", http.MethodGet, "/recipients?limit=-1", "", http.StatusBadRequest},
+		{"error_method_not_allowed", http.MethodDelete, "/quote", "", http.StatusMethodNotAllowed},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			req.Header.Set(requestid.Header, "req-1")
+			if tc.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			rr := makeHTTPCall(svr.router, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			golden.JSON(t, tc.name, rr.Body.Bytes())
+		})
+	}
+}
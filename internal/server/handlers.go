@@ -0,0 +1,840 @@
+// internal/server/handlers.go
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/bind"
+	"github.com/jeniok12/golang-tdd-example/internal/audit"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/jeniok12/golang-tdd-example/internal/webhook"
+	"github.com/jeniok12/golang-tdd-example/requestid"
+	"github.com/jeniok12/golang-tdd-example/worker"
+	"golang.org/x/sync/errgroup"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HandleQuoteResponse ..
+type HandleQuoteResponse struct {
+	XMLName    xml.Name              `json:"-" xml:"quoteResponse"`
+	Quote      *quote.Quote          `json:"quote" xml:"quote"`
+	Recipients []recipient.Recipient `json:"recipients" xml:"recipients>recipient"`
+	// Fallback is set when the quote is in a fallback language rather than
+	// the requested one.
+	Fallback bool `json:"fallback,omitempty" xml:"fallback,omitempty"`
+	// Warnings are set when parts of the response could not be served, such
+	// as the recipients while the database is down.
+	Warnings []string `json:"warnings,omitempty" xml:"warning,omitempty"`
+}
+
+// String renders the response as plain text.
+func (hqr HandleQuoteResponse) String() string {
+	var b strings.Builder
+	if hqr.Quote != nil {
+		fmt.Fprintf(&b, "%s\n- %s\n", hqr.Quote.Text, hqr.Quote.Author)
+	}
+
+	if len(hqr.Recipients) > 0 {
+		b.WriteString("\nRecipients:\n")
+		for _, r := range hqr.Recipients {
+			fmt.Fprintf(&b, "%s <%s>\n", r.Name, r.Email)
+		}
+	}
+
+	return b.String()
+}
+
+// quoteResult is what a /quote request found, before it is rendered in the
+// schema of an API version.
+type quoteResult struct {
+	Quote      *quote.Quote
+	Recipients []recipient.Recipient
+	Fallback   bool
+	// Provider is the provider the quote came from.
+	Provider string
+	// Latency is how long getting the quote took.
+	Latency time.Duration
+	// Warnings explain what is missing from a degraded response.
+	Warnings []string
+}
+
+// warnRecipientsUnavailable is the warning of quotes served without the
+// recipients that were asked for.
+const warnRecipientsUnavailable = "recipients are temporarily unavailable"
+
+// quoteVersion renders /quote responses for one API version.
+type quoteVersion struct {
+	render func(r *http.Request, res quoteResult) fmt.Stringer
+	// recipients says whether recipients are listed unless the request
+	// says otherwise.
+	recipients bool
+	// conditional responses carry an ETag. Versions whose body changes on
+	// every request, as it holds the request ID, cannot be revalidated.
+	conditional bool
+}
+
+// quoteV1 is the original schema, served at /quote and /v1/quote.
+var quoteV1 = quoteVersion{
+	render: func(r *http.Request, res quoteResult) fmt.Stringer {
+		return HandleQuoteResponse{
+			Quote:      res.Quote,
+			Recipients: res.Recipients,
+			Fallback:   res.Fallback,
+			Warnings:   res.Warnings,
+		}
+	},
+	recipients:  true,
+	conditional: true,
+}
+
+func (s *Server) handleQuotes() http.HandlerFunc {
+	return s.quoteHandler(quoteV1)
+}
+
+// quoteHandler serves /quote, rendering the result as v says.
+func (s *Server) quoteHandler(v quoteVersion) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+		format, err := negotiateFormat(r)
+		if err != nil {
+			s.writeError(w, r, apierror.BadRequest(apierror.CodeBadRequest, err.Error()))
+			return
+		}
+
+		var req quoteRequest
+		if !s.bindRequest(w, r, &req) {
+			return
+		}
+
+		if req.Tags, err = normalizeTags(req.Tags); err != nil {
+			s.writeFieldErrors(w, r, bind.Errors{{Field: "tags", Message: err.Error()}})
+			return
+		}
+		if c := req.constraints(); c.MaxLength > 0 && c.MaxLength < c.MinLength {
+			s.writeFieldErrors(w, r, bind.Errors{{Field: "max_length", Message: fmt.Sprintf("must be at least %d for %s quotes", c.MinLength, req.Style)}})
+			return
+		}
+
+		if req.Lang == "" {
+			req.Lang = s.defaultLang
+		}
+		// Without a source, lang must be a language the providers have or
+		// one falling back to such a language.
+		if msg := s.langMessage(req.Lang); req.Source == "" && msg != "" && !s.fallsBack(req.Lang) {
+			s.writeFieldErrors(w, r, bind.Errors{{Field: "lang", Message: msg}})
+			return
+		}
+
+		// The provider and the database are asked at the same time. A failed
+		// quote cancels the recipients, which are useless without it, but an
+		// unavailable database only degrades the response. Asking for the
+		// recipients of an unknown group still fails.
+		var (
+			quote          *quote.Quote
+			fallback       bool
+			latency        time.Duration
+			recipients     []recipient.Recipient
+			recipientsErr  error
+			withRecipients = req.includes(includeRecipients, v.recipients)
+		)
+		g, ctx := errgroup.WithContext(r.Context())
+		g.Go(func() error {
+			start := s.now()
+			var err error
+			quote, fallback, err = s.requestedQuote(ctx, req)
+			latency = s.now().Sub(start)
+			return err
+		})
+		if withRecipients {
+			g.Go(func() error {
+				recipients, recipientsErr = s.quoteRecipients(ctx, req)
+				if e := storeError(recipientsErr); recipientsErr != nil && e.Status < http.StatusInternalServerError {
+					return e
+				}
+				return nil
+			})
+		}
+		if err := g.Wait(); err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+		s.notify(r.Context(), webhook.EventQuoteGenerated, quote)
+
+		var warnings []string
+		if recipientsErr != nil {
+			s.logger.Warn().Err(recipientsErr).Str("requestId", requestid.FromContext(r.Context())).Msg("serving quote without recipients")
+			warnings = append(warnings, warnRecipientsUnavailable)
+		}
+
+		provider := quote.Provider
+		if provider == "" {
+			provider = s.activeProvider()
+		}
+		body, err := encodeFormat(format, v.render(r, quoteResult{
+			Quote:      quote,
+			Recipients: recipients,
+			Fallback:   fallback,
+			Provider:   provider,
+			Latency:    latency,
+			Warnings:   warnings,
+		}))
+		if err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+
+		cacheControl := s.quoteCacheControl()
+		if len(warnings) > 0 {
+			// A degraded response must not outlive the outage.
+			cacheControl = "no-store"
+		}
+		w.Header().Set("Cache-Control", cacheControl)
+		if !v.conditional {
+			w.Header().Set("Content-Type", formatContentTypes[format])
+			w.Write(body)
+			return
+		}
+		writeConditional(w, r, formatContentTypes[format], body, time.Time{})
+	}
+}
+
+// quoteRecipients returns the recipients listed with a quote.
+func (s *Server) quoteRecipients(ctx context.Context, req quoteRequest) ([]recipient.Recipient, error) {
+	var recipients []recipient.Recipient
+	var err error
+	if req.Group != "" || len(req.Tags) > 0 {
+		recipients, err = s.recipientsFor(ctx, req.Group, req.Tags)
+	} else {
+		recipients, err = s.recipientsFetcher.ListRecipients(ctx, req.options())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.dropSuppressed(ctx, recipients)
+}
+
+// HandleSendQuoteResponse ...
+type HandleSendQuoteResponse struct {
+	Quote      *quote.Quote `json:"quote"`
+	Deliveries []Delivery   `json:"deliveries"`
+}
+
+func (s *Server) handleSendQuote() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		lang := r.URL.Query().Get("lang")
+		if err := s.checkLang(lang); err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+
+		tags, err := normalizeTags(strings.Split(r.URL.Query().Get("tags"), ","))
+		if err != nil {
+			s.writeError(w, r, apierror.BadRequest(apierror.CodeValidation, err.Error()))
+			return
+		}
+
+		quote, deliveries, err := s.deliverQuote(r.Context(), lang, r.URL.Query().Get("group"), tags)
+		if err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, HandleSendQuoteResponse{
+			Quote:      quote,
+			Deliveries: deliveries,
+		})
+	}
+}
+
+// DailyRunResponse ...
+type DailyRunResponse struct {
+	Runs []DailyRun `json:"runs"`
+}
+
+func (s *Server) handleDailyRun() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, DailyRunResponse{
+			Runs: s.runDailyQuotes(r.Context()),
+		})
+	}
+}
+
+const (
+	defaultHistoryLimit = 20
+	maxHistoryLimit     = 100
+)
+
+// QuoteHistoryResponse ...
+type QuoteHistoryResponse struct {
+	Quotes []quote.Record `json:"quotes"`
+	Limit  int            `json:"limit"`
+	Offset int            `json:"offset"`
+}
+
+func (s *Server) handleQuoteHistory() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		limit, err := intParam(query.Get("limit"), defaultHistoryLimit)
+		if err != nil || limit < 1 || limit > maxHistoryLimit {
+			s.writeError(w, r, apierror.BadRequest(apierror.CodeBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxHistoryLimit)))
+			return
+		}
+
+		offset, err := intParam(query.Get("offset"), 0)
+		if err != nil || offset < 0 {
+			s.writeError(w, r, apierror.BadRequest(apierror.CodeBadRequest, "offset must not be negative"))
+			return
+		}
+
+		records, err := s.quoteStore.ListQuotes(r.Context(), quote.ListFilter{
+			Lang:   query.Get("lang"),
+			Limit:  limit,
+			Offset: offset,
+		})
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+
+		body, err := json.Marshal(QuoteHistoryResponse{
+			Quotes: records,
+			Limit:  limit,
+			Offset: offset,
+		})
+		if err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+
+		var lastModified time.Time
+		for _, rec := range records {
+			if rec.ServedAt().After(lastModified) {
+				lastModified = rec.ServedAt()
+			}
+		}
+
+		// History only grows, but clients must still revalidate to see new quotes.
+		w.Header().Set("Cache-Control", "no-cache")
+		writeConditional(w, r, "application/json", body, lastModified)
+	}
+}
+
+// QuoteSearchResponse ...
+type QuoteSearchResponse struct {
+	Results []quote.SearchResult `json:"results"`
+	Limit   int                  `json:"limit"`
+	Offset  int                  `json:"offset"`
+}
+
+func (s *Server) handleSearchQuotes() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		q := strings.TrimSpace(query.Get("q"))
+		if len(quote.SearchWords(q)) == 0 {
+			s.writeError(w, r, apierror.BadRequest(apierror.CodeBadRequest, "q must contain at least one word"))
+			return
+		}
+
+		limit, err := intParam(query.Get("limit"), defaultHistoryLimit)
+		if err != nil || limit < 1 || limit > maxHistoryLimit {
+			s.writeError(w, r, apierror.BadRequest(apierror.CodeBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxHistoryLimit)))
+			return
+		}
+
+		offset, err := intParam(query.Get("offset"), 0)
+		if err != nil || offset < 0 {
+			s.writeError(w, r, apierror.BadRequest(apierror.CodeBadRequest, "offset must not be negative"))
+			return
+		}
+
+		results, err := s.quoteStore.SearchQuotes(r.Context(), quote.SearchFilter{
+			Query:  q,
+			Lang:   query.Get("lang"),
+			Limit:  limit,
+			Offset: offset,
+		})
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, QuoteSearchResponse{
+			Results: results,
+			Limit:   limit,
+			Offset:  offset,
+		})
+	}
+}
+
+// quoteCacheControl lets clients reuse a /quote response for as long as the
+// server would keep serving the same cached quote. The response lists
+// recipients, so shared caches must not store it.
+func (s *Server) quoteCacheControl() string {
+	if s.quoteCache == nil || s.quoteCache.CurrentTTL() < time.Second {
+		return "private, no-cache"
+	}
+	return fmt.Sprintf("private, max-age=%d", int(s.quoteCache.CurrentTTL().Seconds()))
+}
+
+// intParam parses an optional integer query parameter.
+func intParam(value string, def int) (int, error) {
+	if value == "" {
+		return def, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func (s *Server) handleListLocalQuotes() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		records, err := s.localQuotes.ListLocal(r.Context(), r.URL.Query().Get("lang"))
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, records)
+	}
+}
+
+func (s *Server) handleAddLocalQuote() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var q quote.Quote
+		if err := json.NewDecoder(r.Body).Decode(&q); err != nil {
+			s.writeError(w, r, errInvalidJSON)
+			return
+		}
+
+		if strings.TrimSpace(q.Text) == "" || strings.TrimSpace(q.Author) == "" || q.Lang == "" {
+			s.writeError(w, r, apierror.BadRequest(apierror.CodeValidation, "text, author and lang are required"))
+			return
+		}
+
+		record, err := s.localQuotes.AddLocal(r.Context(), q)
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+		s.audit(r, audit.ActionCreate, audit.EntityQuote, record.ID)
+
+		writeJSON(w, http.StatusCreated, record)
+	}
+}
+
+func (s *Server) handleRemoveLocalQuote() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.writeError(w, r, errInvalidID)
+			return
+		}
+
+		err = s.localQuotes.RemoveLocal(r.Context(), id)
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+		s.audit(r, audit.ActionDelete, audit.EntityQuote, id)
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// CreateGroupRequest ...
+type CreateGroupRequest struct {
+	Name string `json:"name"`
+}
+
+func (s *Server) handleListGroups() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groups, err := s.groupStore.ListGroups(r.Context())
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, groups)
+	}
+}
+
+func (s *Server) handleCreateGroup() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req CreateGroupRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			s.writeError(w, r, errInvalidJSON)
+			return
+		}
+
+		if strings.TrimSpace(req.Name) == "" {
+			s.writeError(w, r, apierror.BadRequest(apierror.CodeValidation, "name is required"))
+			return
+		}
+
+		group, err := s.groupStore.CreateGroup(r.Context(), req.Name)
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+		s.audit(r, audit.ActionCreate, audit.EntityGroup, group.ID)
+
+		writeJSON(w, http.StatusCreated, group)
+	}
+}
+
+func (s *Server) handleAddGroupMember() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID, recipientID, err := groupMemberVars(r)
+		if err != nil {
+			s.writeError(w, r, errInvalidID)
+			return
+		}
+
+		err = s.groupStore.AddMember(r.Context(), groupID, recipientID)
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+		s.audit(r, audit.ActionUpdate, audit.EntityGroup, groupID)
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) handleRemoveGroupMember() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		groupID, recipientID, err := groupMemberVars(r)
+		if err != nil {
+			s.writeError(w, r, errInvalidID)
+			return
+		}
+
+		err = s.groupStore.RemoveMember(r.Context(), groupID, recipientID)
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+		s.audit(r, audit.ActionUpdate, audit.EntityGroup, groupID)
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func groupMemberVars(r *http.Request) (int, int, error) {
+	vars := mux.Vars(r)
+
+	groupID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	recipientID, err := strconv.Atoi(vars["recipientId"])
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return groupID, recipientID, nil
+}
+
+// ProvidersResponse ...
+type ProvidersResponse struct {
+	Providers []string `json:"providers"`
+	Active    string   `json:"active"`
+}
+
+func (s *Server) handleProviders() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, ProvidersResponse{
+			Providers: quote.Providers(),
+			Active:    s.activeProvider(),
+		})
+	}
+}
+
+// ProvidersHealthResponse ...
+type ProvidersHealthResponse struct {
+	Providers []quote.ProviderHealth `json:"providers"`
+}
+
+func (s *Server) handleProvidersHealth() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.providerHealth == nil {
+			s.writeError(w, r, apierror.NotFound("provider health is not tracked"))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, ProvidersHealthResponse{Providers: s.providerHealth.Snapshot()})
+	}
+}
+
+// LanguagesResponse ...
+type LanguagesResponse struct {
+	Languages []string `json:"languages"`
+	// Any reports that some provider accepts languages beyond Languages.
+	Any bool `json:"any"`
+}
+
+func (s *Server) handleLanguages() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		languages := s.languages
+		if languages == nil {
+			languages = []string{}
+		}
+
+		writeJSON(w, http.StatusOK, LanguagesResponse{
+			Languages: languages,
+			Any:       !s.restrictLanguages,
+		})
+	}
+}
+
+// checkLang rejects languages no configured provider serves. An empty lang
+// leaves the choice to the provider.
+func (s *Server) checkLang(lang string) *apierror.Error {
+	if lang == "" || !s.restrictLanguages {
+		return nil
+	}
+
+	for _, supported := range s.languages {
+		if lang == supported {
+			return nil
+		}
+	}
+
+	return apierror.BadRequest(apierror.CodeInvalidLang, fmt.Sprintf("unsupported language %q", lang)).
+		WithDetails(map[string][]string{"supported": s.languages})
+}
+
+// MetricsResponse ...
+type MetricsResponse struct {
+	Cache    *quote.CacheStats             `json:"cache,omitempty"`
+	Breakers map[string]quote.BreakerStats `json:"breakers,omitempty"`
+	Jobs     map[string]worker.JobStats    `json:"jobs,omitempty"`
+}
+
+func (s *Server) handleMetrics() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var resp MetricsResponse
+		if s.quoteCache != nil {
+			stats := s.quoteCache.Stats()
+			resp.Cache = &stats
+		}
+		for name, b := range s.breakers {
+			if resp.Breakers == nil {
+				resp.Breakers = make(map[string]quote.BreakerStats, len(s.breakers))
+			}
+			resp.Breakers[name] = b.Stats()
+		}
+		if jobs := s.workers.Stats(); len(jobs) > 0 {
+			resp.Jobs = jobs
+		}
+
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
+
+func (s *Server) handleListRecipients() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req recipientsRequest
+		if !s.bindRequest(w, r, &req) {
+			return
+		}
+		if req.All {
+			if req.Offset > 0 || req.Order != "" {
+				s.writeError(w, r, apierror.BadRequest(apierror.CodeBadRequest, "all cannot be combined with offset or order"))
+				return
+			}
+			s.streamRecipients(w, r, req.Query)
+			return
+		}
+
+		recipients, err := s.recipientStore.ListRecipients(r.Context(), req.options())
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+
+		if recipients == nil {
+			recipients = []recipient.Recipient{}
+		}
+
+		writeJSON(w, http.StatusOK, recipients)
+	}
+}
+
+func (s *Server) handleCreateRecipient() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req RecipientRequest
+		if !s.bindRequest(w, r, &req) {
+			return
+		}
+
+		rcpt := recipient.Recipient{Name: req.Name, Email: req.Email}
+		// Checked before the transaction, which holds SQLite's only connection.
+		verify := s.verifiable(r.Context(), rcpt)
+
+		var created *recipient.Recipient
+		err := s.withTx(r.Context(), func(repos TxRepositories) error {
+			var err error
+			if created, err = repos.Recipients.Create(r.Context(), rcpt); err != nil {
+				return err
+			}
+			if err := auditTx(r, repos, audit.ActionCreate, audit.EntityRecipient, created.ID); err != nil {
+				return err
+			}
+			if verify {
+				return s.queueVerification(r.Context(), repos, *created)
+			}
+			return nil
+		})
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+		if verify && s.outbox == nil {
+			s.mailVerification(r.Context(), *created)
+		}
+
+		writeJSON(w, http.StatusCreated, created)
+	}
+}
+
+func (s *Server) handleGetRecipient() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.writeError(w, r, errInvalidID)
+			return
+		}
+
+		rcpt, err := s.recipientStore.GetByID(r.Context(), id)
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+
+		w.Header().Set("ETag", recipientETag(rcpt))
+		writeJSON(w, http.StatusOK, rcpt)
+	}
+}
+
+func (s *Server) handleUpdateRecipient() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.writeError(w, r, errInvalidID)
+			return
+		}
+
+		version, err := ifMatchVersion(r)
+		if err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+
+		var req RecipientRequest
+		if !s.bindRequest(w, r, &req) {
+			return
+		}
+
+		rcpt := recipient.Recipient{ID: id, Name: req.Name, Email: req.Email, Version: version}
+		verify := s.verifiable(r.Context(), rcpt)
+
+		var updated *recipient.Recipient
+		err = s.withTx(r.Context(), func(repos TxRepositories) error {
+			var err error
+			if updated, err = repos.Recipients.Update(r.Context(), rcpt); err != nil {
+				return err
+			}
+			if err := auditTx(r, repos, audit.ActionUpdate, audit.EntityRecipient, id); err != nil {
+				return err
+			}
+			if verify && !updated.Verified {
+				return s.queueVerification(r.Context(), repos, *updated)
+			}
+			return nil
+		})
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+		if verify && !updated.Verified && s.outbox == nil {
+			s.mailVerification(r.Context(), *updated)
+		}
+
+		w.Header().Set("ETag", recipientETag(updated))
+		writeJSON(w, http.StatusOK, updated)
+	}
+}
+
+// recipientETag tags a recipient with its version.
+func recipientETag(r *recipient.Recipient) string {
+	return `"` + strconv.Itoa(r.Version) + `"`
+}
+
+// ifMatchVersion reads the recipient version an update is based on from the
+// If-Match header. A * matches any version and reads as 0. Weak tags are
+// accepted too, since compression weakens the tags GET responses carry.
+func ifMatchVersion(r *http.Request) (int, error) {
+	etag := strings.TrimSpace(r.Header.Get("If-Match"))
+	if etag == "" {
+		return 0, apierror.New(http.StatusPreconditionRequired, apierror.CodePreconditionRequired,
+			"If-Match is required, set it to the ETag of the recipient")
+	}
+	if etag == "*" {
+		return 0, nil
+	}
+
+	etag = strings.TrimPrefix(etag, "W/")
+	version, err := strconv.Atoi(strings.Trim(etag, `"`))
+	if err != nil || version < 1 {
+		return 0, apierror.Wrap(recipient.ErrVersionMismatch, http.StatusPreconditionFailed,
+			apierror.CodePreconditionFailed, "If-Match does not match the recipient")
+	}
+
+	return version, nil
+}
+
+func (s *Server) handleDeleteRecipient() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.writeError(w, r, errInvalidID)
+			return
+		}
+
+		err = s.withTx(r.Context(), func(repos TxRepositories) error {
+			if err := repos.Recipients.Delete(r.Context(), id); err != nil {
+				return err
+			}
+			return auditTx(r, repos, audit.ActionDelete, audit.EntityRecipient, id)
+		})
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	resp, err := json.Marshal(v)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(resp)
+}
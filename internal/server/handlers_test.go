@@ -0,0 +1,1551 @@
+// internal/server/handlers_test.go
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jeniok12/golang-tdd-example/internal/memstore"
+	"github.com/jeniok12/golang-tdd-example/internal/outbox"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/jeniok12/golang-tdd-example/internal/templates"
+	"github.com/jeniok12/golang-tdd-example/mailer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type MockQuoteGenerator struct {
+	mock.Mock
+}
+
+func (m *MockQuoteGenerator) Generate(ctx context.Context, lang string) (*quote.Quote, error) {
+	args := m.Called(ctx, lang)
+	quote, _ := args.Get(0).(*quote.Quote)
+	return quote, args.Error(1)
+}
+
+type MockRecipientsFetcher struct {
+	mock.Mock
+}
+
+func (m *MockRecipientsFetcher) AllRecipients(ctx context.Context) ([]recipient.Recipient, error) {
+	args := m.Called(ctx)
+	r, _ := args.Get(0).([]recipient.Recipient)
+	return r, args.Error(1)
+}
+
+func (m *MockRecipientsFetcher) ListRecipients(ctx context.Context, opts recipient.ListOptions) ([]recipient.Recipient, error) {
+	args := m.Called(ctx, opts)
+	r, _ := args.Get(0).([]recipient.Recipient)
+	return r, args.Error(1)
+}
+
+type MockRecipientStore struct {
+	mock.Mock
+}
+
+func (m *MockRecipientStore) AllRecipients(ctx context.Context) ([]recipient.Recipient, error) {
+	args := m.Called(ctx)
+	r, _ := args.Get(0).([]recipient.Recipient)
+	return r, args.Error(1)
+}
+
+func (m *MockRecipientStore) ListRecipients(ctx context.Context, opts recipient.ListOptions) ([]recipient.Recipient, error) {
+	args := m.Called(ctx, opts)
+	r, _ := args.Get(0).([]recipient.Recipient)
+	return r, args.Error(1)
+}
+
+func (m *MockRecipientStore) Create(ctx context.Context, r recipient.Recipient) (*recipient.Recipient, error) {
+	args := m.Called(ctx, r)
+	created, _ := args.Get(0).(*recipient.Recipient)
+	return created, args.Error(1)
+}
+
+func (m *MockRecipientStore) GetByID(ctx context.Context, id int) (*recipient.Recipient, error) {
+	args := m.Called(ctx, id)
+	r, _ := args.Get(0).(*recipient.Recipient)
+	return r, args.Error(1)
+}
+
+func (m *MockRecipientStore) Update(ctx context.Context, r recipient.Recipient) (*recipient.Recipient, error) {
+	args := m.Called(ctx, r)
+	updated, _ := args.Get(0).(*recipient.Recipient)
+	return updated, args.Error(1)
+}
+
+func (m *MockRecipientStore) Delete(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockRecipientStore) Import(ctx context.Context, rs []recipient.Recipient) ([]*recipient.Recipient, error) {
+	args := m.Called(ctx, rs)
+	created, _ := args.Get(0).([]*recipient.Recipient)
+	return created, args.Error(1)
+}
+
+func (m *MockRecipientStore) EachRecipient(ctx context.Context, fn func(recipient.Recipient) error) error {
+	args := m.Called(ctx)
+	recipients, _ := args.Get(0).([]recipient.Recipient)
+	for _, r := range recipients {
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func (m *MockRecipientStore) MarkVerified(ctx context.Context, id int, email string) (*recipient.Recipient, error) {
+	args := m.Called(ctx, id, email)
+	r, _ := args.Get(0).(*recipient.Recipient)
+	return r, args.Error(1)
+}
+
+func (m *MockRecipientStore) UpdatePreferences(ctx context.Context, id int, prefs recipient.Preferences) (*recipient.Recipient, error) {
+	args := m.Called(ctx, id, prefs)
+	r, _ := args.Get(0).(*recipient.Recipient)
+	return r, args.Error(1)
+}
+
+type MockMailSender struct {
+	mock.Mock
+}
+
+func (m *MockMailSender) Send(msg mailer.Message) error {
+	args := m.Called(msg)
+	return args.Error(0)
+}
+
+var defaultListOptions = recipient.ListOptions{Limit: 100}
+
+// newTestServer builds a Server through NewServer, filling in empty mocks for
+// the required dependencies opts do not provide.
+func newTestServer(t *testing.T, opts ...Option) *Server {
+	t.Helper()
+
+	defaults := []Option{
+		WithQuoteGenerator(&MockQuoteGenerator{}),
+		WithRecipientsFetcher(&MockRecipientsFetcher{}),
+		WithAdminToken(testAdminToken),
+	}
+
+	svr, err := NewServer(append(defaults, opts...)...)
+	require.NoError(t, err, "Should have no error when building the server")
+
+	return svr
+}
+
+// testAdminToken is the admin token of the servers newTestServer builds.
+const testAdminToken = "admin-secret"
+
+// asAdmin authorizes req with testAdminToken for the /admin and /debug routes.
+func asAdmin(req *http.Request) *http.Request {
+	req.Header.Set("Authorization", "Bearer "+testAdminToken)
+	return req
+}
+
+// quoteMessage renders the quote email a server without unsubscribe links
+// sends to rcpt.
+func quoteMessage(q *quote.Quote, rcpt recipient.Recipient) mailer.Message {
+	msg, err := (&Server{templates: templates.Embedded()}).quoteMessage(q, rcpt)
+	if err != nil {
+		panic(err)
+	}
+	return msg
+}
+
+func TestHandleQuotes(t *testing.T) {
+	testCases := []struct {
+		name           string
+		lang           string
+		createMocks    func() (*MockQuoteGenerator, *MockRecipientsFetcher)
+		expectedStatus int
+	}{
+		{
+			"QuoteGenerator_Success",
+			"en",
+			func() (*MockQuoteGenerator, *MockRecipientsFetcher) {
+				mockQuoteGenerator := MockQuoteGenerator{}
+				mockQuoteGenerator.On("Generate", mock.Anything, "en").Return(&quote.Quote{}, nil)
+
+				mockRecipientsFetcher := MockRecipientsFetcher{}
+				mockRecipientsFetcher.On("ListRecipients", mock.Anything, defaultListOptions).Return([]recipient.Recipient{}, nil)
+
+				return &mockQuoteGenerator, &mockRecipientsFetcher
+			},
+			http.StatusOK,
+		},
+		{
+			"QuoteGenerator_Fail",
+			"en",
+			func() (*MockQuoteGenerator, *MockRecipientsFetcher) {
+				mockQuoteGenerator := MockQuoteGenerator{}
+				mockQuoteGenerator.On("Generate", mock.Anything, "en").Return(nil, errors.New("sample error"))
+
+				// Recipients are read while the quote is generated.
+				mockRecipientsFetcher := MockRecipientsFetcher{}
+				mockRecipientsFetcher.On("ListRecipients", mock.Anything, defaultListOptions).Return([]recipient.Recipient{}, nil)
+
+				return &mockQuoteGenerator, &mockRecipientsFetcher
+			},
+			http.StatusBadGateway,
+		},
+		{
+			"RecipientsFetcher_Fail",
+			"en",
+			func() (*MockQuoteGenerator, *MockRecipientsFetcher) {
+				mockQuoteGenerator := MockQuoteGenerator{}
+				mockQuoteGenerator.On("Generate", mock.Anything, "en").Return(&quote.Quote{}, nil)
+
+				mockRecipientsFetcher := MockRecipientsFetcher{}
+				mockRecipientsFetcher.On("ListRecipients", mock.Anything, defaultListOptions).Return(nil, errors.New("sample error"))
+
+				return &mockQuoteGenerator, &mockRecipientsFetcher
+			},
+			http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockQuoteGenerator, mockRecipientsFetcher := tc.createMocks()
+			svr := newTestServer(t,
+				WithQuoteGenerator(mockQuoteGenerator),
+				WithRecipientsFetcher(mockRecipientsFetcher),
+			)
+
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/quote", nil)
+			req.URL.RawQuery = fmt.Sprintf("lang=%s", tc.lang)
+
+			svr.handleQuotes()(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			mockQuoteGenerator.AssertExpectations(t)
+			mockRecipientsFetcher.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandleListRecipients(t *testing.T) {
+	testCases := []struct {
+		name           string
+		query          string
+		opts           *recipient.ListOptions
+		err            error
+		expectedStatus int
+	}{
+		{"Defaults", "", &defaultListOptions, nil, http.StatusOK},
+		{"Page", "limit=10&offset=20&q=bob&order=-name", &recipient.ListOptions{Limit: 10, Offset: 20, Query: "bob", OrderBy: "-name"}, nil, http.StatusOK},
+		{"InvalidLimit", "limit=0", nil, nil, http.StatusBadRequest},
+		{"LimitTooLarge", "limit=5000", nil, nil, http.StatusBadRequest},
+		{"InvalidOffset", "offset=-1", nil, nil, http.StatusBadRequest},
+		{"InvalidOrder", "order=password", nil, nil, http.StatusBadRequest},
+		{"StoreFail", "", &defaultListOptions, errors.New("sample error"), http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRecipientStore := MockRecipientStore{}
+			if tc.opts != nil {
+				mockRecipientStore.On("ListRecipients", mock.Anything, *tc.opts).Return(nil, tc.err)
+			}
+			svr := newTestServer(t, WithRecipientStore(&mockRecipientStore))
+
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/recipients?"+tc.query, nil)
+
+			svr.handleListRecipients()(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			if tc.expectedStatus == http.StatusOK {
+				assert.Equal(t, "[]", strings.TrimSpace(rr.Body.String()), "Empty list should be encoded as an array")
+			}
+			mockRecipientStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandleCreateRecipient(t *testing.T) {
+	validRecipient := recipient.Recipient{Name: "user1", Email: "user1@testmail.com"}
+
+	testCases := []struct {
+		name           string
+		body           string
+		createMock     func() *MockRecipientStore
+		expectedStatus int
+	}{
+		{
+			"Created",
+			`{"name":"user1","email":"user1@testmail.com"}`,
+			func() *MockRecipientStore {
+				m := MockRecipientStore{}
+				m.On("Create", mock.Anything, validRecipient).Return(&recipient.Recipient{ID: 1, Name: "user1", Email: "user1@testmail.com"}, nil)
+				return &m
+			},
+			http.StatusCreated,
+		},
+		{
+			"InvalidJSON",
+			`{"name":`,
+			func() *MockRecipientStore { return &MockRecipientStore{} },
+			http.StatusBadRequest,
+		},
+		{
+			"InvalidEmail",
+			`{"name":"user1","email":"not-an-email"}`,
+			func() *MockRecipientStore { return &MockRecipientStore{} },
+			http.StatusBadRequest,
+		},
+		{
+			"MissingName",
+			`{"email":"user1@testmail.com"}`,
+			func() *MockRecipientStore { return &MockRecipientStore{} },
+			http.StatusBadRequest,
+		},
+		{
+			"DuplicateEmail",
+			`{"name":"user1","email":"user1@testmail.com"}`,
+			func() *MockRecipientStore {
+				m := MockRecipientStore{}
+				m.On("Create", mock.Anything, validRecipient).Return(nil, recipient.ErrDuplicateEmail)
+				return &m
+			},
+			http.StatusConflict,
+		},
+		{
+			"StoreFail",
+			`{"name":"user1","email":"user1@testmail.com"}`,
+			func() *MockRecipientStore {
+				m := MockRecipientStore{}
+				m.On("Create", mock.Anything, validRecipient).Return(nil, errors.New("sample error"))
+				return &m
+			},
+			http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRecipientStore := tc.createMock()
+			svr := newTestServer(t, WithRecipientStore(mockRecipientStore))
+
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/recipients", strings.NewReader(tc.body))
+
+			svr.handleCreateRecipient()(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			mockRecipientStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandleGetRecipient(t *testing.T) {
+	testCases := []struct {
+		name           string
+		id             string
+		createMock     func() *MockRecipientStore
+		expectedStatus int
+	}{
+		{
+			"Found",
+			"1",
+			func() *MockRecipientStore {
+				m := MockRecipientStore{}
+				m.On("GetByID", mock.Anything, 1).Return(&recipient.Recipient{ID: 1}, nil)
+				return &m
+			},
+			http.StatusOK,
+		},
+		{
+			"NotFound",
+			"2",
+			func() *MockRecipientStore {
+				m := MockRecipientStore{}
+				m.On("GetByID", mock.Anything, 2).Return(nil, recipient.ErrNotFound)
+				return &m
+			},
+			http.StatusNotFound,
+		},
+		{
+			"StoreFail",
+			"3",
+			func() *MockRecipientStore {
+				m := MockRecipientStore{}
+				m.On("GetByID", mock.Anything, 3).Return(nil, errors.New("sample error"))
+				return &m
+			},
+			http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRecipientStore := tc.createMock()
+			svr := newTestServer(t, WithRecipientStore(mockRecipientStore))
+
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/recipients/"+tc.id, nil)
+			req = mux.SetURLVars(req, map[string]string{"id": tc.id})
+
+			svr.handleGetRecipient()(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			mockRecipientStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandleUpdateRecipient(t *testing.T) {
+	updatedRecipient := recipient.Recipient{ID: 1, Name: "user1", Email: "new@testmail.com", Version: 3}
+
+	testCases := []struct {
+		name           string
+		body           string
+		ifMatch        string
+		createMock     func() *MockRecipientStore
+		expectedStatus int
+	}{
+		{
+			"Updated",
+			`{"name":"user1","email":"new@testmail.com"}`,
+			`"3"`,
+			func() *MockRecipientStore {
+				m := MockRecipientStore{}
+				m.On("Update", mock.Anything, updatedRecipient).Return(&updatedRecipient, nil)
+				return &m
+			},
+			http.StatusOK,
+		},
+		{
+			"WeakETag",
+			`{"name":"user1","email":"new@testmail.com"}`,
+			`W/"3"`,
+			func() *MockRecipientStore {
+				m := MockRecipientStore{}
+				m.On("Update", mock.Anything, updatedRecipient).Return(&updatedRecipient, nil)
+				return &m
+			},
+			http.StatusOK,
+		},
+		{
+			"AnyVersion",
+			`{"name":"user1","email":"new@testmail.com"}`,
+			"*",
+			func() *MockRecipientStore {
+				m := MockRecipientStore{}
+				unconditional := updatedRecipient
+				unconditional.Version = 0
+				m.On("Update", mock.Anything, unconditional).Return(&updatedRecipient, nil)
+				return &m
+			},
+			http.StatusOK,
+		},
+		{
+			"NoIfMatch",
+			`{"name":"user1","email":"new@testmail.com"}`,
+			"",
+			func() *MockRecipientStore { return &MockRecipientStore{} },
+			http.StatusPreconditionRequired,
+		},
+		{
+			"InvalidIfMatch",
+			`{"name":"user1","email":"new@testmail.com"}`,
+			`"abc"`,
+			func() *MockRecipientStore { return &MockRecipientStore{} },
+			http.StatusPreconditionFailed,
+		},
+		{
+			"VersionMismatch",
+			`{"name":"user1","email":"new@testmail.com"}`,
+			`"3"`,
+			func() *MockRecipientStore {
+				m := MockRecipientStore{}
+				m.On("Update", mock.Anything, updatedRecipient).Return(nil, recipient.ErrVersionMismatch)
+				return &m
+			},
+			http.StatusPreconditionFailed,
+		},
+		{
+			"InvalidEmail",
+			`{"name":"user1","email":"bad"}`,
+			`"3"`,
+			func() *MockRecipientStore { return &MockRecipientStore{} },
+			http.StatusBadRequest,
+		},
+		{
+			"NotFound",
+			`{"name":"user1","email":"new@testmail.com"}`,
+			`"3"`,
+			func() *MockRecipientStore {
+				m := MockRecipientStore{}
+				m.On("Update", mock.Anything, updatedRecipient).Return(nil, recipient.ErrNotFound)
+				return &m
+			},
+			http.StatusNotFound,
+		},
+		{
+			"DuplicateEmail",
+			`{"name":"user1","email":"new@testmail.com"}`,
+			`"3"`,
+			func() *MockRecipientStore {
+				m := MockRecipientStore{}
+				m.On("Update", mock.Anything, updatedRecipient).Return(nil, recipient.ErrDuplicateEmail)
+				return &m
+			},
+			http.StatusConflict,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRecipientStore := tc.createMock()
+			svr := newTestServer(t, WithRecipientStore(mockRecipientStore))
+
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("PUT", "/recipients/1", strings.NewReader(tc.body))
+			if tc.ifMatch != "" {
+				req.Header.Set("If-Match", tc.ifMatch)
+			}
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+
+			svr.handleUpdateRecipient()(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			mockRecipientStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestUpdateRecipient_ConcurrentEdits(t *testing.T) {
+	store := memstore.NewRecipients(memstore.NewQuotes())
+	store.Create(context.Background(), recipient.Recipient{Name: "user1", Email: "user1@testmail.com"})
+	svr := newTestServer(t, WithRecipientStore(store))
+
+	rr := makeHTTPCall(svr.router, httptest.NewRequest(http.MethodGet, "/recipients/1", nil))
+	require.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+	etag := rr.Header().Get("ETag")
+	assert.Equal(t, `"1"`, etag)
+
+	put := func(name string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/recipients/1", strings.NewReader(`{"name":"`+name+`","email":"user1@testmail.com"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("If-Match", etag)
+		return makeHTTPCall(svr.router, req)
+	}
+
+	first := put("alice")
+	assert.Equal(t, http.StatusOK, first.Code, "Response HTTP status in different than expected")
+	assert.Equal(t, `"2"`, first.Header().Get("ETag"), "Updates should return the new ETag")
+
+	second := put("bob")
+	assert.Equal(t, http.StatusPreconditionFailed, second.Code, "Edits based on a stale version should be rejected")
+
+	r, _ := store.GetByID(context.Background(), 1)
+	assert.Equal(t, "alice", r.Name, "The first edit should be kept")
+}
+
+func TestHandleDeleteRecipient(t *testing.T) {
+	testCases := []struct {
+		name           string
+		err            error
+		expectedStatus int
+	}{
+		{"Deleted", nil, http.StatusNoContent},
+		{"NotFound", recipient.ErrNotFound, http.StatusNotFound},
+		{"StoreFail", errors.New("sample error"), http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRecipientStore := MockRecipientStore{}
+			mockRecipientStore.On("Delete", mock.Anything, 1).Return(tc.err)
+			svr := newTestServer(t, WithRecipientStore(&mockRecipientStore))
+
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("DELETE", "/recipients/1", nil)
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+
+			svr.handleDeleteRecipient()(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			mockRecipientStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandleQuotes_Key(t *testing.T) {
+	keyed := mock.MatchedBy(func(ctx context.Context) bool {
+		key, ok := quote.KeyFromContext(ctx)
+		return ok && key == 12345
+	})
+	mockQuoteGenerator := MockQuoteGenerator{}
+	mockQuoteGenerator.On("Generate", keyed, "en").Return(&quote.Quote{}, nil)
+	mockRecipientsFetcher := MockRecipientsFetcher{}
+	mockRecipientsFetcher.On("ListRecipients", mock.Anything, defaultListOptions).Return([]recipient.Recipient{}, nil)
+
+	svr := newTestServer(t,
+		WithQuoteGenerator(&mockQuoteGenerator),
+		WithRecipientsFetcher(&mockRecipientsFetcher),
+	)
+
+	testCases := []struct {
+		name           string
+		key            string
+		expectedStatus int
+	}{
+		{"Valid", "12345", http.StatusOK},
+		{"TooLong", "1234567", http.StatusBadRequest},
+		{"NotANumber", "abc", http.StatusBadRequest},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/quote?lang=en&key="+tc.key, nil)
+
+			svr.handleQuotes()(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+		})
+	}
+	mockQuoteGenerator.AssertNumberOfCalls(t, "Generate", 1)
+}
+
+func TestHandleQuotes_UnsupportedLang(t *testing.T) {
+	svr := newTestServer(t, WithLanguages([]string{"en", "ru"}, true))
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/quote?lang=de", nil)
+
+	svr.handleQuotes()(rr, req)
+
+	var resp map[string]interface{}
+	_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Response HTTP status in different than expected")
+	assert.Equal(t, "VALIDATION_FAILED", resp["code"], "Error code is different than expected")
+	assert.Equal(t, map[string]interface{}{"fields": []interface{}{
+		map[string]interface{}{"field": "lang", "message": `unsupported language "de", supported: en, ru`},
+	}}, resp["details"], "Supported languages should be listed")
+}
+
+func TestHandleLanguages(t *testing.T) {
+	testCases := []struct {
+		name     string
+		opts     []Option
+		expected LanguagesResponse
+	}{
+		{"Restricted", []Option{WithLanguages([]string{"en", "ru"}, true)}, LanguagesResponse{Languages: []string{"en", "ru"}}},
+		{"Any", []Option{WithLanguages([]string{"en"}, false)}, LanguagesResponse{Languages: []string{"en"}, Any: true}},
+		{"None", nil, LanguagesResponse{Languages: []string{}, Any: true}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/languages", nil)
+
+			newTestServer(t, tc.opts...).handleLanguages()(rr, req)
+
+			var resp LanguagesResponse
+			_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+
+			assert.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+			assert.Equal(t, tc.expected, resp, "Languages are different than expected")
+		})
+	}
+}
+
+func TestHandleProviders(t *testing.T) {
+	svr := newTestServer(t, WithQuoteProvider("forismatic"))
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/providers", nil)
+
+	svr.handleProviders()(rr, req)
+
+	var resp ProvidersResponse
+	_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+	assert.Contains(t, resp.Providers, "forismatic", "Forismatic should be listed")
+	assert.Equal(t, "forismatic", resp.Active, "Active provider should be reported")
+}
+
+func TestHandleProvidersHealth(t *testing.T) {
+	health := quote.NewHealthTracker(10, time.Minute)
+	health.Record("forismatic", 20*time.Millisecond, nil)
+	health.Record("forismatic", 40*time.Millisecond, errors.New("sample error"))
+
+	testCases := []struct {
+		name           string
+		opts           []Option
+		expectedStatus int
+		expected       []quote.ProviderHealth
+	}{
+		{"NotTracked", nil, http.StatusNotFound, nil},
+		{"Tracked", []Option{WithProviderHealth(health)}, http.StatusOK, health.Snapshot()},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			svr := newTestServer(t, tc.opts...)
+
+			req, _ := http.NewRequest("GET", "/providers/health", nil)
+			rr := makeHTTPCall(svr.router, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			if tc.expected == nil {
+				return
+			}
+			var resp ProvidersHealthResponse
+			_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+			if assert.Len(t, resp.Providers, 1) {
+				assert.Equal(t, "forismatic", resp.Providers[0].Name)
+				assert.Equal(t, 0.5, resp.Providers[0].SuccessRate, "Success rate should count both calls")
+				assert.Equal(t, int64(40), resp.Providers[0].P95LatencyMs)
+				assert.Equal(t, "sample error", resp.Providers[0].LastError)
+			}
+		})
+	}
+}
+
+func TestHandleSendQuote(t *testing.T) {
+	recipients := []recipient.Recipient{
+		{ID: 1, Name: "user1", Email: "user1@testmail.com"},
+		{ID: 2, Name: "user2", Email: "user2@testmail.com"},
+	}
+	q := &quote.Quote{Text: "Bla Bla Bla", Author: "Bob", Lang: "en"}
+
+	testCases := []struct {
+		name               string
+		createMocks        func() (*MockQuoteGenerator, *MockRecipientsFetcher, *MockMailSender)
+		expectedStatus     int
+		expectedDeliveries []Delivery
+	}{
+		{
+			"PartialDelivery",
+			func() (*MockQuoteGenerator, *MockRecipientsFetcher, *MockMailSender) {
+				mockQuoteGenerator := MockQuoteGenerator{}
+				mockQuoteGenerator.On("Generate", mock.Anything, "en").Return(q, nil)
+
+				mockRecipientsFetcher := MockRecipientsFetcher{}
+				mockRecipientsFetcher.On("AllRecipients", mock.Anything).Return(recipients, nil)
+
+				mockMailSender := MockMailSender{}
+				mockMailSender.On("Send", quoteMessage(q, recipients[0])).Return(nil)
+				mockMailSender.On("Send", quoteMessage(q, recipients[1])).Return(errors.New("sample error"))
+
+				return &mockQuoteGenerator, &mockRecipientsFetcher, &mockMailSender
+			},
+			http.StatusOK,
+			[]Delivery{
+				{Recipient: recipients[0], Sent: true},
+				{Recipient: recipients[1], Sent: false, Error: "sample error"},
+			},
+		},
+		{
+			"QuoteGenerator_Fail",
+			func() (*MockQuoteGenerator, *MockRecipientsFetcher, *MockMailSender) {
+				mockQuoteGenerator := MockQuoteGenerator{}
+				mockQuoteGenerator.On("Generate", mock.Anything, "en").Return(nil, errors.New("sample error"))
+
+				return &mockQuoteGenerator, &MockRecipientsFetcher{}, &MockMailSender{}
+			},
+			http.StatusBadGateway,
+			nil,
+		},
+		{
+			"RecipientsFetcher_Fail",
+			func() (*MockQuoteGenerator, *MockRecipientsFetcher, *MockMailSender) {
+				mockQuoteGenerator := MockQuoteGenerator{}
+				mockQuoteGenerator.On("Generate", mock.Anything, "en").Return(q, nil)
+
+				mockRecipientsFetcher := MockRecipientsFetcher{}
+				mockRecipientsFetcher.On("AllRecipients", mock.Anything).Return(nil, errors.New("sample error"))
+
+				return &mockQuoteGenerator, &mockRecipientsFetcher, &MockMailSender{}
+			},
+			http.StatusServiceUnavailable,
+			nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockQuoteGenerator, mockRecipientsFetcher, mockMailSender := tc.createMocks()
+			svr := newTestServer(t,
+				WithQuoteGenerator(mockQuoteGenerator),
+				WithRecipientsFetcher(mockRecipientsFetcher),
+				WithMailSender(mockMailSender),
+			)
+
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/quote/send?lang=en", nil)
+
+			svr.handleSendQuote()(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			if tc.expectedDeliveries != nil {
+				var resp HandleSendQuoteResponse
+				_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+				assert.Equal(t, tc.expectedDeliveries, resp.Deliveries, "Deliveries are different than expected")
+			}
+			mockQuoteGenerator.AssertExpectations(t)
+			mockRecipientsFetcher.AssertExpectations(t)
+			mockMailSender.AssertExpectations(t)
+		})
+	}
+}
+
+type MockOutbox struct {
+	mock.Mock
+}
+
+func (m *MockOutbox) Enqueue(ctx context.Context, msgs []mailer.Message) ([]outbox.Entry, error) {
+	args := m.Called(ctx, msgs)
+	e, _ := args.Get(0).([]outbox.Entry)
+	return e, args.Error(1)
+}
+
+func TestHandleSendQuote_Outbox(t *testing.T) {
+	recipients := []recipient.Recipient{
+		{ID: 1, Name: "user1", Email: "user1@testmail.com"},
+		{ID: 2, Name: "user2", Email: "user2@testmail.com"},
+	}
+	q := &quote.Quote{Text: "Bla Bla Bla", Author: "Bob", Lang: "en"}
+	msgs := []mailer.Message{quoteMessage(q, recipients[0]), quoteMessage(q, recipients[1])}
+
+	testCases := []struct {
+		name               string
+		enqueueErr         error
+		expectedStatus     int
+		expectedDeliveries []Delivery
+	}{
+		{
+			"Queued",
+			nil,
+			http.StatusOK,
+			[]Delivery{
+				{Recipient: recipients[0], Queued: true},
+				{Recipient: recipients[1], Queued: true},
+			},
+		},
+		{
+			"Outbox_Fail",
+			errors.New("sample error"),
+			http.StatusServiceUnavailable,
+			nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockQuoteGenerator := MockQuoteGenerator{}
+			mockQuoteGenerator.On("Generate", mock.Anything, "en").Return(q, nil)
+			mockRecipientsFetcher := MockRecipientsFetcher{}
+			mockRecipientsFetcher.On("AllRecipients", mock.Anything).Return(recipients, nil)
+			mockOutbox := MockOutbox{}
+			mockOutbox.On("Enqueue", mock.Anything, msgs).Return([]outbox.Entry{}, tc.enqueueErr)
+			mockMailSender := MockMailSender{}
+
+			svr := newTestServer(t,
+				WithQuoteGenerator(&mockQuoteGenerator),
+				WithRecipientsFetcher(&mockRecipientsFetcher),
+				WithMailSender(&mockMailSender),
+				WithOutbox(&mockOutbox, &outbox.Worker{}, time.Minute),
+			)
+
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/quote/send?lang=en", nil)
+
+			svr.handleSendQuote()(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			if tc.expectedDeliveries != nil {
+				var resp HandleSendQuoteResponse
+				_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+				assert.Equal(t, tc.expectedDeliveries, resp.Deliveries, "Deliveries are different than expected")
+			}
+			mockOutbox.AssertExpectations(t)
+			mockMailSender.AssertNotCalled(t, "Send", mock.Anything)
+		})
+	}
+}
+
+type MockChannelSender struct {
+	mock.Mock
+}
+
+func (m *MockChannelSender) Send(ctx context.Context, r recipient.Recipient, text string) error {
+	return m.Called(ctx, r, text).Error(0)
+}
+
+func TestHandleSendQuote_Channels(t *testing.T) {
+	recipients := []recipient.Recipient{
+		{ID: 1, Name: "user1", Email: "user1@testmail.com"},
+		{ID: 2, Name: "user2", Email: "user2@testmail.com", Preferences: recipient.Preferences{
+			Channels:       recipient.Channels{recipient.ChannelSlack, recipient.ChannelTelegram},
+			SlackWebhook:   "https://hooks.slack.com/services/T0/B0/x",
+			TelegramChatID: "42",
+		}},
+		{ID: 3, Name: "user3", Email: "user3@testmail.com", Preferences: recipient.Preferences{
+			Channels:     recipient.Channels{recipient.ChannelEmail, recipient.ChannelSlack},
+			SlackWebhook: "https://hooks.slack.com/services/T0/B0/y",
+		}},
+	}
+	q := &quote.Quote{Text: "Bla Bla Bla", Author: "Bob", Lang: "en"}
+	text := "“Bla Bla Bla”\n— Bob"
+
+	mockQuoteGenerator := MockQuoteGenerator{}
+	mockQuoteGenerator.On("Generate", mock.Anything, "en").Return(q, nil)
+	mockRecipientsFetcher := MockRecipientsFetcher{}
+	mockRecipientsFetcher.On("AllRecipients", mock.Anything).Return(recipients, nil)
+	mockMailSender := MockMailSender{}
+	mockMailSender.On("Send", quoteMessage(q, recipients[0])).Return(nil)
+	mockMailSender.On("Send", quoteMessage(q, recipients[2])).Return(nil)
+	mockSlack := MockChannelSender{}
+	mockSlack.On("Send", mock.Anything, recipients[1], text).Return(nil)
+	mockSlack.On("Send", mock.Anything, recipients[2], text).Return(errors.New("sample error"))
+
+	svr := newTestServer(t,
+		WithQuoteGenerator(&mockQuoteGenerator),
+		WithRecipientsFetcher(&mockRecipientsFetcher),
+		WithMailSender(&mockMailSender),
+		WithChannel(recipient.ChannelSlack, &mockSlack),
+	)
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/quote/send?lang=en", nil)
+
+	svr.handleSendQuote()(rr, req)
+
+	var resp HandleSendQuoteResponse
+	_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+	assert.Equal(t, []Delivery{
+		{Recipient: recipients[0], Sent: true},
+		{Recipient: recipients[2], Sent: true},
+		{Recipient: recipients[1], Channel: recipient.ChannelSlack, Sent: true},
+		{Recipient: recipients[1], Channel: recipient.ChannelTelegram, Error: "channel telegram is not configured"},
+		{Recipient: recipients[2], Channel: recipient.ChannelSlack, Error: "sample error"},
+	}, resp.Deliveries, "Deliveries are different than expected")
+	mockMailSender.AssertExpectations(t)
+	mockSlack.AssertExpectations(t)
+}
+
+func TestWithChannel_Unknown(t *testing.T) {
+	_, err := NewServer(WithQuoteGenerator(&MockQuoteGenerator{}), WithRecipientsFetcher(&MockRecipientsFetcher{}),
+		WithChannel(recipient.ChannelEmail, &MockChannelSender{}))
+
+	assert.EqualError(t, err, `server: unknown chat channel "email"`)
+}
+
+func TestHandleMetrics(t *testing.T) {
+	mockQuoteGenerator := MockQuoteGenerator{}
+	mockQuoteGenerator.On("Generate", mock.Anything, "en").Return(&quote.Quote{}, nil).Once()
+
+	cached := &quote.Cached{Inner: &mockQuoteGenerator, TTL: time.Minute, Cache: quote.NewLRU(10)}
+	cached.Generate(context.Background(), "en")
+	cached.Generate(context.Background(), "en")
+
+	breaker := &quote.Breaker{Inner: &mockQuoteGenerator, Threshold: 5, Cooldown: time.Minute}
+
+	svr := newTestServer(t,
+		WithQuoteCache(cached),
+		WithBreakers(map[string]*quote.Breaker{"forismatic": breaker}),
+	)
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+
+	svr.handleMetrics()(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+	assert.JSONEq(t, `{"cache":{"hits":1,"misses":1},"breakers":{"forismatic":{"state":"closed","consecutiveFailures":0,"trips":0}}}`, rr.Body.String(), "Response HTTP body in different than expected")
+	mockQuoteGenerator.AssertExpectations(t)
+}
+
+type MockQuoteStore struct {
+	mock.Mock
+}
+
+func (m *MockQuoteStore) ListQuotes(ctx context.Context, filter quote.ListFilter) ([]quote.Record, error) {
+	args := m.Called(ctx, filter)
+	r, _ := args.Get(0).([]quote.Record)
+	return r, args.Error(1)
+}
+
+func (m *MockQuoteStore) SearchQuotes(ctx context.Context, filter quote.SearchFilter) ([]quote.SearchResult, error) {
+	args := m.Called(ctx, filter)
+	r, _ := args.Get(0).([]quote.SearchResult)
+	return r, args.Error(1)
+}
+
+func (m *MockQuoteStore) EachQuote(ctx context.Context, lang string, fn func(quote.Record) error) error {
+	args := m.Called(ctx, lang)
+	records, _ := args.Get(0).([]quote.Record)
+	for _, r := range records {
+		if err := fn(r); err != nil {
+			return err
+		}
+	}
+	return args.Error(1)
+}
+
+func TestHandleQuoteHistory(t *testing.T) {
+	records := []quote.Record{{ID: 1, Quote: quote.Quote{Text: "Bla", Author: "Bob", Lang: "en"}}}
+
+	testCases := []struct {
+		name           string
+		query          string
+		createMock     func() *MockQuoteStore
+		expectedStatus int
+	}{
+		{
+			"Defaults",
+			"",
+			func() *MockQuoteStore {
+				m := MockQuoteStore{}
+				m.On("ListQuotes", mock.Anything, quote.ListFilter{Limit: 20}).Return(records, nil)
+				return &m
+			},
+			http.StatusOK,
+		},
+		{
+			"Filtered",
+			"lang=en&limit=5&offset=10",
+			func() *MockQuoteStore {
+				m := MockQuoteStore{}
+				m.On("ListQuotes", mock.Anything, quote.ListFilter{Lang: "en", Limit: 5, Offset: 10}).Return(records, nil)
+				return &m
+			},
+			http.StatusOK,
+		},
+		{
+			"InvalidLimit",
+			"limit=1000",
+			func() *MockQuoteStore { return &MockQuoteStore{} },
+			http.StatusBadRequest,
+		},
+		{
+			"InvalidOffset",
+			"offset=bla",
+			func() *MockQuoteStore { return &MockQuoteStore{} },
+			http.StatusBadRequest,
+		},
+		{
+			"StoreFail",
+			"",
+			func() *MockQuoteStore {
+				m := MockQuoteStore{}
+				m.On("ListQuotes", mock.Anything, quote.ListFilter{Limit: 20}).Return(nil, errors.New("sample error"))
+				return &m
+			},
+			http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockQuoteStore := tc.createMock()
+			svr := newTestServer(t, WithQuoteStore(mockQuoteStore))
+
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/quotes/history?"+tc.query, nil)
+
+			svr.handleQuoteHistory()(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			mockQuoteStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandleSearchQuotes(t *testing.T) {
+	quotes := memstore.NewQuotes()
+	quotes.SaveQuote(context.Background(), quote.Quote{Text: "Life is short", Author: "Bob", Lang: "en"})
+	quotes.SaveQuote(context.Background(), quote.Quote{Text: "Art is long", Author: "Alice", Lang: "en"})
+	svr := newTestServer(t, WithQuoteStore(quotes))
+
+	testCases := []struct {
+		name               string
+		query              string
+		expectedStatus     int
+		expectedHighlights []string
+	}{
+		{"Found", "?q=life", http.StatusOK, []string{"<mark>Life</mark> is short"}},
+		{"NotFound", "?q=death", http.StatusOK, []string{}},
+		{"Paged", "?q=is&limit=1&offset=1", http.StatusOK, []string{"Life <mark>is</mark> short"}},
+		{"MissingQuery", "", http.StatusBadRequest, nil},
+		{"NoWords", "?q=%21%3F", http.StatusBadRequest, nil},
+		{"InvalidLimit", "?q=life&limit=0", http.StatusBadRequest, nil},
+		{"InvalidOffset", "?q=life&offset=-1", http.StatusBadRequest, nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := makeHTTPCall(svr.router, httptest.NewRequest(http.MethodGet, "/quotes/search"+tc.query, nil))
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			if tc.expectedHighlights != nil {
+				var resp QuoteSearchResponse
+				require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp), "Got error when not expected")
+				highlights := []string{}
+				for _, r := range resp.Results {
+					highlights = append(highlights, r.Highlight)
+				}
+				assert.Equal(t, tc.expectedHighlights, highlights)
+			}
+		})
+	}
+}
+
+func TestHandleQuoteHistory_Conditional(t *testing.T) {
+	createdAt := time.Date(2019, 5, 5, 12, 0, 0, 0, time.UTC)
+	records := []quote.Record{{ID: 1, Quote: quote.Quote{Text: "Bla", Author: "Bob", Lang: "en"}, CreatedAt: createdAt}}
+
+	mockQuoteStore := MockQuoteStore{}
+	mockQuoteStore.On("ListQuotes", mock.Anything, quote.ListFilter{Limit: 20}).Return(records, nil)
+	svr := newTestServer(t, WithQuoteStore(&mockQuoteStore))
+
+	do := func(header, value string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/quotes/history", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		svr.handleQuoteHistory()(rr, req)
+		return rr
+	}
+
+	rr := do("", "")
+	etag := rr.Header().Get("ETag")
+	assert.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+	assert.NotEmpty(t, etag, "Response should carry an ETag")
+	assert.Equal(t, createdAt.Format(http.TimeFormat), rr.Header().Get("Last-Modified"), "Last-Modified should be the newest quote")
+	assert.Equal(t, "no-cache", rr.Header().Get("Cache-Control"), "History should be revalidated")
+
+	assert.Equal(t, http.StatusNotModified, do("If-None-Match", etag).Code, "Matching ETag should not be resent")
+	assert.Equal(t, http.StatusOK, do("If-None-Match", `"stale"`).Code, "Stale ETag should be resent")
+	assert.Equal(t, http.StatusNotModified, do("If-Modified-Since", createdAt.Format(http.TimeFormat)).Code, "Unmodified history should not be resent")
+	assert.Equal(t, http.StatusOK, do("If-Modified-Since", createdAt.Add(-time.Hour).Format(http.TimeFormat)).Code, "Modified history should be resent")
+}
+
+func TestHandleQuotes_Conditional(t *testing.T) {
+	q := &quote.Quote{Text: "Bla Bla Bla", Author: "Bob", Lang: "en"}
+
+	testCases := []struct {
+		name                 string
+		opts                 []Option
+		expectedCacheControl string
+	}{
+		{"Uncached", nil, "private, no-cache"},
+		{"Cached", []Option{WithQuoteCache(&quote.Cached{TTL: time.Minute})}, "private, max-age=60"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockQuoteGenerator := MockQuoteGenerator{}
+			mockQuoteGenerator.On("Generate", mock.Anything, "en").Return(q, nil)
+
+			mockRecipientsFetcher := MockRecipientsFetcher{}
+			mockRecipientsFetcher.On("ListRecipients", mock.Anything, defaultListOptions).Return(nil, nil)
+
+			svr := newTestServer(t, append(tc.opts,
+				WithQuoteGenerator(&mockQuoteGenerator),
+				WithRecipientsFetcher(&mockRecipientsFetcher),
+			)...)
+
+			do := func(etag string) *httptest.ResponseRecorder {
+				rr := httptest.NewRecorder()
+				req, _ := http.NewRequest("GET", "/quote?lang=en", nil)
+				if etag != "" {
+					req.Header.Set("If-None-Match", etag)
+				}
+				svr.handleQuotes()(rr, req)
+				return rr
+			}
+
+			rr := do("")
+			assert.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+			assert.Equal(t, tc.expectedCacheControl, rr.Header().Get("Cache-Control"), "Cache-Control is different than expected")
+
+			rr = do(rr.Header().Get("ETag"))
+			assert.Equal(t, http.StatusNotModified, rr.Code, "Unchanged quote should not be resent")
+			assert.Empty(t, rr.Body.String(), "Not modified response should have no body")
+		})
+	}
+}
+
+func TestHandleDailyRun(t *testing.T) {
+	recipients := []recipient.Recipient{{ID: 1, Name: "user1", Email: "user1@testmail.com"}}
+	q := &quote.Quote{Text: "Bla Bla Bla", Author: "Bob", Lang: "en"}
+
+	mockQuoteGenerator := MockQuoteGenerator{}
+	mockQuoteGenerator.On("Generate", mock.Anything, "en").Return(q, nil)
+	mockQuoteGenerator.On("Generate", mock.Anything, "ru").Return(nil, errors.New("sample error"))
+
+	mockRecipientsFetcher := MockRecipientsFetcher{}
+	mockRecipientsFetcher.On("AllRecipients", mock.Anything).Return(recipients, nil)
+
+	mockMailSender := MockMailSender{}
+	mockMailSender.On("Send", quoteMessage(q, recipients[0])).Return(nil)
+
+	svr := newTestServer(t,
+		WithQuoteGenerator(&mockQuoteGenerator),
+		WithRecipientsFetcher(&mockRecipientsFetcher),
+		WithMailSender(&mockMailSender),
+		WithDailyLanguages("en", "ru"),
+	)
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/quote/daily/run", nil)
+
+	svr.handleDailyRun()(rr, req)
+
+	var resp DailyRunResponse
+	_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+	assert.Equal(t, []DailyRun{
+		{Lang: "en", Quote: q, Deliveries: []Delivery{{Recipient: recipients[0], Sent: true}}},
+		{Lang: "ru", Error: "sample error"},
+	}, resp.Runs, "Daily runs are different than expected")
+	assert.EqualError(t, svr.dailyJob(context.Background()), "daily quote failed for ru: sample error", "Scheduler job should report failed languages")
+}
+
+type MockLocalQuoteStore struct {
+	mock.Mock
+}
+
+func (m *MockLocalQuoteStore) AddLocal(ctx context.Context, q quote.Quote) (*quote.Record, error) {
+	args := m.Called(ctx, q)
+	r, _ := args.Get(0).(*quote.Record)
+	return r, args.Error(1)
+}
+
+func (m *MockLocalQuoteStore) ListLocal(ctx context.Context, lang string) ([]quote.Record, error) {
+	args := m.Called(ctx, lang)
+	r, _ := args.Get(0).([]quote.Record)
+	return r, args.Error(1)
+}
+
+func (m *MockLocalQuoteStore) RemoveLocal(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func TestHandleAddLocalQuote(t *testing.T) {
+	q := quote.Quote{Text: "Bla", Author: "Bob", Lang: "en"}
+
+	testCases := []struct {
+		name           string
+		body           string
+		createMock     func() *MockLocalQuoteStore
+		expectedStatus int
+	}{
+		{
+			"Created",
+			`{"quoteText":"Bla","quoteAuthor":"Bob","lang":"en"}`,
+			func() *MockLocalQuoteStore {
+				m := MockLocalQuoteStore{}
+				m.On("AddLocal", mock.Anything, q).Return(&quote.Record{ID: 1, Quote: q}, nil)
+				return &m
+			},
+			http.StatusCreated,
+		},
+		{
+			"MissingAuthor",
+			`{"quoteText":"Bla","lang":"en"}`,
+			func() *MockLocalQuoteStore { return &MockLocalQuoteStore{} },
+			http.StatusBadRequest,
+		},
+		{
+			"StoreFail",
+			`{"quoteText":"Bla","quoteAuthor":"Bob","lang":"en"}`,
+			func() *MockLocalQuoteStore {
+				m := MockLocalQuoteStore{}
+				m.On("AddLocal", mock.Anything, q).Return(nil, errors.New("sample error"))
+				return &m
+			},
+			http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockLocalQuotes := tc.createMock()
+			svr := newTestServer(t, WithLocalQuotes(mockLocalQuotes))
+
+			rr := httptest.NewRecorder()
+			req := asAdmin(httptest.NewRequest("POST", "/admin/local-quotes", strings.NewReader(tc.body)))
+
+			svr.handleAddLocalQuote()(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			mockLocalQuotes.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandleRemoveLocalQuote(t *testing.T) {
+	testCases := []struct {
+		name           string
+		err            error
+		expectedStatus int
+	}{
+		{"Removed", nil, http.StatusNoContent},
+		{"NotFound", quote.ErrNotFound, http.StatusNotFound},
+		{"StoreFail", errors.New("sample error"), http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockLocalQuotes := MockLocalQuoteStore{}
+			mockLocalQuotes.On("RemoveLocal", mock.Anything, 1).Return(tc.err)
+			svr := newTestServer(t, WithLocalQuotes(&mockLocalQuotes))
+
+			rr := httptest.NewRecorder()
+			req := asAdmin(httptest.NewRequest("DELETE", "/admin/local-quotes/1", nil))
+			req = mux.SetURLVars(req, map[string]string{"id": "1"})
+
+			svr.handleRemoveLocalQuote()(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			mockLocalQuotes.AssertExpectations(t)
+		})
+	}
+}
+
+type MockGroupStore struct {
+	mock.Mock
+}
+
+func (m *MockGroupStore) CreateGroup(ctx context.Context, name string) (*recipient.Group, error) {
+	args := m.Called(ctx, name)
+	g, _ := args.Get(0).(*recipient.Group)
+	return g, args.Error(1)
+}
+
+func (m *MockGroupStore) ListGroups(ctx context.Context) ([]recipient.Group, error) {
+	args := m.Called(ctx)
+	g, _ := args.Get(0).([]recipient.Group)
+	return g, args.Error(1)
+}
+
+func (m *MockGroupStore) AddMember(ctx context.Context, groupID, recipientID int) error {
+	args := m.Called(ctx, groupID, recipientID)
+	return args.Error(0)
+}
+
+func (m *MockGroupStore) RemoveMember(ctx context.Context, groupID, recipientID int) error {
+	args := m.Called(ctx, groupID, recipientID)
+	return args.Error(0)
+}
+
+func (m *MockGroupStore) GroupRecipients(ctx context.Context, group string) ([]recipient.Recipient, error) {
+	args := m.Called(ctx, group)
+	r, _ := args.Get(0).([]recipient.Recipient)
+	return r, args.Error(1)
+}
+
+func TestHandleQuotes_Format(t *testing.T) {
+	q := &quote.Quote{Text: "Bla Bla Bla", Author: "Bob", Lang: "en"}
+	recipients := []recipient.Recipient{{ID: 1, Name: "user1", Email: "user1@testmail.com"}}
+
+	testCases := []struct {
+		name                string
+		query               string
+		accept              string
+		expectedStatus      int
+		expectedContentType string
+		expectedBody        string
+	}{
+		{
+			"JSON",
+			"",
+			"",
+			http.StatusOK,
+			"application/json",
+			`{"quote":{"quoteText":"Bla Bla Bla","quoteAuthor":"Bob","lang":"en"},"recipients":[{"id":1,"name":"user1","email":"user1@testmail.com","verified":false}]}`,
+		},
+		{
+			"XML",
+			"",
+			"application/xml",
+			http.StatusOK,
+			"application/xml; charset=utf-8",
+			`<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+				`<quoteResponse><quote><text>Bla Bla Bla</text><author>Bob</author><lang>en</lang></quote>` +
+				`<recipients><recipient><id>1</id><name>user1</name><email>user1@testmail.com</email><verified>false</verified></recipient></recipients></quoteResponse>`,
+		},
+		{
+			"Text",
+			"&format=text",
+			"application/xml",
+			http.StatusOK,
+			"text/plain; charset=utf-8",
+			"Bla Bla Bla\n- Bob\n\nRecipients:\nuser1 <user1@testmail.com>\n",
+		},
+		{
+			"UnsupportedFormat",
+			"&format=yaml",
+			"",
+			http.StatusBadRequest,
+			"application/json",
+			`{"code":"BAD_REQUEST","message":"unsupported format \"yaml\""}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockQuoteGenerator := MockQuoteGenerator{}
+			mockQuoteGenerator.On("Generate", mock.Anything, "en").Return(q, nil)
+
+			mockRecipientsFetcher := MockRecipientsFetcher{}
+			mockRecipientsFetcher.On("ListRecipients", mock.Anything, defaultListOptions).Return(recipients, nil)
+
+			svr := newTestServer(t,
+				WithQuoteGenerator(&mockQuoteGenerator),
+				WithRecipientsFetcher(&mockRecipientsFetcher),
+			)
+
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/quote?lang=en"+tc.query, nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+
+			svr.handleQuotes()(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			assert.Equal(t, tc.expectedContentType, rr.Header().Get("Content-Type"), "Content-Type is different than expected")
+			assert.Equal(t, tc.expectedBody, rr.Body.String(), "Response body is different than expected")
+		})
+	}
+}
+
+func TestHandleQuotes_Group(t *testing.T) {
+	testCases := []struct {
+		name           string
+		err            error
+		expectedStatus int
+	}{
+		{"GroupFound", nil, http.StatusOK},
+		{"GroupNotFound", recipient.ErrGroupNotFound, http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockQuoteGenerator := MockQuoteGenerator{}
+			mockQuoteGenerator.On("Generate", mock.Anything, "en").Return(&quote.Quote{}, nil)
+
+			mockGroupStore := MockGroupStore{}
+			mockGroupStore.On("GroupRecipients", mock.Anything, "marketing").Return([]recipient.Recipient{{ID: 1}}, tc.err)
+
+			svr := newTestServer(t,
+				WithQuoteGenerator(&mockQuoteGenerator),
+				WithRecipientsFetcher(&MockRecipientsFetcher{}),
+				WithGroupStore(&mockGroupStore),
+			)
+
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/quote?lang=en&group=marketing", nil)
+
+			svr.handleQuotes()(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			mockGroupStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandleCreateGroup(t *testing.T) {
+	testCases := []struct {
+		name           string
+		body           string
+		createMock     func() *MockGroupStore
+		expectedStatus int
+	}{
+		{
+			"Created",
+			`{"name":"marketing"}`,
+			func() *MockGroupStore {
+				m := MockGroupStore{}
+				m.On("CreateGroup", mock.Anything, "marketing").Return(&recipient.Group{ID: 1, Name: "marketing"}, nil)
+				return &m
+			},
+			http.StatusCreated,
+		},
+		{
+			"Duplicate",
+			`{"name":"marketing"}`,
+			func() *MockGroupStore {
+				m := MockGroupStore{}
+				m.On("CreateGroup", mock.Anything, "marketing").Return(nil, recipient.ErrDuplicateGroup)
+				return &m
+			},
+			http.StatusConflict,
+		},
+		{
+			"MissingName",
+			`{}`,
+			func() *MockGroupStore { return &MockGroupStore{} },
+			http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockGroupStore := tc.createMock()
+			svr := newTestServer(t, WithGroupStore(mockGroupStore))
+
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/groups", strings.NewReader(tc.body))
+
+			svr.handleCreateGroup()(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			mockGroupStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandleAddGroupMember(t *testing.T) {
+	testCases := []struct {
+		name           string
+		err            error
+		expectedStatus int
+	}{
+		{"Added", nil, http.StatusNoContent},
+		{"UnknownRecipient", recipient.ErrNotFound, http.StatusNotFound},
+		{"StoreFail", errors.New("sample error"), http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockGroupStore := MockGroupStore{}
+			mockGroupStore.On("AddMember", mock.Anything, 1, 2).Return(tc.err)
+			svr := newTestServer(t, WithGroupStore(&mockGroupStore))
+
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("PUT", "/groups/1/members/2", nil)
+			req = mux.SetURLVars(req, map[string]string{"id": "1", "recipientId": "2"})
+
+			svr.handleAddGroupMember()(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			mockGroupStore.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,125 @@
+// internal/server/health.go
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Pinger ...
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+type readinessCheck struct {
+	name   string
+	pinger Pinger
+
+	mu        sync.Mutex
+	downSince time.Time
+}
+
+// probe pings the dependency, keeping track of since when it is down.
+func (c *readinessCheck) probe(ctx context.Context) CheckStatus {
+	ctx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+	err := c.pinger.Ping(ctx)
+	cancel()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err == nil {
+		c.downSince = time.Time{}
+		return CheckStatus{Status: "ok"}
+	}
+	if c.downSince.IsZero() {
+		c.downSince = time.Now()
+	}
+	since := c.downSince
+	return CheckStatus{Status: "unavailable", Error: err.Error(), Since: &since}
+}
+
+// CheckStatus ...
+type CheckStatus struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// Since is when the dependency was first found down, as early as startup.
+	Since *time.Time `json:"since,omitempty"`
+}
+
+// ReadinessResponse ...
+type ReadinessResponse struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckStatus `json:"checks"`
+}
+
+const readinessCheckTimeout = 2 * time.Second
+
+func (s *Server) handleHealthz() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, CheckStatus{Status: "ok"})
+	}
+}
+
+func (s *Server) handleReadyz() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		resp := ReadinessResponse{
+			Status: "ok",
+			Checks: make(map[string]CheckStatus, len(s.readinessChecks)),
+		}
+
+		for _, c := range s.readinessChecks {
+			status := c.probe(r.Context())
+			if status.Status != "ok" {
+				resp.Status = "unavailable"
+			}
+			resp.Checks[c.name] = status
+		}
+
+		status := http.StatusOK
+		if resp.Status != "ok" {
+			status = http.StatusServiceUnavailable
+		}
+
+		writeJSON(w, status, resp)
+	}
+}
+
+// CheckDependencies pings the dependencies of the readiness checks until they
+// all answer, up to attempts times, waiting delay and then twice as long after
+// each round. It returns the dependencies still down, which /readyz keeps
+// reporting until they recover.
+func (s *Server) CheckDependencies(ctx context.Context, attempts int, delay time.Duration) error {
+	pending := s.readinessChecks
+	for attempt := 1; ; attempt++ {
+		var down []*readinessCheck
+		var errs []error
+		for _, c := range pending {
+			if status := c.probe(ctx); status.Status != "ok" {
+				down = append(down, c)
+				errs = append(errs, fmt.Errorf("%s: %s", c.name, status.Error))
+			}
+		}
+		if len(down) == 0 {
+			return nil
+		}
+
+		err := errors.Join(errs...)
+		if attempt >= attempts {
+			return err
+		}
+		s.logger.Warn().Err(err).Int("attempt", attempt).Dur("retryIn", delay).Msg("waiting for dependencies")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+		pending, delay = down, 2*delay
+	}
+}
@@ -0,0 +1,157 @@
+// internal/server/health_test.go
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type MockPinger struct {
+	mock.Mock
+}
+
+func (m *MockPinger) Ping(ctx context.Context) error {
+	args := m.Called(ctx)
+	return args.Error(0)
+}
+
+func TestHandleHealthz(t *testing.T) {
+	svr := newTestServer(t)
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/healthz", nil)
+
+	svr.handleHealthz()(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+	assert.JSONEq(t, `{"status":"ok"}`, rr.Body.String(), "Response HTTP body in different than expected")
+}
+
+func TestHandleReadyz(t *testing.T) {
+	testCases := []struct {
+		name           string
+		postgresErr    error
+		providerErr    error
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			"AllReady",
+			nil,
+			nil,
+			http.StatusOK,
+			`{"status":"ok","checks":{"postgres":{"status":"ok"},"forismatic":{"status":"ok"}}}`,
+		},
+		{
+			"PostgresDown",
+			errors.New("connection refused"),
+			nil,
+			http.StatusServiceUnavailable,
+			`{"status":"unavailable","checks":{"postgres":{"status":"unavailable","error":"connection refused"},"forismatic":{"status":"ok"}}}`,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			postgres := MockPinger{}
+			postgres.On("Ping", mock.Anything).Return(tC.postgresErr)
+			provider := MockPinger{}
+			provider.On("Ping", mock.Anything).Return(tC.providerErr)
+
+			svr := newTestServer(t,
+				WithReadinessCheck("postgres", &postgres),
+				WithReadinessCheck("forismatic", &provider),
+			)
+
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/readyz", nil)
+
+			svr.handleReadyz()(rr, req)
+
+			assert.Equal(t, tC.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			var resp ReadinessResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp), "Got error when not expected")
+			for name, check := range resp.Checks {
+				assert.Equal(t, check.Status != "ok", check.Since != nil, "Only checks down should say since when")
+				check.Since = nil
+				resp.Checks[name] = check
+			}
+			body, _ := json.Marshal(resp)
+			assert.JSONEq(t, tC.expectedBody, string(body), "Response HTTP body in different than expected")
+		})
+	}
+}
+
+func TestHandleReadyz_Since(t *testing.T) {
+	postgres := MockPinger{}
+	postgres.On("Ping", mock.Anything).Return(errors.New("connection refused")).Twice()
+	postgres.On("Ping", mock.Anything).Return(nil)
+	svr := newTestServer(t, WithReadinessCheck("postgres", &postgres))
+
+	readyz := func() CheckStatus {
+		rr := makeHTTPCall(svr.router, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var resp ReadinessResponse
+		require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp), "Got error when not expected")
+		return resp.Checks["postgres"]
+	}
+
+	first := readyz()
+	require.NotNil(t, first.Since, "Checks down should say since when")
+	second := readyz()
+	require.NotNil(t, second.Since)
+	assert.True(t, first.Since.Equal(*second.Since), "Since should be when the dependency was first found down")
+	assert.Equal(t, CheckStatus{Status: "ok"}, readyz(), "Since should be cleared once the dependency recovers")
+}
+
+func TestCheckDependencies(t *testing.T) {
+	testCases := []struct {
+		desc          string
+		failures      int
+		expectedErr   string
+		expectedPings int
+	}{
+		{"Up", 0, "", 1},
+		{"Recovers", 2, "", 3},
+		{"Down", 5, "postgres: connection refused", 3},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			postgres, redis := MockPinger{}, MockPinger{}
+			if tC.failures > 0 {
+				postgres.On("Ping", mock.Anything).Return(errors.New("connection refused")).Times(tC.failures)
+			}
+			postgres.On("Ping", mock.Anything).Return(nil)
+			redis.On("Ping", mock.Anything).Return(nil)
+			svr := newTestServer(t, WithReadinessCheck("postgres", &postgres), WithReadinessCheck("redis", &redis))
+
+			err := svr.CheckDependencies(context.Background(), 3, time.Millisecond)
+
+			if tC.expectedErr == "" {
+				assert.NoError(t, err, "Got error when not expected")
+			} else {
+				assert.EqualError(t, err, tC.expectedErr)
+			}
+			postgres.AssertNumberOfCalls(t, "Ping", tC.expectedPings)
+			redis.AssertNumberOfCalls(t, "Ping", 1)
+		})
+	}
+}
+
+func TestCheckDependencies_Canceled(t *testing.T) {
+	postgres := MockPinger{}
+	postgres.On("Ping", mock.Anything).Return(errors.New("connection refused"))
+	svr := newTestServer(t, WithReadinessCheck("postgres", &postgres))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := svr.CheckDependencies(ctx, 3, time.Hour)
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,171 @@
+// internal/server/idempotency.go
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/internal/idempotency"
+	"github.com/jeniok12/golang-tdd-example/openapi"
+	"github.com/jeniok12/golang-tdd-example/tenant"
+	"io"
+	"net/http"
+	"time"
+)
+
+// IdempotencyKeyHeader carries the key making a retried request safe.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader is set on responses replayed for a key.
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// maxIdempotencyKey is the longest key accepted.
+const maxIdempotencyKey = 255
+
+// idempotencyPurgeInterval is how often expired keys are deleted.
+const idempotencyPurgeInterval = time.Hour
+
+var idempotencyKeyParam = openapi.Param{
+	Name:        IdempotencyKeyHeader,
+	In:          "header",
+	Description: "replaying a request with the same key returns the original response instead of repeating it",
+}
+
+// WithIdempotency keeps the responses of requests made with an
+// Idempotency-Key in store for ttl.
+func WithIdempotency(store idempotency.Store, ttl time.Duration) Option {
+	return func(s *Server) error {
+		if ttl <= 0 {
+			return fmt.Errorf("server: idempotency TTL must be positive, got %s", ttl)
+		}
+		s.idempotency = store
+		s.idempotencyTTL = ttl
+		return nil
+	}
+}
+
+// idempotent answers requests repeating an Idempotency-Key with the response
+// saved for it. The key cannot be reused with another request, nor while the
+// first one runs. Server errors are not saved, so they can be retried.
+func (s *Server) idempotent(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if s.idempotency == nil || key == "" {
+			h(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKey {
+			s.writeError(w, r, apierror.BadRequest(apierror.CodeValidation,
+				fmt.Sprintf("%s must be at most %d characters", IdempotencyKeyHeader, maxIdempotencyKey)))
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		// Tenants choose their keys independently, so they may collide.
+		key = tenant.Key(r.Context(), key)
+		now, fp := time.Now(), fingerprint(r, body)
+		existing, err := s.idempotency.Reserve(r.Context(), idempotency.Record{
+			Key:         key,
+			Fingerprint: fp,
+			CreatedAt:   now,
+			ExpiresAt:   now.Add(s.idempotencyTTL),
+		})
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+
+		switch {
+		case existing == nil:
+		case existing.Fingerprint != fp:
+			s.writeError(w, r, apierror.New(http.StatusUnprocessableEntity, apierror.CodeIdempotencyKeyReused,
+				IdempotencyKeyHeader+" was already used with another request"))
+			return
+		case !existing.Done():
+			s.writeError(w, r, apierror.Conflict("a request with this "+IdempotencyKeyHeader+" is still in progress"))
+			return
+		default:
+			if existing.ContentType != "" {
+				w.Header().Set("Content-Type", existing.ContentType)
+			}
+			w.Header().Set(IdempotentReplayedHeader, "true")
+			w.WriteHeader(existing.Status)
+			w.Write(existing.Body)
+			return
+		}
+
+		capture := &responseCapture{ResponseWriter: w}
+		h(capture, r)
+		if capture.status == 0 {
+			capture.status = http.StatusOK
+		}
+
+		// Saved even when the client went away, so its retry finds the response.
+		ctx := context.WithoutCancel(r.Context())
+		if capture.status >= http.StatusInternalServerError {
+			err = s.idempotency.Release(ctx, key)
+		} else {
+			err = s.idempotency.Complete(ctx, key, capture.status, w.Header().Get("Content-Type"), capture.body.Bytes())
+		}
+		if err != nil {
+			s.logger.Error().Err(err).Str("key", key).Msg("saving idempotent response")
+		}
+	}
+}
+
+// fingerprint identifies a request by its method, URL and body.
+func fingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseCapture keeps a copy of the response written through it.
+type responseCapture struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (c *responseCapture) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *responseCapture) Write(b []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	c.body.Write(b)
+	return c.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the connection.
+func (c *responseCapture) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// purgeIdempotencyKeys deletes the expired idempotency keys.
+func (s *Server) purgeIdempotencyKeys(ctx context.Context) error {
+	n, err := s.idempotency.Purge(ctx, time.Now())
+	if err != nil {
+		return fmt.Errorf("purging idempotency keys: %w", err)
+	}
+	if n > 0 {
+		s.logger.Info().Int("purged", n).Msg("expired idempotency keys deleted")
+	}
+	return nil
+}
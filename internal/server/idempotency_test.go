@@ -0,0 +1,95 @@
+// internal/server/idempotency_test.go
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/internal/idempotency"
+	"github.com/jeniok12/golang-tdd-example/internal/memstore"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdempotentCreateRecipient(t *testing.T) {
+	store := memstore.NewRecipients(memstore.NewQuotes())
+	svr := newTestServer(t,
+		WithRecipientStore(store),
+		WithRecipientsFetcher(store),
+		WithIdempotency(memstore.NewIdempotency(), time.Hour),
+	)
+
+	post := func(key, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/recipients", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if key != "" {
+			req.Header.Set(IdempotencyKeyHeader, key)
+		}
+		return makeHTTPCall(svr.router, req)
+	}
+	body := `{"name":"user1","email":"user1@testmail.com"}`
+
+	first := post("abc", body)
+	require.Equal(t, http.StatusCreated, first.Code, "Response HTTP status in different than expected")
+	assert.Empty(t, first.Header().Get(IdempotentReplayedHeader), "The first response should not be a replay")
+
+	replay := post("abc", body)
+	assert.Equal(t, http.StatusCreated, replay.Code, "Replays should get the original status")
+	assert.Equal(t, "true", replay.Header().Get(IdempotentReplayedHeader))
+	assert.Equal(t, first.Header().Get("Content-Type"), replay.Header().Get("Content-Type"))
+	assert.JSONEq(t, first.Body.String(), replay.Body.String(), "Replays should get the original body")
+
+	recipients, _ := store.ListRecipients(context.Background(), recipient.ListOptions{})
+	assert.Len(t, recipients, 1, "Replays should not create the recipient again")
+
+	conflict := post("abc", `{"name":"user2","email":"user2@testmail.com"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, conflict.Code, "Reusing a key for another body should be rejected")
+	var e apierror.Error
+	require.NoError(t, json.Unmarshal(conflict.Body.Bytes(), &e), "Got error when not expected")
+	assert.Equal(t, apierror.CodeIdempotencyKeyReused, e.Code)
+
+	// Without a key, the duplicate email is reported as usual.
+	assert.Equal(t, http.StatusConflict, post("", body).Code, "Response HTTP status in different than expected")
+	assert.Equal(t, http.StatusBadRequest, post(strings.Repeat("k", 256), body).Code, "Overlong keys should be rejected")
+}
+
+func TestIdempotent(t *testing.T) {
+	keys := memstore.NewIdempotency()
+	svr := newTestServer(t, WithIdempotency(keys, time.Hour))
+
+	calls := 0
+	status := http.StatusServiceUnavailable
+	h := svr.idempotent(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(status)
+	})
+	call := func(key string) int {
+		req := httptest.NewRequest(http.MethodPost, "/quote/send", nil)
+		req.Header.Set(IdempotencyKeyHeader, key)
+		rr := httptest.NewRecorder()
+		h(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusServiceUnavailable, call("abc"))
+	status = http.StatusOK
+	assert.Equal(t, http.StatusOK, call("abc"), "Server errors should not be saved")
+	assert.Equal(t, http.StatusOK, call("abc"))
+	assert.Equal(t, 2, calls, "Saved responses should be replayed without calling the handler")
+
+	now := time.Now()
+	fp := fingerprint(httptest.NewRequest(http.MethodPost, "/quote/send", nil), nil)
+	keys.Reserve(context.Background(), idempotency.Record{Key: "running", Fingerprint: fp, CreatedAt: now, ExpiresAt: now.Add(time.Hour)})
+	assert.Equal(t, http.StatusConflict, call("running"), "Keys still in progress should be rejected")
+}
+
+func TestWithIdempotency(t *testing.T) {
+	assert.Error(t, WithIdempotency(memstore.NewIdempotency(), 0)(&Server{}), "Got no error when expected")
+}
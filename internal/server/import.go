@@ -0,0 +1,200 @@
+// internal/server/import.go
+
+package server
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/internal/audit"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// maxImportRows caps a single import so one upload cannot hold the
+// transaction open indefinitely.
+const maxImportRows = 10000
+
+// ImportRow reports the outcome of one uploaded row. Rows are numbered from 1,
+// not counting a CSV header.
+type ImportRow struct {
+	Row       int                  `json:"row"`
+	Recipient *recipient.Recipient `json:"recipient,omitempty"`
+	Error     string               `json:"error,omitempty"`
+}
+
+// ImportReport ...
+type ImportReport struct {
+	Imported int         `json:"imported"`
+	Failed   int         `json:"failed"`
+	Rows     []ImportRow `json:"rows"`
+}
+
+var errTooManyRows = apierror.BadRequest(apierror.CodeBadRequest, fmt.Sprintf("import is limited to %d rows", maxImportRows))
+
+// handleImportRecipients creates recipients from a CSV (with a name,email
+// header) or JSON array upload. Invalid rows and taken emails are reported
+// per row and do not fail the import.
+func (s *Server) handleImportRecipients() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var parse func(io.Reader, func(recipient.Recipient) error) error
+		switch mediaType(r.Header.Get("Content-Type")) {
+		case "text/csv":
+			parse = parseCSVRecipients
+		case "", "application/json":
+			parse = parseJSONRecipients
+		default:
+			s.writeError(w, r, apierror.New(http.StatusUnsupportedMediaType, apierror.CodeUnsupportedMediaType,
+				"import accepts text/csv or application/json"))
+			return
+		}
+
+		var report ImportReport
+		var valid []recipient.Recipient
+		var validRows []int
+		err := parse(r.Body, func(rcpt recipient.Recipient) error {
+			if len(report.Rows) == maxImportRows {
+				return errTooManyRows
+			}
+
+			row := ImportRow{Row: len(report.Rows) + 1}
+			if err := rcpt.Validate(); err != nil {
+				row.Error = err.Error()
+			} else {
+				valid = append(valid, rcpt)
+				validRows = append(validRows, len(report.Rows))
+			}
+			report.Rows = append(report.Rows, row)
+			return nil
+		})
+		var apiErr *apierror.Error
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &apiErr) || errors.As(err, &tooLarge) {
+			s.writeError(w, r, err)
+			return
+		}
+		if err != nil {
+			s.writeError(w, r, apierror.BadRequest(apierror.CodeBadRequest, err.Error()))
+			return
+		}
+
+		var created []*recipient.Recipient
+		if len(valid) > 0 {
+			created, err = s.recipientStore.Import(r.Context(), valid)
+			if err != nil {
+				s.writeError(w, r, storeError(err))
+				return
+			}
+		}
+
+		for i, rcpt := range created {
+			row := &report.Rows[validRows[i]]
+			if rcpt == nil {
+				row.Error = recipient.ErrDuplicateEmail.Error()
+				continue
+			}
+			row.Recipient = rcpt
+			s.audit(r, audit.ActionCreate, audit.EntityRecipient, rcpt.ID)
+			s.sendVerification(r.Context(), *rcpt)
+		}
+
+		for _, row := range report.Rows {
+			if row.Error != "" {
+				report.Failed++
+			} else {
+				report.Imported++
+			}
+		}
+		if report.Rows == nil {
+			report.Rows = []ImportRow{}
+		}
+
+		writeJSON(w, http.StatusOK, report)
+	}
+}
+
+func mediaType(contentType string) string {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return contentType
+	}
+	return mt
+}
+
+// parseJSONRecipients streams the elements of a JSON array to fn.
+func parseJSONRecipients(body io.Reader, fn func(recipient.Recipient) error) error {
+	dec := json.NewDecoder(body)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return errInvalidJSON
+	}
+
+	for dec.More() {
+		var rcpt recipient.Recipient
+		if err := dec.Decode(&rcpt); err != nil {
+			return errInvalidJSON
+		}
+		if err := fn(recipient.Recipient{Name: rcpt.Name, Email: rcpt.Email}); err != nil {
+			return err
+		}
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return errInvalidJSON
+	}
+	return nil
+}
+
+// parseCSVRecipients streams the rows of a CSV upload to fn. The header names
+// the name and email columns, in any order.
+func parseCSVRecipients(body io.Reader, fn func(recipient.Recipient) error) error {
+	cr := csv.NewReader(body)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("invalid CSV: %w", err)
+	}
+
+	nameCol, emailCol := -1, -1
+	for i, col := range header {
+		switch strings.ToLower(strings.TrimSpace(col)) {
+		case "name":
+			nameCol = i
+		case "email":
+			emailCol = i
+		}
+	}
+	if nameCol < 0 || emailCol < 0 {
+		return errors.New("CSV header must contain name and email columns")
+	}
+
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid CSV: %w", err)
+		}
+
+		var rcpt recipient.Recipient
+		if nameCol < len(record) {
+			rcpt.Name = strings.TrimSpace(record[nameCol])
+		}
+		if emailCol < len(record) {
+			rcpt.Email = strings.TrimSpace(record[emailCol])
+		}
+		if err := fn(rcpt); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// internal/server/import_test.go
+
+package server
+
+import (
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandleImportRecipients(t *testing.T) {
+	valid := []recipient.Recipient{
+		{Name: "user1", Email: "user1@testmail.com"},
+		{Name: "user2", Email: "user2@testmail.com"},
+	}
+	created := []*recipient.Recipient{
+		{ID: 1, Name: "user1", Email: "user1@testmail.com"},
+		nil,
+	}
+	report := `{"imported":1,"failed":2,"rows":[` +
+		`{"row":1,"recipient":{"id":1,"name":"user1","email":"user1@testmail.com","verified":false}},` +
+		`{"row":2,"error":"invalid email \"nope\""},` +
+		`{"row":3,"error":"email already exists"}]}`
+
+	testCases := []struct {
+		name           string
+		contentType    string
+		body           string
+		createMock     func() *MockRecipientStore
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			"JSON",
+			"application/json",
+			`[{"name":"user1","email":"user1@testmail.com"},{"name":"bad","email":"nope"},{"name":"user2","email":"user2@testmail.com","verified":true}]`,
+			func() *MockRecipientStore {
+				m := MockRecipientStore{}
+				m.On("Import", mock.Anything, valid).Return(created, nil)
+				return &m
+			},
+			http.StatusOK,
+			report,
+		},
+		{
+			"CSV",
+			"text/csv; charset=utf-8",
+			"email,name\nuser1@testmail.com,user1\nnope,bad\n\"user2@testmail.com\", user2\n",
+			func() *MockRecipientStore {
+				m := MockRecipientStore{}
+				m.On("Import", mock.Anything, valid).Return(created, nil)
+				return &m
+			},
+			http.StatusOK,
+			report,
+		},
+		{
+			"EmptyArray",
+			"application/json",
+			`[]`,
+			func() *MockRecipientStore { return &MockRecipientStore{} },
+			http.StatusOK,
+			`{"imported":0,"failed":0,"rows":[]}`,
+		},
+		{
+			"InvalidJSON",
+			"application/json",
+			`[{"name":"user1"`,
+			func() *MockRecipientStore { return &MockRecipientStore{} },
+			http.StatusBadRequest,
+			`{"code":"INVALID_JSON","message":"request body is not valid JSON"}`,
+		},
+		{
+			"MissingCSVHeader",
+			"text/csv",
+			"user1,user1@testmail.com\n",
+			func() *MockRecipientStore { return &MockRecipientStore{} },
+			http.StatusBadRequest,
+			`{"code":"BAD_REQUEST","message":"CSV header must contain name and email columns"}`,
+		},
+		{
+			"UnsupportedMediaType",
+			"application/xml",
+			`<recipients/>`,
+			func() *MockRecipientStore { return &MockRecipientStore{} },
+			http.StatusUnsupportedMediaType,
+			`{"code":"UNSUPPORTED_MEDIA_TYPE","message":"import accepts text/csv or application/json"}`,
+		},
+		{
+			"StoreFail",
+			"application/json",
+			`[{"name":"user1","email":"user1@testmail.com"}]`,
+			func() *MockRecipientStore {
+				m := MockRecipientStore{}
+				m.On("Import", mock.Anything, valid[:1]).Return(nil, errors.New("sample error"))
+				return &m
+			},
+			http.StatusServiceUnavailable,
+			`{"code":"DB_ERROR","message":"database unavailable"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRecipientStore := tc.createMock()
+			svr := newTestServer(t, WithRecipientStore(mockRecipientStore))
+
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/recipients/import", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", tc.contentType)
+
+			svr.handleImportRecipients()(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Response HTTP body in different than expected")
+			mockRecipientStore.AssertExpectations(t)
+		})
+	}
+}
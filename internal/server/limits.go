@@ -0,0 +1,40 @@
+// internal/server/limits.go
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// WithMaxBodySize fails requests whose body is larger than n bytes with 413.
+// Zero disables the limit.
+func WithMaxBodySize(n int) Option {
+	return func(s *Server) error {
+		if n < 0 {
+			return fmt.Errorf("server: negative max body size %d", n)
+		}
+		s.maxBodySize = int64(n)
+		return nil
+	}
+}
+
+// withBodyLimit turns away requests announcing too large a body right away,
+// and caps the rest, so a client cannot stream an endless body into a
+// handler.
+func (s *Server) withBodyLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.maxBodySize <= 0 || r.Body == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if r.ContentLength > s.maxBodySize {
+			s.writeError(w, r, &http.MaxBytesError{Limit: s.maxBodySize})
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, s.maxBodySize)
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,97 @@
+// internal/server/limits_test.go
+
+package server
+
+import (
+	"encoding/json"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/internal/memstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWithBodyLimit(t *testing.T) {
+	small := `{"name":"Bob","email":"bob@testmail.com"}`
+	large := `{"name":"` + strings.Repeat("Bob", 100) + `","email":"bob@testmail.com"}`
+
+	testCases := []struct {
+		desc           string
+		path           string
+		contentType    string
+		body           string
+		chunked        bool
+		expectedStatus int
+	}{
+		{"WithinLimit", "/recipients", "application/json", small, false, http.StatusCreated},
+		{"ContentLengthTooLarge", "/recipients", "application/json", large, false, http.StatusRequestEntityTooLarge},
+		{"ChunkedTooLarge", "/recipients", "application/json", large, true, http.StatusRequestEntityTooLarge},
+		{"ImportTooLarge", "/recipients/import", "text/csv", "name,email\n" + strings.Repeat("Bob,bob@testmail.com\n", 10), true, http.StatusRequestEntityTooLarge},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			store := memstore.NewRecipients(memstore.NewQuotes())
+			svr := newTestServer(t, WithRecipientStore(store), WithRecipientsFetcher(store), WithMaxBodySize(128))
+
+			req, _ := http.NewRequest("POST", tC.path, strings.NewReader(tC.body))
+			req.Header.Set("Content-Type", tC.contentType)
+			if tC.chunked {
+				// An unknown length is only caught while reading.
+				req.ContentLength = -1
+			}
+			rr := makeHTTPCall(svr.router, req)
+			assert.Equal(t, tC.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			if tC.expectedStatus != http.StatusRequestEntityTooLarge {
+				return
+			}
+
+			var e apierror.Error
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &e), "Got error when not expected")
+			assert.Equal(t, apierror.CodeBodyTooLarge, e.Code)
+		})
+	}
+}
+
+func TestWithMaxBodySize(t *testing.T) {
+	assert.Error(t, WithMaxBodySize(-1)(&Server{}), "Got no error when expected")
+	assert.NoError(t, WithMaxBodySize(0)(&Server{}), "Got error when not expected")
+}
+
+// TestUntimedPathsWriteDeadline checks that streams outlive the write
+// timeout of the http.Server while other routes are cut off by it.
+func TestUntimedPathsWriteDeadline(t *testing.T) {
+	untimedPaths["/slow/stream"] = true
+	defer delete(untimedPaths, "/slow/stream")
+
+	svr := newTestServer(t)
+	slow := func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("done"))
+	}
+	svr.router.HandleFunc("/slow/stream", slow)
+	svr.router.HandleFunc("/slow/request", slow)
+
+	ts := httptest.NewUnstartedServer(svr.router)
+	ts.Config.WriteTimeout = 20 * time.Millisecond
+	ts.Start()
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/slow/stream")
+	require.NoError(t, err, "Got error when not expected")
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Equal(t, "done", string(body), "Untimed paths should be exempt from the write timeout")
+
+	body = nil
+	resp, err = http.Get(ts.URL + "/slow/request")
+	if err == nil {
+		body, _ = io.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+	assert.NotEqual(t, "done", string(body), "Other paths should be cut off by the write timeout")
+}
@@ -0,0 +1,99 @@
+// internal/server/maintenance.go
+
+package server
+
+import (
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Maintenance is the state of maintenance mode, which admins turn on while
+// they work on the server.
+type Maintenance struct {
+	Enabled bool `json:"enabled"`
+	// Message tells clients what is going on.
+	Message string `json:"message,omitempty"`
+	// ETA is when maintenance is expected to end, if known.
+	ETA *time.Time `json:"eta,omitempty"`
+}
+
+// MaintenanceRequest turns maintenance mode on.
+type MaintenanceRequest struct {
+	Message string     `json:"message" validate:"max=500" doc:"what clients are told, a generic message when empty"`
+	ETA     *time.Time `json:"eta" doc:"when maintenance is expected to end, sent to clients as Retry-After"`
+}
+
+// MaintenanceDetails are the details of the errors answered in maintenance.
+type MaintenanceDetails struct {
+	ETA *time.Time `json:"eta,omitempty"`
+}
+
+const defaultMaintenanceMessage = "the server is under maintenance, retry later"
+
+// withMaintenance answers 503 while maintenance mode is on, except to health
+// probes and to admins, so the server can be worked on and brought back.
+func (s *Server) withMaintenance(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m := s.maintenance.Load()
+		if m == nil || unlimitedPaths[r.URL.Path] || s.isAdmin(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if m.ETA != nil {
+			if wait := m.ETA.Sub(s.now()); wait > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			}
+		}
+		message := m.Message
+		if message == "" {
+			message = defaultMaintenanceMessage
+		}
+		s.writeError(w, r, apierror.New(http.StatusServiceUnavailable, apierror.CodeMaintenance, message).
+			WithDetails(MaintenanceDetails{ETA: m.ETA}))
+	})
+}
+
+// currentMaintenance returns the state of maintenance mode.
+func (s *Server) currentMaintenance() Maintenance {
+	if m := s.maintenance.Load(); m != nil {
+		return *m
+	}
+	return Maintenance{}
+}
+
+func (s *Server) handleMaintenance() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, s.currentMaintenance())
+	}
+}
+
+// handleStartMaintenance turns maintenance mode on, or updates its message
+// and ETA, until it is turned off or the server restarts.
+func (s *Server) handleStartMaintenance() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req MaintenanceRequest
+		if !s.bindRequest(w, r, &req) {
+			return
+		}
+
+		m := &Maintenance{Enabled: true, Message: req.Message, ETA: req.ETA}
+		s.maintenance.Store(m)
+		s.logger.Warn().Str("message", m.Message).Msg("maintenance mode turned on by admin")
+
+		writeJSON(w, http.StatusOK, m)
+	}
+}
+
+func (s *Server) handleStopMaintenance() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.maintenance.Swap(nil) != nil {
+			s.logger.Info().Msg("maintenance mode turned off by admin")
+		}
+
+		writeJSON(w, http.StatusOK, s.currentMaintenance())
+	}
+}
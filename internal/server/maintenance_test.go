@@ -0,0 +1,87 @@
+// internal/server/maintenance_test.go
+
+package server
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaintenance(t *testing.T) {
+	svr := newTestServer(t)
+	now := time.Date(2019, 10, 13, 10, 0, 0, 0, time.UTC)
+	svr.now = func() time.Time { return now }
+
+	req := asAdmin(httptest.NewRequest("PUT", "/admin/maintenance", strings.NewReader(`{"message":"upgrading the database","eta":"2019-10-13T10:30:00Z"}`)))
+	req.Header.Set("Content-Type", "application/json")
+	rr := makeHTTPCall(svr.router, req)
+	require.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+
+	testCases := []struct {
+		desc           string
+		path           string
+		admin          bool
+		expectedStatus int
+	}{
+		{"Quote", "/quote", false, http.StatusServiceUnavailable},
+		{"Languages", "/languages", false, http.StatusServiceUnavailable},
+		{"Liveness", "/healthz", false, http.StatusOK},
+		{"Readiness", "/readyz", false, http.StatusOK},
+		{"Admin", "/admin/maintenance", true, http.StatusOK},
+		{"AdminUnauthenticated", "/admin/maintenance", false, http.StatusServiceUnavailable},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tC.path, nil)
+			if tC.admin {
+				asAdmin(req)
+			}
+			rr := makeHTTPCall(svr.router, req)
+			assert.Equal(t, tC.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+		})
+	}
+
+	req, _ = http.NewRequest("GET", "/quote", nil)
+	rr = makeHTTPCall(svr.router, req)
+	assert.Equal(t, "1800", rr.Header().Get("Retry-After"), "Clients should retry at the ETA")
+	var body struct {
+		Code    string             `json:"code"`
+		Message string             `json:"message"`
+		Details MaintenanceDetails `json:"details"`
+	}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&body), "Got error when not expected")
+	assert.Equal(t, "MAINTENANCE", body.Code)
+	assert.Equal(t, "upgrading the database", body.Message)
+	require.NotNil(t, body.Details.ETA, "The ETA should be reported")
+	assert.True(t, body.Details.ETA.Equal(now.Add(30*time.Minute)))
+
+	req = asAdmin(httptest.NewRequest("DELETE", "/admin/maintenance", nil))
+	rr = makeHTTPCall(svr.router, req)
+	require.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+	assert.JSONEq(t, `{"enabled":false}`, rr.Body.String())
+
+	req, _ = http.NewRequest("GET", "/languages", nil)
+	rr = makeHTTPCall(svr.router, req)
+	assert.Equal(t, http.StatusOK, rr.Code, "Requests should be served again")
+}
+
+func TestMaintenance_NoETA(t *testing.T) {
+	svr := newTestServer(t)
+
+	req := asAdmin(httptest.NewRequest("PUT", "/admin/maintenance", strings.NewReader(`{}`)))
+	req.Header.Set("Content-Type", "application/json")
+	rr := makeHTTPCall(svr.router, req)
+	require.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+
+	req, _ = http.NewRequest("GET", "/quote", nil)
+	rr = makeHTTPCall(svr.router, req)
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code, "Response HTTP status in different than expected")
+	assert.Empty(t, rr.Header().Get("Retry-After"), "Without an ETA, there is nothing to retry after")
+	assert.Contains(t, rr.Body.String(), defaultMaintenanceMessage)
+}
@@ -0,0 +1,110 @@
+// internal/server/middleware_test.go
+
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/jeniok12/golang-tdd-example/ratelimit"
+	"github.com/jeniok12/golang-tdd-example/requestid"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestIDAndLogging(t *testing.T) {
+	testCases := []struct {
+		name       string
+		incomingID string
+	}{
+		{"GeneratedID", ""},
+		{"IncomingID", "abc123"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			svr := newTestServer(t, WithLogger(zerolog.New(&logs)))
+
+			var handlerID string
+			svr.router.HandleFunc("/teapot", func(w http.ResponseWriter, r *http.Request) {
+				handlerID = requestid.FromContext(r.Context())
+				w.WriteHeader(http.StatusTeapot)
+			})
+
+			req, _ := http.NewRequest("GET", "/teapot", nil)
+			if tC.incomingID != "" {
+				req.Header.Set(requestid.Header, tC.incomingID)
+			}
+			rr := httptest.NewRecorder()
+			svr.router.ServeHTTP(rr, req)
+
+			responseID := rr.Header().Get(requestid.Header)
+			assert.NotEmpty(t, responseID, "Response should carry a request ID")
+			assert.Equal(t, responseID, handlerID, "Handler should see the same request ID")
+			if tC.incomingID != "" {
+				assert.Equal(t, tC.incomingID, responseID, "Incoming request ID should be reused")
+			}
+
+			var entry map[string]interface{}
+			_ = json.Unmarshal(logs.Bytes(), &entry)
+			assert.Equal(t, responseID, entry["requestId"], "Log should contain the request ID")
+			assert.Equal(t, "GET", entry["method"], "Log should contain the method")
+			assert.Equal(t, "/teapot", entry["path"], "Log should contain the path")
+			assert.Equal(t, float64(http.StatusTeapot), entry["status"], "Log should contain the status")
+			assert.Contains(t, entry, "duration", "Log should contain the duration")
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	svr := newTestServer(t, WithRateLimiter(&ratelimit.Limiter{
+		Store: ratelimit.NewMemory(),
+		Rate:  0.001,
+		Burst: 1,
+	}))
+	svr.router.HandleFunc("/teapot", func(w http.ResponseWriter, r *http.Request) {})
+
+	do := func(path, remoteAddr, apiKey string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.RemoteAddr = remoteAddr
+		if apiKey != "" {
+			req.Header.Set(APIKeyHeader, apiKey)
+		}
+		rr := httptest.NewRecorder()
+		svr.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusOK, do("/teapot", "10.0.0.1:1234", "").Code, "First request should pass")
+
+	rr := do("/teapot", "10.0.0.1:5678", "")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code, "Second request from the same IP should be limited")
+	assert.NotEmpty(t, rr.Header().Get("Retry-After"), "Limited response should carry Retry-After")
+
+	assert.Equal(t, http.StatusOK, do("/teapot", "10.0.0.2:1234", "").Code, "Other IPs should have their own bucket")
+	assert.Equal(t, http.StatusOK, do("/teapot", "10.0.0.1:1234", "secret").Code, "API keys should have their own bucket")
+	assert.Equal(t, http.StatusOK, do("/healthz", "10.0.0.1:1234", "").Code, "Health checks should not be limited")
+}
+
+func TestWithMiddleware(t *testing.T) {
+	var calls []string
+	record := func(name string) mux.MiddlewareFunc {
+		return func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls = append(calls, name+":"+requestid.FromContext(r.Context()))
+				next.ServeHTTP(w, r)
+			})
+		}
+	}
+
+	svr := newTestServer(t, WithMiddleware(record("first"), record("second")))
+
+	req, _ := http.NewRequest("GET", "/healthz", nil)
+	req.Header.Set(requestid.Header, "abc123")
+	svr.router.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, []string{"first:abc123", "second:abc123"}, calls, "Custom middleware should run in order after the built-in middleware")
+}
@@ -0,0 +1,193 @@
+// internal/server/notifications.go
+
+package server
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/verify"
+	"github.com/jeniok12/golang-tdd-example/internal/webhook"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// CreateWebhookRequest is the body registering a webhook.
+type CreateWebhookRequest struct {
+	URL    string `json:"url" validate:"required,webhookurl" doc:"absolute http or https URL the events are POSTed to"`
+	Secret string `json:"secret" doc:"key signing the payloads; a random one is generated when empty"`
+}
+
+// CreateWebhookResponse is the registered webhook. Its secret is only ever
+// shown here.
+type CreateWebhookResponse struct {
+	webhook.Hook
+	Secret string `json:"secret"`
+}
+
+type webhookDeliveriesRequest struct {
+	Limit int `query:"limit" default:"20" validate:"min=1,max=100" doc:"maximum number of deliveries"`
+}
+
+// QuoteDeliveredEvent is the data of quote.delivered notifications.
+type QuoteDeliveredEvent struct {
+	Quote  *quote.Quote `json:"quote"`
+	Sent   int          `json:"sent"`
+	Queued int          `json:"queued"`
+	Failed int          `json:"failed"`
+}
+
+// WithWebhooks notifies the hooks registered in store of generated and
+// delivered quotes. Once the server is started, w POSTs the notifications
+// every interval.
+func WithWebhooks(store webhook.Store, w *webhook.Worker, interval time.Duration) Option {
+	return func(s *Server) error {
+		if interval <= 0 {
+			return fmt.Errorf("server: webhook interval must be positive, got %s", interval)
+		}
+		s.hooks = store
+		s.hookWorker = w
+		s.hookInterval = interval
+		return nil
+	}
+}
+
+var errWebhooksDisabled = apierror.NotFound("webhooks are disabled")
+
+// notify queues event for every registered hook. Notifications are a side
+// effect, so failing to queue one is logged rather than failing the request.
+func (s *Server) notify(ctx context.Context, event string, data interface{}) {
+	if s.hooks == nil {
+		return
+	}
+
+	payload, err := json.Marshal(webhook.Event{Type: event, CreatedAt: s.now().UTC(), Data: data})
+	if err == nil {
+		// Queued even when the client is gone, the quote was served anyway.
+		_, err = s.hooks.Enqueue(context.WithoutCancel(ctx), event, payload)
+	}
+	if err != nil {
+		s.logger.Error().Err(err).Str("event", event).Msg("queueing webhook notification")
+	}
+}
+
+// notifyDelivered sends quote.delivered for the outcome of deliveries.
+func (s *Server) notifyDelivered(ctx context.Context, q *quote.Quote, deliveries []Delivery) {
+	e := QuoteDeliveredEvent{Quote: q}
+	for _, d := range deliveries {
+		switch {
+		case d.Sent:
+			e.Sent++
+		case d.Queued:
+			e.Queued++
+		default:
+			e.Failed++
+		}
+	}
+	s.notify(ctx, webhook.EventQuoteDelivered, e)
+}
+
+func (s *Server) handleListWebhooks() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.hooks == nil {
+			s.writeError(w, r, errWebhooksDisabled)
+			return
+		}
+
+		hooks, err := s.hooks.ListHooks(r.Context())
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, hooks)
+	}
+}
+
+func (s *Server) handleCreateWebhook() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.hooks == nil {
+			s.writeError(w, r, errWebhooksDisabled)
+			return
+		}
+
+		var req CreateWebhookRequest
+		if !s.bindRequest(w, r, &req) {
+			return
+		}
+
+		if req.Secret == "" {
+			key, err := verify.RandomKey()
+			if err != nil {
+				s.writeError(w, r, apierror.Internal(err))
+				return
+			}
+			req.Secret = hex.EncodeToString(key)
+		}
+
+		hook, err := s.hooks.CreateHook(r.Context(), webhook.Hook{URL: req.URL, Secret: req.Secret})
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+		s.logger.Info().Int("webhook", hook.ID).Str("url", hook.URL).Msg("webhook registered")
+
+		writeJSON(w, http.StatusCreated, CreateWebhookResponse{Hook: *hook, Secret: hook.Secret})
+	}
+}
+
+func (s *Server) handleDeleteWebhook() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.hooks == nil {
+			s.writeError(w, r, errWebhooksDisabled)
+			return
+		}
+
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.writeError(w, r, errInvalidID)
+			return
+		}
+
+		if err := s.hooks.DeleteHook(r.Context(), id); err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+		s.logger.Info().Int("webhook", id).Msg("webhook removed")
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) handleWebhookDeliveries() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.hooks == nil {
+			s.writeError(w, r, errWebhooksDisabled)
+			return
+		}
+
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.writeError(w, r, errInvalidID)
+			return
+		}
+
+		var req webhookDeliveriesRequest
+		if !s.bindRequest(w, r, &req) {
+			return
+		}
+
+		deliveries, err := s.hooks.ListDeliveries(r.Context(), id, req.Limit)
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, deliveries)
+	}
+}
@@ -0,0 +1,121 @@
+// internal/server/notifications_test.go
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/jeniok12/golang-tdd-example/internal/memstore"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/webhook"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleWebhooks(t *testing.T) {
+	hooks := memstore.NewWebhooks()
+	svr := newTestServer(t, WithWebhooks(hooks, &webhook.Worker{Queue: hooks}, time.Minute))
+
+	testCases := []struct {
+		desc           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{"Create", http.MethodPost, "/admin/webhooks", `{"url":"https://example.com/hook","secret":"s3cret"}`, http.StatusCreated},
+		{"CreateWithoutSecret", http.MethodPost, "/admin/webhooks", `{"url":"http://example.com/other"}`, http.StatusCreated},
+		{"CreateWithoutURL", http.MethodPost, "/admin/webhooks", `{"secret":"s3cret"}`, http.StatusBadRequest},
+		{"CreateRelativeURL", http.MethodPost, "/admin/webhooks", `{"url":"/hook"}`, http.StatusBadRequest},
+		{"CreateFTPURL", http.MethodPost, "/admin/webhooks", `{"url":"ftp://example.com/hook"}`, http.StatusBadRequest},
+		{"List", http.MethodGet, "/admin/webhooks", "", http.StatusOK},
+		{"Deliveries", http.MethodGet, "/admin/webhooks/1/deliveries", "", http.StatusOK},
+		{"DeliveriesLimitTooHigh", http.MethodGet, "/admin/webhooks/1/deliveries?limit=1000", "", http.StatusBadRequest},
+		{"DeliveriesUnknownHook", http.MethodGet, "/admin/webhooks/42/deliveries", "", http.StatusNotFound},
+		{"Delete", http.MethodDelete, "/admin/webhooks/1", "", http.StatusNoContent},
+		{"DeleteAgain", http.MethodDelete, "/admin/webhooks/1", "", http.StatusNotFound},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			req := asAdmin(httptest.NewRequest(tC.method, tC.path, strings.NewReader(tC.body)))
+			req.Header.Set("Content-Type", "application/json")
+			rr := makeHTTPCall(svr.router, req)
+
+			assert.Equal(t, tC.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			if tC.method != http.MethodPost || rr.Code != http.StatusCreated {
+				return
+			}
+
+			var resp CreateWebhookResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp), "Response should be a webhook")
+			assert.NotEmpty(t, resp.Secret, "The secret should be shown once")
+		})
+	}
+
+	list, _ := hooks.ListHooks(context.Background())
+	assert.Len(t, list, 1, "Only the second webhook should be left")
+}
+
+func TestHandleWebhooks_Disabled(t *testing.T) {
+	svr := newTestServer(t)
+
+	req := asAdmin(httptest.NewRequest(http.MethodGet, "/admin/webhooks", nil))
+	rr := makeHTTPCall(svr.router, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code, "Response HTTP status in different than expected")
+}
+
+func TestWebhookNotifications(t *testing.T) {
+	expected := &quote.Quote{Text: "Bla", Author: "Me", Lang: "en"}
+
+	received := make(chan webhook.Event, 1)
+	hookServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+		timestamp, _ := strconv.ParseInt(req.Header.Get(webhook.TimestampHeader), 10, 64)
+		assert.Equal(t, webhook.Sign("s3cret", timestamp, body), req.Header.Get(webhook.SignatureHeader), "Payload should be signed")
+
+		var e webhook.Event
+		assert.NoError(t, json.Unmarshal(body, &e), "Payload should be an event")
+		received <- e
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer hookServer.Close()
+
+	generator := &MockQuoteGenerator{}
+	generator.On("Generate", mock.Anything, mock.Anything).Return(expected, nil)
+	recipients := memstore.NewRecipients(memstore.NewQuotes())
+	hooks := memstore.NewWebhooks()
+	hooks.CreateHook(context.Background(), webhook.Hook{URL: hookServer.URL, Secret: "s3cret"})
+
+	svr := newTestServer(t,
+		WithQuoteGenerator(generator),
+		WithRecipientsFetcher(recipients),
+		WithWebhooks(hooks, &webhook.Worker{Queue: hooks, Client: hookServer.Client()}, time.Minute),
+	)
+
+	rr := makeHTTPCall(svr.router, httptest.NewRequest(http.MethodGet, "/quote", nil))
+	require.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+
+	rr = makeHTTPCall(svr.router, asAdmin(httptest.NewRequest(http.MethodPost, "/admin/jobs/webhook-drain/run", nil)))
+	require.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+	assert.JSONEq(t, `{"job":"webhook-drain"}`, rr.Body.String(), "The drain should succeed")
+
+	e := <-received
+	assert.Equal(t, webhook.EventQuoteGenerated, e.Type, "Event type is different than expected")
+	assert.Equal(t, map[string]interface{}{"quoteText": "Bla", "quoteAuthor": "Me", "lang": "en"}, e.Data, "Event data should be the quote")
+
+	rr = makeHTTPCall(svr.router, asAdmin(httptest.NewRequest(http.MethodGet, "/admin/webhooks/1/deliveries", nil)))
+	var deliveries []webhook.Delivery
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &deliveries), "Response should list deliveries")
+	require.Len(t, deliveries, 1, "The notification should be logged")
+	assert.Equal(t, webhook.StatusDelivered, deliveries[0].Status, "Delivery status is different than expected")
+	assert.Equal(t, http.StatusNoContent, deliveries[0].ResponseStatus, "Response status should be logged")
+}
@@ -0,0 +1,361 @@
+// internal/server/oauth.go
+
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/internal/audit"
+	"github.com/jeniok12/golang-tdd-example/internal/auth"
+	"github.com/jeniok12/golang-tdd-example/internal/config"
+	"github.com/jeniok12/golang-tdd-example/internal/oauth"
+	"github.com/jeniok12/golang-tdd-example/mailer"
+	"github.com/jeniok12/golang-tdd-example/openapi"
+	"github.com/rs/zerolog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WithOAuth lets the clients in store exchange their credentials for tokens
+// signed by issuer on POST /oauth/token, with the client credentials grant.
+// Their scopes are the roles the tokens grant. Tokens are then required
+// like with WithJWT.
+func WithOAuth(store oauth.Store, issuer *auth.Issuer) Option {
+	return func(s *Server) error {
+		s.oauthClients = store
+		s.tokenIssuer = issuer
+		s.verifiers = append(s.verifiers, issuer.Verifier(store))
+		return nil
+	}
+}
+
+// WithSecretRotation lets POST /admin/oauth/clients/{id}/rotate give a
+// client a new secret, the previous one still working for grace.
+func WithSecretRotation(grace time.Duration) Option {
+	return func(s *Server) error {
+		if grace < 0 {
+			return fmt.Errorf("server: negative secret rotation grace %s", grace)
+		}
+		s.rotationGrace = grace
+		return nil
+	}
+}
+
+// newTokenIssuer builds the issuer of the tokens handed out to OAuth2
+// clients from cfg.
+func newTokenIssuer(cfg *config.Config, logger zerolog.Logger) (*auth.Issuer, error) {
+	var key *ecdsa.PrivateKey
+	var err error
+	if cfg.OAuth.SigningKey != "" {
+		key, err = auth.ParseKey([]byte(cfg.OAuth.SigningKey))
+	} else {
+		logger.Warn().Msg("no OAuth signing key configured, tokens will not survive a restart")
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	name := cfg.OAuth.Issuer
+	if name == "" {
+		name = cfg.PublicURL
+	}
+	return auth.NewIssuer(name, key, cfg.OAuth.TokenTTL.Duration)
+}
+
+// TokenRequest documents the form exchanged for a token. The client may
+// authenticate with HTTP Basic instead of its ID and secret.
+type TokenRequest struct {
+	GrantType    string `json:"grant_type" doc:"client_credentials"`
+	Scope        string `json:"scope" doc:"space separated roles to grant, all of the client's when empty"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+}
+
+// RevokeRequest documents the form revoking a token.
+type RevokeRequest struct {
+	Token        string `json:"token" doc:"access token to revoke"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+}
+
+// TokenResponse is an access token, as in RFC 6749.
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	Scope       string `json:"scope"`
+}
+
+// OAuthErrorResponse is an error of the token endpoints, as in RFC 6749,
+// which OAuth2 client libraries expect instead of the usual error envelope.
+type OAuthErrorResponse struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+// CreateOAuthClientRequest is the body registering an OAuth2 client.
+type CreateOAuthClientRequest struct {
+	Name   string   `json:"name" validate:"required,max=200"`
+	Scopes []string `json:"scopes" validate:"required,scopes" doc:"roles the client may get tokens for: reader, editor or admin"`
+	Owner  string   `json:"owner" validate:"email" doc:"email address told when the secret is rotated"`
+}
+
+// CreateOAuthClientResponse is the client registered or rotated. Its secret
+// is only ever shown here.
+type CreateOAuthClientResponse struct {
+	oauth.Client
+	Secret string `json:"clientSecret"`
+}
+
+func (s *Server) oauthRoutes() {
+	s.handle(openapi.Operation{
+		Method:           "POST",
+		Path:             "/oauth/token",
+		Summary:          "Exchange OAuth2 client credentials for an access token",
+		Description:      "Only the client_credentials grant is supported. Errors are reported as in RFC 6749 rather than in the usual envelope.",
+		Tags:             []string{"auth"},
+		Body:             TokenRequest{},
+		BodyContentTypes: []string{"application/x-www-form-urlencoded"},
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Body: TokenResponse{}},
+			{Status: http.StatusBadRequest, Body: OAuthErrorResponse{}},
+			{Status: http.StatusUnauthorized, Body: OAuthErrorResponse{}},
+		},
+	}, s.handleOAuthToken())
+	s.handle(openapi.Operation{
+		Method:           "POST",
+		Path:             "/oauth/revoke",
+		Summary:          "Revoke an access token of the client",
+		Description:      "As in RFC 7009, tokens that are invalid or expired already are answered like revoked ones.",
+		Tags:             []string{"auth"},
+		Body:             RevokeRequest{},
+		BodyContentTypes: []string{"application/x-www-form-urlencoded"},
+		Responses:        []openapi.Response{{Status: http.StatusOK}},
+	}, s.handleOAuthRevoke())
+	s.handle(openapi.Operation{
+		Method:      "POST",
+		Path:        "/admin/oauth/clients",
+		Summary:     "Register an OAuth2 client",
+		Description: "The response holds the client secret, which cannot be retrieved later.",
+		Tags:        []string{"admin"},
+		Body:        CreateOAuthClientRequest{},
+		Responses:   []openapi.Response{{Status: http.StatusCreated, Body: CreateOAuthClientResponse{}}},
+	}, s.handleCreateOAuthClient())
+	s.handle(openapi.Operation{
+		Method:      "DELETE",
+		Path:        "/admin/oauth/clients/{id}",
+		Summary:     "Remove an OAuth2 client",
+		Description: "Tokens issued to the client stay valid until they expire, unless revoked.",
+		Tags:        []string{"admin"},
+		Responses:   []openapi.Response{{Status: http.StatusNoContent}},
+	}, s.handleDeleteOAuthClient())
+	s.handle(openapi.Operation{
+		Method:      "POST",
+		Path:        "/admin/oauth/clients/{id}/rotate",
+		Summary:     "Give an OAuth2 client a new secret",
+		Description: "The previous secret keeps working for the configured grace period, so the client can switch over. The response holds the new secret, which cannot be retrieved later. The owner of the client is emailed.",
+		Tags:        []string{"admin"},
+		Responses:   []openapi.Response{{Status: http.StatusOK, Body: CreateOAuthClientResponse{}}},
+	}, s.handleRotateOAuthClient())
+}
+
+// errInvalidClient is the error of unknown clients and wrong secrets alike.
+var errInvalidClient = errors.New("invalid client credentials")
+
+func writeOAuthError(w http.ResponseWriter, status int, code, description string) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, status, OAuthErrorResponse{Error: code, Description: description})
+}
+
+// oauthClient returns the client authenticated by r, through HTTP Basic or
+// the client_id and client_secret form parameters.
+func (s *Server) oauthClient(r *http.Request) (*oauth.Client, error) {
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if id == "" {
+		return nil, errInvalidClient
+	}
+
+	c, err := s.oauthClients.GetClient(r.Context(), id)
+	if errors.Is(err, oauth.ErrNotFound) || (err == nil && !c.Authenticate(secret, s.now())) {
+		return nil, errInvalidClient
+	}
+	return c, err
+}
+
+// authenticateClient parses the form of r and returns its client, or
+// answers the error and returns nil.
+func (s *Server) authenticateClient(w http.ResponseWriter, r *http.Request) *oauth.Client {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, http.StatusBadRequest, "invalid_request", "the body must be a form")
+		return nil
+	}
+	c, err := s.oauthClient(r)
+	if errors.Is(err, errInvalidClient) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		writeOAuthError(w, http.StatusUnauthorized, "invalid_client", err.Error())
+		return nil
+	}
+	if err != nil {
+		s.writeError(w, r, storeError(err))
+		return nil
+	}
+	return c
+}
+
+func (s *Server) handleOAuthToken() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := s.authenticateClient(w, r)
+		if c == nil {
+			return
+		}
+		if grant := r.PostForm.Get("grant_type"); grant != "client_credentials" {
+			writeOAuthError(w, http.StatusBadRequest, "unsupported_grant_type", "only the client_credentials grant is supported")
+			return
+		}
+
+		scopes, err := c.Grant(strings.Fields(r.PostForm.Get("scope")))
+		if err != nil {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_scope", err.Error())
+			return
+		}
+
+		token, _, err := s.tokenIssuer.Issue(c.ID, scopes)
+		if err != nil {
+			s.writeError(w, r, apierror.Internal(err))
+			return
+		}
+
+		w.Header().Set("Cache-Control", "no-store")
+		writeJSON(w, http.StatusOK, TokenResponse{
+			AccessToken: token,
+			TokenType:   "Bearer",
+			ExpiresIn:   int(s.tokenIssuer.TTL.Seconds()),
+			Scope:       strings.Join(scopes, " "),
+		})
+	}
+}
+
+// handleOAuthRevoke revokes a token of the client, as in RFC 7009. Unknown,
+// invalid and expired tokens are answered like revoked ones.
+func (s *Server) handleOAuthRevoke() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := s.authenticateClient(w, r)
+		if c == nil {
+			return
+		}
+
+		// Verified without the revocations, so revoking twice succeeds.
+		p, err := s.tokenIssuer.Verifier(nil).Verify(r.Context(), r.PostForm.Get("token"))
+		if err != nil {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		if p.Subject != c.ID {
+			writeOAuthError(w, http.StatusBadRequest, "invalid_request", "the token was issued to another client")
+			return
+		}
+
+		if err := s.oauthClients.Revoke(r.Context(), p.TokenID, p.ExpiresAt, s.now()); err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+		s.logger.Info().Str("client", c.ID).Msg("oauth token revoked")
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (s *Server) handleCreateOAuthClient() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req CreateOAuthClientRequest
+		if !s.bindRequest(w, r, &req) {
+			return
+		}
+
+		c, secret, err := oauth.NewClient(req.Name, req.Scopes)
+		if err != nil {
+			s.writeError(w, r, apierror.Internal(err))
+			return
+		}
+		c.CreatedAt = s.now().UTC()
+		c.Owner = req.Owner
+		if err := s.oauthClients.CreateClient(r.Context(), c); err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+		s.logger.Info().Str("client", c.ID).Strs("scopes", c.Scopes).Msg("oauth client registered")
+
+		writeJSON(w, http.StatusCreated, CreateOAuthClientResponse{Client: c, Secret: secret})
+	}
+}
+
+func (s *Server) handleDeleteOAuthClient() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		if err := s.oauthClients.DeleteClient(r.Context(), id); err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+		s.logger.Info().Str("client", id).Msg("oauth client removed")
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) handleRotateOAuthClient() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c, err := s.oauthClients.GetClient(r.Context(), mux.Vars(r)["id"])
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+
+		secret, err := c.Rotate(s.rotationGrace, s.now())
+		if err != nil {
+			s.writeError(w, r, apierror.Internal(err))
+			return
+		}
+		if err := s.oauthClients.UpdateSecrets(r.Context(), *c); err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+		s.auditKey(r, audit.ActionUpdate, audit.EntityOAuthClient, c.ID)
+		s.logger.Info().Str("client", c.ID).Msg("oauth client secret rotated")
+		s.mailRotation(*c)
+
+		writeJSON(w, http.StatusOK, CreateOAuthClientResponse{Client: *c, Secret: secret})
+	}
+}
+
+// mailRotation tells the owner of c that its secret was rotated, if it has
+// one. Failing to is logged; the secret is rotated anyway.
+func (s *Server) mailRotation(c oauth.Client) {
+	if c.Owner == "" || s.mailSender == nil {
+		return
+	}
+	if err := s.mailSender.Send(rotationMessage(c)); err != nil {
+		s.logger.Error().Err(err).Str("client", c.ID).Msg("sending rotation email")
+	}
+}
+
+func rotationMessage(c oauth.Client) mailer.Message {
+	previous := "The previous secret no longer works."
+	if c.PreviousSecretExpiresAt != nil {
+		previous = "The previous secret keeps working until " + c.PreviousSecretExpiresAt.Format(time.RFC1123) + "."
+	}
+	return mailer.Message{
+		To:      c.Owner,
+		Subject: "The secret of your inspiring quotes client was rotated",
+		Body:    fmt.Sprintf("Hi,\n\nThe client secret of %s (%s) was rotated. %s\nAsk an admin for the new one.\n", c.Name, c.ID, previous),
+	}
+}
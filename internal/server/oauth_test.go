@@ -0,0 +1,155 @@
+// internal/server/oauth_test.go
+
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"github.com/jeniok12/golang-tdd-example/internal/audit"
+	"github.com/jeniok12/golang-tdd-example/internal/auth"
+	"github.com/jeniok12/golang-tdd-example/internal/memstore"
+	"github.com/jeniok12/golang-tdd-example/mailer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOAuth(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err, "Got error when not expected")
+	issuer, err := auth.NewIssuer("https://quotes.test", key, 15*time.Minute)
+	require.NoError(t, err, "Got error when not expected")
+	recipients := memstore.NewRecipients(memstore.NewQuotes())
+	svr := newTestServer(t,
+		WithRecipientsFetcher(recipients),
+		WithRecipientStore(recipients),
+		WithAdminToken("admin-secret"),
+		WithOAuth(memstore.NewOAuth(), issuer),
+	)
+	now := time.Now().UTC().Truncate(time.Second)
+	svr.now = func() time.Time { return now }
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/oauth/clients", strings.NewReader(`{"name":"reporting","scopes":["reader"]}`))
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	res := makeHTTPCall(svr.router, req)
+	require.Equal(t, http.StatusCreated, res.Code, "Response HTTP status in different than expected")
+	var client CreateOAuthClientResponse
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&client), "Got error when not expected")
+	assert.True(t, now.Equal(client.CreatedAt), "The client should be created at the server's time")
+
+	oauthCall := func(path, secret string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(client.ID, secret)
+		return makeHTTPCall(svr.router, req)
+	}
+
+	t.Run("Errors", func(t *testing.T) {
+		testCases := []struct {
+			desc           string
+			secret         string
+			form           url.Values
+			expectedStatus int
+			expectedError  string
+		}{
+			{"WrongSecret", "wrong", url.Values{"grant_type": {"client_credentials"}}, http.StatusUnauthorized, "invalid_client"},
+			{"UnsupportedGrant", client.Secret, url.Values{"grant_type": {"password"}}, http.StatusBadRequest, "unsupported_grant_type"},
+			{"ScopeNotGranted", client.Secret, url.Values{"grant_type": {"client_credentials"}, "scope": {"editor"}}, http.StatusBadRequest, "invalid_scope"},
+		}
+		for _, tC := range testCases {
+			t.Run(tC.desc, func(t *testing.T) {
+				res := oauthCall("/oauth/token", tC.secret, tC.form)
+				assert.Equal(t, tC.expectedStatus, res.Code, "Response HTTP status in different than expected")
+				var body OAuthErrorResponse
+				require.NoError(t, json.NewDecoder(res.Body).Decode(&body), "Got error when not expected")
+				assert.Equal(t, tC.expectedError, body.Error, "Error is different than expected")
+			})
+		}
+	})
+
+	res = oauthCall("/oauth/token", client.Secret, url.Values{"grant_type": {"client_credentials"}})
+	require.Equal(t, http.StatusOK, res.Code, "Response HTTP status in different than expected")
+	assert.Equal(t, "no-store", res.Header().Get("Cache-Control"), "Token responses should not be cached")
+	var token TokenResponse
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&token), "Got error when not expected")
+	assert.Equal(t, TokenResponse{AccessToken: token.AccessToken, TokenType: "Bearer", ExpiresIn: 900, Scope: "reader"}, token, "Token is different than expected")
+
+	call := func(method, path string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(`{"name":"Ann","email":"ann@example.com"}`))
+		req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+		return makeHTTPCall(svr.router, req).Code
+	}
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/recipients"), "Readers should read")
+	assert.Equal(t, http.StatusForbidden, call(http.MethodPost, "/recipients"), "Readers should not create")
+
+	res = oauthCall("/oauth/revoke", client.Secret, url.Values{"token": {token.AccessToken}})
+	assert.Equal(t, http.StatusOK, res.Code, "Response HTTP status in different than expected")
+	assert.Equal(t, http.StatusUnauthorized, call(http.MethodGet, "/recipients"), "Revoked tokens should be rejected")
+
+	res = oauthCall("/oauth/revoke", client.Secret, url.Values{"token": {"not-a-token"}})
+	assert.Equal(t, http.StatusOK, res.Code, "Invalid tokens should be answered like revoked ones")
+}
+
+func TestOAuth_Rotate(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err, "Got error when not expected")
+	issuer, err := auth.NewIssuer("https://quotes.test", key, 15*time.Minute)
+	require.NoError(t, err, "Got error when not expected")
+	auditLog := memstore.NewAudit()
+	mockMailSender := MockMailSender{}
+	mockMailSender.On("Send", mock.MatchedBy(func(msg mailer.Message) bool {
+		return msg.To == "ops@example.com" && strings.Contains(msg.Body, "keeps working until")
+	})).Return(nil).Once()
+	svr := newTestServer(t,
+		WithAdminToken("admin-secret"),
+		WithOAuth(memstore.NewOAuth(), issuer),
+		WithSecretRotation(time.Hour),
+		WithAuditLog(auditLog),
+		WithMailSender(&mockMailSender),
+	)
+
+	adminCall := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer admin-secret")
+		return makeHTTPCall(svr.router, req)
+	}
+	res := adminCall("/admin/oauth/clients", `{"name":"reporting","scopes":["reader"],"owner":"ops@example.com"}`)
+	require.Equal(t, http.StatusCreated, res.Code, "Response HTTP status in different than expected")
+	var client CreateOAuthClientResponse
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&client), "Got error when not expected")
+
+	res = adminCall("/admin/oauth/clients/"+client.ID+"/rotate", "")
+	require.Equal(t, http.StatusOK, res.Code, "Response HTTP status in different than expected")
+	var rotated CreateOAuthClientResponse
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&rotated), "Got error when not expected")
+	assert.NotEqual(t, client.Secret, rotated.Secret, "A new secret should be issued")
+	require.NotNil(t, rotated.PreviousSecretExpiresAt, "The end of the grace period should be reported")
+	assert.WithinDuration(t, time.Now().Add(time.Hour), *rotated.PreviousSecretExpiresAt, time.Minute)
+
+	for _, secret := range []string{rotated.Secret, client.Secret} {
+		form := url.Values{"grant_type": {"client_credentials"}}
+		req := httptest.NewRequest(http.MethodPost, "/oauth/token", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(client.ID, secret)
+		assert.Equal(t, http.StatusOK, makeHTTPCall(svr.router, req).Code, "Both secrets should work during the grace period")
+	}
+
+	entries, err := auditLog.List(context.Background(), audit.Filter{Entity: audit.EntityOAuthClient, Limit: 10})
+	require.NoError(t, err, "Got error when not expected")
+	require.Len(t, entries, 1, "The rotation should be audited")
+	assert.Equal(t, audit.ActionUpdate, entries[0].Action)
+	assert.Equal(t, client.ID, entries[0].EntityKey, "The client should be named")
+	mockMailSender.AssertExpectations(t)
+
+	res = adminCall("/admin/oauth/clients/unknown/rotate", "")
+	assert.Equal(t, http.StatusNotFound, res.Code, "Response HTTP status in different than expected")
+}
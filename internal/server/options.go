@@ -0,0 +1,411 @@
+// internal/server/options.go
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/errtrack"
+	"github.com/jeniok12/golang-tdd-example/internal/channel"
+	"github.com/jeniok12/golang-tdd-example/internal/outbox"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/jeniok12/golang-tdd-example/internal/replica"
+	"github.com/jeniok12/golang-tdd-example/internal/templates"
+	"github.com/jeniok12/golang-tdd-example/internal/verify"
+	"github.com/jeniok12/golang-tdd-example/openapi"
+	"github.com/jeniok12/golang-tdd-example/ratelimit"
+	"github.com/jeniok12/golang-tdd-example/worker"
+	"github.com/rs/zerolog"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultWorkers is the size of the background worker pool unless
+// WithWorkers says otherwise.
+const defaultWorkers = 4
+
+// Option configures a Server built by NewServer.
+type Option func(*Server) error
+
+// NewServer builds a Server from opts and registers its routes. A quote
+// generator and a recipients fetcher are required; a recipient store doubles
+// as the fetcher when none is given.
+func NewServer(opts ...Option) (*Server, error) {
+	s := &Server{
+		router:         mux.NewRouter(),
+		logger:         zerolog.Nop(),
+		shutdown:       make(chan struct{}),
+		authorAttempts: defaultAuthorAttempts,
+		feedItems:      defaultFeedItems,
+		workerCount:    defaultWorkers,
+		now:            time.Now,
+		templates:      templates.Embedded(),
+		features:       newFeatures(),
+		spec: &openapi.Spec{
+			Title:   "InspiringQuotes API",
+			Version: "1.0.0",
+			Error:   apierror.Error{},
+		},
+	}
+
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.debug && !s.adminEnabled() {
+		return nil, errors.New("server: the debug routes need an admin token or JWTs")
+	}
+
+	if s.recipientsFetcher == nil && s.recipientStore != nil {
+		s.recipientsFetcher = s.recipientStore
+	}
+
+	if s.quoteGenerator == nil {
+		return nil, errors.New("server: a quote generator is required")
+	}
+	if s.recipientsFetcher == nil {
+		return nil, errors.New("server: a recipients fetcher is required")
+	}
+
+	if err := s.checkLang(s.defaultLang); err != nil && !s.fallsBack(s.defaultLang) {
+		return nil, fmt.Errorf("server: default language: %v", err.Message)
+	}
+	for _, lang := range s.dailyLanguages {
+		if err := s.checkLang(lang); err != nil {
+			return nil, fmt.Errorf("server: daily languages: %v", err.Message)
+		}
+	}
+
+	s.workers = worker.New(s.workerCount, s.workerCount)
+	s.workers.OnError = func(job string, err error) {
+		s.logger.Error().Err(err).Str("job", job).Msg("background job failed")
+
+		e := errtrack.Event{Err: err, Tags: map[string]string{"job": job}}
+		var panicErr *worker.PanicError
+		if errors.As(err, &panicErr) {
+			e.Stack = panicErr.Stack
+		}
+		s.report(context.Background(), e)
+	}
+
+	if s.dailyInterval > 0 {
+		s.scheduler = s.schedule("daily-quote", s.dailyInterval, s.dailyJob)
+	}
+	if s.outboxWorker != nil {
+		s.outboxDrainer = s.schedule("outbox-drain", s.outboxInterval, s.outboxWorker.Drain)
+	}
+	if s.idempotency != nil {
+		s.idempotencyPurger = s.schedule("idempotency-purge", idempotencyPurgeInterval, s.purgeIdempotencyKeys)
+	}
+	if s.hookWorker != nil {
+		s.hookDrainer = s.schedule("webhook-drain", s.hookInterval, s.hookWorker.Drain)
+	}
+	if s.replicas != nil {
+		s.replicaChecker = s.schedule("replica-check", s.replicaInterval, s.replicas.Check)
+	}
+
+	s.binder = s.newBinder()
+	s.routes()
+
+	return s, nil
+}
+
+// WithRouter ...
+func WithRouter(router *mux.Router) Option {
+	return func(s *Server) error {
+		if router == nil {
+			return errors.New("server: router is nil")
+		}
+		s.router = router
+		return nil
+	}
+}
+
+// WithLogger ...
+func WithLogger(logger zerolog.Logger) Option {
+	return func(s *Server) error {
+		s.logger = logger
+		return nil
+	}
+}
+
+// WithMiddleware adds middleware that runs after the built-in request ID,
+// logging, compression, HSTS, CORS, rate limiting, deadline and panic
+// recovery middleware.
+func WithMiddleware(middleware ...mux.MiddlewareFunc) Option {
+	return func(s *Server) error {
+		s.middleware = append(s.middleware, middleware...)
+		return nil
+	}
+}
+
+// WithQuoteGenerator ...
+func WithQuoteGenerator(g QuoteGenerator) Option {
+	return func(s *Server) error {
+		s.quoteGenerator = g
+		return nil
+	}
+}
+
+// WithQuoteProvider names the active quote provider reported by /providers.
+func WithQuoteProvider(name string) Option {
+	return func(s *Server) error {
+		s.quoteProvider = name
+		return nil
+	}
+}
+
+// WithRecipientsFetcher ...
+func WithRecipientsFetcher(f RecipientFetcher) Option {
+	return func(s *Server) error {
+		s.recipientsFetcher = f
+		return nil
+	}
+}
+
+// WithRecipientStore ...
+func WithRecipientStore(st RecipientStore) Option {
+	return func(s *Server) error {
+		s.recipientStore = st
+		return nil
+	}
+}
+
+// WithGroupStore ...
+func WithGroupStore(g GroupStore) Option {
+	return func(s *Server) error {
+		s.groupStore = g
+		return nil
+	}
+}
+
+// WithTagStore ...
+func WithTagStore(t TagStore) Option {
+	return func(s *Server) error {
+		s.tagStore = t
+		return nil
+	}
+}
+
+// WithFavoriteStore ...
+func WithFavoriteStore(f FavoriteStore) Option {
+	return func(s *Server) error {
+		s.favoriteStore = f
+		return nil
+	}
+}
+
+// WithVerification makes new recipients confirm their email through a signed
+// link pointing at publicURL before they are sent quotes.
+func WithVerification(signer *verify.Signer, publicURL string) Option {
+	return func(s *Server) error {
+		if signer == nil {
+			return errors.New("server: verification signer is nil")
+		}
+		s.signer = signer
+		// Unsubscribe links must keep working long after the email was sent.
+		s.unsubscriber = signer.For("unsubscribe", 0)
+		s.publicURL = strings.TrimSuffix(publicURL, "/")
+		return nil
+	}
+}
+
+// WithTemplates renders emails from set instead of the embedded templates.
+func WithTemplates(set *templates.Set) Option {
+	return func(s *Server) error {
+		if set == nil {
+			return errors.New("server: templates are nil")
+		}
+		s.templates = set
+		return nil
+	}
+}
+
+// WithMailSender ...
+func WithMailSender(m MailSender) Option {
+	return func(s *Server) error {
+		s.mailSender = m
+		return nil
+	}
+}
+
+// WithChannel sends quotes to the recipients who chose the channel name
+// through sender.
+func WithChannel(name string, sender channel.Sender) Option {
+	return func(s *Server) error {
+		if name == recipient.ChannelEmail || !slices.Contains(recipient.AllChannels, name) {
+			return fmt.Errorf("server: unknown chat channel %q", name)
+		}
+		if s.channels == nil {
+			s.channels = make(map[string]channel.Sender)
+		}
+		s.channels[name] = sender
+		return nil
+	}
+}
+
+// WithQuoteStore ...
+func WithQuoteStore(q QuoteStore) Option {
+	return func(s *Server) error {
+		s.quoteStore = q
+		return nil
+	}
+}
+
+// WithLocalQuotes ...
+func WithLocalQuotes(l LocalQuoteStore) Option {
+	return func(s *Server) error {
+		s.localQuotes = l
+		return nil
+	}
+}
+
+// WithPersistence hands the database to the server, which migrates and
+// closes it.
+func WithPersistence(p Database) Option {
+	return func(s *Server) error {
+		s.persistence = p
+		return nil
+	}
+}
+
+// WithReplicas has the server ping the read replicas of set every interval,
+// bringing back those that answer, and close them on shutdown.
+func WithReplicas(set *replica.Set, interval time.Duration) Option {
+	return func(s *Server) error {
+		if interval <= 0 {
+			return fmt.Errorf("server: replica check interval must be positive, got %s", interval)
+		}
+		s.replicas = set
+		s.replicaInterval = interval
+		return nil
+	}
+}
+
+// WithQuoteCache exposes the cache statistics on /metrics.
+func WithQuoteCache(c *quote.Cached) Option {
+	return func(s *Server) error {
+		s.quoteCache = c
+		return nil
+	}
+}
+
+// WithBreakers exposes the circuit breakers, keyed by provider, on /metrics.
+func WithBreakers(breakers map[string]*quote.Breaker) Option {
+	return func(s *Server) error {
+		s.breakers = breakers
+		return nil
+	}
+}
+
+// WithProviderHealth exposes the provider health on /providers/health.
+func WithProviderHealth(health *quote.HealthTracker) Option {
+	return func(s *Server) error {
+		s.providerHealth = health
+		return nil
+	}
+}
+
+// WithReadinessCheck adds a dependency probed by /readyz.
+func WithReadinessCheck(name string, pinger Pinger) Option {
+	return func(s *Server) error {
+		s.readinessChecks = append(s.readinessChecks, &readinessCheck{name: name, pinger: pinger})
+		return nil
+	}
+}
+
+// WithLanguages lists the languages the quote providers serve. When restrict
+// is set, requests for any other language are rejected.
+func WithLanguages(languages []string, restrict bool) Option {
+	return func(s *Server) error {
+		s.languages = languages
+		s.restrictLanguages = restrict
+		return nil
+	}
+}
+
+// WithRateLimiter ...
+func WithRateLimiter(l *ratelimit.Limiter) Option {
+	return func(s *Server) error {
+		s.limiter = l
+		return nil
+	}
+}
+
+// WithDailyLanguages sets the languages of the quote of the day.
+func WithDailyLanguages(languages ...string) Option {
+	return func(s *Server) error {
+		s.dailyLanguages = languages
+		return nil
+	}
+}
+
+// WithStreamLimit caps the number of open /quote/stream connections. Zero
+// means no limit.
+func WithStreamLimit(n int) Option {
+	return func(s *Server) error {
+		if n < 0 {
+			return fmt.Errorf("server: negative stream limit %d", n)
+		}
+		s.streamLimit = n
+		return nil
+	}
+}
+
+// WithAuthorAttempts caps how many quotes /quote?author= draws from the
+// provider before giving up on the author, and likewise for max_length and
+// style.
+func WithAuthorAttempts(n int) Option {
+	return func(s *Server) error {
+		if n < 1 {
+			return fmt.Errorf("server: author attempts must be positive, got %d", n)
+		}
+		s.authorAttempts = n
+		return nil
+	}
+}
+
+// WithOutbox queues quote emails in o instead of sending them right away.
+// Once the server is started, w drains the outbox every interval.
+func WithOutbox(o Outbox, w *outbox.Worker, interval time.Duration) Option {
+	return func(s *Server) error {
+		if interval <= 0 {
+			return fmt.Errorf("server: outbox interval must be positive, got %s", interval)
+		}
+		s.outbox = o
+		s.outboxWorker = w
+		s.outboxInterval = interval
+		return nil
+	}
+}
+
+// WithWorkers sets how many background jobs, such as scheduled deliveries
+// and outbox drains, may run at once.
+func WithWorkers(n int) Option {
+	return func(s *Server) error {
+		if n < 1 {
+			return fmt.Errorf("server: workers must be positive, got %d", n)
+		}
+		s.workerCount = n
+		return nil
+	}
+}
+
+// WithDailyInterval schedules the quote of the day every interval once the
+// server is started.
+func WithDailyInterval(interval time.Duration) Option {
+	return func(s *Server) error {
+		if interval < 0 {
+			return fmt.Errorf("server: negative daily interval %s", interval)
+		}
+		s.dailyInterval = interval
+		return nil
+	}
+}
@@ -0,0 +1,80 @@
+// internal/server/options_test.go
+
+package server
+
+import (
+	"context"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/internal/outbox"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNewServer(t *testing.T) {
+	testCases := []struct {
+		name               string
+		opts               []Option
+		expectedToGetError bool
+	}{
+		{"Valid", []Option{WithQuoteGenerator(&MockQuoteGenerator{}), WithRecipientsFetcher(&MockRecipientsFetcher{})}, false},
+		{"StoreAsFetcher", []Option{WithQuoteGenerator(&MockQuoteGenerator{}), WithRecipientStore(&MockRecipientStore{})}, false},
+		{"MissingGenerator", []Option{WithRecipientsFetcher(&MockRecipientsFetcher{})}, true},
+		{"MissingFetcher", []Option{WithQuoteGenerator(&MockQuoteGenerator{})}, true},
+		{"NilRouter", []Option{WithQuoteGenerator(&MockQuoteGenerator{}), WithRecipientsFetcher(&MockRecipientsFetcher{}), WithRouter(nil)}, true},
+		{"NegativeInterval", []Option{WithQuoteGenerator(&MockQuoteGenerator{}), WithRecipientsFetcher(&MockRecipientsFetcher{}), WithDailyInterval(-time.Second)}, true},
+		{"ZeroOutboxInterval", []Option{WithQuoteGenerator(&MockQuoteGenerator{}), WithRecipientsFetcher(&MockRecipientsFetcher{}), WithOutbox(&MockOutbox{}, &outbox.Worker{}, 0)}, true},
+		{"NoWorkers", []Option{WithQuoteGenerator(&MockQuoteGenerator{}), WithRecipientsFetcher(&MockRecipientsFetcher{}), WithWorkers(0)}, true},
+		{"NoAuthorAttempts", []Option{WithQuoteGenerator(&MockQuoteGenerator{}), WithRecipientsFetcher(&MockRecipientsFetcher{}), WithAuthorAttempts(0)}, true},
+		{
+			"UnsupportedDailyLanguage",
+			[]Option{
+				WithQuoteGenerator(&MockQuoteGenerator{}),
+				WithRecipientsFetcher(&MockRecipientsFetcher{}),
+				WithLanguages([]string{"en", "ru"}, true),
+				WithDailyLanguages("de"),
+			},
+			true,
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			svr, err := NewServer(tC.opts...)
+
+			if tC.expectedToGetError {
+				assert.Error(t, err, "Got no error when expected")
+				return
+			}
+			assert.NoError(t, err, "Got error when not expected")
+			assert.NotNil(t, svr.recipientsFetcher, "Recipients fetcher should be set")
+		})
+	}
+}
+
+func TestNewServer_Scheduler(t *testing.T) {
+	svr := newTestServer(t, WithDailyInterval(time.Hour))
+
+	assert.NotNil(t, svr.scheduler, "Daily interval should schedule the quote of the day")
+}
+
+func TestServer_Schedule(t *testing.T) {
+	svr := newTestServer(t)
+	runs := make(chan struct{}, 10)
+
+	s := svr.schedule("job", time.Millisecond, func(ctx context.Context) error {
+		runs <- struct{}{}
+		return errors.New("sample error")
+	})
+	s.Start()
+	select {
+	case <-runs:
+	case <-time.After(time.Second):
+		t.Fatal("Job was not scheduled")
+	}
+	s.Stop()
+	assert.NoError(t, svr.Close(), "Should have no error when closing the server")
+
+	stats := svr.workers.Stats()["job"]
+	assert.NotZero(t, stats.Runs, "Scheduled job should run on the worker pool")
+	assert.Equal(t, stats.Runs, stats.Failures, "Job failures should be counted")
+}
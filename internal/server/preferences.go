@@ -0,0 +1,105 @@
+// internal/server/preferences.go
+
+package server
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/jeniok12/golang-tdd-example/bind"
+	"github.com/jeniok12/golang-tdd-example/internal/audit"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// weeklyDay is the day weekly recipients get their scheduled quote.
+const weeklyDay = time.Monday
+
+// PreferencesRequest is the body of preference updates. Fields left out keep
+// their value; an empty string restores the default.
+type PreferencesRequest struct {
+	Lang      *string   `json:"lang" doc:"language of scheduled quotes, every daily language when empty"`
+	Frequency *string   `json:"frequency" doc:"daily, weekly or never, daily when empty"`
+	Channels  *[]string `json:"channels" doc:"channels quotes are sent on: email, slack or telegram, email when empty"`
+	// SlackWebhook and TelegramChatID say where the chat channels post.
+	SlackWebhook   *string `json:"slackWebhook" doc:"Slack incoming webhook URL of the slack channel"`
+	TelegramChatID *string `json:"telegramChatId" doc:"chat the bot sends to on the telegram channel"`
+}
+
+// due reports whether the scheduled delivery at now goes to r.
+func due(r recipient.Recipient, now time.Time) bool {
+	switch r.EffectiveFrequency() {
+	case recipient.FrequencyDaily:
+		return true
+	case recipient.FrequencyWeekly:
+		return now.Weekday() == weeklyDay
+	default:
+		return false
+	}
+}
+
+func (s *Server) handleUpdatePreferences() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.writeError(w, r, errInvalidID)
+			return
+		}
+
+		var req PreferencesRequest
+		if !s.bindRequest(w, r, &req) {
+			return
+		}
+
+		current, err := s.recipientStore.GetByID(r.Context(), id)
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+
+		// Only the fields given are checked, so a language dropped from the
+		// providers does not block changing the frequency.
+		prefs := current.Preferences
+		var fieldErrs bind.Errors
+		if req.Lang != nil {
+			prefs.Lang = *req.Lang
+			if msg := s.langMessage(prefs.Lang); msg != "" {
+				fieldErrs = append(fieldErrs, bind.FieldError{Field: "lang", Message: msg})
+			}
+		}
+		if req.Frequency != nil {
+			prefs.Frequency = *req.Frequency
+			if err := prefs.Validate(); err != nil {
+				fieldErrs = append(fieldErrs, bind.FieldError{Field: "frequency", Message: "must be one of " + strings.Join(recipient.Frequencies, ", ")})
+			}
+		}
+		if req.Channels != nil || req.SlackWebhook != nil || req.TelegramChatID != nil {
+			if req.Channels != nil {
+				prefs.Channels = *req.Channels
+			}
+			if req.SlackWebhook != nil {
+				prefs.SlackWebhook = *req.SlackWebhook
+			}
+			if req.TelegramChatID != nil {
+				prefs.TelegramChatID = *req.TelegramChatID
+			}
+			if err := prefs.ValidateChannels(); err != nil {
+				fieldErrs = append(fieldErrs, bind.FieldError{Field: "channels", Message: err.Error()})
+			}
+		}
+		if len(fieldErrs) > 0 {
+			s.writeFieldErrors(w, r, fieldErrs)
+			return
+		}
+
+		updated, err := s.recipientStore.UpdatePreferences(r.Context(), id, prefs)
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+		s.audit(r, audit.ActionUpdate, audit.EntityRecipient, id)
+
+		writeJSON(w, http.StatusOK, updated)
+	}
+}
@@ -0,0 +1,115 @@
+// internal/server/preferences_test.go
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/jeniok12/golang-tdd-example/internal/memstore"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleUpdatePreferences(t *testing.T) {
+	testCases := []struct {
+		name           string
+		id             string
+		body           string
+		expectedStatus int
+		expectedPrefs  recipient.Preferences
+	}{
+		{"Both", "1", `{"lang":"ru","frequency":"weekly"}`, http.StatusOK, recipient.Preferences{Lang: "ru", Frequency: "weekly"}},
+		{"FrequencyOnly", "1", `{"frequency":"never"}`, http.StatusOK, recipient.Preferences{Lang: "en", Frequency: "never"}},
+		{"ResetLang", "1", `{"lang":""}`, http.StatusOK, recipient.Preferences{Frequency: "daily"}},
+		{"UnsupportedLang", "1", `{"lang":"de"}`, http.StatusBadRequest, recipient.Preferences{}},
+		{"UnknownFrequency", "1", `{"frequency":"hourly"}`, http.StatusBadRequest, recipient.Preferences{}},
+		{"Telegram", "1", `{"channels":["email","telegram"],"telegramChatId":"42"}`, http.StatusOK, recipient.Preferences{Lang: "en", Frequency: "daily", Channels: recipient.Channels{"email", "telegram"}, TelegramChatID: "42"}},
+		{"SlackWithoutWebhook", "1", `{"channels":["slack"]}`, http.StatusBadRequest, recipient.Preferences{}},
+		{"UnknownChannel", "1", `{"channels":["sms"]}`, http.StatusBadRequest, recipient.Preferences{}},
+		{"InvalidJSON", "1", `{`, http.StatusBadRequest, recipient.Preferences{}},
+		{"NotFound", "2", `{"frequency":"never"}`, http.StatusNotFound, recipient.Preferences{}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			recipients := memstore.NewRecipients(memstore.NewQuotes())
+			_, err := recipients.Create(context.Background(), recipient.Recipient{Name: "user1", Email: "user1@testmail.com"})
+			require.NoError(t, err, "Should have no error when creating a recipient")
+			_, err = recipients.UpdatePreferences(context.Background(), 1, recipient.Preferences{Lang: "en", Frequency: "daily"})
+			require.NoError(t, err, "Should have no error when setting preferences")
+
+			svr := newTestServer(t, WithRecipientStore(recipients), WithLanguages([]string{"en", "ru"}, true))
+
+			req, _ := http.NewRequest("PATCH", "/recipients/"+tC.id+"/preferences", strings.NewReader(tC.body))
+			rr := makeHTTPCall(svr.router, req)
+
+			assert.Equal(t, tC.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			if tC.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp recipient.Recipient
+			_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+			assert.Equal(t, tC.expectedPrefs, resp.Preferences, "Preferences are different than expected")
+		})
+	}
+}
+
+func TestRunDailyQuotes_Preferences(t *testing.T) {
+	daily := recipient.Recipient{ID: 1, Name: "daily", Email: "daily@testmail.com"}
+	russian := recipient.Recipient{ID: 2, Name: "russian", Email: "russian@testmail.com", Preferences: recipient.Preferences{Lang: "ru"}}
+	weekly := recipient.Recipient{ID: 3, Name: "weekly", Email: "weekly@testmail.com", Preferences: recipient.Preferences{Frequency: recipient.FrequencyWeekly}}
+	never := recipient.Recipient{ID: 4, Name: "never", Email: "never@testmail.com", Preferences: recipient.Preferences{Frequency: recipient.FrequencyNever}}
+
+	testCases := []struct {
+		name     string
+		now      time.Time
+		expected map[string][]recipient.Recipient
+	}{
+		{
+			"Sunday",
+			time.Date(2019, 6, 30, 9, 0, 0, 0, time.UTC),
+			map[string][]recipient.Recipient{"en": {daily}, "ru": {russian}},
+		},
+		{
+			"Monday",
+			time.Date(2019, 7, 1, 9, 0, 0, 0, time.UTC),
+			map[string][]recipient.Recipient{"en": {daily, weekly}, "ru": {russian}},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			mockQuoteGenerator := MockQuoteGenerator{}
+			mockQuoteGenerator.On("Generate", mock.Anything, mock.Anything).Return(&quote.Quote{Text: "Bla"}, nil)
+			mockRecipientsFetcher := MockRecipientsFetcher{}
+			mockRecipientsFetcher.On("AllRecipients", mock.Anything).Return([]recipient.Recipient{daily, russian, weekly, never}, nil)
+			mockMailSender := MockMailSender{}
+			mockMailSender.On("Send", mock.Anything).Return(nil)
+
+			svr := newTestServer(t,
+				WithQuoteGenerator(&mockQuoteGenerator),
+				WithRecipientsFetcher(&mockRecipientsFetcher),
+				WithMailSender(&mockMailSender),
+				WithDailyLanguages("en"),
+			)
+			svr.now = func() time.Time { return tC.now }
+
+			runs := svr.runDailyQuotes(context.Background())
+
+			got := make(map[string][]recipient.Recipient)
+			for _, run := range runs {
+				assert.Empty(t, run.Error, "Got error when not expected")
+				for _, d := range run.Deliveries {
+					got[run.Lang] = append(got[run.Lang], d.Recipient)
+				}
+			}
+			assert.Equal(t, tC.expected, got, "Recipients per language are different than expected")
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// internal/server/preview.go
+
+package server
+
+import (
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"net/http"
+)
+
+// previewRecipient stands in for a recipient when a preview names none.
+var previewRecipient = recipient.Recipient{Name: "Reader", Email: "reader@example.com"}
+
+type previewRequest struct {
+	Lang      string `query:"lang" validate:"lang" doc:"quote language, left to the provider when empty"`
+	Recipient int    `query:"recipient" validate:"min=0" doc:"id of the recipient to address, a placeholder when 0"`
+}
+
+// PreviewResponse is a rendered quote email.
+type PreviewResponse struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html,omitempty"`
+}
+
+func (s *Server) handleSendPreview() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req previewRequest
+		if !s.bindRequest(w, r, &req) {
+			return
+		}
+
+		rcpt := previewRecipient
+		if req.Recipient != 0 {
+			found, err := s.recipientStore.GetByID(r.Context(), req.Recipient)
+			if err != nil {
+				s.writeError(w, r, storeError(err))
+				return
+			}
+			rcpt = *found
+		}
+
+		q, err := s.generateQuote(r.Context(), req.Lang)
+		if err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+
+		msg, err := s.quoteMessage(q, rcpt)
+		if err != nil {
+			s.writeError(w, r, apierror.Internal(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, PreviewResponse{
+			To:      msg.To,
+			Subject: msg.Subject,
+			Text:    msg.Body,
+			HTML:    msg.HTML,
+		})
+	}
+}
@@ -0,0 +1,63 @@
+// internal/server/preview_test.go
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/jeniok12/golang-tdd-example/internal/memstore"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+)
+
+func TestHandleSendPreview(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		query          string
+		expectedStatus int
+		expectedTo     string
+		expectedName   string
+	}{
+		{"Placeholder", "", http.StatusOK, "reader@example.com", "Reader"},
+		{"Recipient", "?recipient=1", http.StatusOK, "user1@testmail.com", "user1"},
+		{"UnknownRecipient", "?recipient=2", http.StatusNotFound, "", ""},
+		{"InvalidRecipient", "?recipient=-1", http.StatusBadRequest, "", ""},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			recipients := memstore.NewRecipients(memstore.NewQuotes())
+			_, err := recipients.Create(context.Background(), recipient.Recipient{Name: "user1", Email: "user1@testmail.com"})
+			require.NoError(t, err, "Should have no error when creating a recipient")
+
+			mockQuoteGenerator := MockQuoteGenerator{}
+			mockQuoteGenerator.On("Generate", mock.Anything, "").Return(&quote.Quote{Text: "Bla", Author: "Bob"}, nil)
+			mockMailSender := MockMailSender{}
+
+			svr := newTestServer(t,
+				WithQuoteGenerator(&mockQuoteGenerator),
+				WithRecipientStore(recipients),
+				WithMailSender(&mockMailSender),
+			)
+
+			req, _ := http.NewRequest("POST", "/quote/send/preview"+tC.query, nil)
+			rr := makeHTTPCall(svr.router, req)
+			assert.Equal(t, tC.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			mockMailSender.AssertNotCalled(t, "Send", mock.Anything)
+			if tC.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp PreviewResponse
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp), "Got error when not expected")
+			assert.Equal(t, tC.expectedTo, resp.To)
+			assert.Equal(t, "Your inspiring quote", resp.Subject)
+			assert.Equal(t, "Hi "+tC.expectedName+",\n\nBla\n- Bob\n", resp.Text)
+			assert.Contains(t, resp.HTML, "<p>Bla</p>")
+		})
+	}
+}
@@ -0,0 +1,77 @@
+// internal/server/quote_v2.go
+
+package server
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/jeniok12/golang-tdd-example/requestid"
+	"net/http"
+)
+
+// QuoteV2 is a quote in the v2 schema.
+type QuoteV2 struct {
+	Text   string `json:"text" xml:"text"`
+	Author string `json:"author" xml:"author"`
+	Lang   string `json:"lang" xml:"lang"`
+	// OriginalLang is the language a translated quote was translated from.
+	OriginalLang string `json:"originalLang,omitempty" xml:"originalLang,omitempty"`
+}
+
+// QuoteMeta says how a v2 quote was served.
+type QuoteMeta struct {
+	Provider string `json:"provider" xml:"provider"`
+	// LatencyMs is how long getting the quote took, in milliseconds.
+	LatencyMs int64  `json:"latencyMs" xml:"latencyMs"`
+	RequestID string `json:"requestId" xml:"requestId"`
+	// Fallback is set when the quote is in a fallback language rather than
+	// the requested one.
+	Fallback bool `json:"fallback" xml:"fallback"`
+}
+
+// QuoteResponseV2 is the /v2/quote response.
+type QuoteResponseV2 struct {
+	XMLName xml.Name `json:"-" xml:"quoteResponse"`
+	Quote   QuoteV2  `json:"quote" xml:"quote"`
+	// Recipients are only listed with ?include=recipients.
+	Recipients []recipient.Recipient `json:"recipients,omitempty" xml:"recipients>recipient,omitempty"`
+	Meta       QuoteMeta             `json:"meta" xml:"meta"`
+	// Warnings are set when parts of the response could not be served.
+	Warnings []string `json:"warnings,omitempty" xml:"warning,omitempty"`
+}
+
+// String renders the response as plain text, like v1.
+func (r QuoteResponseV2) String() string {
+	q := &quote.Quote{Text: r.Quote.Text, Author: r.Quote.Author}
+	return HandleQuoteResponse{Quote: q, Recipients: r.Recipients}.String()
+}
+
+// quoteV2 moves what v1 mixed into the quote, like the provider, to a
+// metadata block and names every field in camelCase. Recipients are left
+// out unless asked for, sparing most requests a database query.
+var quoteV2 = quoteVersion{
+	render: func(r *http.Request, res quoteResult) fmt.Stringer {
+		return QuoteResponseV2{
+			Quote: QuoteV2{
+				Text:         res.Quote.Text,
+				Author:       res.Quote.Author,
+				Lang:         res.Quote.Lang,
+				OriginalLang: res.Quote.OriginalLang,
+			},
+			Recipients: res.Recipients,
+			Meta: QuoteMeta{
+				Provider:  res.Provider,
+				LatencyMs: res.Latency.Milliseconds(),
+				RequestID: requestid.FromContext(r.Context()),
+				Fallback:  res.Fallback,
+			},
+			Warnings: res.Warnings,
+		}
+	},
+}
+
+func (s *Server) handleQuotesV2() http.HandlerFunc {
+	return s.quoteHandler(quoteV2)
+}
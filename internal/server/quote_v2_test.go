@@ -0,0 +1,189 @@
+// internal/server/quote_v2_test.go
+
+package server
+
+import (
+	"context"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/jeniok12/golang-tdd-example/requestid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHandleQuotes_Versions(t *testing.T) {
+	q := &quote.Quote{Text: "Bla Bla Bla", Author: "Bob", Lang: "en"}
+	recipients := []recipient.Recipient{{ID: 1, Name: "user1", Email: "user1@testmail.com"}}
+
+	testCases := []struct {
+		name                string
+		path                string
+		accept              string
+		expectedContentType string
+		expectedBody        string
+		expectedETag        bool
+		expectedRecipients  bool
+	}{
+		{
+			"Unversioned",
+			"/quote?lang=en",
+			"",
+			"application/json",
+			`{"quote":{"quoteText":"Bla Bla Bla","quoteAuthor":"Bob","lang":"en"},"recipients":[{"id":1,"name":"user1","email":"user1@testmail.com","verified":false}]}`,
+			true,
+			true,
+		},
+		{
+			"V1",
+			"/v1/quote?lang=en",
+			"",
+			"application/json",
+			`{"quote":{"quoteText":"Bla Bla Bla","quoteAuthor":"Bob","lang":"en"},"recipients":[{"id":1,"name":"user1","email":"user1@testmail.com","verified":false}]}`,
+			true,
+			true,
+		},
+		{
+			"V2",
+			"/v2/quote?lang=en",
+			"",
+			"application/json",
+			`{"quote":{"text":"Bla Bla Bla","author":"Bob","lang":"en"},"meta":{"provider":"forismatic","latencyMs":1500,"requestId":"req-1","fallback":false}}`,
+			false,
+			false,
+		},
+		{
+			"V2WithRecipients",
+			"/v2/quote?lang=en&include=recipients",
+			"",
+			"application/json",
+			`{"quote":{"text":"Bla Bla Bla","author":"Bob","lang":"en"},"recipients":[{"id":1,"name":"user1","email":"user1@testmail.com","verified":false}],` +
+				`"meta":{"provider":"forismatic","latencyMs":1500,"requestId":"req-1","fallback":false}}`,
+			false,
+			true,
+		},
+		{
+			"V2XML",
+			"/v2/quote?lang=en&include=recipients",
+			"application/xml",
+			"application/xml; charset=utf-8",
+			`<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+				`<quoteResponse><quote><text>Bla Bla Bla</text><author>Bob</author><lang>en</lang></quote>` +
+				`<recipients><recipient><id>1</id><name>user1</name><email>user1@testmail.com</email><verified>false</verified></recipient></recipients>` +
+				`<meta><provider>forismatic</provider><latencyMs>1500</latencyMs><requestId>req-1</requestId><fallback>false</fallback></meta></quoteResponse>`,
+			false,
+			true,
+		},
+		{
+			"V2Text",
+			"/v2/quote?lang=en&format=text&include=recipients",
+			"",
+			"text/plain; charset=utf-8",
+			"Bla Bla Bla\n- Bob\n\nRecipients:\nuser1 <user1@testmail.com>\n",
+			false,
+			true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockQuoteGenerator := MockQuoteGenerator{}
+			mockQuoteGenerator.On("Generate", mock.Anything, "en").Return(q, nil)
+			mockRecipientsFetcher := MockRecipientsFetcher{}
+			mockRecipientsFetcher.On("ListRecipients", mock.Anything, defaultListOptions).Return(recipients, nil).Maybe()
+
+			svr := newTestServer(t,
+				WithQuoteGenerator(&mockQuoteGenerator),
+				WithRecipientsFetcher(&mockRecipientsFetcher),
+				WithQuoteProvider("forismatic"),
+			)
+			// Every reading of the clock is 1.5s after the previous one.
+			now := time.Date(2019, 9, 1, 10, 0, 0, 0, time.UTC)
+			svr.now = func() time.Time {
+				now = now.Add(1500 * time.Millisecond)
+				return now
+			}
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Header.Set(requestid.Header, "req-1")
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			rr := makeHTTPCall(svr.router, req)
+
+			assert.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+			assert.Equal(t, tc.expectedContentType, rr.Header().Get("Content-Type"), "Content-Type is different than expected")
+			assert.Equal(t, tc.expectedBody, rr.Body.String(), "Response body is different than expected")
+			assert.Equal(t, tc.expectedETag, rr.Header().Get("ETag") != "", "Only v1 responses should be revalidated")
+			if !tc.expectedRecipients {
+				mockRecipientsFetcher.AssertNotCalled(t, "ListRecipients", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestHandleQuotes_UnknownInclude(t *testing.T) {
+	svr := newTestServer(t)
+
+	rr := makeHTTPCall(svr.router, httptest.NewRequest(http.MethodGet, "/v2/quote?include=favorites", nil))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Response HTTP status in different than expected")
+	assert.Contains(t, rr.Body.String(), `unknown part \"favorites\"`, "The unknown part should be reported")
+}
+
+func TestHandleQuotes_Degraded(t *testing.T) {
+	testCases := []struct {
+		name         string
+		path         string
+		expectedBody string
+	}{
+		{
+			"V1",
+			"/v1/quote?lang=en",
+			`{"quote":{"quoteText":"Bla","quoteAuthor":"Bob","lang":"en"},"recipients":null,"warnings":["recipients are temporarily unavailable"]}`,
+		},
+		{
+			"V2",
+			"/v2/quote?lang=en&include=recipients",
+			`{"quote":{"text":"Bla","author":"Bob","lang":"en"},"meta":{"provider":"","latencyMs":0,"requestId":"req-1","fallback":false},"warnings":["recipients are temporarily unavailable"]}`,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockQuoteGenerator := MockQuoteGenerator{}
+			mockQuoteGenerator.On("Generate", mock.Anything, "en").Return(&quote.Quote{Text: "Bla", Author: "Bob", Lang: "en"}, nil)
+			mockRecipientsFetcher := MockRecipientsFetcher{}
+			mockRecipientsFetcher.On("ListRecipients", mock.Anything, defaultListOptions).Return(nil, errors.New("sample error"))
+
+			svr := newTestServer(t, WithQuoteGenerator(&mockQuoteGenerator), WithRecipientsFetcher(&mockRecipientsFetcher))
+			now := time.Date(2019, 9, 1, 10, 0, 0, 0, time.UTC)
+			svr.now = func() time.Time { return now }
+
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			req.Header.Set(requestid.Header, "req-1")
+			rr := makeHTTPCall(svr.router, req)
+
+			assert.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+			assert.Equal(t, tc.expectedBody, rr.Body.String(), "Response body is different than expected")
+			assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"), "Degraded responses should not be cached")
+		})
+	}
+}
+
+func TestHandleQuotes_QuoteFailureCancelsRecipients(t *testing.T) {
+	mockQuoteGenerator := MockQuoteGenerator{}
+	mockQuoteGenerator.On("Generate", mock.Anything, "en").Return(nil, errors.New("sample error"))
+	mockRecipientsFetcher := MockRecipientsFetcher{}
+	mockRecipientsFetcher.On("ListRecipients", mock.Anything, defaultListOptions).Run(func(args mock.Arguments) {
+		<-args.Get(0).(context.Context).Done()
+	}).Return(nil, context.Canceled)
+
+	svr := newTestServer(t, WithQuoteGenerator(&mockQuoteGenerator), WithRecipientsFetcher(&mockRecipientsFetcher))
+
+	rr := makeHTTPCall(svr.router, httptest.NewRequest(http.MethodGet, "/quote?lang=en", nil))
+
+	assert.Equal(t, http.StatusBadGateway, rr.Code, "The quote failure should be reported")
+}
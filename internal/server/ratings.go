@@ -0,0 +1,111 @@
+// internal/server/ratings.go
+
+package server
+
+import (
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/internal/rating"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RateQuoteRequest is the body of rating requests.
+type RateQuoteRequest struct {
+	Score int `json:"score" validate:"required,min=1,max=5" doc:"from 1, the worst, to 5, the best"`
+}
+
+type trendingRequest struct {
+	Window string `query:"window" doc:"how far back ratings count, such as 24h; the configured trending window when empty"`
+	Limit  int    `query:"limit" default:"10" validate:"min=1,max=100" doc:"maximum number of quotes"`
+}
+
+// TrendingResponse lists the best rated quotes of a window.
+type TrendingResponse struct {
+	Quotes []rating.Trend `json:"quotes"`
+	Since  time.Time      `json:"since"`
+}
+
+// WithRatings lets clients rate quotes and ranks the quotes rated within
+// window on /quotes/trending.
+func WithRatings(store rating.Store, window time.Duration) Option {
+	return func(s *Server) error {
+		if window <= 0 {
+			return fmt.Errorf("server: trending window must be positive, got %s", window)
+		}
+		s.ratings = store
+		s.trendingWindow = window
+		return nil
+	}
+}
+
+var errRatingsDisabled = apierror.NotFound("ratings are disabled")
+
+func (s *Server) handleRateQuote() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.ratings == nil {
+			s.writeError(w, r, errRatingsDisabled)
+			return
+		}
+
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.writeError(w, r, errInvalidID)
+			return
+		}
+
+		// Keyed by API key, so a client rates each quote once.
+		if r.Header.Get(APIKeyHeader) == "" {
+			s.writeError(w, r, apierror.Unauthorized("rating quotes requires an "+APIKeyHeader))
+			return
+		}
+
+		var req RateQuoteRequest
+		if !s.bindRequest(w, r, &req) {
+			return
+		}
+
+		rated := rating.Rating{QuoteID: id, Rater: actor(r), Score: req.Score, RatedAt: time.Now()}
+		if err := s.ratings.Rate(r.Context(), rated); err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, rated)
+	}
+}
+
+func (s *Server) handleTrendingQuotes() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.ratings == nil {
+			s.writeError(w, r, errRatingsDisabled)
+			return
+		}
+
+		var req trendingRequest
+		if !s.bindRequest(w, r, &req) {
+			return
+		}
+
+		window := s.trendingWindow
+		if req.Window != "" {
+			d, err := time.ParseDuration(req.Window)
+			if err != nil || d <= 0 {
+				s.writeError(w, r, apierror.BadRequest(apierror.CodeBadRequest, "window must be a positive duration such as 24h"))
+				return
+			}
+			window = d
+		}
+
+		since := time.Now().Add(-window).UTC()
+		trends, err := s.ratings.Trending(r.Context(), since, req.Limit)
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, TrendingResponse{Quotes: trends, Since: since})
+	}
+}
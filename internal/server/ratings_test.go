@@ -0,0 +1,112 @@
+// internal/server/ratings_test.go
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/jeniok12/golang-tdd-example/internal/memstore"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/rating"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleRateQuote(t *testing.T) {
+	quotes := memstore.NewQuotes()
+	quotes.SaveQuote(context.Background(), quote.Quote{Text: "Bla"})
+	svr := newTestServer(t, WithRatings(memstore.NewRatings(quotes), time.Hour))
+
+	testCases := []struct {
+		desc           string
+		path           string
+		apiKey         string
+		body           string
+		expectedStatus int
+	}{
+		{"Rate", "/quotes/1/rating", "secret", `{"score":4}`, http.StatusOK},
+		{"RateAgain", "/quotes/1/rating", "secret", `{"score":5}`, http.StatusOK},
+		{"WithoutAPIKey", "/quotes/1/rating", "", `{"score":4}`, http.StatusUnauthorized},
+		{"ScoreTooLow", "/quotes/1/rating", "secret", `{"score":0}`, http.StatusBadRequest},
+		{"ScoreTooHigh", "/quotes/1/rating", "secret", `{"score":6}`, http.StatusBadRequest},
+		{"UnknownQuote", "/quotes/42/rating", "secret", `{"score":4}`, http.StatusNotFound},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tC.path, strings.NewReader(tC.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tC.apiKey != "" {
+				req.Header.Set(APIKeyHeader, tC.apiKey)
+			}
+			rr := makeHTTPCall(svr.router, req)
+
+			assert.Equal(t, tC.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+		})
+	}
+}
+
+func TestHandleTrendingQuotes(t *testing.T) {
+	ctx := context.Background()
+	quotes := memstore.NewQuotes()
+	for _, text := range []string{"Bla", "Foo"} {
+		quotes.SaveQuote(ctx, quote.Quote{Text: text})
+	}
+	ratings := memstore.NewRatings(quotes)
+	now := time.Now()
+	ratings.Rate(ctx, rating.Rating{QuoteID: 1, Rater: "key:a", Score: 5, RatedAt: now.Add(-48 * time.Hour)})
+	ratings.Rate(ctx, rating.Rating{QuoteID: 2, Rater: "key:a", Score: 3, RatedAt: now})
+	ratings.Rate(ctx, rating.Rating{QuoteID: 2, Rater: "key:b", Score: 4, RatedAt: now})
+	svr := newTestServer(t, WithRatings(ratings, time.Hour))
+
+	testCases := []struct {
+		desc           string
+		query          string
+		expectedStatus int
+		expectedTexts  []string
+	}{
+		{"DefaultWindow", "", http.StatusOK, []string{"Foo"}},
+		{"Window", "?window=72h", http.StatusOK, []string{"Bla", "Foo"}},
+		{"Limit", "?window=72h&limit=1", http.StatusOK, []string{"Bla"}},
+		{"InvalidWindow", "?window=week", http.StatusBadRequest, nil},
+		{"NegativeWindow", "?window=-1h", http.StatusBadRequest, nil},
+		{"InvalidLimit", "?limit=0", http.StatusBadRequest, nil},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			rr := makeHTTPCall(svr.router, httptest.NewRequest(http.MethodGet, "/quotes/trending"+tC.query, nil))
+
+			assert.Equal(t, tC.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			if tC.expectedTexts == nil {
+				return
+			}
+			var resp TrendingResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp), "Got error when not expected")
+			texts := []string{}
+			for _, tr := range resp.Quotes {
+				texts = append(texts, tr.Text)
+			}
+			assert.Equal(t, tC.expectedTexts, texts, "Trending quotes are different than expected")
+		})
+	}
+
+	rr := makeHTTPCall(svr.router, httptest.NewRequest(http.MethodGet, "/quotes/trending", nil))
+	var resp TrendingResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp), "Got error when not expected")
+	require.Len(t, resp.Quotes, 1)
+	assert.Equal(t, 3.5, resp.Quotes[0].Average)
+	assert.Equal(t, 2, resp.Quotes[0].Votes)
+}
+
+func TestRatings_Disabled(t *testing.T) {
+	svr := newTestServer(t)
+
+	rr := makeHTTPCall(svr.router, httptest.NewRequest(http.MethodGet, "/quotes/trending", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code, "Response HTTP status in different than expected")
+
+	assert.Error(t, WithRatings(memstore.NewRatings(memstore.NewQuotes()), 0)(&Server{}), "Got no error when expected")
+}
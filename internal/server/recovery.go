@@ -0,0 +1,88 @@
+// internal/server/recovery.go
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/errtrack"
+	"github.com/jeniok12/golang-tdd-example/requestid"
+	"net/http"
+	"runtime/debug"
+)
+
+// WithErrorReporter reports recovered panics, server errors and failed
+// background jobs to rep, on top of logging them.
+func WithErrorReporter(rep errtrack.Reporter) Option {
+	return func(s *Server) error {
+		if rep == nil {
+			return errors.New("server: error reporter is nil")
+		}
+		s.reporter = rep
+		return nil
+	}
+}
+
+// report sends e to the error reporter, if any, tagged with the request ID.
+func (s *Server) report(ctx context.Context, e errtrack.Event) {
+	if s.reporter == nil {
+		return
+	}
+	if id := requestid.FromContext(ctx); id != "" {
+		tags := map[string]string{"requestId": id}
+		for k, v := range e.Tags {
+			tags[k] = v
+		}
+		e.Tags = tags
+	}
+	s.reporter.Report(ctx, e)
+}
+
+// panicError turns what a handler panicked with into an error.
+func panicError(v interface{}) error {
+	if err, ok := v.(error); ok {
+		return fmt.Errorf("panic: %w", err)
+	}
+	return fmt.Errorf("panic: %v", v)
+}
+
+// withRecovery turns a panicking handler into a 500 error carrying the
+// request ID, so one bad request does not cost the client its connection
+// without an answer. When the handler had already started the response, the
+// connection is aborted as net/http would, but the panic is still logged.
+func (s *Server) withRecovery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := &statusRecorder{ResponseWriter: w}
+
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if v == http.ErrAbortHandler {
+				panic(v)
+			}
+
+			err := panicError(v)
+			stack := debug.Stack()
+			s.logger.Error().
+				Err(err).
+				Str("requestId", requestid.FromContext(r.Context())).
+				Func(logTrace(r.Context())).
+				Str("method", r.Method).
+				Str("path", r.URL.Path).
+				Bytes("stack", stack).
+				Msg("handler panicked")
+			s.report(r.Context(), errtrack.Event{Err: err, Stack: stack, Request: r})
+
+			if rec.status != 0 {
+				panic(http.ErrAbortHandler)
+			}
+			apierror.Write(w, r, apierror.Internal(err))
+		}()
+
+		next.ServeHTTP(rec, r)
+	})
+}
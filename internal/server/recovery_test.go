@@ -0,0 +1,115 @@
+// internal/server/recovery_test.go
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/errtrack"
+	"github.com/jeniok12/golang-tdd-example/requestid"
+	"github.com/jeniok12/golang-tdd-example/worker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// panicking panics instead of serving /admin/webhooks, after writing the
+// response header when wrote is set.
+func panicking(wrote bool) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/admin/webhooks" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if wrote {
+				w.WriteHeader(http.StatusOK)
+			}
+			panic("boom")
+		})
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	var reported errtrack.Event
+	reporter := errtrack.ReporterFunc(func(_ context.Context, e errtrack.Event) {
+		reported = e
+	})
+	svr := newTestServer(t, WithMiddleware(panicking(false)), WithErrorReporter(reporter))
+
+	req := asAdmin(httptest.NewRequest(http.MethodGet, "/admin/webhooks", nil))
+	req.Header.Set(requestid.Header, "req-1")
+	rr := makeHTTPCall(svr.router, req)
+
+	require.Equal(t, http.StatusInternalServerError, rr.Code, "Response HTTP status in different than expected")
+	var resp apierror.Error
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp), "Response should be an error")
+	assert.Equal(t, apierror.CodeInternal, resp.Code, "Error code is different than expected")
+	assert.Equal(t, "req-1", resp.RequestID, "The error should carry the request ID")
+
+	require.Error(t, reported.Err, "The panic should be reported")
+	assert.Equal(t, "panic: boom", reported.Err.Error(), "Reported error is different than expected")
+	assert.Contains(t, string(reported.Stack), "panicking", "The stack should lead to the panic")
+	assert.Equal(t, "/admin/webhooks", reported.Request.URL.Path, "The request should be reported")
+	assert.Equal(t, map[string]string{"requestId": "req-1"}, reported.Tags, "The report should be tagged with the request ID")
+
+	rr = makeHTTPCall(svr.router, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rr.Code, "The server should keep serving")
+}
+
+func TestRecovery_ResponseStarted(t *testing.T) {
+	reported := false
+	svr := newTestServer(t, WithMiddleware(panicking(true)), WithErrorReporter(errtrack.ReporterFunc(func(context.Context, errtrack.Event) {
+		reported = true
+	})))
+
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		makeHTTPCall(svr.router, asAdmin(httptest.NewRequest(http.MethodGet, "/admin/webhooks", nil)))
+	}, "A started response cannot be replaced, so the connection should be aborted")
+	assert.True(t, reported, "The panic should still be reported")
+}
+
+func TestErrorReporter_ServerErrors(t *testing.T) {
+	mockQuoteStore := MockQuoteStore{}
+	mockQuoteStore.On("ListQuotes", mock.Anything, mock.Anything).Return(nil, errors.New("sample error"))
+	var reported []errtrack.Event
+	svr := newTestServer(t, WithQuoteStore(&mockQuoteStore), WithErrorReporter(errtrack.ReporterFunc(func(_ context.Context, e errtrack.Event) {
+		reported = append(reported, e)
+	})))
+
+	makeHTTPCall(svr.router, asAdmin(httptest.NewRequest(http.MethodGet, "/admin/webhooks", nil)))
+	assert.Empty(t, reported, "Client errors should not be reported")
+
+	makeHTTPCall(svr.router, httptest.NewRequest(http.MethodGet, "/quotes/feed.rss", nil))
+	require.Len(t, reported, 1, "Server errors should be reported")
+	assert.EqualError(t, reported[0].Err, "sample error", "Reported error is different than expected")
+	assert.Contains(t, reported[0].Tags, "requestId", "The report should be tagged with the request ID")
+}
+
+func TestErrorReporter_BackgroundJobs(t *testing.T) {
+	reported := make(chan errtrack.Event, 1)
+	svr := newTestServer(t, WithErrorReporter(errtrack.ReporterFunc(func(_ context.Context, e errtrack.Event) {
+		reported <- e
+	})))
+
+	err := svr.workers.Run(context.Background(), worker.Func("sample-job", func(context.Context) error {
+		panic("boom")
+	}))
+
+	require.Error(t, err, "The job should fail")
+	e := <-reported
+	assert.Equal(t, map[string]string{"job": "sample-job"}, e.Tags, "The report should name the job")
+	assert.NotEmpty(t, e.Stack, "The stack of the panic should be reported")
+}
+
+func TestWithErrorReporter_Nil(t *testing.T) {
+	_, err := NewServer(WithErrorReporter(nil))
+
+	assert.Error(t, err, "A nil reporter should be rejected")
+}
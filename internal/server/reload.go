@@ -0,0 +1,149 @@
+// internal/server/reload.go
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/internal/config"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/rs/zerolog"
+	"net/http"
+	"slices"
+)
+
+// WithConfigReload lets Reload and POST /admin/config/reload reread the
+// config with load and apply what can change while serving: the log level,
+// the rate limit, the cache TTL, the order of the providers and the feature
+// flags. The server must have been given its config with WithConfig.
+func WithConfigReload(load func() (*config.Config, error)) Option {
+	return func(s *Server) error {
+		s.loadConfig = load
+		return nil
+	}
+}
+
+// WithProviders lets Reload reorder the providers of m, switching the
+// active one.
+func WithProviders(m *quote.Multi) Option {
+	return func(s *Server) error {
+		s.providers = m
+		return nil
+	}
+}
+
+// ReloadResponse lists the settings a reload changed, by flag name.
+type ReloadResponse struct {
+	Reloaded []string `json:"reloaded"`
+	// RestartRequired changed in the config but only take effect on restart.
+	RestartRequired []string `json:"restartRequired"`
+}
+
+// Reload rereads the config and applies the settings that changed and can
+// change while serving. Requests in flight are not disturbed. Nothing is
+// applied if the new config is invalid.
+func (s *Server) Reload() (*ReloadResponse, error) {
+	if s.loadConfig == nil {
+		return nil, errors.New("server: config reloading is not enabled")
+	}
+	cfg, err := s.loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	level, err := zerolog.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		return nil, fmt.Errorf("server: %v", err)
+	}
+
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+
+	if s.config == nil {
+		return nil, errors.New("server: reloading the config needs WithConfig")
+	}
+	changed := s.config.Changed(cfg)
+	// Configured first: it is the only change that can still fail.
+	if slices.Contains(changed, "features") {
+		if err := s.features.Configure(cfg.Features); err != nil {
+			return nil, fmt.Errorf("server: %v", err)
+		}
+	}
+
+	// running is the config in effect, which settings taking a restart keep.
+	running := *s.config
+	resp := &ReloadResponse{Reloaded: []string{}, RestartRequired: []string{}}
+	for _, name := range changed {
+		applied := false
+		switch name {
+		case "features":
+			running.Features, applied = cfg.Features, true
+		case "log-level":
+			zerolog.SetGlobalLevel(level)
+			running.LogLevel, applied = cfg.LogLevel, true
+		case "rate-limit", "rate-limit-burst":
+			if s.limiter != nil {
+				s.limiter.Set(cfg.RateLimit, cfg.RateLimitBurst)
+				running.RateLimit, running.RateLimitBurst, applied = cfg.RateLimit, cfg.RateLimitBurst, true
+			}
+		case "cache-ttl":
+			// Turning the cache on or off changes the generator chain.
+			if s.quoteCache != nil && cfg.CacheTTL.Duration > 0 {
+				s.quoteCache.SetTTL(cfg.CacheTTL.Duration)
+				running.CacheTTL, applied = cfg.CacheTTL, true
+			}
+		case "provider", "fallbacks":
+			// Only the configured providers were built, so they can only be
+			// reordered.
+			if s.providers != nil && s.providers.Select(append([]string{cfg.Provider}, cfg.Fallbacks...)) == nil {
+				s.quoteProvider = cfg.Provider
+				running.Provider, running.Fallbacks, applied = cfg.Provider, cfg.Fallbacks, true
+			}
+		}
+
+		if applied {
+			resp.Reloaded = append(resp.Reloaded, name)
+		} else {
+			resp.RestartRequired = append(resp.RestartRequired, name)
+		}
+	}
+	s.config = &running
+
+	s.logger.Info().Strs("reloaded", resp.Reloaded).Msg("config reloaded")
+	if len(resp.RestartRequired) > 0 {
+		s.logger.Warn().Strs("settings", resp.RestartRequired).Msg("changed settings take a restart")
+	}
+	return resp, nil
+}
+
+// currentConfig returns the config in effect.
+func (s *Server) currentConfig() *config.Config {
+	s.reloadMu.RLock()
+	defer s.reloadMu.RUnlock()
+
+	return s.config
+}
+
+// activeProvider returns the name of the provider asked first.
+func (s *Server) activeProvider() string {
+	s.reloadMu.RLock()
+	defer s.reloadMu.RUnlock()
+
+	return s.quoteProvider
+}
+
+func (s *Server) handleReloadConfig() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.loadConfig == nil {
+			s.writeError(w, r, apierror.NotFound("config reloading is not enabled"))
+			return
+		}
+
+		resp, err := s.Reload()
+		if err != nil {
+			s.writeError(w, r, apierror.Wrap(err, http.StatusInternalServerError, apierror.CodeInternal, "the config cannot be reloaded: "+err.Error()))
+			return
+		}
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
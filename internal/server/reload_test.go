@@ -0,0 +1,90 @@
+// internal/server/reload_test.go
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/internal/config"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/ratelimit"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReload(t *testing.T) {
+	t.Cleanup(func() { zerolog.SetGlobalLevel(zerolog.TraceLevel) })
+
+	cfg := config.Default()
+	cfg.Provider, cfg.Fallbacks = "forismatic", []string{"zenquotes"}
+	cfg.CacheTTL = config.Duration{Duration: time.Minute}
+	next := *cfg
+	next.LogLevel = "warn"
+	next.RateLimit = 5
+	next.CacheTTL = config.Duration{Duration: time.Hour}
+	next.Provider, next.Fallbacks = "zenquotes", []string{"forismatic"}
+	next.Features = []string{"translation=off"}
+	next.Port = 9090
+	var loadErr error
+
+	limiter := &ratelimit.Limiter{Store: ratelimit.NewMemory()}
+	cache := &quote.Cached{Inner: &MockQuoteGenerator{}, TTL: time.Minute, Cache: quote.NewLRU(10)}
+	multi := &quote.Multi{Providers: []quote.Named{{Name: "forismatic", Generator: &MockQuoteGenerator{}}, {Name: "zenquotes", Generator: &MockQuoteGenerator{}}}}
+	svr := newTestServer(t,
+		WithConfig(cfg),
+		WithConfigReload(func() (*config.Config, error) { return &next, loadErr }),
+		WithRateLimiter(limiter),
+		WithQuoteCache(cache),
+		WithProviders(multi),
+		WithQuoteProvider(cfg.Provider),
+	)
+
+	loadErr = errors.New("config: broken.yaml: bad indentation")
+	req := asAdmin(httptest.NewRequest("POST", "/admin/config/reload", nil))
+	rr := makeHTTPCall(svr.router, req)
+	assert.Equal(t, http.StatusInternalServerError, rr.Code, "Response HTTP status in different than expected")
+	assert.Contains(t, rr.Body.String(), "bad indentation", "The reason should be reported")
+
+	loadErr = nil
+	req = asAdmin(httptest.NewRequest("POST", "/admin/config/reload", nil))
+	rr = makeHTTPCall(svr.router, req)
+	require.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+	var resp ReloadResponse
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp), "Got error when not expected")
+	assert.Equal(t, ReloadResponse{
+		Reloaded:        []string{"log-level", "provider", "fallbacks", "cache-ttl", "rate-limit", "features"},
+		RestartRequired: []string{"port"},
+	}, resp)
+
+	assert.Equal(t, zerolog.WarnLevel, zerolog.GlobalLevel(), "Log level should be reloaded")
+	assert.Equal(t, 5.0, limiter.Rate, "Rate limit should be reloaded")
+	assert.Equal(t, time.Hour, cache.CurrentTTL(), "Cache TTL should be reloaded")
+	assert.Equal(t, "zenquotes", svr.activeProvider(), "Provider should be reloaded")
+	assert.False(t, svr.enabled(featureTranslation), "Features should be reloaded")
+	assert.Equal(t, 8080, svr.currentConfig().Port, "Settings taking a restart should keep their value")
+
+	next.Provider = "quotable"
+	resp2, err := svr.Reload()
+	require.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, []string{"port", "provider"}, resp2.RestartRequired, "Providers that were not built should take a restart")
+	assert.Equal(t, "zenquotes", svr.activeProvider())
+
+	next.Features = []string{"no-such-feature"}
+	_, err = svr.Reload()
+	assert.Error(t, err, "Got no error when expected")
+	assert.False(t, svr.enabled(featureTranslation), "Invalid configs should change nothing")
+}
+
+func TestReload_NotEnabled(t *testing.T) {
+	svr := newTestServer(t, WithConfig(config.Default()))
+
+	req := asAdmin(httptest.NewRequest("POST", "/admin/config/reload", nil))
+	rr := makeHTTPCall(svr.router, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code, "Response HTTP status in different than expected")
+}
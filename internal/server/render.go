@@ -0,0 +1,111 @@
+// internal/server/render.go
+
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Response formats a handler can be asked for, by ?format= or the Accept header.
+const (
+	formatJSON = "json"
+	formatXML  = "xml"
+	formatText = "text"
+)
+
+var formatMediaTypes = map[string]string{
+	"application/json": formatJSON,
+	"application/xml":  formatXML,
+	"text/xml":         formatXML,
+	"text/plain":       formatText,
+}
+
+var formatContentTypes = map[string]string{
+	formatJSON: "application/json",
+	formatXML:  "application/xml; charset=utf-8",
+	formatText: "text/plain; charset=utf-8",
+}
+
+// negotiateFormat picks the response format for r. An explicit ?format= wins
+// and must be supported; otherwise the most preferred supported Accept media
+// type is used, falling back to JSON.
+func negotiateFormat(r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		if _, ok := formatContentTypes[format]; !ok {
+			return "", fmt.Errorf("unsupported format %q", format)
+		}
+		return format, nil
+	}
+
+	type accepted struct {
+		format string
+		q      float64
+	}
+
+	var candidates []accepted
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil || q <= 0 {
+				continue
+			}
+		}
+
+		if format, ok := formatMediaTypes[mediaType]; ok {
+			candidates = append(candidates, accepted{format, q})
+		}
+	}
+
+	// Stable so the client's order breaks ties between equal q values.
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].q > candidates[j].q
+	})
+
+	if len(candidates) == 0 {
+		return formatJSON, nil
+	}
+	return candidates[0].format, nil
+}
+
+// encodeFormat encodes v in format. Plain text uses v's String method.
+func encodeFormat(format string, v fmt.Stringer) ([]byte, error) {
+	switch format {
+	case formatXML:
+		b, err := xml.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(xml.Header), b...), nil
+	case formatText:
+		return []byte(v.String()), nil
+	default:
+		return json.Marshal(v)
+	}
+}
+
+// writeConditional serves body tagged with an ETag derived from it, and with
+// lastModified unless that is zero. Clients whose If-None-Match or
+// If-Modified-Since shows their copy is current get a 304 instead.
+func writeConditional(w http.ResponseWriter, r *http.Request, contentType string, body []byte, lastModified time.Time) {
+	sum := sha256.Sum256(body)
+	w.Header().Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	w.Header().Set("Content-Type", contentType)
+
+	http.ServeContent(w, r, "", lastModified, bytes.NewReader(body))
+}
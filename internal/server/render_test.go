@@ -0,0 +1,44 @@
+// internal/server/render_test.go
+
+package server
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	testCases := []struct {
+		name           string
+		query          string
+		accept         string
+		expectedFormat string
+		expectedErr    bool
+	}{
+		{"Default", "", "", formatJSON, false},
+		{"AnyMediaType", "", "*/*", formatJSON, false},
+		{"XML", "", "application/xml", formatXML, false},
+		{"TextXML", "", "text/xml", formatXML, false},
+		{"Text", "", "text/plain; charset=utf-8", formatText, false},
+		{"FirstSupported", "", "text/html, text/plain, application/json", formatText, false},
+		{"QualityWins", "", "application/json;q=0.5, application/xml", formatXML, false},
+		{"RejectedType", "", "application/xml;q=0, text/plain;q=0.1", formatText, false},
+		{"Unsupported", "", "image/png", formatJSON, false},
+		{"QueryOverride", "format=text", "application/xml", formatText, false},
+		{"QueryUnsupported", "format=yaml", "", "", true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", "/quote?"+tC.query, nil)
+			if tC.accept != "" {
+				req.Header.Set("Accept", tC.accept)
+			}
+
+			format, err := negotiateFormat(req)
+
+			assert.Equal(t, tC.expectedFormat, format, "Format is different than expected")
+			assert.Equal(t, tC.expectedErr, err != nil, "Error is different than expected")
+		})
+	}
+}
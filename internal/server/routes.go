@@ -0,0 +1,644 @@
+// internal/server/routes.go
+
+package server
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/featureflag"
+	"github.com/jeniok12/golang-tdd-example/internal/audit"
+	"github.com/jeniok12/golang-tdd-example/internal/config"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/rating"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/jeniok12/golang-tdd-example/internal/webhook"
+	"github.com/jeniok12/golang-tdd-example/openapi"
+	"net/http"
+	"strings"
+)
+
+// routeMethods are the methods probed when building the Allow header.
+var routeMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+
+// Query parameters shared by several routes.
+var (
+	langParam     = openapi.Param{Name: "lang", In: "query", Description: "quote language, left to the provider when empty"}
+	groupParam    = openapi.Param{Name: "group", In: "query", Description: "restrict recipients to the named group"}
+	tagsParam     = openapi.Param{Name: "tags", In: "query", Description: "restrict recipients to those with any of these comma separated tags"}
+	limitParam    = openapi.Param{Name: "limit", In: "query", Type: "integer", Description: "maximum number of items"}
+	offsetParam   = openapi.Param{Name: "offset", In: "query", Type: "integer", Description: "number of items to skip"}
+	intervalParam = openapi.Param{Name: "interval", In: "query", Description: "time between quotes, such as 30s (default 10s)"}
+	searchParam   = openapi.Param{Name: "q", In: "query", Required: true, Description: "words to search for"}
+	exportParam   = openapi.Param{Name: "format", In: "query", Description: "csv (default) or json, a JSON array streamed as rows are read"}
+)
+
+// ifMatchParam carries the ETag of the recipient an update is based on.
+var ifMatchParam = openapi.Param{
+	Name:        "If-Match",
+	In:          "header",
+	Required:    true,
+	Description: "ETag of the recipient being updated, or * to overwrite any version",
+}
+
+func (s *Server) routes() {
+	s.router.Use(s.withRequestID, s.withTenant, s.withAccessLog, s.withInFlight, s.withLoadShedding, s.withTracing, s.withLogging, s.withCompression, s.withHSTS, s.withCORS, s.withAuth, s.withMaintenance, s.withRateLimit, s.withBodyLimit, s.withDeadline, s.withRecovery)
+	s.router.Use(s.middleware...)
+	s.router.MethodNotAllowedHandler = s.withCORS(s.handleMethodNotAllowed())
+	// A matcher rather than Methods, so other methods on unknown paths stay
+	// 404 instead of 405.
+	s.router.MatcherFunc(func(r *http.Request, _ *mux.RouteMatch) bool {
+		return r.Method == http.MethodOptions
+	}).HandlerFunc(s.handleOptions())
+
+	s.handle(openapi.Operation{
+		Method:      "GET",
+		Path:        "/quote",
+		Summary:     "Get a quote and its recipients",
+		Description: "With author, quotes are drawn from the provider until one matches, then the local quotes are searched. No match is a 404. While the database is down, the quote is served without recipients and with a warning.",
+		Tags:        []string{"quotes"},
+		Query:       quoteRequest{},
+		Params:      []openapi.Param{{Name: "format", In: "query", Description: "json, xml or text; overrides the Accept header"}},
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Body: HandleQuoteResponse{}, ContentTypes: []string{"application/json", "application/xml"}},
+			{Status: http.StatusNotModified},
+		},
+	}, s.handleQuotes())
+	s.handle(openapi.Operation{
+		Method:      "GET",
+		Path:        "/v1/quote",
+		Summary:     "Get a quote and its recipients, v1 schema",
+		Description: "Same as /quote, whose schema v1 keeps.",
+		Tags:        []string{"quotes"},
+		Query:       quoteRequest{},
+		Params:      []openapi.Param{{Name: "format", In: "query", Description: "json, xml or text; overrides the Accept header"}},
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Body: HandleQuoteResponse{}, ContentTypes: []string{"application/json", "application/xml"}},
+			{Status: http.StatusNotModified},
+		},
+	}, s.handleQuotes())
+	s.handle(openapi.Operation{
+		Method:      "GET",
+		Path:        "/v2/quote",
+		Summary:     "Get a quote and its recipients, v2 schema",
+		Description: "Like /v1/quote, with camelCase quote fields and a meta block giving the provider, the quote latency and the request ID. Recipients are only listed with include=recipients. Responses are not revalidated, the meta block changes every time.",
+		Tags:        []string{"quotes"},
+		Query:       quoteRequest{},
+		Params:      []openapi.Param{{Name: "format", In: "query", Description: "json, xml or text; overrides the Accept header"}},
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Body: QuoteResponseV2{}, ContentTypes: []string{"application/json", "application/xml"}},
+		},
+	}, s.handleQuotesV2())
+	s.handle(openapi.Operation{
+		Method:      "GET",
+		Path:        "/quote/stream",
+		Summary:     "Stream quotes over a WebSocket",
+		Description: `Pushes StreamMessage values every interval. Send {"type":"ping"} for a quote right away or {"type":"lang","lang":"ru"} to switch language.`,
+		Tags:        []string{"quotes"},
+		Params:      []openapi.Param{langParam, intervalParam},
+		Responses:   []openapi.Response{{Status: http.StatusSwitchingProtocols, Description: "WebSocket of StreamMessage"}},
+	}, s.handleQuoteStream())
+	s.handle(openapi.Operation{
+		Method:      "GET",
+		Path:        "/quote/events",
+		Summary:     "Stream quotes as Server-Sent Events",
+		Description: "Sends a quote event every interval. Reconnecting with Last-Event-ID keeps the cadence.",
+		Tags:        []string{"quotes"},
+		Params:      []openapi.Param{langParam, intervalParam, {Name: "Last-Event-ID", In: "header"}},
+		Responses:   []openapi.Response{{Status: http.StatusOK, ContentTypes: []string{"text/event-stream"}}},
+	}, s.handleQuoteEvents())
+	s.handle(openapi.Operation{
+		Method:    "POST",
+		Path:      "/quote/send",
+		Summary:   "Email a quote to every verified recipient",
+		Tags:      []string{"quotes"},
+		Params:    []openapi.Param{langParam, groupParam, tagsParam, idempotencyKeyParam},
+		Responses: []openapi.Response{{Status: http.StatusOK, Body: HandleSendQuoteResponse{}}},
+	}, s.idempotent(s.handleSendQuote()))
+	s.handle(openapi.Operation{
+		Method:      "POST",
+		Path:        "/quote/send/preview",
+		Summary:     "Render the quote email without sending it",
+		Description: "Addresses the email to the given recipient, or to a placeholder when none is given.",
+		Tags:        []string{"quotes"},
+		Query:       previewRequest{},
+		Responses:   []openapi.Response{{Status: http.StatusOK, Body: PreviewResponse{}}},
+	}, s.handleSendPreview())
+	s.handle(openapi.Operation{
+		Method:    "POST",
+		Path:      "/quote/daily/run",
+		Summary:   "Run the daily quote delivery now",
+		Tags:      []string{"quotes"},
+		Responses: []openapi.Response{{Status: http.StatusOK, Body: DailyRunResponse{}}},
+	}, s.handleDailyRun())
+	s.handle(openapi.Operation{
+		Method:      "GET",
+		Path:        "/quotes",
+		Summary:     "Draw several distinct quotes",
+		Description: "Draws count quotes concurrently and drops repeats. Draws that fail are counted in failed; the request only fails when all of them do.",
+		Tags:        []string{"quotes"},
+		Query:       quoteBatchRequest{},
+		Responses:   []openapi.Response{{Status: http.StatusOK, Body: QuoteBatchResponse{}}},
+	}, s.requireFeature(featureQuoteBatch, s.handleQuoteBatch()))
+	s.handle(openapi.Operation{
+		Method:  "GET",
+		Path:    "/quotes/history",
+		Summary: "List served quotes, newest first",
+		Tags:    []string{"quotes"},
+		Params:  []openapi.Param{langParam, limitParam, offsetParam},
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Body: QuoteHistoryResponse{}},
+			{Status: http.StatusNotModified},
+		},
+	}, s.handleQuoteHistory())
+	s.handle(openapi.Operation{
+		Method:      "GET",
+		Path:        "/quotes/feed.rss",
+		Summary:     "Follow served quotes as an RSS feed",
+		Description: "Lists the latest served quotes, newest first, as RSS 2.0.",
+		Tags:        []string{"quotes"},
+		Params:      []openapi.Param{langParam},
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, ContentTypes: []string{"application/rss+xml"}},
+			{Status: http.StatusNotModified},
+		},
+	}, s.handleRSSFeed())
+	s.handle(openapi.Operation{
+		Method:      "GET",
+		Path:        "/quotes/feed.atom",
+		Summary:     "Follow served quotes as an Atom feed",
+		Description: "Lists the latest served quotes, newest first, as Atom.",
+		Tags:        []string{"quotes"},
+		Params:      []openapi.Param{langParam},
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, ContentTypes: []string{"application/atom+xml"}},
+			{Status: http.StatusNotModified},
+		},
+	}, s.handleAtomFeed())
+	s.handle(openapi.Operation{
+		Method:      "GET",
+		Path:        "/quotes/search",
+		Summary:     "Search served quotes, best matches first",
+		Description: "Finds the quotes whose text or author contain every word of q. Matched words are wrapped in <mark> tags in highlight.",
+		Tags:        []string{"quotes"},
+		Params:      []openapi.Param{searchParam, langParam, limitParam, offsetParam},
+		Responses:   []openapi.Response{{Status: http.StatusOK, Body: QuoteSearchResponse{}}},
+	}, s.handleSearchQuotes())
+	s.handle(openapi.Operation{
+		Method:      "GET",
+		Path:        "/quotes/trending",
+		Summary:     "List the best rated quotes",
+		Description: "Ranks the quotes rated within window by their average score, then by their number of votes.",
+		Tags:        []string{"quotes"},
+		Query:       trendingRequest{},
+		Responses:   []openapi.Response{{Status: http.StatusOK, Body: TrendingResponse{}}},
+	}, s.handleTrendingQuotes())
+	s.handle(openapi.Operation{
+		Method:      "POST",
+		Path:        "/quotes/{id:[0-9]+}/rating",
+		Summary:     "Rate a quote from the history",
+		Description: "Requires an X-API-Key. Rating a quote again replaces the earlier score of the key.",
+		Tags:        []string{"quotes"},
+		Body:        RateQuoteRequest{},
+		Responses:   []openapi.Response{{Status: http.StatusOK, Body: rating.Rating{}}},
+	}, s.handleRateQuote())
+	s.handle(openapi.Operation{
+		Method:    "GET",
+		Path:      "/quotes/history/export",
+		Summary:   "Download the quote history as CSV or JSON",
+		Tags:      []string{"quotes"},
+		Params:    []openapi.Param{langParam, exportParam},
+		Responses: []openapi.Response{{Status: http.StatusOK, ContentTypes: []string{"text/csv", "application/json"}}},
+	}, s.handleExportQuoteHistory())
+
+	s.handle(openapi.Operation{
+		Method:    "GET",
+		Path:      "/groups",
+		Summary:   "List recipient groups",
+		Tags:      []string{"groups"},
+		Responses: []openapi.Response{{Status: http.StatusOK, Body: []recipient.Group{}}},
+	}, s.handleListGroups())
+	s.handle(openapi.Operation{
+		Method:    "POST",
+		Path:      "/groups",
+		Summary:   "Create a recipient group",
+		Tags:      []string{"groups"},
+		Body:      CreateGroupRequest{},
+		Responses: []openapi.Response{{Status: http.StatusCreated, Body: recipient.Group{}}},
+	}, s.handleCreateGroup())
+	s.handle(openapi.Operation{
+		Method:    "PUT",
+		Path:      "/groups/{id:[0-9]+}/members/{recipientId:[0-9]+}",
+		Summary:   "Add a recipient to a group",
+		Tags:      []string{"groups"},
+		Responses: []openapi.Response{{Status: http.StatusNoContent}},
+	}, s.handleAddGroupMember())
+	s.handle(openapi.Operation{
+		Method:    "DELETE",
+		Path:      "/groups/{id:[0-9]+}/members/{recipientId:[0-9]+}",
+		Summary:   "Remove a recipient from a group",
+		Tags:      []string{"groups"},
+		Responses: []openapi.Response{{Status: http.StatusNoContent}},
+	}, s.handleRemoveGroupMember())
+
+	s.handle(openapi.Operation{
+		Method:    "GET",
+		Path:      "/admin/local-quotes",
+		Summary:   "List quotes of the local provider",
+		Tags:      []string{"admin"},
+		Params:    []openapi.Param{langParam},
+		Responses: []openapi.Response{{Status: http.StatusOK, Body: []quote.Record{}}},
+	}, s.handleListLocalQuotes())
+	s.handle(openapi.Operation{
+		Method:    "POST",
+		Path:      "/admin/local-quotes",
+		Summary:   "Add a quote to the local provider",
+		Tags:      []string{"admin"},
+		Body:      quote.Quote{},
+		Responses: []openapi.Response{{Status: http.StatusCreated, Body: quote.Record{}}},
+	}, s.handleAddLocalQuote())
+	s.handle(openapi.Operation{
+		Method:    "DELETE",
+		Path:      "/admin/local-quotes/{id:[0-9]+}",
+		Summary:   "Remove a quote from the local provider",
+		Tags:      []string{"admin"},
+		Responses: []openapi.Response{{Status: http.StatusNoContent}},
+	}, s.handleRemoveLocalQuote())
+
+	s.handle(openapi.Operation{
+		Method:    "POST",
+		Path:      "/admin/cache/flush",
+		Summary:   "Drop every cached quote",
+		Tags:      []string{"admin"},
+		Responses: []openapi.Response{{Status: http.StatusNoContent}},
+	}, s.handleFlushCache())
+	s.handle(openapi.Operation{
+		Method:    "POST",
+		Path:      "/admin/breakers/{provider}/trip",
+		Summary:   "Open the circuit breaker of a provider",
+		Tags:      []string{"admin"},
+		Responses: []openapi.Response{{Status: http.StatusOK, Body: quote.BreakerStats{}}},
+	}, s.handleBreaker("trip"))
+	s.handle(openapi.Operation{
+		Method:    "POST",
+		Path:      "/admin/breakers/{provider}/reset",
+		Summary:   "Close the circuit breaker of a provider",
+		Tags:      []string{"admin"},
+		Responses: []openapi.Response{{Status: http.StatusOK, Body: quote.BreakerStats{}}},
+	}, s.handleBreaker("reset"))
+	s.handle(openapi.Operation{
+		Method:      "POST",
+		Path:        "/admin/jobs/{job}/run",
+		Summary:     "Run a scheduled job now",
+		Description: "Jobs are daily-quote, outbox-drain, idempotency-purge and webhook-drain, when scheduled. A run in progress is waited for.",
+		Tags:        []string{"admin"},
+		Responses:   []openapi.Response{{Status: http.StatusOK, Body: JobRunResponse{}}},
+	}, s.handleRunJob())
+	s.handle(openapi.Operation{
+		Method:    "GET",
+		Path:      "/admin/outbox",
+		Summary:   "Count outbox entries by status",
+		Tags:      []string{"admin"},
+		Responses: []openapi.Response{{Status: http.StatusOK, Body: OutboxResponse{}}},
+	}, s.handleOutboxDepth())
+	s.handle(openapi.Operation{
+		Method:    "GET",
+		Path:      "/admin/webhooks",
+		Summary:   "List the URLs notified of generated and delivered quotes",
+		Tags:      []string{"admin"},
+		Responses: []openapi.Response{{Status: http.StatusOK, Body: []webhook.Hook{}}},
+	}, s.handleListWebhooks())
+	s.handle(openapi.Operation{
+		Method:      "POST",
+		Path:        "/admin/webhooks",
+		Summary:     "Register a URL notified of generated and delivered quotes",
+		Description: "Events are POSTed as JSON with the X-Webhook-Signature header, sha256= and the hex HMAC-SHA256 of X-Webhook-Timestamp, a dot and the body, keyed with the secret. The secret is only shown in this response.",
+		Tags:        []string{"admin"},
+		Body:        CreateWebhookRequest{},
+		Responses:   []openapi.Response{{Status: http.StatusCreated, Body: CreateWebhookResponse{}}},
+	}, s.handleCreateWebhook())
+	s.handle(openapi.Operation{
+		Method:    "DELETE",
+		Path:      "/admin/webhooks/{id:[0-9]+}",
+		Summary:   "Remove a webhook and its delivery log",
+		Tags:      []string{"admin"},
+		Responses: []openapi.Response{{Status: http.StatusNoContent}},
+	}, s.handleDeleteWebhook())
+	s.handle(openapi.Operation{
+		Method:    "GET",
+		Path:      "/admin/webhooks/{id:[0-9]+}/deliveries",
+		Summary:   "List the notifications sent to a webhook, newest first",
+		Tags:      []string{"admin"},
+		Query:     webhookDeliveriesRequest{},
+		Responses: []openapi.Response{{Status: http.StatusOK, Body: []webhook.Delivery{}}},
+	}, s.handleWebhookDeliveries())
+	s.handle(openapi.Operation{
+		Method:    "GET",
+		Path:      "/admin/config",
+		Summary:   "Show the configuration, secrets redacted",
+		Tags:      []string{"admin"},
+		Responses: []openapi.Response{{Status: http.StatusOK, Body: config.Config{}}},
+	}, s.handleConfig())
+	s.handle(openapi.Operation{
+		Method:      "POST",
+		Path:        "/admin/config/reload",
+		Summary:     "Reread the configuration",
+		Description: "Applies the log level, rate limit, cache TTL, provider order and feature flags without a restart. Other changed settings are listed as taking a restart. The server reloads on SIGHUP too.",
+		Tags:        []string{"admin"},
+		Responses:   []openapi.Response{{Status: http.StatusOK, Body: ReloadResponse{}}},
+	}, s.handleReloadConfig())
+	s.handle(openapi.Operation{
+		Method:    "GET",
+		Path:      "/admin/features",
+		Summary:   "List feature flags",
+		Tags:      []string{"admin"},
+		Responses: []openapi.Response{{Status: http.StatusOK, Body: FeaturesResponse{}}},
+	}, s.handleFeatures())
+	s.handle(openapi.Operation{
+		Method:      "PUT",
+		Path:        "/admin/features/{name}",
+		Summary:     "Turn a feature on or off",
+		Description: "The override holds until deleted or the server restarts.",
+		Tags:        []string{"admin"},
+		Body:        FeatureOverride{},
+		Responses:   []openapi.Response{{Status: http.StatusOK, Body: featureflag.State{}}},
+	}, s.handleOverrideFeature())
+	s.handle(openapi.Operation{
+		Method:    "DELETE",
+		Path:      "/admin/features/{name}",
+		Summary:   "Bring a feature back to its configured value",
+		Tags:      []string{"admin"},
+		Responses: []openapi.Response{{Status: http.StatusOK, Body: featureflag.State{}}},
+	}, s.handleClearFeature())
+	s.handle(openapi.Operation{
+		Method:    "GET",
+		Path:      "/admin/maintenance",
+		Summary:   "Tell whether maintenance mode is on",
+		Tags:      []string{"admin"},
+		Responses: []openapi.Response{{Status: http.StatusOK, Body: Maintenance{}}},
+	}, s.handleMaintenance())
+	s.handle(openapi.Operation{
+		Method:      "PUT",
+		Path:        "/admin/maintenance",
+		Summary:     "Turn maintenance mode on",
+		Description: "Every route but the health probes answers 503 with the MAINTENANCE code, the message and the ETA, until maintenance mode is turned off or the server restarts. Requests with an admin token still go through.",
+		Tags:        []string{"admin"},
+		Body:        MaintenanceRequest{},
+		Responses:   []openapi.Response{{Status: http.StatusOK, Body: Maintenance{}}},
+	}, s.handleStartMaintenance())
+	s.handle(openapi.Operation{
+		Method:    "DELETE",
+		Path:      "/admin/maintenance",
+		Summary:   "Turn maintenance mode off",
+		Tags:      []string{"admin"},
+		Responses: []openapi.Response{{Status: http.StatusOK, Body: Maintenance{}}},
+	}, s.handleStopMaintenance())
+
+	s.handle(openapi.Operation{
+		Method:    "GET",
+		Path:      "/providers",
+		Summary:   "List quote providers",
+		Tags:      []string{"info"},
+		Responses: []openapi.Response{{Status: http.StatusOK, Body: ProvidersResponse{}}},
+	}, s.handleProviders())
+	s.handle(openapi.Operation{
+		Method:    "GET",
+		Path:      "/providers/health",
+		Summary:   "Show recent success rate, p95 latency and last error per provider",
+		Tags:      []string{"info"},
+		Responses: []openapi.Response{{Status: http.StatusOK, Body: ProvidersHealthResponse{}}},
+	}, s.handleProvidersHealth())
+	s.handle(openapi.Operation{
+		Method:    "GET",
+		Path:      "/languages",
+		Summary:   "List supported quote languages",
+		Tags:      []string{"info"},
+		Responses: []openapi.Response{{Status: http.StatusOK, Body: LanguagesResponse{}}},
+	}, s.handleLanguages())
+	s.handle(openapi.Operation{
+		Method:    "GET",
+		Path:      "/healthz",
+		Summary:   "Liveness probe",
+		Tags:      []string{"info"},
+		Responses: []openapi.Response{{Status: http.StatusOK, Body: CheckStatus{}}},
+	}, s.handleHealthz())
+	s.handle(openapi.Operation{
+		Method:  "GET",
+		Path:    "/readyz",
+		Summary: "Readiness probe",
+		Tags:    []string{"info"},
+		Responses: []openapi.Response{
+			{Status: http.StatusOK, Body: ReadinessResponse{}},
+			{Status: http.StatusServiceUnavailable, Body: ReadinessResponse{}},
+		},
+	}, s.handleReadyz())
+	s.handle(openapi.Operation{
+		Method:    "GET",
+		Path:      "/metrics",
+		Summary:   "Cache and circuit breaker statistics",
+		Tags:      []string{"info"},
+		Responses: []openapi.Response{{Status: http.StatusOK, Body: MetricsResponse{}}},
+	}, s.handleMetrics())
+
+	s.handle(openapi.Operation{
+		Method:    "GET",
+		Path:      "/recipients",
+		Summary:   "List recipients",
+		Tags:      []string{"recipients"},
+		Query:     recipientsRequest{},
+		Responses: []openapi.Response{{Status: http.StatusOK, Body: []recipient.Recipient{}}},
+	}, s.handleListRecipients())
+	s.handle(openapi.Operation{
+		Method:    "POST",
+		Path:      "/recipients",
+		Summary:   "Create a recipient and email them a verification link",
+		Tags:      []string{"recipients"},
+		Params:    []openapi.Param{idempotencyKeyParam},
+		Body:      RecipientRequest{},
+		Responses: []openapi.Response{{Status: http.StatusCreated, Body: recipient.Recipient{}}},
+	}, s.idempotent(s.handleCreateRecipient()))
+	s.handle(openapi.Operation{
+		Method:    "GET",
+		Path:      "/recipients/export",
+		Summary:   "Download all recipients as CSV or JSON",
+		Tags:      []string{"recipients"},
+		Params:    []openapi.Param{exportParam},
+		Responses: []openapi.Response{{Status: http.StatusOK, ContentTypes: []string{"text/csv", "application/json"}}},
+	}, s.handleExportRecipients())
+	s.handle(openapi.Operation{
+		Method:           "POST",
+		Path:             "/recipients/import",
+		Summary:          "Import recipients from a CSV or JSON upload",
+		Tags:             []string{"recipients"},
+		Params:           []openapi.Param{idempotencyKeyParam},
+		Body:             []recipient.Recipient{},
+		BodyContentTypes: []string{"application/json", "text/csv"},
+		Responses:        []openapi.Response{{Status: http.StatusOK, Body: ImportReport{}}},
+	}, s.idempotent(s.handleImportRecipients()))
+	s.handle(openapi.Operation{
+		Method:    "GET",
+		Path:      "/recipients/verify",
+		Summary:   "Confirm a recipient's email",
+		Tags:      []string{"recipients"},
+		Query:     verifyRequest{},
+		Responses: []openapi.Response{{Status: http.StatusOK, Body: recipient.Recipient{}}},
+	}, s.handleVerifyRecipient())
+	s.handle(openapi.Operation{
+		Method:      "GET",
+		Path:        "/unsubscribe",
+		Summary:     "Stop emails to the address of an unsubscribe link",
+		Description: "Adds the email to the suppression list, which is never sent emails nor listed as a quote recipient.",
+		Tags:        []string{"recipients"},
+		Query:       unsubscribeRequest{},
+		Responses:   []openapi.Response{{Status: http.StatusOK, Body: UnsubscribeResponse{}}},
+	}, s.handleUnsubscribe())
+	s.handle(openapi.Operation{
+		Method:      "POST",
+		Path:        "/webhooks/email",
+		Summary:     "Receive delivery events from the mail provider",
+		Description: "Takes SendGrid event webhooks or SES notifications through SNS, as configured. Updates the status of the deliveries and suppresses hard bounced emails.",
+		Tags:        []string{"recipients"},
+		Query:       emailWebhookRequest{},
+		Responses:   []openapi.Response{{Status: http.StatusOK, Body: EmailWebhookResponse{}}},
+	}, s.handleEmailWebhook())
+	s.handle(openapi.Operation{
+		Method:    "GET",
+		Path:      "/recipients/{id:[0-9]+}",
+		Summary:   "Get a recipient",
+		Tags:      []string{"recipients"},
+		Responses: []openapi.Response{{Status: http.StatusOK, Body: recipient.Recipient{}}},
+	}, s.handleGetRecipient())
+	s.handle(openapi.Operation{
+		Method:    "PUT",
+		Path:      "/recipients/{id:[0-9]+}",
+		Summary:   "Update a recipient; a new email needs verifying again",
+		Tags:      []string{"recipients"},
+		Params:    []openapi.Param{ifMatchParam},
+		Body:      RecipientRequest{},
+		Responses: []openapi.Response{{Status: http.StatusOK, Body: recipient.Recipient{}}},
+	}, s.handleUpdateRecipient())
+	s.handle(openapi.Operation{
+		Method:    "DELETE",
+		Path:      "/recipients/{id:[0-9]+}",
+		Summary:   "Delete a recipient",
+		Tags:      []string{"recipients"},
+		Responses: []openapi.Response{{Status: http.StatusNoContent}},
+	}, s.handleDeleteRecipient())
+	s.handle(openapi.Operation{
+		Method:    "PATCH",
+		Path:      "/recipients/{id:[0-9]+}/preferences",
+		Summary:   "Change the language and frequency of a recipient's scheduled quotes",
+		Tags:      []string{"recipients"},
+		Body:      PreferencesRequest{},
+		Responses: []openapi.Response{{Status: http.StatusOK, Body: recipient.Recipient{}}},
+	}, s.handleUpdatePreferences())
+	s.handle(openapi.Operation{
+		Method:    "GET",
+		Path:      "/recipients/{id:[0-9]+}/favorites",
+		Summary:   "List a recipient's favorite quotes, newest first",
+		Tags:      []string{"recipients"},
+		Responses: []openapi.Response{{Status: http.StatusOK, Body: []recipient.Favorite{}}},
+	}, s.handleListFavorites())
+	s.handle(openapi.Operation{
+		Method:    "POST",
+		Path:      "/recipients/{id:[0-9]+}/favorites",
+		Summary:   "Save a quote from the history as a recipient's favorite",
+		Tags:      []string{"recipients"},
+		Body:      AddFavoriteRequest{},
+		Responses: []openapi.Response{{Status: http.StatusCreated, Body: recipient.Favorite{}}},
+	}, s.handleAddFavorite())
+	s.handle(openapi.Operation{
+		Method:    "GET",
+		Path:      "/recipients/{id:[0-9]+}/tags",
+		Summary:   "List a recipient's tags",
+		Tags:      []string{"recipients"},
+		Responses: []openapi.Response{{Status: http.StatusOK, Body: []string{}}},
+	}, s.handleListTags())
+	s.handle(openapi.Operation{
+		Method:    "PUT",
+		Path:      "/recipients/{id:[0-9]+}/tags/{tag}",
+		Summary:   "Tag a recipient",
+		Tags:      []string{"recipients"},
+		Responses: []openapi.Response{{Status: http.StatusNoContent}},
+	}, s.handleAddTag())
+	s.handle(openapi.Operation{
+		Method:    "DELETE",
+		Path:      "/recipients/{id:[0-9]+}/tags/{tag}",
+		Summary:   "Remove a tag from a recipient",
+		Tags:      []string{"recipients"},
+		Responses: []openapi.Response{{Status: http.StatusNoContent}},
+	}, s.handleRemoveTag())
+
+	if s.auditLog != nil {
+		s.handle(openapi.Operation{
+			Method:  "GET",
+			Path:    "/admin/audit",
+			Summary: "List mutations made through the API, newest first",
+			Tags:    []string{"admin"},
+			Params: []openapi.Param{
+				{Name: "entity", In: "query", Description: "restrict entries to recipient, group, quote, oauth_client or api_key"},
+				{Name: "since", In: "query", Description: "RFC 3339 time of the oldest entry"},
+				limitParam,
+			},
+			Responses: []openapi.Response{{Status: http.StatusOK, Body: []audit.Entry{}}},
+		}, s.handleAuditLog())
+	}
+
+	s.handle(openapi.Operation{
+		Method:      "POST",
+		Path:        "/admin/apikeys/{id}/rotate",
+		Summary:     "Give a tenant API key a new key",
+		Description: "id is the key's ID, as in the access and audit logs, which the new key keeps. The previous key keeps working for the configured grace period, so the client can switch over, then is turned away with 401. The response holds the new key, which cannot be retrieved later. The owner of the key is emailed. Rotations are kept in memory only: put the new key in tenant-keys before the server restarts.",
+		Tags:        []string{"admin"},
+		Responses:   []openapi.Response{{Status: http.StatusOK, Body: RotateAPIKeyResponse{}}},
+	}, s.handleRotateAPIKey())
+
+	if s.oauthClients != nil {
+		s.oauthRoutes()
+	}
+
+	if s.debug {
+		s.debugRoutes()
+	}
+
+	s.router.Handle("/openapi.json", s.spec.Handler()).Methods("GET")
+	s.router.Handle("/docs", openapi.DocsHandler(s.spec.Title, "/openapi.json")).Methods("GET")
+}
+
+// handle registers h for op and describes it in the OpenAPI spec, so the
+// spec cannot drift from the router. Admin routes take the admin token, and
+// are left out when there is none, and with JWTs every route takes its role.
+func (s *Server) handle(op openapi.Operation, h http.HandlerFunc) {
+	switch {
+	case adminRoute(op) && !s.adminEnabled():
+		return
+	case adminRoute(op):
+		h = s.requireAdmin(h)
+	case len(s.verifiers) > 0:
+		h = s.requireRole(routeRole(op), h)
+	}
+	s.router.HandleFunc(op.Path, h).Methods(op.Method)
+	s.spec.Add(op)
+}
+
+// allowedMethods lists the methods routed for the request's path.
+func (s *Server) allowedMethods(r *http.Request) []string {
+	var allowed []string
+	for _, method := range routeMethods {
+		probe := r.Clone(r.Context())
+		probe.Method = method
+
+		var match mux.RouteMatch
+		if s.router.Match(probe, &match) && match.MatchErr == nil {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed
+}
+
+// handleMethodNotAllowed answers 405 and lists the methods the path does support.
+func (s *Server) handleMethodNotAllowed() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Allow", strings.Join(s.allowedMethods(r), ", "))
+		apierror.Write(w, r, apierror.New(http.StatusMethodNotAllowed, apierror.CodeMethodNotAllowed, "method not allowed"))
+	}
+}
@@ -0,0 +1,83 @@
+// internal/server/routes_test.go
+
+package server
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/jeniok12/golang-tdd-example/openapi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRoutes_MethodNotAllowed(t *testing.T) {
+	svr := newTestServer(t)
+
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+		expectedAllow  string
+	}{
+		{"QuotePost", "POST", "/quote", http.StatusMethodNotAllowed, "GET"},
+		{"SendGet", "GET", "/quote/send", http.StatusMethodNotAllowed, "POST"},
+		{"RecipientPatch", "PATCH", "/recipients/1", http.StatusMethodNotAllowed, "GET, PUT, DELETE"},
+		{"RecipientsDelete", "DELETE", "/recipients", http.StatusMethodNotAllowed, "GET, POST"},
+		{"UnknownPath", "GET", "/nope", http.StatusNotFound, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest(tc.method, tc.path, nil)
+
+			svr.router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			assert.Equal(t, tc.expectedAllow, rr.Header().Get("Allow"), "Allow header is different than expected")
+		})
+	}
+}
+
+func TestRoutes_OpenAPI(t *testing.T) {
+	svr := newTestServer(t)
+
+	documented := make(map[string]bool)
+	for _, op := range svr.spec.Operations() {
+		documented[op.Method+" "+op.Path] = true
+	}
+
+	undocumented := map[string]bool{"GET /openapi.json": true, "GET /docs": true}
+	err := svr.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		path, _ := route.GetPathTemplate()
+		methods, _ := route.GetMethods()
+		for _, method := range methods {
+			key := method + " " + path
+			assert.True(t, documented[key] || undocumented[key], "Route %s should be in the OpenAPI spec", key)
+			delete(documented, key)
+		}
+		return nil
+	})
+	require.NoError(t, err, "Should have no error when walking the routes")
+	assert.Empty(t, documented, "Every documented operation should be routed")
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/openapi.json", nil)
+	svr.router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+	var doc openapi.Document
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &doc), "Spec should be JSON")
+	assert.Contains(t, doc.Paths, "/recipients/{id}", "Spec should describe recipient routes")
+	assert.Contains(t, doc.Components.Schemas, "HandleQuoteResponse", "Spec should describe the quote response")
+	assert.Contains(t, doc.Components.Schemas, "Error", "Spec should describe the error envelope")
+
+	rr = httptest.NewRecorder()
+	req, _ = http.NewRequest("GET", "/docs", nil)
+	svr.router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+}
@@ -0,0 +1,60 @@
+// internal/server/seed.go
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+)
+
+// SeedResult counts the quotes a Seed added and the repeats it skipped.
+type SeedResult struct {
+	Added   int
+	Skipped int
+}
+
+// Seed fetches count quotes in lang from the quote providers and adds those
+// not already there to the local quotes. Like every quote served, they are
+// saved to the history as well, where search finds them.
+func (s *Server) Seed(ctx context.Context, lang string, count int) (SeedResult, error) {
+	var res SeedResult
+	if s.localQuotes == nil {
+		return res, errors.New("server: seeding needs a local quote store")
+	}
+	if lang == "" {
+		return res, errors.New("server: seeding needs a language")
+	}
+
+	existing, err := s.localQuotes.ListLocal(ctx, lang)
+	if err != nil {
+		return res, err
+	}
+	seen := make(map[string]bool, len(existing))
+	for _, r := range existing {
+		seen[r.Text+"\n"+r.Author] = true
+	}
+
+	for i := 0; i < count; i++ {
+		// Past the cache, which would hand out the same quote every time.
+		q, err := s.quoteGenerator.Generate(quote.SkipCache(ctx), lang)
+		if err != nil {
+			return res, fmt.Errorf("fetching quote %d of %d: %w", i+1, count, err)
+		}
+
+		key := q.Text + "\n" + q.Author
+		if seen[key] {
+			res.Skipped++
+			continue
+		}
+		seen[key] = true
+
+		if _, err := s.localQuotes.AddLocal(ctx, quote.Quote{Text: q.Text, Author: q.Author, Lang: lang}); err != nil {
+			return res, err
+		}
+		res.Added++
+	}
+
+	return res, nil
+}
@@ -0,0 +1,44 @@
+// internal/server/seed_test.go
+
+package server
+
+import (
+	"context"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/internal/memstore"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSeed(t *testing.T) {
+	ctx := context.Background()
+	local := memstore.NewLocalQuotes()
+	_, err := local.AddLocal(ctx, quote.Quote{Text: "Old", Author: "Bob", Lang: "en"})
+	require.NoError(t, err, "Got error when not expected")
+
+	mockQuoteGenerator := MockQuoteGenerator{}
+	for _, text := range []string{"One", "Old", "Two", "One"} {
+		mockQuoteGenerator.On("Generate", mock.Anything, "en").Return(&quote.Quote{Text: text, Author: "Bob", Lang: "en"}, nil).Once()
+	}
+	mockQuoteGenerator.On("Generate", mock.Anything, "en").Return(nil, errors.New("provider down")).Once()
+	svr := newTestServer(t, WithQuoteGenerator(&mockQuoteGenerator), WithLocalQuotes(local))
+
+	res, err := svr.Seed(ctx, "en", 4)
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, SeedResult{Added: 2, Skipped: 2}, res, "Quotes already there should be skipped")
+
+	records, _ := local.ListLocal(ctx, "en")
+	assert.Len(t, records, 3)
+
+	res, err = svr.Seed(ctx, "en", 1)
+	assert.Error(t, err, "Got no error when expected")
+	assert.Equal(t, SeedResult{}, res)
+
+	_, err = svr.Seed(ctx, "", 1)
+	assert.Error(t, err, "Got no error when expected")
+	_, err = newTestServer(t, WithQuoteGenerator(&mockQuoteGenerator)).Seed(ctx, "en", 1)
+	assert.Error(t, err, "Seeding without local quotes should fail")
+}
@@ -0,0 +1,978 @@
+// internal/server/server.go
+
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jeniok12/golang-tdd-example/bind"
+	"github.com/jeniok12/golang-tdd-example/errtrack"
+	"github.com/jeniok12/golang-tdd-example/featureflag"
+	"github.com/jeniok12/golang-tdd-example/filter"
+	"github.com/jeniok12/golang-tdd-example/internal/audit"
+	"github.com/jeniok12/golang-tdd-example/internal/auth"
+	"github.com/jeniok12/golang-tdd-example/internal/channel"
+	"github.com/jeniok12/golang-tdd-example/internal/config"
+	"github.com/jeniok12/golang-tdd-example/internal/delivery"
+	"github.com/jeniok12/golang-tdd-example/internal/idempotency"
+	"github.com/jeniok12/golang-tdd-example/internal/memstore"
+	"github.com/jeniok12/golang-tdd-example/internal/oauth"
+	"github.com/jeniok12/golang-tdd-example/internal/outbox"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/rating"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/jeniok12/golang-tdd-example/internal/redisstore"
+	"github.com/jeniok12/golang-tdd-example/internal/replica"
+	"github.com/jeniok12/golang-tdd-example/internal/sqlitestore"
+	"github.com/jeniok12/golang-tdd-example/internal/templates"
+	"github.com/jeniok12/golang-tdd-example/internal/translate"
+	"github.com/jeniok12/golang-tdd-example/internal/verify"
+	"github.com/jeniok12/golang-tdd-example/internal/webhook"
+	"github.com/jeniok12/golang-tdd-example/mailer"
+	"github.com/jeniok12/golang-tdd-example/openapi"
+	"github.com/jeniok12/golang-tdd-example/ratelimit"
+	"github.com/jeniok12/golang-tdd-example/scheduler"
+	"github.com/jeniok12/golang-tdd-example/worker"
+	"github.com/redis/go-redis/v9"
+	"github.com/rs/zerolog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// QuoteGenerator ...
+type QuoteGenerator interface {
+	Generate(ctx context.Context, lang string) (*quote.Quote, error)
+}
+
+// RecipientFetcher ...
+type RecipientFetcher interface {
+	AllRecipients(ctx context.Context) ([]recipient.Recipient, error)
+	ListRecipients(ctx context.Context, opts recipient.ListOptions) ([]recipient.Recipient, error)
+}
+
+// RecipientStore ...
+type RecipientStore interface {
+	RecipientFetcher
+	Create(ctx context.Context, r recipient.Recipient) (*recipient.Recipient, error)
+	GetByID(ctx context.Context, id int) (*recipient.Recipient, error)
+	Update(ctx context.Context, r recipient.Recipient) (*recipient.Recipient, error)
+	Delete(ctx context.Context, id int) error
+	MarkVerified(ctx context.Context, id int, email string) (*recipient.Recipient, error)
+	UpdatePreferences(ctx context.Context, id int, prefs recipient.Preferences) (*recipient.Recipient, error)
+	Import(ctx context.Context, rs []recipient.Recipient) ([]*recipient.Recipient, error)
+	EachRecipient(ctx context.Context, fn func(recipient.Recipient) error) error
+}
+
+// QuoteStore ...
+type QuoteStore interface {
+	ListQuotes(ctx context.Context, filter quote.ListFilter) ([]quote.Record, error)
+	EachQuote(ctx context.Context, lang string, fn func(quote.Record) error) error
+	SearchQuotes(ctx context.Context, filter quote.SearchFilter) ([]quote.SearchResult, error)
+}
+
+// GroupStore ...
+type GroupStore interface {
+	CreateGroup(ctx context.Context, name string) (*recipient.Group, error)
+	ListGroups(ctx context.Context) ([]recipient.Group, error)
+	AddMember(ctx context.Context, groupID, recipientID int) error
+	RemoveMember(ctx context.Context, groupID, recipientID int) error
+	GroupRecipients(ctx context.Context, group string) ([]recipient.Recipient, error)
+}
+
+// TagStore keeps free-form tags on recipients.
+type TagStore interface {
+	AddTag(ctx context.Context, recipientID int, tag string) error
+	RemoveTag(ctx context.Context, recipientID int, tag string) error
+	ListTags(ctx context.Context, recipientID int) ([]string, error)
+	TaggedRecipients(ctx context.Context, tags []string) ([]recipient.Recipient, error)
+}
+
+// FavoriteStore ...
+type FavoriteStore interface {
+	AddFavorite(ctx context.Context, recipientID, quoteID int) (*recipient.Favorite, error)
+	ListFavorites(ctx context.Context, recipientID int) ([]recipient.Favorite, error)
+}
+
+// SuppressionStore is the list of emails that unsubscribed.
+type SuppressionStore interface {
+	Suppress(ctx context.Context, email string) error
+	Suppressed(ctx context.Context, emails []string) (map[string]bool, error)
+}
+
+// RecipientRepository is everything the server persists about recipients.
+// recipient.Persistence keeps it in Postgres, memstore.Recipients in memory.
+type RecipientRepository interface {
+	RecipientStore
+	GroupStore
+	TagStore
+	FavoriteStore
+	SuppressionStore
+}
+
+// QuoteRepository is the history of served quotes. quote.Persistence keeps it
+// in Postgres, memstore.Quotes in memory.
+type QuoteRepository interface {
+	quote.Store
+	QuoteStore
+}
+
+// Database is the connection behind the repositories.
+type Database interface {
+	Pinger
+	Migrate(ctx context.Context) error
+	Close() error
+}
+
+// LocalQuoteStore ...
+type LocalQuoteStore interface {
+	AddLocal(ctx context.Context, q quote.Quote) (*quote.Record, error)
+	ListLocal(ctx context.Context, lang string) ([]quote.Record, error)
+	RemoveLocal(ctx context.Context, id int) error
+}
+
+// MailSender ...
+type MailSender interface {
+	Send(msg mailer.Message) error
+}
+
+// Outbox queues emails to be sent in the background.
+type Outbox interface {
+	Enqueue(ctx context.Context, msgs []mailer.Message) ([]outbox.Entry, error)
+}
+
+// Server serves the InspiringQuotes HTTP API.
+type Server struct {
+	router            *mux.Router
+	quoteGenerator    QuoteGenerator
+	recipientsFetcher RecipientFetcher
+	recipientStore    RecipientStore
+	groupStore        GroupStore
+	tagStore          TagStore
+	favoriteStore     FavoriteStore
+	quoteProvider     string
+	mailSender        MailSender
+	channels          map[string]channel.Sender
+	quoteStore        QuoteStore
+	localQuotes       LocalQuoteStore
+	persistence       Database
+	quoteCache        *quote.Cached
+	breakers          map[string]*quote.Breaker
+	providerHealth    *quote.HealthTracker
+	logger            zerolog.Logger
+	accessLog         *accessLogger
+	reporter          errtrack.Reporter
+	readinessChecks   []*readinessCheck
+	dailyLanguages    []string
+	dailyInterval     time.Duration
+	scheduler         *scheduler.Scheduler
+	outbox            Outbox
+	transactor        Transactor
+	outboxWorker      *outbox.Worker
+	outboxInterval    time.Duration
+	outboxDrainer     *scheduler.Scheduler
+	idempotency       idempotency.Store
+	idempotencyTTL    time.Duration
+	idempotencyPurger *scheduler.Scheduler
+	hooks             webhook.Store
+	hookWorker        *webhook.Worker
+	hookInterval      time.Duration
+	hookDrainer       *scheduler.Scheduler
+	replicas          *replica.Set
+	replicaInterval   time.Duration
+	replicaChecker    *scheduler.Scheduler
+	workers           *worker.Pool
+	workerCount       int
+	limiter           *ratelimit.Limiter
+	languages         []string
+	restrictLanguages bool
+	middleware        []mux.MiddlewareFunc
+	signer            *verify.Signer
+	unsubscriber      *verify.Signer
+	suppressions      SuppressionStore
+	templates         *templates.Set
+	deliveries        delivery.Store
+	ratings           rating.Store
+	trendingWindow    time.Duration
+	webhookProvider   string
+	webhookSecret     string
+	adminToken        string
+	config            *config.Config
+	features          *featureflag.Registry
+	publicURL         string
+	streamLimit       int
+	feedItems         int
+	authorAttempts    int
+	hstsMaxAge        time.Duration
+	cors              *CORS
+	compress          bool
+	compressMinSize   int
+	requestTimeout    time.Duration
+	debug             bool
+	maxBodySize       int64
+	inFlight          atomic.Int64
+	maxInFlight       int64
+	shedRetryAfter    time.Duration
+	sheddable         atomic.Int64
+	shed              atomic.Int64
+	providerBudget    float64
+	activeStreams     atomic.Int64
+	shutdown          chan struct{}
+	closeOnce         sync.Once
+	spec              *openapi.Spec
+	auditLog          audit.Store
+	translator        translate.Translator
+	defaultLang       string
+	langFallbacks     map[string]string
+	apiKeys           apiKeys
+	keyRotationGrace  time.Duration
+	verifiers         []*auth.Verifier
+	oauthClients      oauth.Store
+	tokenIssuer       *auth.Issuer
+	rotationGrace     time.Duration
+	maintenance       atomic.Pointer[Maintenance]
+	providers         *quote.Multi
+	loadConfig        func() (*config.Config, error)
+	now               func() time.Time
+	binder            *bind.Binder
+
+	// reloadMu guards config and quoteProvider, which Reload changes.
+	reloadMu sync.RWMutex
+}
+
+// NewFromConfig wires all server dependencies from cfg. The opts are applied
+// after the options cfg makes.
+func NewFromConfig(cfg *config.Config, logger zerolog.Logger, opts ...Option) (*Server, error) {
+	if cfg.StartupMode != "fail-fast" && cfg.StartupMode != "degrade" {
+		return nil, fmt.Errorf("server: unknown startup mode %q, expected fail-fast or degrade", cfg.StartupMode)
+	}
+	switch cfg.ProviderRecording {
+	case "off", string(quote.ModeRecord), string(quote.ModeReplay):
+	default:
+		return nil, fmt.Errorf("server: unknown provider recording %q, expected off, record or replay", cfg.ProviderRecording)
+	}
+
+	repos, err := newRepositories(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	names := append([]string{cfg.Provider}, cfg.Fallbacks...)
+	breakers := make(map[string]*quote.Breaker)
+
+	var provider quote.Generator
+	var chain []quote.Named
+	languages := make(map[string]bool)
+	restrictLanguages := true
+	for _, name := range names {
+		p, err := repos.provider(cfg, name)
+		if err != nil {
+			return nil, err
+		}
+		if provider == nil {
+			provider = p
+		}
+
+		langs := quote.SupportedLanguages(p)
+		if langs == nil {
+			restrictLanguages = false
+		}
+		for _, lang := range langs {
+			languages[lang] = true
+		}
+
+		if cfg.RetryAttempts > 1 {
+			p = &quote.Retrying{
+				Inner:       p,
+				MaxAttempts: cfg.RetryAttempts,
+				BaseDelay:   cfg.RetryBaseDelay.Duration,
+				MaxDelay:    cfg.RetryMaxDelay.Duration,
+			}
+		}
+
+		if cfg.BreakerFailures > 0 {
+			breaker := &quote.Breaker{
+				Inner:     p,
+				Threshold: cfg.BreakerFailures,
+				Cooldown:  cfg.BreakerCooldown.Duration,
+				// With fallbacks configured, failing over beats serving stale quotes.
+				ServeStale: len(names) == 1,
+			}
+			breakers[name] = breaker
+			p = breaker
+		}
+
+		chain = append(chain, quote.Named{Name: name, Generator: p})
+	}
+
+	health := quote.NewHealthTracker(quote.DefaultHealthSamples, cfg.HealthWindow.Duration)
+	multi := &quote.Multi{Providers: chain, Health: health}
+	var quoteGenerator quote.Generator = multi
+
+	screener, err := newScreener(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if screener != nil {
+		// Inside dedup, so rejected quotes are not remembered as served.
+		quoteGenerator = &quote.Filtered{
+			Inner:    quoteGenerator,
+			Screener: screener,
+			Attempts: cfg.FilterAttempts,
+			OnDecision: func(q quote.Quote, d filter.Decision) {
+				if d.Allowed {
+					logger.Debug().Str("provider", q.Provider).Msg("quote accepted by filter")
+					return
+				}
+				logger.Warn().
+					Str("provider", q.Provider).
+					Str("author", q.Author).
+					Str("reason", d.Reason).
+					Msg("quote rejected by filter")
+			},
+		}
+	}
+
+	if cfg.DedupWindow.Duration > 0 {
+		// Inside the cache, so a cached quote is still served for its whole TTL.
+		quoteGenerator = &quote.Deduplicated{
+			Inner:    quoteGenerator,
+			Seen:     &quote.SeenMemory{Window: cfg.DedupWindow.Duration},
+			Window:   cfg.DedupWindow.Duration,
+			Attempts: cfg.DedupAttempts,
+		}
+	}
+
+	stores, err := newSharedStores(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	var quoteCache *quote.Cached
+	if cfg.CacheTTL.Duration > 0 {
+		quoteCache = &quote.Cached{
+			Inner: quoteGenerator,
+			TTL:   cfg.CacheTTL.Duration,
+			Cache: stores.cache,
+		}
+		quoteGenerator = quoteCache
+	}
+
+	quoteGenerator = &quote.Recording{
+		Inner: quoteGenerator,
+		Store: repos.quotes,
+		OnError: func(err error) {
+			logger.Error().Err(err).Msg("saving quote history")
+		},
+	}
+
+	sender, err := newMailSender(cfg)
+	if err != nil {
+		return nil, err
+	}
+	mailSender := &delivery.Tracker{
+		Sender: sender,
+		Store:  repos.deliveries,
+		OnError: func(err error) {
+			logger.Error().Err(err).Msg("recording delivery")
+		},
+	}
+
+	opts = append([]Option{
+		WithLogger(logger),
+		WithConfig(cfg),
+		WithQuoteGenerator(quoteGenerator),
+		WithQuoteProvider(cfg.Provider),
+		WithRecipientStore(repos.recipients),
+		WithGroupStore(repos.recipients),
+		WithTagStore(repos.recipients),
+		WithFavoriteStore(repos.recipients),
+		WithSuppressionStore(repos.recipients),
+		WithMailSender(mailSender),
+		WithQuoteStore(repos.quotes),
+		WithLocalQuotes(repos.local),
+		WithBreakers(breakers),
+		WithProviderHealth(health),
+		WithProviders(multi),
+		WithAuditLog(repos.audit),
+		WithDeliveries(repos.deliveries),
+		WithRatings(repos.ratings, cfg.TrendingWindow.Duration),
+		WithLanguages(sortedKeys(languages), restrictLanguages),
+		WithDailyLanguages(cfg.DailyLanguages...),
+		WithDefaultLang(cfg.DefaultLang),
+		WithLangFallbacks(cfg.LangFallbacks...),
+		WithFeatures(cfg.Features...),
+		WithDailyInterval(cfg.DailyInterval.Duration),
+	}, opts...)
+
+	if cfg.AdminToken != "" {
+		opts = append(opts, WithAdminToken(cfg.AdminToken))
+	} else if cfg.JWT.Issuer == "" {
+		logger.Warn().Msg("no admin token configured, the /admin routes are off")
+	}
+
+	if cfg.JWT.Issuer != "" {
+		if cfg.JWT.JWKSURL == "" {
+			return nil, fmt.Errorf("server: JWT issuer %q needs a JWKS URL", cfg.JWT.Issuer)
+		}
+		opts = append(opts, WithJWT(&auth.Verifier{
+			Issuer:     cfg.JWT.Issuer,
+			Audience:   cfg.JWT.Audience,
+			RolesClaim: cfg.JWT.RolesClaim,
+			Keys:       auth.NewJWKS(cfg.JWT.JWKSURL, &http.Client{Timeout: cfg.ProviderTimeout.Duration}),
+			Leeway:     time.Minute,
+		}))
+	}
+
+	if cfg.OAuth.Enabled {
+		issuer, err := newTokenIssuer(cfg, logger)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithOAuth(repos.oauth, issuer), WithSecretRotation(cfg.OAuth.RotationGrace.Duration))
+	}
+
+	if cfg.Debug {
+		opts = append(opts, WithDebug())
+	}
+
+	if cfg.Sentry.DSN != "" {
+		sentry, err := errtrack.NewSentry(cfg.Sentry.DSN)
+		if err != nil {
+			return nil, err
+		}
+		sentry.Environment = cfg.Sentry.Environment
+		sentry.Release = cfg.Sentry.Release
+		sentry.Client = &http.Client{Timeout: cfg.ProviderTimeout.Duration}
+		sentry.OnError = func(err error) {
+			logger.Warn().Err(err).Msg("reporting error")
+		}
+		opts = append(opts, WithErrorReporter(sentry))
+	}
+
+	if cfg.AccessLog != "" && cfg.AccessLog != "off" {
+		opts = append(opts, WithAccessLog(AccessLog{Format: cfg.AccessLog, Output: os.Stdout, SampleRate: cfg.AccessLogSample}))
+	}
+
+	if cfg.EmailWebhookSecret != "" {
+		opts = append(opts, WithEmailWebhook(cfg.Mailer, cfg.EmailWebhookSecret))
+	}
+
+	if cfg.TemplatesDir != "" {
+		set, err := templates.Dir(cfg.TemplatesDir)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, WithTemplates(set))
+	}
+
+	if repos.tx != nil {
+		opts = append(opts, WithTransactor(repos.tx))
+	}
+
+	if repos.persistence != nil {
+		opts = append(opts, WithPersistence(repos.persistence), WithReadinessCheck(cfg.Database, repos.persistence))
+	}
+
+	if repos.replicas != nil {
+		opts = append(opts, WithReplicas(repos.replicas, cfg.DBReplicaCheck.Duration))
+	} else if len(cfg.DBReplicaHosts) > 0 {
+		logger.Warn().Str("database", cfg.Database).Msg("read replicas are only used with postgres")
+	}
+
+	if quoteCache != nil {
+		opts = append(opts, WithQuoteCache(quoteCache))
+	}
+
+	if stores.pinger != nil {
+		opts = append(opts, WithReadinessCheck(cfg.Store, stores.pinger))
+	}
+
+	if pinger, ok := provider.(Pinger); ok && cfg.ProbeProvider {
+		opts = append(opts, WithReadinessCheck(cfg.Provider, pinger))
+	}
+
+	verifyKey := []byte(cfg.VerifyKey)
+	if len(verifyKey) == 0 {
+		logger.Warn().Msg("no verification key configured, verification links will not survive a restart")
+		if verifyKey, err = verify.RandomKey(); err != nil {
+			return nil, err
+		}
+	}
+	opts = append(opts, WithVerification(verify.NewSigner(verifyKey, cfg.VerifyTTL.Duration), cfg.PublicURL))
+
+	opts = append(opts, WithRequestTimeout(cfg.RequestTimeout.Duration, cfg.ProviderBudget), WithMaxBodySize(cfg.MaxBodyBytes))
+
+	if cfg.Compression {
+		opts = append(opts, WithCompression(cfg.CompressionMinSize))
+	}
+
+	if len(cfg.CORSOrigins) > 0 {
+		opts = append(opts, WithCORS(CORS{
+			Origins: cfg.CORSOrigins,
+			Methods: cfg.CORSMethods,
+			Headers: cfg.CORSHeaders,
+			MaxAge:  cfg.CORSMaxAge.Duration,
+		}))
+	}
+
+	if cfg.Translate.URL != "" {
+		var translator translate.Translator = &translate.LibreTranslate{
+			URL:    cfg.Translate.URL,
+			APIKey: cfg.Translate.APIKey,
+			Client: &http.Client{Timeout: cfg.ProviderTimeout.Duration},
+		}
+		if cfg.Translate.CacheTTL.Duration > 0 {
+			translator = &translate.Cached{Inner: translator, Cache: stores.cache, TTL: cfg.Translate.CacheTTL.Duration}
+		}
+		opts = append(opts, WithTranslator(translator))
+	}
+
+	if cfg.TLSEnabled() {
+		opts = append(opts, WithHSTS(cfg.TLS.HSTSMaxAge.Duration))
+	}
+
+	opts = append(opts, WithStreamLimit(cfg.StreamLimit), WithAuthorAttempts(cfg.AuthorAttempts), WithWorkers(cfg.Workers))
+	opts = append(opts, WithFeedItems(cfg.FeedItems))
+
+	if cfg.OutboxInterval.Duration > 0 {
+		opts = append(opts, WithOutbox(repos.outbox, &outbox.Worker{
+			Store:       repos.outbox,
+			Sender:      mailSender,
+			MaxAttempts: cfg.OutboxMaxAttempts,
+		}, cfg.OutboxInterval.Duration))
+	}
+
+	if cfg.WebhookInterval.Duration > 0 {
+		opts = append(opts, WithWebhooks(repos.webhooks, &webhook.Worker{
+			Queue:       repos.webhooks,
+			Client:      &http.Client{Timeout: webhook.DefaultTimeout},
+			MaxAttempts: cfg.WebhookMaxAttempts,
+		}, cfg.WebhookInterval.Duration))
+	}
+
+	opts = append(opts, WithChannel(recipient.ChannelSlack, &channel.Slack{
+		Client: &http.Client{Timeout: cfg.ProviderTimeout.Duration},
+	}))
+	if cfg.Telegram.Token != "" {
+		opts = append(opts, WithChannel(recipient.ChannelTelegram, &channel.Telegram{
+			Token:  cfg.Telegram.Token,
+			URL:    cfg.Telegram.URL,
+			Client: &http.Client{Timeout: cfg.ProviderTimeout.Duration},
+		}))
+	}
+
+	if cfg.IdempotencyTTL.Duration > 0 {
+		opts = append(opts, WithIdempotency(repos.keys, cfg.IdempotencyTTL.Duration))
+	}
+
+	// Installed at a rate of 0 too, so a reload can turn it on.
+	opts = append(opts, WithRateLimiter(&ratelimit.Limiter{
+		Store: stores.rateLimit,
+		Rate:  cfg.RateLimit,
+		Burst: cfg.RateLimitBurst,
+	}))
+
+	if len(cfg.TenantKeys) > 0 {
+		opts = append(opts, WithTenantKeys(cfg.TenantKeys...), WithKeyRotation(cfg.KeyRotationGrace.Duration))
+	}
+	if cfg.MaxInFlight > 0 {
+		opts = append(opts, WithMaxInFlight(cfg.MaxInFlight, cfg.ShedRetryAfter.Duration))
+	}
+
+	return NewServer(opts...)
+}
+
+// localQuotes is the local provider together with the store admins add its
+// quotes through.
+type localQuotes interface {
+	quote.Generator
+	LocalQuoteStore
+}
+
+// outboxStore is an outbox the worker can drain.
+type outboxStore interface {
+	Outbox
+	outbox.Store
+}
+
+// repositories hold what the server persists.
+type repositories struct {
+	recipients RecipientRepository
+	quotes     QuoteRepository
+	local      localQuotes
+	outbox     outboxStore
+	audit      audit.Store
+	deliveries delivery.Store
+	keys       idempotency.Store
+	ratings    rating.Store
+	webhooks   webhook.Store
+	oauth      oauth.Store
+	// tx binds the stores to a transaction, nil when running in memory.
+	tx Transactor
+	// persistence is the database connection, nil when running in memory.
+	persistence Database
+	// db is handed to providers that read the database themselves.
+	db *sql.DB
+	// replicas are the read replicas of db, nil without any.
+	replicas *replica.Set
+}
+
+// newRepositories builds the repositories selected by cfg.Database. In memory,
+// nothing survives a restart.
+func newRepositories(cfg *config.Config) (repositories, error) {
+	switch cfg.Database {
+	case "postgres":
+		p, err := recipient.NewPersistence(cfg.DBHost, cfg.DBName, recipient.Pool{
+			MaxOpenConns:    cfg.DBMaxOpenConns,
+			MaxIdleConns:    cfg.DBMaxIdleConns,
+			ConnMaxIdleTime: cfg.DBConnMaxIdleTime.Duration,
+			ConnMaxLifetime: cfg.DBConnMaxLifetime.Duration,
+		})
+		if err != nil {
+			return repositories{}, err
+		}
+		replicas, err := openReplicas(cfg, p.DB)
+		if err != nil {
+			p.Close()
+			return repositories{}, err
+		}
+		p.Replicas = replicas
+		box, auditLog := &outbox.Persistence{DB: p.DB}, &audit.Persistence{DB: p.DB}
+		return repositories{
+			recipients: p,
+			quotes:     &quote.Persistence{DB: p.DB, Replicas: replicas},
+			local:      &quote.Local{DB: p.DB},
+			outbox:     box,
+			audit:      auditLog,
+			deliveries: &delivery.Persistence{DB: p.DB},
+			keys:       &idempotency.Persistence{DB: p.DB},
+			ratings:    &rating.Persistence{DB: p.DB},
+			webhooks:   &webhook.Persistence{DB: p.DB},
+			oauth:      &oauth.Persistence{DB: p.DB},
+			tx: sqlTransactor{db: p.DB, bind: func(tx *sql.Tx) TxRepositories {
+				return TxRepositories{Recipients: p.WithTx(tx), Audit: auditLog.WithTx(tx), Outbox: box.WithTx(tx)}
+			}},
+			persistence: p,
+			db:          p.DB,
+			replicas:    replicas,
+		}, nil
+	case "sqlite":
+		db, err := sqlitestore.Open(cfg.SQLitePath)
+		if err != nil {
+			return repositories{}, err
+		}
+		recipients, box, auditLog := sqlitestore.NewRecipients(db), sqlitestore.NewOutbox(db), &audit.Persistence{DB: db.DB}
+		return repositories{
+			recipients: recipients,
+			quotes:     sqlitestore.NewQuotes(db),
+			local:      sqlitestore.NewLocalQuotes(db),
+			outbox:     box,
+			audit:      auditLog,
+			deliveries: &delivery.Persistence{DB: db.DB},
+			keys:       &idempotency.Persistence{DB: db.DB},
+			ratings:    &rating.Persistence{DB: db.DB},
+			webhooks:   &webhook.Persistence{DB: db.DB},
+			oauth:      &oauth.Persistence{DB: db.DB},
+			tx: sqlTransactor{db: db.DB, bind: func(tx *sql.Tx) TxRepositories {
+				return TxRepositories{Recipients: recipients.WithTx(tx), Audit: auditLog.WithTx(tx), Outbox: box.WithTx(tx)}
+			}},
+			persistence: db,
+			db:          db.DB,
+		}, nil
+	case "memory":
+		quotes := memstore.NewQuotes()
+		return repositories{
+			recipients: memstore.NewRecipients(quotes),
+			quotes:     quotes,
+			local:      memstore.NewLocalQuotes(),
+			outbox:     memstore.NewOutbox(),
+			audit:      memstore.NewAudit(),
+			deliveries: memstore.NewDeliveries(),
+			keys:       memstore.NewIdempotency(),
+			ratings:    memstore.NewRatings(quotes),
+			webhooks:   memstore.NewWebhooks(),
+			oauth:      memstore.NewOAuth(),
+		}, nil
+	default:
+		return repositories{}, fmt.Errorf("server: unknown database %q, expected postgres, sqlite or memory", cfg.Database)
+	}
+}
+
+// openReplicas opens the read replicas of primary in cfg.DBReplicaHosts,
+// returning nil without any.
+func openReplicas(cfg *config.Config, primary *sql.DB) (*replica.Set, error) {
+	if len(cfg.DBReplicaHosts) == 0 {
+		return nil, nil
+	}
+
+	set := replica.New(primary)
+	for _, host := range cfg.DBReplicaHosts {
+		db, err := recipient.OpenDB(host, cfg.DBName, recipient.Pool{
+			MaxOpenConns:    cfg.DBMaxOpenConns,
+			MaxIdleConns:    cfg.DBMaxIdleConns,
+			ConnMaxIdleTime: cfg.DBConnMaxIdleTime.Duration,
+			ConnMaxLifetime: cfg.DBConnMaxLifetime.Duration,
+		})
+		if err != nil {
+			set.Close()
+			return nil, err
+		}
+		set.Add(host, db)
+	}
+	return set, nil
+}
+
+// provider builds the named quote provider. The local provider serves the
+// quotes in r.local, wherever they live.
+func (r repositories) provider(cfg *config.Config, name string) (quote.Generator, error) {
+	if name == "local" {
+		return r.local, nil
+	}
+
+	return quote.New(name, providerOptions(cfg, name, r.db))
+}
+
+// sharedStores back the quote cache and the rate limiter.
+type sharedStores struct {
+	cache     quote.Cache
+	rateLimit ratelimit.Store
+	// pinger checks the stores when they live outside the process.
+	pinger Pinger
+}
+
+// newSharedStores builds the stores selected by cfg.Store. In memory, every
+// instance caches and limits on its own; in Redis, instances share state.
+func newSharedStores(cfg *config.Config, logger zerolog.Logger) (sharedStores, error) {
+	switch cfg.Store {
+	case "memory":
+		return sharedStores{
+			cache:     quote.NewLRU(cfg.CacheSize),
+			rateLimit: ratelimit.NewMemory(),
+		}, nil
+	case "redis":
+		client := redis.NewClient(&redis.Options{
+			Addr:     cfg.Redis.Addr,
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+		})
+		cache := &redisstore.Cache{
+			Client: client,
+			Prefix: "quotes:cache:",
+			OnError: func(err error) {
+				logger.Warn().Err(err).Msg("redis quote cache")
+			},
+		}
+		return sharedStores{
+			cache:     cache,
+			rateLimit: &redisstore.RateLimit{Client: client, Prefix: "quotes:ratelimit:"},
+			pinger:    cache,
+		}, nil
+	default:
+		return sharedStores{}, fmt.Errorf("server: unknown store %q, expected memory or redis", cfg.Store)
+	}
+}
+
+// newMailSender builds the mailer selected by cfg.Mailer.
+func newMailSender(cfg *config.Config) (mailer.Sender, error) {
+	switch cfg.Mailer {
+	case "smtp":
+		return &mailer.SMTP{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+			From:     cfg.SMTP.From,
+		}, nil
+	case "sendgrid":
+		return &mailer.SendGrid{
+			APIKey: cfg.SendGrid.APIKey,
+			From:   cfg.SMTP.From,
+			Client: &http.Client{Timeout: cfg.ProviderTimeout.Duration},
+		}, nil
+	case "ses":
+		return &mailer.SES{
+			Region:          cfg.SES.Region,
+			AccessKeyID:     cfg.SES.AccessKeyID,
+			SecretAccessKey: cfg.SES.SecretAccessKey,
+			From:            cfg.SMTP.From,
+			Client:          &http.Client{Timeout: cfg.ProviderTimeout.Duration},
+		}, nil
+	default:
+		return nil, fmt.Errorf("server: unknown mailer %q, expected smtp, sendgrid or ses", cfg.Mailer)
+	}
+}
+
+// newScreener builds the quote filter configured by cfg, nil when there is
+// nothing to screen against.
+func newScreener(cfg *config.Config) (filter.Screener, error) {
+	var chain filter.Chain
+	if len(cfg.Blocklist) > 0 {
+		chain = append(chain, filter.NewBlocklist(cfg.Blocklist...))
+	}
+	if cfg.BlocklistFile != "" {
+		blocklist, err := filter.LoadBlocklist(cfg.BlocklistFile)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, blocklist)
+	}
+	if cfg.ModerationURL != "" {
+		chain = append(chain, &filter.Moderation{
+			URL:    cfg.ModerationURL,
+			Token:  cfg.ModerationToken,
+			Client: &http.Client{Timeout: cfg.ProviderTimeout.Duration},
+		})
+	}
+
+	if len(chain) == 0 {
+		return nil, nil
+	}
+	return chain, nil
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// providerOptions returns the options to build the named quote provider with.
+// With cfg.ProviderRecording on, its calls are recorded to or replayed from
+// cfg.ProviderFixtures.
+func providerOptions(cfg *config.Config, name string, db *sql.DB) quote.Options {
+	var client quote.HTTPWrapper = &http.Client{
+		Timeout: cfg.ProviderTimeout.Duration,
+	}
+	if cfg.ProviderRecording != "" && cfg.ProviderRecording != "off" {
+		client = &quote.Recorder{
+			Mode:   quote.RecordingMode(cfg.ProviderRecording),
+			Dir:    filepath.Join(cfg.ProviderFixtures, name),
+			Client: client,
+		}
+	}
+	opts := quote.Options{
+		Client: client,
+		DB:     db,
+	}
+
+	switch name {
+	case "forismatic":
+		opts.URL, opts.Format = cfg.ForismaticURL, cfg.ForismaticFormat
+	case "zenquotes":
+		opts.URL = cfg.ZenQuotesURL
+	case "quotable":
+		opts.URL = cfg.QuotableURL
+	}
+
+	return opts
+}
+
+// Handler returns the HTTP handler serving the API.
+func (s *Server) Handler() http.Handler {
+	return s.router
+}
+
+// Migrate brings the database schema up to date.
+func (s *Server) Migrate(ctx context.Context) error {
+	if s.persistence == nil {
+		return nil
+	}
+	return s.persistence.Migrate(ctx)
+}
+
+// Start starts background work.
+func (s *Server) Start() {
+	if s.scheduler != nil {
+		s.scheduler.Start()
+	}
+	if s.outboxDrainer != nil {
+		s.outboxDrainer.Start()
+	}
+	if s.idempotencyPurger != nil {
+		s.idempotencyPurger.Start()
+	}
+	if s.hookDrainer != nil {
+		s.hookDrainer.Start()
+	}
+	if s.replicaChecker != nil {
+		s.replicaChecker.Start()
+	}
+}
+
+// EndStreams ends open quote streams and event feeds. Register it with
+// http.Server.RegisterOnShutdown so a graceful shutdown need not wait them out.
+func (s *Server) EndStreams() {
+	s.closeOnce.Do(func() {
+		if s.shutdown != nil {
+			close(s.shutdown)
+		}
+	})
+}
+
+// Shutdown stops scheduling background work, ends open quote streams, waits
+// for running background jobs and error reports until ctx is done and
+// releases the database.
+func (s *Server) Shutdown(ctx context.Context) error {
+	if s.scheduler != nil {
+		s.scheduler.Stop()
+	}
+	if s.outboxDrainer != nil {
+		s.outboxDrainer.Stop()
+	}
+	if s.idempotencyPurger != nil {
+		s.idempotencyPurger.Stop()
+	}
+	if s.hookDrainer != nil {
+		s.hookDrainer.Stop()
+	}
+	if s.replicaChecker != nil {
+		s.replicaChecker.Stop()
+	}
+
+	s.EndStreams()
+
+	if err := s.workers.Shutdown(ctx); err != nil {
+		s.logger.Warn().Err(err).Msg("background jobs did not finish in time")
+	}
+	if s.reporter != nil {
+		if err := s.reporter.Flush(ctx); err != nil {
+			s.logger.Warn().Err(err).Msg("errors were not all reported in time")
+		}
+	}
+
+	if s.replicas != nil {
+		if err := s.replicas.Close(); err != nil {
+			s.logger.Warn().Err(err).Msg("closing read replicas")
+		}
+	}
+	if s.persistence == nil {
+		return nil
+	}
+	return s.persistence.Close()
+}
+
+// Close is Shutdown without a deadline.
+func (s *Server) Close() error {
+	return s.Shutdown(context.Background())
+}
+
+// schedule returns a scheduler running job on the worker pool every interval.
+// Failures are logged by the pool.
+func (s *Server) schedule(name string, interval time.Duration, job scheduler.Job) *scheduler.Scheduler {
+	return &scheduler.Scheduler{
+		Interval: interval,
+		Job: func(ctx context.Context) error {
+			return s.workers.Run(ctx, worker.Func(name, job))
+		},
+	}
+}
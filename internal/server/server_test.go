@@ -0,0 +1,300 @@
+// internal/server/server_test.go
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jeniok12/golang-tdd-example/internal/config"
+	"github.com/jeniok12/golang-tdd-example/internal/memstore"
+	"github.com/jeniok12/golang-tdd-example/internal/pgtest"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/jeniok12/golang-tdd-example/mailer"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+var mockForismaticServiceResponse = map[string]interface{}{
+	"quoteText":   "Bla Bla Bla",
+	"quoteAuthor": "Bob",
+}
+
+var expectedQuote = map[string]interface{}{
+	"quoteAuthor": "Bob",
+	"quoteText":   "Bla Bla Bla",
+	"lang":        "en",
+}
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Main(m))
+}
+
+// newRecipientRepository returns recipients kept in a Postgres schema of
+// their own or, when there is no Postgres to test against, in memory.
+func newRecipientRepository(t *testing.T) RecipientStore {
+	if !pgtest.Available() {
+		return memstore.NewRecipients(memstore.NewQuotes())
+	}
+	return recipient.NewPersistenceDB(pgtest.Open(t))
+}
+
+func TestQuoteAPI(t *testing.T) {
+	testCases := []struct {
+		name               string
+		lang               string
+		mockHTTPService    func() *httptest.Server
+		recipients         []recipient.Recipient
+		expectedStatus     int
+		expectedQuote      map[string]interface{}
+		expectedRecipients []string
+	}{
+		{
+			"SuccessResponseFromForismaticService",
+			"en",
+			func() *httptest.Server {
+				server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+					assert.Equal(t, http.MethodGet, req.Method, "Should have different request method")
+
+					assert.Equal(t, "getQuote", req.URL.Query().Get("method"), "Wrong method query param")
+					assert.Equal(t, "json", req.URL.Query().Get("format"), "Wrong method query param")
+					assert.Equal(t, "en", req.URL.Query().Get("lang"), "Wrong method query param")
+					assert.Equal(t, "12345", req.URL.Query().Get("key"), "Key should make the quote reproducible")
+
+					res, _ := json.Marshal(mockForismaticServiceResponse)
+					rw.WriteHeader(http.StatusOK)
+					rw.Write(res)
+				}))
+
+				return server
+			},
+			[]recipient.Recipient{
+				{Name: "user1", Email: "user1@testmail.com"},
+				{Name: "user2", Email: "user2@testmail.com"},
+				{Name: "user3", Email: "user3@testmail.com"},
+			},
+			http.StatusOK,
+			expectedQuote,
+			[]string{"user1@testmail.com", "user2@testmail.com", "user3@testmail.com"},
+		},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			s := tC.mockHTTPService()
+			defer s.Close()
+
+			recipients := newRecipientRepository(t)
+			ctx := context.Background()
+			for _, r := range tC.recipients {
+				created, err := recipients.Create(ctx, r)
+				require.NoError(t, err, "Should have no error when creating a recipient")
+				_, err = recipients.MarkVerified(ctx, created.ID, created.Email)
+				require.NoError(t, err, "Should have no error when verifying a recipient")
+			}
+
+			svr := newTestServer(t,
+				WithQuoteGenerator(&quote.Forismatic{URL: s.URL, Client: s.Client()}),
+				WithRecipientsFetcher(recipients),
+			)
+
+			req, _ := http.NewRequest("GET", "/quote", nil)
+			req.URL.RawQuery = fmt.Sprintf("lang=%s&key=12345", tC.lang)
+			response := makeHTTPCall(svr.router, req)
+
+			var resp struct {
+				Quote      map[string]interface{} `json:"quote"`
+				Recipients []recipient.Recipient  `json:"recipients"`
+			}
+			_ = json.Unmarshal(response.Body.Bytes(), &resp)
+
+			var emails []string
+			for _, r := range resp.Recipients {
+				emails = append(emails, r.Email)
+			}
+
+			assert.Equal(t, tC.expectedStatus, response.Code, "Response HTTP status in different than expected")
+			assert.Equal(t, tC.expectedQuote, resp.Quote, "Response quote in different than expected")
+			assert.ElementsMatch(t, tC.expectedRecipients, emails, "Response recipients in different than expected")
+		})
+	}
+}
+
+func makeHTTPCall(router *mux.Router, req *http.Request) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	return rr
+}
+
+func TestNewMailSender(t *testing.T) {
+	testCases := []struct {
+		name               string
+		mailer             string
+		expectedSender     mailer.Sender
+		expectedToGetError bool
+	}{
+		{"SMTP", "smtp", &mailer.SMTP{}, false},
+		{"SendGrid", "sendgrid", &mailer.SendGrid{}, false},
+		{"SES", "ses", &mailer.SES{}, false},
+		{"Unknown", "mailgun", nil, true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Mailer = tC.mailer
+
+			sender, err := newMailSender(cfg)
+
+			if tC.expectedToGetError {
+				assert.Error(t, err, "Got no error when expected")
+				return
+			}
+			assert.NoError(t, err, "Got error when not expected")
+			assert.IsType(t, tC.expectedSender, sender)
+		})
+	}
+}
+
+func TestNewScreener(t *testing.T) {
+	testCases := []struct {
+		name               string
+		configure          func(cfg *config.Config)
+		expectedScreeners  int
+		expectedToGetError bool
+	}{
+		{"Nothing", func(cfg *config.Config) {}, 0, false},
+		{"Blocklist", func(cfg *config.Config) { cfg.Blocklist = []string{"damn"} }, 1, false},
+		{
+			"BlocklistAndModeration",
+			func(cfg *config.Config) {
+				cfg.Blocklist = []string{"damn"}
+				cfg.ModerationURL = "http://localhost/moderations"
+			},
+			2,
+			false,
+		},
+		{"MissingFile", func(cfg *config.Config) { cfg.BlocklistFile = "testdata/missing.txt" }, 0, true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			cfg := config.Default()
+			tC.configure(cfg)
+
+			screener, err := newScreener(cfg)
+
+			if tC.expectedToGetError {
+				assert.Error(t, err, "Got no error when expected")
+				return
+			}
+			assert.NoError(t, err, "Got error when not expected")
+			if tC.expectedScreeners == 0 {
+				assert.Nil(t, screener, "No filter should be built without a blocklist or moderation")
+				return
+			}
+			assert.Len(t, screener, tC.expectedScreeners)
+		})
+	}
+}
+
+func TestNewSharedStores(t *testing.T) {
+	testCases := []struct {
+		name               string
+		store              string
+		expectedPinger     bool
+		expectedToGetError bool
+	}{
+		{"Memory", "memory", false, false},
+		{"Redis", "redis", true, false},
+		{"Unknown", "memcached", false, true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Store = tC.store
+
+			stores, err := newSharedStores(cfg, zerolog.Nop())
+
+			if tC.expectedToGetError {
+				assert.Error(t, err, "Got no error when expected")
+				return
+			}
+			assert.NoError(t, err, "Got error when not expected")
+			assert.NotNil(t, stores.cache, "Cache store should be set")
+			assert.NotNil(t, stores.rateLimit, "Rate limit store should be set")
+			assert.Equal(t, tC.expectedPinger, stores.pinger != nil, "Only external stores should be probed")
+		})
+	}
+}
+
+func TestNewFromConfig_WithoutPostgres(t *testing.T) {
+	for _, database := range []string{"memory", "sqlite"} {
+		t.Run(database, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Database = database
+			cfg.SQLitePath = ":memory:"
+			cfg.Provider = "local"
+			cfg.AdminToken = testAdminToken
+
+			s, err := NewFromConfig(cfg, zerolog.Nop())
+			if !assert.NoError(t, err, "Got error when not expected") {
+				return
+			}
+			defer s.Close()
+			assert.NoError(t, s.Migrate(context.Background()), "Got error when not expected")
+
+			calls := []struct {
+				method         string
+				path           string
+				body           string
+				expectedStatus int
+			}{
+				{http.MethodPost, "/admin/local-quotes", `{"quoteText":"Bla Bla Bla","quoteAuthor":"Bob","lang":"en"}`, http.StatusCreated},
+				{http.MethodGet, "/quote?lang=en", "", http.StatusOK},
+				{http.MethodGet, "/quotes/history", "", http.StatusOK},
+				{http.MethodPost, "/recipients", `{"name":"user1","email":"user1@testmail.com"}`, http.StatusCreated},
+				{http.MethodPost, "/recipients", `{"name":"user2","email":"user1@testmail.com"}`, http.StatusConflict},
+				{http.MethodPost, "/recipients/1/favorites", `{"quoteId":1}`, http.StatusCreated},
+				{http.MethodGet, "/readyz", "", http.StatusOK},
+			}
+			for _, c := range calls {
+				req := asAdmin(httptest.NewRequest(c.method, c.path, strings.NewReader(c.body)))
+				req.Header.Set("Content-Type", "application/json")
+				rr := makeHTTPCall(s.router, req)
+
+				assert.Equal(t, c.expectedStatus, rr.Code, "Response HTTP status in different than expected for %s %s: %s", c.method, c.path, rr.Body)
+			}
+		})
+	}
+}
+
+func TestNewFromConfig_UnknownDatabase(t *testing.T) {
+	cfg := config.Default()
+	cfg.Database = "mysql"
+
+	_, err := NewFromConfig(cfg, zerolog.Nop())
+	assert.EqualError(t, err, `server: unknown database "mysql", expected postgres, sqlite or memory`)
+}
+
+func TestNewFromConfig_UnknownStartupMode(t *testing.T) {
+	cfg := config.Default()
+	cfg.StartupMode = "retry"
+
+	_, err := NewFromConfig(cfg, zerolog.Nop())
+	assert.EqualError(t, err, `server: unknown startup mode "retry", expected fail-fast or degrade`)
+}
+
+func TestNewFromConfig_UnknownProviderRecording(t *testing.T) {
+	cfg := config.Default()
+	cfg.ProviderRecording = "rewind"
+
+	_, err := NewFromConfig(cfg, zerolog.Nop())
+	assert.EqualError(t, err, `server: unknown provider recording "rewind", expected off, record or replay`)
+}
@@ -0,0 +1,52 @@
+// internal/server/shed.go
+
+package server
+
+import (
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// WithMaxInFlight turns requests away with a 503 while max others are being
+// served, asking clients to come back after retryAfter. Past the ceiling,
+// requests would only queue on the database pool and the quote provider.
+// Probes and long-lived requests, such as streams and exports, are not
+// counted.
+func WithMaxInFlight(max int, retryAfter time.Duration) Option {
+	return func(s *Server) error {
+		if max < 1 {
+			return fmt.Errorf("server: max in-flight requests must be positive, got %d", max)
+		}
+		if retryAfter <= 0 {
+			return fmt.Errorf("server: shedding retry after must be positive, got %s", retryAfter)
+		}
+		s.maxInFlight = int64(max)
+		s.shedRetryAfter = retryAfter
+		return nil
+	}
+}
+
+var errOverloaded = apierror.New(http.StatusServiceUnavailable, apierror.CodeUnavailable, "server is overloaded, retry later")
+
+func (s *Server) withLoadShedding(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.maxInFlight == 0 || unlimitedPaths[r.URL.Path] || untimed(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		defer s.sheddable.Add(-1)
+		if s.sheddable.Add(1) > s.maxInFlight {
+			s.shed.Add(1)
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(s.shedRetryAfter.Seconds()))))
+			apierror.Write(w, r, errOverloaded)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
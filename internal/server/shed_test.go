@@ -0,0 +1,66 @@
+// internal/server/shed_test.go
+
+package server
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// blocking holds requests to /admin/webhooks until release is closed,
+// telling entered about each one.
+func blocking(entered chan<- struct{}, release <-chan struct{}) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/admin/webhooks" {
+				entered <- struct{}{}
+				<-release
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestLoadShedding(t *testing.T) {
+	entered := make(chan struct{}, 1)
+	release := make(chan struct{})
+	svr := newTestServer(t, WithMaxInFlight(1, 1500*time.Millisecond), WithMiddleware(blocking(entered, release)))
+
+	done := make(chan int)
+	go func() {
+		done <- makeHTTPCall(svr.router, asAdmin(httptest.NewRequest(http.MethodGet, "/admin/webhooks", nil))).Code
+	}()
+	<-entered
+
+	rr := makeHTTPCall(svr.router, asAdmin(httptest.NewRequest(http.MethodGet, "/admin/webhooks", nil)))
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code, "Response HTTP status in different than expected")
+	assert.Equal(t, "2", rr.Header().Get("Retry-After"), "Retry-After should be rounded up to seconds")
+	var body apierror.Error
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body), "Response should be an error")
+	assert.Equal(t, apierror.CodeUnavailable, body.Code, "Error code is different than expected")
+
+	rr = makeHTTPCall(svr.router, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	assert.Equal(t, http.StatusOK, rr.Code, "Probes should never be shed")
+
+	close(release)
+	assert.Equal(t, http.StatusNotFound, <-done, "The request in flight should be served")
+	assert.Equal(t, int64(1), svr.shed.Load(), "Only the request over the ceiling should be shed")
+
+	rr = makeHTTPCall(svr.router, asAdmin(httptest.NewRequest(http.MethodGet, "/admin/webhooks", nil)))
+	assert.Equal(t, http.StatusNotFound, rr.Code, "Requests should be served again once below the ceiling")
+}
+
+func TestWithMaxInFlight_Invalid(t *testing.T) {
+	_, err := NewServer(WithMaxInFlight(0, time.Second))
+	assert.Error(t, err, "The ceiling should be positive")
+
+	_, err = NewServer(WithMaxInFlight(10, 0))
+	assert.Error(t, err, "Retry-After should be positive")
+}
@@ -0,0 +1,187 @@
+// internal/server/stream.go
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultStreamInterval = 10 * time.Second
+	minStreamInterval     = time.Second
+	maxStreamInterval     = time.Hour
+	streamWriteTimeout    = 10 * time.Second
+	maxStreamMessageSize  = 512
+)
+
+// StreamRequest is a message sent by a /quote/stream client. "ping" asks for a
+// quote right away, "lang" switches the language of the following quotes.
+type StreamRequest struct {
+	Type string `json:"type"`
+	Lang string `json:"lang,omitempty"`
+}
+
+// StreamMessage is a message pushed to a /quote/stream client, either a
+// quote or an error.
+type StreamMessage struct {
+	Type  string          `json:"type"`
+	Quote *quote.Quote    `json:"quote,omitempty"`
+	Error *apierror.Error `json:"error,omitempty"`
+}
+
+var streamUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// handleQuoteStream upgrades to a WebSocket that pushes a quote every
+// interval, and whenever the client asks for one.
+func (s *Server) handleQuoteStream() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		lang := query.Get("lang")
+		if err := s.checkLang(lang); err != nil {
+			s.writeError(w, r, err)
+			return
+		}
+
+		interval, err := streamInterval(query.Get("interval"))
+		if err != nil {
+			s.writeError(w, r, apierror.BadRequest(apierror.CodeBadRequest, err.Error()))
+			return
+		}
+
+		if !s.acquireStream() {
+			s.writeError(w, r, apierror.New(http.StatusServiceUnavailable, apierror.CodeUnavailable, "too many open streams"))
+			return
+		}
+		defer s.releaseStream()
+
+		// The upgrader answers failed handshakes itself.
+		conn, err := streamUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		s.streamQuotes(r.Context(), conn, lang, interval)
+	}
+}
+
+func streamInterval(value string) (time.Duration, error) {
+	if value == "" {
+		return defaultStreamInterval, nil
+	}
+
+	interval, err := time.ParseDuration(value)
+	if err != nil || interval < minStreamInterval || interval > maxStreamInterval {
+		return 0, fmt.Errorf("interval must be a duration between %s and %s", minStreamInterval, maxStreamInterval)
+	}
+	return interval, nil
+}
+
+func (s *Server) acquireStream() bool {
+	n := s.activeStreams.Add(1)
+	if s.streamLimit > 0 && n > int64(s.streamLimit) {
+		s.activeStreams.Add(-1)
+		return false
+	}
+	return true
+}
+
+func (s *Server) releaseStream() {
+	s.activeStreams.Add(-1)
+}
+
+// streamQuotes runs a stream until the client goes away, ctx is done or the
+// server shuts down. Quotes are fetched within ctx, the context of the
+// request, so they are asked for its tenant. Only this goroutine writes to
+// conn.
+func (s *Server) streamQuotes(ctx context.Context, conn *websocket.Conn, lang string, interval time.Duration) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	requests := make(chan []byte)
+	go func() {
+		defer cancel()
+		conn.SetReadLimit(maxStreamMessageSize)
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			select {
+			case requests <- data:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	send := func(msg StreamMessage) bool {
+		conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		return conn.WriteJSON(msg) == nil
+	}
+	sendQuote := func() bool {
+		q, err := s.quoteGenerator.Generate(ctx, lang)
+		if err != nil {
+			return send(StreamMessage{Type: "error", Error: providerError(err)})
+		}
+		return send(StreamMessage{Type: "quote", Quote: q})
+	}
+
+	if !sendQuote() {
+		return
+	}
+
+	for {
+		ok := true
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.shutdown:
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
+				time.Now().Add(streamWriteTimeout))
+			return
+		case <-ticker.C:
+			ok = sendQuote()
+		case data := <-requests:
+			ok = s.handleStreamRequest(data, &lang, send, sendQuote)
+		}
+		if !ok {
+			return
+		}
+	}
+}
+
+func (s *Server) handleStreamRequest(data []byte, lang *string, send func(StreamMessage) bool, sendQuote func() bool) bool {
+	var req StreamRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		return send(StreamMessage{Type: "error", Error: errInvalidJSON})
+	}
+
+	switch req.Type {
+	case "ping":
+		return sendQuote()
+	case "lang":
+		if err := s.checkLang(req.Lang); err != nil {
+			return send(StreamMessage{Type: "error", Error: err})
+		}
+		*lang = req.Lang
+		return sendQuote()
+	default:
+		return send(StreamMessage{Type: "error", Error: apierror.BadRequest(apierror.CodeBadRequest, fmt.Sprintf("unknown message type %q", req.Type))})
+	}
+}
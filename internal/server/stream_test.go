@@ -0,0 +1,159 @@
+// internal/server/stream_test.go
+
+package server
+
+import (
+	"context"
+	"github.com/gorilla/websocket"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func dialStream(t *testing.T, ts *httptest.Server, query string) (*websocket.Conn, *http.Response, error) {
+	t.Helper()
+
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/quote/stream" + query
+	conn, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if conn != nil {
+		t.Cleanup(func() { conn.Close() })
+	}
+	return conn, resp, err
+}
+
+func readStreamMessage(t *testing.T, conn *websocket.Conn) StreamMessage {
+	t.Helper()
+
+	var msg StreamMessage
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	require.NoError(t, conn.ReadJSON(&msg), "Should have no error when reading a stream message")
+	return msg
+}
+
+func TestHandleQuoteStream(t *testing.T) {
+	enQuote := &quote.Quote{Text: "Bla Bla Bla", Author: "Bob", Lang: "en"}
+	ruQuote := &quote.Quote{Text: "Бла Бла Бла", Author: "Боб", Lang: "ru"}
+
+	mockQuoteGenerator := MockQuoteGenerator{}
+	mockQuoteGenerator.On("Generate", mock.Anything, "en").Return(enQuote, nil)
+	mockQuoteGenerator.On("Generate", mock.Anything, "ru").Return(ruQuote, nil)
+
+	svr := newTestServer(t,
+		WithQuoteGenerator(&mockQuoteGenerator),
+		WithLanguages([]string{"en", "ru"}, true),
+	)
+	ts := httptest.NewServer(svr.Handler())
+	defer ts.Close()
+
+	conn, _, err := dialStream(t, ts, "?lang=en&interval=1h")
+	require.NoError(t, err, "Should have no error when opening the stream")
+
+	assert.Equal(t, StreamMessage{Type: "quote", Quote: enQuote}, readStreamMessage(t, conn), "Should get a quote on connect")
+
+	require.NoError(t, conn.WriteJSON(StreamRequest{Type: "ping"}))
+	assert.Equal(t, StreamMessage{Type: "quote", Quote: enQuote}, readStreamMessage(t, conn), "Should get a quote on ping")
+
+	require.NoError(t, conn.WriteJSON(StreamRequest{Type: "lang", Lang: "ru"}))
+	assert.Equal(t, StreamMessage{Type: "quote", Quote: ruQuote}, readStreamMessage(t, conn), "Should get a quote in the new language")
+
+	require.NoError(t, conn.WriteJSON(StreamRequest{Type: "lang", Lang: "xx"}))
+	msg := readStreamMessage(t, conn)
+	assert.Equal(t, "error", msg.Type, "Should get an error for an unsupported language")
+	assert.EqualValues(t, "INVALID_LANG", msg.Error.Code, "Should get an error for an unsupported language")
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("{")))
+	msg = readStreamMessage(t, conn)
+	assert.EqualValues(t, "INVALID_JSON", msg.Error.Code, "Should get an error for malformed messages")
+
+	require.NoError(t, svr.Close())
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), "Stream should be closed on shutdown")
+}
+
+func TestHandleQuoteStream_Interval(t *testing.T) {
+	mockQuoteGenerator := MockQuoteGenerator{}
+	mockQuoteGenerator.On("Generate", mock.Anything, "").Return(&quote.Quote{Text: "Bla"}, nil)
+
+	svr := newTestServer(t, WithQuoteGenerator(&mockQuoteGenerator))
+	ts := httptest.NewServer(svr.Handler())
+	defer ts.Close()
+
+	conn, _, err := dialStream(t, ts, "?interval=1s")
+	require.NoError(t, err, "Should have no error when opening the stream")
+
+	readStreamMessage(t, conn)
+	start := time.Now()
+	readStreamMessage(t, conn)
+	assert.GreaterOrEqual(t, time.Since(start), 500*time.Millisecond, "Should push quotes at the requested interval")
+}
+
+func TestHandleQuoteStream_Rejected(t *testing.T) {
+	testCases := []struct {
+		name           string
+		query          string
+		expectedStatus int
+	}{
+		{"IntervalTooShort", "?interval=10ms", http.StatusBadRequest},
+		{"InvalidInterval", "?interval=soon", http.StatusBadRequest},
+		{"UnsupportedLang", "?lang=xx", http.StatusBadRequest},
+	}
+
+	svr := newTestServer(t, WithLanguages([]string{"en"}, true))
+	ts := httptest.NewServer(svr.Handler())
+	defer ts.Close()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, resp, err := dialStream(t, ts, tc.query)
+			require.Error(t, err, "Handshake should be refused")
+			assert.Equal(t, tc.expectedStatus, resp.StatusCode, "Response HTTP status in different than expected")
+		})
+	}
+}
+
+func TestHandleQuoteStream_Limit(t *testing.T) {
+	mockQuoteGenerator := MockQuoteGenerator{}
+	mockQuoteGenerator.On("Generate", mock.Anything, "").Return(&quote.Quote{Text: "Bla"}, nil)
+
+	svr := newTestServer(t, WithQuoteGenerator(&mockQuoteGenerator), WithStreamLimit(1))
+	ts := httptest.NewServer(svr.Handler())
+	defer ts.Close()
+
+	first, _, err := dialStream(t, ts, "")
+	require.NoError(t, err, "Should have no error when opening the stream")
+	readStreamMessage(t, first)
+
+	_, resp, err := dialStream(t, ts, "")
+	require.Error(t, err, "Handshake over the limit should be refused")
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode, "Response HTTP status in different than expected")
+
+	first.Close()
+	assert.Eventually(t, func() bool {
+		conn, _, err := dialStream(t, ts, "")
+		return err == nil && conn != nil
+	}, 5*time.Second, 50*time.Millisecond, "Closing a stream should free its slot")
+}
+
+func TestHandleQuoteStream_RequestContext(t *testing.T) {
+	mockQuoteGenerator := MockQuoteGenerator{}
+	mockQuoteGenerator.On("Generate", mock.MatchedBy(func(ctx context.Context) bool {
+		return tenant.FromContext(ctx) == "acme"
+	}), "en").Return(&quote.Quote{Text: "Bla"}, nil)
+
+	svr := newTestServer(t, WithQuoteGenerator(&mockQuoteGenerator))
+	ts := httptest.NewServer(svr.Handler())
+	defer ts.Close()
+
+	conn, _, err := dialStream(t, ts, "?lang=en&interval=1h&tenant=acme")
+	require.NoError(t, err, "Should have no error when opening the stream")
+
+	assert.Equal(t, "quote", readStreamMessage(t, conn).Type, "Quotes should be fetched for the tenant of the request")
+}
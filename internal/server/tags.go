@@ -0,0 +1,100 @@
+// internal/server/tags.go
+
+package server
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/internal/audit"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"net/http"
+	"strconv"
+)
+
+func (s *Server) handleListTags() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(mux.Vars(r)["id"])
+		if err != nil {
+			s.writeError(w, r, errInvalidID)
+			return
+		}
+
+		tags, err := s.tagStore.ListTags(r.Context(), id)
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+
+		writeJSON(w, http.StatusOK, tags)
+	}
+}
+
+func (s *Server) handleAddTag() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, tag, ok := s.tagVars(w, r)
+		if !ok {
+			return
+		}
+
+		if err := s.tagStore.AddTag(r.Context(), id, tag); err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+		s.audit(r, audit.ActionUpdate, audit.EntityRecipient, id)
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) handleRemoveTag() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, tag, ok := s.tagVars(w, r)
+		if !ok {
+			return
+		}
+
+		if err := s.tagStore.RemoveTag(r.Context(), id, tag); err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+		s.audit(r, audit.ActionUpdate, audit.EntityRecipient, id)
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// tagVars reads the recipient id and the normalized tag from the path,
+// writing the error and returning false when either is invalid.
+func (s *Server) tagVars(w http.ResponseWriter, r *http.Request) (int, string, bool) {
+	vars := mux.Vars(r)
+
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		s.writeError(w, r, errInvalidID)
+		return 0, "", false
+	}
+
+	tag, err := recipient.NormalizeTag(vars["tag"])
+	if err != nil {
+		s.writeError(w, r, apierror.BadRequest(apierror.CodeValidation, err.Error()))
+		return 0, "", false
+	}
+
+	return id, tag, true
+}
+
+// normalizeTags normalizes the tags of a query, skipping empty ones.
+func normalizeTags(tags []string) ([]string, error) {
+	var normalized []string
+	for _, tag := range tags {
+		if tag == "" {
+			continue
+		}
+		tag, err := recipient.NormalizeTag(tag)
+		if err != nil {
+			return nil, err
+		}
+		normalized = append(normalized, tag)
+	}
+	return normalized, nil
+}
@@ -0,0 +1,167 @@
+// internal/server/tags_test.go
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/jeniok12/golang-tdd-example/internal/memstore"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newTaggedRecipients returns verified recipients alice, tagged devs, bob,
+// tagged managers, and carol, untagged. Alice and bob are in the group team.
+func newTaggedRecipients(t *testing.T) *memstore.Recipients {
+	ctx := context.Background()
+	recipients := memstore.NewRecipients(memstore.NewQuotes())
+	for _, name := range []string{"alice", "bob", "carol"} {
+		r, err := recipients.Create(ctx, recipient.Recipient{Name: name, Email: name + "@testmail.com"})
+		require.NoError(t, err, "Got error when not expected")
+		_, err = recipients.MarkVerified(ctx, r.ID, r.Email)
+		require.NoError(t, err, "Got error when not expected")
+	}
+	require.NoError(t, recipients.AddTag(ctx, 1, "devs"), "Got error when not expected")
+	require.NoError(t, recipients.AddTag(ctx, 2, "managers"), "Got error when not expected")
+
+	g, err := recipients.CreateGroup(ctx, "team")
+	require.NoError(t, err, "Got error when not expected")
+	require.NoError(t, recipients.AddMember(ctx, g.ID, 1), "Got error when not expected")
+	require.NoError(t, recipients.AddMember(ctx, g.ID, 2), "Got error when not expected")
+
+	return recipients
+}
+
+func names(recipients []recipient.Recipient) []string {
+	names := []string{}
+	for _, r := range recipients {
+		names = append(names, r.Name)
+	}
+	return names
+}
+
+func TestHandleTags(t *testing.T) {
+	recipients := memstore.NewRecipients(memstore.NewQuotes())
+	recipients.Create(context.Background(), recipient.Recipient{Name: "user1", Email: "user1@testmail.com"})
+	svr := newTestServer(t, WithRecipientStore(recipients), WithTagStore(recipients))
+
+	calls := []struct {
+		desc           string
+		method         string
+		path           string
+		expectedStatus int
+		expectedTags   []string
+	}{
+		{"Add", http.MethodPut, "/recipients/1/tags/Devs", http.StatusNoContent, nil},
+		{"AddAgain", http.MethodPut, "/recipients/1/tags/devs", http.StatusNoContent, nil},
+		{"AddInvalid", http.MethodPut, "/recipients/1/tags/dev%20ops", http.StatusBadRequest, nil},
+		{"AddUnknownRecipient", http.MethodPut, "/recipients/42/tags/devs", http.StatusNotFound, nil},
+		{"List", http.MethodGet, "/recipients/1/tags", http.StatusOK, []string{"devs"}},
+		{"ListUnknownRecipient", http.MethodGet, "/recipients/42/tags", http.StatusNotFound, nil},
+		{"Remove", http.MethodDelete, "/recipients/1/tags/DEVS", http.StatusNoContent, nil},
+		{"RemoveAgain", http.MethodDelete, "/recipients/1/tags/devs", http.StatusNotFound, nil},
+		{"ListEmpty", http.MethodGet, "/recipients/1/tags", http.StatusOK, []string{}},
+	}
+	for _, c := range calls {
+		t.Run(c.desc, func(t *testing.T) {
+			rr := makeHTTPCall(svr.router, httptest.NewRequest(c.method, c.path, nil))
+
+			assert.Equal(t, c.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			if c.expectedTags != nil {
+				var tags []string
+				_ = json.Unmarshal(rr.Body.Bytes(), &tags)
+				assert.Equal(t, c.expectedTags, tags)
+			}
+		})
+	}
+}
+
+func TestHandleQuotes_Tags(t *testing.T) {
+	testCases := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedNames  []string
+	}{
+		{"OneTag", "?tags=devs", http.StatusOK, []string{"alice"}},
+		{"AnyTag", "?tags=devs,Managers", http.StatusOK, []string{"alice", "bob"}},
+		{"UnknownTag", "?tags=sales", http.StatusOK, []string{}},
+		{"TagsInGroup", "?tags=managers&group=team", http.StatusOK, []string{"bob"}},
+		{"InvalidTag", "?tags=dev%20ops", http.StatusBadRequest, nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockQuoteGenerator := MockQuoteGenerator{}
+			mockQuoteGenerator.On("Generate", mock.Anything, "en").Return(&quote.Quote{Text: "Bla", Lang: "en"}, nil).Maybe()
+			recipients := newTaggedRecipients(t)
+			svr := newTestServer(t,
+				WithQuoteGenerator(&mockQuoteGenerator),
+				WithRecipientsFetcher(recipients),
+				WithGroupStore(recipients),
+				WithTagStore(recipients),
+				WithDefaultLang("en"),
+			)
+
+			rr := makeHTTPCall(svr.router, httptest.NewRequest(http.MethodGet, "/quote"+tc.query, nil))
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			if tc.expectedNames != nil {
+				var resp HandleQuoteResponse
+				_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+				assert.Equal(t, tc.expectedNames, names(resp.Recipients), "Recipients are different than expected")
+			}
+		})
+	}
+}
+
+func TestHandleSendQuote_Tags(t *testing.T) {
+	testCases := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedNames  []string
+	}{
+		{"AllRecipients", "", http.StatusOK, []string{"alice", "bob", "carol"}},
+		{"Tags", "?tags=managers", http.StatusOK, []string{"bob"}},
+		{"TagsInGroup", "?tags=devs,managers&group=team", http.StatusOK, []string{"alice", "bob"}},
+		{"InvalidTag", "?tags=-devs", http.StatusBadRequest, nil},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockQuoteGenerator := MockQuoteGenerator{}
+			mockQuoteGenerator.On("Generate", mock.Anything, "").Return(&quote.Quote{Text: "Bla"}, nil).Maybe()
+			mockMailSender := MockMailSender{}
+			mockMailSender.On("Send", mock.Anything).Return(nil)
+			recipients := newTaggedRecipients(t)
+			svr := newTestServer(t,
+				WithQuoteGenerator(&mockQuoteGenerator),
+				WithRecipientsFetcher(recipients),
+				WithGroupStore(recipients),
+				WithTagStore(recipients),
+				WithMailSender(&mockMailSender),
+			)
+
+			rr := makeHTTPCall(svr.router, httptest.NewRequest(http.MethodPost, "/quote/send"+tc.query, nil))
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			if tc.expectedNames == nil {
+				mockMailSender.AssertNotCalled(t, "Send", mock.Anything)
+				return
+			}
+			var resp HandleSendQuoteResponse
+			_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+			var sent []recipient.Recipient
+			for _, d := range resp.Deliveries {
+				sent = append(sent, d.Recipient)
+			}
+			assert.Equal(t, tc.expectedNames, names(sent), "Quote should be sent to the tagged recipients")
+			mockMailSender.AssertNumberOfCalls(t, "Send", len(tc.expectedNames))
+		})
+	}
+}
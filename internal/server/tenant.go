@@ -0,0 +1,78 @@
+// internal/server/tenant.go
+
+package server
+
+import (
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/tenant"
+	"net/http"
+	"net/mail"
+	"strings"
+)
+
+// tenantParam carries the tenant in links sent by email, which cannot set
+// headers.
+const tenantParam = "tenant"
+
+// WithTenantKeys binds API keys to tenants, as pairs such as "key:acme",
+// optionally followed by the email address of the key's owner, as in
+// "key:acme:ops@acme.com". Requests with one of those keys act for its tenant,
+// and cannot name another one.
+func WithTenantKeys(pairs ...string) Option {
+	return func(s *Server) error {
+		for _, pair := range pairs {
+			parts := strings.SplitN(pair, ":", 3)
+			if len(parts) < 2 || parts[0] == "" || !tenant.Valid(parts[1]) {
+				return fmt.Errorf("server: tenant key is not api-key:tenant with a valid tenant")
+			}
+			var owner string
+			if len(parts) == 3 {
+				addr, err := mail.ParseAddress(parts[2])
+				if err != nil {
+					return fmt.Errorf("server: owner of tenant key for %s: %v", parts[1], err)
+				}
+				owner = addr.Address
+			}
+			s.apiKeys.add(parts[0], parts[1], owner)
+		}
+		return nil
+	}
+}
+
+var errInvalidTenant = apierror.BadRequest(apierror.CodeBadRequest,
+	tenant.Header+" must be up to 63 lowercase letters, digits, dashes and underscores")
+
+// withTenant puts the tenant of the request in its context: the one bound to
+// its API key, which is turned away once rotated away, else the one it names in X-Tenant-ID or the tenant parameter,
+// else the default tenant.
+func (s *Server) withTenant(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(tenant.Header)
+		if id == "" {
+			id = r.URL.Query().Get(tenantParam)
+		}
+		if id != "" && !tenant.Valid(id) {
+			apierror.Write(w, r, errInvalidTenant)
+			return
+		}
+
+		if key, ok := s.apiKeys.lookup(r.Header.Get(APIKeyHeader)); ok {
+			if key.expired(s.now()) {
+				apierror.Write(w, r, apierror.Unauthorized("this "+APIKeyHeader+" was rotated, use the new one"))
+				return
+			}
+			if id != "" && id != key.Tenant {
+				apierror.Write(w, r, apierror.Forbidden("this "+APIKeyHeader+" cannot act for tenant "+id))
+				return
+			}
+			id = key.Tenant
+			r = r.WithContext(withAPIKeyID(r.Context(), key.ID))
+		}
+		if id == "" {
+			id = tenant.Default
+		}
+
+		next.ServeHTTP(w, r.WithContext(tenant.NewContext(r.Context(), id)))
+	})
+}
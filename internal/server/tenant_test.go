@@ -0,0 +1,79 @@
+// internal/server/tenant_test.go
+
+package server
+
+import (
+	"encoding/json"
+	"github.com/jeniok12/golang-tdd-example/internal/memstore"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/jeniok12/golang-tdd-example/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTenants(t *testing.T) {
+	recipients := memstore.NewRecipients(memstore.NewQuotes())
+	svr := newTestServer(t,
+		WithRecipientsFetcher(recipients),
+		WithRecipientStore(recipients),
+		WithTenantKeys("acme-key:acme"),
+	)
+
+	req := httptest.NewRequest(http.MethodPost, "/recipients", strings.NewReader(`{"name":"alice","email":"alice@testmail.com"}`))
+	req.Header.Set(tenant.Header, "acme")
+	rr := makeHTTPCall(svr.router, req)
+	require.Equal(t, http.StatusCreated, rr.Code, "Response HTTP status in different than expected")
+
+	testCases := []struct {
+		desc          string
+		header        string
+		apiKey        string
+		expectedCount int
+	}{
+		{"Header", "acme", "", 1},
+		{"APIKey", "", "acme-key", 1},
+		{"OtherTenant", "globex", "", 0},
+		{"DefaultTenant", "", "", 0},
+		{"UnboundAPIKey", "", "other-key", 0},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/recipients", nil)
+			req.Header.Set(tenant.Header, tC.header)
+			req.Header.Set(APIKeyHeader, tC.apiKey)
+
+			rr := makeHTTPCall(svr.router, req)
+
+			require.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+			var rs []recipient.Recipient
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &rs), "Response should be a list of recipients")
+			assert.Len(t, rs, tC.expectedCount, "Recipients should be scoped to the tenant")
+		})
+	}
+}
+
+func TestTenants_Rejected(t *testing.T) {
+	svr := newTestServer(t, WithTenantKeys("acme-key:acme"))
+
+	req := httptest.NewRequest(http.MethodGet, "/recipients", nil)
+	req.Header.Set(tenant.Header, "Not A Tenant")
+	rr := makeHTTPCall(svr.router, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code, "Invalid tenants should be rejected")
+
+	req = httptest.NewRequest(http.MethodGet, "/recipients", nil)
+	req.Header.Set(tenant.Header, "globex")
+	req.Header.Set(APIKeyHeader, "acme-key")
+	rr = makeHTTPCall(svr.router, req)
+	assert.Equal(t, http.StatusForbidden, rr.Code, "Keys should not act for other tenants")
+}
+
+func TestWithTenantKeys_Invalid(t *testing.T) {
+	for _, pair := range []string{"acme-key", ":acme", "acme-key:", "acme-key:Acme", "acme-key:acme:not-an-email"} {
+		_, err := NewServer(WithTenantKeys(pair))
+		assert.Error(t, err, "Pair %q should be rejected", pair)
+	}
+}
@@ -0,0 +1,52 @@
+// internal/server/tls.go
+
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// WithHSTS sends a Strict-Transport-Security header with the given max-age
+// on responses served over HTTPS. Zero disables the header.
+func WithHSTS(maxAge time.Duration) Option {
+	return func(s *Server) error {
+		if maxAge < 0 {
+			return fmt.Errorf("server: negative HSTS max-age %s", maxAge)
+		}
+		s.hstsMaxAge = maxAge
+		return nil
+	}
+}
+
+// withHSTS adds the Strict-Transport-Security header. Browsers ignore it on
+// plain HTTP, so it is only sent over TLS.
+func (s *Server) withHSTS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.hstsMaxAge > 0 && r.TLS != nil {
+			w.Header().Set("Strict-Transport-Security", fmt.Sprintf("max-age=%d; includeSubDomains", int(s.hstsMaxAge.Seconds())))
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RedirectHTTPS redirects every request to the same host and path over HTTPS
+// on port. Port 443 is left out of the redirect URL.
+func RedirectHTTPS(port int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != 443 {
+			host = net.JoinHostPort(host, strconv.Itoa(port))
+		}
+
+		target := "https://" + host + r.URL.RequestURI()
+		// 308 keeps the method and body, so API clients can follow it too.
+		http.Redirect(w, r, target, http.StatusPermanentRedirect)
+	})
+}
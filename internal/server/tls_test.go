@@ -0,0 +1,69 @@
+// internal/server/tls_test.go
+
+package server
+
+import (
+	"crypto/tls"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWithHSTS(t *testing.T) {
+	testCases := []struct {
+		name           string
+		maxAge         time.Duration
+		tls            bool
+		expectedHeader string
+	}{
+		{"HTTPS", 24 * time.Hour, true, "max-age=86400; includeSubDomains"},
+		{"PlainHTTP", 24 * time.Hour, false, ""},
+		{"Disabled", 0, true, ""},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			svr := newTestServer(t, WithHSTS(tC.maxAge))
+			svr.router.HandleFunc("/teapot", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTeapot)
+			})
+
+			req, _ := http.NewRequest("GET", "/teapot", nil)
+			if tC.tls {
+				req.TLS = &tls.ConnectionState{}
+			}
+			response := makeHTTPCall(svr.router, req)
+
+			assert.Equal(t, http.StatusTeapot, response.Code, "Response HTTP status in different than expected")
+			assert.Equal(t, tC.expectedHeader, response.Header().Get("Strict-Transport-Security"), "Wrong HSTS header")
+		})
+	}
+
+	_, err := NewServer(WithHSTS(-time.Second))
+	assert.Error(t, err, "Negative max-age should be rejected")
+}
+
+func TestRedirectHTTPS(t *testing.T) {
+	testCases := []struct {
+		name             string
+		port             int
+		target           string
+		expectedLocation string
+	}{
+		{"DefaultPort", 443, "http://quotes.example.com:8080/quote?lang=en", "https://quotes.example.com/quote?lang=en"},
+		{"CustomPort", 8443, "http://quotes.example.com/recipients", "https://quotes.example.com:8443/recipients"},
+		{"IPv6", 8443, "http://[::1]:8080/", "https://[::1]:8443/"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tC.target, nil)
+			rr := httptest.NewRecorder()
+
+			RedirectHTTPS(tC.port).ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusPermanentRedirect, rr.Code, "Response HTTP status in different than expected")
+			assert.Equal(t, tC.expectedLocation, rr.Header().Get("Location"), "Wrong redirect location")
+		})
+	}
+}
@@ -0,0 +1,73 @@
+// internal/server/tracing_test.go
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/jeniok12/golang-tdd-example/tracing"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWithTracing(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		status         int
+		traceparent    string
+		expectedStatus codes.Code
+	}{
+		{"NewTrace", http.StatusTeapot, "", codes.Unset},
+		{"ContinuedTrace", http.StatusOK, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", codes.Unset},
+		{"ServerError", http.StatusBadGateway, "", codes.Error},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			previous := otel.GetTracerProvider()
+			otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+			defer otel.SetTracerProvider(previous)
+			_, err := tracing.Setup(context.Background(), tracing.Config{})
+			require.NoError(t, err, "Got error when not expected")
+
+			var logs bytes.Buffer
+			svr := newTestServer(t, WithLogger(zerolog.New(&logs)))
+			var handlerTraceID string
+			svr.router.HandleFunc("/teapots/{id}", func(w http.ResponseWriter, r *http.Request) {
+				handlerTraceID = tracing.TraceID(r.Context())
+				w.WriteHeader(tC.status)
+			})
+
+			req, _ := http.NewRequest("GET", "/teapots/42", nil)
+			if tC.traceparent != "" {
+				req.Header.Set("traceparent", tC.traceparent)
+			}
+			svr.router.ServeHTTP(httptest.NewRecorder(), req)
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 1)
+			span := spans[0]
+			assert.Equal(t, "GET /teapots/{id}", span.Name(), "Spans should be named after the route template")
+			assert.Contains(t, span.Attributes(), attribute.Int("http.response.status_code", tC.status))
+			assert.Equal(t, tC.expectedStatus, span.Status().Code)
+			assert.Equal(t, span.SpanContext().TraceID().String(), handlerTraceID, "Handlers should run within the span")
+			if tC.traceparent != "" {
+				assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", handlerTraceID, "The caller's trace should be continued")
+			}
+
+			var entry map[string]interface{}
+			_ = json.Unmarshal(logs.Bytes(), &entry)
+			assert.Equal(t, handlerTraceID, entry["traceId"], "Log should contain the trace ID")
+		})
+	}
+}
@@ -0,0 +1,72 @@
+// internal/server/translate.go
+
+package server
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/translate"
+	"net/http"
+)
+
+// WithTranslator lets GET /quote translate quotes from the language given in
+// source to lang.
+func WithTranslator(t translate.Translator) Option {
+	return func(s *Server) error {
+		s.translator = t
+		return nil
+	}
+}
+
+// translates reports whether quotes can be translated.
+func (s *Server) translates() bool {
+	return s.translator != nil && s.enabled(featureTranslation)
+}
+
+// requestedQuote finds the quote req asks for, translating it when a source
+// language other than lang is given. Lengths are those of the text served,
+// after translation. Without one, fallback reports whether
+// the quote came from a fallback language. Errors are API errors.
+func (s *Server) requestedQuote(ctx context.Context, req quoteRequest) (q *quote.Quote, fallback bool, err error) {
+	ctx = quote.WithKey(ctx, req.Key)
+	if req.Source == "" || req.Source == req.Lang {
+		return s.findQuoteFallback(ctx, req.Lang, req.constraints())
+	}
+
+	if !s.translates() {
+		return nil, false, apierror.BadRequest(apierror.CodeBadRequest, "translation is not configured")
+	}
+	if req.Lang == "" {
+		return nil, false, apierror.BadRequest(apierror.CodeBadRequest, "lang is required to translate from source")
+	}
+
+	// Translating changes the length, so only the author narrows down the
+	// source quotes and the length is checked on the translations.
+	c := req.constraints()
+	length := quote.Constraints{MinLength: c.MinLength, MaxLength: c.MaxLength}
+	attempts := 1
+	if !length.IsZero() {
+		// A cached quote would come back on every draw.
+		ctx = quote.SkipCache(ctx)
+		if _, keyed := quote.KeyFromContext(ctx); !keyed {
+			attempts = s.authorAttempts
+		}
+	}
+	for i := 0; i < attempts; i++ {
+		q, err = s.findQuote(ctx, req.Source, quote.Constraints{Author: c.Author})
+		if err != nil {
+			return nil, false, err
+		}
+
+		translated, err := translate.Quote(ctx, s.translator, q, req.Lang)
+		if err != nil {
+			return nil, false, apierror.Wrap(err, http.StatusBadGateway, apierror.CodeUpstreamError, "translation failed")
+		}
+		if length.Match(*translated) {
+			return translated, false, nil
+		}
+	}
+
+	return nil, false, apierror.NotFound("no quote " + describeConstraints(c) + " found once translated")
+}
@@ -0,0 +1,103 @@
+// internal/server/translate_test.go
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type stubTranslator struct {
+	err error
+}
+
+func (t stubTranslator) Translate(ctx context.Context, text, source, target string) (string, error) {
+	if t.err != nil {
+		return "", t.err
+	}
+	return target + ":" + text, nil
+}
+
+func TestHandleQuotes_Translate(t *testing.T) {
+	testCases := []struct {
+		name           string
+		query          string
+		translator     *stubTranslator
+		expectedStatus int
+	}{
+		{"Translated", "lang=fr&source=en", &stubTranslator{}, http.StatusOK},
+		{"SameLanguage", "lang=en&source=en", nil, http.StatusOK},
+		{"NotConfigured", "lang=en&source=ru", nil, http.StatusBadRequest},
+		{"UnsupportedWithoutSource", "lang=fr", &stubTranslator{}, http.StatusBadRequest},
+		{"UnsupportedSource", "lang=fr&source=xx", &stubTranslator{}, http.StatusBadRequest},
+		{"MissingLang", "source=en", &stubTranslator{}, http.StatusBadRequest},
+		{"TranslatorFails", "lang=fr&source=en", &stubTranslator{err: errors.New("sample error")}, http.StatusBadGateway},
+		{"TranslationFits", "lang=fr&source=en&max_length=8", &stubTranslator{}, http.StatusOK},
+		{"TranslationTooLong", "lang=fr&source=en&max_length=5", &stubTranslator{}, http.StatusNotFound},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			mockQuoteGenerator := MockQuoteGenerator{}
+			mockQuoteGenerator.On("Generate", mock.Anything, mock.Anything).Return(&quote.Quote{Text: "Hello", Lang: "en"}, nil)
+			mockRecipientsFetcher := MockRecipientsFetcher{}
+			mockRecipientsFetcher.On("ListRecipients", mock.Anything, defaultListOptions).Return([]recipient.Recipient{}, nil)
+
+			opts := []Option{
+				WithQuoteGenerator(&mockQuoteGenerator),
+				WithRecipientsFetcher(&mockRecipientsFetcher),
+				WithLanguages([]string{"en", "ru"}, true),
+			}
+			if tC.translator != nil {
+				opts = append(opts, WithTranslator(*tC.translator))
+			}
+			svr := newTestServer(t, opts...)
+
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/quote?"+tC.query, nil)
+
+			svr.handleQuotes()(rr, req)
+
+			assert.Equal(t, tC.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			if tC.name == "Translated" {
+				var resp struct {
+					Quote quote.Quote `json:"quote"`
+				}
+				_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+				assert.Equal(t, quote.Quote{Text: "fr:Hello", Lang: "fr", OriginalLang: "en"}, resp.Quote, "Translated quote is different than expected")
+			}
+		})
+	}
+}
+
+func TestHandleQuotes_TranslateLength(t *testing.T) {
+	mockQuoteGenerator := MockQuoteGenerator{}
+	mockQuoteGenerator.On("Generate", mock.Anything, "en").Return(&quote.Quote{Text: "Hello", Lang: "en"}, nil).Once()
+	mockQuoteGenerator.On("Generate", mock.Anything, "en").Return(&quote.Quote{Text: "Hi", Lang: "en"}, nil).Once()
+	mockRecipientsFetcher := MockRecipientsFetcher{}
+	mockRecipientsFetcher.On("ListRecipients", mock.Anything, defaultListOptions).Return([]recipient.Recipient{}, nil)
+	svr := newTestServer(t,
+		WithQuoteGenerator(&mockQuoteGenerator),
+		WithRecipientsFetcher(&mockRecipientsFetcher),
+		WithLanguages([]string{"en", "ru"}, true),
+		WithTranslator(stubTranslator{}),
+	)
+
+	req, _ := http.NewRequest("GET", "/quote?lang=fr&source=en&max_length=5", nil)
+	rr := makeHTTPCall(svr.router, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+	var resp struct {
+		Quote quote.Quote `json:"quote"`
+	}
+	_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Equal(t, "fr:Hi", resp.Quote.Text, "Quotes too long once translated should be drawn again")
+	mockQuoteGenerator.AssertExpectations(t)
+}
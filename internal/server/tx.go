@@ -0,0 +1,65 @@
+// internal/server/tx.go
+
+package server
+
+import (
+	"context"
+	"database/sql"
+	"github.com/jeniok12/golang-tdd-example/internal/audit"
+	"github.com/jeniok12/golang-tdd-example/internal/dbtx"
+)
+
+// TxRepositories are the repositories bound to one transaction. Audit and
+// Outbox are nil when the server has none.
+type TxRepositories struct {
+	Recipients RecipientStore
+	Audit      audit.Store
+	Outbox     Outbox
+}
+
+// Transactor runs fn with repositories sharing a transaction, committed when
+// fn returns nil and rolled back otherwise.
+type Transactor interface {
+	WithTx(ctx context.Context, fn func(repos TxRepositories) error) error
+}
+
+// WithTransactor makes the changes of a request atomic, such as a recipient
+// together with its audit entry and verification email.
+func WithTransactor(t Transactor) Option {
+	return func(s *Server) error {
+		s.transactor = t
+		return nil
+	}
+}
+
+// sqlTransactor runs transactions on db, binding the repositories to each.
+type sqlTransactor struct {
+	db   *sql.DB
+	bind func(tx *sql.Tx) TxRepositories
+}
+
+// WithTx ...
+func (t sqlTransactor) WithTx(ctx context.Context, fn func(repos TxRepositories) error) error {
+	return dbtx.Run(ctx, t.db, func(tx *sql.Tx) error {
+		return fn(t.bind(tx))
+	})
+}
+
+// withTx runs fn in a transaction. Without a Transactor, as in memory, fn gets
+// the server's own stores and its steps are not atomic.
+func (s *Server) withTx(ctx context.Context, fn func(repos TxRepositories) error) error {
+	if s.transactor == nil {
+		return fn(TxRepositories{Recipients: s.recipientStore, Audit: s.auditLog, Outbox: s.outbox})
+	}
+
+	return s.transactor.WithTx(ctx, func(repos TxRepositories) error {
+		// The transaction spans only the stores the server was given.
+		if s.auditLog == nil {
+			repos.Audit = nil
+		}
+		if s.outbox == nil {
+			repos.Outbox = nil
+		}
+		return fn(repos)
+	})
+}
@@ -0,0 +1,104 @@
+// internal/server/tx_test.go
+
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/internal/audit"
+	"github.com/jeniok12/golang-tdd-example/internal/outbox"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/jeniok12/golang-tdd-example/internal/sqlitestore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// failingAudit is an audit log that cannot record anything.
+type failingAudit struct {
+	audit.Store
+}
+
+func (failingAudit) Record(context.Context, audit.Entry) error {
+	return errors.New("disk full")
+}
+
+// newSQLiteRepositories returns the sqlite stores of a fresh database and a
+// transactor binding them, with auditLog standing in for the audit log.
+func newSQLiteRepositories(t *testing.T, auditLog func(tx *sql.Tx) audit.Store) (*sqlitestore.Recipients, *sqlitestore.Outbox, Transactor) {
+	db, err := sqlitestore.Open(":memory:")
+	require.NoError(t, err, "Got error when not expected")
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, db.Migrate(context.Background()), "Got error when not expected")
+
+	recipients, box := sqlitestore.NewRecipients(db), sqlitestore.NewOutbox(db)
+	return recipients, box, sqlTransactor{db: db.DB, bind: func(tx *sql.Tx) TxRepositories {
+		return TxRepositories{Recipients: recipients.WithTx(tx), Audit: auditLog(tx), Outbox: box.WithTx(tx)}
+	}}
+}
+
+func TestRecipientChanges_Atomic(t *testing.T) {
+	recipients, _, transactor := newSQLiteRepositories(t, func(*sql.Tx) audit.Store { return failingAudit{} })
+	_, err := recipients.Create(context.Background(), recipient.Recipient{Name: "user1", Email: "user1@testmail.com"})
+	require.NoError(t, err, "Got error when not expected")
+	svr := newTestServer(t,
+		WithRecipientStore(recipients),
+		WithAuditLog(failingAudit{}),
+		WithTransactor(transactor),
+	)
+
+	calls := []struct {
+		desc   string
+		method string
+		path   string
+		body   string
+	}{
+		{"Create", http.MethodPost, "/recipients", `{"name":"user2","email":"user2@testmail.com"}`},
+		{"Update", http.MethodPut, "/recipients/1", `{"name":"user3","email":"user3@testmail.com"}`},
+		{"Delete", http.MethodDelete, "/recipients/1", ""},
+	}
+	for _, c := range calls {
+		t.Run(c.desc, func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.path, strings.NewReader(c.body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("If-Match", "*")
+			rr := makeHTTPCall(svr.router, req)
+
+			assert.Equal(t, http.StatusServiceUnavailable, rr.Code, "Response HTTP status in different than expected")
+			all, _ := recipients.ListRecipients(context.Background(), recipient.ListOptions{Limit: 100})
+			assert.Equal(t, []recipient.Recipient{{ID: 1, Name: "user1", Email: "user1@testmail.com", Version: 1}}, all,
+				"Changes should be rolled back when they cannot be audited")
+		})
+	}
+}
+
+func TestCreateRecipient_QueuesVerification(t *testing.T) {
+	recipients, box, transactor := newSQLiteRepositories(t, func(*sql.Tx) audit.Store { return nil })
+	mockMailSender := MockMailSender{}
+	svr := newTestServer(t,
+		WithRecipientStore(recipients),
+		WithMailSender(&mockMailSender),
+		WithVerification(testSigner, "http://quotes.test"),
+		WithOutbox(box, &outbox.Worker{Store: box}, time.Hour),
+		WithTransactor(transactor),
+	)
+
+	req := httptest.NewRequest(http.MethodPost, "/recipients", strings.NewReader(`{"name":"user1","email":"user1@testmail.com"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rr := makeHTTPCall(svr.router, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code, "Response HTTP status in different than expected")
+	mockMailSender.AssertNotCalled(t, "Send", mock.Anything)
+	claimed, err := box.Claim(context.Background(), 10, time.Minute)
+	require.NoError(t, err, "Got error when not expected")
+	if assert.Len(t, claimed, 1, "The verification email should be queued with the recipient") {
+		assert.Equal(t, "user1@testmail.com", claimed[0].Message.To)
+		assert.Contains(t, claimed[0].Message.Body, "http://quotes.test/recipients/verify?token=")
+	}
+}
@@ -0,0 +1,104 @@
+// internal/server/unsubscribe.go
+
+package server
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/internal/audit"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/jeniok12/golang-tdd-example/mailer"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type unsubscribeRequest struct {
+	Token string `query:"token" validate:"required" doc:"token from the unsubscribe link of an email"`
+}
+
+// UnsubscribeResponse ...
+type UnsubscribeResponse struct {
+	Email string `json:"email"`
+}
+
+// WithSuppressionStore keeps the emails that unsubscribed in store. They are
+// sent no more emails and left out of the /quote recipients.
+func WithSuppressionStore(store SuppressionStore) Option {
+	return func(s *Server) error {
+		s.suppressions = store
+		return nil
+	}
+}
+
+// unsubscribeURL returns a signed unsubscribe link for the recipient with id
+// and email, or "" without suppressions or a signer.
+func (s *Server) unsubscribeURL(id int, email string) string {
+	if s.suppressions == nil || s.unsubscriber == nil {
+		return ""
+	}
+	return s.publicURL + "/unsubscribe?token=" + url.QueryEscape(s.unsubscriber.Sign(id, email))
+}
+
+// withUnsubscribe appends the unsubscribe link of the recipient with id to
+// msg, for emails not rendered from templates.
+func (s *Server) withUnsubscribe(msg mailer.Message, id int) mailer.Message {
+	if link := s.unsubscribeURL(id, msg.To); link != "" {
+		msg.Body += "\n--\nUnsubscribe: " + link + "\n"
+	}
+	return msg
+}
+
+// dropSuppressed returns recipients without those whose email is on the
+// suppression list.
+func (s *Server) dropSuppressed(ctx context.Context, recipients []recipient.Recipient) ([]recipient.Recipient, error) {
+	if s.suppressions == nil || len(recipients) == 0 {
+		return recipients, nil
+	}
+
+	emails := make([]string, len(recipients))
+	for i, rcpt := range recipients {
+		emails[i] = rcpt.Email
+	}
+	suppressed, err := s.suppressions.Suppressed(ctx, emails)
+	if err != nil {
+		return nil, err
+	}
+
+	kept := make([]recipient.Recipient, 0, len(recipients))
+	for _, rcpt := range recipients {
+		if !suppressed[strings.ToLower(rcpt.Email)] {
+			kept = append(kept, rcpt)
+		}
+	}
+	return kept, nil
+}
+
+func (s *Server) handleUnsubscribe() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.suppressions == nil || s.unsubscriber == nil {
+			s.writeError(w, r, apierror.NotFound("unsubscribing is disabled"))
+			return
+		}
+
+		var req unsubscribeRequest
+		if !s.bindRequest(w, r, &req) {
+			return
+		}
+
+		// Unsubscribe tokens do not expire, so any failure means a bad token.
+		id, email, err := s.unsubscriber.Verify(req.Token)
+		if err != nil {
+			s.writeError(w, r, errInvalidToken)
+			return
+		}
+
+		if err := s.suppressions.Suppress(r.Context(), email); err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+		s.audit(r, audit.ActionUpdate, audit.EntityRecipient, id)
+
+		writeJSON(w, http.StatusOK, UnsubscribeResponse{Email: email})
+	}
+}
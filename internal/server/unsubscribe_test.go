@@ -0,0 +1,100 @@
+// internal/server/unsubscribe_test.go
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/jeniok12/golang-tdd-example/internal/memstore"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/jeniok12/golang-tdd-example/mailer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestUnsubscribe(t *testing.T) {
+	ctx := context.Background()
+	recipients := memstore.NewRecipients(memstore.NewQuotes())
+	for _, name := range []string{"user1", "user2"} {
+		created, err := recipients.Create(ctx, recipient.Recipient{Name: name, Email: name + "@testmail.com"})
+		require.NoError(t, err, "Should have no error when creating a recipient")
+		_, err = recipients.MarkVerified(ctx, created.ID, created.Email)
+		require.NoError(t, err, "Should have no error when verifying a recipient")
+	}
+
+	mockQuoteGenerator := MockQuoteGenerator{}
+	mockQuoteGenerator.On("Generate", mock.Anything, mock.Anything).Return(&quote.Quote{Text: "Bla", Author: "Bob"}, nil)
+	var sent []mailer.Message
+	mockMailSender := MockMailSender{}
+	mockMailSender.On("Send", mock.Anything).Run(func(args mock.Arguments) {
+		sent = append(sent, args.Get(0).(mailer.Message))
+	}).Return(nil)
+
+	svr := newTestServer(t,
+		WithQuoteGenerator(&mockQuoteGenerator),
+		WithRecipientStore(recipients),
+		WithRecipientsFetcher(recipients),
+		WithSuppressionStore(recipients),
+		WithMailSender(&mockMailSender),
+		WithVerification(testSigner, "http://quotes.test"),
+	)
+
+	req, _ := http.NewRequest("POST", "/quote/send", nil)
+	rr := makeHTTPCall(svr.router, req)
+	require.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+	require.Len(t, sent, 2, "Every recipient should get the quote")
+
+	prefix := "Unsubscribe: http://quotes.test/unsubscribe?token="
+	body := sent[0].Body
+	require.Contains(t, body, prefix, "Emails should carry an unsubscribe link")
+	token, _ := url.QueryUnescape(strings.TrimSpace(body[strings.Index(body, prefix)+len(prefix):]))
+
+	req, _ = http.NewRequest("GET", "/unsubscribe?token="+url.QueryEscape(token), nil)
+	rr = makeHTTPCall(svr.router, req)
+	assert.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+	assert.JSONEq(t, `{"email":"user1@testmail.com"}`, rr.Body.String(), "Response HTTP body in different than expected")
+
+	sent = nil
+	req, _ = http.NewRequest("POST", "/quote/send", nil)
+	rr = makeHTTPCall(svr.router, req)
+	assert.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+	if assert.Len(t, sent, 1, "Suppressed recipients should not be emailed") {
+		assert.Equal(t, "user2@testmail.com", sent[0].To, "Wrong recipient emailed")
+	}
+
+	req, _ = http.NewRequest("GET", "/quote", nil)
+	rr = makeHTTPCall(svr.router, req)
+	var resp HandleQuoteResponse
+	_ = json.Unmarshal(rr.Body.Bytes(), &resp)
+	assert.Equal(t, []recipient.Recipient{{ID: 2, Name: "user2", Email: "user2@testmail.com", Verified: true, Version: 2}}, resp.Recipients, "Suppressed recipients should not be listed")
+}
+
+func TestHandleUnsubscribe_Invalid(t *testing.T) {
+	testCases := []struct {
+		name           string
+		opts           []Option
+		token          string
+		expectedStatus int
+	}{
+		{"Disabled", nil, testSigner.For("unsubscribe", 0).Sign(1, "user1@testmail.com"), http.StatusNotFound},
+		{"VerificationToken", []Option{WithVerification(testSigner, "")}, testSigner.Sign(1, "user1@testmail.com"), http.StatusBadRequest},
+		{"Bogus", []Option{WithVerification(testSigner, "")}, "bogus", http.StatusBadRequest},
+		{"Missing", []Option{WithVerification(testSigner, "")}, "", http.StatusBadRequest},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			svr := newTestServer(t, append(tC.opts, WithSuppressionStore(memstore.NewRecipients(memstore.NewQuotes())))...)
+
+			req, _ := http.NewRequest("GET", "/unsubscribe?token="+url.QueryEscape(tC.token), nil)
+			rr := makeHTTPCall(svr.router, req)
+
+			assert.Equal(t, tC.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+		})
+	}
+}
@@ -0,0 +1,109 @@
+// internal/server/verification.go
+
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/internal/audit"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/jeniok12/golang-tdd-example/internal/verify"
+	"github.com/jeniok12/golang-tdd-example/mailer"
+	"github.com/jeniok12/golang-tdd-example/tenant"
+	"net/http"
+	"net/url"
+)
+
+// sendVerification emails rcpt a link confirming their address, if they
+// should get one. Failing to send is logged; the recipient can be updated to
+// get a new link.
+func (s *Server) sendVerification(ctx context.Context, rcpt recipient.Recipient) {
+	if s.verifiable(ctx, rcpt) {
+		s.mailVerification(ctx, rcpt)
+	}
+}
+
+// verifiable reports whether rcpt should be sent a verification link: there
+// is a signer and a mail sender, and their address is not suppressed.
+func (s *Server) verifiable(ctx context.Context, rcpt recipient.Recipient) bool {
+	if s.signer == nil || s.mailSender == nil {
+		return false
+	}
+
+	kept, err := s.dropSuppressed(ctx, []recipient.Recipient{rcpt})
+	if err != nil {
+		s.logger.Error().Err(err).Int("recipient", rcpt.ID).Msg("checking suppression list")
+		return false
+	}
+	return len(kept) > 0
+}
+
+// verificationEmail is the email sending rcpt their verification link. The
+// link names the tenant of ctx, unless it is the default one.
+func (s *Server) verificationEmail(ctx context.Context, rcpt recipient.Recipient) mailer.Message {
+	link := s.publicURL + "/recipients/verify?token=" + url.QueryEscape(s.signer.Sign(rcpt.ID, rcpt.Email))
+	if id := tenant.FromContext(ctx); id != tenant.Default {
+		link += "&" + tenantParam + "=" + id
+	}
+	return s.withUnsubscribe(verificationMessage(rcpt, link), rcpt.ID)
+}
+
+func (s *Server) mailVerification(ctx context.Context, rcpt recipient.Recipient) {
+	if err := s.mailSender.Send(s.verificationEmail(ctx, rcpt)); err != nil {
+		s.logger.Error().Err(err).Int("recipient", rcpt.ID).Msg("sending verification email")
+	}
+}
+
+// queueVerification puts the verification email of rcpt in the outbox of a
+// transaction, so it only goes out if the recipient is committed. Without an
+// outbox it is a no-op; the handler mails it after the commit instead.
+func (s *Server) queueVerification(ctx context.Context, repos TxRepositories, rcpt recipient.Recipient) error {
+	if repos.Outbox == nil {
+		return nil
+	}
+	_, err := repos.Outbox.Enqueue(ctx, []mailer.Message{s.verificationEmail(ctx, rcpt)})
+	return err
+}
+
+func (s *Server) handleVerifyRecipient() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.signer == nil {
+			s.writeError(w, r, apierror.NotFound("email verification is disabled"))
+			return
+		}
+
+		var req verifyRequest
+		if !s.bindRequest(w, r, &req) {
+			return
+		}
+
+		id, email, err := s.signer.Verify(req.Token)
+		if errors.Is(err, verify.ErrExpiredToken) {
+			s.writeError(w, r, apierror.BadRequest(apierror.CodeInvalidToken, err.Error()))
+			return
+		}
+		if err != nil {
+			s.writeError(w, r, errInvalidToken)
+			return
+		}
+
+		rcpt, err := s.recipientStore.MarkVerified(r.Context(), id, email)
+		if err != nil {
+			s.writeError(w, r, storeError(err))
+			return
+		}
+		s.audit(r, audit.ActionUpdate, audit.EntityRecipient, id)
+
+		writeJSON(w, http.StatusOK, rcpt)
+	}
+}
+
+func verificationMessage(rcpt recipient.Recipient, link string) mailer.Message {
+	return mailer.Message{
+		To:      rcpt.Email,
+		Subject: "Confirm your email for inspiring quotes",
+		Body:    fmt.Sprintf("Hi %s,\n\nPlease confirm your email to start receiving quotes:\n%s\n", rcpt.Name, link),
+	}
+}
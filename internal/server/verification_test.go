@@ -0,0 +1,139 @@
+// internal/server/verification_test.go
+
+package server
+
+import (
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/jeniok12/golang-tdd-example/internal/verify"
+	"github.com/jeniok12/golang-tdd-example/mailer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testSigner = verify.NewSigner([]byte("test-key"), time.Hour)
+
+func TestHandleVerifyRecipient(t *testing.T) {
+	expired := verify.NewSigner(testSigner.Key, -time.Hour)
+
+	testCases := []struct {
+		name           string
+		token          string
+		createMock     func() *MockRecipientStore
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			"Verified",
+			testSigner.Sign(1, "user1@testmail.com"),
+			func() *MockRecipientStore {
+				m := MockRecipientStore{}
+				m.On("MarkVerified", mock.Anything, 1, "user1@testmail.com").
+					Return(&recipient.Recipient{ID: 1, Name: "user1", Email: "user1@testmail.com", Verified: true}, nil)
+				return &m
+			},
+			http.StatusOK,
+			`{"id":1,"name":"user1","email":"user1@testmail.com","verified":true}`,
+		},
+		{
+			"InvalidToken",
+			"bogus",
+			func() *MockRecipientStore { return &MockRecipientStore{} },
+			http.StatusBadRequest,
+			`{"code":"INVALID_TOKEN","message":"invalid verification token"}`,
+		},
+		{
+			"ExpiredToken",
+			expired.Sign(1, "user1@testmail.com"),
+			func() *MockRecipientStore { return &MockRecipientStore{} },
+			http.StatusBadRequest,
+			`{"code":"INVALID_TOKEN","message":"verification token expired"}`,
+		},
+		{
+			"EmailChanged",
+			testSigner.Sign(1, "old@testmail.com"),
+			func() *MockRecipientStore {
+				m := MockRecipientStore{}
+				m.On("MarkVerified", mock.Anything, 1, "old@testmail.com").Return(nil, recipient.ErrNotFound)
+				return &m
+			},
+			http.StatusNotFound,
+			`{"code":"NOT_FOUND","message":"recipient not found"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRecipientStore := tc.createMock()
+			svr := newTestServer(t,
+				WithRecipientStore(mockRecipientStore),
+				WithVerification(testSigner, "http://quotes.test"),
+			)
+
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/recipients/verify?token="+url.QueryEscape(tc.token), nil)
+
+			svr.handleVerifyRecipient()(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			assert.JSONEq(t, tc.expectedBody, rr.Body.String(), "Response HTTP body in different than expected")
+			mockRecipientStore.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandleCreateRecipient_SendsVerification(t *testing.T) {
+	created := &recipient.Recipient{ID: 1, Name: "user1", Email: "user1@testmail.com"}
+	prefix := "http://quotes.test/recipients/verify?token="
+
+	// isVerification matches the verification email whatever the token's expiry.
+	isVerification := mock.MatchedBy(func(msg mailer.Message) bool {
+		i := strings.Index(msg.Body, prefix)
+		if msg.To != created.Email || i < 0 {
+			return false
+		}
+		token, err := url.QueryUnescape(strings.TrimSpace(msg.Body[i+len(prefix):]))
+		if err != nil {
+			return false
+		}
+		id, email, err := testSigner.Verify(token)
+		return err == nil && id == created.ID && email == created.Email
+	})
+
+	testCases := []struct {
+		name    string
+		sendErr error
+	}{
+		{"Sent", nil},
+		{"SendFail", errors.New("sample error")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRecipientStore := MockRecipientStore{}
+			mockRecipientStore.On("Create", mock.Anything, mock.Anything).Return(created, nil)
+			mockMailSender := MockMailSender{}
+			mockMailSender.On("Send", isVerification).Return(tc.sendErr)
+
+			svr := newTestServer(t,
+				WithRecipientStore(&mockRecipientStore),
+				WithMailSender(&mockMailSender),
+				WithVerification(testSigner, "http://quotes.test/"),
+			)
+
+			rr := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/recipients", strings.NewReader(`{"name":"user1","email":"user1@testmail.com"}`))
+
+			svr.handleCreateRecipient()(rr, req)
+
+			assert.Equal(t, http.StatusCreated, rr.Code, "Failing to send the verification should not fail the request")
+			mockMailSender.AssertExpectations(t)
+		})
+	}
+}
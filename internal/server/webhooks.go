@@ -0,0 +1,107 @@
+// internal/server/webhooks.go
+
+package server
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/apierror"
+	"github.com/jeniok12/golang-tdd-example/internal/delivery"
+	"io"
+	"net/http"
+)
+
+// maxWebhookBody caps the size of a webhook request body.
+const maxWebhookBody = 1 << 20
+
+// EmailWebhookResponse ...
+type EmailWebhookResponse struct {
+	Events     int `json:"events"`
+	Suppressed int `json:"suppressed"`
+}
+
+// WithDeliveries updates the delivery attempts in store with the events
+// received on /webhooks/email.
+func WithDeliveries(store delivery.Store) Option {
+	return func(s *Server) error {
+		s.deliveries = store
+		return nil
+	}
+}
+
+// WithEmailWebhook accepts delivery events of provider, sendgrid or ses, on
+// /webhooks/email. Callers must pass secret in the secret query parameter.
+func WithEmailWebhook(provider, secret string) Option {
+	return func(s *Server) error {
+		if provider != delivery.ProviderSendGrid && provider != delivery.ProviderSES {
+			return fmt.Errorf("server: unknown webhook provider %q, expected sendgrid or ses", provider)
+		}
+		if secret == "" {
+			return errors.New("server: webhook secret is empty")
+		}
+		s.webhookProvider = provider
+		s.webhookSecret = secret
+		return nil
+	}
+}
+
+type emailWebhookRequest struct {
+	Secret string `query:"secret" validate:"required" doc:"shared secret configured with the provider's webhook URL"`
+}
+
+func (s *Server) handleEmailWebhook() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.webhookProvider == "" {
+			s.writeError(w, r, apierror.NotFound("the email webhook is disabled"))
+			return
+		}
+
+		var req emailWebhookRequest
+		if !s.bindRequest(w, r, &req) {
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(req.Secret), []byte(s.webhookSecret)) != 1 {
+			s.writeError(w, r, apierror.Unauthorized("invalid webhook secret"))
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+		if err != nil {
+			s.writeError(w, r, apierror.BadRequest(apierror.CodeBadRequest, err.Error()))
+			return
+		}
+
+		events, subscribeURL, err := delivery.Parse(s.webhookProvider, body)
+		if err != nil {
+			s.writeError(w, r, apierror.BadRequest(apierror.CodeBadRequest, err.Error()))
+			return
+		}
+		if subscribeURL != "" {
+			s.logger.Warn().Str("url", subscribeURL).Msg("confirm the SNS subscription of the email webhook by visiting the url")
+		}
+
+		resp := EmailWebhookResponse{Events: len(events)}
+		for _, e := range events {
+			if s.deliveries != nil && e.MessageID != "" {
+				if _, err := s.deliveries.UpdateStatus(r.Context(), e.MessageID, e.Status, e.Detail, s.now()); err != nil {
+					s.writeError(w, r, storeError(err))
+					return
+				}
+			}
+
+			// Hard bounces never get through; mailing them again hurts the
+			// sender's reputation.
+			if e.Status == delivery.StatusBounced && e.Permanent && s.suppressions != nil {
+				if err := s.suppressions.Suppress(r.Context(), e.Email); err != nil {
+					s.writeError(w, r, storeError(err))
+					return
+				}
+				s.logger.Info().Str("email", e.Email).Msg("suppressed hard bounced email")
+				resp.Suppressed++
+			}
+		}
+
+		writeJSON(w, http.StatusOK, resp)
+	}
+}
@@ -0,0 +1,100 @@
+// internal/server/webhooks_test.go
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/jeniok12/golang-tdd-example/internal/delivery"
+	"github.com/jeniok12/golang-tdd-example/internal/memstore"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleEmailWebhook(t *testing.T) {
+	body := `[
+		{"email":"user1@testmail.com","event":"delivered","sg_message_id":"abc.filter0001"},
+		{"email":"user2@testmail.com","event":"bounce","type":"bounce","reason":"550 no such user","sg_message_id":"def.filter0001"},
+		{"email":"user3@testmail.com","event":"bounce","type":"blocked","reason":"421 try later","sg_message_id":"ghi.filter0001"}
+	]`
+
+	testCases := []struct {
+		desc             string
+		webhook          bool
+		secret           string
+		body             string
+		expectedStatus   int
+		expectedResponse EmailWebhookResponse
+	}{
+		{"Events", true, "s3cret", body, http.StatusOK, EmailWebhookResponse{Events: 3, Suppressed: 1}},
+		{"WrongSecret", true, "guess", body, http.StatusUnauthorized, EmailWebhookResponse{}},
+		{"NoSecret", true, "", body, http.StatusBadRequest, EmailWebhookResponse{}},
+		{"InvalidBody", true, "s3cret", `{`, http.StatusBadRequest, EmailWebhookResponse{}},
+		{"Disabled", false, "s3cret", body, http.StatusNotFound, EmailWebhookResponse{}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			ctx := context.Background()
+			now := time.Date(2019, 7, 21, 10, 0, 0, 0, time.UTC)
+			deliveries := memstore.NewDeliveries()
+			for _, id := range []string{"abc", "def", "ghi"} {
+				deliveries.Record(ctx, delivery.Attempt{MessageID: id, Status: delivery.StatusSent, CreatedAt: now})
+			}
+			recipients := memstore.NewRecipients(memstore.NewQuotes())
+
+			opts := []Option{WithDeliveries(deliveries), WithSuppressionStore(recipients)}
+			if tC.webhook {
+				opts = append(opts, WithEmailWebhook(delivery.ProviderSendGrid, "s3cret"))
+			}
+			svr := newTestServer(t, opts...)
+			svr.now = func() time.Time { return now.Add(time.Minute) }
+
+			req, _ := http.NewRequest("POST", "/webhooks/email?secret="+tC.secret, strings.NewReader(tC.body))
+			rr := makeHTTPCall(svr.router, req)
+			assert.Equal(t, tC.expectedStatus, rr.Code, "Response HTTP status in different than expected")
+			if tC.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var resp EmailWebhookResponse
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp), "Got error when not expected")
+			assert.Equal(t, tC.expectedResponse, resp)
+
+			var statuses []string
+			for _, a := range deliveries.Attempts() {
+				statuses = append(statuses, a.Status)
+			}
+			assert.Equal(t, []string{delivery.StatusDelivered, delivery.StatusBounced, delivery.StatusBounced}, statuses)
+
+			suppressed, _ := recipients.Suppressed(ctx, []string{"user2@testmail.com", "user3@testmail.com"})
+			assert.Equal(t, map[string]bool{"user2@testmail.com": true}, suppressed, "Only hard bounces should be suppressed")
+		})
+	}
+}
+
+func TestWithEmailWebhook(t *testing.T) {
+	testCases := []struct {
+		desc               string
+		provider           string
+		secret             string
+		expectedToGetError bool
+	}{
+		{"SendGrid", "sendgrid", "s3cret", false},
+		{"SES", "ses", "s3cret", false},
+		{"SMTP", "smtp", "s3cret", true},
+		{"NoSecret", "sendgrid", "", true},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			err := WithEmailWebhook(tC.provider, tC.secret)(&Server{})
+			if tC.expectedToGetError {
+				assert.Error(t, err, "Got no error when expected")
+				return
+			}
+			assert.NoError(t, err, "Got error when not expected")
+		})
+	}
+}
@@ -0,0 +1,116 @@
+// internal/sqlitestore/outbox.go
+
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/internal/dbtx"
+	"github.com/jeniok12/golang-tdd-example/internal/outbox"
+	"github.com/jeniok12/golang-tdd-example/mailer"
+	"time"
+)
+
+// Outbox keeps queued emails until a worker drains them.
+type Outbox struct {
+	db *DB
+	tx *sql.Tx
+}
+
+// NewOutbox ...
+func NewOutbox(db *DB) *Outbox {
+	return &Outbox{db: db}
+}
+
+// WithTx returns the Outbox queueing its messages in tx.
+func (o *Outbox) WithTx(tx *sql.Tx) *Outbox {
+	return &Outbox{db: o.db, tx: tx}
+}
+
+// Enqueue stores msgs as pending entries in a single transaction, so either
+// every message of a send is queued or none is.
+func (o *Outbox) Enqueue(ctx context.Context, msgs []mailer.Message) ([]outbox.Entry, error) {
+	entries := make([]outbox.Entry, 0, len(msgs))
+	err := dbtx.Within(ctx, o.db.DB, o.tx, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, `INSERT INTO outbox (recipient, subject, body, html, next_attempt_at, created_at)
+			VALUES ($1, $2, $3, $4, $5, $5)
+			RETURNING id, status, attempts, next_attempt_at, created_at`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		now := o.db.now()
+		for _, msg := range msgs {
+			e := outbox.Entry{Message: msg}
+			err := stmt.QueryRowContext(ctx, msg.To, msg.Subject, msg.Body, msg.HTML, now).
+				Scan(&e.ID, &e.Status, &e.Attempts, &e.NextAttemptAt, &e.CreatedAt)
+			if err != nil {
+				return fmt.Errorf("outbox: queueing message to %s: %w", msg.To, err)
+			}
+			entries = append(entries, e)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// Claim returns up to limit pending entries that are due and counts an
+// attempt for each. Claimed entries are not due again until lease has passed.
+// SQLite runs one write at a time, so no two workers claim the same entry.
+func (o *Outbox) Claim(ctx context.Context, limit int, lease time.Duration) ([]outbox.Entry, error) {
+	now := o.db.now()
+
+	query := `UPDATE outbox SET attempts = attempts + 1, next_attempt_at = $3
+		WHERE id IN (
+			SELECT id FROM outbox WHERE status = 'pending' AND next_attempt_at <= $2
+			ORDER BY next_attempt_at, id LIMIT $1
+		)
+		RETURNING id, recipient, subject, body, html, status, attempts, last_error, next_attempt_at, created_at`
+	rows, err := o.db.DB.QueryContext(ctx, query, limit, now, now.Add(lease))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []outbox.Entry
+	for rows.Next() {
+		var e outbox.Entry
+		err := rows.Scan(&e.ID, &e.Message.To, &e.Message.Subject, &e.Message.Body, &e.Message.HTML,
+			&e.Status, &e.Attempts, &e.LastError, &e.NextAttemptAt, &e.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, rows.Err()
+}
+
+// MarkSent ...
+func (o *Outbox) MarkSent(ctx context.Context, id int) error {
+	_, err := o.db.DB.ExecContext(ctx, "UPDATE outbox SET status = 'sent', sent_at = $2, last_error = '' WHERE id = $1", id, o.db.now())
+	return err
+}
+
+// MarkRetry records a failed attempt and when to try again.
+func (o *Outbox) MarkRetry(ctx context.Context, id int, cause string, at time.Time) error {
+	_, err := o.db.DB.ExecContext(ctx, "UPDATE outbox SET last_error = $2, next_attempt_at = $3 WHERE id = $1", id, cause, at.UTC())
+	return err
+}
+
+// MarkFailed gives up on the entry.
+func (o *Outbox) MarkFailed(ctx context.Context, id int, cause string) error {
+	_, err := o.db.DB.ExecContext(ctx, "UPDATE outbox SET status = 'failed', last_error = $2 WHERE id = $1", id, cause)
+	return err
+}
+
+// Counts ...
+func (o *Outbox) Counts(ctx context.Context) (map[string]int, error) {
+	return outbox.CountStatuses(ctx, o.db.DB)
+}
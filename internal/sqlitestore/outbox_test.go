@@ -0,0 +1,63 @@
+// internal/sqlitestore/outbox_test.go
+
+package sqlitestore
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/internal/outbox"
+	"github.com/jeniok12/golang-tdd-example/mailer"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestOutbox(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2021, 1, 1, 9, 0, 0, 0, time.UTC)
+	db := newDB(t)
+	db.now = func() time.Time { return now }
+	o := NewOutbox(db)
+
+	o.Enqueue(ctx, []mailer.Message{{To: "a@testmail.com", Body: "Bla", HTML: "<p>Bla</p>"}, {To: "b@testmail.com"}})
+
+	claimed, err := o.Claim(ctx, 1, time.Minute)
+	assert.NoError(t, err, "Got error when not expected")
+	if assert.Len(t, claimed, 1) {
+		assert.Equal(t, 1, claimed[0].ID)
+		assert.Equal(t, 1, claimed[0].Attempts)
+		assert.Equal(t, mailer.Message{To: "a@testmail.com", Body: "Bla", HTML: "<p>Bla</p>"}, claimed[0].Message, "Message should round trip")
+	}
+
+	claimed, _ = o.Claim(ctx, 10, time.Minute)
+	if assert.Len(t, claimed, 1, "Leased entries should not be claimed again") {
+		assert.Equal(t, 2, claimed[0].ID)
+	}
+
+	o.MarkSent(ctx, 1)
+	o.MarkRetry(ctx, 2, "timeout", now.Add(time.Hour))
+	claimed, _ = o.Claim(ctx, 10, time.Minute)
+	assert.Empty(t, claimed, "Entries should not be claimed before their retry")
+
+	now = now.Add(time.Hour)
+	claimed, _ = o.Claim(ctx, 10, time.Minute)
+	if assert.Len(t, claimed, 1) {
+		assert.Equal(t, 2, claimed[0].Attempts)
+	}
+	o.MarkFailed(ctx, 2, "rejected")
+
+	var statuses, lastErrors []string
+	rows, err := db.DB.Query("SELECT status, last_error FROM outbox ORDER BY id")
+	assert.NoError(t, err, "Got error when not expected")
+	defer rows.Close()
+	for rows.Next() {
+		var status, lastError string
+		rows.Scan(&status, &lastError)
+		statuses, lastErrors = append(statuses, status), append(lastErrors, lastError)
+	}
+	assert.Equal(t, []string{outbox.StatusSent, outbox.StatusFailed}, statuses)
+	assert.Equal(t, []string{"", "rejected"}, lastErrors)
+
+	counts, err := o.Counts(ctx)
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, map[string]int{outbox.StatusPending: 0, outbox.StatusSent: 1, outbox.StatusFailed: 1}, counts)
+}
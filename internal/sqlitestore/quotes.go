@@ -0,0 +1,273 @@
+// internal/sqlitestore/quotes.go
+
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/tenant"
+	"strings"
+	"unicode/utf8"
+)
+
+// Quotes keeps the history of served quotes, one per tenant.
+type Quotes struct {
+	db *DB
+}
+
+// NewQuotes ...
+func NewQuotes(db *DB) *Quotes {
+	return &Quotes{db: db}
+}
+
+// scanRecords reads id, text, author, lang and created_at rows.
+func scanRecords(rows *sql.Rows) ([]quote.Record, error) {
+	defer rows.Close()
+
+	records := []quote.Record{}
+	for rows.Next() {
+		var r quote.Record
+		if err := rows.Scan(&r.ID, &r.Text, &r.Author, &r.Lang, &r.CreatedAt); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+
+	return records, rows.Err()
+}
+
+// historyColumns lists the columns scanned into a Record of the history.
+const historyColumns = "id, text, author, lang, created_at, times_served, last_served_at"
+
+// scanHistory reads historyColumns rows.
+func scanHistory(rows *sql.Rows) ([]quote.Record, error) {
+	defer rows.Close()
+
+	records := []quote.Record{}
+	for rows.Next() {
+		var r quote.Record
+		if err := rows.Scan(&r.ID, &r.Text, &r.Author, &r.Lang, &r.CreatedAt, &r.TimesServed, &r.LastServedAt); err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+
+	return records, rows.Err()
+}
+
+// SaveQuote stores q in the history, once: a quote served again, by
+// quote.Hash, is counted rather than stored again, and keeps its ID and first
+// text.
+func (s *Quotes) SaveQuote(ctx context.Context, q quote.Quote) (*quote.Record, error) {
+	query := `INSERT INTO quotes (tenant_id, text, author, lang, hash, created_at, last_served_at) VALUES ($1, $2, $3, $4, $5, $6, $6)
+		ON CONFLICT (tenant_id, hash) DO UPDATE SET times_served = quotes.times_served + 1, last_served_at = excluded.last_served_at
+		RETURNING ` + historyColumns
+	var r quote.Record
+	row := s.db.DB.QueryRowContext(ctx, query, tenant.FromContext(ctx), q.Text, q.Author, q.Lang, quote.Hash(q), s.db.now())
+	if err := row.Scan(&r.ID, &r.Text, &r.Author, &r.Lang, &r.CreatedAt, &r.TimesServed, &r.LastServedAt); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// ListQuotes returns the quotes served last first. An empty Lang matches all
+// languages.
+func (s *Quotes) ListQuotes(ctx context.Context, filter quote.ListFilter) ([]quote.Record, error) {
+	query := `SELECT ` + historyColumns + ` FROM quotes
+		WHERE tenant_id = $1 AND ($2 = '' OR lang = $2)
+		ORDER BY last_served_at DESC, id DESC
+		LIMIT $3 OFFSET $4`
+
+	rows, err := s.db.DB.QueryContext(ctx, query, tenant.FromContext(ctx), filter.Lang, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanHistory(rows)
+}
+
+// EachQuote calls fn for every stored quote, oldest first, stopping at the
+// first error. An empty lang matches all languages.
+func (s *Quotes) EachQuote(ctx context.Context, lang string, fn func(quote.Record) error) error {
+	query := `SELECT ` + historyColumns + ` FROM quotes
+		WHERE tenant_id = $1 AND ($2 = '' OR lang = $2) AND id > $3
+		ORDER BY id
+		LIMIT $4`
+
+	lastID := 0
+	for {
+		rows, err := s.db.DB.QueryContext(ctx, query, tenant.FromContext(ctx), lang, lastID, eachPageSize)
+		if err != nil {
+			return err
+		}
+		page, err := scanHistory(rows)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range page {
+			if err := fn(r); err != nil {
+				return err
+			}
+		}
+
+		if len(page) < eachPageSize {
+			return nil
+		}
+		lastID = page[len(page)-1].ID
+	}
+}
+
+// SearchQuotes finds the quotes containing every word of the query, best
+// matches first. SQLite has no full text search here, so LIKE narrows down the
+// candidates and quote.Match ranks them.
+func (s *Quotes) SearchQuotes(ctx context.Context, filter quote.SearchFilter) ([]quote.SearchResult, error) {
+	words := quote.SearchWords(filter.Query)
+	if len(words) == 0 {
+		return []quote.SearchResult{}, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("SELECT " + historyColumns + " FROM quotes WHERE tenant_id = $1 AND ($2 = '' OR lang = $2)")
+	args := []interface{}{tenant.FromContext(ctx), filter.Lang}
+	for _, w := range words {
+		// LIKE only folds the case of ASCII letters.
+		if !isASCII(w) {
+			continue
+		}
+		args = append(args, "%"+likeEscaper.Replace(w)+"%")
+		fmt.Fprintf(&sb, ` AND text || ' ' || author LIKE $%d ESCAPE '\'`, len(args))
+	}
+
+	rows, err := s.db.DB.QueryContext(ctx, sb.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+	records, err := scanHistory(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []quote.SearchResult{}
+	for _, r := range records {
+		if m, ok := quote.Match(r, words); ok {
+			results = append(results, m)
+		}
+	}
+
+	return quote.PageResults(results, filter), nil
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// GetQuote ...
+func (s *Quotes) GetQuote(ctx context.Context, id int) (*quote.Record, error) {
+	var r quote.Record
+
+	query := "SELECT " + historyColumns + " FROM quotes WHERE tenant_id = $1 AND id = $2"
+	err := s.db.DB.QueryRowContext(ctx, query, tenant.FromContext(ctx), id).Scan(&r.ID, &r.Text, &r.Author, &r.Lang, &r.CreatedAt, &r.TimesServed, &r.LastServedAt)
+	if err == sql.ErrNoRows {
+		return nil, quote.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// LocalQuotes serves random quotes added by admins, like the local provider.
+type LocalQuotes struct {
+	db *DB
+}
+
+// NewLocalQuotes ...
+func NewLocalQuotes(db *DB) *LocalQuotes {
+	return &LocalQuotes{db: db}
+}
+
+// Generate ...
+func (s *LocalQuotes) Generate(ctx context.Context, lang string) (*quote.Quote, error) {
+	q := quote.Quote{Lang: lang}
+
+	query := "SELECT text, author FROM local_quotes WHERE lang = $1 ORDER BY random() LIMIT 1"
+	err := s.db.DB.QueryRowContext(ctx, query, lang).Scan(&q.Text, &q.Author)
+	if err == sql.ErrNoRows {
+		return nil, quote.ErrNoQuotes
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &q, nil
+}
+
+// FindByAuthor returns a random quote whose author contains author, ignoring
+// the case of ASCII letters. An empty lang matches quotes in any language.
+func (s *LocalQuotes) FindByAuthor(ctx context.Context, lang, author string) (*quote.Quote, error) {
+	return s.Find(ctx, lang, quote.Constraints{Author: author})
+}
+
+// Find returns a random quote satisfying c, matching the author ignoring the
+// case of ASCII letters only. An empty lang matches quotes in any language.
+func (s *LocalQuotes) Find(ctx context.Context, lang string, c quote.Constraints) (*quote.Quote, error) {
+	var q quote.Quote
+
+	query := `SELECT text, author, lang FROM local_quotes
+		WHERE ($1 = '' OR lang = $1) AND lower(author) LIKE '%' || $2 || '%' ESCAPE '\'
+		AND length(trim(text, $5)) >= $3 AND ($4 = 0 OR length(trim(text, $5)) <= $4)
+		ORDER BY random() LIMIT 1`
+	pattern := likeEscaper.Replace(strings.ToLower(strings.TrimSpace(c.Author)))
+	err := s.db.DB.QueryRowContext(ctx, query, lang, pattern, c.MinLength, c.MaxLength, quote.Whitespace).Scan(&q.Text, &q.Author, &q.Lang)
+	if err == sql.ErrNoRows {
+		return nil, quote.ErrNoQuotes
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &q, nil
+}
+
+// AddLocal ...
+func (s *LocalQuotes) AddLocal(ctx context.Context, q quote.Quote) (*quote.Record, error) {
+	r := quote.Record{Quote: quote.Quote{Text: q.Text, Author: q.Author, Lang: q.Lang}}
+
+	query := "INSERT INTO local_quotes (text, author, lang, created_at) VALUES ($1, $2, $3, $4) RETURNING id, created_at"
+	if err := s.db.DB.QueryRowContext(ctx, query, q.Text, q.Author, q.Lang, s.db.now()).Scan(&r.ID, &r.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// ListLocal ...
+func (s *LocalQuotes) ListLocal(ctx context.Context, lang string) ([]quote.Record, error) {
+	query := "SELECT id, text, author, lang, created_at FROM local_quotes WHERE ($1 = '' OR lang = $1) ORDER BY id"
+	rows, err := s.db.DB.QueryContext(ctx, query, lang)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanRecords(rows)
+}
+
+// RemoveLocal ...
+func (s *LocalQuotes) RemoveLocal(ctx context.Context, id int) error {
+	res, err := s.db.DB.ExecContext(ctx, "DELETE FROM local_quotes WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res, quote.ErrNotFound)
+}
@@ -0,0 +1,152 @@
+// internal/sqlitestore/quotes_test.go
+
+package sqlitestore
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestQuotes_ListQuotes(t *testing.T) {
+	s := NewQuotes(newDB(t))
+	for _, q := range []quote.Quote{{Text: "one", Lang: "en"}, {Text: "two", Lang: "ru"}, {Text: "three", Lang: "en"}} {
+		s.SaveQuote(context.Background(), q)
+	}
+
+	testCases := []struct {
+		desc          string
+		filter        quote.ListFilter
+		expectedTexts []string
+	}{
+		{"All", quote.ListFilter{Limit: 10}, []string{"three", "two", "one"}},
+		{"Lang", quote.ListFilter{Lang: "en", Limit: 10}, []string{"three", "one"}},
+		{"Page", quote.ListFilter{Limit: 1, Offset: 1}, []string{"two"}},
+		{"PastTheEnd", quote.ListFilter{Limit: 1, Offset: 3}, []string{}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			records, err := s.ListQuotes(context.Background(), tC.filter)
+			assert.NoError(t, err, "Got error when not expected")
+
+			texts := []string{}
+			for _, r := range records {
+				texts = append(texts, r.Text)
+			}
+			assert.Equal(t, tC.expectedTexts, texts)
+		})
+	}
+}
+
+func TestQuotes_SearchQuotes(t *testing.T) {
+	s := NewQuotes(newDB(t))
+	for _, q := range []quote.Quote{
+		{Text: "Life is short", Author: "Bob", Lang: "en"},
+		{Text: "Art is long", Author: "Alice", Lang: "en"},
+		{Text: "Life, life", Author: "Carol", Lang: "en"},
+		{Text: "Жизнь коротка", Author: "Гиппократ", Lang: "ru"},
+	} {
+		s.SaveQuote(context.Background(), q)
+	}
+
+	testCases := []struct {
+		desc          string
+		filter        quote.SearchFilter
+		expectedTexts []string
+	}{
+		{"BestFirst", quote.SearchFilter{Query: "LIFE", Limit: 10}, []string{"Life, life", "Life is short"}},
+		{"EveryWord", quote.SearchFilter{Query: "life bob", Limit: 10}, []string{"Life is short"}},
+		{"Author", quote.SearchFilter{Query: "alice", Limit: 10}, []string{"Art is long"}},
+		{"NonASCII", quote.SearchFilter{Query: "ЖИЗНЬ", Limit: 10}, []string{"Жизнь коротка"}},
+		{"Lang", quote.SearchFilter{Query: "life", Lang: "ru", Limit: 10}, []string{}},
+		{"Page", quote.SearchFilter{Query: "life", Limit: 1, Offset: 1}, []string{"Life is short"}},
+		{"NoMatch", quote.SearchFilter{Query: "death", Limit: 10}, []string{}},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			results, err := s.SearchQuotes(context.Background(), tC.filter)
+			assert.NoError(t, err, "Got error when not expected")
+
+			texts := []string{}
+			for _, r := range results {
+				texts = append(texts, r.Text)
+			}
+			assert.Equal(t, tC.expectedTexts, texts)
+		})
+	}
+}
+
+func TestQuotes_SaveQuote_Dedup(t *testing.T) {
+	ctx := context.Background()
+	db := newDB(t)
+	s := NewQuotes(db)
+	start := time.Date(2019, 6, 1, 10, 0, 0, 0, time.UTC)
+	now := start
+	db.now = func() time.Time { return now }
+
+	first, err := s.SaveQuote(ctx, quote.Quote{Text: "Life is short", Author: "Bob", Lang: "en"})
+	require.NoError(t, err, "Got error when not expected")
+	now = now.Add(time.Minute)
+	other, err := s.SaveQuote(ctx, quote.Quote{Text: "Art is long", Author: "Alice", Lang: "en"})
+	require.NoError(t, err, "Got error when not expected")
+	now = now.Add(time.Minute)
+	again, err := s.SaveQuote(ctx, quote.Quote{Text: " LIFE IS SHORT ", Author: "bob", Lang: "en"})
+	require.NoError(t, err, "Got error when not expected")
+
+	assert.Equal(t, first.ID, again.ID, "The same quote should be stored once")
+	assert.Equal(t, 2, again.TimesServed, "Serving a quote again should be counted")
+	assert.Equal(t, "Life is short", again.Text, "The first text should be kept")
+	assert.True(t, again.CreatedAt.Equal(start), "The first serve time should be kept")
+	assert.True(t, again.LastServedAt.Equal(now), "The last serve time should be updated")
+
+	records, err := s.ListQuotes(ctx, quote.ListFilter{Limit: 10})
+	require.NoError(t, err, "Got error when not expected")
+	require.Len(t, records, 2)
+	assert.Equal(t, []int{first.ID, other.ID}, []int{records[0].ID, records[1].ID}, "Quotes served last should come first")
+	assert.Equal(t, []int{2, 1}, []int{records[0].TimesServed, records[1].TimesServed})
+
+	acme, err := s.SaveQuote(tenant.NewContext(ctx, "acme"), quote.Quote{Text: "Life is short", Author: "Bob", Lang: "en"})
+	require.NoError(t, err, "Got error when not expected")
+	assert.NotEqual(t, first.ID, acme.ID, "Tenants should have histories of their own")
+	assert.Equal(t, 1, acme.TimesServed)
+}
+
+func TestLocalQuotes(t *testing.T) {
+	ctx := context.Background()
+	s := NewLocalQuotes(newDB(t))
+
+	_, err := s.Generate(ctx, "en")
+	assert.Equal(t, quote.ErrNoQuotes, err)
+
+	s.AddLocal(ctx, quote.Quote{Text: "Bla Bla Bla", Author: "Bob Marley", Lang: "en"})
+	s.AddLocal(ctx, quote.Quote{Text: "Бла", Author: "Боб", Lang: "ru"})
+
+	q, err := s.Generate(ctx, "en")
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, &quote.Quote{Text: "Bla Bla Bla", Author: "Bob Marley", Lang: "en"}, q)
+
+	q, err = s.FindByAuthor(ctx, "", "marley")
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, "Bla Bla Bla", q.Text)
+
+	q, err = s.Find(ctx, "", quote.Constraints{MaxLength: 3})
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, "Бла", q.Text, "Length should count characters")
+	_, err = s.Find(ctx, "en", quote.Constraints{Author: "marley", MaxLength: 10})
+	assert.Equal(t, quote.ErrNoQuotes, err)
+	s.AddLocal(ctx, quote.Quote{Text: "Bla\n", Author: "Bob", Lang: "de"})
+	q, err = s.Find(ctx, "de", quote.Constraints{MaxLength: 3})
+	assert.NoError(t, err, "Whitespace around the text should not count, as in Quote.Length")
+
+	records, _ := s.ListLocal(ctx, "ru")
+	assert.Len(t, records, 1)
+
+	assert.NoError(t, s.RemoveLocal(ctx, 1), "Got error when not expected")
+	assert.Equal(t, quote.ErrNotFound, s.RemoveLocal(ctx, 1))
+	_, err = s.Generate(ctx, "en")
+	assert.Equal(t, quote.ErrNoQuotes, err)
+}
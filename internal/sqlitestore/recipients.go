@@ -0,0 +1,567 @@
+// internal/sqlitestore/recipients.go
+
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/internal/dbtx"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/jeniok12/golang-tdd-example/tenant"
+	"github.com/jmoiron/sqlx"
+	"strings"
+)
+
+// recipientColumns lists the columns scanned into a Recipient.
+const recipientColumns = "id, name, email, verified, lang, frequency, channels, slack_webhook, telegram_chat_id, version"
+
+// Recipients keeps recipients, their groups, tags and favorites. Each tenant
+// sees its own recipients and favorites, the tenant of the context.
+type Recipients struct {
+	db *DB
+	tx *sqlx.Tx
+}
+
+// NewRecipients ...
+func NewRecipients(db *DB) *Recipients {
+	return &Recipients{db: db}
+}
+
+// WithTx returns the Recipients running their queries in tx. SQLite has a
+// single connection, so nothing else may query the pool until tx ends.
+func (s *Recipients) WithTx(tx *sql.Tx) *Recipients {
+	return &Recipients{db: s.db, tx: &sqlx.Tx{Tx: tx, Mapper: s.db.dbx.Mapper}}
+}
+
+// exec is the transaction, if any, or the pool.
+func (s *Recipients) exec() dbtx.Executor {
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.db.DB
+}
+
+// queryer is exec for sqlx, which scans rows into structs.
+func (s *Recipients) queryer() sqlx.QueryerContext {
+	if s.tx != nil {
+		return s.tx
+	}
+	return s.db.dbx
+}
+
+// AllRecipients returns every verified recipient.
+func (s *Recipients) AllRecipients(ctx context.Context) ([]recipient.Recipient, error) {
+	var recipients []recipient.Recipient
+	query := "SELECT " + recipientColumns + " FROM recipients WHERE tenant_id = $1 AND verified ORDER BY id"
+	err := sqlx.SelectContext(ctx, s.queryer(), &recipients, query, tenant.FromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return recipients, nil
+}
+
+// ListRecipients returns one page of the recipients matching opts. SQLite
+// only folds the case of ASCII letters when matching opts.Query.
+func (s *Recipients) ListRecipients(ctx context.Context, opts recipient.ListOptions) ([]recipient.Recipient, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Validate accepts only the id, name and email fields, named like their columns.
+	column, direction := strings.TrimPrefix(opts.OrderBy, "-"), "ASC"
+	if column == "" {
+		column = "id"
+	}
+	if strings.HasPrefix(opts.OrderBy, "-") {
+		direction = "DESC"
+	}
+
+	var sb strings.Builder
+	args := []interface{}{tenant.FromContext(ctx)}
+	sb.WriteString("SELECT " + recipientColumns + " FROM recipients WHERE tenant_id = $1")
+	if opts.Query != "" {
+		args = append(args, "%"+likeEscaper.Replace(strings.ToLower(opts.Query))+"%")
+		sb.WriteString(` AND (lower(name) LIKE $2 ESCAPE '\' OR lower(email) LIKE $2 ESCAPE '\')`)
+	}
+
+	fmt.Fprintf(&sb, " ORDER BY %s %s", column, direction)
+	if column != "id" {
+		sb.WriteString(", id")
+	}
+
+	// SQLite only takes OFFSET after a LIMIT, where -1 means no limit.
+	if opts.Limit > 0 || opts.Offset > 0 {
+		limit := opts.Limit
+		if limit == 0 {
+			limit = -1
+		}
+		args = append(args, limit, opts.Offset)
+		fmt.Fprintf(&sb, " LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+	}
+
+	var recipients []recipient.Recipient
+	if err := sqlx.SelectContext(ctx, s.queryer(), &recipients, sb.String(), args...); err != nil {
+		return nil, err
+	}
+
+	return recipients, nil
+}
+
+// EachRecipient calls fn for every recipient, verified or not, in id order,
+// stopping at the first error.
+func (s *Recipients) EachRecipient(ctx context.Context, fn func(recipient.Recipient) error) error {
+	query := "SELECT " + recipientColumns + " FROM recipients WHERE tenant_id = $1 AND id > $2 ORDER BY id LIMIT $3"
+
+	lastID := 0
+	for {
+		var page []recipient.Recipient
+		if err := sqlx.SelectContext(ctx, s.queryer(), &page, query, tenant.FromContext(ctx), lastID, eachPageSize); err != nil {
+			return err
+		}
+
+		for _, r := range page {
+			if err := fn(r); err != nil {
+				return err
+			}
+		}
+
+		if len(page) < eachPageSize {
+			return nil
+		}
+		lastID = page[len(page)-1].ID
+	}
+}
+
+// Create adds an unverified recipient.
+func (s *Recipients) Create(ctx context.Context, r recipient.Recipient) (*recipient.Recipient, error) {
+	r.Verified = false
+
+	query := "INSERT INTO recipients (tenant_id, name, email) VALUES ($1, $2, $3) RETURNING id, version"
+	err := s.exec().QueryRowContext(ctx, query, tenant.FromContext(ctx), r.Name, r.Email).Scan(&r.ID, &r.Version)
+	if isUniqueViolation(err) {
+		return nil, recipient.ErrDuplicateEmail
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// GetByID ...
+func (s *Recipients) GetByID(ctx context.Context, id int) (*recipient.Recipient, error) {
+	var r recipient.Recipient
+
+	query := "SELECT " + recipientColumns + " FROM recipients WHERE tenant_id = $1 AND id = $2"
+	err := sqlx.GetContext(ctx, s.queryer(), &r, query, tenant.FromContext(ctx), id)
+	if err == sql.ErrNoRows {
+		return nil, recipient.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// Update changes the recipient's name and email. Changing the email clears
+// the verified flag. A non-zero r.Version must match the stored one, or
+// recipient.ErrVersionMismatch is returned.
+func (s *Recipients) Update(ctx context.Context, r recipient.Recipient) (*recipient.Recipient, error) {
+	query := `UPDATE recipients SET name = $1, email = $2, verified = verified AND lower(email) = lower($2),
+		version = version + 1
+		WHERE id = $3 AND ($4 = 0 OR version = $4) AND tenant_id = $5
+		RETURNING verified, lang, frequency, channels, slack_webhook, telegram_chat_id, version`
+	err := s.exec().QueryRowContext(ctx, query, r.Name, r.Email, r.ID, r.Version, tenant.FromContext(ctx)).Scan(&r.Verified, &r.Lang, &r.Frequency, &r.Channels, &r.SlackWebhook, &r.TelegramChatID, &r.Version)
+	if isUniqueViolation(err) {
+		return nil, recipient.ErrDuplicateEmail
+	}
+	if err == sql.ErrNoRows {
+		return nil, s.missing(ctx, r)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// missing tells why updating r matched no row: the recipient is gone, or it
+// changed since r.Version.
+func (s *Recipients) missing(ctx context.Context, r recipient.Recipient) error {
+	if r.Version == 0 {
+		return recipient.ErrNotFound
+	}
+
+	var exists bool
+	query := "SELECT EXISTS (SELECT 1 FROM recipients WHERE id = $1 AND tenant_id = $2)"
+	err := s.exec().QueryRowContext(ctx, query, r.ID, tenant.FromContext(ctx)).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return recipient.ErrNotFound
+	}
+
+	return recipient.ErrVersionMismatch
+}
+
+// MarkVerified marks the recipient verified, provided its email is still the
+// one the verification was issued for.
+func (s *Recipients) MarkVerified(ctx context.Context, id int, email string) (*recipient.Recipient, error) {
+	var r recipient.Recipient
+
+	query := `UPDATE recipients SET verified = TRUE, version = version + 1
+		WHERE id = $1 AND lower(email) = lower($2) AND tenant_id = $3 RETURNING ` + recipientColumns
+	err := sqlx.GetContext(ctx, s.queryer(), &r, query, id, email, tenant.FromContext(ctx))
+	if err == sql.ErrNoRows {
+		return nil, recipient.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// UpdatePreferences replaces the recipient's preferences.
+func (s *Recipients) UpdatePreferences(ctx context.Context, id int, prefs recipient.Preferences) (*recipient.Recipient, error) {
+	var r recipient.Recipient
+
+	query := `UPDATE recipients SET lang = $1, frequency = $2, channels = $3, slack_webhook = $4, telegram_chat_id = $5,
+		version = version + 1 WHERE id = $6 AND tenant_id = $7 RETURNING ` + recipientColumns
+	err := sqlx.GetContext(ctx, s.queryer(), &r, query, prefs.Lang, prefs.Frequency, prefs.Channels, prefs.SlackWebhook, prefs.TelegramChatID, id, tenant.FromContext(ctx))
+	if err == sql.ErrNoRows {
+		return nil, recipient.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &r, nil
+}
+
+// Delete removes the recipient along with its memberships and favorites.
+func (s *Recipients) Delete(ctx context.Context, id int) error {
+	res, err := s.exec().ExecContext(ctx, "DELETE FROM recipients WHERE id = $1 AND tenant_id = $2", id, tenant.FromContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res, recipient.ErrNotFound)
+}
+
+// checkAffected returns notFound when res changed no row.
+func checkAffected(res sql.Result, notFound error) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return notFound
+	}
+
+	return nil
+}
+
+// importBatchSize bounds the rows of a single INSERT, keeping it below the
+// SQLite limit of 32766 bind parameters.
+const importBatchSize = 500
+
+// Import inserts rs as unverified recipients in a single transaction, skipping
+// emails that are already taken, including earlier in rs. The result is
+// indexed like rs, holding nil for skipped recipients.
+func (s *Recipients) Import(ctx context.Context, rs []recipient.Recipient) ([]*recipient.Recipient, error) {
+	tx := s.tx
+	if tx == nil {
+		var err error
+		if tx, err = s.db.dbx.BeginTxx(ctx, nil); err != nil {
+			return nil, err
+		}
+		defer tx.Rollback()
+	}
+
+	created := make([]*recipient.Recipient, len(rs))
+	for start := 0; start < len(rs); start += importBatchSize {
+		end := start + importBatchSize
+		if end > len(rs) {
+			end = len(rs)
+		}
+
+		if err := importBatch(ctx, tx, rs[start:end], created[start:end]); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.tx == nil {
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+	}
+
+	return created, nil
+}
+
+func importBatch(ctx context.Context, tx *sqlx.Tx, batch []recipient.Recipient, created []*recipient.Recipient) error {
+	values := make([]string, 0, len(batch))
+	// The tenant is the first argument of every row.
+	args := make([]interface{}, 1, 1+2*len(batch))
+	args[0] = tenant.FromContext(ctx)
+	// Rows come back keyed by email; only the first of duplicate emails can be inserted.
+	pending := make(map[string]int, len(batch))
+	for i, r := range batch {
+		key := strings.ToLower(r.Email)
+		if _, ok := pending[key]; ok {
+			continue
+		}
+		pending[key] = i
+
+		values = append(values, fmt.Sprintf("($1, $%d, $%d)", len(args)+1, len(args)+2))
+		args = append(args, r.Name, r.Email)
+	}
+	if len(values) == 0 {
+		return nil
+	}
+
+	query := "INSERT INTO recipients (tenant_id, name, email) VALUES " + strings.Join(values, ", ") +
+		" ON CONFLICT (tenant_id, lower(email)) DO NOTHING RETURNING " + recipientColumns
+	rows, err := tx.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var r recipient.Recipient
+		if err := rows.StructScan(&r); err != nil {
+			return err
+		}
+		created[pending[strings.ToLower(r.Email)]] = &r
+	}
+
+	return rows.Err()
+}
+
+// CreateGroup ...
+func (s *Recipients) CreateGroup(ctx context.Context, name string) (*recipient.Group, error) {
+	g := recipient.Group{Name: name}
+
+	err := s.exec().QueryRowContext(ctx, "INSERT INTO groups (name) VALUES ($1) RETURNING id", name).Scan(&g.ID)
+	if isUniqueViolation(err) {
+		return nil, recipient.ErrDuplicateGroup
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &g, nil
+}
+
+// ListGroups returns the groups ordered by name.
+func (s *Recipients) ListGroups(ctx context.Context) ([]recipient.Group, error) {
+	groups := []recipient.Group{}
+	if err := sqlx.SelectContext(ctx, s.queryer(), &groups, "SELECT id, name FROM groups ORDER BY name"); err != nil {
+		return nil, err
+	}
+
+	return groups, nil
+}
+
+// AddMember ...
+func (s *Recipients) AddMember(ctx context.Context, groupID, recipientID int) error {
+	// Groups are shared, their members are not.
+	if _, err := s.GetByID(ctx, recipientID); err != nil {
+		return err
+	}
+
+	query := "INSERT INTO group_members (group_id, recipient_id) VALUES ($1, $2) ON CONFLICT DO NOTHING"
+	_, err := s.exec().ExecContext(ctx, query, groupID, recipientID)
+	if isForeignKeyViolation(err) {
+		return recipient.ErrNotFound
+	}
+
+	return err
+}
+
+// RemoveMember ...
+func (s *Recipients) RemoveMember(ctx context.Context, groupID, recipientID int) error {
+	query := `DELETE FROM group_members WHERE group_id = $1 AND recipient_id = $2
+		AND recipient_id IN (SELECT id FROM recipients WHERE tenant_id = $3)`
+	res, err := s.exec().ExecContext(ctx, query, groupID, recipientID, tenant.FromContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res, recipient.ErrNotFound)
+}
+
+// GroupRecipients returns the verified members of the group with the given name.
+func (s *Recipients) GroupRecipients(ctx context.Context, group string) ([]recipient.Recipient, error) {
+	var groupID int
+	err := s.exec().QueryRowContext(ctx, "SELECT id FROM groups WHERE name = $1", group).Scan(&groupID)
+	if err == sql.ErrNoRows {
+		return nil, recipient.ErrGroupNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT r.id, r.name, r.email, r.verified, r.lang, r.frequency, r.channels, r.slack_webhook, r.telegram_chat_id, r.version FROM recipients r
+		JOIN group_members m ON m.recipient_id = r.id
+		WHERE m.group_id = $1 AND r.tenant_id = $2 AND r.verified ORDER BY r.id`
+	var recipients []recipient.Recipient
+	if err := sqlx.SelectContext(ctx, s.queryer(), &recipients, query, groupID, tenant.FromContext(ctx)); err != nil {
+		return nil, err
+	}
+
+	return recipients, nil
+}
+
+// AddTag tags the recipient. Adding a tag twice is a no-op.
+func (s *Recipients) AddTag(ctx context.Context, recipientID int, tag string) error {
+	if _, err := s.GetByID(ctx, recipientID); err != nil {
+		return err
+	}
+
+	query := "INSERT INTO recipient_tags (recipient_id, tag) VALUES ($1, $2) ON CONFLICT DO NOTHING"
+	_, err := s.exec().ExecContext(ctx, query, recipientID, tag)
+	if isForeignKeyViolation(err) {
+		return recipient.ErrNotFound
+	}
+
+	return err
+}
+
+// RemoveTag ...
+func (s *Recipients) RemoveTag(ctx context.Context, recipientID int, tag string) error {
+	query := `DELETE FROM recipient_tags WHERE recipient_id = $1 AND tag = $2
+		AND recipient_id IN (SELECT id FROM recipients WHERE tenant_id = $3)`
+	res, err := s.exec().ExecContext(ctx, query, recipientID, tag, tenant.FromContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	return checkAffected(res, recipient.ErrNotFound)
+}
+
+// ListTags returns the recipient's tags in alphabetical order.
+func (s *Recipients) ListTags(ctx context.Context, recipientID int) ([]string, error) {
+	if _, err := s.GetByID(ctx, recipientID); err != nil {
+		return nil, err
+	}
+
+	tags := []string{}
+	query := "SELECT tag FROM recipient_tags WHERE recipient_id = $1 ORDER BY tag"
+	if err := sqlx.SelectContext(ctx, s.queryer(), &tags, query, recipientID); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// TaggedRecipients returns the verified recipients having any of tags.
+func (s *Recipients) TaggedRecipients(ctx context.Context, tags []string) ([]recipient.Recipient, error) {
+	if len(tags) == 0 {
+		return nil, nil
+	}
+
+	placeholders := make([]string, len(tags))
+	args := []interface{}{tenant.FromContext(ctx)}
+	for i, tag := range tags {
+		placeholders[i] = fmt.Sprintf("$%d", i+2)
+		args = append(args, tag)
+	}
+	query := `SELECT ` + recipientColumns + ` FROM recipients
+		WHERE tenant_id = $1 AND verified AND id IN (SELECT recipient_id FROM recipient_tags WHERE tag IN (` + strings.Join(placeholders, ", ") + `))
+		ORDER BY id`
+
+	var recipients []recipient.Recipient
+	if err := sqlx.SelectContext(ctx, s.queryer(), &recipients, query, args...); err != nil {
+		return nil, err
+	}
+
+	return recipients, nil
+}
+
+// AddFavorite saves the quote with quoteID as a favorite of the recipient.
+// Saving a favorite twice keeps the original one.
+func (s *Recipients) AddFavorite(ctx context.Context, recipientID, quoteID int) (*recipient.Favorite, error) {
+	// The recipient and the quote of other tenants are as good as missing.
+	if _, err := s.GetByID(ctx, recipientID); err != nil {
+		return nil, err
+	}
+	if ok, err := s.hasQuote(ctx, quoteID); !ok || err != nil {
+		if err == nil {
+			err = recipient.ErrQuoteNotFound
+		}
+		return nil, err
+	}
+
+	query := `INSERT INTO favorites (tenant_id, recipient_id, quote_id, created_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (recipient_id, quote_id) DO NOTHING`
+	_, err := s.exec().ExecContext(ctx, query, tenant.FromContext(ctx), recipientID, quoteID, s.db.now())
+	if isForeignKeyViolation(err) {
+		// Unlike Postgres, SQLite does not tell which key was violated.
+		return nil, s.missingFavoriteKey(ctx, quoteID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var f recipient.Favorite
+	query = `SELECT q.id AS quote_id, q.text, q.author, q.lang, f.created_at FROM favorites f
+		JOIN quotes q ON q.id = f.quote_id
+		WHERE f.recipient_id = $1 AND f.quote_id = $2`
+	if err := sqlx.GetContext(ctx, s.queryer(), &f, query, recipientID, quoteID); err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
+
+// missingFavoriteKey tells whether the quote or the recipient of a favorite
+// is missing.
+func (s *Recipients) missingFavoriteKey(ctx context.Context, quoteID int) error {
+	ok, err := s.hasQuote(ctx, quoteID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return recipient.ErrQuoteNotFound
+	}
+
+	return recipient.ErrNotFound
+}
+
+// hasQuote reports whether the history of the tenant holds the quote.
+func (s *Recipients) hasQuote(ctx context.Context, quoteID int) (bool, error) {
+	var exists bool
+	query := "SELECT EXISTS (SELECT 1 FROM quotes WHERE id = $1 AND tenant_id = $2)"
+	err := s.exec().QueryRowContext(ctx, query, quoteID, tenant.FromContext(ctx)).Scan(&exists)
+	return exists, err
+}
+
+// ListFavorites returns the recipient's favorites, newest first.
+func (s *Recipients) ListFavorites(ctx context.Context, recipientID int) ([]recipient.Favorite, error) {
+	var exists bool
+	query := "SELECT EXISTS (SELECT 1 FROM recipients WHERE id = $1 AND tenant_id = $2)"
+	err := s.exec().QueryRowContext(ctx, query, recipientID, tenant.FromContext(ctx)).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, recipient.ErrNotFound
+	}
+
+	query = `SELECT q.id AS quote_id, q.text, q.author, q.lang, f.created_at FROM favorites f
+		JOIN quotes q ON q.id = f.quote_id
+		WHERE f.recipient_id = $1 AND f.tenant_id = $2 ORDER BY f.created_at DESC, q.id DESC`
+	favorites := []recipient.Favorite{}
+	if err := sqlx.SelectContext(ctx, s.queryer(), &favorites, query, recipientID, tenant.FromContext(ctx)); err != nil {
+		return nil, err
+	}
+
+	return favorites, nil
+}
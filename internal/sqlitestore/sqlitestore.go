@@ -0,0 +1,91 @@
+// internal/sqlitestore/sqlitestore.go
+
+// Package sqlitestore keeps everything the server persists in SQLite, so the
+// full stack runs without Postgres. The schema comes from the Postgres
+// migrations, and the stores mirror the behavior and errors of their Postgres
+// counterparts.
+package sqlitestore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/internal/migrations"
+	"github.com/jeniok12/golang-tdd-example/tracing"
+	"github.com/jmoiron/sqlx"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+	"strings"
+	"time"
+)
+
+// DB is a SQLite database shared by the stores.
+type DB struct {
+	DB *sql.DB
+
+	dbx *sqlx.DB
+	// now stamps rows in Go rather than with CURRENT_TIMESTAMP, so every
+	// time is stored in the same format and compares as text.
+	now func() time.Time
+}
+
+// Open opens the SQLite database at path, creating the file if needed. The
+// path ":memory:" opens a database that lives as long as the DB.
+func Open(path string) (*DB, error) {
+	dsn := "file:" + path + "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_time_format=sqlite"
+	db, err := tracing.OpenDB("sqlite", dsn, "sqlite")
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite allows a single writer anyway, and every connection to
+	// :memory: would get a database of its own.
+	db.SetMaxOpenConns(1)
+
+	return &DB{
+		DB:  db,
+		dbx: sqlx.NewDb(db, "sqlite3"),
+		now: func() time.Time { return time.Now().UTC() },
+	}, nil
+}
+
+// Migrate brings the database schema up to date.
+func (db *DB) Migrate(ctx context.Context) error {
+	return migrations.UpSQLite(ctx, db.DB)
+}
+
+// Ping ...
+func (db *DB) Ping(ctx context.Context) error {
+	return db.DB.PingContext(ctx)
+}
+
+// Stats ...
+func (db *DB) Stats() sql.DBStats {
+	return db.DB.Stats()
+}
+
+// Close ...
+func (db *DB) Close() error {
+	return db.DB.Close()
+}
+
+// isSQLiteError reports whether err is a SQLite error with the extended code.
+func isSQLiteError(err error, code int) bool {
+	var sqliteErr *sqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == code
+}
+
+func isUniqueViolation(err error) bool {
+	return isSQLiteError(err, sqlite3.SQLITE_CONSTRAINT_UNIQUE)
+}
+
+func isForeignKeyViolation(err error) bool {
+	return isSQLiteError(err, sqlite3.SQLITE_CONSTRAINT_FOREIGNKEY)
+}
+
+// likeEscaper makes a search term match literally inside a LIKE pattern
+// declared with ESCAPE '\'.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// eachPageSize is the number of rows fetched per query when iterating.
+const eachPageSize = 500
@@ -0,0 +1,42 @@
+// internal/sqlitestore/sqlitestore_test.go
+
+package sqlitestore
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"testing"
+)
+
+// newDB returns a migrated in-memory database.
+func newDB(t *testing.T) *DB {
+	db, err := Open(":memory:")
+	require.NoError(t, err, "Got error when not expected")
+	t.Cleanup(func() { db.Close() })
+
+	require.NoError(t, db.Migrate(context.Background()), "Got error when not expected")
+	return db
+}
+
+func TestDB_Migrate(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "quotes.db")
+
+	db, err := Open(path)
+	require.NoError(t, err, "Got error when not expected")
+	require.NoError(t, db.Migrate(ctx), "Got error when not expected")
+	_, err = NewRecipients(db).Create(ctx, recipientNamed("alice"))
+	require.NoError(t, err, "Got error when not expected")
+	require.NoError(t, db.Close(), "Got error when not expected")
+
+	db, err = Open(path)
+	require.NoError(t, err, "Got error when not expected")
+	defer db.Close()
+	require.NoError(t, db.Migrate(ctx), "Migrating twice should be a no-op")
+	require.NoError(t, db.Ping(ctx), "Got error when not expected")
+
+	r, err := NewRecipients(db).GetByID(ctx, 1)
+	require.NoError(t, err, "Recipients should survive reopening the file")
+	require.Equal(t, "alice", r.Name)
+}
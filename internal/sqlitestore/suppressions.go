@@ -0,0 +1,48 @@
+// internal/sqlitestore/suppressions.go
+
+package sqlitestore
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+)
+
+// Suppress adds email to the suppression list. Suppressing an email twice
+// is not an error.
+func (s *Recipients) Suppress(ctx context.Context, email string) error {
+	query := "INSERT INTO suppressions (email) VALUES (lower($1)) ON CONFLICT (email) DO NOTHING"
+	_, err := s.exec().ExecContext(ctx, query, email)
+	return err
+}
+
+// Suppressed returns which of emails are on the suppression list, keyed by
+// their lowercased form. The emails are passed as a single JSON array, so
+// no number of them runs into the bind parameter limit.
+func (s *Recipients) Suppressed(ctx context.Context, emails []string) (map[string]bool, error) {
+	lowered := make([]string, len(emails))
+	for i, email := range emails {
+		lowered[i] = strings.ToLower(email)
+	}
+	arg, err := json.Marshal(lowered)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.exec().QueryContext(ctx, "SELECT email FROM suppressions WHERE email IN (SELECT value FROM json_each($1))", string(arg))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suppressed := make(map[string]bool)
+	for rows.Next() {
+		var email string
+		if err := rows.Scan(&email); err != nil {
+			return nil, err
+		}
+		suppressed[email] = true
+	}
+
+	return suppressed, rows.Err()
+}
@@ -0,0 +1,136 @@
+// internal/templates/templates.go
+
+// Package templates renders the emails the server sends. An email named
+// quote is made of quote.subject.txt, quote.txt and an optional quote.html;
+// the text parts use text/template and the HTML part html/template.
+package templates
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	texttemplate "text/template"
+)
+
+//go:embed email
+var embedded embed.FS
+
+// Email is a rendered email.
+type Email struct {
+	Subject string `json:"subject"`
+	Text    string `json:"text"`
+	HTML    string `json:"html,omitempty"`
+}
+
+// Set is a set of email templates.
+type Set struct {
+	fsys fs.FS
+	// reload parses the templates on every render, so edits show up without
+	// a restart.
+	reload bool
+
+	mu     sync.Mutex
+	parsed *parsed
+}
+
+type parsed struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+// Embedded returns the templates built into the binary.
+func Embedded() *Set {
+	sub, _ := fs.Sub(embedded, "email")
+	return &Set{fsys: sub}
+}
+
+// Dir returns the templates in dir, reloaded on every render. It is meant for
+// editing templates while the server runs; the templates are parsed once up
+// front so mistakes show at startup.
+func Dir(dir string) (*Set, error) {
+	s := &Set{fsys: os.DirFS(dir), reload: true}
+	if _, err := parse(s.fsys); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func parse(fsys fs.FS) (*parsed, error) {
+	text, err := texttemplate.New("").ParseFS(fsys, "*.txt")
+	if err != nil {
+		return nil, fmt.Errorf("templates: %v", err)
+	}
+
+	p := &parsed{text: text}
+	if matches, _ := fs.Glob(fsys, "*.html"); len(matches) > 0 {
+		if p.html, err = htmltemplate.New("").ParseFS(fsys, "*.html"); err != nil {
+			return nil, fmt.Errorf("templates: %v", err)
+		}
+	}
+
+	return p, nil
+}
+
+func (s *Set) templates() (*parsed, error) {
+	if s.reload {
+		return parse(s.fsys)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.parsed == nil {
+		p, err := parse(s.fsys)
+		if err != nil {
+			return nil, err
+		}
+		s.parsed = p
+	}
+	return s.parsed, nil
+}
+
+// Render renders the email called name with data.
+func (s *Set) Render(name string, data interface{}) (Email, error) {
+	p, err := s.templates()
+	if err != nil {
+		return Email{}, err
+	}
+
+	var e Email
+	subject, err := executeText(p.text, name+".subject.txt", data)
+	if err != nil {
+		return Email{}, err
+	}
+	e.Subject = strings.TrimSpace(subject)
+
+	if e.Text, err = executeText(p.text, name+".txt", data); err != nil {
+		return Email{}, err
+	}
+
+	if p.html != nil && p.html.Lookup(name+".html") != nil {
+		var buf bytes.Buffer
+		if err := p.html.ExecuteTemplate(&buf, name+".html", data); err != nil {
+			return Email{}, fmt.Errorf("templates: %v", err)
+		}
+		e.HTML = buf.String()
+	}
+
+	return e, nil
+}
+
+func executeText(t *texttemplate.Template, name string, data interface{}) (string, error) {
+	if t.Lookup(name) == nil {
+		return "", fmt.Errorf("templates: no template %q", name)
+	}
+
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, name, data); err != nil {
+		return "", fmt.Errorf("templates: %v", err)
+	}
+	return buf.String(), nil
+}
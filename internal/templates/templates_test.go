@@ -0,0 +1,83 @@
+// internal/templates/templates_test.go
+
+package templates
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type quoteData struct {
+	Recipient      struct{ Name string }
+	Quote          struct{ Text, Author string }
+	UnsubscribeURL string
+}
+
+func newQuoteData(unsubscribeURL string) quoteData {
+	var d quoteData
+	d.Recipient.Name = "<user1>"
+	d.Quote.Text, d.Quote.Author = "Bla", "Bob"
+	d.UnsubscribeURL = unsubscribeURL
+	return d
+}
+
+func TestEmbedded_Render(t *testing.T) {
+	testCases := []struct {
+		desc           string
+		unsubscribeURL string
+		expectedText   string
+	}{
+		{"WithoutUnsubscribe", "", "Hi <user1>,\n\nBla\n- Bob\n"},
+		{"WithUnsubscribe", "http://quotes.test/unsubscribe?token=a&b", "Hi <user1>,\n\nBla\n- Bob\n\n--\nUnsubscribe: http://quotes.test/unsubscribe?token=a&b\n"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			e, err := Embedded().Render("quote", newQuoteData(tC.unsubscribeURL))
+			assert.NoError(t, err, "Got error when not expected")
+
+			assert.Equal(t, "Your inspiring quote", e.Subject)
+			assert.Equal(t, tC.expectedText, e.Text, "Text should not be HTML escaped")
+			assert.Contains(t, e.HTML, "Hi &lt;user1&gt;,", "HTML should be escaped")
+			if tC.unsubscribeURL != "" {
+				assert.Contains(t, e.HTML, `href="http://quotes.test/unsubscribe?token=a&amp;b"`)
+			} else {
+				assert.NotContains(t, e.HTML, "Unsubscribe")
+			}
+		})
+	}
+}
+
+func TestRender_UnknownTemplate(t *testing.T) {
+	_, err := Embedded().Render("welcome", nil)
+	assert.Error(t, err, "Got no error when expected")
+}
+
+func TestDir(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644))
+	}
+	write("quote.subject.txt", "Hello")
+	write("quote.txt", "{{.Quote.Text}}")
+
+	set, err := Dir(dir)
+	require.NoError(t, err, "Got error when not expected")
+
+	e, err := set.Render("quote", newQuoteData(""))
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, Email{Subject: "Hello", Text: "Bla"}, e, "HTML should be optional")
+
+	write("quote.txt", "{{.Quote.Author}}")
+	e, _ = set.Render("quote", newQuoteData(""))
+	assert.Equal(t, "Bob", e.Text, "Edited templates should be reloaded")
+
+	write("quote.txt", "{{.Quote.Author")
+	_, err = set.Render("quote", newQuoteData(""))
+	assert.Error(t, err, "Broken templates should fail to render")
+
+	_, err = Dir(dir)
+	assert.Error(t, err, "Broken templates should fail at startup")
+}
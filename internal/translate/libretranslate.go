@@ -0,0 +1,79 @@
+// internal/translate/libretranslate.go
+
+package translate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/requestid"
+	"net/http"
+	"strings"
+)
+
+// LibreTranslate translates with a LibreTranslate server, see
+// https://libretranslate.com/docs.
+type LibreTranslate struct {
+	URL string
+	// APIKey is only needed by servers that require one.
+	APIKey string
+	Client quote.HTTPWrapper
+}
+
+type libreTranslateRequest struct {
+	Q      string `json:"q"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Format string `json:"format"`
+	APIKey string `json:"api_key,omitempty"`
+}
+
+type libreTranslateResponse struct {
+	TranslatedText string `json:"translatedText"`
+	Error          string `json:"error"`
+}
+
+// Translate ...
+func (l *LibreTranslate) Translate(ctx context.Context, text, source, target string) (string, error) {
+	body, err := json.Marshal(libreTranslateRequest{
+		Q:      text,
+		Source: source,
+		Target: target,
+		Format: "text",
+		APIKey: l.APIKey,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", strings.TrimSuffix(l.URL, "/")+"/translate", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if id := requestid.FromContext(ctx); id != "" {
+		req.Header.Set(requestid.Header, id)
+	}
+
+	resp, err := l.Client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var res libreTranslateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&res); err != nil && resp.StatusCode == http.StatusOK {
+		return "", err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		if res.Error != "" {
+			return "", fmt.Errorf("libretranslate: %s (status %d)", res.Error, resp.StatusCode)
+		}
+		return "", &quote.StatusError{StatusCode: resp.StatusCode}
+	}
+
+	return res.TranslatedText, nil
+}
@@ -0,0 +1,63 @@
+// internal/translate/libretranslate_test.go
+
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/requestid"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLibreTranslate_Translate(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "/translate", req.URL.Path, "Wrong path")
+		assert.Equal(t, "abc123", req.Header.Get(requestid.Header), "Request ID should be propagated")
+
+		var body libreTranslateRequest
+		_ = json.NewDecoder(req.Body).Decode(&body)
+		assert.Equal(t, libreTranslateRequest{Q: "Hello", Source: "en", Target: "fr", Format: "text", APIKey: "secret"}, body, "Wrong request body")
+
+		rw.Write([]byte(`{"translatedText":"Bonjour"}`))
+	}))
+	defer server.Close()
+
+	libre := LibreTranslate{URL: server.URL + "/", APIKey: "secret", Client: server.Client()}
+
+	text, err := libre.Translate(requestid.NewContext(context.Background(), "abc123"), "Hello", "en", "fr")
+
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, "Bonjour", text, "Translation is different than expected")
+}
+
+func TestLibreTranslate_TranslateError(t *testing.T) {
+	testCases := []struct {
+		name     string
+		status   int
+		body     string
+		expected string
+	}{
+		{"ErrorMessage", http.StatusBadRequest, `{"error":"fr is not supported"}`, "libretranslate: fr is not supported (status 400)"},
+		{"Status", http.StatusInternalServerError, "oops", (&quote.StatusError{StatusCode: http.StatusInternalServerError}).Error()},
+		{"InvalidBody", http.StatusOK, "oops", "invalid character 'o' looking for beginning of value"},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				rw.WriteHeader(tC.status)
+				rw.Write([]byte(tC.body))
+			}))
+			defer server.Close()
+
+			libre := LibreTranslate{URL: server.URL, Client: server.Client()}
+
+			_, err := libre.Translate(context.Background(), "Hello", "en", "fr")
+
+			assert.EqualError(t, err, tC.expected, "Error is different than expected")
+		})
+	}
+}
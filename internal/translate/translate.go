@@ -0,0 +1,59 @@
+// internal/translate/translate.go
+
+// Package translate serves quotes in languages their providers do not have,
+// by translating them.
+package translate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"time"
+)
+
+// Translator translates text between languages given as ISO 639-1 codes.
+type Translator interface {
+	Translate(ctx context.Context, text, source, target string) (string, error)
+}
+
+// Quote returns a copy of q translated to target, recording the language it
+// was translated from in OriginalLang.
+func Quote(ctx context.Context, t Translator, q *quote.Quote, target string) (*quote.Quote, error) {
+	text, err := t.Translate(ctx, q.Text, q.Lang, target)
+	if err != nil {
+		return nil, err
+	}
+
+	translated := *q
+	translated.Text = text
+	translated.Lang = target
+	translated.OriginalLang = q.Lang
+
+	return &translated, nil
+}
+
+// Cached remembers translations for TTL. It shares the quote cache storage,
+// keeping the translated text in the cached quote.
+type Cached struct {
+	Inner Translator
+	Cache quote.Cache
+	TTL   time.Duration
+}
+
+// Translate ...
+func (c *Cached) Translate(ctx context.Context, text, source, target string) (string, error) {
+	sum := sha256.Sum256([]byte(text))
+	key := "translate:" + source + ":" + target + ":" + hex.EncodeToString(sum[:])
+	if q, ok := c.Cache.Get(key); ok {
+		return q.Text, nil
+	}
+
+	translated, err := c.Inner.Translate(ctx, text, source, target)
+	if err != nil {
+		return "", err
+	}
+	c.Cache.Set(key, &quote.Quote{Text: translated, Lang: target}, c.TTL)
+
+	return translated, nil
+}
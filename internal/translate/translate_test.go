@@ -0,0 +1,64 @@
+// internal/translate/translate_test.go
+
+package translate
+
+import (
+	"context"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type countingTranslator struct {
+	calls int
+	err   error
+}
+
+func (t *countingTranslator) Translate(ctx context.Context, text, source, target string) (string, error) {
+	t.calls++
+	if t.err != nil {
+		return "", t.err
+	}
+	return target + ":" + text, nil
+}
+
+func TestQuote(t *testing.T) {
+	q := &quote.Quote{Text: "Bla Bla Bla", Author: "Bob", Lang: "en"}
+
+	translated, err := Quote(context.Background(), &countingTranslator{}, q, "fr")
+
+	assert.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, &quote.Quote{Text: "fr:Bla Bla Bla", Author: "Bob", Lang: "fr", OriginalLang: "en"}, translated, "Translated quote is different than expected")
+	assert.Equal(t, "Bla Bla Bla", q.Text, "Original quote should not change")
+}
+
+func TestQuote_Error(t *testing.T) {
+	_, err := Quote(context.Background(), &countingTranslator{err: errors.New("sample error")}, &quote.Quote{Lang: "en"}, "fr")
+
+	assert.Error(t, err, "Got no error when expected")
+}
+
+func TestCached_Translate(t *testing.T) {
+	inner := &countingTranslator{}
+	cached := Cached{Inner: inner, Cache: quote.NewLRU(10), TTL: time.Minute}
+
+	for _, target := range []string{"fr", "fr", "de"} {
+		text, err := cached.Translate(context.Background(), "Bla", "en", target)
+		assert.NoError(t, err, "Got error when not expected")
+		assert.Equal(t, target+":Bla", text, "Translation is different than expected")
+	}
+
+	assert.Equal(t, 2, inner.calls, "Inner translator should be called once per language")
+}
+
+func TestCached_TranslateError(t *testing.T) {
+	lru := quote.NewLRU(10)
+	cached := Cached{Inner: &countingTranslator{err: errors.New("sample error")}, Cache: lru, TTL: time.Minute}
+
+	_, err := cached.Translate(context.Background(), "Bla", "en", "fr")
+
+	assert.Error(t, err, "Got no error when expected")
+	assert.Equal(t, 0, lru.Len(), "Errors should not be cached")
+}
@@ -0,0 +1,60 @@
+// internal/verify/verify_test.go
+
+package verify
+
+import (
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSigner(t *testing.T) {
+	now := time.Date(2019, 5, 26, 12, 0, 0, 0, time.UTC)
+	signer := NewSigner([]byte("secret"), time.Hour)
+	signer.now = func() time.Time { return now }
+
+	token := signer.Sign(42, "user1@testmail.com")
+
+	id, email, err := signer.Verify(token)
+	assert.NoError(t, err, "Should have no error for a fresh token")
+	assert.Equal(t, 42, id, "ID should round trip")
+	assert.Equal(t, "user1@testmail.com", email, "Email should round trip")
+
+	other := NewSigner([]byte("other"), time.Hour)
+	other.now = signer.now
+	_, _, err = other.Verify(token)
+	assert.Equal(t, ErrInvalidToken, err, "Token signed with another key should be rejected")
+
+	payload := strings.Split(token, ".")[0]
+	forged := strings.Split(signer.Sign(43, "user1@testmail.com"), ".")[1]
+	_, _, err = signer.Verify(payload + "." + forged)
+	assert.Equal(t, ErrInvalidToken, err, "Mismatched signature should be rejected")
+
+	_, _, err = signer.Verify("garbage")
+	assert.Equal(t, ErrInvalidToken, err, "Malformed token should be rejected")
+
+	now = now.Add(2 * time.Hour)
+	_, _, err = signer.Verify(token)
+	assert.Equal(t, ErrExpiredToken, err, "Expired token should be rejected")
+}
+
+func TestSigner_For(t *testing.T) {
+	now := time.Date(2019, 7, 7, 12, 0, 0, 0, time.UTC)
+	signer := NewSigner([]byte("secret"), time.Hour)
+	signer.now = func() time.Time { return now }
+	unsubscribe := signer.For("unsubscribe", 0)
+
+	token := unsubscribe.Sign(42, "user1@testmail.com")
+
+	_, _, err := signer.Verify(token)
+	assert.Equal(t, ErrInvalidToken, err, "Token signed for another purpose should be rejected")
+	_, _, err = unsubscribe.Verify(signer.Sign(42, "user1@testmail.com"))
+	assert.Equal(t, ErrInvalidToken, err, "Token signed without the purpose should be rejected")
+
+	now = now.AddDate(10, 0, 0)
+	id, email, err := unsubscribe.Verify(token)
+	assert.NoError(t, err, "Tokens without a TTL should not expire")
+	assert.Equal(t, 42, id, "ID should round trip")
+	assert.Equal(t, "user1@testmail.com", email, "Email should round trip")
+}
@@ -0,0 +1,292 @@
+// internal/webhook/webhook.go
+
+// Package webhook notifies the URLs admins register of what happens to
+// quotes. Every event is queued as a delivery per hook, then POSTed by the
+// Worker as a JSON payload signed with the secret of the hook.
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// ErrNotFound ...
+var ErrNotFound = errors.New("webhook not found")
+
+// Events sent to the hooks.
+const (
+	EventQuoteGenerated = "quote.generated"
+	EventQuoteDelivered = "quote.delivered"
+)
+
+// Delivery statuses.
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+// Hook is a URL registered to receive the events.
+type Hook struct {
+	ID  int    `json:"id"`
+	URL string `json:"url"`
+	// Secret signs the payloads. It is only shown when the hook is created.
+	Secret    string    `json:"-"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Event is the payload POSTed to the hooks.
+type Event struct {
+	Type      string      `json:"type"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Data      interface{} `json:"data"`
+}
+
+// Delivery is an event queued for, or POSTed to, a hook.
+type Delivery struct {
+	ID       int             `json:"id"`
+	HookID   int             `json:"hookId"`
+	Event    string          `json:"event"`
+	Payload  json.RawMessage `json:"payload"`
+	Status   string          `json:"status"`
+	Attempts int             `json:"attempts"`
+	// ResponseStatus is the HTTP status of the last attempt, zero when the
+	// hook could not be reached.
+	ResponseStatus int        `json:"responseStatus,omitempty"`
+	LastError      string     `json:"lastError,omitempty"`
+	NextAttemptAt  time.Time  `json:"nextAttemptAt"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	DeliveredAt    *time.Time `json:"deliveredAt,omitempty"`
+
+	// URL and Secret are those of the hook, set by Claim.
+	URL    string `json:"-"`
+	Secret string `json:"-"`
+}
+
+// Store keeps the hooks and their delivery log.
+type Store interface {
+	Queue
+	CreateHook(ctx context.Context, h Hook) (*Hook, error)
+	ListHooks(ctx context.Context) ([]Hook, error)
+	// DeleteHook removes the hook and its deliveries.
+	DeleteHook(ctx context.Context, id int) error
+	// Enqueue queues payload for every hook, due right away.
+	Enqueue(ctx context.Context, event string, payload []byte) ([]Delivery, error)
+	// ListDeliveries returns up to limit deliveries to the hook, newest first.
+	ListDeliveries(ctx context.Context, hookID, limit int) ([]Delivery, error)
+}
+
+// Sign returns the signature of a payload sent at timestamp, in Unix
+// seconds: the hex HMAC-SHA256, keyed with secret, of the timestamp, a dot
+// and the body. Signing the timestamp lets receivers reject replays.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Persistence keeps the hooks in the webhooks table and their deliveries in
+// webhook_deliveries. Like the audit log, its queries run on both Postgres
+// and SQLite, with times stored in UTC.
+type Persistence struct {
+	DB *sql.DB
+
+	now func() time.Time
+}
+
+func (p *Persistence) clock() time.Time {
+	if p.now != nil {
+		return p.now().UTC()
+	}
+	return time.Now().UTC()
+}
+
+// CreateHook ...
+func (p *Persistence) CreateHook(ctx context.Context, h Hook) (*Hook, error) {
+	h.CreatedAt = p.clock()
+	query := "INSERT INTO webhooks (url, secret, created_at) VALUES ($1, $2, $3) RETURNING id"
+	if err := p.DB.QueryRowContext(ctx, query, h.URL, h.Secret, h.CreatedAt).Scan(&h.ID); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// ListHooks returns every hook, oldest first.
+func (p *Persistence) ListHooks(ctx context.Context) ([]Hook, error) {
+	rows, err := p.DB.QueryContext(ctx, "SELECT id, url, secret, created_at FROM webhooks ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := []Hook{}
+	for rows.Next() {
+		var h Hook
+		if err := rows.Scan(&h.ID, &h.URL, &h.Secret, &h.CreatedAt); err != nil {
+			return nil, err
+		}
+		h.CreatedAt = h.CreatedAt.UTC()
+		hooks = append(hooks, h)
+	}
+
+	return hooks, rows.Err()
+}
+
+// DeleteHook ...
+func (p *Persistence) DeleteHook(ctx context.Context, id int) error {
+	// SQLite only cascades with foreign keys on, so the deliveries go first.
+	if _, err := p.DB.ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE webhook_id = $1", id); err != nil {
+		return err
+	}
+
+	res, err := p.DB.ExecContext(ctx, "DELETE FROM webhooks WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+const deliveryColumns = `id, webhook_id, event, payload, status, attempts, response_status, last_error,
+	next_attempt_at, created_at, delivered_at`
+
+func scanDelivery(rows *sql.Rows) (Delivery, error) {
+	var d Delivery
+	var payload string
+	var deliveredAt sql.NullTime
+	err := rows.Scan(&d.ID, &d.HookID, &d.Event, &payload, &d.Status, &d.Attempts, &d.ResponseStatus, &d.LastError,
+		&d.NextAttemptAt, &d.CreatedAt, &deliveredAt)
+	if err != nil {
+		return Delivery{}, err
+	}
+	d.Payload = json.RawMessage(payload)
+	d.NextAttemptAt, d.CreatedAt = d.NextAttemptAt.UTC(), d.CreatedAt.UTC()
+	if deliveredAt.Valid {
+		at := deliveredAt.Time.UTC()
+		d.DeliveredAt = &at
+	}
+	return d, nil
+}
+
+func collectDeliveries(rows *sql.Rows) ([]Delivery, error) {
+	defer rows.Close()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	return deliveries, rows.Err()
+}
+
+// Enqueue ...
+func (p *Persistence) Enqueue(ctx context.Context, event string, payload []byte) ([]Delivery, error) {
+	query := `INSERT INTO webhook_deliveries (webhook_id, event, payload, next_attempt_at, created_at)
+		SELECT id, $1, $2, $3, $3 FROM webhooks ORDER BY id
+		RETURNING ` + deliveryColumns
+	rows, err := p.DB.QueryContext(ctx, query, event, string(payload), p.clock())
+	if err != nil {
+		return nil, err
+	}
+	return collectDeliveries(rows)
+}
+
+// ListDeliveries ...
+func (p *Persistence) ListDeliveries(ctx context.Context, hookID, limit int) ([]Delivery, error) {
+	var exists bool
+	err := p.DB.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM webhooks WHERE id = $1)", hookID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	query := "SELECT " + deliveryColumns + " FROM webhook_deliveries WHERE webhook_id = $1 ORDER BY id DESC LIMIT $2"
+	rows, err := p.DB.QueryContext(ctx, query, hookID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return collectDeliveries(rows)
+}
+
+// Claim returns up to limit pending deliveries that are due and counts an
+// attempt for each. Claimed deliveries are not due again until lease has
+// passed. The outer conditions are checked again once a row is locked, so
+// concurrent workers never claim the same delivery.
+func (p *Persistence) Claim(ctx context.Context, limit int, lease time.Duration) ([]Delivery, error) {
+	now := p.clock()
+	query := `UPDATE webhook_deliveries SET attempts = attempts + 1, next_attempt_at = $3
+		WHERE status = 'pending' AND next_attempt_at <= $2 AND id IN (
+			SELECT id FROM webhook_deliveries WHERE status = 'pending' AND next_attempt_at <= $2
+			ORDER BY next_attempt_at, id LIMIT $1
+		)
+		RETURNING ` + deliveryColumns
+	rows, err := p.DB.QueryContext(ctx, query, limit, now, now.Add(lease))
+	if err != nil {
+		return nil, err
+	}
+	claimed, err := collectDeliveries(rows)
+	if err != nil || len(claimed) == 0 {
+		return claimed, err
+	}
+
+	hooks, err := p.ListHooks(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return withHooks(claimed, hooks), nil
+}
+
+// withHooks sets the URL and secret of the hook on each delivery.
+func withHooks(deliveries []Delivery, hooks []Hook) []Delivery {
+	byID := make(map[int]Hook, len(hooks))
+	for _, h := range hooks {
+		byID[h.ID] = h
+	}
+	for i := range deliveries {
+		h := byID[deliveries[i].HookID]
+		deliveries[i].URL, deliveries[i].Secret = h.URL, h.Secret
+	}
+	return deliveries
+}
+
+// MarkDelivered ...
+func (p *Persistence) MarkDelivered(ctx context.Context, id, status int) error {
+	query := "UPDATE webhook_deliveries SET status = 'delivered', response_status = $2, last_error = '', delivered_at = $3 WHERE id = $1"
+	_, err := p.DB.ExecContext(ctx, query, id, status, p.clock())
+	return err
+}
+
+// MarkRetry records a failed attempt and when to try again.
+func (p *Persistence) MarkRetry(ctx context.Context, id, status int, cause string, at time.Time) error {
+	query := "UPDATE webhook_deliveries SET response_status = $2, last_error = $3, next_attempt_at = $4 WHERE id = $1"
+	_, err := p.DB.ExecContext(ctx, query, id, status, cause, at.UTC())
+	return err
+}
+
+// MarkFailed gives up on the delivery.
+func (p *Persistence) MarkFailed(ctx context.Context, id, status int, cause string) error {
+	query := "UPDATE webhook_deliveries SET status = 'failed', response_status = $2, last_error = $3 WHERE id = $1"
+	_, err := p.DB.ExecContext(ctx, query, id, status, cause)
+	return err
+}
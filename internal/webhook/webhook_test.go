@@ -0,0 +1,123 @@
+// internal/webhook/webhook_test.go
+
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"github.com/jeniok12/golang-tdd-example/internal/dbtest"
+	"github.com/jeniok12/golang-tdd-example/internal/pgtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(pgtest.Main(m))
+}
+
+// newTestPersistence returns a Persistence on the migrated db whose clock is
+// stopped at now.
+func newTestPersistence(db *sql.DB, now time.Time) *Persistence {
+	return &Persistence{DB: db, now: func() time.Time { return now }}
+}
+
+func TestPersistence_Hooks(t *testing.T) {
+	for _, d := range dbtest.Dialects {
+		t.Run(d.Name, func(t *testing.T) {
+			testPersistenceHooks(t, d.Open(t))
+		})
+	}
+}
+
+func testPersistenceHooks(t *testing.T, db *sql.DB) {
+	ctx := context.Background()
+	now := time.Date(2019, 9, 1, 10, 0, 0, 0, time.UTC)
+	p := newTestPersistence(db, now)
+
+	first, err := p.CreateHook(ctx, Hook{URL: "https://one.example.com/hook", Secret: "s1"})
+	require.NoError(t, err, "Got error when not expected")
+	second, err := p.CreateHook(ctx, Hook{URL: "https://two.example.com/hook", Secret: "s2"})
+	require.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, now, first.CreatedAt, "Creation time is different than expected")
+
+	hooks, err := p.ListHooks(ctx)
+	require.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, []Hook{*first, *second}, hooks, "Hooks are different than expected")
+
+	deliveries, err := p.Enqueue(ctx, EventQuoteGenerated, []byte(`{"type":"quote.generated"}`))
+	require.NoError(t, err, "Got error when not expected")
+	require.Len(t, deliveries, 2, "A delivery should be queued per hook")
+	assert.Equal(t, first.ID, deliveries[0].HookID, "Delivery hook is different than expected")
+	assert.Equal(t, StatusPending, deliveries[0].Status, "New deliveries should be pending")
+	assert.JSONEq(t, `{"type":"quote.generated"}`, string(deliveries[0].Payload), "Payload is different than expected")
+
+	require.NoError(t, p.DeleteHook(ctx, first.ID), "Got error when not expected")
+	assert.ErrorIs(t, p.DeleteHook(ctx, first.ID), ErrNotFound)
+	_, err = p.ListDeliveries(ctx, first.ID, 10)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	listed, err := p.ListDeliveries(ctx, second.ID, 10)
+	require.NoError(t, err, "Got error when not expected")
+	assert.Equal(t, []Delivery{deliveries[1]}, listed, "Deliveries are different than expected")
+}
+
+func TestPersistence_Claim(t *testing.T) {
+	for _, d := range dbtest.Dialects {
+		t.Run(d.Name, func(t *testing.T) {
+			testPersistenceClaim(t, d.Open(t))
+		})
+	}
+}
+
+func testPersistenceClaim(t *testing.T, db *sql.DB) {
+	ctx := context.Background()
+	now := time.Date(2019, 9, 1, 10, 0, 0, 0, time.UTC)
+	p := newTestPersistence(db, now)
+
+	hook, err := p.CreateHook(ctx, Hook{URL: "https://one.example.com/hook", Secret: "s1"})
+	require.NoError(t, err, "Got error when not expected")
+	for i := 0; i < 3; i++ {
+		_, err := p.Enqueue(ctx, EventQuoteGenerated, []byte(`{}`))
+		require.NoError(t, err, "Got error when not expected")
+	}
+
+	claimed, err := p.Claim(ctx, 2, time.Minute)
+	require.NoError(t, err, "Got error when not expected")
+	require.Len(t, claimed, 2, "Claim should stop at the limit")
+	assert.Equal(t, 1, claimed[0].Attempts, "Claim should count an attempt")
+	assert.Equal(t, now.Add(time.Minute), claimed[0].NextAttemptAt, "Claimed deliveries should be leased")
+	assert.Equal(t, hook.URL, claimed[0].URL, "Claimed deliveries should carry the hook URL")
+	assert.Equal(t, hook.Secret, claimed[0].Secret, "Claimed deliveries should carry the hook secret")
+
+	require.NoError(t, p.MarkDelivered(ctx, claimed[0].ID, 200), "Got error when not expected")
+	require.NoError(t, p.MarkRetry(ctx, claimed[1].ID, 503, "unavailable", now), "Got error when not expected")
+
+	claimed, err = p.Claim(ctx, 10, time.Minute)
+	require.NoError(t, err, "Got error when not expected")
+	require.Len(t, claimed, 2, "Retried and unclaimed deliveries should be due")
+	assert.Equal(t, 2, claimed[0].Attempts, "Retried delivery should count its second attempt")
+	require.NoError(t, p.MarkFailed(ctx, claimed[0].ID, 503, "unavailable"), "Got error when not expected")
+
+	listed, err := p.ListDeliveries(ctx, hook.ID, 10)
+	require.NoError(t, err, "Got error when not expected")
+	require.Len(t, listed, 3, "Every delivery should be listed")
+	assert.Equal(t, StatusFailed, listed[1].Status, "Failed delivery status is different than expected")
+	assert.Equal(t, 503, listed[1].ResponseStatus, "Response status should be recorded")
+	assert.Equal(t, "unavailable", listed[1].LastError, "Failure should be recorded")
+	assert.Equal(t, StatusDelivered, listed[2].Status, "Delivered status is different than expected")
+	if assert.NotNil(t, listed[2].DeliveredAt, "Delivery time should be recorded") {
+		assert.Equal(t, now, *listed[2].DeliveredAt, "Delivery time is different than expected")
+	}
+}
+
+func TestSign(t *testing.T) {
+	signature := Sign("s3cret", 1567332000, []byte(`{"type":"quote.generated"}`))
+
+	assert.Equal(t, "sha256=", signature[:7], "Signature should name its algorithm")
+	assert.Len(t, signature, 7+64, "Signature should be a hex SHA-256")
+	assert.NotEqual(t, signature, Sign("other", 1567332000, []byte(`{"type":"quote.generated"}`)), "Signature should depend on the secret")
+	assert.NotEqual(t, signature, Sign("s3cret", 1567332001, []byte(`{"type":"quote.generated"}`)), "Signature should depend on the timestamp")
+}
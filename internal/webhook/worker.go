@@ -0,0 +1,176 @@
+// internal/webhook/worker.go
+
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Queue is the delivery log as seen by the Worker.
+type Queue interface {
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]Delivery, error)
+	MarkDelivered(ctx context.Context, id, status int) error
+	MarkRetry(ctx context.Context, id, status int, cause string, at time.Time) error
+	MarkFailed(ctx context.Context, id, status int, cause string) error
+}
+
+// Headers of the POSTed payloads.
+const (
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+	TimestampHeader = "X-Webhook-Timestamp"
+	SignatureHeader = "X-Webhook-Signature"
+)
+
+// Worker defaults used for zero fields.
+const (
+	DefaultMaxAttempts = 8
+	DefaultBaseDelay   = 10 * time.Second
+	DefaultMaxDelay    = time.Hour
+	DefaultBatchSize   = 100
+	DefaultLease       = 5 * time.Minute
+	DefaultTimeout     = 10 * time.Second
+)
+
+// Worker POSTs the due deliveries. A delivery succeeds when the hook answers
+// with a 2xx status; anything else is retried with exponential backoff until
+// MaxAttempts, then the delivery is marked failed.
+type Worker struct {
+	Queue       Queue
+	Client      *http.Client
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	BatchSize   int
+	// Lease is how long a claimed delivery is left alone before another
+	// drain may pick it up again. It should outlast POSTing a whole batch.
+	Lease time.Duration
+
+	now func() time.Time
+}
+
+// Drain POSTs due deliveries batch by batch until none is left, so it can be
+// used as a scheduler.Job. Failed POSTs are recorded on the deliveries; only
+// store failures are returned.
+func (w *Worker) Drain(ctx context.Context) error {
+	batchSize := orDefault(w.BatchSize, DefaultBatchSize)
+	for {
+		deliveries, err := w.Queue.Claim(ctx, batchSize, orDefaultDuration(w.Lease, DefaultLease))
+		if err != nil {
+			return err
+		}
+
+		for _, d := range deliveries {
+			if err := w.deliver(ctx, d); err != nil {
+				return err
+			}
+		}
+
+		if len(deliveries) < batchSize {
+			return nil
+		}
+	}
+}
+
+func (w *Worker) deliver(ctx context.Context, d Delivery) error {
+	status, retryAfter, postErr := w.post(ctx, d)
+	if postErr == nil {
+		return w.Queue.MarkDelivered(ctx, d.ID, status)
+	}
+
+	if d.Attempts >= orDefault(w.MaxAttempts, DefaultMaxAttempts) {
+		return w.Queue.MarkFailed(ctx, d.ID, status, postErr.Error())
+	}
+
+	// A throttling hook says how long to wait; retrying sooner is wasted.
+	delay := w.backoff(d.Attempts)
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	return w.Queue.MarkRetry(ctx, d.ID, status, postErr.Error(), w.clock().Add(delay))
+}
+
+// post sends the signed payload of d to its hook and returns the response
+// status and Retry-After delay.
+func (w *Worker) post(ctx context.Context, d Delivery) (int, time.Duration, error) {
+	if d.URL == "" {
+		return 0, 0, fmt.Errorf("webhook: hook %d is gone", d.HookID)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, 0, err
+	}
+	timestamp := w.clock().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "InspiringQuotes-Webhook/1.0")
+	req.Header.Set(EventHeader, d.Event)
+	req.Header.Set(DeliveryHeader, strconv.Itoa(d.ID))
+	req.Header.Set(TimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(SignatureHeader, Sign(d.Secret, timestamp, d.Payload))
+
+	resp, err := w.client().Do(req)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer resp.Body.Close()
+	// Drained, so the connection is reused.
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var retryAfter time.Duration
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs > 0 {
+			retryAfter = time.Duration(secs) * time.Second
+		}
+		return resp.StatusCode, retryAfter, fmt.Errorf("webhook: %s answered %s", d.URL, resp.Status)
+	}
+
+	return resp.StatusCode, 0, nil
+}
+
+// backoff returns the delay after the given number of failed attempts.
+func (w *Worker) backoff(attempts int) time.Duration {
+	base := orDefaultDuration(w.BaseDelay, DefaultBaseDelay)
+	max := orDefaultDuration(w.MaxDelay, DefaultMaxDelay)
+
+	delay := base << uint(attempts-1)
+	if delay > max || delay <= 0 {
+		delay = max
+	}
+
+	return delay
+}
+
+func (w *Worker) client() *http.Client {
+	if w.Client != nil {
+		return w.Client
+	}
+	return &http.Client{Timeout: DefaultTimeout}
+}
+
+func (w *Worker) clock() time.Time {
+	if w.now != nil {
+		return w.now()
+	}
+	return time.Now()
+}
+
+func orDefault(v, def int) int {
+	if v > 0 {
+		return v
+	}
+	return def
+}
+
+func orDefaultDuration(v, def time.Duration) time.Duration {
+	if v > 0 {
+		return v
+	}
+	return def
+}
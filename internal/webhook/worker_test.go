@@ -0,0 +1,123 @@
+// internal/webhook/worker_test.go
+
+package webhook
+
+import (
+	"context"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/internal/dbtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestWorker_Drain(t *testing.T) {
+	now := time.Date(2019, 9, 1, 10, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name             string
+		attempts         int
+		status           int
+		retryAfter       string
+		expectedStatus   string
+		expectedNextTime time.Time
+	}{
+		{"Delivered", 0, http.StatusNoContent, "", StatusDelivered, now.Add(DefaultLease)},
+		{"FirstFailure", 0, http.StatusInternalServerError, "", StatusPending, now.Add(time.Second)},
+		{"ThirdFailure", 2, http.StatusInternalServerError, "", StatusPending, now.Add(4 * time.Second)},
+		{"BackoffCapped", 3, http.StatusInternalServerError, "", StatusPending, now.Add(5 * time.Second)},
+		{"LastFailure", 4, http.StatusInternalServerError, "", StatusFailed, now.Add(DefaultLease)},
+		{"Throttled", 0, http.StatusTooManyRequests, "60", StatusPending, now.Add(time.Minute)},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			payload := `{"type":"quote.generated"}`
+
+			hookServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				body, _ := io.ReadAll(req.Body)
+				assert.Equal(t, http.MethodPost, req.Method, "Should have different request method")
+				assert.Equal(t, payload, string(body), "Body should be the payload")
+				assert.Equal(t, EventQuoteGenerated, req.Header.Get(EventHeader), "Event header is different than expected")
+				assert.Equal(t, strconv.FormatInt(now.Unix(), 10), req.Header.Get(TimestampHeader), "Timestamp header is different than expected")
+				assert.Equal(t, Sign("s3cret", now.Unix(), body), req.Header.Get(SignatureHeader), "Payload should be signed")
+
+				if tc.retryAfter != "" {
+					rw.Header().Set("Retry-After", tc.retryAfter)
+				}
+				rw.WriteHeader(tc.status)
+			}))
+			defer hookServer.Close()
+
+			p := newTestPersistence(dbtest.SQLite(t), now)
+			hook, err := p.CreateHook(ctx, Hook{URL: hookServer.URL, Secret: "s3cret"})
+			require.NoError(t, err, "Got error when not expected")
+			queued, err := p.Enqueue(ctx, EventQuoteGenerated, []byte(payload))
+			require.NoError(t, err, "Got error when not expected")
+			_, err = p.DB.ExecContext(ctx, "UPDATE webhook_deliveries SET attempts = $1", tc.attempts)
+			require.NoError(t, err, "Got error when not expected")
+
+			w := Worker{
+				Queue:       p,
+				Client:      hookServer.Client(),
+				MaxAttempts: 5,
+				BaseDelay:   time.Second,
+				MaxDelay:    5 * time.Second,
+				now:         func() time.Time { return now },
+			}
+			assert.NoError(t, w.Drain(ctx), "Should have no error when draining")
+
+			deliveries, err := p.ListDeliveries(ctx, hook.ID, 10)
+			require.NoError(t, err, "Got error when not expected")
+			d := deliveries[0]
+			assert.Equal(t, queued[0].ID, d.ID, "Delivery is different than expected")
+			assert.Equal(t, tc.expectedStatus, d.Status, "Delivery status is different than expected")
+			assert.Equal(t, tc.attempts+1, d.Attempts, "Drain should count one attempt")
+			assert.Equal(t, tc.status, d.ResponseStatus, "Response status should be recorded")
+			assert.Equal(t, tc.expectedNextTime, d.NextAttemptAt, "Next attempt is different than expected")
+			if tc.expectedStatus != StatusDelivered {
+				assert.Contains(t, d.LastError, strconv.Itoa(tc.status), "Failure should be recorded")
+			}
+		})
+	}
+}
+
+func TestWorker_DrainUnreachable(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2019, 9, 1, 10, 0, 0, 0, time.UTC)
+	hookServer := httptest.NewServer(http.NotFoundHandler())
+	hookServer.Close()
+
+	p := newTestPersistence(dbtest.SQLite(t), now)
+	hook, _ := p.CreateHook(ctx, Hook{URL: hookServer.URL, Secret: "s3cret"})
+	p.Enqueue(ctx, EventQuoteDelivered, []byte(`{}`))
+
+	w := Worker{Queue: p, BaseDelay: time.Second, now: func() time.Time { return now }}
+	assert.NoError(t, w.Drain(ctx), "Should have no error when draining")
+
+	deliveries, _ := p.ListDeliveries(ctx, hook.ID, 10)
+	assert.Equal(t, StatusPending, deliveries[0].Status, "Unreachable hooks should be retried")
+	assert.Equal(t, 0, deliveries[0].ResponseStatus, "No response status should be recorded")
+	assert.Equal(t, now.Add(time.Second), deliveries[0].NextAttemptAt, "Next attempt is different than expected")
+	assert.NotEmpty(t, deliveries[0].LastError, "Failure should be recorded")
+}
+
+// failingQueue fails every claim.
+type failingQueue struct {
+	Queue
+}
+
+func (failingQueue) Claim(ctx context.Context, limit int, lease time.Duration) ([]Delivery, error) {
+	return nil, errors.New("sample error")
+}
+
+func TestWorker_DrainStoreError(t *testing.T) {
+	w := Worker{Queue: failingQueue{}}
+
+	assert.EqualError(t, w.Drain(context.Background()), "sample error", "Store errors should be returned")
+}
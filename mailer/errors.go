@@ -0,0 +1,75 @@
+// mailer/errors.go
+
+package mailer
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Error is a failure reported by an email API.
+type Error struct {
+	Provider string
+	Status   int
+	// Code is the API's name for the failure, if it has one.
+	Code    string
+	Message string
+	// Permanent errors fail again however often the message is retried,
+	// such as a rejected address or bad credentials.
+	Permanent bool
+	// RetryAfter is how long the API asked to wait before sending again
+	// after rate limiting.
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.Status)
+	}
+	if e.Code != "" {
+		msg = e.Code + ": " + msg
+	}
+	return fmt.Sprintf("mailer: %s: %s (status %d)", e.Provider, msg, e.Status)
+}
+
+// RateLimited reports whether the API refused the message for sending too
+// fast.
+func (e *Error) RateLimited() bool {
+	return e.Status == http.StatusTooManyRequests
+}
+
+// IsPermanent reports whether err is an Error that retrying will not fix.
+func IsPermanent(err error) bool {
+	var e *Error
+	return errors.As(err, &e) && e.Permanent
+}
+
+// RetryAfter returns how long the API behind err asked to wait before
+// sending again, or 0.
+func RetryAfter(err error) time.Duration {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.RetryAfter
+	}
+	return 0
+}
+
+// retryAfter parses a Retry-After header given in seconds, falling back to
+// def for a rate limited response without one.
+func retryAfter(header http.Header, def time.Duration) time.Duration {
+	if secs, err := strconv.Atoi(header.Get("Retry-After")); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	return def
+}
+
+// permanentStatus reports whether a failed API call with status is not worth
+// retrying: client errors other than timeouts and rate limiting.
+func permanentStatus(status int) bool {
+	return status >= 400 && status < 500 &&
+		status != http.StatusRequestTimeout && status != http.StatusTooManyRequests
+}
@@ -0,0 +1,110 @@
+// mailer/mailer.go
+
+package mailer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/smtp"
+	"net/textproto"
+	"strings"
+)
+
+// Message ...
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+	// HTML is an optional alternative to the plain text Body.
+	HTML string
+}
+
+// Sender ...
+type Sender interface {
+	Send(msg Message) error
+}
+
+// IDSender is a Sender whose API names every message it accepts, so later
+// delivery events can be matched to it.
+type IDSender interface {
+	Sender
+	SendWithID(msg Message) (id string, err error)
+}
+
+// SendWithID sends msg with s and returns the ID the API gave it, or "" when
+// s does not report one.
+func SendWithID(s Sender, msg Message) (string, error) {
+	if ids, ok := s.(IDSender); ok {
+		return ids.SendWithID(msg)
+	}
+	return "", s.Send(msg)
+}
+
+// SendMailFunc has the signature of smtp.SendMail so tests can replace it.
+type SendMailFunc func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+
+// SMTP ...
+type SMTP struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	SendMail SendMailFunc
+}
+
+// Send ...
+func (s *SMTP) Send(msg Message) error {
+	if msg.To == "" {
+		return errors.New("mailer: message has no recipient")
+	}
+
+	var auth smtp.Auth
+	if s.Username != "" {
+		auth = smtp.PlainAuth("", s.Username, s.Password, s.Host)
+	}
+
+	sendMail := s.SendMail
+	if sendMail == nil {
+		sendMail = smtp.SendMail
+	}
+
+	addr := fmt.Sprintf("%s:%d", s.Host, s.Port)
+	return sendMail(addr, auth, s.From, []string{msg.To}, s.format(msg))
+}
+
+func (s *SMTP) format(msg Message) []byte {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "From: %s\r\n", s.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", msg.Subject)
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	if msg.HTML == "" {
+		buf.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+		buf.WriteString("\r\n")
+		buf.WriteString(crlf(msg.Body))
+		return buf.Bytes()
+	}
+
+	// Clients show the last alternative they support, so HTML goes last.
+	mw := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%q\r\n", mw.Boundary())
+	buf.WriteString("\r\n")
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain", msg.Body},
+		{"text/html", msg.HTML},
+	} {
+		w, _ := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {part.contentType + `; charset="utf-8"`}})
+		w.Write([]byte(crlf(part.body)))
+	}
+	mw.Close()
+
+	return buf.Bytes()
+}
+
+func crlf(s string) string {
+	return strings.Replace(s, "\n", "\r\n", -1)
+}
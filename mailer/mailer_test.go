@@ -0,0 +1,111 @@
+// mailer/mailer_test.go
+
+package mailer
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"net/smtp"
+	"testing"
+)
+
+func TestSMTP_Send(t *testing.T) {
+	testCases := []struct {
+		name               string
+		msg                Message
+		sendMailErr        error
+		expectedToGetError bool
+	}{
+		{
+			"Sent",
+			Message{To: "user1@testmail.com", Subject: "Quote", Body: "Bla Bla Bla\n- Bob"},
+			nil,
+			false,
+		},
+		{
+			"SendMailFail",
+			Message{To: "user1@testmail.com", Subject: "Quote", Body: "Bla"},
+			errors.New("sample error"),
+			true,
+		},
+		{
+			"NoRecipient",
+			Message{Subject: "Quote", Body: "Bla"},
+			nil,
+			true,
+		},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.name, func(t *testing.T) {
+			var gotAddr, gotFrom string
+			var gotTo []string
+			var gotMsg []byte
+
+			sender := SMTP{
+				Host: "smtp.testmail.com",
+				Port: 25,
+				From: "quotes@testmail.com",
+				SendMail: func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+					gotAddr, gotFrom, gotTo, gotMsg = addr, from, to, msg
+					return tC.sendMailErr
+				},
+			}
+
+			err := sender.Send(tC.msg)
+
+			if tC.expectedToGetError {
+				assert.Error(t, err, "Got no error when expected")
+				return
+			}
+			assert.NoError(t, err, "Got error when not expected")
+			assert.Equal(t, "smtp.testmail.com:25", gotAddr, "Wrong SMTP address")
+			assert.Equal(t, "quotes@testmail.com", gotFrom, "Wrong sender")
+			assert.Equal(t, []string{tC.msg.To}, gotTo, "Wrong recipients")
+			assert.Contains(t, string(gotMsg), "Subject: Quote\r\n", "Message should contain subject header")
+			assert.Contains(t, string(gotMsg), "\r\n\r\nBla Bla Bla\r\n- Bob", "Message should contain CRLF body")
+		})
+	}
+}
+
+func TestSMTP_SendHTML(t *testing.T) {
+	var gotMsg []byte
+	sender := SMTP{
+		Host: "smtp.testmail.com",
+		Port: 25,
+		From: "quotes@testmail.com",
+		SendMail: func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+			gotMsg = msg
+			return nil
+		},
+	}
+
+	err := sender.Send(Message{To: "user1@testmail.com", Subject: "Quote", Body: "Bla\n- Bob", HTML: "<p>Bla</p>"})
+	assert.NoError(t, err, "Got error when not expected")
+
+	m, err := mail.ReadMessage(bytes.NewReader(gotMsg))
+	require.NoError(t, err, "Message should parse")
+	mediaType, params, _ := mime.ParseMediaType(m.Header.Get("Content-Type"))
+	assert.Equal(t, "multipart/alternative", mediaType, "Wrong content type")
+
+	var parts []string
+	mr := multipart.NewReader(m.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		body, _ := io.ReadAll(p)
+		parts = append(parts, p.Header.Get("Content-Type")+": "+string(body))
+	}
+	assert.Equal(t, []string{
+		`text/plain; charset="utf-8": Bla` + "\r\n- Bob",
+		`text/html; charset="utf-8": <p>Bla</p>`,
+	}, parts, "Message should have a text and an HTML alternative")
+}
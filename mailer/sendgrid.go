@@ -0,0 +1,142 @@
+// mailer/sendgrid.go
+
+package mailer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSendGridURL is the SendGrid v3 API.
+const DefaultSendGridURL = "https://api.sendgrid.com"
+
+// sendGridRetryAfter is how long to back off when SendGrid rate limits
+// without saying for how long.
+const sendGridRetryAfter = time.Minute
+
+// SendGrid sends emails through the SendGrid v3 mail send API, see
+// https://docs.sendgrid.com/api-reference/mail-send/mail-send.
+type SendGrid struct {
+	APIKey string
+	From   string
+	// URL defaults to DefaultSendGridURL.
+	URL    string
+	Client *http.Client
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridPersonalization struct {
+	To []sendGridAddress `json:"to"`
+}
+
+type sendGridRequest struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+}
+
+type sendGridErrorResponse struct {
+	Errors []struct {
+		Message string `json:"message"`
+		Field   string `json:"field"`
+	} `json:"errors"`
+}
+
+// Send ...
+func (s *SendGrid) Send(msg Message) error {
+	_, err := s.SendWithID(msg)
+	return err
+}
+
+// SendWithID sends msg and returns the X-Message-Id SendGrid gave it.
+func (s *SendGrid) SendWithID(msg Message) (string, error) {
+	if msg.To == "" {
+		return "", errors.New("mailer: message has no recipient")
+	}
+
+	sgReq := sendGridRequest{
+		Personalizations: []sendGridPersonalization{{To: []sendGridAddress{{Email: msg.To}}}},
+		From:             sendGridAddress{Email: s.From},
+		Subject:          msg.Subject,
+		Content:          []sendGridContent{{Type: "text/plain", Value: msg.Body}},
+	}
+	if msg.HTML != "" {
+		sgReq.Content = append(sgReq.Content, sendGridContent{Type: "text/html", Value: msg.HTML})
+	}
+
+	body, err := json.Marshal(sgReq)
+	if err != nil {
+		return "", err
+	}
+
+	url := s.URL
+	if url == "" {
+		url = DefaultSendGridURL
+	}
+	req, err := http.NewRequest("POST", strings.TrimSuffix(url, "/")+"/v3/mail/send", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client(s.Client).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 == 2 {
+		return resp.Header.Get("X-Message-Id"), nil
+	}
+
+	e := &Error{
+		Provider:  "sendgrid",
+		Status:    resp.StatusCode,
+		Permanent: permanentStatus(resp.StatusCode),
+	}
+	var res sendGridErrorResponse
+	if json.NewDecoder(resp.Body).Decode(&res) == nil && len(res.Errors) > 0 {
+		e.Message = res.Errors[0].Message
+		e.Code = res.Errors[0].Field
+	}
+	if e.RateLimited() {
+		e.RetryAfter = rateLimitReset(resp.Header, sendGridRetryAfter)
+	}
+	return "", e
+}
+
+// rateLimitReset reads how long to wait from Retry-After or, as SendGrid
+// reports it, the X-RateLimit-Reset Unix time.
+func rateLimitReset(header http.Header, def time.Duration) time.Duration {
+	if d := retryAfter(header, 0); d > 0 {
+		return d
+	}
+	if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil && reset > 0 {
+		if d := time.Until(time.Unix(reset, 0)); d > 0 {
+			return d.Round(time.Second)
+		}
+	}
+	return def
+}
+
+func client(c *http.Client) *http.Client {
+	if c != nil {
+		return c
+	}
+	return http.DefaultClient
+}
@@ -0,0 +1,73 @@
+// mailer/sendgrid_test.go
+
+package mailer
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSendGrid_SendWithID(t *testing.T) {
+	testCases := []struct {
+		desc              string
+		status            int
+		header            http.Header
+		body              string
+		expectedID        string
+		expectedErr       bool
+		expectedPermanent bool
+		expectedRetry     time.Duration
+	}{
+		{"Accepted", http.StatusAccepted, http.Header{"X-Message-Id": {"abc123"}}, "", "abc123", false, false, 0},
+		{"BadRequest", http.StatusBadRequest, nil, `{"errors":[{"message":"Does not contain a valid address.","field":"personalizations.0.to.0.email"}]}`, "", true, true, 0},
+		{"Unauthorized", http.StatusUnauthorized, nil, `{"errors":[{"message":"The provided authorization grant is invalid"}]}`, "", true, true, 0},
+		{"RateLimited", http.StatusTooManyRequests, http.Header{"Retry-After": {"30"}}, "", "", true, false, 30 * time.Second},
+		{"RateLimitedWithoutReset", http.StatusTooManyRequests, nil, "", "", true, false, sendGridRetryAfter},
+		{"ServerError", http.StatusInternalServerError, nil, "", "", true, false, 0},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var got sendGridRequest
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/v3/mail/send", r.URL.Path)
+				assert.Equal(t, "Bearer key", r.Header.Get("Authorization"))
+				json.NewDecoder(r.Body).Decode(&got)
+				for name, values := range tC.header {
+					w.Header()[name] = values
+				}
+				w.WriteHeader(tC.status)
+				w.Write([]byte(tC.body))
+			}))
+			defer srv.Close()
+
+			s := &SendGrid{APIKey: "key", From: "quotes@testmail.com", URL: srv.URL}
+			id, err := s.SendWithID(Message{To: "user1@testmail.com", Subject: "Quote", Body: "Bla", HTML: "<p>Bla</p>"})
+
+			assert.Equal(t, "user1@testmail.com", got.Personalizations[0].To[0].Email)
+			assert.Equal(t, []sendGridContent{{"text/plain", "Bla"}, {"text/html", "<p>Bla</p>"}}, got.Content, "Plain text should come first")
+			if !tC.expectedErr {
+				assert.NoError(t, err, "Got error when not expected")
+				assert.Equal(t, tC.expectedID, id)
+				return
+			}
+			assert.Error(t, err, "Got no error when expected")
+			assert.Equal(t, tC.expectedPermanent, IsPermanent(err))
+			assert.Equal(t, tC.expectedRetry, RetryAfter(err))
+		})
+	}
+}
+
+func TestSendGrid_ErrorMessage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"errors":[{"message":"Does not contain a valid address.","field":"personalizations.0.to.0.email"}]}`))
+	}))
+	defer srv.Close()
+
+	err := (&SendGrid{URL: srv.URL}).Send(Message{To: "user1"})
+	assert.EqualError(t, err, "mailer: sendgrid: personalizations.0.to.0.email: Does not contain a valid address. (status 400)")
+}
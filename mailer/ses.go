@@ -0,0 +1,209 @@
+// mailer/ses.go
+
+package mailer
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sesRetryAfter is how long to back off when SES throttles, as it never says
+// for how long.
+const sesRetryAfter = time.Minute
+
+// sesTemporary are the SES errors that go away with time: throttling, the
+// daily quota and paused sending.
+var sesTemporary = map[string]bool{
+	"TooManyRequestsException": true,
+	"LimitExceededException":   true,
+	"SendingPausedException":   true,
+}
+
+// SES sends emails through the Amazon SES v2 API, see
+// https://docs.aws.amazon.com/ses/latest/APIReference-V2/API_SendEmail.html.
+type SES struct {
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is only needed with temporary credentials.
+	SessionToken string
+	From         string
+	// URL defaults to the SES endpoint of Region.
+	URL    string
+	Client *http.Client
+
+	now func() time.Time
+}
+
+type sesContent struct {
+	Data    string `json:"Data"`
+	Charset string `json:"Charset"`
+}
+
+type sesRequest struct {
+	FromEmailAddress string `json:"FromEmailAddress"`
+	Destination      struct {
+		ToAddresses []string `json:"ToAddresses"`
+	} `json:"Destination"`
+	Content struct {
+		Simple struct {
+			Subject sesContent `json:"Subject"`
+			Body    struct {
+				Text sesContent  `json:"Text"`
+				HTML *sesContent `json:"Html,omitempty"`
+			} `json:"Body"`
+		} `json:"Simple"`
+	} `json:"Content"`
+}
+
+type sesResponse struct {
+	MessageID string `json:"MessageId"`
+	Message   string `json:"message"`
+}
+
+// Send ...
+func (s *SES) Send(msg Message) error {
+	_, err := s.SendWithID(msg)
+	return err
+}
+
+// SendWithID sends msg and returns the MessageId SES gave it.
+func (s *SES) SendWithID(msg Message) (string, error) {
+	if msg.To == "" {
+		return "", errors.New("mailer: message has no recipient")
+	}
+
+	var sesReq sesRequest
+	sesReq.FromEmailAddress = s.From
+	sesReq.Destination.ToAddresses = []string{msg.To}
+	sesReq.Content.Simple.Subject = sesContent{msg.Subject, "UTF-8"}
+	sesReq.Content.Simple.Body.Text = sesContent{msg.Body, "UTF-8"}
+	if msg.HTML != "" {
+		sesReq.Content.Simple.Body.HTML = &sesContent{msg.HTML, "UTF-8"}
+	}
+
+	body, err := json.Marshal(sesReq)
+	if err != nil {
+		return "", err
+	}
+
+	url := s.URL
+	if url == "" {
+		url = fmt.Sprintf("https://email.%s.amazonaws.com", s.Region)
+	}
+	req, err := http.NewRequest("POST", strings.TrimSuffix(url, "/")+"/v2/email/outbound-emails", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.SessionToken)
+	}
+	now := time.Now
+	if s.now != nil {
+		now = s.now
+	}
+	signV4(req, body, "ses", s.Region, s.AccessKeyID, s.SecretAccessKey, now())
+
+	resp, err := client(s.Client).Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var res sesResponse
+	decodeErr := json.NewDecoder(resp.Body).Decode(&res)
+	if resp.StatusCode == http.StatusOK {
+		return res.MessageID, decodeErr
+	}
+
+	// X-Amzn-ErrorType looks like "MessageRejected:http://internal.amazon.com/...".
+	code, _, _ := strings.Cut(resp.Header.Get("X-Amzn-ErrorType"), ":")
+	e := &Error{
+		Provider:  "ses",
+		Status:    resp.StatusCode,
+		Code:      code,
+		Message:   res.Message,
+		Permanent: permanentStatus(resp.StatusCode) && !sesTemporary[code],
+	}
+	if e.RateLimited() || sesTemporary[code] {
+		e.RetryAfter = retryAfter(resp.Header, sesRetryAfter)
+	}
+	return "", e
+}
+
+// signV4 signs req with AWS Signature Version 4, see
+// https://docs.aws.amazon.com/IAM/latest/UserGuide/create-signed-request.html.
+// Every header set on req is signed, along with Host and X-Amz-Date.
+func signV4(req *http.Request, payload []byte, service, region, keyID, secret string, t time.Time) {
+	t = t.UTC()
+	amzDate := t.Format("20060102T150405Z")
+	date := t.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hexSHA256(payload),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hexSHA256([]byte(canonicalRequest)),
+	}, "\n")
+
+	key := []byte("AWS4" + secret)
+	for _, part := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		keyID, scope, signedHeaders, signature))
+}
+
+func hexSHA256(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,75 @@
+// mailer/ses_test.go
+
+package mailer
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestSignV4 checks the get-vanilla case of the AWS Signature Version 4 test
+// suite.
+func TestSignV4(t *testing.T) {
+	req, _ := http.NewRequest("GET", "https://example.amazonaws.com/", nil)
+	signV4(req, nil, "service", "us-east-1", "AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		req.Header.Get("Authorization"))
+}
+
+func TestSES_SendWithID(t *testing.T) {
+	testCases := []struct {
+		desc              string
+		status            int
+		errorType         string
+		body              string
+		expectedID        string
+		expectedErr       bool
+		expectedPermanent bool
+		expectedRetry     time.Duration
+	}{
+		{"Sent", http.StatusOK, "", `{"MessageId":"0100-abc"}`, "0100-abc", false, false, 0},
+		{"Rejected", http.StatusBadRequest, "MessageRejected:http://internal.amazon.com/coral/com.amazonaws.sesv2/", `{"message":"Email address is not verified."}`, "", true, true, 0},
+		{"Throttled", http.StatusTooManyRequests, "TooManyRequestsException", `{"message":"Maximum sending rate exceeded."}`, "", true, false, sesRetryAfter},
+		{"QuotaExceeded", http.StatusBadRequest, "LimitExceededException", `{"message":"Daily message quota exceeded."}`, "", true, false, sesRetryAfter},
+		{"ServerError", http.StatusInternalServerError, "InternalFailure", "", "", true, false, 0},
+	}
+	for _, tC := range testCases {
+		t.Run(tC.desc, func(t *testing.T) {
+			var got sesRequest
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "/v2/email/outbound-emails", r.URL.Path)
+				assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/20210101/eu-west-1/ses/aws4_request, "))
+				json.NewDecoder(r.Body).Decode(&got)
+				if tC.errorType != "" {
+					w.Header().Set("X-Amzn-ErrorType", tC.errorType)
+				}
+				w.WriteHeader(tC.status)
+				w.Write([]byte(tC.body))
+			}))
+			defer srv.Close()
+
+			s := &SES{Region: "eu-west-1", AccessKeyID: "AKID", SecretAccessKey: "secret", From: "quotes@testmail.com", URL: srv.URL}
+			s.now = func() time.Time { return time.Date(2021, 1, 1, 9, 0, 0, 0, time.UTC) }
+			id, err := s.SendWithID(Message{To: "user1@testmail.com", Subject: "Quote", Body: "Bla"})
+
+			assert.Equal(t, []string{"user1@testmail.com"}, got.Destination.ToAddresses)
+			assert.Equal(t, "Bla", got.Content.Simple.Body.Text.Data)
+			assert.Nil(t, got.Content.Simple.Body.HTML, "HTML should be left out when there is none")
+			if !tC.expectedErr {
+				assert.NoError(t, err, "Got error when not expected")
+				assert.Equal(t, tC.expectedID, id)
+				return
+			}
+			assert.Error(t, err, "Got no error when expected")
+			assert.Equal(t, tC.expectedPermanent, IsPermanent(err))
+			assert.Equal(t, tC.expectedRetry, RetryAfter(err))
+		})
+	}
+}
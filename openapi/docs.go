@@ -0,0 +1,32 @@
+// openapi/docs.go
+
+package openapi
+
+import (
+	"bytes"
+	_ "embed"
+	"html/template"
+	"net/http"
+)
+
+//go:embed docs.html
+var docsHTML string
+
+var docsTemplate = template.Must(template.New("docs").Parse(docsHTML))
+
+// DocsHandler serves a Swagger UI page for the document at specURL. The page
+// is part of the binary; the Swagger UI assets load from a CDN.
+func DocsHandler(title, specURL string) http.Handler {
+	var page bytes.Buffer
+	err := docsTemplate.Execute(&page, struct{ Title, SpecURL string }{title, specURL})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(page.Bytes())
+	})
+}
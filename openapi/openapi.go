@@ -0,0 +1,233 @@
+// openapi/openapi.go
+
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Version is the OpenAPI version of generated documents.
+const Version = "3.0.3"
+
+// Param describes a query, path or header parameter. Path parameters are
+// derived from the route path and only need listing to add a description.
+type Param struct {
+	Name        string
+	In          string
+	Description string
+	Required    bool
+	// Type is the JSON schema type, "string" when empty.
+	Type string
+}
+
+// Response describes one possible response of an Operation.
+type Response struct {
+	Status      int
+	Description string
+	// Body is a value of the body type, nil when there is no body or it is
+	// not JSON shaped.
+	Body interface{}
+	// ContentTypes default to application/json.
+	ContentTypes []string
+}
+
+// Operation is the typed metadata of a route. Path is the gorilla/mux
+// template the route is registered with; variable patterns are dropped in
+// the document.
+type Operation struct {
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	Tags        []string
+	// Query is a value of a request struct whose query-tagged fields are
+	// parameters, see queryParams.
+	Query  interface{}
+	Params []Param
+	// Body is a value of the request body type, nil for no body.
+	Body             interface{}
+	BodyContentTypes []string
+	Responses        []Response
+}
+
+// Spec collects Operations and renders them as an OpenAPI document.
+type Spec struct {
+	Title   string
+	Version string
+	// Error, when set, is the body of the default response of every operation.
+	Error interface{}
+
+	mu  sync.Mutex
+	ops []Operation
+}
+
+// Add ...
+func (s *Spec) Add(op Operation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ops = append(s.ops, op)
+}
+
+// Operations returns the added operations in order.
+func (s *Spec) Operations() []Operation {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Operation(nil), s.ops...)
+}
+
+// Document builds the OpenAPI document of the added operations.
+func (s *Spec) Document() *Document {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: s.Title, Version: s.Version},
+		Paths:   make(map[string]PathItem),
+	}
+	schemas := newRegistry()
+
+	for _, op := range s.Operations() {
+		path, pathParams := convertPath(op.Path)
+
+		item := doc.Paths[path]
+		if item == nil {
+			item = make(PathItem)
+			doc.Paths[path] = item
+		}
+
+		o := &OperationObject{
+			Summary:     op.Summary,
+			Description: op.Description,
+			Tags:        op.Tags,
+			OperationID: operationID(op.Method, path),
+			Responses:   make(map[string]ResponseObject),
+		}
+
+		described := make(map[string]Param)
+		for _, p := range append(queryParams(op.Query), op.Params...) {
+			if p.In == "path" {
+				described[p.Name] = p
+				continue
+			}
+			o.Parameters = append(o.Parameters, parameter(p))
+		}
+		for _, p := range pathParams {
+			if d, ok := described[p.Name]; ok {
+				p.Description = d.Description
+			}
+			o.Parameters = append(o.Parameters, parameter(p))
+		}
+
+		if op.Body != nil {
+			o.RequestBody = &RequestBody{
+				Required: true,
+				Content:  content(schemas.schema(op.Body), op.BodyContentTypes),
+			}
+		}
+
+		for _, r := range op.Responses {
+			resp := ResponseObject{Description: r.Description}
+			if resp.Description == "" {
+				resp.Description = http.StatusText(r.Status)
+			}
+			if r.Body != nil || len(r.ContentTypes) > 0 {
+				var schema *Schema
+				if r.Body != nil {
+					schema = schemas.schema(r.Body)
+				} else {
+					schema = &Schema{Type: "string"}
+				}
+				resp.Content = content(schema, r.ContentTypes)
+			}
+			o.Responses[strconv.Itoa(r.Status)] = resp
+		}
+		if s.Error != nil {
+			o.Responses["default"] = ResponseObject{
+				Description: "Error",
+				Content:     content(schemas.schema(s.Error), nil),
+			}
+		}
+
+		item[strings.ToLower(op.Method)] = o
+	}
+
+	doc.Components.Schemas = schemas.components
+	return doc
+}
+
+// Handler serves the document as JSON. It is built on first use, after all
+// routes have been added.
+func (s *Spec) Handler() http.Handler {
+	var once sync.Once
+	var body []byte
+	var err error
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		once.Do(func() {
+			body, err = json.Marshal(s.Document())
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	})
+}
+
+var pathVar = regexp.MustCompile(`\{([^}:]+)(?::([^}]*))?\}`)
+
+// convertPath turns a gorilla/mux template into an OpenAPI path and its
+// parameters. Variables restricted to digits are integers.
+func convertPath(template string) (string, []Param) {
+	var params []Param
+	path := pathVar.ReplaceAllStringFunc(template, func(v string) string {
+		m := pathVar.FindStringSubmatch(v)
+		p := Param{Name: m[1], In: "path", Required: true, Type: "string"}
+		if m[2] == "[0-9]+" {
+			p.Type = "integer"
+		}
+		params = append(params, p)
+		return "{" + m[1] + "}"
+	})
+	return path, params
+}
+
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, part := range strings.FieldsFunc(path, func(r rune) bool { return r == '/' || r == '-' || r == '{' || r == '}' }) {
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	return b.String()
+}
+
+func parameter(p Param) ParameterObject {
+	typ := p.Type
+	if typ == "" {
+		typ = "string"
+	}
+	return ParameterObject{
+		Name:        p.Name,
+		In:          p.In,
+		Description: p.Description,
+		Required:    p.Required || p.In == "path",
+		Schema:      &Schema{Type: typ},
+	}
+}
+
+func content(schema *Schema, contentTypes []string) map[string]MediaType {
+	if len(contentTypes) == 0 {
+		contentTypes = []string{"application/json"}
+	}
+
+	c := make(map[string]MediaType, len(contentTypes))
+	for _, ct := range contentTypes {
+		c[ct] = MediaType{Schema: schema}
+	}
+	return c
+}
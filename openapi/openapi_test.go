@@ -0,0 +1,150 @@
+// openapi/openapi_test.go
+
+package openapi
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type item struct {
+	ID      int               `json:"id"`
+	Name    string            `json:"name,omitempty"`
+	Tags    []string          `json:"tags"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Created time.Time         `json:"createdAt"`
+	Hidden  string            `json:"-"`
+	Parent  *item             `json:"parent,omitempty"`
+}
+
+type record struct {
+	item
+	Score float64 `json:"score"`
+}
+
+type apiError struct {
+	Code    string      `json:"code"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+func TestConvertPath(t *testing.T) {
+	testCases := []struct {
+		template       string
+		expectedPath   string
+		expectedParams []Param
+	}{
+		{"/items", "/items", nil},
+		{
+			"/items/{id:[0-9]+}/tags/{tag}",
+			"/items/{id}/tags/{tag}",
+			[]Param{
+				{Name: "id", In: "path", Required: true, Type: "integer"},
+				{Name: "tag", In: "path", Required: true, Type: "string"},
+			},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.template, func(t *testing.T) {
+			path, params := convertPath(tc.template)
+
+			assert.Equal(t, tc.expectedPath, path, "Path is different than expected")
+			assert.Equal(t, tc.expectedParams, params, "Params are different than expected")
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := newRegistry()
+
+	assert.Equal(t, &Schema{Type: "array", Items: &Schema{Ref: "#/components/schemas/record"}}, r.schema([]record{}), "Named structs should be referenced")
+
+	assert.Equal(t, &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"id":        {Type: "integer"},
+			"name":      {Type: "string"},
+			"tags":      {Type: "array", Items: &Schema{Type: "string"}},
+			"labels":    {Type: "object", AdditionalProperties: &Schema{Type: "string"}},
+			"createdAt": {Type: "string", Format: "date-time"},
+			"parent":    {Ref: "#/components/schemas/item"},
+			"score":     {Type: "number"},
+		},
+		Required: []string{"id", "tags", "createdAt", "score"},
+	}, r.components["record"], "Embedded fields should be flattened")
+
+	assert.Contains(t, r.components, "item", "Referenced structs should become components")
+}
+
+func TestSpec_Document(t *testing.T) {
+	spec := &Spec{Title: "Test API", Version: "1.0.0", Error: apiError{}}
+	spec.Add(Operation{
+		Method:  "GET",
+		Path:    "/items/{id:[0-9]+}",
+		Summary: "Get an item",
+		Params:  []Param{{Name: "id", In: "path", Description: "item id"}, {Name: "expand", In: "query"}},
+		Responses: []Response{
+			{Status: http.StatusOK, Body: item{}},
+			{Status: http.StatusNoContent},
+		},
+	})
+	spec.Add(Operation{
+		Method:           "POST",
+		Path:             "/items",
+		Body:             []item{},
+		BodyContentTypes: []string{"application/json", "text/csv"},
+		Responses:        []Response{{Status: http.StatusOK, ContentTypes: []string{"text/csv"}}},
+	})
+
+	doc := spec.Document()
+
+	assert.Equal(t, Version, doc.OpenAPI, "OpenAPI version is different than expected")
+	require.Contains(t, doc.Paths, "/items/{id}", "Path patterns should be dropped")
+
+	get := doc.Paths["/items/{id}"]["get"]
+	require.NotNil(t, get, "GET operation should be documented")
+	assert.Equal(t, "getItemsId", get.OperationID, "Operation ID is different than expected")
+	assert.Equal(t, []ParameterObject{
+		{Name: "expand", In: "query", Schema: &Schema{Type: "string"}},
+		{Name: "id", In: "path", Description: "item id", Required: true, Schema: &Schema{Type: "integer"}},
+	}, get.Parameters, "Parameters are different than expected")
+	assert.Equal(t, "OK", get.Responses["200"].Description, "Description should default to the status text")
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/item"}, get.Responses["200"].Content["application/json"].Schema, "Response body should be referenced")
+	assert.Nil(t, get.Responses["204"].Content, "Responses without a body should have no content")
+	assert.Equal(t, &Schema{Ref: "#/components/schemas/apiError"}, get.Responses["default"].Content["application/json"].Schema, "Errors should be the default response")
+
+	post := doc.Paths["/items"]["post"]
+	require.NotNil(t, post, "POST operation should be documented")
+	assert.Len(t, post.RequestBody.Content, 2, "Request body should list every content type")
+	assert.Equal(t, &Schema{Type: "string"}, post.Responses["200"].Content["text/csv"].Schema, "Non-JSON bodies should be strings")
+}
+
+func TestSpec_Handler(t *testing.T) {
+	spec := &Spec{Title: "Test API", Version: "1.0.0"}
+	spec.Add(Operation{Method: "GET", Path: "/items", Responses: []Response{{Status: http.StatusOK, Body: []item{}}}})
+
+	rr := httptest.NewRecorder()
+	spec.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/openapi.json", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"), "Response content type is different than expected")
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &doc), "Response should be JSON")
+	assert.Equal(t, "3.0.3", doc["openapi"], "OpenAPI version is different than expected")
+}
+
+func TestDocsHandler(t *testing.T) {
+	rr := httptest.NewRecorder()
+	DocsHandler("Test API", "/openapi.json").ServeHTTP(rr, httptest.NewRequest("GET", "/docs", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code, "Response HTTP status in different than expected")
+	assert.Equal(t, "text/html; charset=utf-8", rr.Header().Get("Content-Type"), "Response content type is different than expected")
+	assert.True(t, strings.Contains(rr.Body.String(), `url: "/openapi.json"`), "Page should load the spec")
+	assert.True(t, strings.Contains(rr.Body.String(), "<title>Test API</title>"), "Page should be titled")
+}
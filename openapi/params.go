@@ -0,0 +1,73 @@
+// openapi/params.go
+
+package openapi
+
+import (
+	"reflect"
+	"strings"
+)
+
+// queryParams describes the fields of the request struct v tagged
+// `query:"name"`, the convention of the bind package. Fields with a required
+// validation are required and the doc tag is the description. Embedded
+// structs are flattened.
+func queryParams(v interface{}) []Param {
+	if v == nil {
+		return nil
+	}
+
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+
+	return structParams(t)
+}
+
+func structParams(t reflect.Type) []Param {
+	var params []Param
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			params = append(params, structParams(f.Type)...)
+			continue
+		}
+
+		name, ok := f.Tag.Lookup("query")
+		if !ok {
+			continue
+		}
+
+		params = append(params, Param{
+			Name:        name,
+			In:          "query",
+			Description: f.Tag.Get("doc"),
+			Required:    hasRule(f.Tag.Get("validate"), "required"),
+			Type:        paramType(f.Type),
+		})
+	}
+	return params
+}
+
+func hasRule(validate, rule string) bool {
+	for _, r := range strings.Split(validate, ",") {
+		if name, _, _ := strings.Cut(r, "="); name == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func paramType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "integer"
+	case reflect.Bool:
+		return "boolean"
+	default:
+		return "string"
+	}
+}
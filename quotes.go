@@ -0,0 +1,29 @@
+// quotes.go
+
+// Package quotes exposes the types other services need to talk to the
+// InspiringQuotes service. The implementations live under internal/.
+package quotes
+
+import (
+	"github.com/jeniok12/golang-tdd-example/internal/quote"
+	"github.com/jeniok12/golang-tdd-example/internal/recipient"
+	"github.com/jeniok12/golang-tdd-example/internal/server"
+)
+
+// Quote ...
+type Quote = quote.Quote
+
+// Generator ...
+type Generator = quote.Generator
+
+// Recipient ...
+type Recipient = recipient.Recipient
+
+// Group ...
+type Group = recipient.Group
+
+// QuoteResponse is the body of GET /quote.
+type QuoteResponse = server.HandleQuoteResponse
+
+// SendQuoteResponse is the body of POST /quote/send.
+type SendQuoteResponse = server.HandleSendQuoteResponse
@@ -0,0 +1,112 @@
+// ratelimit/ratelimit.go
+
+package ratelimit
+
+import (
+	"github.com/jeniok12/golang-tdd-example/clock"
+	"math"
+	"sync"
+	"time"
+)
+
+// Store keeps token buckets. Take removes one token from the bucket of key,
+// which refills at rate tokens per second up to burst. When the bucket is
+// empty it reports how long until the next token.
+type Store interface {
+	Take(key string, rate float64, burst int) (allowed bool, retryAfter time.Duration, err error)
+}
+
+// Limiter allows Rate requests per second per key, bursting up to Burst. A
+// Rate of 0 allows every request.
+type Limiter struct {
+	Store Store
+	Rate  float64
+	Burst int
+
+	mu sync.RWMutex
+}
+
+// Allow ...
+func (l *Limiter) Allow(key string) (bool, time.Duration, error) {
+	l.mu.RLock()
+	rate, burst := l.Rate, l.Burst
+	l.mu.RUnlock()
+
+	if rate <= 0 {
+		return true, 0, nil
+	}
+	return l.Store.Take(key, rate, burst)
+}
+
+// Set changes the rate and burst of a limiter in use. Buckets keep their
+// tokens and refill at the new rate.
+func (l *Limiter) Set(rate float64, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.Rate, l.Burst = rate, burst
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// sweepEvery is how many Take calls pass between removals of idle buckets.
+const sweepEvery = 1024
+
+// Memory is an in-process Store.
+type Memory struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	calls   int
+	clock   clock.Clock
+}
+
+// NewMemory ...
+func NewMemory() *Memory {
+	return &Memory{
+		buckets: make(map[string]*bucket),
+		clock:   clock.Real,
+	}
+}
+
+// Take ...
+func (m *Memory) Take(key string, rate float64, burst int) (bool, time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.clock.Now()
+
+	m.calls++
+	if m.calls%sweepEvery == 0 {
+		m.sweep(now, rate, burst)
+	}
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(burst), last: now}
+		m.buckets[key] = b
+	}
+
+	b.tokens = math.Min(float64(burst), b.tokens+now.Sub(b.last).Seconds()*rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0, nil
+	}
+
+	wait := time.Duration((1 - b.tokens) / rate * float64(time.Second))
+	return false, wait, nil
+}
+
+// sweep drops buckets that have refilled completely; they behave exactly
+// like missing ones.
+func (m *Memory) sweep(now time.Time, rate float64, burst int) {
+	for key, b := range m.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*rate >= float64(burst) {
+			delete(m.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+// ratelimit/ratelimit_test.go
+
+package ratelimit
+
+import (
+	"github.com/jeniok12/golang-tdd-example/clock"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestMemory_Take(t *testing.T) {
+	now := clock.NewMock(time.Now())
+	store := NewMemory()
+	store.clock = now
+	limiter := Limiter{Store: store, Rate: 2, Burst: 3}
+
+	for i := 0; i < 3; i++ {
+		allowed, _, err := limiter.Allow("client1")
+		assert.NoError(t, err, "Got error when not expected")
+		assert.True(t, allowed, "Burst should be allowed")
+	}
+
+	allowed, retryAfter, _ := limiter.Allow("client1")
+	assert.False(t, allowed, "Requests over burst should be limited")
+	assert.Equal(t, 500*time.Millisecond, retryAfter, "Next token should arrive after 1/rate seconds")
+
+	allowed, _, _ = limiter.Allow("client2")
+	assert.True(t, allowed, "Clients should have separate buckets")
+
+	now.Add(500 * time.Millisecond)
+	allowed, _, _ = limiter.Allow("client1")
+	assert.True(t, allowed, "Bucket should refill over time")
+}
+
+func TestMemory_Sweep(t *testing.T) {
+	now := clock.NewMock(time.Now())
+	store := NewMemory()
+	store.clock = now
+
+	store.Take("idle", 1, 1)
+	now.Add(time.Minute)
+	for i := 0; i < sweepEvery; i++ {
+		store.Take("busy", 1000000, 1)
+	}
+
+	_, ok := store.buckets["idle"]
+	assert.False(t, ok, "Refilled buckets should be swept")
+}
+
+func TestLimiter_Set(t *testing.T) {
+	now := clock.NewMock(time.Now())
+	store := NewMemory()
+	store.clock = now
+	limiter := Limiter{Store: store, Rate: 1, Burst: 1}
+
+	allowed, _, _ := limiter.Allow("client1")
+	assert.True(t, allowed, "Burst should be allowed")
+	allowed, _, _ = limiter.Allow("client1")
+	assert.False(t, allowed, "Requests over burst should be limited")
+
+	limiter.Set(0, 1)
+	allowed, _, _ = limiter.Allow("client1")
+	assert.True(t, allowed, "A rate of 0 should allow every request")
+
+	limiter.Set(10, 1)
+	now.Add(100 * time.Millisecond)
+	allowed, _, _ = limiter.Allow("client1")
+	assert.True(t, allowed, "Bucket should refill at the new rate")
+}
@@ -0,0 +1,19 @@
+// requestid/requestid_test.go
+
+package requestid
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	id := New()
+	assert.Len(t, id, 32, "Request ID should be 16 hex encoded bytes")
+	assert.NotEqual(t, id, New(), "Request IDs should be unique")
+
+	ctx := NewContext(context.Background(), id)
+	assert.Equal(t, id, FromContext(ctx), "Request ID should be read from context")
+	assert.Empty(t, FromContext(context.Background()), "Empty context should have no request ID")
+}
@@ -0,0 +1,85 @@
+// scheduler/scheduler.go
+
+package scheduler
+
+import (
+	"context"
+	"github.com/jeniok12/golang-tdd-example/clock"
+	"sync"
+	"time"
+)
+
+// Job ...
+type Job func(ctx context.Context) error
+
+// Scheduler runs Job every Interval between Start and Stop. Runs never
+// overlap: a manual Run waits for a scheduled one and vice versa.
+type Scheduler struct {
+	Interval time.Duration
+	Job      Job
+	OnError  func(err error)
+	// Clock defaults to the system clock.
+	Clock clock.Clock
+
+	runMu  sync.Mutex
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Start ...
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.done = make(chan struct{})
+
+	go s.loop(ctx, s.done)
+}
+
+// Stop cancels the running job, if any, and waits for the loop to exit.
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel, s.done = nil, nil
+	s.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+
+	cancel()
+	<-done
+}
+
+// Run executes the job immediately.
+func (s *Scheduler) Run(ctx context.Context) error {
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+
+	return s.Job(ctx)
+}
+
+func (s *Scheduler) loop(ctx context.Context, done chan struct{}) {
+	defer close(done)
+
+	ticker := clock.Or(s.Clock).NewTicker(s.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+			if err := s.Run(ctx); err != nil && s.OnError != nil {
+				s.OnError(err)
+			}
+		}
+	}
+}
@@ -0,0 +1,60 @@
+// scheduler/scheduler_test.go
+
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"github.com/jeniok12/golang-tdd-example/clock"
+	"github.com/stretchr/testify/assert"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestScheduler(t *testing.T) {
+	var runs int32
+	errs := make(chan error, 10)
+	mock := clock.NewMock(time.Now())
+
+	s := Scheduler{
+		Interval: time.Hour,
+		Job: func(ctx context.Context) error {
+			atomic.AddInt32(&runs, 1)
+			return errors.New("sample error")
+		},
+		OnError: func(err error) { errs <- err },
+		Clock:   mock,
+	}
+
+	s.Start()
+	mock.BlockUntil(1)
+	mock.Add(59 * time.Minute)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&runs), "Job should wait for the interval")
+
+	mock.Add(time.Minute)
+	assert.EqualError(t, <-errs, "sample error", "Job error should be reported")
+	mock.Add(time.Hour)
+	assert.EqualError(t, <-errs, "sample error", "Job should run every interval")
+	s.Stop()
+
+	mock.Add(time.Hour)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&runs), "Job should run once per interval and not after Stop")
+
+	s.Stop()
+}
+
+func TestScheduler_Run(t *testing.T) {
+	var gotCtx context.Context
+	s := Scheduler{
+		Interval: time.Hour,
+		Job: func(ctx context.Context) error {
+			gotCtx = ctx
+			return nil
+		},
+	}
+
+	ctx := context.WithValue(context.Background(), struct{}{}, "bla")
+	assert.NoError(t, s.Run(ctx), "Got error when not expected")
+	assert.Equal(t, ctx, gotCtx, "Manual run should use the caller's context")
+}
@@ -0,0 +1,52 @@
+// tenant/tenant.go
+
+// Package tenant carries the tenant a request acts for. The stores read it
+// from the context, so every query is scoped without threading an argument
+// through each call.
+package tenant
+
+import (
+	"context"
+	"regexp"
+)
+
+// Header selects the tenant of a request.
+const Header = "X-Tenant-ID"
+
+// Default is the tenant of requests naming none, and of the rows that
+// existed before there were tenants.
+const Default = "default"
+
+// validID keeps IDs safe to log, to put in cache keys and in URLs.
+var validID = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+type contextKey struct{}
+
+// Valid reports whether id may name a tenant: up to 63 lowercase letters,
+// digits, dashes and underscores, starting with a letter or a digit.
+func Valid(id string) bool {
+	return validID.MatchString(id)
+}
+
+// NewContext returns a copy of ctx acting for the tenant id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the tenant of ctx, or Default when it has none.
+func FromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(contextKey{}).(string); ok && id != "" {
+		return id
+	}
+	return Default
+}
+
+// Key scopes key, say of a cache entry, to the tenant of ctx. The keys of the
+// default tenant are left as they are, so entries saved before there were
+// tenants are still found.
+func Key(ctx context.Context, key string) string {
+	if id := FromContext(ctx); id != Default {
+		return id + ":" + key
+	}
+	return key
+}
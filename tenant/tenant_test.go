@@ -0,0 +1,43 @@
+// tenant/tenant_test.go
+
+package tenant
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestContext(t *testing.T) {
+	assert.Equal(t, Default, FromContext(context.Background()), "Context without a tenant should be the default tenant")
+
+	ctx := NewContext(context.Background(), "acme")
+	assert.Equal(t, "acme", FromContext(ctx), "Tenant should be read from context")
+}
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, "en", Key(context.Background(), "en"), "Default tenant keys should be unchanged")
+	assert.Equal(t, "acme:en", Key(NewContext(context.Background(), "acme"), "en"), "Keys should be prefixed with the tenant")
+}
+
+func TestValid(t *testing.T) {
+	testCases := []struct {
+		id       string
+		expected bool
+	}{
+		{"acme", true},
+		{"acme-corp_2", true},
+		{"7eleven", true},
+		{"", false},
+		{"-acme", false},
+		{"Acme", false},
+		{"acme:en", false},
+		{"acme corp", false},
+		{strings.Repeat("a", 63), true},
+		{strings.Repeat("a", 64), false},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.expected, Valid(tc.id), "Validity of %q is different than expected", tc.id)
+	}
+}
@@ -0,0 +1,71 @@
+// tracing/tracing_test.go
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"net/http"
+	"testing"
+)
+
+// record installs a global tracer provider recording ended spans.
+func record(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	_, err := Setup(context.Background(), Config{})
+	require.NoError(t, err, "Got error when not expected")
+
+	return recorder
+}
+
+func TestStartEnd(t *testing.T) {
+	recorder := record(t)
+
+	ctx, parent := Start(context.Background(), "parent")
+	_, child := Start(ctx, "child")
+	End(child, errors.New("sample error"))
+	End(parent, nil)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "child", spans[0].Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), spans[0].Parent().SpanID(), "Child should be started within the parent span")
+	assert.Equal(t, codes.Error, spans[0].Status().Code, "Errors should be recorded")
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+}
+
+func TestPropagation(t *testing.T) {
+	record(t)
+
+	ctx, span := Start(context.Background(), "client")
+	defer span.End()
+
+	header := http.Header{}
+	Inject(ctx, header)
+	assert.NotEmpty(t, header.Get("traceparent"), "The trace context should be sent")
+
+	remote := Extract(context.Background(), header)
+	assert.Equal(t, TraceID(ctx), TraceID(remote), "The trace should continue across the call")
+}
+
+func TestTraceID(t *testing.T) {
+	record(t)
+
+	ctx, span := Start(context.Background(), "request")
+	defer span.End()
+
+	assert.Equal(t, span.SpanContext().TraceID().String(), TraceID(ctx))
+	assert.Empty(t, TraceID(context.Background()), "Outside a trace there is no trace ID")
+}
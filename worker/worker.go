@@ -0,0 +1,255 @@
+// worker/worker.go
+
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned when submitting to a pool that is shutting down.
+var ErrClosed = errors.New("worker: pool is closed")
+
+// ErrQueueFull is returned by Submit when every worker is busy and the queue
+// has no room left.
+var ErrQueueFull = errors.New("worker: queue is full")
+
+// Job is a unit of background work. Name groups runs in the pool metrics.
+type Job interface {
+	Name() string
+	Run(ctx context.Context) error
+}
+
+type funcJob struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+func (j funcJob) Name() string                  { return j.name }
+func (j funcJob) Run(ctx context.Context) error { return j.fn(ctx) }
+
+// Func turns fn into a Job called name.
+func Func(name string, fn func(ctx context.Context) error) Job {
+	return funcJob{name: name, fn: fn}
+}
+
+// PanicError is the error of a job that panicked.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("worker: job panicked: %v", e.Value)
+}
+
+// JobStats ...
+type JobStats struct {
+	Runs         uint64        `json:"runs"`
+	Failures     uint64        `json:"failures"`
+	Panics       uint64        `json:"panics"`
+	Running      int           `json:"running"`
+	LastDuration time.Duration `json:"lastDurationNs"`
+	LastError    string        `json:"lastError,omitempty"`
+}
+
+type task struct {
+	job  Job
+	done chan error
+}
+
+// Pool runs jobs on a fixed number of goroutines. Jobs get a context that is
+// canceled when Shutdown gives up waiting for them.
+type Pool struct {
+	// OnError is called with the jobs that fail, panics included.
+	OnError func(job string, err error)
+
+	queue  chan task
+	size   int
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	closeMu sync.RWMutex
+	closed  bool
+
+	mu    sync.Mutex
+	stats map[string]*JobStats
+}
+
+// New returns a pool of size workers with room for queue waiting jobs. The
+// workers start right away.
+func New(size, queue int) *Pool {
+	if size < 1 {
+		size = 1
+	}
+	if queue < 0 {
+		queue = 0
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &Pool{
+		queue:  make(chan task, queue),
+		size:   size,
+		ctx:    ctx,
+		cancel: cancel,
+		stats:  make(map[string]*JobStats),
+	}
+
+	p.wg.Add(size)
+	for i := 0; i < size; i++ {
+		go p.work()
+	}
+
+	return p
+}
+
+// Submit queues job without waiting for it to run.
+func (p *Pool) Submit(job Job) error {
+	return p.enqueue(context.Background(), task{job: job}, false)
+}
+
+// Run runs job on the pool and returns its error, waiting for a free worker
+// if needed. Canceling ctx stops the wait, not a job that already started.
+func (p *Pool) Run(ctx context.Context, job Job) error {
+	t := task{job: job, done: make(chan error, 1)}
+	if err := p.enqueue(ctx, t, true); err != nil {
+		return err
+	}
+
+	select {
+	case err := <-t.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// enqueue adds t to the queue. With wait set it waits for room until ctx is
+// done instead of failing with ErrQueueFull.
+func (p *Pool) enqueue(ctx context.Context, t task, wait bool) error {
+	// Holding the read lock keeps Shutdown from closing the queue under us.
+	p.closeMu.RLock()
+	defer p.closeMu.RUnlock()
+
+	if p.closed {
+		return ErrClosed
+	}
+
+	if !wait {
+		select {
+		case p.queue <- t:
+			return nil
+		default:
+			return ErrQueueFull
+		}
+	}
+
+	select {
+	case p.queue <- t:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown stops accepting jobs and waits for the queued and running ones to
+// finish. When ctx is done first, running jobs are canceled and Shutdown
+// returns ctx.Err() once they have returned.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	p.closeMu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.queue)
+	}
+	p.closeMu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		<-drained
+		return ctx.Err()
+	}
+}
+
+// Stats returns the metrics of every job name the pool has run.
+func (p *Pool) Stats() map[string]JobStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	stats := make(map[string]JobStats, len(p.stats))
+	for name, s := range p.stats {
+		stats[name] = *s
+	}
+	return stats
+}
+
+func (p *Pool) work() {
+	defer p.wg.Done()
+
+	for t := range p.queue {
+		err := p.run(t.job)
+		if t.done != nil {
+			t.done <- err
+		}
+	}
+}
+
+func (p *Pool) run(job Job) (err error) {
+	name := job.Name()
+	p.record(name, func(s *JobStats) { s.Running++ })
+
+	start := time.Now()
+	defer func() {
+		if v := recover(); v != nil {
+			err = &PanicError{Value: v, Stack: debug.Stack()}
+		}
+
+		elapsed := time.Since(start)
+		p.record(name, func(s *JobStats) {
+			s.Running--
+			s.Runs++
+			s.LastDuration = elapsed
+			s.LastError = ""
+			if err != nil {
+				s.Failures++
+				s.LastError = err.Error()
+			}
+			var panicErr *PanicError
+			if errors.As(err, &panicErr) {
+				s.Panics++
+			}
+		})
+
+		if err != nil && p.OnError != nil {
+			p.OnError(name, err)
+		}
+	}()
+
+	return job.Run(p.ctx)
+}
+
+func (p *Pool) record(name string, update func(s *JobStats)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s, ok := p.stats[name]
+	if !ok {
+		s = &JobStats{}
+		p.stats[name] = s
+	}
+	update(s)
+}
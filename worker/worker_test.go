@@ -0,0 +1,128 @@
+// worker/worker_test.go
+
+package worker
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPool_Run(t *testing.T) {
+	testCases := []struct {
+		name          string
+		job           func(ctx context.Context) error
+		expectedError string
+		expectedStats JobStats
+	}{
+		{
+			"Success",
+			func(ctx context.Context) error { return nil },
+			"",
+			JobStats{Runs: 1},
+		},
+		{
+			"Failure",
+			func(ctx context.Context) error { return errors.New("sample error") },
+			"sample error",
+			JobStats{Runs: 1, Failures: 1, LastError: "sample error"},
+		},
+		{
+			"Panic",
+			func(ctx context.Context) error { panic("boom") },
+			"worker: job panicked: boom",
+			JobStats{Runs: 1, Failures: 1, Panics: 1, LastError: "worker: job panicked: boom"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var reported []string
+			p := New(1, 0)
+			p.OnError = func(job string, err error) { reported = append(reported, job) }
+
+			err := p.Run(context.Background(), Func("job", tc.job))
+			require.NoError(t, p.Shutdown(context.Background()), "Should have no error when shutting down")
+
+			stats := p.Stats()["job"]
+			stats.LastDuration = 0
+			assert.Equal(t, tc.expectedStats, stats, "Job stats are different than expected")
+			if tc.expectedError == "" {
+				assert.NoError(t, err, "Got error when not expected")
+				assert.Empty(t, reported, "Successful jobs should not be reported")
+				return
+			}
+			assert.EqualError(t, err, tc.expectedError, "Job error is different than expected")
+			assert.Equal(t, []string{"job"}, reported, "Failed jobs should be reported")
+		})
+	}
+}
+
+func TestPool_Submit(t *testing.T) {
+	release := make(chan struct{})
+	var runs int32
+	blocking := Func("blocking", func(ctx context.Context) error {
+		<-release
+		atomic.AddInt32(&runs, 1)
+		return nil
+	})
+
+	p := New(1, 1)
+	started := make(chan struct{})
+	require.NoError(t, p.Submit(Func("first", func(ctx context.Context) error {
+		close(started)
+		<-release
+		atomic.AddInt32(&runs, 1)
+		return nil
+	})))
+	<-started
+
+	assert.NoError(t, p.Submit(blocking), "Queued job should be accepted")
+	assert.Equal(t, ErrQueueFull, p.Submit(blocking), "Submit should not wait for room")
+
+	close(release)
+	assert.NoError(t, p.Shutdown(context.Background()), "Shutdown should drain the queue")
+	assert.Equal(t, int32(2), atomic.LoadInt32(&runs), "Queued jobs should run before shutdown")
+	assert.Equal(t, ErrClosed, p.Submit(blocking), "Closed pool should refuse jobs")
+}
+
+func TestPool_ShutdownTimeout(t *testing.T) {
+	p := New(1, 1)
+	started := make(chan struct{})
+	require.NoError(t, p.Submit(Func("stuck", func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	})))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	assert.Equal(t, context.DeadlineExceeded, p.Shutdown(ctx), "Shutdown should give up at the deadline")
+	assert.Equal(t, uint64(1), p.Stats()["stuck"].Failures, "Canceled job should have returned")
+}
+
+func TestPool_RunCanceled(t *testing.T) {
+	p := New(1, 1)
+	defer p.Shutdown(context.Background())
+
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	require.NoError(t, p.Submit(Func("busy", func(ctx context.Context) error {
+		close(started)
+		<-release
+		return nil
+	})))
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err := p.Run(ctx, Func("waiting", func(ctx context.Context) error { return nil }))
+	assert.Equal(t, context.DeadlineExceeded, err, "Run should stop waiting for a worker")
+}